@@ -3,24 +3,67 @@ package api
 import "time"
 
 type Track struct {
-	ID        string        `json:"id"`
-	Title     string        `json:"title"`
-	Artist    string        `json:"artist"`
-	Album     string        `json:"album"`
-	Duration  time.Duration `json:"duration"`
-	FilePath  string        `json:"file_path"`
-	Genre     string        `json:"genre"`
-	Year      int           `json:"year"`
-	TrackNum  int           `json:"track_number"`
-	CoverArt  []byte        `json:"-"`
-	CreatedAt time.Time     `json:"created_at"`
+	ID          string        `json:"id"`
+	Title       string        `json:"title"`
+	Artist      string        `json:"artist"`
+	Album       string        `json:"album"`
+	AlbumArtist string        `json:"album_artist,omitempty"`
+	Compilation bool          `json:"compilation,omitempty"` // tagged as a various-artists release
+	Duration    time.Duration `json:"duration"`
+	Bitrate     int           `json:"bitrate,omitempty"`     // average kbit/s of the file, 0 if unknown
+	SampleRate  int           `json:"sample_rate,omitempty"` // Hz
+	Channels    int           `json:"channels,omitempty"`
+	Codec       string        `json:"codec,omitempty"` // "MP3", "FLAC" or "PCM"
+	FileSize    int64         `json:"file_size,omitempty"`
+	FilePath    string        `json:"file_path"`
+	Genre       string        `json:"genre"`
+	Year        int           `json:"year"`
+	TrackNum    int           `json:"track_number"`
+	CoverArt    []byte        `json:"-"`
+	Chapters    []Chapter     `json:"chapters,omitempty"`
+	Gapless     bool          `json:"gapless,omitempty"` // tagged to play without gaps or crossfades
+	CreatedAt   time.Time     `json:"created_at"`
 }
 
+// Album is one release in the library, derived from its tracks' tags.
+// Albums are maintained by the library as tracks change and not stored.
+type Album struct {
+	ID       string        `json:"id"`
+	Name     string        `json:"name"`
+	Artist   string        `json:"artist"` // "Various Artists" when the tracks differ
+	Year     int           `json:"year,omitempty"`
+	ArtPath  string        `json:"art_path,omitempty"` // cover image next to the tracks, if any
+	TrackIDs []string      `json:"track_ids"`          // in track order
+	Duration time.Duration `json:"duration"`
+}
+
+// Artist aggregates an artist's tracks and the albums they appear on,
+// derived by the library from the tracks' artist tags
+type Artist struct {
+	Name       string        `json:"name"`
+	AlbumIDs   []string      `json:"album_ids"` // oldest first
+	AlbumCount int           `json:"album_count"`
+	TrackCount int           `json:"track_count"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// Chapter is a named section of a long track such as an audiobook
+type Chapter struct {
+	Title string        `json:"title"`
+	Start time.Duration `json:"start"`
+	End   time.Duration `json:"end"`
+}
+
+// Playlist is an ordered list of tracks. It refers to them by library ID,
+// so tag edits and rescans show in it; Tracks is filled in from the library
+// by the playlist manager.
 type Playlist struct {
 	ID          string    `json:"id"`
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
-	Tracks      []Track   `json:"tracks"`
+	Creator     string    `json:"creator,omitempty"` // author, kept for XSPF interchange
+	TrackIDs    []string  `json:"track_ids"`         // what is saved: the tracks' library IDs, in order
+	Tracks      []Track   `json:"-"`                 // the tracks of TrackIDs as the library has them
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -33,6 +76,37 @@ const (
 	StatusPaused
 )
 
+// BufferState tells where the playing audio is read from
+type BufferState int
+
+const (
+	BufferNone      BufferState = iota // nothing loaded
+	BufferLocal                        // a local file, available in full
+	BufferStreaming                    // an HTTP stream downloaded as it plays
+)
+
+// ScanProgress is the payload of EventScanProgress, a snapshot of a
+// library scan
+type ScanProgress struct {
+	Discovered int    // supported files found so far
+	Processed  int    // files whose tags were read, failed ones included
+	Errors     int    // files and directories that could not be read
+	Current    string // file most recently read
+	LastError  string // most recent error, if any
+	Done       bool   // set on the last event of a scan
+	Cancelled  bool   // set with Done when the scan was stopped early
+}
+
+// ProgressPayload is the payload of EventPositionUpdate
+type ProgressPayload struct {
+	Position    time.Duration `json:"position"`
+	OutputDelay time.Duration `json:"output_delay"` // how far decoding is ahead of Position, see PlaybackState
+	Duration    time.Duration `json:"duration"`     // decoded length, or the tagged duration; 0 if unknown
+	Bitrate     int           `json:"bitrate"`      // average kbit/s of the source, 0 if unknown
+	Buffer      BufferState   `json:"buffer"`
+	Buffered    float64       `json:"buffered"` // fraction of the source read so far, 1 for local files
+}
+
 // RepeatMode represents repeat options
 type RepeatMode int
 
@@ -46,12 +120,71 @@ const (
 type PlaybackState struct {
 	CurrentTrack *Track        `json:"current_track"`
 	Status       PlayerStatus  `json:"status"`
-	Position     time.Duration `json:"position"`
-	Volume       float64       `json:"volume"` // 0.0 to 1.0
+	Position     time.Duration `json:"position"`     // what is heard on the output
+	OutputDelay  time.Duration `json:"output_delay"` // audio decoded but not heard yet; Position+OutputDelay is what was sent
+	Volume       float64       `json:"volume"`       // 0.0 to 1.0
+	VolumeDB     float64       `json:"volume_db"`    // gain of Volume on the output's volume curve
+	Muted        bool          `json:"muted"`        // output silenced; Volume keeps the level to restore
 	Repeat       RepeatMode    `json:"repeat"`
 	Shuffle      bool          `json:"shuffle"`
+	Consume      bool          `json:"consume"` // played tracks are removed from the queue
+	Party        bool          `json:"party"`   // play actions append to the queue
 	Queue        []*Track      `json:"queue"`
 	QueueIndex   int           `json:"queue_index"`
+	Output       string        `json:"output"`      // name of the active audio sink
+	OutputTrim   string        `json:"output_trim"` // per-sink trim/delay summary, empty if none
+	DuckDB       float64       `json:"duck_db"`     // current ducking attenuation in dB, 0 when not ducked
+	GainDB       float64       `json:"gain_db"`     // net gain of volume, ducking and output trim
+	PeakDB       float64       `json:"peak_db"`     // recent output peak in dBFS, before limiting
+	Clipping     bool          `json:"clipping"`    // samples went above full scale within the last seconds
+	Limiter      bool          `json:"limiter"`     // the auto-limiter is enabled
+	Limiting     bool          `json:"limiting"`    // the limiter is currently reducing gain
+	Buffering    bool          `json:"buffering"`   // playback waits for the file to be read
+}
+
+// Levels are the live output levels of the left and right channels in
+// dBFS. Peaks fall off gradually and RMS is averaged over a short window,
+// like a hardware meter.
+type Levels struct {
+	PeakL float64 `json:"peak_l"`
+	PeakR float64 `json:"peak_r"`
+	RMSL  float64 `json:"rms_l"`
+	RMSR  float64 `json:"rms_r"`
+}
+
+// LevelsProvider reports the live output levels. Reads do not reset them,
+// so it can be polled as often as a meter redraws.
+type LevelsProvider interface {
+	Levels() Levels
+}
+
+// Snapshot is the playback state and the queue captured together, so the
+// position, current track and queue index all describe the same instant
+type Snapshot struct {
+	PlaybackState
+	TrackStartedAt time.Time `json:"track_started_at"` // when the current track started, zero if none
+	TakenAt        time.Time `json:"taken_at"`
+}
+
+// QueueState is a queue that can copy its contents, index, repeat mode and
+// shuffle flag into a PlaybackState in one locked read
+type QueueState interface {
+	FillState(state *PlaybackState)
+}
+
+// Sequencer supplies the tracks a player moves to on its own: Next when a
+// track ends or is skipped, Previous when stepping back. Both return nil
+// when there is nowhere to go.
+type Sequencer interface {
+	Next() *Track
+	Previous() *Track
+}
+
+// Consumer is a Sequencer that drops tracks once they have played. Players
+// call Consume when playback runs off the end, where Next returned nil and
+// so removed nothing.
+type Consumer interface {
+	Consume()
 }
 
 // CommandType enumerates audio commands
@@ -66,12 +199,16 @@ const (
 	CmdVolume
 	CmdNext
 	CmdPrevious
+	CmdSwitchSink
+	CmdDuck
+	CmdCrossfade
+	CmdMute
 )
 
 // AudioCommand represents commands sent to the audio engine
 type AudioCommand struct {
 	Type    CommandType
-	Payload interface{} // Can be *Track, float64 for volume, time.Duration for seek
+	Payload interface{} // Can be *Track, float64 for volume, time.Duration for seek, string for sink name, bool for mute
 }
 
 // EventType enumerates audio events
@@ -80,17 +217,36 @@ type EventType int
 const (
 	EventTrackStarted EventType = iota
 	EventTrackEnded
-	EventPositionUpdate
+	EventPositionUpdate // Payload: ProgressPayload
 	EventError
 	EventStateChange
+	EventLibraryChanged  // Payload: changed track ID, or nil after a scan
+	EventScanProgress    // Payload: ScanProgress
+	EventPlaylistChanged // Payload: playlist ID
+	EventTrackFailed     // Payload: TrackFailure
+	EventBuffering       // Payload: bool, true while playback waits for a slow file
+	EventQueueChanged    // Payload: nil; tracks were added, removed or reordered
 )
 
+// TrackFailure is the payload of EventTrackFailed: a track that failed to
+// open, to decode or partway through playback
+type TrackFailure struct {
+	Track   *Track
+	Err     error
+	Skipped bool // playback moved on to the next track
+}
+
 // AudioEvent represents events emitted by the audio engine
 type AudioEvent struct {
 	Type    EventType
 	Payload interface{}
 }
 
+// Publisher accepts events for distribution, e.g. an events.EventBus
+type Publisher interface {
+	Publish(event AudioEvent)
+}
+
 // Player defines the core playback interface
 type Player interface {
 	Play(track *Track) error
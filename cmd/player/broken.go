@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/pkg/events"
+)
+
+// markBroken marks the tracks that fail to play as broken in the library,
+// with the error, and clears the mark once one plays again, until ctx is
+// done. Streamed tracks are not in the library and are left alone.
+func markBroken(ctx context.Context, bus *events.EventBus, lib *library.Library) {
+	sub := bus.SubscribeWith(events.Policy{
+		Types:    []api.EventType{api.EventTrackFailed, api.EventTrackStarted},
+		Lossless: true,
+	})
+	go func() {
+		defer bus.Unsubscribe(sub)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-sub:
+				switch p := ev.Payload.(type) {
+				case api.TrackFailure:
+					if p.Track != nil && p.Err != nil {
+						lib.SetBroken(p.Track.ID, p.Err.Error())
+					}
+				case *api.Track:
+					if p != nil {
+						lib.SetBroken(p.ID, "")
+					}
+				}
+			}
+		}
+	}()
+}
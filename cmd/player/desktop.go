@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/jscyril/golang_music_player/internal/desktop"
+)
+
+// runInstallDesktopEntry registers the player as the application for
+// audio files on freedesktop.org desktops, running this binary (with the
+// profile in use) on the files opened
+func runInstallDesktopEntry(profile string, args []string) error {
+	fs := flag.NewFlagSet("install-desktop-entry", flag.ContinueOnError)
+	printOnly := fs.Bool("print", false, "Print the desktop entry instead of installing it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("find the player binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	command := []string{exe}
+	if profile != "" {
+		command = append(command, "--profile", profile)
+	}
+	if *printOnly {
+		fmt.Print(desktop.Entry(command))
+		return nil
+	}
+	if runtime.GOOS == "windows" || runtime.GOOS == "darwin" {
+		return fmt.Errorf("desktop entries are for Linux and BSD desktops, not %s", runtime.GOOS)
+	}
+
+	written, err := desktop.Install(command)
+	if err != nil {
+		return err
+	}
+	for _, path := range written {
+		fmt.Println("Wrote", path)
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import (
+	"context"
+
+	"github.com/jscyril/golang_music_player/internal/audio"
+)
+
+// handleDuckSignals is a no-op where SIGUSR1/SIGUSR2 do not exist
+func handleDuckSignals(ctx context.Context, engine *audio.AudioEngine, db float64) {}
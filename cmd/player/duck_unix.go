@@ -0,0 +1,38 @@
+//go:build unix
+
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jscyril/golang_music_player/internal/audio"
+	"github.com/jscyril/golang_music_player/internal/logger"
+)
+
+// handleDuckSignals ducks playback on SIGUSR1 and restores it on SIGUSR2,
+// so notification daemons, VoIP hooks or TTS scripts can run e.g.
+// `pkill -USR1 player` before speaking and `pkill -USR2 player` after.
+func handleDuckSignals(ctx context.Context, engine *audio.AudioEngine, db float64) {
+	sigs := make(chan os.Signal, 4)
+	signal.Notify(sigs, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		defer signal.Stop(sigs)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-sigs:
+				if sig == syscall.SIGUSR1 {
+					logger.Info("Duck requested by signal")
+					engine.Duck(db)
+				} else {
+					logger.Info("Unduck requested by signal")
+					engine.Unduck()
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/audio"
+	"github.com/jscyril/golang_music_player/internal/crash"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/logger"
+	"github.com/jscyril/golang_music_player/internal/playlist"
+	"github.com/jscyril/golang_music_player/internal/schedule"
+)
+
+// runHeadless plays the queue without the terminal UI, printing each track
+// as it starts, until the queue runs out or ctx is cancelled. Alarms set
+// off their playlists and quiet hours stop playback as in the UI; while an
+// alarm is still to go off, the player waits for it instead of exiting.
+// queue may be empty only then.
+func runHeadless(ctx context.Context, engine *audio.AudioEngine, lib *library.Library, plManager *playlist.Manager, queue *playlist.Queue, sched *schedule.Scheduler) error {
+	crash.AddState("queue", func() any { return queue.Saved("", engine.GetState().Position) })
+	current := queue.Current()
+	var started time.Time
+	// record adds the finished (or interrupted) track to the play history
+	record := func(track *api.Track, completed bool) {
+		if track == nil {
+			return
+		}
+		played := engine.GetState().Position
+		if completed {
+			played = track.Duration
+		}
+		rec := library.PlayRecord{
+			TrackID:   track.ID,
+			FilePath:  track.FilePath,
+			Title:     track.Title,
+			Artist:    track.Artist,
+			PlayedAt:  started,
+			Played:    played,
+			Duration:  track.Duration,
+			Completed: completed,
+		}
+		if err := lib.RecordPlay(rec); err != nil {
+			logger.Warn("Failed to record play: %v", err)
+		}
+	}
+
+	// The engine advances the queue, past tracks that fail to play as its
+	// failure policy allows
+	engine.SetQueue(queue)
+	if current != nil {
+		if err := engine.Play(current); err != nil {
+			return err
+		}
+	}
+
+	var tick <-chan time.Time
+	var ramp schedule.Ramp
+	if sched != nil {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		tick = ticker.C
+		sched.Update(time.Now())
+	}
+	waiting := func() bool { return sched != nil && sched.Waiting() }
+	if current == nil {
+		fmt.Println("Waiting for the next alarm")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			record(current, false)
+			engine.Stop()
+			return nil
+		case now := <-tick:
+			if ramp.Running() {
+				volume, done := ramp.Volume(now)
+				engine.SetVolume(volume)
+				if done {
+					ramp = schedule.Ramp{}
+				}
+			}
+			due, quiet := sched.Update(now)
+			if quiet && engine.GetState().Status != api.StatusStopped {
+				logger.Info("Quiet hours began, stopping playback")
+				fmt.Println("Quiet hours began, stopping playback")
+				record(current, false)
+				current = nil
+				ramp = schedule.Ramp{}
+				engine.Stop()
+			}
+			// Alarms due at once would only replace each other's queue
+			if len(due) > 0 {
+				record(current, false)
+				if err := startAlarm(engine, plManager, queue, due[0], now, &ramp); err != nil {
+					logger.Warn("%v", err)
+					fmt.Printf("  error: %v\n", err)
+				}
+			}
+		case event := <-engine.Events():
+			switch event.Type {
+			case api.EventTrackStarted:
+				if track, ok := event.Payload.(*api.Track); ok {
+					current = track
+					fmt.Printf("▶ %s - %s\n", track.Artist, track.Title)
+					logger.Info("Headless playback: %q by %s", track.Title, track.Artist)
+					started = time.Now()
+				}
+			case api.EventTrackEnded:
+				record(current, true)
+			case api.EventStateChange:
+				// Playback stops once the queue runs out or quiet hours begin
+				if state, ok := event.Payload.(*api.PlaybackState); ok && state.Status == api.StatusStopped {
+					if !waiting() {
+						return nil
+					}
+					current = nil
+				}
+			case api.EventTrackFailed:
+				if f, ok := event.Payload.(api.TrackFailure); ok {
+					fmt.Printf("  error: %v\n", f.Err)
+				}
+			case api.EventError:
+				fmt.Printf("  error: %v\n", event.Payload)
+			}
+		}
+	}
+}
+
+// startAlarm replaces the queue with the alarm's playlist and plays it,
+// setting ramp when the alarm fades in
+func startAlarm(engine *audio.AudioEngine, plManager *playlist.Manager, queue *playlist.Queue, a schedule.Alarm, now time.Time, ramp *schedule.Ramp) error {
+	pl, err := findPlaylist(plManager, a.Playlist)
+	if err != nil || len(pl.Tracks) == 0 {
+		return fmt.Errorf("alarm %s: no playlist %q or it is empty", a.Label(), a.Playlist)
+	}
+	tracks := make([]*api.Track, len(pl.Tracks))
+	for i := range pl.Tracks {
+		tracks[i] = &pl.Tracks[i]
+	}
+	startQueue(queue, tracks, a.Shuffle)
+
+	volume := a.Volume
+	if volume == 0 {
+		volume = engine.GetState().Volume
+	}
+	*ramp = schedule.Ramp{}
+	if a.Ramp > 0 {
+		*ramp = schedule.Ramp{To: volume, Start: now, Length: a.Ramp}
+		volume = 0
+	}
+	engine.SetVolume(volume)
+	logger.Info("Alarm %s: playing %q", a.Label(), pl.Name)
+	return engine.Play(queue.Current())
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/audio"
+	"github.com/jscyril/golang_music_player/internal/config"
+	"github.com/jscyril/golang_music_player/internal/hotkey"
+	"github.com/jscyril/golang_music_player/internal/logger"
+)
+
+// handleHotkeys registers the configured global hotkeys and drives the
+// engine from them until ctx is done. Keys that cannot be parsed or
+// grabbed are reported and leave the others working where possible.
+func handleHotkeys(ctx context.Context, engine *audio.AudioEngine, keys config.GlobalHotkeys) {
+	var bindings []hotkey.Binding
+	for _, k := range []struct{ action, spec string }{
+		{"play_pause", keys.PlayPause}, {"next", keys.Next}, {"previous", keys.Previous},
+	} {
+		if k.spec == "" {
+			continue
+		}
+		h, err := hotkey.Parse(k.spec)
+		if err != nil {
+			logger.Warn("global_hotkeys.%s: %v", k.action, err)
+			continue
+		}
+		bindings = append(bindings, hotkey.Binding{Action: k.action, Hotkey: h})
+	}
+	if len(bindings) == 0 {
+		return
+	}
+	pressed, err := hotkey.Listen(ctx, bindings)
+	if err != nil {
+		logger.Warn("%v", err)
+		return
+	}
+	go func() {
+		for action := range pressed {
+			logger.Info("Global hotkey: %s", action)
+			switch action {
+			case "play_pause":
+				state := engine.GetState()
+				switch {
+				case state.Status == api.StatusPlaying:
+					engine.Pause()
+				case state.Status == api.StatusPaused:
+					engine.Resume()
+				case state.CurrentTrack != nil:
+					engine.Play(state.CurrentTrack)
+				}
+			case "next":
+				engine.Next()
+			case "previous":
+				engine.Previous()
+			}
+		}
+	}()
+}
@@ -2,20 +2,46 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"slices"
+	"strings"
 	"syscall"
+	"time"
 
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/apiserver"
+	"github.com/jscyril/golang_music_player/internal/artwork"
 	"github.com/jscyril/golang_music_player/internal/audio"
+	"github.com/jscyril/golang_music_player/internal/audiobook"
 	"github.com/jscyril/golang_music_player/internal/config"
+	"github.com/jscyril/golang_music_player/internal/crash"
+	"github.com/jscyril/golang_music_player/internal/dlna"
+	"github.com/jscyril/golang_music_player/internal/enrich"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/inhibit"
 	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/logger"
+	"github.com/jscyril/golang_music_player/internal/migrate"
 	"github.com/jscyril/golang_music_player/internal/playlist"
+	"github.com/jscyril/golang_music_player/internal/search"
+	"github.com/jscyril/golang_music_player/internal/status"
+	"github.com/jscyril/golang_music_player/internal/store"
+	"github.com/jscyril/golang_music_player/internal/streaming"
+	"github.com/jscyril/golang_music_player/internal/sysevents"
 	"github.com/jscyril/golang_music_player/internal/ui"
+	"github.com/jscyril/golang_music_player/internal/ui/components"
+	"github.com/jscyril/golang_music_player/internal/ui/keymap"
+	"github.com/jscyril/golang_music_player/internal/webhook"
+	"github.com/jscyril/golang_music_player/pkg/events"
 )
 
 func main() {
+	defer crash.Guard()
 	if err := run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
@@ -23,18 +49,96 @@ func main() {
 }
 
 func run() error {
+	profile := flag.String("profile", "", "Use a named profile with its own config, library and playlists")
+	noUI := flag.Bool("no-ui", false, "Play without the terminal UI until the queue ends, or while alarms are set")
+	playName := flag.String("play", "", "Start playing the playlist with this name or ID")
+	shuffle := flag.Bool("shuffle", false, "Shuffle the startup queue")
+	exportLib := flag.String("export-library", "", "Write the library with play counts to a .json or .csv file and exit")
+	importLib := flag.String("import-library", "", "Add the tracks of a .json or .csv library export and exit")
+	importFrom := flag.String("import-from", "", "Bring over play counts, ratings and playlists from another player, as `player:path`, and exit")
+	noColor := flag.Bool("no-color", false, "Draw the UI in ASCII without colors (also set by NO_COLOR)")
+	flag.Parse()
+
+	// Warnings show on the terminal until the UI takes it over
+	logger.SetConsole(os.Stderr)
+
 	// Load configuration
 	configPath := config.GetConfigPath()
 	cfg, err := config.LoadOrCreate(configPath)
-	if err != nil {
+	var invalid *config.ValidationError
+	if err != nil && !errors.As(err, &invalid) {
 		return fmt.Errorf("load config: %w", err)
 	}
+	cfgPath := configPath // the file settings changed from the UI are saved to
+	if *profile != "" {
+		if cfg, err = config.LoadProfile(configPath, cfg, *profile); err != nil {
+			return fmt.Errorf("load profile: %w", err)
+		}
+		cfgPath = config.ProfilePath(configPath, *profile)
+		// The profile's own settings are what count
+		invalid = nil
+		errors.As(cfg.Validate(), &invalid)
+	}
+	if invalid != nil {
+		for _, p := range invalid.Problems {
+			logger.Warn("config %s", p)
+		}
+		if invalid.Fatal() {
+			return fmt.Errorf("config %s has errors, fix them and start again", configPath)
+		}
+	}
+
+	switch flag.Arg(0) {
+	case "status":
+		return runStatus(cfg, *profile, "status", status.DefaultFormat, flag.Args()[1:])
+	case "now-playing":
+		return runStatus(cfg, *profile, "now-playing", status.NowPlayingFormat, flag.Args()[1:])
+	case "install-desktop-entry":
+		return runInstallDesktopEntry(*profile, flag.Args()[1:])
+	}
+
+	keys, err := keymap.FromConfig(cfg.KeyBindings)
+	if err != nil {
+		return fmt.Errorf("key bindings: %w", err)
+	}
 
 	// Create data directory
 	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
 		return fmt.Errorf("create data directory: %w", err)
 	}
 
+	// Log file, also holding the warnings printed so far
+	level, err := logger.ParseLevel(cfg.LogLevel)
+	if cfg.LogLevel == "" || err != nil {
+		level = logger.INFO
+	}
+	if err := logger.Init(cfg.DataDir, level); err != nil {
+		logger.Warn("%v", err)
+	}
+	defer logger.Close()
+	crash.SetDir(cfg.DataDir)
+
+	// UI language; catalogs in <data_dir>/locales add to or override the
+	// built-in ones
+	if err := i18n.Load(i18n.Detect(cfg.Language), filepath.Join(cfg.DataDir, "locales")); err != nil {
+		logger.Warn("language: %v", err)
+	}
+
+	// One instance owns the data directory; another one started alongside
+	// it (say a TUI next to a --no-ui player) gets a read-only view rather
+	// than overwriting the library and playlists from its stale copy
+	lock, err := store.LockDir(cfg.DataDir)
+	readOnly := errors.Is(err, store.ErrLocked)
+	switch {
+	case readOnly:
+		logger.Warn("%v; opening read-only, changes will not be saved", err)
+		store.SetReadOnly(true)
+	case err != nil:
+		logger.Warn("%v", err)
+	default:
+		defer lock.Unlock()
+	}
+
 	// Setup context with graceful shutdown
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -47,9 +151,114 @@ func run() error {
 		cancel()
 	}()
 
+	// Events from the engine, library and playlists go through one bus;
+	// the UI and other consumers subscribe to it independently
+	bus := events.NewEventBus()
+
 	// Initialize audio engine
 	audioEngine := audio.NewAudioEngine()
+	audioEngine.SetBus(bus)
+	for _, out := range cfg.OutputSinks {
+		name := out.Name
+		switch out.Type {
+		case "speaker":
+			name = audio.SpeakerSinkName
+		case "cast":
+			name = audio.CastSinkName
+			audioEngine.RegisterSink(audio.NewNullSink(name))
+		case "file":
+			audioEngine.RegisterSink(audio.NewFileSink(name, out.Path))
+		case "pipe":
+			audioEngine.RegisterSink(audio.NewPipeSink(name, out.Path))
+		default:
+			logger.Warn("unknown output sink type %q for %s", out.Type, out.Name)
+			continue
+		}
+		curve, err := audio.ParseVolumeCurve(out.VolumeCurve)
+		if err != nil {
+			logger.Warn("output %s: %v", out.Name, err)
+		}
+		trim := audio.SinkTrim{GainDB: out.TrimDB, Delay: time.Duration(out.DelayMS) * time.Millisecond, Curve: curve}
+		if !trim.IsZero() {
+			audioEngine.SetSinkTrim(name, trim)
+		}
+	}
+	if cfg.OutputSampleRate != 0 {
+		if err := audioEngine.SetSampleRate(cfg.OutputSampleRate); err != nil {
+			logger.Warn("%v", err)
+		}
+	}
+	audioEngine.SetLimiter(cfg.Limiter)
+	policy := audio.DefaultFailurePolicy()
+	policy.Skip = !cfg.PlaybackErrors.Stop
+	if n := cfg.PlaybackErrors.MaxSkips; n > 0 {
+		policy.MaxSkips = n
+	}
+	if n := cfg.PlaybackErrors.Retries; n != 0 {
+		policy.Retries = max(n, 0)
+	}
+	audioEngine.SetFailurePolicy(policy)
+	audioEngine.SetPreload(int64(cfg.PreloadMB) << 20)
+	if s := cfg.ReadAheadSeconds; s != 0 {
+		audioEngine.SetReadAhead(time.Duration(s * float64(time.Second)))
+	}
 	audioEngine.Start(ctx)
+	crash.AddState("playback", func() any { return audioEngine.Snapshot(nil) })
+
+	// Now-playing file for `player status`, removed again on exit. It
+	// belongs to the instance owning the data directory.
+	statusDone := make(chan struct{})
+	go func() {
+		defer close(statusDone)
+		if readOnly {
+			return
+		}
+		status.Publish(ctx, bus, status.Path(cfg.DataDir, *profile), func() *api.Snapshot {
+			return audioEngine.Snapshot(nil)
+		})
+	}()
+	defer func() {
+		cancel()
+		<-statusDone
+	}()
+
+	// Track in the terminal (or tmux pane) title, restored on exit
+	if info, err := os.Stdout.Stat(); cfg.TerminalTitle && err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		titleDone := make(chan struct{})
+		go func() {
+			defer close(titleDone)
+			status.ShowTitle(ctx, bus, os.Stdout, func() *api.Snapshot {
+				return audioEngine.Snapshot(nil)
+			})
+		}()
+		defer func() {
+			cancel()
+			<-titleDone
+		}()
+	}
+
+	// Hold off system sleep, and the screen lock if asked, while playing;
+	// released on pause/stop and exit
+	if cfg.InhibitSleep {
+		inhibitor := inhibit.New(cfg.InhibitScreenLock)
+		go inhibitor.Watch(ctx, time.Second, func() bool {
+			return audioEngine.GetState().Status == api.StatusPlaying
+		})
+		defer inhibitor.Release()
+	}
+
+	// External volume ducking (SIGUSR1 ducks, SIGUSR2 restores)
+	if cfg.DuckDB > 0 {
+		handleDuckSignals(ctx, audioEngine, cfg.DuckDB)
+	}
+
+	// System-wide play/pause, next and previous keys
+	handleHotkeys(ctx, audioEngine, cfg.GlobalHotkeys)
+
+	// Pause on resume from suspend or when an audio device goes away
+	if cfg.PauseOnSuspend || cfg.PauseOnUnplug {
+		go watchSystemEvents(ctx, audioEngine, cfg)
+	}
 
 	// Load persisted library (or create empty)
 	libraryPath := filepath.Join(cfg.DataDir, "library.json")
@@ -59,33 +268,417 @@ func run() error {
 	}
 	fmt.Printf("Loaded %d tracks from library\n", lib.TotalTracks)
 
-	// Scan only if library is empty and directories are configured
-	if lib.TotalTracks == 0 && len(cfg.MusicDirectories) > 0 {
+	// Genre hierarchy and tag mapping rules
+	taxonomy, err := library.LoadTaxonomy(filepath.Join(cfg.DataDir, "genres.json"))
+	if err != nil {
+		logger.Warn("load genre taxonomy: %v", err)
+		taxonomy = library.NewTaxonomy()
+	}
+	lib.SetTaxonomy(taxonomy)
+	lib.SetScanWorkers(cfg.ScanWorkers)
+	lib.SetPublisher(bus)
+	markBroken(ctx, bus, lib)
+
+	// Play history, used for skip detection
+	history, err := library.OpenHistory(filepath.Join(cfg.DataDir, "history.jsonl"))
+	if err != nil {
+		logger.Warn("load play history: %v", err)
+	} else {
+		history.SetSkipRule(float64(cfg.SkipPercent)/100, cfg.SkipCount)
+		lib.SetHistory(history)
+	}
+
+	if *exportLib != "" || *importLib != "" {
+		return exchangeLibrary(lib, libraryPath, *exportLib, *importLib)
+	}
+	if *importFrom != "" {
+		return importPlayer(cfg, lib, libraryPath, *importFrom)
+	}
+
+	// Scan only if library is empty and directories are configured. The
+	// UI scans in the background and shows its progress, unless the
+	// startup queue needs the tracks right away.
+	scanOnStart := lib.TotalTracks == 0 && len(cfg.MusicDirectories) > 0
+	if scanOnStart && (*noUI || *shuffle) {
 		fmt.Println("Library empty, scanning music directories...")
-		if err := lib.Scan(ctx, cfg.MusicDirectories); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: scan error: %v\n", err)
+		report, err := lib.Scan(ctx, cfg.MusicDirectories)
+		if err != nil {
+			logger.Warn("scan error: %v", err)
+		}
+		for _, e := range report.Errors {
+			logger.Warn("%v", e)
 		}
-		fmt.Printf("Found %d tracks\n", lib.TotalTracks)
+		fmt.Println(report.Summary())
+		scanOnStart = false
 	}
 
 	// Save library on exit
 	defer func() {
+		if readOnly {
+			return
+		}
 		if err := lib.Save(libraryPath); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: save library: %v\n", err)
+			logger.Warn("save library: %v", err)
 		}
 	}()
 
+	// Share the library with other devices on the network
+	if cfg.MediaServer.Enabled {
+		ms, err := dlna.NewMediaServer(lib, cfg.MediaServer.Name, cfg.MediaServer.Port)
+		if err != nil {
+			logger.Warn("%v", err)
+		} else {
+			defer ms.Close()
+		}
+	}
+
+	// Serve the library to other players over the REST API
+	if cfg.APIServer.Enabled {
+		srv, err := apiserver.NewServer(lib, apiserver.Options{
+			Port:      cfg.APIServer.Port,
+			Token:     cfg.APIServer.Token,
+			Transcode: cfg.APIServer.Transcode,
+			Bitrate:   cfg.APIServer.Bitrate,
+			Events:    bus,
+			Ducker:    audioEngine,
+			DuckDB:    cfg.DuckDB,
+		})
+		if err != nil {
+			logger.Warn("%v", err)
+		} else {
+			defer srv.Close()
+		}
+	}
+
 	// Initialize playlist manager
 	playlistPath := filepath.Join(cfg.DataDir, "playlists")
 	plManager := playlist.NewManager(playlistPath)
+	plManager.SetResolver(lib)
+	plManager.SetPublisher(bus)
+	var saveRules func(map[string]bool) error
+	if !readOnly {
+		saveRules = func(rules map[string]bool) error {
+			cfg.PlaylistDuplicates.Playlists = rules
+			return config.SaveConfig(cfg, cfgPath)
+		}
+	}
+	plManager.SetDuplicatePolicy(cfg.PlaylistDuplicates.Prevent, cfg.PlaylistDuplicates.Playlists, saveRules)
 	if err := plManager.LoadAll(); err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: load playlists: %v\n", err)
+		logger.Warn("load playlists: %v", err)
+	}
+
+	// Search the local library and playlists plus any configured remote servers
+	searcher := search.NewFederated(search.NewLibrarySource(lib))
+	searcher.Register(search.NewPlaylistSource(plManager), 0)
+	for _, rs := range cfg.RemoteSources {
+		name := rs.Name
+		if name == "" {
+			name = rs.URL
+		}
+		timeout := time.Duration(rs.TimeoutMS) * time.Millisecond
+		switch rs.Type {
+		case "", "gtmpc":
+			src := search.NewRemoteSource(name, rs.URL, rs.Token)
+			src.SetStreamBitrate(rs.Bitrate)
+			searcher.Add(src, timeout)
+		case "subsonic":
+			src := search.NewSubsonicSource(name, rs.URL, rs.User, rs.Password)
+			src.SetStreamBitrate(rs.Bitrate)
+			searcher.Add(src, timeout)
+		default:
+			logger.Warn("remote source %s: unknown type %q", name, rs.Type)
+		}
+	}
+
+	// Outbound webhooks for track and queue changes
+	var hooks *webhook.Dispatcher
+	if len(cfg.Webhooks) > 0 {
+		var list []*webhook.Hook
+		for _, wh := range cfg.Webhooks {
+			h, err := webhook.NewHook(wh.URL, wh.Events, wh.Template, wh.ContentType)
+			if err != nil {
+				logger.Warn("%v", err)
+				continue
+			}
+			list = append(list, h)
+		}
+		hooks = webhook.NewDispatcher(list)
+		defer hooks.Close()
+
+		// Fired from the bus so the UI and --no-ui post the same events
+		hooksDone := make(chan struct{})
+		go func() {
+			defer close(hooksDone)
+			hooks.Follow(ctx, bus, func() *api.Snapshot {
+				q, _ := audioEngine.Queue().(api.QueueState)
+				return audioEngine.Snapshot(q)
+			})
+		}()
+		defer func() {
+			cancel()
+			<-hooksDone
+		}()
+	}
+
+	// Audiobook resume positions
+	minBook := time.Duration(cfg.AudiobookMinMinutes) * time.Minute
+	books, err := audiobook.Load(filepath.Join(cfg.DataDir, "resume.json"), minBook)
+	if err != nil {
+		logger.Warn("load resume positions: %v", err)
+		books = nil // resume disabled rather than overwriting the unreadable file
+	}
+	defer func() {
+		if err := books.Save(); err != nil {
+			logger.Warn("save resume positions: %v", err)
+		}
+	}()
+
+	// Bookmarks in tracks
+	marks, err := audiobook.LoadBookmarks(filepath.Join(cfg.DataDir, "bookmarks.json"))
+	if err != nil {
+		logger.Warn("load bookmarks: %v", err)
+		marks = nil // bookmarks disabled rather than overwriting the unreadable file
+	}
+
+	// Album-art accent colors are tuned for the dark theme
+	var accents *artwork.Cache
+	if cfg.DynamicAccent && (cfg.Theme == "" || cfg.Theme == "dark") {
+		accents = artwork.NewCache(library.NewMetadataReader().ReadCoverArt)
+	}
+
+	// Startup queue from files given as arguments or --play (or the whole
+	// library for --shuffle / --no-ui)
+	files := flag.Args()
+	var start []*api.Track
+	if len(files) > 0 {
+		if *playName != "" {
+			return fmt.Errorf("--play cannot be combined with files to play")
+		}
+		if start = openPaths(lib, files); len(start) == 0 {
+			return fmt.Errorf("nothing to play in %s", strings.Join(files, ", "))
+		}
+	} else if *playName != "" {
+		pl, err := findPlaylist(plManager, *playName)
+		if err != nil {
+			return err
+		}
+		for i := range pl.Tracks {
+			start = append(start, &pl.Tracks[i])
+		}
+		if len(start) == 0 {
+			return fmt.Errorf("playlist %q is empty", pl.Name)
+		}
+	} else if *shuffle || *noUI {
+		start = lib.GetAllTracks()
+	}
+
+	var queue *playlist.Queue
+	if len(start) > 0 {
+		queue = playlist.NewQueue()
+		queue.SetShuffleExclude(lib.ShuffleExcluded)
+		queue.SetPublisher(bus)
+		startQueue(queue, start, *shuffle)
+	}
+	sched, alarmIndex := loadSchedule(cfg.Schedule)
+	if *noUI {
+		if queue == nil {
+			if sched == nil || !sched.Waiting() {
+				return fmt.Errorf("nothing to play")
+			}
+			queue = playlist.NewQueue()
+			queue.SetShuffleExclude(lib.ShuffleExcluded)
+			queue.SetPublisher(bus)
+		}
+		return runHeadless(ctx, audioEngine, lib, plManager, queue, sched)
 	}
 
 	// Run UI
-	if err := ui.Run(audioEngine, lib, plManager); err != nil {
+	opts := ui.Options{Searcher: searcher, Books: books, Marks: marks, Accents: accents, Keys: keys}
+	opts.Queue = queue
+	if !readOnly {
+		opts.LibraryPath = libraryPath
+	}
+	opts.ReadOnly = readOnly
+	opts.ScanPaths = slices.Clone(cfg.MusicDirectories)
+	opts.AddMusicDir = func(dir string) error {
+		cfg.MusicDirectories = append(cfg.MusicDirectories, dir)
+		return config.SaveConfig(cfg, cfgPath)
+	}
+	opts.Bookmarks = cfg.FileBrowser.Bookmarks
+	opts.SaveBookmarks = func(bookmarks []string) error {
+		cfg.FileBrowser.Bookmarks = bookmarks
+		return config.SaveConfig(cfg, cfgPath)
+	}
+	opts.SkipConfirm = cfg.SkipConfirm
+	opts.SaveSkipConfirm = func(actions []string) error {
+		cfg.SkipConfirm = actions
+		return config.SaveConfig(cfg, cfgPath)
+	}
+	opts.Schedule = sched
+	opts.SaveAlarm = func(i int, disabled bool) error {
+		cfg.Schedule.Alarms[alarmIndex[i]].Disabled = disabled
+		return config.SaveConfig(cfg, cfgPath)
+	}
+	columns := func(view string, names []string) []components.Column {
+		cols, err := components.ParseColumns(names)
+		if err != nil {
+			logger.Warn("config track_columns.%s: %v", view, err)
+		}
+		return cols
+	}
+	opts.LibraryColumns = columns("library", cfg.TrackColumns.Library)
+	opts.QueueColumns = columns("queue", cfg.TrackColumns.Queue)
+	opts.PlaylistColumns = columns("playlist", cfg.TrackColumns.Playlist)
+	opts.SplitPane = cfg.Layout.SplitPane
+	opts.SplitPercent = cfg.Layout.SplitPercent
+	opts.ScanOnStart = scanOnStart
+	if len(files) == 0 {
+		// Files opened from the command line make a one-off queue, so the
+		// saved one is kept for next time
+		opts.QueueFile = filepath.Join(cfg.DataDir, "queue.json")
+	}
+	opts.Queues = playlist.NewQueueStore(filepath.Join(cfg.DataDir, "queues"))
+	opts.Bus = bus
+	opts.Crossfade = time.Duration(cfg.CrossfadeSeconds * float64(time.Second))
+	opts.UpNext = time.Duration(cfg.UpNext.Seconds) * time.Second
+	opts.UpNextNotify = cfg.UpNext.Notify
+	opts.CastPort = cfg.CastPort
+	opts.Accessible = cfg.Accessible
+	opts.NoColor = *noColor || os.Getenv("NO_COLOR") != ""
+	if cfg.MetadataLookup.Enabled {
+		opts.Enricher = enrich.NewClient(cfg.MetadataLookup.AcoustIDKey)
+	}
+	if sp := cfg.Streaming.Spotify; sp.ClientID != "" && sp.RefreshToken != "" {
+		opts.Streaming = append(opts.Streaming, streaming.NewSpotify(sp.ClientID, sp.ClientSecret, sp.RefreshToken))
+	}
+	if am := cfg.Streaming.AppleMusic; am.DeveloperToken != "" && am.UserToken != "" {
+		opts.Streaming = append(opts.Streaming, streaming.NewAppleMusic(am.DeveloperToken, am.UserToken, am.Storefront))
+	}
+	if opts.ErrorAlert, err = ui.ParseAlert(cfg.Alerts.Error); err != nil {
+		return fmt.Errorf("alerts.error: %w", err)
+	}
+	if opts.TrackAlert, err = ui.ParseAlert(cfg.Alerts.TrackChange); err != nil {
+		return fmt.Errorf("alerts.track_change: %w", err)
+	}
+	// Stray writes would garble the UI; its log viewer (L) shows warnings
+	logger.SetConsole(nil)
+	err = ui.Run(audioEngine, lib, plManager, opts)
+	logger.SetConsole(os.Stderr)
+	if err != nil {
 		return fmt.Errorf("run ui: %w", err)
 	}
 
 	return nil
 }
+
+// exchangeLibrary imports and/or exports the library for the
+// --import-library and --export-library flags, importing first
+func exchangeLibrary(lib *library.Library, libraryPath, exportPath, importPath string) error {
+	if importPath != "" {
+		added, skipped, err := lib.Import(importPath)
+		if err != nil {
+			return err
+		}
+		if err := lib.Save(libraryPath); err != nil {
+			return fmt.Errorf("save library: %w", err)
+		}
+		fmt.Printf("Imported %d tracks (%d missing files skipped)\n", added, skipped)
+	}
+	if exportPath != "" {
+		var err error
+		switch strings.ToLower(filepath.Ext(exportPath)) {
+		case ".json":
+			err = lib.ExportJSON(exportPath)
+		case ".csv":
+			err = lib.ExportCSV(exportPath)
+		default:
+			return fmt.Errorf("export library: unsupported format %q", filepath.Ext(exportPath))
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Exported %d tracks to %s\n", lib.TotalTracks, exportPath)
+	}
+	return nil
+}
+
+// importPlayer brings over the listening data of another player for the
+// --import-from flag, whose value names the player and its data file
+func importPlayer(cfg *config.Config, lib *library.Library, libraryPath, from string) error {
+	source, path, ok := strings.Cut(from, ":")
+	if !ok || path == "" {
+		return fmt.Errorf("--import-from wants player:path, with player one of %s", strings.Join(migrate.Sources, ", "))
+	}
+	data, err := migrate.Read(source, path)
+	if err != nil {
+		return err
+	}
+	pm := playlist.NewManager(filepath.Join(cfg.DataDir, "playlists"))
+	pm.SetResolver(lib)
+	pm.SetDuplicatePolicy(cfg.PlaylistDuplicates.Prevent, cfg.PlaylistDuplicates.Playlists, nil)
+	if err := pm.LoadAll(); err != nil {
+		logger.Warn("load playlists: %v", err)
+	}
+	report, err := migrate.Apply(data, lib, pm, cfg.MusicDirectories)
+	if saveErr := lib.Save(libraryPath); saveErr != nil && err == nil {
+		err = fmt.Errorf("save library: %w", saveErr)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d tracks from %s: %d play counts, %d ratings, %d playlists (%d missing files skipped)\n",
+		report.Tracks, source, report.Plays, report.Ratings, report.Playlists, report.Missing)
+	return nil
+}
+
+// findPlaylist returns the playlist with the given ID, or else the one whose
+// name matches case-insensitively
+func findPlaylist(pm *playlist.Manager, nameOrID string) (*api.Playlist, error) {
+	if pl, err := pm.GetByID(nameOrID); err == nil {
+		return pl, nil
+	}
+	for _, pl := range pm.GetAll() {
+		if strings.EqualFold(pl.Name, nameOrID) {
+			return pl, nil
+		}
+	}
+	return nil, fmt.Errorf("no playlist named %q", nameOrID)
+}
+
+// startQueue fills queue with tracks, shuffled from a random first track
+// when shuffle is set
+func startQueue(queue *playlist.Queue, tracks []*api.Track, shuffle bool) {
+	if shuffle {
+		queue.SetShuffled(tracks)
+	} else {
+		queue.Set(tracks)
+	}
+}
+
+// watchSystemEvents pauses playback on suspend/unplug as configured, and
+// resumes it when the unplugged device returns if ResumeOnReplug is set
+func watchSystemEvents(ctx context.Context, engine *audio.AudioEngine, cfg *config.Config) {
+	unpluggedFrom := "" // device whose removal paused playback
+	for ev := range sysevents.NewWatcher().Run(ctx) {
+		playing := engine.GetState().Status == api.StatusPlaying
+		switch ev.Kind {
+		case sysevents.Resumed:
+			if cfg.PauseOnSuspend && playing {
+				logger.Info("Resumed from suspend, pausing playback")
+				engine.Pause()
+			}
+		case sysevents.DeviceRemoved:
+			if cfg.PauseOnUnplug && playing {
+				logger.Info("Audio device removed (%s), pausing playback", ev.Device)
+				engine.Pause()
+				unpluggedFrom = ev.Device
+			}
+		case sysevents.DeviceAdded:
+			if cfg.ResumeOnReplug && ev.Device == unpluggedFrom && engine.GetState().Status == api.StatusPaused {
+				logger.Info("Audio device returned (%s), resuming playback", ev.Device)
+				engine.Resume()
+			}
+			unpluggedFrom = ""
+		}
+	}
+}
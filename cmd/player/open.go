@@ -0,0 +1,50 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/audio"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/logger"
+)
+
+// openPaths returns the tracks for the files and directories given on the
+// command line, in order, directories walked in name order. Files outside
+// the library are read from disk but not added to it. Paths that cannot be
+// played are reported and skipped.
+func openPaths(lib *library.Library, paths []string) []*api.Track {
+	var tracks []*api.Track
+	add := func(path string) {
+		t, err := lib.LookupPath(path)
+		if err != nil {
+			logger.Warn("%s: %v", path, err)
+			return
+		}
+		tracks = append(tracks, t)
+	}
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			logger.Warn("%v", err)
+			continue
+		}
+		if !info.IsDir() {
+			add(p)
+			continue
+		}
+		filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				logger.Warn("%v", err)
+				return nil
+			}
+			if !d.IsDir() && audio.IsSupported(path) {
+				add(path)
+			}
+			return nil
+		})
+	}
+	return tracks
+}
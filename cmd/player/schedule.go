@@ -0,0 +1,78 @@
+package main
+
+import (
+	"time"
+
+	"github.com/jscyril/golang_music_player/internal/config"
+	"github.com/jscyril/golang_music_player/internal/logger"
+	"github.com/jscyril/golang_music_player/internal/schedule"
+)
+
+// defaultRamp is how long an alarm fades in when ramp_seconds is unset
+const defaultRamp = 60 * time.Second
+
+// loadSchedule reads the configured alarms and quiet hours, leaving out
+// invalid ones with a warning. index maps each alarm of the scheduler to
+// its entry in cfg.Alarms.
+func loadSchedule(cfg config.Schedule) (s *schedule.Scheduler, index []int) {
+	var alarms []schedule.Alarm
+	for i, c := range cfg.Alarms {
+		at, err := schedule.ParseClock(c.Time)
+		if err != nil {
+			logger.Warn("schedule.alarms[%d]: %v", i, err)
+			continue
+		}
+		days, err := schedule.ParseDays(c.Days)
+		if err != nil {
+			logger.Warn("schedule.alarms[%d]: %v", i, err)
+			continue
+		}
+		if c.Playlist == "" {
+			logger.Warn("schedule.alarms[%d]: no playlist", i)
+			continue
+		}
+		ramp := time.Duration(c.RampSeconds) * time.Second
+		switch {
+		case c.RampSeconds == 0:
+			ramp = defaultRamp
+		case c.RampSeconds < 0:
+			ramp = 0
+		}
+		alarms = append(alarms, schedule.Alarm{
+			Name:     c.Name,
+			At:       at,
+			Days:     days,
+			Playlist: c.Playlist,
+			Shuffle:  c.Shuffle,
+			Volume:   min(max(c.Volume, 0), 1),
+			Ramp:     ramp,
+			Disabled: c.Disabled,
+		})
+		index = append(index, i)
+	}
+
+	var quiet []schedule.Quiet
+	for i, c := range cfg.QuietHours {
+		start, err := schedule.ParseClock(c.Start)
+		if err != nil {
+			logger.Warn("schedule.quiet_hours[%d]: start: %v", i, err)
+			continue
+		}
+		end, err := schedule.ParseClock(c.End)
+		if err != nil {
+			logger.Warn("schedule.quiet_hours[%d]: end: %v", i, err)
+			continue
+		}
+		days, err := schedule.ParseDays(c.Days)
+		if err != nil {
+			logger.Warn("schedule.quiet_hours[%d]: %v", i, err)
+			continue
+		}
+		if start == end {
+			logger.Warn("schedule.quiet_hours[%d]: starts and ends at %s", i, start)
+			continue
+		}
+		quiet = append(quiet, schedule.Quiet{Start: start, End: end, Days: days})
+	}
+	return schedule.New(alarms, quiet), index
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/jscyril/golang_music_player/internal/config"
+	"github.com/jscyril/golang_music_player/internal/status"
+)
+
+// runStatus prints what the running player is playing, for scripts and
+// status bars, as the command name does by default. Nothing is printed
+// when no player is running or it is stopped, unless --json is given.
+func runStatus(cfg *config.Config, profile, name, defaultFormat string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	format := fs.String("format", defaultFormat, "Output template with {artist}, {title}, {album}, {status}, {icon}, {position}, {duration}, {volume} and {output}")
+	asJSON := fs.Bool("json", false, "Print the state as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	snap, err := status.Read(status.Path(cfg.DataDir, profile))
+	if err != nil {
+		return err
+	}
+	info := status.InfoAt(snap, time.Now())
+	if *asJSON {
+		data, err := json.Marshal(info)
+		if err != nil {
+			return fmt.Errorf("encode status: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	if line := status.Format(*format, info); line != "" {
+		fmt.Println(line)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Package apiserver serves the part of the gtmpc REST API that
+// pkg/apiclient speaks: the health check, the track listing and
+// /api/stream, so another player can list this library as a remote source
+// and listen to it, transcoded for slow links if asked. Remote clients can
+// also follow playback on /api/events and duck it on /api/duck.
+package apiserver
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/pkg/apiclient"
+	"github.com/jscyril/golang_music_player/pkg/events"
+)
+
+// DefaultPort is the port served on when none is configured, the one
+// clients assume
+const DefaultPort = 8080
+
+// Options configures a Server
+type Options struct {
+	Port  int    // DefaultPort if 0
+	Token string // bearer token requests must carry; "" lets anyone in
+	// Transcode is the format streams are sent in when a request does not
+	// ask for one: "" for the file as it is, "mp3" or "opus"
+	Transcode string
+	Bitrate   int // kbit/s of transcoded streams, DefaultBitrate if 0
+
+	Events        *events.EventBus // streamed on /api/events; nil leaves it out
+	EventInterval time.Duration    // DefaultEventInterval if 0
+
+	Ducker Ducker  // ducked on /api/duck; nil leaves it out
+	DuckDB float64 // decibels ducked by when a request names none
+}
+
+// Server serves the library over HTTP
+type Server struct {
+	lib  *library.Library
+	opts Options
+	mux  *http.ServeMux
+	srv  *http.Server
+}
+
+// NewServer serves lib on every interface
+func NewServer(lib *library.Library, opts Options) (*Server, error) {
+	if opts.Port == 0 {
+		opts.Port = DefaultPort
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(opts.Port)))
+	if err != nil {
+		return nil, fmt.Errorf("start api server: %w", err)
+	}
+	s := newServer(lib, opts)
+	s.srv = &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	go s.srv.Serve(ln)
+	return s, nil
+}
+
+// newServer sets up the routes without listening
+func newServer(lib *library.Library, opts Options) *Server {
+	if opts.EventInterval == 0 {
+		opts.EventInterval = DefaultEventInterval
+	}
+	s := &Server{lib: lib, opts: opts, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /api/health", s.health)
+	s.mux.HandleFunc("GET /api/library/tracks", s.tracks)
+	s.mux.HandleFunc("GET /api/stream/{id}", s.stream)
+	if opts.Events != nil {
+		s.mux.HandleFunc("GET /api/events", s.events)
+	}
+	if opts.Ducker != nil {
+		s.mux.HandleFunc("POST /api/duck", s.duck)
+		s.mux.HandleFunc("DELETE /api/duck", s.unduck)
+	}
+	return s
+}
+
+// Close stops the server
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+// ServeHTTP checks the token, if one is set, and routes the request. The
+// health check is open so clients can tell a wrong token from a wrong
+// address.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Token != "" && r.URL.Path != "/api/health" {
+		got, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(s.opts.Token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "invalid token")
+			return
+		}
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, apiclient.HealthResponse{Status: "ok", Version: "gtmpc"})
+}
+
+func (s *Server) tracks(w http.ResponseWriter, r *http.Request) {
+	all := s.lib.GetAllTracks()
+	resp := apiclient.TrackListResponse{Tracks: make([]apiclient.Track, len(all))}
+	for i, t := range all {
+		resp.Tracks[i] = apiclient.Track{
+			ID:              t.ID,
+			Title:           t.Title,
+			Artist:          t.Artist,
+			Album:           t.Album,
+			DurationSeconds: int(t.Duration / time.Second),
+			Format:          strings.TrimPrefix(strings.ToLower(filepath.Ext(t.FilePath)), "."),
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// writeJSON writes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an apiclient.APIError
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, apiclient.APIError{Error: msg})
+}
@@ -0,0 +1,61 @@
+package apiserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/pkg/apiclient"
+)
+
+// TestStream verifies the token is required and a track's file is served
+// by range, as the player's HTTP streamer seeks
+func TestStream(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song.flac")
+	os.WriteFile(path, []byte("fLaC-data"), 0644)
+	lib := library.NewLibrary()
+	lib.AddTrack(&api.Track{ID: "t1", Title: "Song", FilePath: path})
+	srv := httptest.NewServer(newServer(lib, Options{Token: "secret"}))
+	defer srv.Close()
+
+	client := apiclient.NewAPIClient(srv.URL)
+	if _, err := client.GetTracks(); err == nil {
+		t.Error("listing without the token succeeded")
+	}
+	client.SetToken("secret")
+	list, err := client.GetTracks()
+	if err != nil || len(list.Tracks) != 1 || list.Tracks[0].Format != "flac" {
+		t.Fatalf("tracks = %+v, %v", list, err)
+	}
+
+	req, _ := client.StreamRequest(client.StreamURL("t1"))
+	req.Header.Set("Range", "bytes=5-")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent || string(body) != "data" {
+		t.Errorf("range = %d %q", resp.StatusCode, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "audio/flac" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	client.StreamFormat = "wma"
+	req, _ = client.StreamRequest(client.StreamURL("t1"))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown format answered %d", resp.StatusCode)
+	}
+}
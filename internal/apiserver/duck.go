@@ -0,0 +1,49 @@
+package apiserver
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/jscyril/golang_music_player/internal/logger"
+)
+
+// Ducker lowers the volume for a while and restores it, as
+// audio.AudioEngine does
+type Ducker interface {
+	Duck(db float64) error
+	Unduck() error
+}
+
+// duck lowers the volume by the "db" query parameter, or the server's
+// DuckDB, until DELETE /api/duck, so a notification daemon or a phone on
+// another machine can talk over the music
+func (s *Server) duck(w http.ResponseWriter, r *http.Request) {
+	db := s.opts.DuckDB
+	if v := r.URL.Query().Get("db"); v != "" {
+		var err error
+		if db, err = strconv.ParseFloat(v, 64); err != nil {
+			db = 0
+		}
+	}
+	if !(db > 0) || math.IsInf(db, 0) {
+		writeError(w, http.StatusBadRequest, "db must be a positive number of decibels")
+		return
+	}
+	if err := s.opts.Ducker.Duck(db); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	logger.Info("Duck requested over the API by %s", r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// unduck restores the volume lowered by duck
+func (s *Server) unduck(w http.ResponseWriter, r *http.Request) {
+	if err := s.opts.Ducker.Unduck(); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	logger.Info("Unduck requested over the API by %s", r.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,52 @@
+package apiserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jscyril/golang_music_player/internal/library"
+)
+
+// fakeDucker records the attenuation it was ducked by, 0 when restored
+type fakeDucker struct{ db float64 }
+
+func (d *fakeDucker) Duck(db float64) error { d.db = db; return nil }
+func (d *fakeDucker) Unduck() error         { d.db = 0; return nil }
+
+// TestDuck verifies the volume is ducked by the default or the requested
+// amount, bad amounts are refused and DELETE restores it
+func TestDuck(t *testing.T) {
+	d := &fakeDucker{}
+	srv := httptest.NewServer(newServer(library.NewLibrary(), Options{Ducker: d, DuckDB: 12}))
+	defer srv.Close()
+
+	do := func(method, query string) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+"/api/duck"+query, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := do(http.MethodPost, ""); code != http.StatusNoContent || d.db != 12 {
+		t.Errorf("default duck = %d, %v dB", code, d.db)
+	}
+	if code := do(http.MethodPost, "?db=20"); code != http.StatusNoContent || d.db != 20 {
+		t.Errorf("duck by 20 = %d, %v dB", code, d.db)
+	}
+	for _, q := range []string{"?db=-3", "?db=loud", "?db=NaN"} {
+		if code := do(http.MethodPost, q); code != http.StatusBadRequest || d.db != 20 {
+			t.Errorf("duck %s = %d, %v dB", q, code, d.db)
+		}
+	}
+	if code := do(http.MethodDelete, ""); code != http.StatusNoContent || d.db != 0 {
+		t.Errorf("unduck = %d, %v dB", code, d.db)
+	}
+	if code := do(http.MethodGet, ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET answered %d", code)
+	}
+}
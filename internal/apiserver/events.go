@@ -0,0 +1,96 @@
+package apiserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// DefaultEventInterval is how often /api/events sends position, state and
+// scan updates when none is configured
+const DefaultEventInterval = time.Second
+
+// eventNames are the SSE event names of the player events /api/events sends
+var eventNames = map[api.EventType]string{
+	api.EventTrackStarted:    "track_started",
+	api.EventTrackEnded:      "track_ended",
+	api.EventPositionUpdate:  "position",
+	api.EventError:           "error",
+	api.EventStateChange:     "state",
+	api.EventLibraryChanged:  "library_changed",
+	api.EventScanProgress:    "scan_progress",
+	api.EventPlaylistChanged: "playlist_changed",
+	api.EventTrackFailed:     "track_failed",
+	api.EventBuffering:       "buffering",
+	api.EventQueueChanged:    "queue_changed",
+}
+
+// trackFailure is api.TrackFailure with the error as text
+type trackFailure struct {
+	Track   *api.Track `json:"track"`
+	Error   string     `json:"error"`
+	Skipped bool       `json:"skipped"`
+}
+
+// events streams player events as server-sent events. Each client gets
+// its own throttled subscription: position, state and scan updates reach
+// it at most once per interval, only the newest of each, and everything
+// waiting is written before one flush, so remote clients cost a wakeup
+// and a packet per interval instead of one per update.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	ch, stop := s.opts.Events.SubscribeThrottled(s.opts.EventInterval)
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			writeEvent(w, ev)
+			for len(ch) > 0 {
+				writeEvent(w, <-ch)
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes ev as a server-sent event named after its type, with
+// its payload as JSON data
+func writeEvent(w http.ResponseWriter, ev api.AudioEvent) {
+	name, ok := eventNames[ev.Type]
+	if !ok {
+		return
+	}
+	payload := ev.Payload
+	switch p := payload.(type) {
+	case error:
+		payload = p.Error()
+	case api.TrackFailure:
+		f := trackFailure{Track: p.Track, Skipped: p.Skipped}
+		if p.Err != nil {
+			f.Error = p.Err.Error()
+		}
+		payload = f
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+}
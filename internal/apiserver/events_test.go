@@ -0,0 +1,58 @@
+package apiserver
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/pkg/events"
+)
+
+// TestEvents verifies position updates reach a client coalesced to the
+// newest, ahead of the track event that followed them
+func TestEvents(t *testing.T) {
+	bus := events.NewEventBus()
+	srv := httptest.NewServer(newServer(library.NewLibrary(), Options{Events: bus, EventInterval: time.Hour}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+
+	for i := 1; i <= 5; i++ {
+		bus.Publish(api.AudioEvent{Type: api.EventPositionUpdate, Payload: api.ProgressPayload{Position: time.Duration(i) * time.Second}})
+	}
+	bus.Publish(api.AudioEvent{Type: api.EventTrackStarted, Payload: &api.Track{ID: "t1"}})
+
+	var got []string
+	lines := bufio.NewScanner(resp.Body)
+	for len(got) < 4 && lines.Scan() {
+		if line := lines.Text(); line != "" {
+			got = append(got, line)
+		}
+	}
+	want := []string{
+		"event: position",
+		`"position":5000000000`,
+		"event: track_started",
+		`"id":"t1"`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("stream = %q", got)
+	}
+	for i := range want {
+		if !strings.Contains(got[i], want[i]) {
+			t.Errorf("line %d = %q, want it to contain %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package apiserver
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/logger"
+	"github.com/jscyril/golang_music_player/internal/renderer"
+)
+
+// Transcoded stream bitrates, in kbit/s
+const (
+	DefaultBitrate = 128
+	minBitrate     = 32
+	maxBitrate     = 320
+)
+
+// encoders maps the formats streams can be transcoded to onto their
+// ffmpeg encoder, container and content type
+var encoders = map[string]struct{ codec, container, contentType string }{
+	"mp3":  {"libmp3lame", "mp3", "audio/mpeg"},
+	"opus": {"libopus", "ogg", "audio/ogg; codecs=opus"},
+}
+
+// stream sends a track's file, with range requests for seeking, or
+// transcodes it as asked by the "format" ("original", "mp3" or "opus")
+// and "bitrate" (kbit/s) query parameters, falling back to the server's
+// options
+func (s *Server) stream(w http.ResponseWriter, r *http.Request) {
+	t, err := s.lib.GetTrack(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "track not found")
+		return
+	}
+	format, bitrate, err := s.streamFormat(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if format == "" || alreadyFits(t, format, bitrate) {
+		serveFile(w, r, t)
+		return
+	}
+	if err := transcode(w, r, t.FilePath, format, bitrate); err != nil {
+		logger.Warn("transcode %s: %v", t.FilePath, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// streamFormat returns the format, "" for the original file, and bitrate
+// a stream is asked for
+func (s *Server) streamFormat(q url.Values) (string, int, error) {
+	format := s.opts.Transcode
+	if f := q.Get("format"); f != "" {
+		format = strings.ToLower(f)
+	}
+	if format == "original" {
+		format = ""
+	}
+	if _, ok := encoders[format]; format != "" && !ok {
+		return "", 0, fmt.Errorf("unknown format %q", format)
+	}
+	bitrate := s.opts.Bitrate
+	if b := q.Get("bitrate"); b != "" {
+		n, err := strconv.Atoi(b)
+		if err != nil {
+			return "", 0, fmt.Errorf("bad bitrate %q", b)
+		}
+		bitrate = n
+	}
+	if bitrate == 0 {
+		bitrate = DefaultBitrate
+	}
+	return format, min(max(bitrate, minBitrate), maxBitrate), nil
+}
+
+// alreadyFits reports whether t is already an MP3 no larger than the
+// bitrate asked for, so transcoding would only lose quality
+func alreadyFits(t *api.Track, format string, bitrate int) bool {
+	return format == "mp3" && t.Codec == "MP3" && t.Bitrate > 0 && t.Bitrate <= bitrate
+}
+
+// serveFile sends the track's file as it is
+func serveFile(w http.ResponseWriter, r *http.Request, t *api.Track) {
+	f, err := os.Open(t.FilePath)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "file unavailable")
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		writeError(w, http.StatusNotFound, "file unavailable")
+		return
+	}
+	w.Header().Set("Content-Type", renderer.ContentType(t.FilePath))
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
+
+// transcode streams the file at path through ffmpeg as format at bitrate
+// kbit/s. The output's length is unknown, so transcoded streams cannot be
+// fetched by range. ffmpeg stops when the client goes away.
+func transcode(w http.ResponseWriter, r *http.Request, path, format string, bitrate int) error {
+	bin, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return errors.New("transcoding needs ffmpeg")
+	}
+	enc := encoders[format]
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Type", enc.contentType)
+		w.Header().Set("Accept-Ranges", "none")
+		return nil
+	}
+	cmd := exec.CommandContext(r.Context(), bin, "-nostdin", "-v", "error", "-i", path,
+		"-map", "0:a:0", "-vn", "-c:a", enc.codec, "-b:a", strconv.Itoa(bitrate)+"k", "-f", enc.container, "pipe:1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start ffmpeg: %w", err)
+	}
+
+	// Wait for the first output before answering, so a file ffmpeg cannot
+	// read is an error rather than an empty stream
+	buf := make([]byte, 32<<10)
+	n, _ := io.ReadFull(out, buf)
+	if n == 0 {
+		cmd.Wait()
+		return fmt.Errorf("ffmpeg: %s", strings.TrimSpace(stderr.String()))
+	}
+	w.Header().Set("Content-Type", enc.contentType)
+	w.Header().Set("Accept-Ranges", "none")
+	w.Write(buf[:n])
+	io.Copy(w, out)
+	cmd.Wait()
+	return nil
+}
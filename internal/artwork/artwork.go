@@ -0,0 +1,215 @@
+// Package artwork derives accent colors from album art
+package artwork
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for image.Decode
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrNoColor is returned when an image has no usable (non-gray,
+// non-extreme) pixels to pick an accent from
+var ErrNoColor = errors.New("no dominant color")
+
+// sampleSide is roughly how many pixels per axis are sampled
+const sampleSide = 96
+
+// sidecarNames are cover images looked for next to a track without
+// embedded art
+var sidecarNames = []string{"cover.jpg", "cover.png", "folder.jpg", "folder.png", "front.jpg", "front.png"}
+
+// Sidecar returns the path of the first cover image found in dir, or ""
+func Sidecar(dir string) string {
+	for _, name := range sidecarNames {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Dominant returns the most prominent color of an encoded image as a
+// "#rrggbb" string, adjusted to stay readable on a dark background.
+// Saturated colors are weighted above grays so that a small vivid area
+// wins over a large dull one.
+func Dominant(data []byte) (string, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("decode image: %w", err)
+	}
+
+	type bucket struct {
+		weight, r, g, b float64
+	}
+	buckets := make(map[int]*bucket)
+
+	bounds := img.Bounds()
+	stepX := max(bounds.Dx()/sampleSide, 1)
+	stepY := max(bounds.Dy()/sampleSide, 1)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += stepY {
+		for x := bounds.Min.X; x < bounds.Max.X; x += stepX {
+			r16, g16, b16, a16 := img.At(x, y).RGBA()
+			if a16 < 0x8000 {
+				continue
+			}
+			r, g, b := float64(r16>>8), float64(g16>>8), float64(b16>>8)
+			hi := math.Max(r, math.Max(g, b))
+			lo := math.Min(r, math.Min(g, b))
+			if hi < 40 || lo > 220 {
+				continue // near black or near white
+			}
+			sat := (hi - lo) / hi
+			w := 1 + 4*sat
+
+			key := int(r)>>4<<8 | int(g)>>4<<4 | int(b)>>4
+			bk := buckets[key]
+			if bk == nil {
+				bk = &bucket{}
+				buckets[key] = bk
+			}
+			bk.weight += w
+			bk.r += r * w
+			bk.g += g * w
+			bk.b += b * w
+		}
+	}
+
+	var best *bucket
+	for _, bk := range buckets {
+		if best == nil || bk.weight > best.weight {
+			best = bk
+		}
+	}
+	if best == nil {
+		return "", ErrNoColor
+	}
+
+	r, g, b := readable(best.r/best.weight, best.g/best.weight, best.b/best.weight)
+	return fmt.Sprintf("#%02x%02x%02x", r, g, b), nil
+}
+
+// readable clamps the lightness of a color so it shows up on a dark
+// terminal without washing out
+func readable(r, g, b float64) (uint8, uint8, uint8) {
+	h, s, l := toHSL(r/255, g/255, b/255)
+	l = math.Min(math.Max(l, 0.5), 0.75)
+	s = math.Max(s, 0.35)
+	rf, gf, bf := fromHSL(h, s, l)
+	return uint8(math.Round(rf * 255)), uint8(math.Round(gf * 255)), uint8(math.Round(bf * 255))
+}
+
+func toHSL(r, g, b float64) (h, s, l float64) {
+	hi := math.Max(r, math.Max(g, b))
+	lo := math.Min(r, math.Min(g, b))
+	l = (hi + lo) / 2
+	if hi == lo {
+		return 0, 0, l
+	}
+	d := hi - lo
+	if l > 0.5 {
+		s = d / (2 - hi - lo)
+	} else {
+		s = d / (hi + lo)
+	}
+	switch hi {
+	case r:
+		h = (g - b) / d
+		if g < b {
+			h += 6
+		}
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	return h / 6, s, l
+}
+
+func fromHSL(h, s, l float64) (r, g, b float64) {
+	if s == 0 {
+		return l, l, l
+	}
+	q := l * (1 + s)
+	if l >= 0.5 {
+		q = l + s - l*s
+	}
+	p := 2*l - q
+	return hueToRGB(p, q, h+1.0/3), hueToRGB(p, q, h), hueToRGB(p, q, h-1.0/3)
+}
+
+func hueToRGB(p, q, t float64) float64 {
+	if t < 0 {
+		t++
+	}
+	if t > 1 {
+		t--
+	}
+	switch {
+	case t < 1.0/6:
+		return p + (q-p)*6*t
+	case t < 0.5:
+		return q
+	case t < 2.0/3:
+		return p + (q-p)*(2.0/3-t)*6
+	}
+	return p
+}
+
+// Cache remembers the accent color of each track file, including the
+// absence of one, so art is decoded once per file
+type Cache struct {
+	read func(path string) ([]byte, error)
+
+	mu     sync.Mutex
+	colors map[string]string // file path -> "#rrggbb", "" when there is none
+}
+
+// NewCache creates a cache that extracts embedded art with read and falls
+// back to a cover image in the track's directory
+func NewCache(read func(path string) ([]byte, error)) *Cache {
+	return &Cache{read: read, colors: make(map[string]string)}
+}
+
+// Cached returns the accent for path if it has already been computed
+func (c *Cache) Cached(path string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	color, ok := c.colors[path]
+	return color, ok
+}
+
+// Accent returns the accent color for a track file, computing and caching
+// it on first use. An empty string means no usable art was found.
+func (c *Cache) Accent(path string) string {
+	if color, ok := c.Cached(path); ok {
+		return color
+	}
+
+	color := ""
+	if data, err := c.read(path); err == nil && len(data) > 0 {
+		color, _ = Dominant(data)
+	}
+	if color == "" {
+		for _, name := range sidecarNames {
+			if data, err := os.ReadFile(filepath.Join(filepath.Dir(path), name)); err == nil {
+				if color, err = Dominant(data); err == nil {
+					break
+				}
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.colors[path] = color
+	c.mu.Unlock()
+	return color
+}
@@ -3,13 +3,15 @@ package audio
 import (
 	"fmt"
 	"io"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/faiface/beep"
 	"github.com/faiface/beep/flac"
-	"github.com/faiface/beep/mp3"
 	"github.com/faiface/beep/wav"
+	"github.com/jscyril/golang_music_player/internal/audio/mp3trim"
 	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
 )
 
@@ -35,7 +37,7 @@ func DecodeAudio(r io.ReadSeekCloser, filePath string) (beep.StreamSeekCloser, b
 
 	switch ext {
 	case ".mp3":
-		return mp3.Decode(r)
+		return mp3trim.Decode(r)
 	case ".wav":
 		return wav.Decode(r)
 	case ".flac":
@@ -44,3 +46,48 @@ func DecodeAudio(r io.ReadSeekCloser, filePath string) (beep.StreamSeekCloser, b
 		return nil, beep.Format{}, fmt.Errorf("%w: %s", playerrors.ErrInvalidFormat, ext)
 	}
 }
+
+// probeFrames is how many frames Probe decodes past the header
+const probeFrames = 4096
+
+// ProbeInfo is what Probe reads from a file's header
+type ProbeInfo struct {
+	Codec      string        // "MP3", "FLAC" or "PCM"
+	Duration   time.Duration // 0 if the stream does not tell
+	SampleRate int
+	Channels   int
+}
+
+// Probe checks that the file at path decodes, reading its header and the
+// first few thousand frames rather than the whole file, and returns its
+// codec and duration. Unsupported files fail with ErrInvalidFormat and
+// damaged ones with ErrCorruptAudio, so they can be turned away before
+// they are queued instead of failing when their turn comes.
+func Probe(path string) (ProbeInfo, error) {
+	codecs := map[string]string{".mp3": "MP3", ".flac": "FLAC", ".wav": "PCM"}
+	codec, ok := codecs[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return ProbeInfo{}, fmt.Errorf("%s: %w", filepath.Base(path), playerrors.ErrInvalidFormat)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return ProbeInfo{}, err
+	}
+	streamer, format, err := DecodeAudio(file, path)
+	if err != nil {
+		file.Close()
+		return ProbeInfo{}, fmt.Errorf("%s: %w (%v)", filepath.Base(path), playerrors.ErrCorruptAudio, err)
+	}
+	defer streamer.Close()
+
+	samples := make([][2]float64, probeFrames)
+	streamer.Stream(samples)
+	if err := streamer.Err(); err != nil {
+		return ProbeInfo{}, fmt.Errorf("%s: %w (%v)", filepath.Base(path), playerrors.ErrCorruptAudio, err)
+	}
+	info := ProbeInfo{Codec: codec, SampleRate: int(format.SampleRate), Channels: format.NumChannels}
+	if n := streamer.Len(); n > 0 && format.SampleRate > 0 {
+		info.Duration = format.SampleRate.D(n)
+	}
+	return info, nil
+}
@@ -3,16 +3,17 @@ package audio
 import (
 	"context"
 	"fmt"
-	"os"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/faiface/beep"
 	"github.com/faiface/beep/effects"
-	"github.com/faiface/beep/speaker"
 	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/crash"
 	"github.com/jscyril/golang_music_player/internal/logger"
 	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
+	"github.com/jscyril/golang_music_player/pkg/events"
 )
 
 var _ api.Player = (*AudioEngine)(nil)
@@ -20,49 +21,364 @@ var _ api.Player = (*AudioEngine)(nil)
 type AudioEngine struct {
 	state      *api.PlaybackState
 	commands   chan api.AudioCommand
-	events     chan api.AudioEvent
+	bus        *events.EventBus
+	events     <-chan api.AudioEvent // subscription handed out by Events, made on first use
+	eventsOnce sync.Once
 	mu         sync.RWMutex
 	streamer   beep.StreamSeekCloser
 	ctrl       *beep.Ctrl
 	volume     *effects.Volume
+	duck       *effects.Gain // temporary attenuation for announcements/calls; 0 is unity
+	fade       *effects.Gain // ramped during sink switches; Gain -1 is silent, 0 is unity
+	output     beep.Streamer // top of the current chain, re-routed on sink switch
 	format     beep.Format
 	done       chan struct{}
-	sampleRate beep.SampleRate // speaker sample rate (fixed at init)
-	trackRate  beep.SampleRate // current track's native sample rate
+	sampleRate beep.SampleRate         // output sample rate (fixed at init)
+	trackRate  beep.SampleRate         // current track's native sample rate
+	gen        uint64                  // bumped per started track; stale chains end silently
+	fading     []beep.StreamSeekCloser // outgoing tracks still fading after a crossfade
+	startedAt  time.Time               // when the current track started
+	sourceSize int64                   // bytes in the current file or stream, 0 if unknown
+	stream     *httpReadSeekCloser     // the current HTTP stream, nil for local files
+	source     *readAhead              // the current file's read-ahead, nil without one
+	readAhead  time.Duration           // audio decoded ahead of playback, 0 for none
+	preload    int64                   // files up to this size are read into memory, 0 for none
+	queue      api.Sequencer           // where Next, Previous and auto-advance take tracks from
+	resumeAt   func(*api.Track) time.Duration
+	policy     FailurePolicy
+	failures   int           // tracks in a row that failed to play
+	heardFrom  time.Duration // where playback last started or seeked to on the output
+
+	sink  AudioSink   // active output
+	sinks []AudioSink // all registered outputs, in registration order
+	trims map[string]SinkTrim
+	meter *levelMeter // output level and limiter, after the mix
+	mix   *beep.Mixer // streams routed to the active sink; nil until one is
+}
+
+// sinkFadeDuration is the length of the fade-out/fade-in when switching sinks
+const sinkFadeDuration = 150 * time.Millisecond
+
+// crossfadeStep is how often the crossfade gains are updated
+const crossfadeStep = 50 * time.Millisecond
+
+// crossfade is the payload of CmdCrossfade
+type crossfade struct {
+	track    *api.Track
+	duration time.Duration
 }
 
+// duckFadeDuration is how long ducking takes to lower or restore the volume
+const duckFadeDuration = 400 * time.Millisecond
+
 func NewAudioEngine() *AudioEngine {
+	speakerSink := NewSpeakerSink()
 	return &AudioEngine{
 		state: &api.PlaybackState{
 			Status: api.StatusStopped,
-			Volume: 0.5,
+			Volume: 0.9, // unity gain on the log volume curve
 			Repeat: api.RepeatNone,
+			Output: speakerSink.Name(),
+			PeakDB: meterFloorDB,
 		},
-		commands: make(chan api.AudioCommand, 10),
-		events:   make(chan api.AudioEvent, 20),
-		done:     make(chan struct{}),
+		commands:   make(chan api.AudioCommand, 10),
+		bus:        events.NewEventBus(),
+		done:       make(chan struct{}),
+		sampleRate: DefaultSampleRate,
+		sink:       speakerSink,
+		sinks:      []AudioSink{speakerSink},
+		trims:      make(map[string]SinkTrim),
+		meter:      &levelMeter{},
+		policy:     DefaultFailurePolicy(),
+		readAhead:  DefaultReadAhead,
 	}
 }
 
 func (e *AudioEngine) Start(ctx context.Context) error {
-	// Initialize the speaker ONCE with a standard sample rate.
-	// Calling speaker.Init() more than once causes the oto backend to panic.
-	e.sampleRate = beep.SampleRate(44100)
-	if err := speaker.Init(e.sampleRate, e.sampleRate.N(time.Second/10)); err != nil {
-		logger.Error("Speaker init failed: %v", err)
-		return fmt.Errorf("speaker init: %w", err)
-	}
-	logger.Info("Audio engine started (sample_rate=%d)", e.sampleRate)
+	// Open the initial sink ONCE at a standard sample rate; tracks at other
+	// rates are resampled rather than re-initializing the output.
+	sink := e.activeSink()
+	if err := sink.Open(e.sampleRate); err != nil {
+		logger.Error("Sink %s init failed: %v", sink.Name(), err)
+		return fmt.Errorf("%s init: %w", sink.Name(), err)
+	}
+	logger.Info("Audio engine started (sample_rate=%d, output=%s)", e.sampleRate, sink.Name())
 	go e.run(ctx)
 	go e.trackPosition(ctx)
 	return nil
 }
 
+// DefaultSampleRate is the output rate unless SetSampleRate picks another
+const DefaultSampleRate = 44100
+
+// resampleQuality is the interpolation quality passed to beep.Resample
+const resampleQuality = 4
+
+// SetSampleRate sets the rate every output is opened at. The outputs are
+// opened once at this rate and never re-initialized; tracks at other rates
+// are resampled into it. Call it before Start.
+func (e *AudioEngine) SetSampleRate(rate int) error {
+	if rate < 8000 || rate > 192000 {
+		return fmt.Errorf("unsupported output sample rate %d Hz", rate)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sampleRate = beep.SampleRate(rate)
+	return nil
+}
+
+// toOutputRate resamples a decoded stream to the output rate if needed, so
+// streams of any rate can share the already-open sink mixer
+func (e *AudioEngine) toOutputRate(s beep.Streamer, rate beep.SampleRate) beep.Streamer {
+	if rate == e.sampleRate {
+		return s
+	}
+	logger.Info("Resampling stream from %d to %d Hz", rate, e.sampleRate)
+	return beep.Resample(resampleQuality, rate, e.sampleRate, s)
+}
+
+// RegisterSink makes an additional output available for SwitchSink
+func (e *AudioEngine) RegisterSink(sink AudioSink) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.sinks = append(e.sinks, sink)
+}
+
+// Sinks returns the names of all registered outputs
+func (e *AudioEngine) Sinks() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	names := make([]string, len(e.sinks))
+	for i, s := range e.sinks {
+		names[i] = s.Name()
+	}
+	return names
+}
+
+// SwitchSink re-routes playback to the named output with a short fade
+func (e *AudioEngine) SwitchSink(name string) error {
+	if e.findSink(name) == nil {
+		return fmt.Errorf("unknown audio sink %q", name)
+	}
+	e.commands <- api.AudioCommand{Type: api.CmdSwitchSink, Payload: name}
+	return nil
+}
+
+// Duck temporarily lowers the volume by db decibels with a short fade, so
+// notifications, calls or announcements can be heard over the music. The
+// attenuation persists across tracks until Unduck is called.
+func (e *AudioEngine) Duck(db float64) error {
+	if db < 0 {
+		return fmt.Errorf("duck amount must be positive, got %.1f dB", db)
+	}
+	e.commands <- api.AudioCommand{Type: api.CmdDuck, Payload: db}
+	return nil
+}
+
+// Unduck fades the volume back to its level before Duck
+func (e *AudioEngine) Unduck() error {
+	e.commands <- api.AudioCommand{Type: api.CmdDuck, Payload: 0.0}
+	return nil
+}
+
+// SetSinkTrim sets the volume trim, delay and volume curve for a named
+// output. The gain takes effect the next time a stream is routed to that
+// output (track start or sink switch), the delay with the next position.
+func (e *AudioEngine) SetSinkTrim(name string, trim SinkTrim) error {
+	if e.findSink(name) == nil {
+		return fmt.Errorf("unknown audio sink %q", name)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.trims[name] = trim
+	if name == e.sink.Name() {
+		e.state.OutputTrim = trim.String()
+	}
+	return nil
+}
+
+// SetReadAhead sets how much audio is decoded ahead of playback, so files
+// on slow or network file systems play without stuttering; 0 decodes as
+// the sink plays. It applies from the next track.
+func (e *AudioEngine) SetReadAhead(d time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.readAhead = max(d, 0)
+}
+
+// decodeAhead wraps a track's decoder in a read-ahead as set with
+// SetReadAhead, reporting its stalls as EventBuffering; nil if it is off
+func (e *AudioEngine) decodeAhead(dec beep.StreamSeekCloser, rate beep.SampleRate) *readAhead {
+	e.mu.RLock()
+	d := e.readAhead
+	e.mu.RUnlock()
+	if d <= 0 {
+		return nil
+	}
+	ra := newReadAhead(dec, rate.N(d))
+	ra.notify(func(waiting bool) {
+		e.bus.Publish(api.AudioEvent{Type: api.EventBuffering, Payload: waiting})
+	})
+	return ra
+}
+
+// SetLimiter turns the output limiter on or off. When on, peaks that
+// volume, trim and the track itself push above full scale are pulled down
+// instead of clipping.
+func (e *AudioEngine) SetLimiter(on bool) {
+	e.meter.SetLimit(on)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.state.Limiter = on
+}
+
+// routeTo plays output on sink with that sink's trim applied. Streams are
+// summed in the engine's own mix, which the sink plays through a single
+// clip guard, so the level is metered and limited after the tracks of a
+// crossfade add up.
+func (e *AudioEngine) routeTo(sink AudioSink, output beep.Streamer) {
+	sink.Lock()
+	e.mu.Lock()
+	trim := e.trims[sink.Name()]
+	mix := e.mix
+	fresh := mix == nil
+	if fresh {
+		mix = &beep.Mixer{}
+		e.mix = mix
+	}
+	mix.Add(trim.apply(output))
+	e.mu.Unlock()
+	sink.Unlock()
+
+	// Play takes the sink's lock
+	if fresh {
+		sink.Play(newClipGuard(mix, e.meter, e.sampleRate))
+	}
+}
+
+// clearSink removes everything playing on sink, the engine's mix with it,
+// so the next stream routed starts a new mix
+func (e *AudioEngine) clearSink(sink AudioSink) {
+	sink.Clear()
+	e.mu.Lock()
+	e.mix = nil
+	e.mu.Unlock()
+}
+
+// heard returns the position heard when the decoder is at decoded. The
+// active output's delay holds it back, as what was decoded is heard that
+// much later, but never before where playback last started or seeked to:
+// the output drops what it held then. Callers hold e.mu.
+func (e *AudioEngine) heard(decoded time.Duration) time.Duration {
+	return max(decoded-e.trims[e.sink.Name()].Delay, min(e.heardFrom, decoded))
+}
+
+// setPosition sets the reported position from where the decoder is.
+// Callers hold e.mu.
+func (e *AudioEngine) setPosition(decoded time.Duration) {
+	e.state.Position = e.heard(decoded)
+	e.state.OutputDelay = decoded - e.state.Position
+}
+
+// restartPosition sets the position after the output started over at
+// pos: a new track, a seek or another output. Callers hold e.mu.
+func (e *AudioEngine) restartPosition(pos time.Duration) {
+	e.heardFrom = pos
+	e.setPosition(pos)
+}
+
+// gainDB returns the net gain of the volume, ducking and output trim
+// stages. Callers hold e.mu.
+func (e *AudioEngine) gainDB() float64 {
+	return e.volumeDB() - e.state.DuckDB + e.trims[e.sink.Name()].GainDB
+}
+
+// volumeDB returns the gain of the volume level on the active output's
+// curve, or silence when muted. Callers hold e.mu.
+func (e *AudioEngine) volumeDB() float64 {
+	if e.state.Muted {
+		return meterFloorDB
+	}
+	return VolumeDB(e.state.Volume, e.trims[e.sink.Name()].Curve)
+}
+
+// applyVolume sets the volume stage from the level and the active output's
+// curve. Callers hold e.mu, and the sink lock while a stream plays.
+func (e *AudioEngine) applyVolume() {
+	if e.volume == nil {
+		return
+	}
+	// effects.Volume with base 10 scales by 10^Volume
+	e.volume.Volume = e.volumeDB() / 20
+	e.volume.Silent = e.state.Volume <= 0 || e.state.Muted
+}
+
+// activeSink returns the current output
+func (e *AudioEngine) activeSink() AudioSink {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.sink
+}
+
+// findSink looks up a registered output by name
+func (e *AudioEngine) findSink(name string) AudioSink {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for _, s := range e.sinks {
+		if s.Name() == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Events returns the engine's default subscription to all events on its
+// bus. Position and state updates are dropped while it is full; TrackEnded
+// never is. Consumers that subscribe to the bus themselves should not call
+// it, or the unread subscription keeps growing.
 func (e *AudioEngine) Events() <-chan api.AudioEvent {
+	e.eventsOnce.Do(func() {
+		e.events = e.bus.SubscribeAll()
+	})
 	return e.events
 }
 
+// SetQueue gives the engine the queue it advances through when a track
+// ends, so playback continues without a UI
+func (e *AudioEngine) SetQueue(q api.Sequencer) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.queue = q
+}
+
+// Queue returns the queue set by SetQueue, or nil
+func (e *AudioEngine) Queue() api.Sequencer {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.queue
+}
+
+// SetResume sets where tracks started from the queue begin, for resuming
+// audiobooks
+func (e *AudioEngine) SetResume(f func(*api.Track) time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.resumeAt = f
+}
+
+// SetBus makes the engine publish to a bus shared with other components.
+// Call it before Start.
+func (e *AudioEngine) SetBus(bus *events.EventBus) {
+	e.bus = bus
+}
+
+// Bus returns the bus the engine publishes to, for additional subscribers
+func (e *AudioEngine) Bus() *events.EventBus {
+	return e.bus
+}
+
 func (e *AudioEngine) run(ctx context.Context) {
+	defer crash.Guard()
 	for {
 		select {
 		case <-ctx.Done():
@@ -74,59 +390,141 @@ func (e *AudioEngine) run(ctx context.Context) {
 			case api.CmdPlay:
 				track := cmd.Payload.(*api.Track)
 				logger.Info("Play command received: %q by %s (%s)", track.Title, track.Artist, track.FilePath)
-				if err := e.playTrack(track); err != nil {
-					logger.Error("Failed to play track %q: %v", track.Title, err)
-					e.events <- api.AudioEvent{Type: api.EventError, Payload: err}
+				e.resetFailures()
+				if err := e.begin(track, false); err != nil {
+					e.failed(track, err, true)
+				}
+
+			case api.CmdNext:
+				// A generation payload is the auto-advance from that track's
+				// end; it is stale if anything was started since
+				gen, auto := cmd.Payload.(uint64)
+				e.mu.RLock()
+				stale := auto && e.gen != gen
+				e.mu.RUnlock()
+				if stale {
+					break
+				}
+				if !auto {
+					e.resetFailures()
+				}
+				e.advance(auto)
+
+			case api.CmdPrevious:
+				e.mu.RLock()
+				q := e.queue
+				e.mu.RUnlock()
+				if q == nil {
+					break
+				}
+				if track := q.Previous(); track != nil {
+					logger.Info("Previous track: %q", track.Title)
+					e.resetFailures()
+					if err := e.begin(track, false); err != nil {
+						e.failed(track, err, false)
+					}
+				}
+
+			case api.CmdCrossfade:
+				cf := cmd.Payload.(crossfade)
+				logger.Info("Crossfading to %q over %s", cf.track.Title, cf.duration)
+				// A track that fails to fade in is passed over once the one
+				// still playing ends
+				playing := e.GetState().Status == api.StatusPlaying
+				if err := e.crossfadeTo(cf.track, cf.duration); err != nil {
+					if playing {
+						e.fail(cf.track, err, true)
+					} else {
+						e.failed(cf.track, err, true)
+					}
 				}
 
 			case api.CmdPause:
 				logger.Debug("Pause command received")
-				speaker.Lock()
+				sink := e.activeSink()
+				sink.Lock()
 				e.mu.Lock()
 				if e.ctrl != nil {
 					e.ctrl.Paused = true
 					e.state.Status = api.StatusPaused
 				}
 				e.mu.Unlock()
-				speaker.Unlock()
-				e.events <- api.AudioEvent{Type: api.EventStateChange, Payload: e.state}
+				sink.Unlock()
+				e.bus.Publish(api.AudioEvent{Type: api.EventStateChange, Payload: e.GetState()})
 
 			case api.CmdResume:
-				speaker.Lock()
+				sink := e.activeSink()
+				sink.Lock()
 				e.mu.Lock()
 				if e.ctrl != nil {
 					e.ctrl.Paused = false
 					e.state.Status = api.StatusPlaying
 				}
 				e.mu.Unlock()
-				speaker.Unlock()
-				e.events <- api.AudioEvent{Type: api.EventStateChange, Payload: e.state}
+				sink.Unlock()
+				e.bus.Publish(api.AudioEvent{Type: api.EventStateChange, Payload: e.GetState()})
 
 			case api.CmdStop:
+				// A generation payload stops after that track failed
+				if gen, ok := cmd.Payload.(uint64); ok {
+					e.mu.RLock()
+					stale := e.gen != gen
+					e.mu.RUnlock()
+					if stale {
+						break
+					}
+				}
 				e.stopPlayback()
-				e.events <- api.AudioEvent{Type: api.EventStateChange, Payload: e.state}
+				e.bus.Publish(api.AudioEvent{Type: api.EventStateChange, Payload: e.GetState()})
 
 			case api.CmdVolume:
 				level := cmd.Payload.(float64)
-				speaker.Lock()
+				sink := e.activeSink()
+				sink.Lock()
 				e.mu.Lock()
-				if e.volume != nil {
-					// Convert 0-1 range to decibel-like scale
-					e.volume.Volume = level*2 - 1 // -1 to 1 range
-				}
 				e.state.Volume = level
+				e.state.Muted = false // changing the volume unmutes
+				e.applyVolume()
 				e.mu.Unlock()
-				speaker.Unlock()
+				sink.Unlock()
+
+			case api.CmdMute:
+				muted := cmd.Payload.(bool)
+				sink := e.activeSink()
+				sink.Lock()
+				e.mu.Lock()
+				e.state.Muted = muted
+				e.applyVolume()
+				e.mu.Unlock()
+				sink.Unlock()
+				logger.Info("Muted set to %v", muted)
+				e.bus.Publish(api.AudioEvent{Type: api.EventStateChange, Payload: e.GetState()})
 
 			case api.CmdSeek:
 				pos := cmd.Payload.(time.Duration)
 				e.seekTo(pos)
+				e.bus.Publish(api.AudioEvent{Type: api.EventStateChange, Payload: e.GetState()})
+
+			case api.CmdDuck:
+				db := cmd.Payload.(float64)
+				logger.Info("Ducking to -%.1f dB", db)
+				e.rampDuck(db)
+				e.bus.Publish(api.AudioEvent{Type: api.EventStateChange, Payload: e.GetState()})
+
+			case api.CmdSwitchSink:
+				name := cmd.Payload.(string)
+				if err := e.switchSink(name); err != nil {
+					logger.Error("Failed to switch output to %s: %v", name, err)
+					e.bus.Publish(api.AudioEvent{Type: api.EventError, Payload: err})
+				}
+				e.bus.Publish(api.AudioEvent{Type: api.EventStateChange, Payload: e.GetState()})
 			}
 		}
 	}
 }
 
 func (e *AudioEngine) trackPosition(ctx context.Context) {
+	defer crash.Guard()
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
 
@@ -135,33 +533,127 @@ func (e *AudioEngine) trackPosition(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			speaker.Lock()
-			e.mu.RLock()
+			sink := e.activeSink()
+			sink.Lock()
+			e.mu.Lock()
 			if e.state.Status == api.StatusPlaying && e.streamer != nil {
-				pos := e.streamer.Position()
-				e.state.Position = e.trackRate.D(pos)
+				e.setPosition(e.trackRate.D(e.streamer.Position()))
 			}
-			e.mu.RUnlock()
-			speaker.Unlock()
+			progress := e.progress()
+			e.mu.Unlock()
+			sink.Unlock()
+
+			peak, clipping, limiting := e.meter.read(time.Now())
+			e.mu.Lock()
+			e.state.PeakDB, e.state.Clipping, e.state.Limiting = peak, clipping, limiting
+			e.mu.Unlock()
 
-			// Send event outside of locks to avoid blocking
 			e.mu.RLock()
-			if e.state.Status == api.StatusPlaying {
-				e.events <- api.AudioEvent{
-					Type:    api.EventPositionUpdate,
-					Payload: e.state.Position,
+			playing := e.state.Status == api.StatusPlaying
+			e.mu.RUnlock()
+			if playing {
+				e.bus.Publish(api.AudioEvent{Type: api.EventPositionUpdate, Payload: progress})
+			}
+		}
+	}
+}
+
+// advance moves to the queue's next track, passing over tracks that fail
+// to start while the failure policy allows. Auto-advance stops playback
+// when the queue runs out; a skip with nothing left keeps the current
+// track playing.
+func (e *AudioEngine) advance(auto bool) {
+	e.mu.RLock()
+	q, prev := e.queue, e.state.CurrentTrack
+	e.mu.RUnlock()
+	if q == nil {
+		return
+	}
+	for {
+		track := q.Next()
+		if track == nil {
+			if auto {
+				logger.Info("Queue exhausted, no next track")
+				if c, ok := q.(api.Consumer); ok {
+					c.Consume()
 				}
+				e.stopPlayback()
+				e.bus.Publish(api.AudioEvent{Type: api.EventStateChange, Payload: e.GetState()})
 			}
-			e.mu.RUnlock()
+			return
 		}
+		logger.Info("Advancing to next track: %q", track.Title)
+		// Repeat-one starts the same track over rather than resuming it
+		again := auto && prev != nil && prev.ID == track.ID
+		err := e.begin(track, again)
+		if err == nil {
+			return
+		}
+		if !e.fail(track, err, true) {
+			break
+		}
+		// The failed track stopped the current one, so this is an
+		// auto-advance from here on
+		auto = true
 	}
+	e.stopPlayback()
+	e.bus.Publish(api.AudioEvent{Type: api.EventStateChange, Payload: e.GetState()})
+}
+
+// begin plays track, resuming it where SetResume says unless fromStart,
+// and retrying transient failures as the failure policy says
+func (e *AudioEngine) begin(track *api.Track, fromStart bool) error {
+	if err := e.playRetrying(track); err != nil {
+		return err
+	}
+	e.mu.RLock()
+	resumeAt := e.resumeAt
+	e.mu.RUnlock()
+	if resumeAt != nil && !fromStart {
+		if pos := resumeAt(track); pos > 0 {
+			logger.Info("Resuming %q at %s", track.Title, pos.Truncate(time.Second))
+			e.seekTo(pos)
+		}
+	}
+	return nil
+}
+
+// progress describes the current track for EventPositionUpdate. The
+// caller holds the sink lock and e.mu, as the stream is read under them.
+func (e *AudioEngine) progress() api.ProgressPayload {
+	p := api.ProgressPayload{Position: e.state.Position, OutputDelay: e.state.OutputDelay}
+	if e.streamer == nil {
+		return p
+	}
+	if n := e.streamer.Len(); n > 0 && e.trackRate > 0 {
+		p.Duration = e.trackRate.D(n)
+	} else if e.state.CurrentTrack != nil {
+		p.Duration = e.state.CurrentTrack.Duration
+	}
+	if e.sourceSize > 0 && p.Duration > 0 {
+		p.Bitrate = int(float64(e.sourceSize) * 8 / p.Duration.Seconds() / 1000)
+	}
+	p.Buffer, p.Buffered = api.BufferLocal, 1
+	if e.stream != nil {
+		p.Buffer, p.Buffered = api.BufferStreaming, 0
+		if pos, size := e.stream.fetched(); size > 0 {
+			p.Buffered = min(float64(pos)/float64(size), 1)
+		}
+	}
+	return p
 }
 
 func (e *AudioEngine) playTrack(track *api.Track) error {
 	logger.Debug("Stopping previous playback before starting new track")
 	e.stopPlayback()
+	return e.startTrack(track, 0)
+}
 
-	file, err := os.Open(track.FilePath)
+// startTrack decodes track and routes it to the sink next to anything still
+// playing there. fade is the initial fade gain: 0 for full volume, -1 for
+// silence when the track is faded in.
+func (e *AudioEngine) startTrack(track *api.Track, fade float64) error {
+	file, size, err := e.openTrack(track.FilePath)
 	if err != nil {
 		logger.Error("Failed to open file %s: %v", track.FilePath, err)
 		return playerrors.NewPlayerError("open", track.ID, err)
@@ -176,25 +668,30 @@ func (e *AudioEngine) playTrack(track *api.Track) error {
 
 	logger.Debug("Decoded track: sample_rate=%d, channels=%d", format.SampleRate, format.NumChannels)
 
-	// If the track's sample rate differs from the speaker's initialized rate,
-	// wrap it in a resampler so we never need to call speaker.Init() again.
-	var src beep.Streamer = streamer
-	if format.SampleRate != e.sampleRate {
-		logger.Info("Resampling track from %d to %d Hz", format.SampleRate, e.sampleRate)
-		src = beep.Resample(4, format.SampleRate, e.sampleRate, streamer)
+	ra := e.decodeAhead(streamer, format.SampleRate)
+	if ra != nil {
+		streamer = ra
 	}
 
+	src := e.toOutputRate(streamer, format.SampleRate)
+
 	e.mu.Lock()
+	e.gen++
+	gen := e.gen
 	e.streamer = streamer
 	e.format = format
 	e.trackRate = format.SampleRate
+	e.sourceSize = size
+	e.stream = nil
+	e.source = ra
 	e.ctrl = &beep.Ctrl{Streamer: src, Paused: false}
-	e.volume = &effects.Volume{
-		Streamer: e.ctrl,
-		Base:     2,
-		Volume:   e.state.Volume*2 - 1,
-		Silent:   false,
-	}
+	e.volume = &effects.Volume{Streamer: e.ctrl, Base: 10}
+	e.applyVolume()
+	e.duck = &effects.Gain{Streamer: e.volume, Gain: dbToGain(-e.state.DuckDB)}
+	e.fade = &effects.Gain{Streamer: e.duck, Gain: fade}
+	e.output = beep.Seq(e.fade, beep.Callback(func() {
+		e.trackDone(track, gen, streamer)
+	}))
 	e.state.CurrentTrack = track
 	// Backfill duration from the decoded stream if the track was scanned
 	// before duration computation was added (e.g. loaded from a cached library).
@@ -202,44 +699,145 @@ func (e *AudioEngine) playTrack(track *api.Track) error {
 		track.Duration = format.SampleRate.D(streamer.Len())
 	}
 	e.state.Status = api.StatusPlaying
-	e.state.Position = 0
+	e.restartPosition(0)
+	e.startedAt = time.Now()
+	output := e.output
+	sink := e.sink
 	e.mu.Unlock()
 
-	speaker.Play(beep.Seq(e.volume, beep.Callback(func() {
-		logger.Info("Track ended: %q", track.Title)
-		e.events <- api.AudioEvent{Type: api.EventTrackEnded, Payload: track}
-	})))
+	e.routeTo(sink, output)
 
 	logger.Info("Track started: %q by %s", track.Title, track.Artist)
-	e.events <- api.AudioEvent{Type: api.EventTrackStarted, Payload: track}
+	e.bus.Publish(api.AudioEvent{Type: api.EventTrackStarted, Payload: track})
 	return nil
 }
 
+// trackDone runs on the sink's goroutine when a track's stream runs out,
+// at its end or when decoding fails partway. A track that was crossfaded
+// away just releases its decoder.
+func (e *AudioEngine) trackDone(track *api.Track, gen uint64, streamer beep.StreamSeekCloser) {
+	e.mu.Lock()
+	current := e.gen == gen
+	advance := e.queue != nil
+	if !current {
+		for i, s := range e.fading {
+			if s == streamer {
+				e.fading = append(e.fading[:i], e.fading[i+1:]...)
+				break
+			}
+		}
+	}
+	e.mu.Unlock()
+
+	if !current {
+		streamer.Close()
+		return
+	}
+	// Decoding that fails partway is a failure rather than a play
+	if err := streamer.Err(); err != nil {
+		cmd := api.AudioCommand{Type: api.CmdStop, Payload: gen}
+		if e.fail(track, playerrors.NewPlayerError("decode", track.ID, err), true) {
+			cmd.Type = api.CmdNext
+		}
+		go func() { e.commands <- cmd }() // the run loop takes the sink lock
+		return
+	}
+	e.resetFailures()
+	logger.Info("Track ended: %q", track.Title)
+	e.bus.Publish(api.AudioEvent{Type: api.EventTrackEnded, Payload: track})
+	if advance {
+		// Not on the sink's goroutine: the run loop takes the sink lock
+		go func() { e.commands <- api.AudioCommand{Type: api.CmdNext, Payload: gen} }()
+	}
+}
+
+// crossfadeTo starts track silently next to the current one and swaps
+// their levels over d. Without anything playing it is a plain start.
+func (e *AudioEngine) crossfadeTo(track *api.Track, d time.Duration) error {
+	e.mu.Lock()
+	playing := e.output != nil && e.state.Status == api.StatusPlaying
+	oldFade, oldStreamer := e.fade, e.streamer
+	e.mu.Unlock()
+	if !playing || d <= 0 {
+		return e.playTrack(track)
+	}
+
+	if err := e.startTrack(track, -1); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.fading = append(e.fading, oldStreamer)
+	newFade, gen := e.fade, e.gen
+	e.mu.Unlock()
+
+	go e.rampCrossfade(oldFade, newFade, gen, d)
+	return nil
+}
+
+// rampCrossfade moves the outgoing track's gain to silence and the incoming
+// one's to unity over d. It stops early if another track replaces the
+// incoming one, and jumps to the end if playback is paused.
+func (e *AudioEngine) rampCrossfade(out, in *effects.Gain, gen uint64, d time.Duration) {
+	steps := max(int(d/crossfadeStep), 1)
+	for i := 1; i <= steps; i++ {
+		sink := e.activeSink()
+		sink.Lock()
+		e.mu.Lock()
+		if e.gen != gen {
+			e.mu.Unlock()
+			sink.Unlock()
+			return
+		}
+		frac := float64(i) / float64(steps)
+		if e.state.Status != api.StatusPlaying {
+			frac, i = 1, steps
+		}
+		out.Gain = -frac
+		in.Gain = frac - 1
+		e.mu.Unlock()
+		sink.Unlock()
+		time.Sleep(d / time.Duration(steps))
+	}
+}
+
 func (e *AudioEngine) stopPlayback() {
-	logger.Debug("Stopping playback: clearing speaker")
-	// speaker.Clear() has its own internal lock, call it first
-	speaker.Clear()
+	logger.Debug("Stopping playback: clearing output")
+	// Clear() takes the sink's internal lock, call it first
+	e.clearSink(e.activeSink())
 
 	e.mu.Lock()
 	streamer := e.streamer
+	fading := e.fading
 	e.streamer = nil
+	e.stream = nil
+	e.source = nil
+	e.sourceSize = 0
+	e.fading = nil
 	e.ctrl = nil
 	e.volume = nil
+	e.fade = nil
+	e.output = nil
 	e.state.Status = api.StatusStopped
-	e.state.Position = 0
+	e.restartPosition(0)
 	e.mu.Unlock()
 
-	// Close streamer outside of locks
+	// Close streamers outside of locks
 	if streamer != nil {
 		streamer.Close()
 	}
+	for _, s := range fading {
+		s.Close()
+	}
 }
 
+// seekTo moves playback to the absolute position pos, clamped to the track
 func (e *AudioEngine) seekTo(pos time.Duration) {
-	speaker.Lock()
+	sink := e.activeSink()
+	sink.Lock()
 	e.mu.Lock()
 	defer e.mu.Unlock()
-	defer speaker.Unlock()
+	defer sink.Unlock()
 
 	if e.streamer != nil {
 		newPos := e.trackRate.N(pos)
@@ -250,15 +848,117 @@ func (e *AudioEngine) seekTo(pos time.Duration) {
 			newPos = length - 1
 		}
 		if err := e.streamer.Seek(newPos); err == nil {
-			e.state.Position = pos
+			e.restartPosition(e.trackRate.D(newPos))
+		}
+	}
+}
+
+// switchSink moves the current output chain to another sink. The stream is
+// faded out on the old sink, re-routed without being stopped, and faded in
+// on the new one so playback continues from the same position.
+func (e *AudioEngine) switchSink(name string) error {
+	next := e.findSink(name)
+	if next == nil {
+		return fmt.Errorf("unknown audio sink %q", name)
+	}
+	prev := e.activeSink()
+	if prev == next {
+		return nil
+	}
+
+	if err := next.Open(e.sampleRate); err != nil {
+		return err
+	}
+
+	e.mu.RLock()
+	output := e.output
+	e.mu.RUnlock()
+
+	if output != nil {
+		e.rampFade(prev, 0, -1)
+	}
+	e.clearSink(prev)
+
+	e.mu.Lock()
+	e.sink = next
+	e.state.Output = next.Name()
+	e.state.OutputTrim = e.trims[next.Name()].String()
+	e.applyVolume() // the new output may use another volume curve
+	// The old output's delay went with it; the new one starts from here
+	if e.streamer != nil {
+		e.restartPosition(e.trackRate.D(e.streamer.Position()))
+	}
+	e.mu.Unlock()
+
+	if output != nil {
+		e.routeTo(next, output)
+		e.rampFade(next, -1, 0)
+	}
+
+	if err := prev.Close(); err != nil {
+		logger.Warn("Closing sink %s: %v", prev.Name(), err)
+	}
+	logger.Info("Output switched from %s to %s", prev.Name(), next.Name())
+	return nil
+}
+
+// rampFade moves the fade gain from one level to another over sinkFadeDuration
+func (e *AudioEngine) rampFade(sink AudioSink, from, to float64) {
+	const steps = 10
+	for i := 1; i <= steps; i++ {
+		sink.Lock()
+		e.mu.Lock()
+		if e.fade != nil {
+			e.fade.Gain = from + (to-from)*float64(i)/steps
+		}
+		e.mu.Unlock()
+		sink.Unlock()
+		time.Sleep(sinkFadeDuration / steps)
+	}
+}
+
+// rampDuck fades the duck gain from its current attenuation to db over
+// duckFadeDuration, interpolating in dB so the change sounds even
+func (e *AudioEngine) rampDuck(db float64) {
+	e.mu.RLock()
+	from := e.state.DuckDB
+	e.mu.RUnlock()
+
+	const steps = 20
+	sink := e.activeSink()
+	for i := 1; i <= steps; i++ {
+		level := from + (db-from)*float64(i)/steps
+		sink.Lock()
+		e.mu.Lock()
+		playing := e.duck != nil
+		if playing {
+			e.duck.Gain = dbToGain(-level)
+		}
+		e.state.DuckDB = level
+		e.mu.Unlock()
+		sink.Unlock()
+		// Nothing audible to fade when idle; just record the new level
+		if playing {
+			time.Sleep(duckFadeDuration / steps)
 		}
 	}
 }
 
+// dbToGain converts decibels to an effects.Gain value (0 is unity)
+func dbToGain(db float64) float64 {
+	return math.Pow(10, db/20) - 1
+}
+
 func (e *AudioEngine) cleanup() {
 	logger.Info("Audio engine shutting down")
 	e.stopPlayback()
-	close(e.events)
+	if err := e.activeSink().Close(); err != nil {
+		logger.Warn("Closing sink: %v", err)
+	}
+	if st := e.bus.Stats(); len(st.Dropped) > 0 {
+		logger.Info("Events dropped by slow subscribers: %v", st.Dropped)
+	}
+	e.bus.Close()
 }
 
 func (e *AudioEngine) Play(track *api.Track) error {
@@ -269,6 +969,29 @@ func (e *AudioEngine) Play(track *api.Track) error {
 	return nil
 }
 
+// CrossfadeTo starts track while fading out the current one over d. The
+// outgoing track does not send EventTrackEnded.
+func (e *AudioEngine) CrossfadeTo(track *api.Track, d time.Duration) error {
+	if track == nil {
+		return playerrors.ErrTrackNotFound
+	}
+	e.commands <- api.AudioCommand{Type: api.CmdCrossfade, Payload: crossfade{track: track, duration: d}}
+	return nil
+}
+
+// Next skips to the queue's next track. It does nothing without a queue
+// or when the queue has no next track.
+func (e *AudioEngine) Next() error {
+	e.commands <- api.AudioCommand{Type: api.CmdNext}
+	return nil
+}
+
+// Previous steps back to the queue's previous track
+func (e *AudioEngine) Previous() error {
+	e.commands <- api.AudioCommand{Type: api.CmdPrevious}
+	return nil
+}
+
 func (e *AudioEngine) Pause() error {
 	e.commands <- api.AudioCommand{Type: api.CmdPause}
 	return nil
@@ -283,11 +1006,24 @@ func (e *AudioEngine) Stop() error {
 	return nil
 }
 
+// Levels returns the live per-channel output levels
+func (e *AudioEngine) Levels() api.Levels {
+	return e.meter.levels(time.Now())
+}
+
+// Seek moves playback to an absolute position in the current track.
+// Positions outside the track are clamped to its start or end.
 func (e *AudioEngine) Seek(position time.Duration) error {
 	e.commands <- api.AudioCommand{Type: api.CmdSeek, Payload: position}
 	return nil
 }
 
+// SetMuted silences the output or restores it. The volume level is kept
+// while muted, and setting a new volume unmutes.
+func (e *AudioEngine) SetMuted(muted bool) {
+	e.commands <- api.AudioCommand{Type: api.CmdMute, Payload: muted}
+}
+
 func (e *AudioEngine) SetVolume(level float64) error {
 	if level < 0 || level > 1 {
 		return playerrors.ErrInvalidVolume
@@ -301,6 +1037,9 @@ func (e *AudioEngine) GetState() *api.PlaybackState {
 	defer e.mu.RUnlock()
 
 	state := *e.state
+	state.GainDB = e.gainDB()
+	state.VolumeDB = e.volumeDB()
+	state.Buffering = e.source != nil && e.source.stalled()
 	if e.state.CurrentTrack != nil {
 		track := *e.state.CurrentTrack
 		state.CurrentTrack = &track
@@ -308,47 +1047,87 @@ func (e *AudioEngine) GetState() *api.PlaybackState {
 	return &state
 }
 
+// Snapshot returns the playback state with q's contents, read under the
+// sink, engine and queue locks together so they are consistent. The
+// position is read from the stream rather than the last position tick.
+// q may be nil.
+func (e *AudioEngine) Snapshot(q api.QueueState) *api.Snapshot {
+	sink := e.activeSink()
+	sink.Lock()
+	defer sink.Unlock()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	snap := &api.Snapshot{PlaybackState: *e.state, TakenAt: time.Now()}
+	snap.GainDB = e.gainDB()
+	snap.VolumeDB = e.volumeDB()
+	snap.Buffering = e.source != nil && e.source.stalled()
+	if e.state.CurrentTrack != nil {
+		track := *e.state.CurrentTrack
+		snap.CurrentTrack = &track
+		snap.TrackStartedAt = e.startedAt
+	}
+	if e.streamer != nil && e.state.Status != api.StatusStopped {
+		decoded := e.trackRate.D(e.streamer.Position())
+		snap.Position = e.heard(decoded)
+		snap.OutputDelay = decoded - snap.Position
+	}
+	if q != nil {
+		q.FillState(&snap.PlaybackState)
+	}
+	return snap
+}
+
 // PlayFromURL streams audio from an HTTP URL using Authorization header.
-// It uses NewHTTPStreamer to decode the audio and plays it through the existing speaker pipeline.
+// It uses NewHTTPStreamer to decode the audio and plays it through the active output sink.
 // This method is used by the client-server TUI (cmd/client) to stream from the server.
 func (e *AudioEngine) PlayFromURL(streamURL string, token string) error {
-	streamer, format, err := NewHTTPStreamer(streamURL, token)
+	body, err := newHTTPReadSeekCloser(streamURL, token)
+	if err != nil {
+		return fmt.Errorf("http streamer: open http stream: %w", err)
+	}
+	streamer, format, err := decodeHTTP(body)
 	if err != nil {
 		return fmt.Errorf("http streamer: %w", err)
 	}
+	ra := e.decodeAhead(streamer, format.SampleRate)
+	if ra != nil {
+		streamer = ra
+	}
 
 	e.stopPlayback()
 
-	var src beep.Streamer = streamer
-	if format.SampleRate != e.sampleRate {
-		logger.Info("Resampling HTTP stream from %d to %d Hz", format.SampleRate, e.sampleRate)
-		src = beep.Resample(4, format.SampleRate, e.sampleRate, streamer)
-	}
+	src := e.toOutputRate(streamer, format.SampleRate)
 
 	e.mu.Lock()
 	e.streamer = streamer
 	e.format = format
 	e.trackRate = format.SampleRate
+	e.stream = body
+	e.source = ra
+	e.sourceSize = max(body.size, 0)
 	e.ctrl = &beep.Ctrl{Streamer: src, Paused: false}
-	e.volume = &effects.Volume{
-		Streamer: e.ctrl,
-		Base:     2,
-		Volume:   e.state.Volume*2 - 1,
-		Silent:   false,
-	}
+	e.volume = &effects.Volume{Streamer: e.ctrl, Base: 10}
+	e.applyVolume()
+	e.duck = &effects.Gain{Streamer: e.volume, Gain: dbToGain(-e.state.DuckDB)}
+	e.fade = &effects.Gain{Streamer: e.duck}
+	e.output = beep.Seq(e.fade, beep.Callback(func() {
+		logger.Info("HTTP stream ended")
+		e.bus.Publish(api.AudioEvent{Type: api.EventTrackEnded})
+	}))
 	e.state.Status = api.StatusPlaying
-	e.state.Position = 0
+	e.restartPosition(0)
+	e.startedAt = time.Now()
 	// Clear current track metadata (populated by the caller via Play() for local files;
 	// for HTTP streams the caller tracks this via the apiclient.Track struct).
 	e.state.CurrentTrack = nil
+	output := e.output
+	sink := e.sink
 	e.mu.Unlock()
 
-	speaker.Play(beep.Seq(e.volume, beep.Callback(func() {
-		logger.Info("HTTP stream ended")
-		e.events <- api.AudioEvent{Type: api.EventTrackEnded}
-	})))
+	e.routeTo(sink, output)
 
 	logger.Info("HTTP stream playback started: %s", streamURL)
-	e.events <- api.AudioEvent{Type: api.EventTrackStarted}
+	e.bus.Publish(api.AudioEvent{Type: api.EventTrackStarted})
 	return nil
 }
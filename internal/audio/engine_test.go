@@ -1,9 +1,22 @@
 package audio
 
 import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/faiface/beep"
 	"github.com/jscyril/golang_music_player/api"
+	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
 )
 
 func TestNewAudioEngine(t *testing.T) {
@@ -21,15 +34,15 @@ func TestNewAudioEngine(t *testing.T) {
 		t.Errorf("Expected status StatusStopped, got %v", engine.state.Status)
 	}
 
-	if engine.state.Volume != 0.5 {
-		t.Errorf("Expected volume 0.5, got %f", engine.state.Volume)
+	if engine.state.Volume != 0.9 {
+		t.Errorf("Expected volume 0.9, got %f", engine.state.Volume)
 	}
 
 	if engine.commands == nil {
 		t.Error("Commands channel is nil")
 	}
 
-	if engine.events == nil {
+	if engine.Events() == nil {
 		t.Error("Events channel is nil")
 	}
 }
@@ -126,3 +139,643 @@ func TestSupportedFormats(t *testing.T) {
 		}
 	}
 }
+
+func TestSwitchSink_Unknown(t *testing.T) {
+	engine := NewAudioEngine()
+
+	if err := engine.SwitchSink("nope"); err == nil {
+		t.Error("SwitchSink with an unregistered name should return an error")
+	}
+
+	engine.RegisterSink(NewFileSink("recorder", filepath.Join(t.TempDir(), "out.wav")))
+	sinks := engine.Sinks()
+	if len(sinks) != 2 || sinks[0] != SpeakerSinkName || sinks[1] != "recorder" {
+		t.Errorf("Sinks() = %v, want [speaker recorder]", sinks)
+	}
+}
+
+func TestFileSink_WritesWAV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.wav")
+	sink := NewFileSink("recorder", path)
+
+	if err := sink.Open(beep.SampleRate(44100)); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	sink.Play(beep.Silence(-1))
+	time.Sleep(3 * pumpInterval)
+	if err := sink.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read recording: %v", err)
+	}
+	if len(data) <= 44 {
+		t.Fatalf("expected samples after the header, got %d bytes", len(data))
+	}
+	if string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		t.Error("missing RIFF/WAVE header")
+	}
+	if got := binary.LittleEndian.Uint32(data[40:44]); int(got) != len(data)-44 {
+		t.Errorf("data chunk size = %d, want %d", got, len(data)-44)
+	}
+}
+
+func TestSinkTrim_Apply(t *testing.T) {
+	trim := SinkTrim{GainDB: -6, Delay: 10 * time.Millisecond}
+
+	src := beep.Take(20, beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		for i := range samples {
+			samples[i] = [2]float64{1, 1}
+		}
+		return len(samples), true
+	}))
+	samples := make([][2]float64, 20)
+	n, _ := trim.apply(src).Stream(samples)
+	if n != 20 {
+		t.Fatalf("expected 20 samples, got %d", n)
+	}
+	if got := samples[0][0]; got < 0.49 || got > 0.51 {
+		t.Errorf("expected -6 dB to roughly halve the signal, got %f", got)
+	}
+
+	engine := NewAudioEngine()
+	if err := engine.SetSinkTrim("missing", trim); err == nil {
+		t.Error("expected error for unknown sink")
+	}
+	if err := engine.SetSinkTrim(SpeakerSinkName, trim); err != nil {
+		t.Fatalf("SetSinkTrim: %v", err)
+	}
+	if got := engine.GetState().OutputTrim; got != "-6.0 dB, +10ms" {
+		t.Errorf("OutputTrim = %q", got)
+	}
+}
+
+func TestSinkDelay_Position(t *testing.T) {
+	engine := NewAudioEngine()
+	engine.RegisterSink(NewNullSink(CastSinkName))
+	engine.SetSinkTrim(CastSinkName, SinkTrim{Delay: 2 * time.Second})
+	engine.sink = engine.findSink(CastSinkName)
+
+	tests := []struct {
+		name             string
+		decoded          time.Duration
+		restart          bool
+		heard, remaining time.Duration
+	}{
+		{"track start", 0, true, 0, 0},
+		{"within the delay", 1500 * time.Millisecond, false, 0, 1500 * time.Millisecond},
+		{"past the delay", 5 * time.Second, false, 3 * time.Second, 2 * time.Second},
+		{"seek", 60 * time.Second, true, 60 * time.Second, 0},
+		{"after the seek", 61 * time.Second, false, 60 * time.Second, time.Second},
+		{"caught up", 70 * time.Second, false, 68 * time.Second, 2 * time.Second},
+		{"seek back", 10 * time.Second, true, 10 * time.Second, 0},
+	}
+	for _, tt := range tests {
+		if tt.restart {
+			engine.restartPosition(tt.decoded)
+		} else {
+			engine.setPosition(tt.decoded)
+		}
+		state := engine.GetState()
+		if state.Position != tt.heard || state.OutputDelay != tt.remaining {
+			t.Errorf("%s: position %v, delay %v; want %v, %v",
+				tt.name, state.Position, state.OutputDelay, tt.heard, tt.remaining)
+		}
+	}
+}
+
+func TestClipGuard_Limit(t *testing.T) {
+	rate := beep.SampleRate(1000)
+	hot := beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+		for i := range samples {
+			samples[i] = [2]float64{1.5, -1.5}
+		}
+		return len(samples), true
+	})
+
+	meter := &levelMeter{}
+	samples := make([][2]float64, 10)
+	newClipGuard(hot, meter, rate).Stream(samples)
+	if samples[0][0] != 1.5 {
+		t.Errorf("limiter off should pass samples through, got %f", samples[0][0])
+	}
+	peak, clipping, limiting := meter.read(time.Now())
+	if !clipping || limiting || peak < 3.5 || peak > 3.6 {
+		t.Errorf("got peak %.2f dB clipping=%v limiting=%v, want ~3.5 dB clipping", peak, clipping, limiting)
+	}
+
+	meter = &levelMeter{}
+	meter.SetLimit(true)
+	newClipGuard(hot, meter, rate).Stream(samples)
+	for i, s := range samples {
+		if s[0] > limitCeiling+1e-9 || s[1] < -limitCeiling-1e-9 {
+			t.Fatalf("sample %d = %v exceeds the ceiling", i, s)
+		}
+	}
+	if _, clipping, limiting := meter.read(time.Now()); clipping || !limiting {
+		t.Errorf("clipping=%v limiting=%v, want limiting only", clipping, limiting)
+	}
+}
+
+// playSink is a sink that only collects what it is given to play
+type playSink struct {
+	writerSink
+	played []beep.Streamer
+}
+
+func (s *playSink) Play(st beep.Streamer) { s.played = append(s.played, st) }
+
+// TestRouteTo_MetersTheMix verifies streams routed to a sink are metered
+// and limited together, so a crossfade adding up above full scale clips
+// or is limited although neither track does on its own
+func TestRouteTo_MetersTheMix(t *testing.T) {
+	level := func(v float64) beep.Streamer {
+		return beep.StreamerFunc(func(samples [][2]float64) (int, bool) {
+			for i := range samples {
+				samples[i] = [2]float64{v, v}
+			}
+			return len(samples), true
+		})
+	}
+	for _, limit := range []bool{false, true} {
+		engine := NewAudioEngine()
+		engine.SetLimiter(limit)
+		sink := &playSink{writerSink: writerSink{name: "test"}}
+		engine.routeTo(sink, level(0.7))
+		engine.routeTo(sink, level(0.7))
+		if len(sink.played) != 1 {
+			t.Fatalf("sink was given %d streams, want the one mix", len(sink.played))
+		}
+
+		samples := make([][2]float64, 100)
+		sink.played[0].Stream(samples)
+		peak, clipping, limiting := engine.meter.read(time.Now())
+		if peak < 2.9 || peak > 3 {
+			t.Errorf("limit=%v: peak %.2f dB, want the sum at ~2.9 dB", limit, peak)
+		}
+		if clipping == limit || limiting != limit {
+			t.Errorf("limit=%v: clipping=%v limiting=%v", limit, clipping, limiting)
+		}
+		if limit && samples[99][0] > limitCeiling+1e-9 {
+			t.Errorf("mix played at %f above the ceiling", samples[99][0])
+		}
+
+		engine.clearSink(sink)
+		engine.routeTo(sink, level(0.1))
+		if len(sink.played) != 2 {
+			t.Errorf("limit=%v: a cleared sink should get a new mix", limit)
+		}
+	}
+}
+
+func TestVolumeDB(t *testing.T) {
+	tests := []struct {
+		level float64
+		curve VolumeCurve
+		want  float64
+	}{
+		{1, CurveLog, 6},
+		{0.9, CurveLog, 0},
+		{0.5, CurveLog, -24},
+		{0, CurveLog, meterFloorDB},
+		{1, CurveLinear, 6},
+		{0.5, CurveLinear, 6 - 6.0206},
+	}
+	for _, tt := range tests {
+		if got := VolumeDB(tt.level, tt.curve); math.Abs(got-tt.want) > 0.01 {
+			t.Errorf("VolumeDB(%v, %s) = %.2f, want %.2f", tt.level, tt.curve, got, tt.want)
+		}
+	}
+}
+
+func TestLevelMeter_Live(t *testing.T) {
+	meter := &levelMeter{}
+	meter.live([2]float64{0.5, 0}, [2]float64{0.25, 0}, time.Second)
+	now := time.Now()
+
+	l := meter.levels(now)
+	if l.PeakL < -6.1 || l.PeakL > -5.9 || l.PeakR != meterFloorDB {
+		t.Errorf("peaks L %.2f R %.2f, want ~-6 dB and silence", l.PeakL, l.PeakR)
+	}
+	if l.RMSL >= l.PeakL || l.RMSL < -12 {
+		t.Errorf("RMS L %.2f should sit below the peak after one block", l.RMSL)
+	}
+	if again := meter.levels(now); again != l {
+		t.Errorf("reading levels changed them: %+v then %+v", l, again)
+	}
+	if later := meter.levels(now.Add(time.Second)); later.PeakL > l.PeakL-levelFalloff+0.1 {
+		t.Errorf("peak after 1s = %.2f, want it %.0f dB lower", later.PeakL, levelFalloff)
+	}
+}
+
+// writeTestWAV writes n frames of 16-bit mono silence at rate
+func writeTestWAV(t *testing.T, path string, rate, n int) {
+	t.Helper()
+	data := make([]byte, 44+2*n)
+	copy(data[0:], "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], uint32(36+2*n))
+	copy(data[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(data[16:], 16)
+	binary.LittleEndian.PutUint16(data[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(data[22:], 1) // mono
+	binary.LittleEndian.PutUint32(data[24:], uint32(rate))
+	binary.LittleEndian.PutUint32(data[28:], uint32(2*rate))
+	binary.LittleEndian.PutUint16(data[32:], 2)
+	binary.LittleEndian.PutUint16(data[34:], 16)
+	copy(data[36:], "data")
+	binary.LittleEndian.PutUint32(data[40:], uint32(2*n))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlay_ResamplesIntoOpenSink(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewFileSink("recorder", filepath.Join(dir, "out.wav"))
+	engine := NewAudioEngine()
+	engine.sink = sink
+	if err := sink.Open(engine.sampleRate); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer sink.Close()
+	evs := engine.Events()
+
+	// Tracks at two other rates play one after the other into the same sink
+	for _, rate := range []int{22050, 48000} {
+		path := filepath.Join(dir, "track.wav")
+		writeTestWAV(t, path, rate, rate/10)
+		track := &api.Track{ID: "t", Title: "t", FilePath: path}
+		if err := engine.playTrack(track); err != nil {
+			t.Fatalf("play %d Hz: %v", rate, err)
+		}
+		if got := engine.GetState().CurrentTrack.Duration; got != 100*time.Millisecond {
+			t.Errorf("%d Hz: duration = %v, want 100ms", rate, got)
+		}
+
+		deadline := time.After(2 * time.Second)
+	wait:
+		for {
+			select {
+			case ev := <-evs:
+				if ev.Type == api.EventTrackEnded {
+					break wait
+				}
+			case <-deadline:
+				t.Fatalf("%d Hz track never ended", rate)
+			}
+		}
+	}
+	if rate := sink.(*writerSink).rate; rate != DefaultSampleRate {
+		t.Errorf("sink rate = %d, want %d", rate, DefaultSampleRate)
+	}
+}
+
+// fakeQueue fills a fixed queue into snapshots
+type fakeQueue []*api.Track
+
+func (q fakeQueue) FillState(state *api.PlaybackState) {
+	state.Queue = q
+	state.QueueIndex = 1
+	state.Repeat = api.RepeatAll
+}
+
+func TestSnapshot(t *testing.T) {
+	engine := NewAudioEngine()
+	q := fakeQueue{{ID: "a"}, {ID: "b"}}
+
+	snap := engine.Snapshot(q)
+	if len(snap.Queue) != 2 || snap.QueueIndex != 1 || snap.Repeat != api.RepeatAll {
+		t.Errorf("queue not filled in: %+v", snap.PlaybackState)
+	}
+	if snap.TakenAt.IsZero() || !snap.TrackStartedAt.IsZero() {
+		t.Errorf("TakenAt = %v, TrackStartedAt = %v", snap.TakenAt, snap.TrackStartedAt)
+	}
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var fields map[string]any
+	json.Unmarshal(data, &fields)
+	for _, key := range []string{"status", "queue", "queue_index", "repeat", "shuffle", "taken_at"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("snapshot JSON lacks %q: %s", key, data)
+		}
+	}
+}
+
+// listSequencer hands out tracks in order
+type listSequencer struct {
+	mu     sync.Mutex
+	tracks []*api.Track
+	i      int
+}
+
+func (s *listSequencer) Next() *api.Track {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.i+1 >= len(s.tracks) {
+		return nil
+	}
+	s.i++
+	return s.tracks[s.i]
+}
+
+func (s *listSequencer) Previous() *api.Track { return nil }
+
+func TestAdvance_SkipsUnplayable(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.wav")
+	writeTestWAV(t, good, DefaultSampleRate, DefaultSampleRate/20)
+	q := &listSequencer{tracks: []*api.Track{
+		{ID: "a", Title: "a", FilePath: good},
+		{ID: "missing", Title: "missing", FilePath: filepath.Join(dir, "missing.wav")},
+		{ID: "b", Title: "b", FilePath: good},
+	}}
+
+	engine := NewAudioEngine()
+	engine.sink = NewFileSink("recorder", filepath.Join(dir, "out.wav"))
+	engine.SetQueue(q)
+	evs := engine.Events()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := engine.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	engine.Play(q.tracks[0])
+
+	// Without anyone reacting to the end events the engine plays a, skips
+	// the missing track, plays b and stops
+	var started []string
+	deadline := time.After(3 * time.Second)
+	for {
+		select {
+		case ev := <-evs:
+			switch ev.Type {
+			case api.EventTrackStarted:
+				started = append(started, ev.Payload.(*api.Track).ID)
+			case api.EventStateChange:
+				if ev.Payload.(*api.PlaybackState).Status == api.StatusStopped {
+					if got := strings.Join(started, ","); got != "a,b" {
+						t.Errorf("started = %s, want a,b", got)
+					}
+					return
+				}
+			}
+		case <-deadline:
+			t.Fatalf("queue never ran out, started %v", started)
+		}
+	}
+}
+
+func TestProgress_LocalFile(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewFileSink("recorder", filepath.Join(dir, "out.wav"))
+	engine := NewAudioEngine()
+	engine.sink = sink
+	if err := sink.Open(engine.sampleRate); err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer sink.Close()
+
+	// One second of 16-bit mono at 8 kHz is 128 kbit/s
+	path := filepath.Join(dir, "track.wav")
+	writeTestWAV(t, path, 8000, 8000)
+	if err := engine.playTrack(&api.Track{ID: "t", FilePath: path}); err != nil {
+		t.Fatalf("play: %v", err)
+	}
+	defer engine.stopPlayback()
+
+	sink.Lock()
+	engine.mu.RLock()
+	p := engine.progress()
+	engine.mu.RUnlock()
+	sink.Unlock()
+
+	if p.Duration != time.Second {
+		t.Errorf("Duration = %v, want 1s", p.Duration)
+	}
+	if p.Bitrate != 128 {
+		t.Errorf("Bitrate = %d, want 128", p.Bitrate)
+	}
+	if p.Buffer != api.BufferLocal || p.Buffered != 1 {
+		t.Errorf("Buffer = %v (%.2f), want local (1)", p.Buffer, p.Buffered)
+	}
+}
+
+func TestPlay_FailurePolicy(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.wav")
+	writeTestWAV(t, good, DefaultSampleRate, DefaultSampleRate/20)
+
+	for _, skip := range []bool{true, false} {
+		q := &listSequencer{tracks: []*api.Track{
+			{ID: "missing", Title: "missing", FilePath: filepath.Join(dir, "missing.wav")},
+			{ID: "a", Title: "a", FilePath: good},
+		}}
+		engine := NewAudioEngine()
+		engine.sink = NewFileSink("recorder", filepath.Join(dir, "out.wav"))
+		engine.SetQueue(q)
+		engine.SetFailurePolicy(FailurePolicy{Skip: skip, MaxSkips: 10})
+		evs := engine.Events()
+		ctx, cancel := context.WithCancel(context.Background())
+		if err := engine.Start(ctx); err != nil {
+			t.Fatalf("Start failed: %v", err)
+		}
+		engine.Play(q.tracks[0])
+
+		var started []string
+		var failure *api.TrackFailure
+		deadline := time.After(3 * time.Second)
+	wait:
+		for {
+			select {
+			case ev := <-evs:
+				switch ev.Type {
+				case api.EventTrackStarted:
+					started = append(started, ev.Payload.(*api.Track).ID)
+				case api.EventTrackFailed:
+					f := ev.Payload.(api.TrackFailure)
+					failure = &f
+				case api.EventStateChange:
+					if ev.Payload.(*api.PlaybackState).Status == api.StatusStopped {
+						break wait
+					}
+				}
+			case <-deadline:
+				t.Fatalf("skip=%v: playback never stopped, started %v", skip, started)
+			}
+		}
+		cancel()
+
+		if failure == nil || failure.Track.ID != "missing" || failure.Skipped != skip {
+			t.Errorf("skip=%v: failure = %+v, want missing, skipped %v", skip, failure, skip)
+		}
+		want := ""
+		if skip {
+			want = "a"
+		}
+		if got := strings.Join(started, ","); got != want {
+			t.Errorf("skip=%v: started = %q, want %q", skip, got, want)
+		}
+	}
+}
+
+func TestTransient(t *testing.T) {
+	_, missing := os.Open(filepath.Join(t.TempDir(), "missing.wav"))
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{&os.PathError{Op: "open", Path: "/mnt/nfs/a.mp3", Err: syscall.ESTALE}, true},
+		{&os.PathError{Op: "read", Path: "/mnt/smb/a.mp3", Err: syscall.EIO}, true},
+		{os.ErrDeadlineExceeded, true},
+		{missing, false},
+		{errors.New("mp3: bad frame"), false},
+	} {
+		if got := Transient(tt.err); got != tt.want {
+			t.Errorf("Transient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestProbe(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.wav")
+	writeTestWAV(t, good, 8000, 8000)
+	info, err := Probe(good)
+	if err != nil {
+		t.Fatalf("Probe(good.wav): %v", err)
+	}
+	if info.Codec != "PCM" || info.Duration != time.Second || info.SampleRate != 8000 || info.Channels != 1 {
+		t.Errorf("Probe(good.wav) = %+v, want PCM, 1s, 8000 Hz, mono", info)
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.mp3")
+	if err := os.WriteFile(corrupt, []byte("definitely not mpeg audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Probe(corrupt); !errors.Is(err, playerrors.ErrCorruptAudio) {
+		t.Errorf("Probe(corrupt.mp3) = %v, want ErrCorruptAudio", err)
+	}
+	if _, err := Probe(filepath.Join(dir, "notes.txt")); !errors.Is(err, playerrors.ErrInvalidFormat) {
+		t.Errorf("Probe(notes.txt) = %v, want ErrInvalidFormat", err)
+	}
+}
+
+// slowDecoder counts up from its position, one frame per sample value,
+// decoding only while gate lets it
+type slowDecoder struct {
+	pos, length int
+	gate        chan struct{}
+}
+
+func (d *slowDecoder) Stream(samples [][2]float64) (int, bool) {
+	<-d.gate
+	if d.pos >= d.length {
+		return 0, false
+	}
+	n := min(len(samples), d.length-d.pos)
+	for i := range n {
+		samples[i] = [2]float64{float64(d.pos + i), 0}
+	}
+	d.pos += n
+	return n, true
+}
+
+func (d *slowDecoder) Err() error       { return nil }
+func (d *slowDecoder) Len() int         { return d.length }
+func (d *slowDecoder) Position() int    { return d.pos }
+func (d *slowDecoder) Seek(p int) error { d.pos = p; return nil }
+func (d *slowDecoder) Close() error     { return nil }
+
+func TestReadAhead_Underrun(t *testing.T) {
+	gate := make(chan struct{})
+	dec := &slowDecoder{length: 3 * decodeChunk, gate: gate}
+	go func() { gate <- struct{}{} }() // just the first chunk
+	ra := newReadAhead(dec, 2*decodeChunk)
+	defer func() {
+		close(gate)
+		ra.Close()
+	}()
+	var waits []bool
+	ra.notify(func(waiting bool) { waits = append(waits, waiting) })
+
+	samples := make([][2]float64, decodeChunk)
+	if n, ok := ra.Stream(samples); n != decodeChunk || !ok || samples[decodeChunk-1][0] != decodeChunk-1 {
+		t.Fatalf("first Stream = %d, %v, last frame %v", n, ok, samples[decodeChunk-1])
+	}
+
+	// The decoder is held up: silence, and the position stays put
+	if n, ok := ra.Stream(samples); n != decodeChunk || !ok || samples[0][0] != 0 {
+		t.Fatalf("stalled Stream = %d, %v, first frame %v; want silence", n, ok, samples[0])
+	}
+	if !ra.stalled() || ra.Position() != decodeChunk {
+		t.Errorf("stalled = %v at %d, want true at %d", ra.stalled(), ra.Position(), decodeChunk)
+	}
+
+	gate <- struct{}{}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		ra.mu.Lock()
+		n := ra.n
+		ra.mu.Unlock()
+		if n > 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if n, _ := ra.Stream(samples); n != decodeChunk || samples[0][0] != decodeChunk {
+		t.Fatalf("resumed Stream = %d, first frame %v, want %d", n, samples[0], decodeChunk)
+	}
+	if ra.stalled() || len(waits) != 2 || !waits[0] || waits[1] {
+		t.Errorf("waits = %v, want [true false]", waits)
+	}
+}
+
+func TestOpenTrack_Preload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "small.wav")
+	writeTestWAV(t, path, 8000, 800)
+	engine := NewAudioEngine()
+
+	for _, tt := range []struct {
+		limit  int64
+		memory bool
+	}{{0, false}, {100, false}, {1 << 20, true}} {
+		engine.SetPreload(tt.limit)
+		f, size, err := engine.openTrack(path)
+		if err != nil {
+			t.Fatalf("openTrack: %v", err)
+		}
+		if _, ok := f.(memFile); ok != tt.memory || size != 44+1600 {
+			t.Errorf("limit %d: in memory %v, size %d; want %v, %d", tt.limit, ok, size, tt.memory, 44+1600)
+		}
+		if _, _, err := DecodeAudio(f, path); err != nil {
+			t.Errorf("limit %d: decode: %v", tt.limit, err)
+		}
+		f.Close()
+	}
+}
+
+func TestNameStream_Restores(t *testing.T) {
+	t.Setenv("PULSE_PROP", "application.name='mine'")
+	t.Setenv("PIPEWIRE_PROPS", "")
+	os.Unsetenv("PIPEWIRE_PROPS")
+
+	restore := nameStream()
+	if got := os.Getenv("PIPEWIRE_PROPS"); !strings.Contains(got, StreamName) {
+		t.Errorf("PIPEWIRE_PROPS = %q, want the stream named", got)
+	}
+	if got := os.Getenv("PULSE_PROP"); got != "application.name='mine'" {
+		t.Errorf("PULSE_PROP = %q, want the user's own", got)
+	}
+	restore()
+	if _, set := os.LookupEnv("PIPEWIRE_PROPS"); set {
+		t.Error("PIPEWIRE_PROPS still set after the speaker started")
+	}
+	if got := os.Getenv("PULSE_PROP"); got != "application.name='mine'" {
+		t.Errorf("PULSE_PROP = %q after restore, want the user's own", got)
+	}
+}
@@ -0,0 +1,110 @@
+package audio
+
+import (
+	"errors"
+	"syscall"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/logger"
+)
+
+// FailurePolicy says what the engine does when a track cannot be played,
+// whether it fails to open, to decode or partway through
+type FailurePolicy struct {
+	Skip     bool          // move on to the queue's next track
+	MaxSkips int           // failures in a row after which playback stops
+	Retries  int           // further attempts after a transient file error
+	Backoff  time.Duration // wait before the first retry, doubled for each
+}
+
+// DefaultFailurePolicy skips up to 10 failing tracks in a row and tries a
+// file twice more when opening it fails for what may be a passing reason
+func DefaultFailurePolicy() FailurePolicy {
+	return FailurePolicy{Skip: true, MaxSkips: 10, Retries: 2, Backoff: 500 * time.Millisecond}
+}
+
+// SetFailurePolicy sets what happens when a track cannot be played
+func (e *AudioEngine) SetFailurePolicy(p FailurePolicy) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.policy = p
+}
+
+// transientErrnos are the system errors a file on a network share or a
+// busy disk returns until it recovers
+var transientErrnos = []error{
+	syscall.EIO, syscall.EAGAIN, syscall.EBUSY, syscall.EINTR,
+	syscall.ETIMEDOUT, syscall.ESTALE, syscall.ENETDOWN, syscall.ENETUNREACH,
+	syscall.ECONNRESET, syscall.EHOSTUNREACH,
+}
+
+// Transient reports whether err is a file error that tends to pass, such
+// as an NFS or SMB share timing out while it reconnects, so the file is
+// worth trying again. Missing files and decode errors are not.
+func Transient(err error) bool {
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+	for _, errno := range transientErrnos {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// playRetrying plays track, trying again while the policy allows when it
+// fails transiently. The waits hold up the run loop, a second or two with
+// the default policy.
+func (e *AudioEngine) playRetrying(track *api.Track) error {
+	e.mu.RLock()
+	p := e.policy
+	e.mu.RUnlock()
+	err := e.playTrack(track)
+	wait := p.Backoff
+	for i := 0; err != nil && i < p.Retries && Transient(err); i++ {
+		logger.Warn("Playing %q failed (%v), trying again in %s", track.Title, err, wait)
+		time.Sleep(wait)
+		wait *= 2
+		err = e.playTrack(track)
+	}
+	return err
+}
+
+// fail publishes that track could not be played and counts the failure
+// against the policy. It reports whether to move on to the next track,
+// never when canSkip is false.
+func (e *AudioEngine) fail(track *api.Track, err error, canSkip bool) bool {
+	e.mu.Lock()
+	e.failures++
+	failures, p, queued := e.failures, e.policy, e.queue != nil
+	skip := canSkip && p.Skip && queued && failures < p.MaxSkips
+	e.mu.Unlock()
+
+	logger.Error("Failed to play track %q: %v", track.Title, err)
+	if canSkip && p.Skip && queued && !skip {
+		logger.Warn("%d tracks in a row failed to play, stopping", failures)
+	}
+	e.bus.Publish(api.AudioEvent{Type: api.EventTrackFailed, Payload: api.TrackFailure{Track: track, Err: err, Skipped: skip}})
+	return skip
+}
+
+// failed handles track failing to start: playback moves on to the next
+// track if the policy says so and stays stopped otherwise
+func (e *AudioEngine) failed(track *api.Track, err error, canSkip bool) {
+	if e.fail(track, err, canSkip) {
+		e.advance(true)
+		return
+	}
+	e.bus.Publish(api.AudioEvent{Type: api.EventStateChange, Payload: e.GetState()})
+}
+
+// resetFailures starts counting failures in a row afresh, after a track
+// played through or the listener picked one
+func (e *AudioEngine) resetFailures() {
+	e.mu.Lock()
+	e.failures = 0
+	e.mu.Unlock()
+}
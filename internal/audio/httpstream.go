@@ -133,6 +133,17 @@ func NewHTTPStreamer(url string, token string) (beep.StreamSeekCloser, beep.Form
 	if err != nil {
 		return nil, beep.Format{}, fmt.Errorf("open http stream: %w", err)
 	}
+	return decodeHTTP(body)
+}
+
+// fetched reports how far into the stream has been downloaded and the
+// stream's size in bytes, -1 if unknown
+func (h *httpReadSeekCloser) fetched() (pos, size int64) {
+	return h.position, h.size
+}
+
+// decodeHTTP picks a decoder for an opened stream from its Content-Type
+func decodeHTTP(body *httpReadSeekCloser) (beep.StreamSeekCloser, beep.Format, error) {
 
 	ct := contentType(body.resp.Header.Get("Content-Type"))
 
@@ -0,0 +1,203 @@
+package audio
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/crash"
+)
+
+// limitCeiling is the highest sample level the limiter lets through (-0.3 dBFS)
+const limitCeiling = 0.966
+
+// limitRelease is how long the limiter takes to recover after a peak
+const limitRelease = 200 * time.Millisecond
+
+// meterFloorDB is the level reported for silence
+const meterFloorDB = -96.0
+
+// clipHold keeps the clipping indicator lit after the last clipped sample
+const clipHold = 2 * time.Second
+
+// levelFalloff is how fast the live peak meter drops, in dB per second
+const levelFalloff = 20.0
+
+// rmsWindow is the time constant of the live RMS meter
+const rmsWindow = 300 * time.Millisecond
+
+// levelMeter collects the output peak between reads. It is fed by the one
+// clip guard after the engine's mix, so it measures what the sink plays:
+// both tracks of a crossfade summed.
+type levelMeter struct {
+	mu       sync.Mutex
+	peak     float64   // highest level before limiting since the last read
+	limited  bool      // the limiter reduced gain since the last read
+	limit    bool      // limiter enabled
+	lastClip time.Time // when a sample last left the stream above full scale
+
+	// Live per-channel levels for api.LevelsProvider, after the limiter,
+	// decayed rather than reset between reads
+	livePeak [2]float64
+	liveMS   [2]float64 // mean square
+	liveAt   time.Time
+}
+
+// SetLimit turns the limiter on or off
+func (m *levelMeter) SetLimit(on bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limit = on
+}
+
+// read returns the peak in dBFS since the last read, whether a sample would
+// have clipped within clipHold, and whether the limiter was active
+func (m *levelMeter) read(now time.Time) (peakDB float64, clipping, limiting bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	peakDB = toDB(m.peak)
+	clipping = !m.lastClip.IsZero() && now.Sub(m.lastClip) < clipHold
+	limiting = m.limited
+	m.peak, m.limited = 0, false
+	return peakDB, clipping, limiting
+}
+
+// levels returns the live levels at now, decayed since the last block
+func (m *levelMeter) levels(now time.Time) api.Levels {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	peak, ms := m.decayed(now)
+	return api.Levels{
+		PeakL: toDB(peak[0]), PeakR: toDB(peak[1]),
+		RMSL: toDB(math.Sqrt(ms[0])), RMSR: toDB(math.Sqrt(ms[1])),
+	}
+}
+
+// decayed returns the live peaks and mean squares as they have fallen off
+// by now. Callers hold m.mu.
+func (m *levelMeter) decayed(now time.Time) (peak, ms [2]float64) {
+	if m.liveAt.IsZero() {
+		return peak, ms
+	}
+	dt := max(now.Sub(m.liveAt), 0)
+	fall := math.Pow(10, -levelFalloff*dt.Seconds()/20)
+	fade := math.Exp(-float64(dt) / float64(rmsWindow))
+	for c := range 2 {
+		peak[c] = m.livePeak[c] * fall
+		ms[c] = m.liveMS[c] * fade
+	}
+	return peak, ms
+}
+
+// toDB converts a linear level to dBFS, with silence at meterFloorDB
+func toDB(level float64) float64 {
+	if level <= 0 {
+		return meterFloorDB
+	}
+	return math.Max(20*math.Log10(level), meterFloorDB)
+}
+
+// record adds the peak of one block before limiting and reports whether
+// to limit it
+func (m *levelMeter) record(peak float64) (limit bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.peak = math.Max(m.peak, peak)
+	if peak > 1 && !m.limit {
+		m.lastClip = time.Now()
+	}
+	return m.limit
+}
+
+// live adds the per-channel peaks and mean squares of one block lasting d,
+// as it is played, to the live levels
+func (m *levelMeter) live(peak, ms [2]float64, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	livePeak, liveMS := m.decayed(now)
+	blend := 1 - math.Exp(-float64(d)/float64(rmsWindow))
+	for c := range 2 {
+		m.livePeak[c] = math.Max(livePeak[c], peak[c])
+		m.liveMS[c] = liveMS[c] + (ms[c]-liveMS[c])*blend
+	}
+	m.liveAt = now
+}
+
+// recordLimited notes that the limiter reduced gain
+func (m *levelMeter) recordLimited() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limited = true
+}
+
+// clipGuard is the last stage before a sink, after the engine's mix. It
+// measures the level and, when the limiter is on, pulls peaks down to
+// limitCeiling with an instant attack and a smooth release.
+type clipGuard struct {
+	s       beep.Streamer
+	meter   *levelMeter
+	rate    beep.SampleRate
+	gain    float64 // current limiter gain, 1 is unity
+	release float64 // per-frame recovery factor towards unity
+}
+
+// newClipGuard wraps the mix s for a sink running at rate
+func newClipGuard(s beep.Streamer, meter *levelMeter, rate beep.SampleRate) *clipGuard {
+	frames := float64(rate.N(limitRelease))
+	return &clipGuard{s: s, meter: meter, rate: rate, gain: 1, release: 1 - math.Exp(-1/math.Max(frames, 1))}
+}
+
+func (g *clipGuard) Stream(samples [][2]float64) (int, bool) {
+	// Decoders run on the sink's goroutine, which is not ours to guard
+	defer crash.Guard()
+	n, ok := g.s.Stream(samples)
+	peak, ms := measure(samples[:n])
+	if !g.meter.record(math.Max(peak[0], peak[1])) {
+		g.gain = 1
+	} else if g.limit(samples[:n]) {
+		g.meter.recordLimited()
+		// The live levels show what is played
+		peak, ms = measure(samples[:n])
+	}
+	g.meter.live(peak, ms, g.rate.D(n))
+	return n, ok
+}
+
+// measure returns the per-channel peaks and mean squares of samples
+func measure(samples [][2]float64) (peak, ms [2]float64) {
+	for i := range samples {
+		for c := range 2 {
+			peak[c] = math.Max(peak[c], math.Abs(samples[i][c]))
+			ms[c] += samples[i][c] * samples[i][c]
+		}
+	}
+	if n := len(samples); n > 0 {
+		ms[0] /= float64(n)
+		ms[1] /= float64(n)
+	}
+	return peak, ms
+}
+
+// limit applies the limiter gain to samples and reports whether it
+// reduced any of them
+func (g *clipGuard) limit(samples [][2]float64) bool {
+	limited := false
+	for i := range samples {
+		p := math.Max(math.Abs(samples[i][0]), math.Abs(samples[i][1]))
+		g.gain += (1 - g.gain) * g.release
+		if p*g.gain > limitCeiling {
+			g.gain = limitCeiling / p
+		}
+		if g.gain < 1 {
+			samples[i][0] *= g.gain
+			samples[i][1] *= g.gain
+			limited = true
+		}
+	}
+	return limited
+}
+
+func (g *clipGuard) Err() error { return g.s.Err() }
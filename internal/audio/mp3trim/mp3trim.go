@@ -0,0 +1,250 @@
+// Package mp3trim decodes MP3s with the header frame, encoder delay and
+// padding cut off. The player and the library scanner both decode through
+// it, so the duration the library shows is the length the player plays.
+package mp3trim
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/mp3"
+)
+
+// Decode decodes the MP3 in r trimmed to its music, so positions, seeks
+// and Len match what was encoded
+func Decode(r io.ReadSeekCloser) (beep.StreamSeekCloser, beep.Format, error) {
+	layout, err := readMP3Layout(r)
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+	dec, format, err := mp3.Decode(r)
+	if err != nil {
+		return nil, beep.Format{}, err
+	}
+	streamer, err := newMP3Stream(dec, layout)
+	if err != nil {
+		dec.Close()
+		return nil, beep.Format{}, err
+	}
+	return streamer, format, nil
+}
+
+// mp3DecoderDelay is the delay, in samples, every standard MP3 decoder
+// adds in front of the encoder's own
+const mp3DecoderDelay = 529
+
+// mp3HeaderScan is how far into a file, past its ID3v2 tag, the first
+// frame is looked for
+const mp3HeaderScan = 64 << 10
+
+// mp3Layout is what the first frame of an MP3 tells about the rest. VBR
+// encoders put a Xing (or "Info" for CBR) or a VBRI header in that frame
+// in place of audio; the decoder still plays it as a frame of silence,
+// so left alone every position is off by a frame plus the encoder's delay.
+type mp3Layout struct {
+	samplesPerFrame int
+	header          bool // the first frame is a Xing/Info or VBRI header
+	frames          int  // audio frames after the header, 0 if it does not say
+	delay           int  // encoder delay from the LAME tag
+	padding         int  // encoder padding at the end from the LAME tag
+	lame            bool // delay and padding were read
+}
+
+// readMP3Layout parses the first frame of the MP3 in r, leaving r at its
+// start. A file without a header gets the zero layout.
+func readMP3Layout(r io.ReadSeeker) (mp3Layout, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return mp3Layout{}, err
+	}
+	head := make([]byte, 10)
+	var skip int64
+	if _, err := io.ReadFull(r, head); err == nil && string(head[:3]) == "ID3" {
+		skip = 10 + (int64(head[6])<<21 | int64(head[7])<<14 | int64(head[8])<<7 | int64(head[9]))
+		if head[5]&0x10 != 0 {
+			skip += 10 // footer
+		}
+	}
+	if _, err := r.Seek(skip, io.SeekStart); err != nil {
+		return mp3Layout{}, err
+	}
+	buf := make([]byte, mp3HeaderScan)
+	n, err := io.ReadFull(r, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return mp3Layout{}, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return mp3Layout{}, err
+	}
+	return parseMP3Layout(buf[:n]), nil
+}
+
+// parseMP3Layout looks for the first Layer III frame in data and reads
+// its Xing/Info or VBRI header, if any
+func parseMP3Layout(data []byte) mp3Layout {
+	for i := 0; i+4 <= len(data); i++ {
+		if data[i] != 0xFF || data[i+1]&0xE0 != 0xE0 {
+			continue
+		}
+		version := data[i+1] >> 3 & 3 // 3 is MPEG-1, 2 MPEG-2, 0 MPEG-2.5
+		layer := data[i+1] >> 1 & 3   // 1 is Layer III
+		bitrate := data[i+2] >> 4
+		rate := data[i+2] >> 2 & 3
+		if version == 1 || layer != 1 || bitrate == 0 || bitrate == 15 || rate == 3 {
+			continue
+		}
+		return parseMP3Frame(data[i:])
+	}
+	return mp3Layout{}
+}
+
+// parseMP3Frame reads the header of the Layer III frame that frame starts
+// with and the Xing/Info or VBRI header in it
+func parseMP3Frame(frame []byte) mp3Layout {
+	mpeg1 := frame[1]>>3&3 == 3
+	mono := frame[3]>>6 == 3
+	layout := mp3Layout{samplesPerFrame: 576}
+	if mpeg1 {
+		layout.samplesPerFrame = 1152
+	}
+
+	// The Xing header follows the side information, whose size depends on
+	// the version and channels
+	side := 17
+	switch {
+	case mpeg1 && !mono:
+		side = 32
+	case !mpeg1 && mono:
+		side = 9
+	}
+	off := 4 + side
+	if frame[1]&1 == 0 {
+		off += 2 // CRC
+	}
+	if off+8 <= len(frame) {
+		if tag := string(frame[off : off+4]); tag == "Xing" || tag == "Info" {
+			layout.header = true
+			parseXing(frame[off+4:], &layout)
+			return layout
+		}
+	}
+
+	// VBRI always sits 32 bytes past the frame header
+	if len(frame) >= 4+32+18 && string(frame[36:40]) == "VBRI" {
+		layout.header = true
+		layout.frames = int(binary.BigEndian.Uint32(frame[50:54]))
+	}
+	return layout
+}
+
+// parseXing reads the Xing header fields in b, which starts with its flags,
+// and the LAME tag after them
+func parseXing(b []byte, layout *mp3Layout) {
+	flags := binary.BigEndian.Uint32(b)
+	b = b[4:]
+	if flags&1 != 0 {
+		if len(b) < 4 {
+			return
+		}
+		layout.frames = int(binary.BigEndian.Uint32(b))
+		b = b[4:]
+	}
+	for _, field := range []struct {
+		flag uint32
+		size int
+	}{{2, 4}, {4, 100}, {8, 4}} { // bytes, table of contents, quality
+		if flags&field.flag != 0 {
+			if len(b) < field.size {
+				return
+			}
+			b = b[field.size:]
+		}
+	}
+
+	// The LAME tag: a 9-byte encoder version, 12 bytes of settings, then
+	// the delay and padding as two 12-bit numbers. FFmpeg writes the same.
+	if len(b) < 24 {
+		return
+	}
+	switch string(b[:4]) {
+	case "LAME", "Lavf", "Lavc":
+		d := b[21:24]
+		layout.delay = int(d[0])<<4 | int(d[1])>>4
+		layout.padding = int(d[1]&0x0F)<<8 | int(d[2])
+		layout.lame = true
+	}
+}
+
+// trim returns how many samples to skip at the start of a decoded stream
+// of length samples and how many of the rest are music
+func (l mp3Layout) trim(length int) (offset, music int) {
+	if !l.header {
+		return 0, length
+	}
+	offset = l.samplesPerFrame
+	music = length - offset
+	if l.lame {
+		offset += l.delay + mp3DecoderDelay
+		music -= l.delay + l.padding
+	}
+	if l.frames > 0 {
+		want := l.frames * l.samplesPerFrame
+		if l.lame {
+			want -= l.delay + l.padding
+		}
+		music = min(music, want)
+	}
+	offset = min(offset, length)
+	return offset, max(min(music, length-offset), 0)
+}
+
+// mp3Stream is an MP3 decoder with the header frame, encoder delay and
+// padding cut off, so position 0 is the first sample of music and Len is
+// what was encoded. Seeking lands on the exact sample: the decoder indexes
+// every frame of a seekable file when it opens, whatever its bitrate, and
+// that index stands in for the header's table of contents, which is only
+// accurate to a hundredth of the track. A file without a header plays as
+// decoded.
+type mp3Stream struct {
+	beep.StreamSeekCloser
+	offset int // samples decoded before the music starts
+	length int // samples of music
+}
+
+// newMP3Stream trims dec, decoded from a file laid out as layout, and
+// moves it to the first frame of music
+func newMP3Stream(dec beep.StreamSeekCloser, layout mp3Layout) (*mp3Stream, error) {
+	offset, length := layout.trim(dec.Len())
+	s := &mp3Stream{StreamSeekCloser: dec, offset: offset, length: length}
+	if offset == 0 {
+		return s, nil
+	}
+	if err := s.Seek(0); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Stream stops at the end of the music, before the encoder's padding
+func (s *mp3Stream) Stream(samples [][2]float64) (int, bool) {
+	left := s.length - s.Position()
+	if left <= 0 {
+		return 0, false
+	}
+	if len(samples) > left {
+		samples = samples[:left]
+	}
+	return s.StreamSeekCloser.Stream(samples)
+}
+
+func (s *mp3Stream) Len() int {
+	return s.length
+}
+
+func (s *mp3Stream) Position() int {
+	return max(s.StreamSeekCloser.Position()-s.offset, 0)
+}
+
+func (s *mp3Stream) Seek(p int) error {
+	return s.StreamSeekCloser.Seek(min(max(p, 0), s.length) + s.offset)
+}
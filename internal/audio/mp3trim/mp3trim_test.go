@@ -0,0 +1,72 @@
+package mp3trim
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// fakeDecoder counts up from its position, one frame per sample value
+type fakeDecoder struct {
+	pos, length int
+}
+
+func (d *fakeDecoder) Stream(samples [][2]float64) (int, bool) {
+	if d.pos >= d.length {
+		return 0, false
+	}
+	n := min(len(samples), d.length-d.pos)
+	for i := range n {
+		samples[i] = [2]float64{float64(d.pos + i), 0}
+	}
+	d.pos += n
+	return n, true
+}
+
+func (d *fakeDecoder) Err() error       { return nil }
+func (d *fakeDecoder) Len() int         { return d.length }
+func (d *fakeDecoder) Position() int    { return d.pos }
+func (d *fakeDecoder) Seek(p int) error { d.pos = p; return nil }
+func (d *fakeDecoder) Close() error     { return nil }
+
+func TestMP3Stream_XingTrim(t *testing.T) {
+	// An MPEG-1 Layer III stereo frame header at 128 kbit/s, 44.1 kHz,
+	// with a Xing header after the 32 bytes of side information
+	frame := make([]byte, 417)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0x00})
+	xing := frame[4+32:]
+	copy(xing, "Xing")
+	binary.BigEndian.PutUint32(xing[4:], 1) // frame count only
+	binary.BigEndian.PutUint32(xing[8:], 100)
+	copy(xing[12:], "LAME3.100")
+	copy(xing[12+21:], []byte{0x24, 0x07, 0x20}) // delay 576, padding 1824
+
+	layout := parseMP3Layout(append([]byte{0, 0}, frame...))
+	want := mp3Layout{samplesPerFrame: 1152, header: true, frames: 100, delay: 576, padding: 1824, lame: true}
+	if layout != want {
+		t.Fatalf("parseMP3Layout = %+v, want %+v", layout, want)
+	}
+
+	dec := &fakeDecoder{length: 101 * 1152}
+	s, err := newMP3Stream(dec, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset, music := 1152+576+mp3DecoderDelay, 100*1152-576-1824
+	if s.Len() != music || s.Position() != 0 || dec.pos != offset {
+		t.Errorf("Len %d, Position %d, decoder at %d; want %d, 0, %d", s.Len(), s.Position(), dec.pos, music, offset)
+	}
+	if err := s.Seek(44100); err != nil || dec.pos != 44100+offset || s.Position() != 44100 {
+		t.Errorf("Seek(44100) = %v, decoder at %d", err, dec.pos)
+	}
+	samples := make([][2]float64, 2*music)
+	if n, _ := s.Stream(samples); n != music-44100 {
+		t.Errorf("Stream to the end = %d frames, want %d", n, music-44100)
+	}
+	if n, ok := s.Stream(samples); n != 0 || ok {
+		t.Errorf("Stream past the padding = %d, %v", n, ok)
+	}
+
+	if layout := parseMP3Layout([]byte{0xFF, 0xFB, 0x90, 0x00, 0, 0}); layout.header {
+		t.Errorf("plain frame parsed as header: %+v", layout)
+	}
+}
@@ -0,0 +1,50 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+// SetPreload makes tracks of up to limit bytes be read into memory whole
+// as they start, so a spinning disk can power down while they play and
+// seeking does not touch it; 0 turns it off. It applies from the next
+// track.
+func (e *AudioEngine) SetPreload(limit int64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.preload = max(limit, 0)
+}
+
+// memFile is a file read into memory
+type memFile struct {
+	*bytes.Reader
+}
+
+func (memFile) Close() error { return nil }
+
+// openTrack opens the file at path, read into memory if it is within the
+// preload limit, and returns it with its size
+func (e *AudioEngine) openTrack(path string) (io.ReadSeekCloser, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	var size int64
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+	e.mu.RLock()
+	limit := e.preload
+	e.mu.RUnlock()
+	if size == 0 || size > limit {
+		return file, size, nil
+	}
+
+	defer file.Close()
+	data := make([]byte, size)
+	if _, err := io.ReadFull(file, data); err != nil {
+		return nil, 0, err
+	}
+	return memFile{bytes.NewReader(data)}, size, nil
+}
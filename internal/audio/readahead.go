@@ -0,0 +1,208 @@
+package audio
+
+import (
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/jscyril/golang_music_player/internal/crash"
+)
+
+// DefaultReadAhead is how much audio is decoded ahead of playback unless
+// SetReadAhead picks another length
+const DefaultReadAhead = 10 * time.Second
+
+// decodeChunk is how many frames the read-ahead decodes at a time
+const decodeChunk = 4096
+
+// readAhead decodes a track ahead of playback on a goroutine of its own,
+// into a ring buffer, so a slow read from NFS, SMB or sshfs happens while
+// buffered audio plays instead of holding up the sink. Should the buffer
+// run dry anyway, the sink gets silence rather than stalling, the position
+// holds still and onWait is told, for a buffering indicator.
+type readAhead struct {
+	dec    beep.StreamSeekCloser
+	length int // dec.Len(), read once
+
+	decMu sync.Mutex // held while dec is used
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	buf     [][2]float64 // the ring
+	start   int          // index of the next frame to play
+	n       int          // frames buffered from start on
+	pos     int          // stream position of buf[start]
+	done    bool         // the decoder ran out
+	err     error        // the decoder's error once done
+	epoch   int          // bumped by seeks; frames decoded before one are dropped
+	closed  bool
+	waiting bool
+	onWait  func(waiting bool) // called, with mu held, as playback starts and stops waiting
+}
+
+// newReadAhead starts decoding dec ahead into a buffer of frames frames and
+// returns once the first of them are decoded
+func newReadAhead(dec beep.StreamSeekCloser, frames int) *readAhead {
+	r := &readAhead{dec: dec, length: dec.Len(), buf: make([][2]float64, max(frames, decodeChunk))}
+	r.cond = sync.NewCond(&r.mu)
+	go r.fill()
+
+	r.mu.Lock()
+	for r.n < decodeChunk && !r.done {
+		r.cond.Wait()
+	}
+	r.mu.Unlock()
+	return r
+}
+
+// notify sets the function told when playback starts and stops waiting
+// for the decoder. It runs on the sink's goroutine with the read-ahead
+// locked, so it must not call back into it.
+func (r *readAhead) notify(f func(waiting bool)) {
+	r.mu.Lock()
+	r.onWait = f
+	r.mu.Unlock()
+}
+
+// stalled reports whether playback waits for the decoder
+func (r *readAhead) stalled() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.waiting
+}
+
+// fill keeps the buffer full until the decoder runs out or r is closed
+func (r *readAhead) fill() {
+	defer crash.Guard()
+	chunk := make([][2]float64, decodeChunk)
+	for {
+		r.mu.Lock()
+		for !r.closed && (r.n == len(r.buf) || r.done) {
+			r.cond.Wait()
+		}
+		if r.closed {
+			r.mu.Unlock()
+			return
+		}
+		epoch, want := r.epoch, min(decodeChunk, len(r.buf)-r.n)
+		r.mu.Unlock()
+
+		r.decMu.Lock()
+		got, ok := r.dec.Stream(chunk[:want])
+		err := r.dec.Err()
+		r.decMu.Unlock()
+
+		r.mu.Lock()
+		if r.epoch == epoch {
+			r.push(chunk[:got], ok, err)
+		}
+		r.mu.Unlock()
+	}
+}
+
+// push adds decoded frames to the buffer, and the decoder's end if !ok;
+// the caller holds mu
+func (r *readAhead) push(frames [][2]float64, ok bool, err error) {
+	for len(frames) > 0 {
+		end := (r.start + r.n) % len(r.buf)
+		c := copy(r.buf[end:min(len(r.buf), end+len(frames))], frames)
+		r.n += c
+		frames = frames[c:]
+	}
+	if !ok {
+		r.done, r.err = true, err
+	}
+	r.cond.Broadcast()
+}
+
+// Stream plays buffered frames, padding with silence while the decoder
+// lags behind
+func (r *readAhead) Stream(samples [][2]float64) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for n < len(samples) && r.n > 0 {
+		c := copy(samples[n:], r.buf[r.start:min(len(r.buf), r.start+r.n)])
+		r.start = (r.start + c) % len(r.buf)
+		r.n -= c
+		r.pos += c
+		n += c
+	}
+	r.cond.Broadcast()
+	switch {
+	case n == len(samples):
+		r.setWaiting(false)
+	case r.done || r.closed:
+		return n, n > 0
+	default:
+		clear(samples[n:])
+		r.setWaiting(true)
+	}
+	return len(samples), true
+}
+
+// setWaiting records whether playback waits and tells onWait of changes;
+// the caller holds mu
+func (r *readAhead) setWaiting(waiting bool) {
+	if r.waiting == waiting {
+		return
+	}
+	r.waiting = waiting
+	if r.onWait != nil {
+		r.onWait(waiting)
+	}
+}
+
+// Err returns the decoder's error once everything before it was played
+func (r *readAhead) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.n > 0 {
+		return nil
+	}
+	return r.err
+}
+
+func (r *readAhead) Len() int {
+	return r.length
+}
+
+// Position is the position of the next frame played, behind the decoder
+// by what is buffered
+func (r *readAhead) Position() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.pos
+}
+
+// Seek empties the buffer, seeks the decoder and decodes the first chunk
+// from there before returning, so playback goes on without a gap
+func (r *readAhead) Seek(p int) error {
+	r.decMu.Lock()
+	defer r.decMu.Unlock()
+	if err := r.dec.Seek(p); err != nil {
+		return err
+	}
+	chunk := make([][2]float64, decodeChunk)
+	got, ok := r.dec.Stream(chunk)
+	err := r.dec.Err()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.start, r.n, r.pos = 0, 0, p
+	r.done, r.err = false, nil
+	r.epoch++
+	r.push(chunk[:got], ok, err)
+	return nil
+}
+
+// Close stops decoding ahead and closes the decoder
+func (r *readAhead) Close() error {
+	r.mu.Lock()
+	r.closed = true
+	r.cond.Broadcast()
+	r.mu.Unlock()
+	r.decMu.Lock()
+	defer r.decMu.Unlock()
+	return r.dec.Close()
+}
@@ -0,0 +1,370 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/faiface/beep"
+	"github.com/faiface/beep/effects"
+	"github.com/faiface/beep/speaker"
+	"github.com/jscyril/golang_music_player/internal/crash"
+	"github.com/jscyril/golang_music_player/internal/logger"
+)
+
+// AudioSink is an output the engine routes its stream into. The engine
+// locks the sink while mutating streamers that are currently playing.
+type AudioSink interface {
+	Name() string
+	Open(sampleRate beep.SampleRate) error
+	Play(s beep.Streamer)
+	Clear()
+	Lock()
+	Unlock()
+	Close() error
+}
+
+// SpeakerSinkName is the name of the built-in local speaker sink
+const SpeakerSinkName = "speaker"
+
+// CastSinkName is the silent output the engine plays to while a network
+// device plays the track itself, keeping the engine's clock for it
+const CastSinkName = "cast"
+
+// StreamName is what sound servers list the speaker's output stream as
+const StreamName = "gtmpc"
+
+// The beep speaker is a process-wide singleton and the oto backend panics
+// if it is initialized twice, so initialization is tracked at package level.
+var speakerInit struct {
+	once sync.Once
+	rate beep.SampleRate
+	err  error
+}
+
+// SpeakerSink plays through the local sound device
+type SpeakerSink struct{}
+
+// NewSpeakerSink creates the local speaker sink
+func NewSpeakerSink() *SpeakerSink {
+	return &SpeakerSink{}
+}
+
+// Name returns the sink name
+func (s *SpeakerSink) Name() string { return SpeakerSinkName }
+
+// Open initializes the speaker on first use; later calls are no-ops
+func (s *SpeakerSink) Open(sampleRate beep.SampleRate) error {
+	speakerInit.once.Do(func() {
+		restore := nameStream()
+		speakerInit.rate = sampleRate
+		speakerInit.err = speaker.Init(sampleRate, sampleRate.N(time.Second/10))
+		restore()
+	})
+	if speakerInit.err != nil {
+		return speakerInit.err
+	}
+	if speakerInit.rate != sampleRate {
+		return fmt.Errorf("speaker already initialized at %d Hz", speakerInit.rate)
+	}
+	return nil
+}
+
+// nameStream sets the properties PulseAudio and PipeWire give the
+// speaker's stream, so mixers such as pavucontrol list it as StreamName
+// playing music rather than as an anonymous ALSA client. The sound server's
+// ALSA plugin reads them from the environment when the device is opened,
+// so they are only needed while the speaker starts: the returned function
+// unsets them again, and child processes such as hooks and fpcalc do not
+// inherit them. Variables the user has set are left alone.
+func nameStream() (restore func()) {
+	props := map[string]string{
+		// libpulse client properties
+		"PULSE_PROP": fmt.Sprintf("application.name='%[1]s' application.id='%[1]s' "+
+			"application.icon_name='audio-x-generic' media.role='music'", StreamName),
+		// pipewire-alsa stream properties
+		"PIPEWIRE_PROPS": fmt.Sprintf("{ application.name = %[1]s application.id = %[1]s "+
+			"application.icon-name = audio-x-generic media.name = %[1]s media.role = Music "+
+			"node.description = %[1]s }", StreamName),
+	}
+	var set []string
+	for key, value := range props {
+		if _, ok := os.LookupEnv(key); !ok {
+			os.Setenv(key, value)
+			set = append(set, key)
+		}
+	}
+	return func() {
+		for _, key := range set {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+// Play adds a streamer to the speaker mixer
+func (s *SpeakerSink) Play(st beep.Streamer) { speaker.Play(st) }
+
+// Clear removes all streamers from the speaker mixer
+func (s *SpeakerSink) Clear() { speaker.Clear() }
+
+// Lock locks the speaker mixer
+func (s *SpeakerSink) Lock() { speaker.Lock() }
+
+// Unlock unlocks the speaker mixer
+func (s *SpeakerSink) Unlock() { speaker.Unlock() }
+
+// Close silences the speaker. The device itself stays open because it
+// cannot be safely re-initialized.
+func (s *SpeakerSink) Close() error {
+	speaker.Clear()
+	return nil
+}
+
+// pumpInterval is how often writer-based sinks pull samples from their mixer
+const pumpInterval = 50 * time.Millisecond
+
+// writerSink mixes streamers in real time and writes 16-bit little-endian
+// stereo PCM to an io.Writer. It backs the file recorder and pipe sinks.
+type writerSink struct {
+	name  string
+	open  func(rate beep.SampleRate) (io.Writer, error)
+	close func() error
+
+	mu      sync.Mutex
+	mixer   beep.Mixer
+	rate    beep.SampleRate
+	w       io.Writer
+	written int64
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func (s *writerSink) Name() string { return s.name }
+
+func (s *writerSink) Open(sampleRate beep.SampleRate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done != nil {
+		return nil // already open
+	}
+
+	w, err := s.open(sampleRate)
+	if err != nil {
+		return fmt.Errorf("open sink %s: %w", s.name, err)
+	}
+
+	s.w = w
+	s.rate = sampleRate
+	s.written = 0
+	s.mixer = beep.Mixer{}
+	s.done = make(chan struct{})
+	s.wg.Add(1)
+	go s.pump(s.done)
+	return nil
+}
+
+// pump streams one interval's worth of samples per tick
+func (s *writerSink) pump(done chan struct{}) {
+	defer crash.Guard()
+	defer s.wg.Done()
+	ticker := time.NewTicker(pumpInterval)
+	defer ticker.Stop()
+
+	samples := make([][2]float64, s.rate.N(pumpInterval))
+	buf := make([]byte, len(samples)*4)
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			s.mixer.Stream(samples)
+			encodePCM16(buf, samples)
+			n, err := s.w.Write(buf)
+			s.written += int64(n)
+			s.mu.Unlock()
+			if err != nil {
+				logger.Warn("Sink %s write failed: %v", s.name, err)
+			}
+		}
+	}
+}
+
+func (s *writerSink) Play(st beep.Streamer) {
+	s.mu.Lock()
+	s.mixer.Add(st)
+	s.mu.Unlock()
+}
+
+func (s *writerSink) Clear() {
+	s.mu.Lock()
+	s.mixer.Clear()
+	s.mu.Unlock()
+}
+
+func (s *writerSink) Lock()   { s.mu.Lock() }
+func (s *writerSink) Unlock() { s.mu.Unlock() }
+
+func (s *writerSink) Close() error {
+	s.mu.Lock()
+	done := s.done
+	s.done = nil
+	s.mu.Unlock()
+
+	if done == nil {
+		return nil
+	}
+	close(done)
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mixer.Clear()
+	return s.close()
+}
+
+// encodePCM16 converts float samples to interleaved signed 16-bit little-endian
+func encodePCM16(buf []byte, samples [][2]float64) {
+	for i := range samples {
+		for c := range samples[i] {
+			val := samples[i][c]
+			if val < -1 {
+				val = -1
+			}
+			if val > 1 {
+				val = 1
+			}
+			binary.LittleEndian.PutUint16(buf[i*4+c*2:], uint16(int16(val*(1<<15-1))))
+		}
+	}
+}
+
+// NewPipeSink writes raw PCM (s16le, stereo) to a named pipe, e.g. a
+// Snapcast server's snapfifo. The pipe is opened read-write so Open does
+// not block while no reader is attached.
+func NewPipeSink(name, path string) AudioSink {
+	s := &writerSink{name: name}
+	var f *os.File
+	s.open = func(rate beep.SampleRate) (io.Writer, error) {
+		var err error
+		f, err = os.OpenFile(path, os.O_RDWR, 0)
+		return f, err
+	}
+	s.close = func() error {
+		return f.Close()
+	}
+	return s
+}
+
+// NewFileSink records everything played to a 16-bit stereo WAV file.
+// The header sizes are patched when the sink is closed.
+func NewFileSink(name, path string) AudioSink {
+	s := &writerSink{name: name}
+	var f *os.File
+	s.open = func(rate beep.SampleRate) (io.Writer, error) {
+		var err error
+		f, err = os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeWAVHeader(f, rate, 0); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return f, nil
+	}
+	s.close = func() error {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			f.Close()
+			return err
+		}
+		if err := writeWAVHeader(f, s.rate, s.written); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	}
+	return s
+}
+
+// NewNullSink plays in real time without making a sound. It keeps the
+// engine's position and track changes going while another device, such as
+// a cast receiver, does the actual playing.
+func NewNullSink(name string) AudioSink {
+	s := &writerSink{name: name}
+	s.open = func(rate beep.SampleRate) (io.Writer, error) { return io.Discard, nil }
+	s.close = func() error { return nil }
+	return s
+}
+
+// writeWAVHeader writes a canonical 44-byte PCM WAV header
+func writeWAVHeader(w io.Writer, rate beep.SampleRate, dataLen int64) error {
+	const channels, bitsPerSample = 2, 16
+	blockAlign := channels * bitsPerSample / 8
+
+	header := make([]byte, 44)
+	copy(header[0:], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:], uint32(36+dataLen))
+	copy(header[8:], "WAVE")
+	copy(header[12:], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:], 16)
+	binary.LittleEndian.PutUint16(header[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(header[22:], channels)
+	binary.LittleEndian.PutUint32(header[24:], uint32(rate))
+	binary.LittleEndian.PutUint32(header[28:], uint32(int(rate)*blockAlign))
+	binary.LittleEndian.PutUint16(header[32:], uint16(blockAlign))
+	binary.LittleEndian.PutUint16(header[34:], bitsPerSample)
+	copy(header[36:], "data")
+	binary.LittleEndian.PutUint32(header[40:], uint32(dataLen))
+
+	_, err := w.Write(header)
+	return err
+}
+
+// SinkTrim adjusts a single output relative to the others: GainDB is a
+// volume trim applied on top of the player volume, Delay is the output's
+// latency, and Curve is how the volume level maps to gain on that output.
+//
+// Delay is how long the output takes to make audio heard, such as a TV's
+// buffer when casting or a Bluetooth speaker's. The engine reports the
+// position that much behind what it decodes, so the progress bar, lyrics
+// and seeks follow what is heard rather than what was sent.
+type SinkTrim struct {
+	GainDB float64
+	Delay  time.Duration
+	Curve  VolumeCurve
+}
+
+// IsZero reports whether the trim leaves the stream untouched
+func (t SinkTrim) IsZero() bool {
+	return t.GainDB == 0 && t.Delay <= 0 && (t.Curve == "" || t.Curve == CurveLog)
+}
+
+// String formats the trim for display, e.g. "-3.0 dB, +120ms"
+func (t SinkTrim) String() string {
+	var parts []string
+	if t.GainDB != 0 {
+		parts = append(parts, fmt.Sprintf("%+.1f dB", t.GainDB))
+	}
+	if t.Delay > 0 {
+		parts = append(parts, fmt.Sprintf("+%dms", t.Delay.Milliseconds()))
+	}
+	if t.Curve != "" && t.Curve != CurveLog {
+		parts = append(parts, string(t.Curve)+" volume")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// apply wraps s with the trim's gain
+func (t SinkTrim) apply(s beep.Streamer) beep.Streamer {
+	if t.GainDB != 0 {
+		s = &effects.Gain{Streamer: s, Gain: dbToGain(t.GainDB)}
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+)
+
+// VolumeCurve maps the 0-1 volume level to a gain
+type VolumeCurve string
+
+const (
+	// CurveLog spreads the level evenly over volumeRangeDB, so each step
+	// sounds about as loud as the last. It is the default.
+	CurveLog VolumeCurve = "log"
+	// CurveLinear scales the amplitude with the level, for outputs whose
+	// own volume control already applies a loudness curve
+	CurveLinear VolumeCurve = "linear"
+)
+
+// volumeMaxDB is the gain at full volume, leaving headroom for quiet tracks
+const volumeMaxDB = 6.0
+
+// volumeRangeDB is the span of the log curve from full volume down to the
+// smallest step above silence
+const volumeRangeDB = 60.0
+
+// ParseVolumeCurve reads a curve name from the config; empty is CurveLog
+func ParseVolumeCurve(name string) (VolumeCurve, error) {
+	switch c := VolumeCurve(name); c {
+	case "", CurveLog:
+		return CurveLog, nil
+	case CurveLinear:
+		return c, nil
+	}
+	return "", fmt.Errorf("unknown volume curve %q (want %q or %q)", name, CurveLog, CurveLinear)
+}
+
+// VolumeDB returns the gain in dB of level on curve. Level 0 is silence,
+// reported as meterFloorDB.
+func VolumeDB(level float64, curve VolumeCurve) float64 {
+	if level <= 0 {
+		return meterFloorDB
+	}
+	level = math.Min(level, 1)
+	if curve == CurveLinear {
+		return math.Max(volumeMaxDB+20*math.Log10(level), meterFloorDB)
+	}
+	return volumeMaxDB - volumeRangeDB*(1-level)
+}
@@ -0,0 +1,171 @@
+// Package audiobook handles long-form playback: resume positions that
+// survive restarts, chapter navigation and bookmarks.
+package audiobook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/store"
+)
+
+// finishedSlack is how close to the end a saved position may be before the
+// book counts as finished and playback starts over instead of resuming
+const finishedSlack = 30 * time.Second
+
+// chapterRestart is how far into a chapter "previous chapter" restarts the
+// current one instead of jumping back
+const chapterRestart = 3 * time.Second
+
+// Store keeps per-file resume positions for audiobooks
+type Store struct {
+	path        string
+	minDuration time.Duration
+
+	mu        sync.Mutex
+	positions map[string]time.Duration // file path -> position
+	dirty     bool
+}
+
+// Load reads the resume file at path, returning an empty store if it does
+// not exist. Tracks at least minDuration long are treated as audiobooks in
+// addition to those with chapters or an "Audiobook" genre; 0 disables the
+// length rule.
+func Load(path string, minDuration time.Duration) (*Store, error) {
+	s := &Store{
+		path:        path,
+		minDuration: minDuration,
+		positions:   make(map[string]time.Duration),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read resume file: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.positions); err != nil {
+		return nil, fmt.Errorf("unmarshal resume positions: %w", err)
+	}
+	return s, nil
+}
+
+// Applies reports whether track gets audiobook treatment
+func (s *Store) Applies(track *api.Track) bool {
+	if s == nil || track == nil || track.FilePath == "" {
+		return false
+	}
+	if len(track.Chapters) > 0 {
+		return true
+	}
+	for _, genre := range library.SplitGenres(track.Genre) {
+		if strings.EqualFold(genre, "audiobook") {
+			return true
+		}
+	}
+	return s.minDuration > 0 && track.Duration >= s.minDuration
+}
+
+// Position returns where to resume track, or 0 to start from the beginning
+func (s *Store) Position(track *api.Track) time.Duration {
+	if !s.Applies(track) {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pos := s.positions[track.FilePath]
+	if track.Duration > 0 && pos >= track.Duration-finishedSlack {
+		return 0
+	}
+	return pos
+}
+
+// Remember records the current position in track
+func (s *Store) Remember(track *api.Track, pos time.Duration) {
+	if !s.Applies(track) || pos <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.positions[track.FilePath] != pos {
+		s.positions[track.FilePath] = pos
+		s.dirty = true
+	}
+}
+
+// Finish forgets the position of a track that played to the end
+func (s *Store) Finish(track *api.Track) {
+	if !s.Applies(track) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.positions[track.FilePath]; ok {
+		delete(s.positions, track.FilePath)
+		s.dirty = true
+	}
+}
+
+// Save writes the positions to disk if they changed since the last save.
+// A read-only instance keeps its positions in memory only.
+func (s *Store) Save() error {
+	if s == nil || store.ReadOnly() {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.positions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal resume positions: %w", err)
+	}
+	if err := store.WriteFile(s.path, data); err != nil {
+		return fmt.Errorf("write resume file: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
+
+// ChapterAt returns the index of the chapter containing pos, or -1
+func ChapterAt(chapters []api.Chapter, pos time.Duration) int {
+	idx := -1
+	for i, ch := range chapters {
+		if ch.Start > pos {
+			break
+		}
+		idx = i
+	}
+	return idx
+}
+
+// NextChapter returns the start of the chapter after the one containing pos
+func NextChapter(chapters []api.Chapter, pos time.Duration) (time.Duration, bool) {
+	i := ChapterAt(chapters, pos) + 1
+	if i >= len(chapters) {
+		return 0, false
+	}
+	return chapters[i].Start, true
+}
+
+// PrevChapter returns the start of the current chapter, or of the one
+// before it when pos is within the first few seconds of the current one
+func PrevChapter(chapters []api.Chapter, pos time.Duration) (time.Duration, bool) {
+	i := ChapterAt(chapters, pos)
+	if i < 0 {
+		return 0, false
+	}
+	if pos-chapters[i].Start < chapterRestart && i > 0 {
+		i--
+	}
+	return chapters[i].Start, true
+}
@@ -0,0 +1,148 @@
+package audiobook
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestStore_Applies verifies which tracks are treated as audiobooks
+func TestStore_Applies(t *testing.T) {
+	s := &Store{minDuration: time.Hour}
+	tests := []struct {
+		name  string
+		track *api.Track
+		want  bool
+	}{
+		{"chapters", &api.Track{FilePath: "a", Chapters: []api.Chapter{{Title: "One"}}}, true},
+		{"genre", &api.Track{FilePath: "a", Genre: "Fiction; audiobook"}, true},
+		{"long", &api.Track{FilePath: "a", Duration: 2 * time.Hour}, true},
+		{"song", &api.Track{FilePath: "a", Genre: "Rock", Duration: 4 * time.Minute}, false},
+		{"stream", &api.Track{Chapters: []api.Chapter{{Title: "One"}}}, false},
+		{"nil", nil, false},
+	}
+	for _, tt := range tests {
+		if got := s.Applies(tt.track); got != tt.want {
+			t.Errorf("%s: Applies = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if (&Store{}).Applies(&api.Track{FilePath: "a", Duration: 10 * time.Hour}) {
+		t.Error("a zero minimum length should not make long tracks audiobooks")
+	}
+	var none *Store
+	if none.Applies(&api.Track{FilePath: "a", Genre: "Audiobook"}) {
+		t.Error("a nil store should apply to nothing")
+	}
+}
+
+// TestStore_Positions verifies positions are remembered per file, survive
+// a save and load, start over near the end and are forgotten on Finish
+func TestStore_Positions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resume.json")
+	s, err := Load(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	book := &api.Track{FilePath: "/books/dune.m4b", Genre: "Audiobook", Duration: 21 * time.Hour}
+	other := &api.Track{FilePath: "/books/emma.mp3", Genre: "Audiobook", Duration: time.Hour}
+	song := &api.Track{FilePath: "/music/song.mp3", Duration: 3 * time.Minute}
+
+	s.Remember(book, 90*time.Minute)
+	s.Remember(other, 59*time.Minute+45*time.Second)
+	s.Remember(song, time.Minute)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Load(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Position(book); got != 90*time.Minute {
+		t.Errorf("book resumes at %v, want 1h30m", got)
+	}
+	if got := s.Position(other); got != 0 {
+		t.Errorf("book within the last seconds resumes at %v, want the start", got)
+	}
+	if got := s.Position(song); got != 0 {
+		t.Errorf("song resumes at %v, want the start", got)
+	}
+
+	s.Finish(book)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ = Load(path, 0); s.Position(book) != 0 {
+		t.Error("finished book still resumes")
+	}
+}
+
+// TestStore_SaveOnlyChanges verifies an unchanged store leaves the file alone
+func TestStore_SaveOnlyChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resume.json")
+	s, _ := Load(path, 0)
+	book := &api.Track{FilePath: "/books/dune.m4b", Genre: "Audiobook"}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("saving no positions wrote a file")
+	}
+	s.Remember(book, time.Minute)
+	s.Save()
+	os.Remove(path)
+	s.Remember(book, time.Minute)
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("saving the same position again wrote the file")
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path, 0); err == nil {
+		t.Error("expected an error for a corrupt file")
+	}
+}
+
+// TestChapterNavigation verifies the chapter at a position and the jumps
+// to the next and previous ones
+func TestChapterNavigation(t *testing.T) {
+	chapters := []api.Chapter{
+		{Title: "One", Start: 10 * time.Second},
+		{Title: "Two", Start: time.Minute},
+		{Title: "Three", Start: 2 * time.Minute},
+	}
+	tests := []struct {
+		pos        time.Duration
+		at         int
+		next, prev time.Duration
+		nextOK     bool
+		prevOK     bool
+	}{
+		{0, -1, 10 * time.Second, 0, true, false},
+		{10 * time.Second, 0, time.Minute, 10 * time.Second, true, true},
+		{61 * time.Second, 1, 2 * time.Minute, 10 * time.Second, true, true},
+		{90 * time.Second, 1, 2 * time.Minute, time.Minute, true, true},
+		{3 * time.Minute, 2, 0, 2 * time.Minute, false, true},
+	}
+	for _, tt := range tests {
+		if got := ChapterAt(chapters, tt.pos); got != tt.at {
+			t.Errorf("ChapterAt(%v) = %d, want %d", tt.pos, got, tt.at)
+		}
+		if got, ok := NextChapter(chapters, tt.pos); got != tt.next || ok != tt.nextOK {
+			t.Errorf("NextChapter(%v) = %v, %v; want %v, %v", tt.pos, got, ok, tt.next, tt.nextOK)
+		}
+		if got, ok := PrevChapter(chapters, tt.pos); got != tt.prev || ok != tt.prevOK {
+			t.Errorf("PrevChapter(%v) = %v, %v; want %v, %v", tt.pos, got, ok, tt.prev, tt.prevOK)
+		}
+	}
+	if _, ok := NextChapter(nil, 0); ok {
+		t.Error("NextChapter without chapters should find none")
+	}
+}
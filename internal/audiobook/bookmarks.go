@@ -0,0 +1,162 @@
+package audiobook
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/store"
+)
+
+// Bookmark is a named position in a track, such as the start of a song
+// in a DJ mix
+type Bookmark struct {
+	Name     string        `json:"name"`
+	Position time.Duration `json:"position"`
+}
+
+// Bookmarks keeps the user's bookmarks per local track, in position order.
+// Unlike resume positions they apply to every track, not only audiobooks.
+// They are keyed by track ID, like ratings and user tags, so removing a
+// track from the library can drop them with Forget.
+type Bookmarks struct {
+	path string
+
+	mu    sync.Mutex
+	marks map[string][]Bookmark // track ID -> bookmarks
+}
+
+// LoadBookmarks reads the bookmark file at path, returning an empty set if
+// it does not exist
+func LoadBookmarks(path string) (*Bookmarks, error) {
+	b := &Bookmarks{path: path, marks: make(map[string][]Bookmark)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read bookmark file: %w", err)
+	}
+	if err := json.Unmarshal(data, &b.marks); err != nil {
+		return nil, fmt.Errorf("unmarshal bookmarks: %w", err)
+	}
+	return b, nil
+}
+
+// lookup returns the bookmarks of track. Bookmarks saved by file path,
+// as they were before they were keyed by track ID, move to the ID here.
+// The caller holds b.mu.
+func (b *Bookmarks) lookup(track *api.Track) []Bookmark {
+	if old, ok := b.marks[track.FilePath]; ok {
+		marks := append(b.marks[track.ID], old...)
+		sort.SliceStable(marks, func(i, j int) bool { return marks[i].Position < marks[j].Position })
+		delete(b.marks, track.FilePath)
+		b.marks[track.ID] = marks
+	}
+	return b.marks[track.ID]
+}
+
+// List returns the bookmarks of track in position order
+func (b *Bookmarks) List(track *api.Track) []Bookmark {
+	if b == nil || track == nil || track.FilePath == "" {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.lookup(track))
+}
+
+// Add bookmarks pos in track under name and saves
+func (b *Bookmarks) Add(track *api.Track, name string, pos time.Duration) error {
+	if b == nil || track == nil || track.FilePath == "" {
+		return nil
+	}
+	b.mu.Lock()
+	marks := append(b.lookup(track), Bookmark{Name: name, Position: pos})
+	sort.SliceStable(marks, func(i, j int) bool { return marks[i].Position < marks[j].Position })
+	b.marks[track.ID] = marks
+	b.mu.Unlock()
+	return b.save()
+}
+
+// Remove deletes the bookmark named name at pos in track and saves
+func (b *Bookmarks) Remove(track *api.Track, name string, pos time.Duration) error {
+	if b == nil || track == nil || track.FilePath == "" {
+		return nil
+	}
+	b.mu.Lock()
+	marks := b.lookup(track)
+	i := slices.Index(marks, Bookmark{Name: name, Position: pos})
+	if i < 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	if marks = slices.Delete(marks, i, i+1); len(marks) == 0 {
+		delete(b.marks, track.ID)
+	} else {
+		b.marks[track.ID] = marks
+	}
+	b.mu.Unlock()
+	return b.save()
+}
+
+// Forget drops every bookmark of the tracks with the given IDs, for tracks
+// removed from the library, and saves if there were any
+func (b *Bookmarks) Forget(ids []string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	n := len(b.marks)
+	for _, id := range ids {
+		delete(b.marks, id)
+	}
+	changed := len(b.marks) != n
+	b.mu.Unlock()
+	if !changed {
+		return nil
+	}
+	return b.save()
+}
+
+// save writes the bookmarks to disk. A read-only instance keeps them in
+// memory only.
+func (b *Bookmarks) save() error {
+	if store.ReadOnly() {
+		return nil
+	}
+	b.mu.Lock()
+	data, err := json.MarshalIndent(b.marks, "", "  ")
+	b.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("marshal bookmarks: %w", err)
+	}
+	return store.WriteFile(b.path, data)
+}
+
+// NextBookmark returns the first bookmark after pos
+func NextBookmark(marks []Bookmark, pos time.Duration) (time.Duration, bool) {
+	for _, m := range marks {
+		if m.Position > pos {
+			return m.Position, true
+		}
+	}
+	return 0, false
+}
+
+// PrevBookmark returns the last bookmark before pos, skipping one just
+// passed so that repeated presses keep going back
+func PrevBookmark(marks []Bookmark, pos time.Duration) (time.Duration, bool) {
+	for i := len(marks) - 1; i >= 0; i-- {
+		if pos-marks[i].Position >= chapterRestart {
+			return marks[i].Position, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,141 @@
+package audiobook
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestBookmarks_AddRemove verifies bookmarks are kept per track in
+// position order, survive a save and load, and that removing the last one
+// of a track drops the track
+func TestBookmarks_AddRemove(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	b, err := LoadBookmarks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mix := &api.Track{ID: "mix", FilePath: "/music/mix.mp3"}
+	other := &api.Track{ID: "other", FilePath: "/music/other.mp3"}
+	for _, m := range []Bookmark{{"Drop", 20 * time.Minute}, {"Intro", 0}, {"Outro", 55 * time.Minute}} {
+		if err := b.Add(mix, m.Name, m.Position); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.Add(other, "Chorus", time.Minute)
+	b.Add(&api.Track{ID: "stream"}, "Nowhere", time.Minute)
+
+	b, err = LoadBookmarks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Bookmark{{"Intro", 0}, {"Drop", 20 * time.Minute}, {"Outro", 55 * time.Minute}}
+	if got := b.List(mix); !slices.Equal(got, want) {
+		t.Errorf("bookmarks = %v, want %v", got, want)
+	}
+
+	b.Remove(mix, "Drop", 20*time.Minute)
+	b.Remove(mix, "Drop", 20*time.Minute)
+	b.Remove(mix, "Intro", time.Second)
+	if got := b.List(mix); !slices.Equal(got, []Bookmark{{"Intro", 0}, {"Outro", 55 * time.Minute}}) {
+		t.Errorf("after remove = %v", got)
+	}
+	b.Remove(other, "Chorus", time.Minute)
+	if _, ok := b.marks[other.ID]; ok {
+		t.Error("a track without bookmarks should be dropped")
+	}
+	if len(b.List(&api.Track{ID: "stream"})) != 0 {
+		t.Error("a stream should have no bookmarks")
+	}
+
+	var none *Bookmarks
+	if none.Add(mix, "x", 0) != nil || none.List(mix) != nil {
+		t.Error("a nil set should do nothing")
+	}
+}
+
+// TestBookmarks_Forget verifies removed tracks lose their bookmarks on disk
+// too, and that forgetting tracks without any leaves the file alone
+func TestBookmarks_Forget(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	b, _ := LoadBookmarks(path)
+	keep := &api.Track{ID: "keep", FilePath: "/music/keep.mp3"}
+	gone := &api.Track{ID: "gone", FilePath: "/music/gone.mp3"}
+	b.Add(keep, "Solo", time.Minute)
+	b.Add(gone, "Solo", time.Minute)
+
+	if err := b.Forget([]string{"gone", "unknown"}); err != nil {
+		t.Fatal(err)
+	}
+	b, _ = LoadBookmarks(path)
+	if len(b.List(gone)) != 0 || len(b.List(keep)) != 1 {
+		t.Errorf("after forget: gone %v, keep %v", b.List(gone), b.List(keep))
+	}
+
+	os.Remove(path)
+	if err := b.Forget([]string{"unknown"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("forgetting nothing should not save")
+	}
+}
+
+// TestBookmarks_ByPath verifies bookmarks saved by file path move to the
+// track's ID
+func TestBookmarks_ByPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bookmarks.json")
+	old := `{"/music/mix.mp3": [{"name": "Drop", "position": 1200000000000}]}`
+	if err := os.WriteFile(path, []byte(old), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadBookmarks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mix := &api.Track{ID: "mix", FilePath: "/music/mix.mp3"}
+	if err := b.Add(mix, "Intro", 0); err != nil {
+		t.Fatal(err)
+	}
+	b, _ = LoadBookmarks(path)
+	want := []Bookmark{{"Intro", 0}, {"Drop", 20 * time.Minute}}
+	if got := b.marks[mix.ID]; !slices.Equal(got, want) {
+		t.Errorf("bookmarks by ID = %v, want %v", got, want)
+	}
+	if _, ok := b.marks[mix.FilePath]; ok {
+		t.Error("bookmarks still saved by path")
+	}
+}
+
+// TestNextPrevBookmark verifies jumping forward takes the first bookmark
+// after the position, and back skips one just passed
+func TestNextPrevBookmark(t *testing.T) {
+	marks := []Bookmark{{"A", 0}, {"B", time.Minute}, {"C", 2 * time.Minute}}
+	tests := []struct {
+		pos        time.Duration
+		next, prev time.Duration
+		hasNext    bool
+		hasPrev    bool
+	}{
+		{0, time.Minute, 0, true, false},
+		{time.Minute, 2 * time.Minute, 0, true, true},
+		{time.Minute + time.Second, 2 * time.Minute, 0, true, true},
+		{time.Minute + 5*time.Second, 2 * time.Minute, time.Minute, true, true},
+		{3 * time.Minute, 0, 2 * time.Minute, false, true},
+	}
+	for _, tt := range tests {
+		if next, ok := NextBookmark(marks, tt.pos); ok != tt.hasNext || next != tt.next {
+			t.Errorf("NextBookmark(%v) = %v, %v; want %v, %v", tt.pos, next, ok, tt.next, tt.hasNext)
+		}
+		if prev, ok := PrevBookmark(marks, tt.pos); ok != tt.hasPrev || prev != tt.prev {
+			t.Errorf("PrevBookmark(%v) = %v, %v; want %v, %v", tt.pos, prev, ok, tt.prev, tt.hasPrev)
+		}
+	}
+	if _, ok := NextBookmark(nil, 0); ok {
+		t.Error("no bookmarks should give no next")
+	}
+}
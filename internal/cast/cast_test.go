@@ -0,0 +1,58 @@
+package cast
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/jscyril/golang_music_player/internal/renderer"
+)
+
+// TestMessageRoundTrip verifies CastMessages decode to what was encoded
+func TestMessageRoundTrip(t *testing.T) {
+	in := message{source: senderID, dest: receiverID, namespace: nsMedia, payload: `{"type":"PLAY"}`}
+	out, err := decodeMessage(encodeMessage(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("decoded %+v, want %+v", out, in)
+	}
+}
+
+// TestDiscoveryAnswers verifies a typical Chromecast mDNS response, with
+// compressed names, yields its friendly name and address
+func TestDiscoveryAnswers(t *testing.T) {
+	instance := "Chromecast-abc123._googlecast._tcp.local."
+	record := func(msg []byte, name string, rtype uint16, data []byte) []byte {
+		msg = appendName(msg, name)
+		msg = binary.BigEndian.AppendUint16(msg, rtype)
+		msg = binary.BigEndian.AppendUint16(msg, 1)
+		msg = binary.BigEndian.AppendUint32(msg, 120)
+		msg = binary.BigEndian.AppendUint16(msg, uint16(len(data)))
+		return append(msg, data...)
+	}
+
+	msg := make([]byte, 12)
+	binary.BigEndian.PutUint16(msg[6:], 1)  // answers
+	binary.BigEndian.PutUint16(msg[10:], 3) // additional records
+	serviceAt := len(msg)
+	ptr := appendName(nil, instance)
+	msg = record(msg, service, typePTR, ptr)
+	// The SRV target points back into the PTR record's name
+	pointer := []byte{0xC0 | byte(serviceAt>>8), byte(serviceAt)}
+	target := append([]byte{4}, "host"...)
+	target = append(target, pointer...)
+	msg = record(msg, instance, typeSRV, append([]byte{0, 0, 0, 0, 0x1F, 0x49}, target...))
+	txt := append([]byte{19}, "fn=Living Room Mini"...)
+	msg = record(msg, instance, typeTXT, append(txt, append([]byte{12}, "md=Nest Mini"...)...))
+	msg = record(msg, "host._googlecast._tcp.local.", typeA, []byte{192, 168, 1, 20})
+
+	a := newAnswers()
+	a.parse(msg, net.IPv4(192, 168, 1, 99))
+	got := a.devices()
+	want := renderer.Device{Name: "Living Room Mini", Model: "Nest Mini", Kind: Kind, Addr: "192.168.1.20:8009"}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("devices = %+v, want %+v", got, want)
+	}
+}
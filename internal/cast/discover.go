@@ -0,0 +1,263 @@
+// Package cast plays tracks on Chromecasts through the Cast v2 protocol.
+// Devices are found with mDNS, and the Default Media Receiver on the
+// device loads each track from a renderer.Server.
+package cast
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jscyril/golang_music_player/internal/renderer"
+)
+
+// service is the DNS-SD service Chromecasts announce
+const service = "_googlecast._tcp.local."
+
+// mdnsAddr is the IPv4 mDNS group
+var mdnsAddr = &net.UDPAddr{IP: net.IPv4(224, 0, 0, 251), Port: 5353}
+
+// DNS record types used by discovery
+const (
+	typeA   = 1
+	typePTR = 12
+	typeTXT = 16
+	typeSRV = 33
+)
+
+// Kind is the renderer.Device kind of Chromecasts
+const Kind = "Chromecast"
+
+// Discover asks the local network for cast devices and collects the
+// answers that arrive within wait, sorted by name
+func Discover(ctx context.Context, wait time.Duration) ([]renderer.Device, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("discover cast devices: %w", err)
+	}
+	defer conn.Close()
+
+	// Queries from a port other than 5353 are answered straight back to
+	// it. The query is repeated once in case the first was lost.
+	query := buildQuery(service)
+	for _, at := range []time.Duration{0, wait / 2} {
+		time.AfterFunc(at, func() { conn.WriteToUDP(query, mdnsAddr) })
+	}
+	deadline := time.Now().Add(wait)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetReadDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	found := newAnswers()
+	buf := make([]byte, 9000)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var timeout net.Error
+			if errors.As(err, &timeout) && timeout.Timeout() {
+				break
+			}
+			return nil, fmt.Errorf("discover cast devices: %w", err)
+		}
+		found.parse(buf[:n], from.IP)
+	}
+	return found.devices(), ctx.Err()
+}
+
+// buildQuery encodes a DNS query for the PTR records of name
+func buildQuery(name string) []byte {
+	msg := make([]byte, 12, 64)
+	binary.BigEndian.PutUint16(msg[4:], 1) // one question
+	msg = appendName(msg, name)
+	msg = binary.BigEndian.AppendUint16(msg, typePTR)
+	return binary.BigEndian.AppendUint16(msg, 1) // class IN
+}
+
+// appendName appends name in DNS label encoding
+func appendName(msg []byte, name string) []byte {
+	for _, label := range strings.Split(strings.TrimSuffix(name, "."), ".") {
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	return append(msg, 0)
+}
+
+// srv is the target of an SRV record
+type srv struct {
+	host string
+	port int
+}
+
+// answers collects the records of every response to a discovery query
+type answers struct {
+	instances []string
+	srv       map[string]srv
+	txt       map[string]map[string]string
+	addrs     map[string]net.IP
+	from      map[string]net.IP // the sender of an instance's records
+}
+
+func newAnswers() *answers {
+	return &answers{
+		srv:   make(map[string]srv),
+		txt:   make(map[string]map[string]string),
+		addrs: make(map[string]net.IP),
+		from:  make(map[string]net.IP),
+	}
+}
+
+// parse adds the records of one DNS response sent by from. Malformed
+// responses are ignored.
+func (a *answers) parse(msg []byte, from net.IP) {
+	if len(msg) < 12 {
+		return
+	}
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	records := int(binary.BigEndian.Uint16(msg[6:])) + int(binary.BigEndian.Uint16(msg[8:])) +
+		int(binary.BigEndian.Uint16(msg[10:]))
+	off := 12
+	for range questions {
+		var err error
+		if _, off, err = readName(msg, off); err != nil || off+4 > len(msg) {
+			return
+		}
+		off += 4
+	}
+	for range records {
+		name, next, err := readName(msg, off)
+		if err != nil || next+10 > len(msg) {
+			return
+		}
+		rtype := binary.BigEndian.Uint16(msg[next:])
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		data := next + 10
+		if data+length > len(msg) {
+			return
+		}
+		a.add(msg, name, rtype, data, length, from)
+		off = data + length
+	}
+}
+
+// add records the resource record of type rtype whose data is
+// msg[data:data+length]
+func (a *answers) add(msg []byte, name string, rtype uint16, data, length int, from net.IP) {
+	switch rtype {
+	case typePTR:
+		if !strings.EqualFold(name, service) {
+			return
+		}
+		instance, _, err := readName(msg, data)
+		if err == nil && !containsFold(a.instances, instance) {
+			a.instances = append(a.instances, instance)
+			a.from[strings.ToLower(instance)] = from
+		}
+	case typeSRV:
+		if length < 7 {
+			return
+		}
+		host, _, err := readName(msg, data+6)
+		if err == nil {
+			a.srv[strings.ToLower(name)] = srv{host: host, port: int(binary.BigEndian.Uint16(msg[data+4:]))}
+		}
+	case typeTXT:
+		values := make(map[string]string)
+		for i := data; i < data+length; {
+			n := int(msg[i])
+			if i+1+n > data+length {
+				break
+			}
+			key, value, _ := strings.Cut(string(msg[i+1:i+1+n]), "=")
+			values[strings.ToLower(key)] = value
+			i += 1 + n
+		}
+		a.txt[strings.ToLower(name)] = values
+	case typeA:
+		if length == 4 {
+			a.addrs[strings.ToLower(name)] = net.IP(msg[data : data+4])
+		}
+	}
+}
+
+// devices returns the instances with a known port, named after their
+// "fn" TXT value
+func (a *answers) devices() []renderer.Device {
+	var list []renderer.Device
+	for _, instance := range a.instances {
+		key := strings.ToLower(instance)
+		target, ok := a.srv[key]
+		if !ok {
+			continue
+		}
+		ip := a.addrs[strings.ToLower(target.host)]
+		if ip == nil {
+			ip = a.from[key]
+		}
+		txt := a.txt[key]
+		name := txt["fn"]
+		if name == "" {
+			name, _, _ = strings.Cut(instance, ".")
+		}
+		list = append(list, renderer.Device{
+			Name:  name,
+			Model: txt["md"],
+			Kind:  Kind,
+			Addr:  net.JoinHostPort(ip.String(), strconv.Itoa(target.port)),
+		})
+	}
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name) })
+	return list
+}
+
+// readName decodes the possibly compressed name at msg[off:] and returns
+// it with the offset just past it
+func readName(msg []byte, off int) (string, int, error) {
+	var labels []string
+	end := -1
+	for jumps := 0; ; {
+		if off >= len(msg) {
+			return "", 0, errors.New("dns name out of range")
+		}
+		n := int(msg[off])
+		switch {
+		case n == 0:
+			if end < 0 {
+				end = off + 1
+			}
+			return strings.Join(labels, ".") + ".", end, nil
+		case n&0xC0 == 0xC0:
+			if off+1 >= len(msg) || jumps > 10 {
+				return "", 0, errors.New("bad dns name pointer")
+			}
+			if end < 0 {
+				end = off + 2
+			}
+			off = int(binary.BigEndian.Uint16(msg[off:]) & 0x3FFF)
+			jumps++
+		default:
+			if off+1+n > len(msg) {
+				return "", 0, errors.New("dns label out of range")
+			}
+			labels = append(labels, string(msg[off+1:off+1+n]))
+			off += 1 + n
+		}
+	}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
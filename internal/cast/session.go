@@ -0,0 +1,526 @@
+package cast
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/renderer"
+)
+
+// Namespaces of the cast protocol's channels
+const (
+	nsConnection = "urn:x-cast:com.google.cast.tp.connection"
+	nsHeartbeat  = "urn:x-cast:com.google.cast.tp.heartbeat"
+	nsReceiver   = "urn:x-cast:com.google.cast.receiver"
+	nsMedia      = "urn:x-cast:com.google.cast.media"
+)
+
+const (
+	senderID   = "sender-0"
+	receiverID = "receiver-0"
+
+	// mediaReceiverApp is the Default Media Receiver, which plays a URL
+	mediaReceiverApp = "CC1AD845"
+
+	heartbeatInterval = 5 * time.Second
+	// idleTimeout is how long the device may stay silent, heartbeats
+	// included, before the session is given up
+	idleTimeout    = 3 * heartbeatInterval
+	requestTimeout = 15 * time.Second
+	maxMessageSize = 64 << 10
+)
+
+// ErrClosed is returned once the device has ended the session, e.g.
+// because another app was cast to it
+var ErrClosed = errors.New("cast session closed by the device")
+
+// Session is a connection to the media receiver app on a device. It is a
+// renderer.Renderer.
+type Session struct {
+	device   renderer.Device
+	server   *renderer.Server
+	conn     net.Conn
+	wmu      sync.Mutex
+	lastRead atomic.Int64 // unix nanoseconds
+
+	mu        sync.Mutex
+	transport string // the receiver app's destination ID
+	sessionID string
+	nextID    int
+	pending   map[int]chan reply
+	mediaID   int // mediaSessionId of the loaded media, 0 if none
+	track     *api.Track
+	state     mediaStatus // last status of the loaded media
+	err       error
+	done      chan struct{}
+}
+
+// reply is the part of an incoming payload the session looks at
+type reply struct {
+	Type      string          `json:"type"`
+	RequestID int             `json:"requestId"`
+	Status    json.RawMessage `json:"status"`
+	Reason    string          `json:"reason"`
+}
+
+// receiverStatus is the status of a RECEIVER_STATUS reply
+type receiverStatus struct {
+	Applications []struct {
+		AppID       string `json:"appId"`
+		SessionID   string `json:"sessionId"`
+		TransportID string `json:"transportId"`
+	} `json:"applications"`
+}
+
+// mediaStatus is one entry of a MEDIA_STATUS reply
+type mediaStatus struct {
+	MediaSessionID int     `json:"mediaSessionId"`
+	PlayerState    string  `json:"playerState"`
+	CurrentTime    float64 `json:"currentTime"`
+}
+
+// Dial connects to d and launches its media receiver app. Tracks are
+// served to it from srv.
+func Dial(ctx context.Context, d renderer.Device, srv *renderer.Server) (*Session, error) {
+	// Receivers present certificates signed by Google's device CA, which
+	// is not in the system roots
+	dialer := &tls.Dialer{Config: &tls.Config{InsecureSkipVerify: true}}
+	conn, err := dialer.DialContext(ctx, "tcp", d.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", d.Name, err)
+	}
+	s := &Session{
+		device:  d,
+		server:  srv,
+		conn:    conn,
+		pending: make(map[int]chan reply),
+		done:    make(chan struct{}),
+	}
+	s.lastRead.Store(time.Now().UnixNano())
+	go s.readLoop()
+	go s.heartbeat()
+
+	if err := s.launch(); err != nil {
+		s.close(err)
+		return nil, fmt.Errorf("start receiver on %s: %w", d.Name, err)
+	}
+	return s, nil
+}
+
+// launch starts the media receiver and connects to it
+func (s *Session) launch() error {
+	if err := s.send(receiverID, nsConnection, map[string]any{"type": "CONNECT"}); err != nil {
+		return err
+	}
+	r, err := s.request(receiverID, nsReceiver, map[string]any{"type": "LAUNCH", "appId": mediaReceiverApp})
+	if err != nil {
+		return err
+	}
+	var status receiverStatus
+	json.Unmarshal(r.Status, &status)
+	s.mu.Lock()
+	for _, app := range status.Applications {
+		if app.AppID == mediaReceiverApp {
+			s.transport, s.sessionID = app.TransportID, app.SessionID
+		}
+	}
+	s.mu.Unlock()
+	if s.transport == "" {
+		return errors.New("receiver app did not start")
+	}
+	return s.send(s.transport, nsConnection, map[string]any{"type": "CONNECT"})
+}
+
+// Device returns the device the session is connected to
+func (s *Session) Device() renderer.Device {
+	return s.device
+}
+
+// Play loads t and starts it
+func (s *Session) Play(t *api.Track) error {
+	m, err := renderer.Publish(s.server, t, s.device.Addr)
+	if err != nil {
+		return err
+	}
+	if err := s.load(m, 0, true); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.track = t
+	s.mu.Unlock()
+	return nil
+}
+
+// load plays m from start, or leaves it paused there unless autoplay
+func (s *Session) load(m renderer.Media, start time.Duration, autoplay bool) error {
+	_, err := s.request(s.transport, nsMedia, map[string]any{
+		"type": "LOAD",
+		"media": map[string]any{
+			"contentId":   m.URL,
+			"contentType": m.ContentType,
+			"streamType":  "BUFFERED",
+			"metadata": map[string]any{
+				"metadataType": 3, // music track
+				"title":        m.Title,
+				"artist":       m.Artist,
+				"albumName":    m.Album,
+			},
+		},
+		"autoplay":    autoplay,
+		"currentTime": start.Seconds(),
+	})
+	return err
+}
+
+// Resume resumes the loaded media
+func (s *Session) Resume() error {
+	return s.mediaCommand(map[string]any{"type": "PLAY"})
+}
+
+// Pause pauses the loaded media
+func (s *Session) Pause() error {
+	return s.mediaCommand(map[string]any{"type": "PAUSE"})
+}
+
+// Seek moves the loaded media to pos
+func (s *Session) Seek(pos time.Duration) error {
+	return s.mediaCommand(map[string]any{"type": "SEEK", "currentTime": pos.Seconds()})
+}
+
+// Stop unloads the media, leaving the receiver app running
+func (s *Session) Stop() error {
+	return s.mediaCommand(map[string]any{"type": "STOP"})
+}
+
+// SetVolume sets the device's volume, from 0 to 1
+func (s *Session) SetVolume(level float64) error {
+	_, err := s.request(receiverID, nsReceiver, map[string]any{
+		"type":   "SET_VOLUME",
+		"volume": map[string]any{"level": min(max(level, 0), 1)},
+	})
+	return err
+}
+
+// GetState returns the loaded track and where the device last reported
+// playing it
+func (s *Session) GetState() *api.PlaybackState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state := &api.PlaybackState{Output: s.device.Name}
+	if s.mediaID == 0 {
+		return state
+	}
+	state.CurrentTrack = s.track
+	state.Position = time.Duration(s.state.CurrentTime * float64(time.Second))
+	switch s.state.PlayerState {
+	case "PLAYING", "BUFFERING":
+		state.Status = api.StatusPlaying
+	case "PAUSED":
+		state.Status = api.StatusPaused
+	}
+	return state
+}
+
+// mediaCommand sends a command for the loaded media; without one it does
+// nothing
+func (s *Session) mediaCommand(payload map[string]any) error {
+	s.mu.Lock()
+	id := s.mediaID
+	s.mu.Unlock()
+	if id == 0 {
+		return nil
+	}
+	payload["mediaSessionId"] = id
+	_, err := s.request(s.transport, nsMedia, payload)
+	return err
+}
+
+// Close stops the receiver app, so the device returns to its idle
+// screen, and disconnects
+func (s *Session) Close() error {
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	s.mu.Lock()
+	s.nextID++
+	stop := map[string]any{"type": "STOP", "sessionId": s.sessionID, "requestId": s.nextID}
+	s.mu.Unlock()
+	err := s.send(receiverID, nsReceiver, stop)
+	s.close(nil)
+	return err
+}
+
+// Done is closed when the session has ended
+func (s *Session) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns why the session ended; nil if it is open or was closed by
+// Close
+func (s *Session) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// close ends the session with err, once
+func (s *Session) close(err error) {
+	s.mu.Lock()
+	select {
+	case <-s.done:
+		s.mu.Unlock()
+		return
+	default:
+	}
+	s.err = err
+	close(s.done)
+	s.mu.Unlock()
+	s.conn.Close()
+}
+
+// request sends payload with a new request ID and waits for the reply to
+// it. Error replies from the device are returned as errors.
+func (s *Session) request(dest, namespace string, payload map[string]any) (reply, error) {
+	s.mu.Lock()
+	s.nextID++
+	id := s.nextID
+	ch := make(chan reply, 1)
+	s.pending[id] = ch
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+
+	payload["requestId"] = id
+	if err := s.send(dest, namespace, payload); err != nil {
+		return reply{}, err
+	}
+	select {
+	case r := <-ch:
+		switch r.Type {
+		case "LOAD_FAILED", "LOAD_CANCELLED", "INVALID_REQUEST", "INVALID_PLAYER_STATE", "LAUNCH_ERROR":
+			if r.Reason != "" {
+				return r, fmt.Errorf("%s: %s", r.Type, r.Reason)
+			}
+			return r, errors.New(r.Type)
+		}
+		return r, nil
+	case <-s.done:
+		if err := s.Err(); err != nil {
+			return reply{}, err
+		}
+		return reply{}, ErrClosed
+	case <-time.After(requestTimeout):
+		return reply{}, fmt.Errorf("%s: no reply from %s", payload["type"], s.device.Name)
+	}
+}
+
+// send writes one message with a JSON payload
+func (s *Session) send(dest, namespace string, payload map[string]any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	msg := encodeMessage(message{source: senderID, dest: dest, namespace: namespace, payload: string(data)})
+	frame := binary.BigEndian.AppendUint32(nil, uint32(len(msg)))
+
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	s.conn.SetWriteDeadline(time.Now().Add(requestTimeout))
+	if _, err := s.conn.Write(append(frame, msg...)); err != nil {
+		return fmt.Errorf("send to %s: %w", s.device.Name, err)
+	}
+	return nil
+}
+
+// readLoop handles incoming messages until the connection fails
+func (s *Session) readLoop() {
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(s.conn, header); err != nil {
+			s.close(fmt.Errorf("connection to %s lost: %w", s.device.Name, err))
+			return
+		}
+		size := binary.BigEndian.Uint32(header)
+		if size > maxMessageSize {
+			s.close(fmt.Errorf("message of %d bytes from %s", size, s.device.Name))
+			return
+		}
+		buf := make([]byte, size)
+		if _, err := io.ReadFull(s.conn, buf); err != nil {
+			s.close(fmt.Errorf("connection to %s lost: %w", s.device.Name, err))
+			return
+		}
+		s.lastRead.Store(time.Now().UnixNano())
+		msg, err := decodeMessage(buf)
+		if err != nil {
+			continue
+		}
+		s.handle(msg)
+	}
+}
+
+// handle answers heartbeats, hands replies to their requests and follows
+// the receiver and media status
+func (s *Session) handle(msg message) {
+	var r reply
+	if json.Unmarshal([]byte(msg.payload), &r) != nil {
+		return
+	}
+	s.mu.Lock()
+	transport, sessionID := s.transport, s.sessionID
+	s.mu.Unlock()
+	switch {
+	case msg.namespace == nsHeartbeat && r.Type == "PING":
+		s.send(msg.source, nsHeartbeat, map[string]any{"type": "PONG"})
+		return
+	case msg.namespace == nsConnection && r.Type == "CLOSE" && msg.source == transport:
+		s.close(ErrClosed)
+		return
+	case msg.namespace == nsMedia && r.Type == "MEDIA_STATUS":
+		var status []mediaStatus
+		json.Unmarshal(r.Status, &status)
+		s.mu.Lock()
+		s.mediaID = 0
+		for _, st := range status {
+			if st.PlayerState != "IDLE" {
+				s.mediaID, s.state = st.MediaSessionID, st
+			}
+		}
+		s.mu.Unlock()
+	case msg.namespace == nsReceiver && r.Type == "RECEIVER_STATUS" && r.RequestID == 0 && transport != "":
+		// Another app taking over the device ends this session
+		var status receiverStatus
+		json.Unmarshal(r.Status, &status)
+		running := false
+		for _, app := range status.Applications {
+			running = running || app.SessionID == sessionID
+		}
+		if !running {
+			s.close(ErrClosed)
+			return
+		}
+	}
+
+	s.mu.Lock()
+	ch := s.pending[r.RequestID]
+	s.mu.Unlock()
+	if ch != nil && r.RequestID != 0 {
+		select {
+		case ch <- r:
+		default:
+		}
+	}
+}
+
+// heartbeat pings the device and ends the session when it stops answering
+func (s *Session) heartbeat() {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+			if time.Since(time.Unix(0, s.lastRead.Load())) > idleTimeout {
+				s.close(fmt.Errorf("%s stopped responding", s.device.Name))
+				return
+			}
+			s.send(receiverID, nsHeartbeat, map[string]any{"type": "PING"})
+		}
+	}
+}
+
+// message is a CastMessage with a UTF-8 payload
+type message struct {
+	source    string
+	dest      string
+	namespace string
+	payload   string
+}
+
+// CastMessage field numbers
+const (
+	fieldProtocolVersion = 1
+	fieldSourceID        = 2
+	fieldDestinationID   = 3
+	fieldNamespace       = 4
+	fieldPayloadType     = 5
+	fieldPayloadUTF8     = 6
+)
+
+// encodeMessage encodes m as a CastMessage protocol buffer
+func encodeMessage(m message) []byte {
+	var b []byte
+	b = appendVarintField(b, fieldProtocolVersion, 0) // CASTV2_1_0
+	b = appendStringField(b, fieldSourceID, m.source)
+	b = appendStringField(b, fieldDestinationID, m.dest)
+	b = appendStringField(b, fieldNamespace, m.namespace)
+	b = appendVarintField(b, fieldPayloadType, 0) // STRING
+	return appendStringField(b, fieldPayloadUTF8, m.payload)
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3))
+	return binary.AppendUvarint(b, v)
+}
+
+func appendStringField(b []byte, field int, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|2))
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// decodeMessage decodes a CastMessage, skipping fields it does not use
+func decodeMessage(b []byte) (message, error) {
+	var m message
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return m, errors.New("bad field key")
+		}
+		b = b[n:]
+		field, wire := int(key>>3), key&7
+		switch wire {
+		case 0:
+			if _, n = binary.Uvarint(b); n <= 0 {
+				return m, errors.New("bad varint")
+			}
+			b = b[n:]
+		case 2:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return m, errors.New("bad field length")
+			}
+			value := string(b[n : n+int(size)])
+			b = b[n+int(size):]
+			switch field {
+			case fieldSourceID:
+				m.source = value
+			case fieldDestinationID:
+				m.dest = value
+			case fieldNamespace:
+				m.namespace = value
+			case fieldPayloadUTF8:
+				m.payload = value
+			}
+		default:
+			return m, fmt.Errorf("unsupported wire type %d", wire)
+		}
+	}
+	return m, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // Config holds application configuration
@@ -12,10 +13,336 @@ type Config struct {
 	MusicDirectories []string `json:"music_directories"`
 	DefaultVolume    float64  `json:"default_volume"`
 	Theme            string   `json:"theme"`
+	DynamicAccent    bool     `json:"dynamic_accent"` // tint the player with the album art's color (dark theme)
 	KeyBindings      KeyMap   `json:"key_bindings"`
 	EnableCache      bool     `json:"enable_cache"`
 	CachePath        string   `json:"cache_path"`
 	DataDir          string   `json:"data_dir"`
+
+	// Accessible announces track changes as plain text on a line of their
+	// own and draws the UI without emoji or box drawing, for screen readers
+	Accessible bool `json:"accessible,omitempty"`
+
+	// Language selects the UI's translation, e.g. "de"; empty follows the
+	// LANG environment variable
+	Language string `json:"language,omitempty"`
+
+	// ScanWorkers is how many files a library scan reads at once
+	ScanWorkers int `json:"scan_workers"`
+
+	// InhibitSleep keeps the system from idling to sleep while playing
+	InhibitSleep bool `json:"inhibit_sleep"`
+	// InhibitScreenLock also keeps the screen on and unlocked while playing
+	InhibitScreenLock bool `json:"inhibit_screen_lock"`
+
+	// PauseOnSuspend pauses playback when the machine wakes from suspend
+	PauseOnSuspend bool `json:"pause_on_suspend"`
+	// PauseOnUnplug pauses playback when an audio device disappears
+	PauseOnUnplug bool `json:"pause_on_unplug"`
+	// ResumeOnReplug resumes playback paused by an unplug when the device returns
+	ResumeOnReplug bool `json:"resume_on_replug"`
+
+	// DuckDB is how far the volume drops when ducking is triggered
+	DuckDB float64 `json:"duck_db"`
+
+	// OutputSampleRate is the rate audio outputs are opened at; tracks at
+	// other rates are resampled to it
+	OutputSampleRate int `json:"output_sample_rate"`
+
+	// Limiter pulls output peaks below full scale instead of letting them
+	// clip when volume and output trim add up to more than the track allows
+	Limiter bool `json:"limiter"`
+
+	// CrossfadeSeconds overlaps consecutive tracks; 0 disables crossfading.
+	// Consecutive tracks of one album and tracks tagged gapless never fade.
+	CrossfadeSeconds float64 `json:"crossfade_seconds"`
+
+	// ReadAheadSeconds is how much audio is decoded ahead of playback, so
+	// files on network shares play through slow reads; 0 uses the default
+	// of 10 and -1 turns it off
+	ReadAheadSeconds float64 `json:"read_ahead_seconds,omitempty"`
+
+	// PreloadMB reads tracks of up to this many megabytes into memory as
+	// they start, so the disk can spin down while they play and seeks are
+	// instant; 0 turns it off
+	PreloadMB int `json:"preload_mb,omitempty"`
+
+	// PlaybackErrors sets what happens when a track cannot be played
+	PlaybackErrors PlaybackErrors `json:"playback_errors"`
+
+	// AudiobookMinMinutes is the length from which a track remembers its
+	// position across restarts (tracks with chapters always do); 0 disables
+	AudiobookMinMinutes int `json:"audiobook_min_minutes"`
+
+	// A track is listed as frequently skipped once it has been abandoned
+	// within the first SkipPercent of playback at least SkipCount times.
+	// The defaults here are the only ones, the play history has none. A
+	// SkipPercent of 0 turns skip detection off.
+	SkipPercent int `json:"skip_percent"`
+	SkipCount   int `json:"skip_count"`
+
+	// RemoteSources are additional servers searched alongside the local library
+	RemoteSources []RemoteSource `json:"remote_sources"`
+
+	// OutputSinks are extra audio outputs that playback can be switched to
+	OutputSinks []OutputSink `json:"output_sinks"`
+
+	// Webhooks are notified of track and queue changes
+	Webhooks []Webhook `json:"webhooks"`
+
+	// Alerts ring the terminal bell or flash the screen on events
+	Alerts Alerts `json:"alerts"`
+
+	// UpNext announces the next track near the end of the current one
+	UpNext UpNext `json:"up_next"`
+
+	// MetadataLookup suggests tags for badly tagged files from MusicBrainz
+	MetadataLookup MetadataLookup `json:"metadata_lookup"`
+
+	// Streaming holds the streaming accounts playlists can be exported to
+	Streaming Streaming `json:"streaming"`
+
+	// FileBrowser holds the library file browser's bookmarks
+	FileBrowser FileBrowser `json:"file_browser"`
+
+	// TrackColumns picks the columns of each view's track list
+	TrackColumns TrackColumns `json:"track_columns"`
+
+	// Layout sets up the split library and queue layout
+	Layout Layout `json:"layout"`
+
+	// PlaylistDuplicates keeps tracks from being added twice to a playlist
+	PlaylistDuplicates PlaylistDuplicates `json:"playlist_duplicates"`
+
+	// SkipConfirm lists the actions no longer confirmed, added when
+	// "don't ask again" is chosen: "remove_tracks", "delete_playlist"
+	SkipConfirm []string `json:"skip_confirm,omitempty"`
+
+	// CastPort is the port the file being cast to a Chromecast or DLNA
+	// renderer is served on; 0 picks a free one
+	CastPort int `json:"cast_port,omitempty"`
+
+	// MediaServer shares the library with other devices on the network
+	MediaServer MediaServer `json:"media_server"`
+
+	// APIServer serves the library to other players over the REST API
+	APIServer APIServer `json:"api_server"`
+
+	// TerminalTitle shows the current track in the terminal or tmux pane
+	// title, e.g. "▶ Artist – Title"
+	TerminalTitle bool `json:"terminal_title"`
+
+	// GlobalHotkeys control playback while another window has the focus
+	GlobalHotkeys GlobalHotkeys `json:"global_hotkeys"`
+
+	// Schedule holds the alarms and quiet hours, kept while the UI runs
+	Schedule Schedule `json:"schedule"`
+
+	// LogLevel is the least severe level written to player.log in the data
+	// directory: "debug", "info" (the default), "warn" or "error"
+	LogLevel string `json:"log_level"`
+}
+
+// PlaybackErrors sets what happens when a track fails to open or decode.
+// The player moves on to the next track unless Stop is set, and stops
+// after MaxSkips failures in a row (10 if 0). A file failing with an error
+// that tends to pass, like a network share reconnecting, is tried Retries
+// more times (2 if 0, -1 for none) first. Failed tracks are marked broken
+// in the library until they play again.
+type PlaybackErrors struct {
+	Stop     bool `json:"stop"`
+	MaxSkips int  `json:"max_skips,omitempty"`
+	Retries  int  `json:"retries,omitempty"`
+}
+
+// PlaylistDuplicates sets whether adding a track a playlist already holds
+// is refused. Prevent applies to every playlist but those in Playlists,
+// which maps a playlist's ID or name to whether it prevents them; a name
+// is updated when the playlist is renamed. Duplicates that are allowed
+// are added with a warning.
+type PlaylistDuplicates struct {
+	Prevent   bool            `json:"prevent"`
+	Playlists map[string]bool `json:"playlists,omitempty"`
+}
+
+// GlobalHotkeys are system-wide shortcuts such as "ctrl+alt+p", "super+f9"
+// or "media_next"; empty ones are not registered
+type GlobalHotkeys struct {
+	PlayPause string `json:"play_pause,omitempty"`
+	Next      string `json:"next,omitempty"`
+	Previous  string `json:"previous,omitempty"`
+}
+
+// Schedule starts playlists at set times and stops playback during quiet
+// hours. Times are "HH:MM" in local time. Days name the days a schedule
+// applies on, such as "mon", "friday", "weekdays" or "weekends"; none
+// means every day.
+type Schedule struct {
+	Alarms     []Alarm      `json:"alarms,omitempty"`
+	QuietHours []QuietHours `json:"quiet_hours,omitempty"`
+}
+
+// Alarm plays Playlist (a name or ID), shuffled if Shuffle is set, at Time,
+// fading the volume in from silence to Volume (0 to 1; 0 keeps the current
+// volume) over RampSeconds (60 if 0, -1 for none). Disabled alarms are
+// listed but do not go off.
+type Alarm struct {
+	Name        string   `json:"name,omitempty"`
+	Time        string   `json:"time"`
+	Days        []string `json:"days,omitempty"`
+	Playlist    string   `json:"playlist"`
+	Shuffle     bool     `json:"shuffle,omitempty"`
+	Volume      float64  `json:"volume,omitempty"`
+	RampSeconds int      `json:"ramp_seconds,omitempty"`
+	Disabled    bool     `json:"disabled,omitempty"`
+}
+
+// QuietHours stop playback when they begin, from Start to End; a period
+// ending before it starts runs past midnight, and Days are the days it
+// starts on. Playback can still be started by hand.
+type QuietHours struct {
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+	Days  []string `json:"days,omitempty"`
+}
+
+// APIServer serves the track listing and /api/stream of the REST API when
+// Enabled, on Port (8080 if 0), so other players can use this library as
+// a remote source. Requests must carry Token as a bearer token if it is
+// set. Streams are sent as the files are unless Transcode is "mp3" or
+// "opus", at Bitrate kbit/s (128 if 0); clients can ask for either.
+type APIServer struct {
+	Enabled   bool   `json:"enabled"`
+	Port      int    `json:"port,omitempty"`
+	Token     string `json:"token,omitempty"`
+	Transcode string `json:"transcode,omitempty"`
+	Bitrate   int    `json:"bitrate,omitempty"`
+}
+
+// MediaServer shares the library as a DLNA/UPnP media server when Enabled,
+// announced as Name ("gtmpc on <host>" if empty) and served on Port (0
+// picks a free one)
+type MediaServer struct {
+	Enabled bool   `json:"enabled"`
+	Name    string `json:"name,omitempty"`
+	Port    int    `json:"port,omitempty"`
+}
+
+// Layout starts the UI with the library and queue side by side when
+// SplitPane is set. SplitPercent is the library's share of the width,
+// 25 to 75; 0 splits in half.
+type Layout struct {
+	SplitPane    bool `json:"split_pane"`
+	SplitPercent int  `json:"split_percent,omitempty"`
+}
+
+// TrackColumns lists the columns shown by the library, queue and playlist
+// track lists, in order, from "index", "track", "title", "artist", "album",
+// "year", "duration", "format", "bitrate", "samplerate", "channels", "codec"
+// and "size". An empty list keeps the default.
+type TrackColumns struct {
+	Library  []string `json:"library,omitempty"`
+	Queue    []string `json:"queue,omitempty"`
+	Playlist []string `json:"playlist,omitempty"`
+}
+
+// FileBrowser keeps the folders bookmarked in the file browser, reached
+// there with the number keys
+type FileBrowser struct {
+	Bookmarks []string `json:"bookmarks"`
+}
+
+// MetadataLookup enables the online tag lookup. With an AcoustIDKey and
+// Chromaprint's fpcalc installed, files are identified by fingerprint;
+// otherwise MusicBrainz is searched by title.
+type MetadataLookup struct {
+	Enabled     bool   `json:"enabled"`
+	AcoustIDKey string `json:"acoustid_key"`
+}
+
+// Streaming holds the accounts playlists are exported to with "s" in the
+// playlist view. An account without its credentials is left out.
+type Streaming struct {
+	Spotify    SpotifyAccount    `json:"spotify"`
+	AppleMusic AppleMusicAccount `json:"apple_music"`
+}
+
+// SpotifyAccount is a Spotify app's ClientID and ClientSecret and a
+// RefreshToken the account granted the app with the
+// playlist-modify-private scope
+type SpotifyAccount struct {
+	ClientID     string `json:"client_id,omitempty"`
+	ClientSecret string `json:"client_secret,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+// AppleMusicAccount is a MusicKit DeveloperToken, the UserToken the account
+// granted it and the account's Storefront country code ("us" if empty)
+type AppleMusicAccount struct {
+	DeveloperToken string `json:"developer_token,omitempty"`
+	UserToken      string `json:"user_token,omitempty"`
+	Storefront     string `json:"storefront,omitempty"`
+}
+
+// UpNext shows "Up next" in the player during the last Seconds of a track
+// (0 disables it). Notify also sends a desktop notification.
+type UpNext struct {
+	Seconds int  `json:"seconds"`
+	Notify  bool `json:"notify"`
+}
+
+// Alerts picks how errors and track changes are signalled: "bell",
+// "flash", "both", or "" for nothing
+type Alerts struct {
+	Error       string `json:"error"`
+	TrackChange string `json:"track_change"`
+}
+
+// Webhook describes an outbound HTTP notification.
+// Events lists any of "track_start", "track_stop", "queue_change" (empty
+// means all). Template is a Go text/template for the request body; when empty
+// the event is posted as JSON.
+type Webhook struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Template    string   `json:"template"`
+	ContentType string   `json:"content_type"`
+}
+
+// OutputSink describes an audio output.
+// Type is "file" (WAV recorder), "pipe" (raw PCM FIFO, e.g. Snapcast), or
+// "speaker" or "cast" to set the trim of the built-in speaker output or of
+// casting to a network device.
+// TrimDB levels outputs with each other, and DelayMS is the output's
+// latency, which the reported position is held back by so progress,
+// lyrics and seeks match what is heard there.
+// VolumeCurve is "log" (default) or "linear" for outputs that apply their
+// own loudness curve.
+type OutputSink struct {
+	Name        string  `json:"name"`
+	Type        string  `json:"type"`
+	Path        string  `json:"path"`
+	TrimDB      float64 `json:"trim_db"`
+	DelayMS     int     `json:"delay_ms"`
+	VolumeCurve string  `json:"volume_curve,omitempty"`
+}
+
+// RemoteSource describes a remote server to include in searches. Type is
+// "gtmpc" (default), another player's API server signed in to with Token,
+// or "subsonic", a Subsonic-compatible server signed in to with User and
+// Password.
+type RemoteSource struct {
+	Name      string `json:"name"`
+	Type      string `json:"type,omitempty"`
+	URL       string `json:"url"`
+	Token     string `json:"token"`
+	User      string `json:"user,omitempty"`
+	Password  string `json:"password,omitempty"`
+	TimeoutMS int    `json:"timeout_ms"` // per-source search timeout; 0 uses the default
+	// Bitrate asks the server to transcode streams to this many kbit/s
+	// (MP3 from a gtmpc server), for slow links; 0 plays the files as
+	// they are
+	Bitrate int `json:"bitrate,omitempty"`
 }
 
 // KeyMap defines keyboard shortcuts
@@ -32,23 +359,41 @@ type KeyMap struct {
 	Search      string `json:"search"`
 	Library     string `json:"library"`
 	Playlist    string `json:"playlist"`
+
+	// Bindings sets the keys of any action by name, replacing its defaults,
+	// e.g. {"library.mark": ["x"], "play_pause": ["space", "k"]}
+	Bindings map[string][]string `json:"bindings,omitempty"`
 }
 
 // GetDefaultConfig returns default configuration
 func GetDefaultConfig() *Config {
 	return &Config{
-		MusicDirectories: []string{},
-		DefaultVolume:    0.5,
-		Theme:            "dark",
-		EnableCache:      true,
-		CachePath:        ".cache/musicplayer",
-		DataDir:          "./data",
+		MusicDirectories:    []string{},
+		DefaultVolume:       0.5,
+		Theme:               "dark",
+		DynamicAccent:       true,
+		EnableCache:         true,
+		CachePath:           ".cache/musicplayer",
+		DataDir:             "./data",
+		ScanWorkers:         4,
+		InhibitSleep:        true,
+		InhibitScreenLock:   true,
+		PauseOnSuspend:      true,
+		PauseOnUnplug:       true,
+		DuckDB:              12,
+		OutputSampleRate:    44100,
+		AudiobookMinMinutes: 30,
+		SkipPercent:         20,
+		SkipCount:           3,
+		RemoteSources:       []RemoteSource{},
+		OutputSinks:         []OutputSink{},
+		Webhooks:            []Webhook{},
 		KeyBindings: KeyMap{
 			PlayPause:   " ",
 			Stop:        "s",
 			Next:        "n",
 			Previous:    "p",
-			VolumeUp:    "+",
+			VolumeUp:    "=",
 			VolumeDown:  "-",
 			SeekForward: "right",
 			SeekBack:    "left",
@@ -70,15 +415,19 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	var config Config
-	if err := json.Unmarshal(data, &config); err != nil {
+	// Settings missing from the file, such as those added since it was
+	// written, keep their defaults
+	config := GetDefaultConfig()
+	if err := json.Unmarshal(data, config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	return &config, nil
+	return config, nil
 }
 
-// SaveConfig marshals and saves configuration to file
+// SaveConfig marshals and saves configuration to file. The file holds
+// account secrets and tokens, so only the user may read it; a file
+// written before with wider permissions is narrowed.
 func SaveConfig(config *Config, path string) error {
 	// Ensure directory exists
 	dir := filepath.Dir(path)
@@ -91,14 +440,20 @@ func SaveConfig(config *Config, path string) error {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := os.WriteFile(path, data, 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
+	if err := os.Chmod(path, 0600); err != nil {
+		return fmt.Errorf("failed to restrict config file: %w", err)
+	}
 
 	return nil
 }
 
-// LoadOrCreate loads config from path or creates default if not exists
+// LoadOrCreate loads config from path or creates default if not exists.
+// A config that loads but fails Validate is returned together with its
+// *ValidationError, so callers can report the problems and decide whether
+// to go on.
 func LoadOrCreate(path string) (*Config, error) {
 	config, err := LoadConfig(path)
 	if err != nil {
@@ -112,7 +467,34 @@ func LoadOrCreate(path string) (*Config, error) {
 		}
 	}
 
-	return config, nil
+	return config, config.Validate()
+}
+
+// ProfilePath returns the config file of a named profile, kept in a
+// profiles directory next to the main config file
+func ProfilePath(basePath, name string) string {
+	return filepath.Join(filepath.Dir(basePath), "profiles", name+".json")
+}
+
+// LoadProfile loads the config of a named profile. A profile that does not
+// exist yet is created from base with its own data directory, so it starts
+// with the same settings but a separate library and playlists.
+func LoadProfile(basePath string, base *Config, name string) (*Config, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid profile name %q", name)
+	}
+
+	path := ProfilePath(basePath, name)
+	if _, err := os.Stat(path); err == nil {
+		return LoadConfig(path)
+	}
+
+	profile := *base
+	profile.DataDir = filepath.Join(base.DataDir, "profiles", name)
+	if err := SaveConfig(&profile, path); err != nil {
+		return nil, fmt.Errorf("failed to save profile config: %w", err)
+	}
+	return &profile, nil
 }
 
 // GetConfigPath returns the default config file path
@@ -2,8 +2,10 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
+	"runtime"
 	"testing"
 )
 
@@ -165,6 +167,30 @@ func TestSaveLoadConfig(t *testing.T) {
 	}
 }
 
+// TestSaveConfigPrivate verifies the config, which holds secrets, is only
+// readable by the user, also when it was written with wider permissions
+func TestSaveConfigPrivate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file modes are not enforced on Windows")
+	}
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := GetDefaultConfig()
+	cfg.Streaming.Spotify.ClientSecret = "secret"
+	if err := SaveConfig(cfg, configPath); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	info, err := os.Stat(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("config saved with mode %v, want -rw-------", mode)
+	}
+}
+
 // TestLoadConfigNotExists tests loading non-existent config
 func TestLoadConfigNotExists(t *testing.T) {
 	config, err := LoadConfig("/non/existent/path.json")
@@ -178,6 +204,63 @@ func TestLoadConfigNotExists(t *testing.T) {
 	}
 }
 
+// oldConfig is a config file as written before settings such as
+// inhibit_sleep were added
+const oldConfig = `{
+  "music_directories": ["/home/user/Music"],
+  "default_volume": 0.7,
+  "theme": "dark",
+  "key_bindings": {"play_pause": " ", "quit": "q"},
+  "enable_cache": true,
+  "cache_path": ".cache/musicplayer",
+  "data_dir": "./data"
+}`
+
+// TestLoadConfigKeepsNewDefaults verifies that settings missing from an
+// older config file keep their defaults instead of reading as zero
+func TestLoadConfigKeepsNewDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(oldConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.DefaultVolume != 0.7 {
+		t.Errorf("DefaultVolume = %v, want the file's 0.7", config.DefaultVolume)
+	}
+	if !config.InhibitSleep {
+		t.Error("InhibitSleep lost its default")
+	}
+	if !config.InhibitScreenLock {
+		t.Error("InhibitScreenLock lost its default")
+	}
+	if !config.DynamicAccent {
+		t.Error("DynamicAccent lost its default")
+	}
+	if !config.PauseOnSuspend || !config.PauseOnUnplug {
+		t.Error("PauseOnSuspend and PauseOnUnplug lost their defaults")
+	}
+	if config.DuckDB != 12 {
+		t.Errorf("DuckDB = %v, want 12; 0 would turn ducking off", config.DuckDB)
+	}
+	if config.AudiobookMinMinutes != 30 {
+		t.Errorf("AudiobookMinMinutes = %d, want 30", config.AudiobookMinMinutes)
+	}
+	if config.KeyBindings.Next != "n" {
+		t.Errorf("KeyBindings.Next = %q, want the default n", config.KeyBindings.Next)
+	}
+
+	// Saving it back, as the UI does when a setting changes, keeps them
+	if err := SaveConfig(config, path); err != nil {
+		t.Fatal(err)
+	}
+	if config, err = LoadConfig(path); err != nil || !config.InhibitSleep {
+		t.Errorf("after saving: InhibitSleep = %v, %v", config.InhibitSleep, err)
+	}
+}
+
 // TestInvalidJSON tests error handling for malformed JSON
 func TestInvalidJSON(t *testing.T) {
 	invalidJSON := `{"music_directories": [1, 2, 3], "volume":}`
@@ -210,3 +293,76 @@ func TestGetDefaultConfig(t *testing.T) {
 		t.Errorf("Expected default quit 'q', got %s", config.KeyBindings.Quit)
 	}
 }
+
+// TestLoadProfile verifies a new profile inherits settings with its own data directory
+func TestLoadProfile(t *testing.T) {
+	base := GetDefaultConfig()
+	base.DefaultVolume = 0.8
+	basePath := filepath.Join(t.TempDir(), "config.json")
+
+	profile, err := LoadProfile(basePath, base, "work")
+	if err != nil {
+		t.Fatalf("LoadProfile failed: %v", err)
+	}
+	if profile.DefaultVolume != 0.8 {
+		t.Errorf("Expected inherited volume 0.8, got %f", profile.DefaultVolume)
+	}
+	if want := filepath.Join(base.DataDir, "profiles", "work"); profile.DataDir != want {
+		t.Errorf("Expected data dir %s, got %s", want, profile.DataDir)
+	}
+
+	// Later loads read the saved profile rather than the base
+	base.DefaultVolume = 0.2
+	profile, err = LoadProfile(basePath, base, "work")
+	if err != nil {
+		t.Fatalf("LoadProfile failed: %v", err)
+	}
+	if profile.DefaultVolume != 0.8 {
+		t.Errorf("Expected saved volume 0.8, got %f", profile.DefaultVolume)
+	}
+
+	if _, err := LoadProfile(basePath, base, "../escape"); err == nil {
+		t.Error("Expected error for a profile name with a path")
+	}
+}
+
+// TestValidate verifies every kind of problem is reported with its field,
+// and that only the serious ones are fatal
+func TestValidate(t *testing.T) {
+	dir := t.TempDir()
+	config := GetDefaultConfig()
+	config.DataDir = filepath.Join(dir, "data")
+	config.CachePath = filepath.Join(dir, "cache")
+	if err := config.Validate(); err != nil {
+		t.Fatalf("default config with writable dirs: %v", err)
+	}
+
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.DefaultVolume = 1.5
+	config.MusicDirectories = []string{dir, filepath.Join(dir, "missing")}
+	config.KeyBindings.Stop = " "
+	config.DataDir = file
+
+	var invalid *ValidationError
+	if !errors.As(config.Validate(), &invalid) {
+		t.Fatal("expected a *ValidationError")
+	}
+	fields := make(map[string]bool)
+	for _, p := range invalid.Problems {
+		fields[p.Field] = true
+	}
+	for _, want := range []string{"default_volume", "music_directories[1]", "key_bindings.stop", "data_dir"} {
+		if !fields[want] {
+			t.Errorf("no problem reported for %s in %v", want, invalid)
+		}
+	}
+	if fields["music_directories[0]"] || len(invalid.Problems) != 4 {
+		t.Errorf("unexpected problems: %v", invalid)
+	}
+	if !invalid.Fatal() {
+		t.Error("volume and data dir problems should be fatal")
+	}
+}
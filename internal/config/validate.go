@@ -0,0 +1,220 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// confirmActions are the actions skip_confirm may name, as the UI's
+// confirmation prompts call them
+var confirmActions = []string{"remove_tracks", "delete_playlist"}
+
+// Problem is one invalid setting. Fatal problems keep the player from
+// starting; the rest are warnings, e.g. a music directory on a drive that
+// is not mounted right now.
+type Problem struct {
+	Field   string // JSON name of the setting, e.g. "music_directories[1]"
+	Message string
+	Fatal   bool
+}
+
+func (p Problem) String() string {
+	return p.Field + ": " + p.Message
+}
+
+// ValidationError lists every problem Validate found
+type ValidationError struct {
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.String()
+	}
+	return "invalid config: " + strings.Join(lines, "; ")
+}
+
+// Fatal reports whether any problem keeps the player from starting
+func (e *ValidationError) Fatal() bool {
+	for _, p := range e.Problems {
+		if p.Fatal {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks settings that would otherwise misbehave later: the
+// volume range, music directories, clashing key bindings, and whether the
+// data and cache directories can be written. It returns a
+// *ValidationError listing every problem, or nil.
+func (c *Config) Validate() error {
+	var problems []Problem
+	add := func(field string, fatal bool, format string, args ...any) {
+		problems = append(problems, Problem{Field: field, Message: fmt.Sprintf(format, args...), Fatal: fatal})
+	}
+
+	if c.DefaultVolume < 0 || c.DefaultVolume > 1 {
+		add("default_volume", true, "%v is outside 0.0 to 1.0", c.DefaultVolume)
+	}
+
+	for i, dir := range c.MusicDirectories {
+		field := fmt.Sprintf("music_directories[%d]", i)
+		info, err := os.Stat(dir)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			add(field, false, "%s does not exist", dir)
+		case err != nil:
+			add(field, false, "%v", err)
+		case !info.IsDir():
+			add(field, false, "%s is not a directory", dir)
+		}
+	}
+
+	if p := c.Layout.SplitPercent; p != 0 && (p < 25 || p > 75) {
+		add("layout.split_percent", false, "%d is outside 25 to 75", p)
+	}
+
+	if c.CastPort < 0 || c.CastPort > 65535 {
+		add("cast_port", false, "%d is not a port number", c.CastPort)
+	}
+	if p := c.MediaServer.Port; p < 0 || p > 65535 {
+		add("media_server.port", false, "%d is not a port number", p)
+	}
+	if p := c.APIServer.Port; p < 0 || p > 65535 {
+		add("api_server.port", false, "%d is not a port number", p)
+	}
+	switch c.APIServer.Transcode {
+	case "", "mp3", "opus":
+	default:
+		add("api_server.transcode", false, "unknown format %q, want \"mp3\" or \"opus\"", c.APIServer.Transcode)
+	}
+	if s := c.ReadAheadSeconds; s < 0 && s != -1 {
+		add("read_ahead_seconds", false, "%v is negative; -1 turns the read-ahead off", s)
+	}
+	if n := c.PreloadMB; n < 0 {
+		add("preload_mb", false, "%d is negative", n)
+	}
+	if n := c.PlaybackErrors.MaxSkips; n < 0 {
+		add("playback_errors.max_skips", false, "%d is negative", n)
+	}
+	if n := c.PlaybackErrors.Retries; n < -1 {
+		add("playback_errors.retries", false, "%d is below -1", n)
+	}
+	switch strings.ToLower(c.LogLevel) {
+	case "", "debug", "info", "warn", "warning", "error":
+	default:
+		add("log_level", false, "unknown level %q, want \"debug\", \"info\", \"warn\" or \"error\"", c.LogLevel)
+	}
+
+	problems = append(problems, c.KeyBindings.duplicates()...)
+
+	for i, action := range c.SkipConfirm {
+		if !slices.Contains(confirmActions, action) {
+			add(fmt.Sprintf("skip_confirm[%d]", i), false, "unknown action %q", action)
+		}
+	}
+
+	if c.DataDir == "" {
+		add("data_dir", true, "is empty")
+	} else if err := writableDir(c.DataDir); err != nil {
+		add("data_dir", true, "%v", err)
+	}
+	if c.EnableCache && c.CachePath != "" {
+		if err := writableDir(c.CachePath); err != nil {
+			add("cache_path", false, "%v", err)
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+// duplicates reports keys given to more than one of the named key_bindings
+// fields, and keys listed twice for one action in bindings. Clashes between
+// bindings entries depend on the view and are checked by the keymap.
+func (km KeyMap) duplicates() []Problem {
+	fields := []struct{ name, key string }{
+		{"play_pause", km.PlayPause}, {"stop", km.Stop}, {"next", km.Next},
+		{"previous", km.Previous}, {"volume_up", km.VolumeUp}, {"volume_down", km.VolumeDown},
+		{"seek_forward", km.SeekForward}, {"seek_back", km.SeekBack}, {"quit", km.Quit},
+		{"search", km.Search}, {"library", km.Library}, {"playlist", km.Playlist},
+	}
+
+	var problems []Problem
+	owner := make(map[string]string)
+	for _, f := range fields {
+		if f.key == "" {
+			continue
+		}
+		key := f.key
+		if key == "space" {
+			key = " "
+		}
+		if prev, ok := owner[key]; ok {
+			problems = append(problems, Problem{
+				Field:   "key_bindings." + f.name,
+				Message: fmt.Sprintf("key %q is already bound to %s", f.key, prev),
+				Fatal:   true,
+			})
+			continue
+		}
+		owner[key] = f.name
+	}
+
+	actions := make([]string, 0, len(km.Bindings))
+	for action := range km.Bindings {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		seen := make(map[string]bool)
+		for _, k := range km.Bindings[action] {
+			if seen[k] {
+				problems = append(problems, Problem{
+					Field:   "key_bindings.bindings." + action,
+					Message: fmt.Sprintf("key %q is listed twice", k),
+				})
+			}
+			seen[k] = true
+		}
+	}
+	return problems
+}
+
+// writableDir checks that files can be created in dir, or in its nearest
+// existing parent when dir has not been created yet
+func writableDir(dir string) error {
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			break
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return err
+		}
+		dir = parent
+	}
+
+	f, err := os.CreateTemp(dir, ".write-test-*")
+	if err != nil {
+		return fmt.Errorf("%s is not writable: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
@@ -0,0 +1,144 @@
+// Package crash turns a panic into a crash report instead of a stack
+// trace on a terminal left in raw mode. The report holds the panic, the
+// stack, the player state registered with AddState and the latest log
+// entries; hooks registered with OnExit save what can be saved and give
+// the terminal back before the process exits.
+package crash
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jscyril/golang_music_player/internal/logger"
+)
+
+// logEntries is how many of the latest log entries a report includes
+const logEntries = 50
+
+// state is a named part of the player's state for reports
+type state struct {
+	name string
+	dump func() any
+}
+
+var (
+	mu     sync.Mutex
+	dir    string
+	states []state
+	hooks  []func()
+	once   sync.Once
+)
+
+// SetDir sets the directory reports are written to, the temp directory
+// until it is set
+func SetDir(d string) {
+	mu.Lock()
+	dir = d
+	mu.Unlock()
+}
+
+// AddState adds a part of the player's state to reports, as the JSON
+// encoding of what dump returns
+func AddState(name string, dump func() any) {
+	mu.Lock()
+	states = append(states, state{name, dump})
+	mu.Unlock()
+}
+
+// OnExit registers f to run after a report is written and before the
+// process exits, in the order registered
+func OnExit(f func()) {
+	mu.Lock()
+	hooks = append(hooks, f)
+	mu.Unlock()
+}
+
+// Guard, deferred at the top of a goroutine, turns a panic in it into a
+// crash report and exits
+func Guard() {
+	if r := recover(); r != nil {
+		Exit(r, debug.Stack())
+	}
+}
+
+// Exit writes a report for the panic value r and its stack, runs the
+// OnExit hooks, tells the user where the report is and exits with status 2.
+// When several goroutines crash at once the first one reports; the others
+// wait for it to exit.
+func Exit(r any, stack []byte) {
+	first := false
+	once.Do(func() { first = true })
+	if !first {
+		select {}
+	}
+
+	path, err := Report(r, stack)
+	mu.Lock()
+	run := hooks
+	mu.Unlock()
+	for _, f := range run {
+		func() {
+			defer func() { recover() }() // a broken hook must not stop the exit
+			f()
+		}()
+	}
+
+	fmt.Fprintf(os.Stderr, "\ngtmpc crashed: %v\n", r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "The crash report could not be written (%v):\n\n%s\n", err, stack)
+	} else {
+		fmt.Fprintf(os.Stderr, "A report with the details is in %s;\nplease attach it when reporting the problem.\n", path)
+	}
+	logger.Close()
+	os.Exit(2)
+}
+
+// Report writes a crash report for the panic value r and its stack and
+// returns its path
+func Report(r any, stack []byte) (string, error) {
+	mu.Lock()
+	d, parts := dir, states
+	mu.Unlock()
+	if d == "" {
+		d = os.TempDir()
+	}
+	now := time.Now()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "gtmpc crashed at %s\n\npanic: %v\n\n%s\n", now.Format(time.RFC3339), r, stack)
+	for _, s := range parts {
+		fmt.Fprintf(&sb, "\n== %s ==\n%s\n", s.name, dumpState(s.dump))
+	}
+	sb.WriteString("\n== log ==\n")
+	entries := logger.Recent()
+	for _, e := range entries[max(len(entries)-logEntries, 0):] {
+		fmt.Fprintf(&sb, "%s %-5s %s %s\n", e.Time.Format("15:04:05.000"), e.Level, e.Source, e.Message)
+	}
+
+	path := filepath.Join(d, "crash-"+now.Format("20060102-150405")+".txt")
+	if err := os.WriteFile(path, []byte(sb.String()), 0644); err != nil {
+		return "", fmt.Errorf("write crash report: %w", err)
+	}
+	logger.Fatal("PANIC: %v (report: %s)", r, path)
+	return path, nil
+}
+
+// dumpState encodes one part of the state, or why it could not be
+func dumpState(dump func() any) (out string) {
+	defer func() {
+		if r := recover(); r != nil {
+			out = fmt.Sprintf("(unavailable: %v)", r)
+		}
+	}()
+	data, err := json.MarshalIndent(dump(), "", "  ")
+	if err != nil {
+		return fmt.Sprintf("(unavailable: %v)", err)
+	}
+	return string(data)
+}
@@ -0,0 +1,35 @@
+package crash
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jscyril/golang_music_player/internal/logger"
+)
+
+// TestReport verifies a report holds the panic, the stack, each state and
+// the latest log entries, even when a state cannot be dumped
+func TestReport(t *testing.T) {
+	SetDir(t.TempDir())
+	AddState("queue", func() any { return map[string]int{"index": 3} })
+	AddState("broken", func() any { panic("no state") })
+	logger.Info("about to crash")
+
+	path, err := Report("boom", []byte("goroutine 1 [running]:"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(path)
+	for _, want := range []string{
+		"panic: boom",
+		"goroutine 1 [running]:",
+		"== queue ==\n{\n  \"index\": 3\n}",
+		"== broken ==\n(unavailable: no state)",
+		"INFO  crash_test.go:17 about to crash",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("report lacks %q:\n%s", want, data)
+		}
+	}
+}
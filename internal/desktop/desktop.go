@@ -0,0 +1,166 @@
+// Package desktop registers the player with freedesktop.org desktops
+// (GNOME, KDE, Xfce and the like): a .desktop entry that opens audio files
+// in gtmpc and MIME associations making it their default application, so
+// double-clicking a song in a file manager plays it.
+package desktop
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jscyril/golang_music_player/internal/store"
+)
+
+// EntryName is the file name, and desktop ID, of the entry
+const EntryName = "gtmpc.desktop"
+
+// MIMETypes are the file types the entry opens, those the player decodes
+var MIMETypes = []string{"audio/mpeg", "audio/flac", "audio/x-wav"}
+
+// Entry returns the contents of the .desktop file running command, the
+// player's path and any arguments, with the files opened appended. The
+// player is a terminal program, so desktops open it in a terminal.
+func Entry(command []string) string {
+	quoted := make([]string, len(command))
+	for i, arg := range command {
+		quoted[i] = quoteExec(arg)
+	}
+	var sb strings.Builder
+	sb.WriteString("[Desktop Entry]\n")
+	sb.WriteString("Type=Application\n")
+	sb.WriteString("Name=gtmpc\n")
+	sb.WriteString("GenericName=Music Player\n")
+	sb.WriteString("Comment=Play music in the terminal\n")
+	fmt.Fprintf(&sb, "Exec=%s %%F\n", strings.Join(quoted, " "))
+	sb.WriteString("Terminal=true\n")
+	sb.WriteString("Categories=AudioVideo;Audio;Player;\n")
+	fmt.Fprintf(&sb, "MimeType=%s;\n", strings.Join(MIMETypes, ";"))
+	return sb.String()
+}
+
+// quoteExec quotes an Exec argument as the desktop entry spec asks
+func quoteExec(arg string) string {
+	arg = strings.ReplaceAll(arg, "%", "%%")
+	if !strings.ContainsAny(arg, " \t\n\"'\\><~|&;$*?#()`") {
+		return arg
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`", `$`, `\$`)
+	// The key's value is itself unescaped once before the quoting is read
+	return strings.ReplaceAll(`"`+r.Replace(arg)+`"`, `\`, `\\`)
+}
+
+// Install writes the entry for command to the user's applications
+// directory and makes it the default application for MIMETypes in the
+// user's mimeapps.list, keeping every other association there. It returns
+// the files written.
+func Install(command []string) ([]string, error) {
+	apps := filepath.Join(dataHome(), "applications")
+	entry := filepath.Join(apps, EntryName)
+	if err := os.MkdirAll(apps, 0755); err != nil {
+		return nil, fmt.Errorf("create applications directory: %w", err)
+	}
+	if err := os.WriteFile(entry, []byte(Entry(command)), 0644); err != nil {
+		return nil, fmt.Errorf("write desktop entry: %w", err)
+	}
+
+	list := filepath.Join(configHome(), "mimeapps.list")
+	data, err := os.ReadFile(list)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("read mimeapps.list: %w", err)
+	}
+	if err := store.WriteFile(list, setDefaults(data, EntryName, MIMETypes)); err != nil {
+		return nil, fmt.Errorf("write mimeapps.list: %w", err)
+	}
+
+	// Desktops that cache the MIME types of entries see the new one once
+	// the cache is rebuilt; others scan the directory themselves
+	if bin, err := exec.LookPath("update-desktop-database"); err == nil {
+		exec.Command(bin, apps).Run()
+	}
+	return []string{entry, list}, nil
+}
+
+// setDefaults returns the mimeapps.list data with app as the default for
+// types and first among their added associations
+func setDefaults(data []byte, app string, types []string) []byte {
+	var lines []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		lines = append(lines, sc.Text())
+	}
+	for _, t := range types {
+		lines = setKey(lines, "Default Applications", t, func(string) string {
+			return app + ";"
+		})
+		lines = setKey(lines, "Added Associations", t, func(old string) string {
+			apps := []string{app}
+			for _, a := range strings.Split(old, ";") {
+				if a != "" && a != app {
+					apps = append(apps, a)
+				}
+			}
+			return strings.Join(apps, ";") + ";"
+		})
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// setKey sets key in section of the ini lines to value(old value),
+// adding the key, or the section, if it is missing
+func setKey(lines []string, section, key string, value func(old string) string) []string {
+	header := "[" + section + "]"
+	start := -1
+	end := len(lines) // the line after the section
+	for i, l := range lines {
+		l = strings.TrimSpace(l)
+		if start < 0 {
+			if l == header {
+				start = i
+			}
+			continue
+		}
+		if strings.HasPrefix(l, "[") {
+			end = i
+			break
+		}
+		if k, v, ok := strings.Cut(l, "="); ok && strings.TrimSpace(k) == key {
+			lines[i] = key + "=" + value(strings.TrimSpace(v))
+			return lines
+		}
+	}
+	if start < 0 {
+		if len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) != "" {
+			lines = append(lines, "")
+		}
+		return append(lines, header, key+"="+value(""))
+	}
+	// Add the key at the end of the section, before its blank lines
+	for end > start+1 && strings.TrimSpace(lines[end-1]) == "" {
+		end--
+	}
+	return slices.Insert(lines, end, key+"="+value(""))
+}
+
+// dataHome is $XDG_DATA_HOME, ~/.local/share by default
+func dataHome() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".local", "share")
+}
+
+// configHome is $XDG_CONFIG_HOME, ~/.config by default
+func configHome() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return dir
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config")
+}
@@ -0,0 +1,36 @@
+package desktop
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestInstall verifies the entry is written and made the default for the
+// audio types while other associations in mimeapps.list are kept, also
+// when installing twice
+func TestInstall(t *testing.T) {
+	data, conf := t.TempDir(), t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+	t.Setenv("XDG_CONFIG_HOME", conf)
+	t.Setenv("PATH", "")
+	list := filepath.Join(conf, "mimeapps.list")
+	os.WriteFile(list, []byte("[Default Applications]\ntext/plain=vim.desktop;\naudio/mpeg=vlc.desktop;\n\n[Added Associations]\naudio/mpeg=vlc.desktop;\n"), 0644)
+
+	for range 2 {
+		if _, err := Install([]string{"/opt/my player/gtmpc", "--profile", "50%"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entry, _ := os.ReadFile(filepath.Join(data, "applications", EntryName))
+	if !strings.Contains(string(entry), `Exec="/opt/my player/gtmpc" --profile 50%% %F`) {
+		t.Errorf("entry:\n%s", entry)
+	}
+	want := "[Default Applications]\ntext/plain=vim.desktop;\naudio/mpeg=gtmpc.desktop;\naudio/flac=gtmpc.desktop;\naudio/x-wav=gtmpc.desktop;\n\n" +
+		"[Added Associations]\naudio/mpeg=gtmpc.desktop;vlc.desktop;\naudio/flac=gtmpc.desktop;\naudio/x-wav=gtmpc.desktop;\n"
+	if got, _ := os.ReadFile(list); string(got) != want {
+		t.Errorf("mimeapps.list:\n%s\nwant:\n%s", got, want)
+	}
+}
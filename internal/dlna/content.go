@@ -0,0 +1,155 @@
+package dlna
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/renderer"
+)
+
+// UPnP classes of the media server's containers
+const (
+	folderClass = "object.container.storageFolder"
+	artistClass = "object.container.person.musicArtist"
+	albumClass  = "object.container.album.musicAlbum"
+)
+
+// object is an entry of the content tree. IDs are paths: "artists",
+// "artists/<artist>/<album>/<track>", "albums/<album>/<track>",
+// "tracks/<track>", with artist names path-escaped, so an object's parent
+// is its ID up to the last slash and "0", the root, above the top level.
+type object struct {
+	id       string
+	parentID string
+	title    string
+	class    string // container class, "" for a track
+	children int
+	albumID  string     // album whose cover is shown, if it has one
+	track    *api.Track // set for tracks
+}
+
+// lookup returns the object with the given ID
+func (s *MediaServer) lookup(id string) (object, bool) {
+	if id == "0" {
+		return object{id: "0", parentID: "-1", title: s.name, class: folderClass, children: 3}, true
+	}
+	parent := "0"
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		parent = id[:i]
+	}
+	siblings, _ := s.children(parent)
+	for _, o := range siblings {
+		if o.id == id {
+			return o, true
+		}
+	}
+	return object{}, false
+}
+
+// children lists the objects in the container with the given ID, built
+// from the library's artist and album indices; false if there is no such
+// container
+func (s *MediaServer) children(id string) ([]object, bool) {
+	if id == "0" {
+		return []object{
+			{id: "artists", parentID: "0", title: "Artists", class: folderClass, children: len(s.lib.Artists())},
+			{id: "albums", parentID: "0", title: "Albums", class: folderClass, children: len(s.lib.Albums())},
+			{id: "tracks", parentID: "0", title: "All Tracks", class: folderClass, children: len(s.lib.GetAllTracks())},
+		}, true
+	}
+
+	parts := strings.Split(id, "/")
+	switch {
+	case parts[0] == "artists" && len(parts) == 1:
+		artists := s.lib.Artists()
+		list := make([]object, len(artists))
+		for i, ar := range artists {
+			list[i] = object{
+				id:       id + "/" + url.PathEscape(ar.Name),
+				parentID: id,
+				title:    ar.Name,
+				class:    artistClass,
+				children: ar.AlbumCount,
+			}
+		}
+		return list, true
+	case parts[0] == "artists" && len(parts) == 2:
+		name, err := url.PathUnescape(parts[1])
+		if err != nil {
+			return nil, false
+		}
+		if _, err := s.lib.GetArtist(name); err != nil {
+			return nil, false
+		}
+		return albumObjects(id, s.lib.ArtistAlbums(name)), true
+	case parts[0] == "albums" && len(parts) == 1:
+		return albumObjects(id, s.lib.Albums()), true
+	case parts[0] == "artists" && len(parts) == 3, parts[0] == "albums" && len(parts) == 2:
+		album, err := s.lib.GetAlbum(parts[len(parts)-1])
+		if err != nil {
+			return nil, false
+		}
+		return trackObjects(id, album, s.lib.AlbumTracks(album.ID)), true
+	case parts[0] == "tracks" && len(parts) == 1:
+		return trackObjects(id, nil, s.lib.GetAllTracks()), true
+	}
+	return nil, false
+}
+
+// albumObjects lists albums as containers in parent
+func albumObjects(parent string, albums []*api.Album) []object {
+	list := make([]object, len(albums))
+	for i, a := range albums {
+		list[i] = object{
+			id:       parent + "/" + a.ID,
+			parentID: parent,
+			title:    a.Name,
+			class:    albumClass,
+			children: len(a.TrackIDs),
+		}
+		if a.ArtPath != "" {
+			list[i].albumID = a.ID
+		}
+	}
+	return list
+}
+
+// trackObjects lists tracks in parent, showing the cover of album if it
+// is not nil and has one
+func trackObjects(parent string, album *api.Album, tracks []*api.Track) []object {
+	list := make([]object, 0, len(tracks))
+	for _, t := range tracks {
+		if t == nil {
+			continue
+		}
+		o := object{id: parent + "/" + t.ID, parentID: parent, title: t.Title, track: t}
+		if album != nil && album.ArtPath != "" {
+			o.albumID = album.ID
+		}
+		list = append(list, o)
+	}
+	return list
+}
+
+// write writes o as DIDL-Lite, with URLs under base
+func (o object) write(sb *strings.Builder, base string) {
+	var art string
+	if o.albumID != "" {
+		art = base + "/art/" + url.PathEscape(o.albumID)
+	}
+	if o.track == nil {
+		writeContainer(sb, o.id, o.parentID, o.title, o.class, o.children, art)
+		return
+	}
+	t := o.track
+	writeItem(sb, o.id, o.parentID, renderer.Media{
+		URL:         base + "/media/" + url.PathEscape(t.ID) + strings.ToLower(filepath.Ext(t.FilePath)),
+		ContentType: renderer.ContentType(t.FilePath),
+		Title:       t.Title,
+		Artist:      t.Artist,
+		Album:       t.Album,
+		Duration:    t.Duration,
+	}, art)
+}
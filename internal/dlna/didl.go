@@ -0,0 +1,86 @@
+package dlna
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jscyril/golang_music_player/internal/renderer"
+)
+
+// DIDL-Lite document framing
+const (
+	didlOpen = `<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/"` +
+		` xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">`
+	didlClose = `</DIDL-Lite>`
+)
+
+// didl describes m as a DIDL-Lite music track, the metadata renderers
+// show while playing it
+func didl(m renderer.Media) string {
+	var sb strings.Builder
+	sb.WriteString(didlOpen)
+	writeItem(&sb, "0", "-1", m, "")
+	sb.WriteString(didlClose)
+	return sb.String()
+}
+
+// writeItem writes m as a music track item, with the cover at artURL if
+// not empty. The resource is marked seekable by byte range, which both the
+// renderer file server and the media server support.
+func writeItem(sb *strings.Builder, id, parentID string, m renderer.Media, artURL string) {
+	fmt.Fprintf(sb, `<item id="%s" parentID="%s" restricted="1">`, escape(id), escape(parentID))
+	element(sb, "dc:title", m.Title)
+	element(sb, "upnp:artist", m.Artist)
+	element(sb, "dc:creator", m.Artist)
+	element(sb, "upnp:album", m.Album)
+	element(sb, "upnp:albumArtURI", artURL)
+	sb.WriteString(`<upnp:class>object.item.audioItem.musicTrack</upnp:class>`)
+	fmt.Fprintf(sb, `<res protocolInfo="http-get:*:%s:DLNA.ORG_OP=01"`, escape(m.ContentType))
+	if m.Duration > 0 {
+		fmt.Fprintf(sb, ` duration="%s.000"`, formatTime(m.Duration))
+	}
+	fmt.Fprintf(sb, `>%s</res></item>`, escape(m.URL))
+}
+
+// writeContainer writes a container of class with the given number of
+// children, with the cover at artURL if not empty
+func writeContainer(sb *strings.Builder, id, parentID, title, class string, children int, artURL string) {
+	fmt.Fprintf(sb, `<container id="%s" parentID="%s" restricted="1" searchable="0" childCount="%d">`,
+		escape(id), escape(parentID), children)
+	element(sb, "dc:title", title)
+	element(sb, "upnp:albumArtURI", artURL)
+	fmt.Fprintf(sb, `<upnp:class>%s</upnp:class></container>`, class)
+}
+
+// element writes <name>value</name>, or nothing if value is empty
+func element(sb *strings.Builder, name, value string) {
+	if value != "" {
+		fmt.Fprintf(sb, "<%s>%s</%s>", name, escape(value), name)
+	}
+}
+
+// escape escapes s for XML text and attribute values
+func escape(s string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// formatTime formats d as the H:MM:SS of UPnP time values
+func formatTime(d time.Duration) string {
+	s := int(d.Round(time.Second) / time.Second)
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+}
+
+// parseTime reads a UPnP H:MM:SS time value, with optional fractions of
+// a second; anything else, such as "NOT_IMPLEMENTED", is 0
+func parseTime(s string) time.Duration {
+	var h, m int
+	var sec float64
+	if n, _ := fmt.Sscanf(s, "%d:%d:%f", &h, &m, &sec); n != 3 {
+		return 0
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec*float64(time.Second))
+}
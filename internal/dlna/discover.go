@@ -0,0 +1,202 @@
+// Package dlna plays tracks on DLNA/UPnP media renderers such as smart
+// TVs and AV receivers. Renderers are found with SSDP and driven through
+// their AVTransport and RenderingControl services, loading each track from
+// a renderer.Server. MediaServer works the other way round, sharing the
+// library for devices to browse and stream.
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jscyril/golang_music_player/internal/renderer"
+)
+
+// Kind is the renderer.Device kind of DLNA renderers
+const Kind = "DLNA"
+
+// UPnP types a renderer offers
+const (
+	mediaRendererType = "urn:schemas-upnp-org:device:MediaRenderer:1"
+	avTransportType   = "urn:schemas-upnp-org:service:AVTransport:1"
+	renderingType     = "urn:schemas-upnp-org:service:RenderingControl:1"
+)
+
+// ssdpAddr is the SSDP multicast group
+var ssdpAddr = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+
+// httpClient fetches descriptions and sends control requests
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// Discover searches the local network for media renderers and returns
+// those that answer within wait and can play a URL, sorted by name
+func Discover(ctx context.Context, wait time.Duration) ([]renderer.Device, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("discover dlna renderers: %w", err)
+	}
+	defer conn.Close()
+
+	mx := max(int(wait/time.Second)-1, 1) // seconds devices spread their answers over
+	search := fmt.Sprintf("M-SEARCH * HTTP/1.1\r\nHOST: %s\r\nMAN: \"ssdp:discover\"\r\nMX: %d\r\nST: %s\r\n\r\n",
+		ssdpAddr, mx, mediaRendererType)
+	for _, at := range []time.Duration{0, wait / 3} {
+		time.AfterFunc(at, func() { conn.WriteToUDP([]byte(search), ssdpAddr) })
+	}
+	conn.SetReadDeadline(time.Now().Add(wait))
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	var locations []string
+	buf := make([]byte, 4096)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			var timeout net.Error
+			if errors.As(err, &timeout) && timeout.Timeout() {
+				break
+			}
+			return nil, fmt.Errorf("discover dlna renderers: %w", err)
+		}
+		if loc := searchLocation(buf[:n]); loc != "" && !slices.Contains(locations, loc) {
+			locations = append(locations, loc)
+		}
+	}
+
+	// Descriptions are fetched in parallel; devices that fail to describe
+	// themselves or cannot play a URL are left out
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var list []renderer.Device
+	for _, loc := range locations {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			desc, err := fetchDescription(ctx, loc)
+			if err != nil || desc.service(avTransportType) == nil {
+				return
+			}
+			u, _ := url.Parse(loc)
+			addr := u.Host
+			if u.Port() == "" {
+				addr = net.JoinHostPort(u.Hostname(), "80")
+			}
+			mu.Lock()
+			list = append(list, renderer.Device{
+				Name:     desc.Device.FriendlyName,
+				Model:    desc.Device.ModelName,
+				Kind:     Kind,
+				Addr:     addr,
+				Location: loc,
+			})
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	sort.Slice(list, func(i, j int) bool { return strings.ToLower(list[i].Name) < strings.ToLower(list[j].Name) })
+	return list, ctx.Err()
+}
+
+// searchLocation returns the description URL of an M-SEARCH answer, or
+// "" if msg is not a successful one
+func searchLocation(msg []byte) string {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(msg)), nil)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return ""
+	}
+	resp.Body.Close()
+	return resp.Header.Get("Location")
+}
+
+// description is the part of a UPnP device description used here
+type description struct {
+	URLBase string       `xml:"URLBase"`
+	Device  deviceSchema `xml:"device"`
+}
+
+type deviceSchema struct {
+	FriendlyName string          `xml:"friendlyName"`
+	ModelName    string          `xml:"modelName"`
+	Services     []serviceSchema `xml:"serviceList>service"`
+	Devices      []deviceSchema  `xml:"deviceList>device"`
+}
+
+type serviceSchema struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+// service finds a service of type typ in the device or its embedded
+// devices
+func (d *description) service(typ string) *serviceSchema {
+	var find func(dev *deviceSchema) *serviceSchema
+	find = func(dev *deviceSchema) *serviceSchema {
+		for i := range dev.Services {
+			if dev.Services[i].ServiceType == typ {
+				return &dev.Services[i]
+			}
+		}
+		for i := range dev.Devices {
+			if s := find(&dev.Devices[i]); s != nil {
+				return s
+			}
+		}
+		return nil
+	}
+	return find(&d.Device)
+}
+
+// controlURL resolves the control URL of service typ against the
+// description's base, or its location when it has none
+func (d *description) controlURL(location, typ string) (string, error) {
+	s := d.service(typ)
+	if s == nil {
+		return "", fmt.Errorf("no %s service", typ)
+	}
+	base := d.URLBase
+	if base == "" {
+		base = location
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	ref, err := url.Parse(strings.TrimSpace(s.ControlURL))
+	if err != nil {
+		return "", err
+	}
+	return b.ResolveReference(ref).String(), nil
+}
+
+// fetchDescription loads the device description at location
+func fetchDescription(ctx context.Context, location string) (*description, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch device description: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch device description: %s", resp.Status)
+	}
+	var desc description
+	if err := xml.NewDecoder(resp.Body).Decode(&desc); err != nil {
+		return nil, fmt.Errorf("parse device description: %w", err)
+	}
+	return &desc, nil
+}
@@ -0,0 +1,144 @@
+package dlna
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/renderer"
+)
+
+const testDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <device>
+    <friendlyName>Living Room TV</friendlyName>
+    <deviceList><device>
+      <serviceList><service>
+        <serviceType>urn:schemas-upnp-org:service:AVTransport:1</serviceType>
+        <controlURL>control/avt</controlURL>
+      </service></serviceList>
+    </device></deviceList>
+  </device>
+</root>`
+
+// TestRendererPlay verifies a track is handed to a renderer found in an
+// embedded device as a URI from the file server, then played
+func TestRendererPlay(t *testing.T) {
+	var mu sync.Mutex
+	var actions []string
+	var uri string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/desc.xml", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, testDescription)
+	})
+	mux.HandleFunc("/control/avt", func(w http.ResponseWriter, r *http.Request) {
+		_, action, _ := strings.Cut(strings.Trim(r.Header.Get("SOAPAction"), `"`), "#")
+		values, _ := responseValues(r.Body)
+		mu.Lock()
+		actions = append(actions, action)
+		if action == "SetAVTransportURI" {
+			uri = values["CurrentURI"]
+		}
+		mu.Unlock()
+		io.WriteString(w, `<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body/></s:Envelope>`)
+	})
+	fake := httptest.NewServer(mux)
+	defer fake.Close()
+
+	srv, err := renderer.NewServer(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	path := filepath.Join(t.TempDir(), "song.flac")
+	os.WriteFile(path, []byte("fLaC"), 0644)
+
+	d := renderer.Device{Name: "Living Room TV", Kind: Kind, Addr: fake.Listener.Addr().String(), Location: fake.URL + "/desc.xml"}
+	r, err := Open(context.Background(), d, srv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.Play(&api.Track{ID: "1", Title: "Song", FilePath: path, Duration: 75 * time.Second}); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(actions, ","); got != "Stop,SetAVTransportURI,Play" {
+		t.Errorf("actions = %s", got)
+	}
+	if !strings.HasSuffix(uri, "/song.flac") {
+		t.Errorf("CurrentURI = %q", uri)
+	}
+	if got := parseTime("0:01:15.500"); got != 75500*time.Millisecond {
+		t.Errorf("parseTime = %s", got)
+	}
+}
+
+// TestMediaServerBrowse walks the media server from the root through an
+// artist and album to a track, then streams it
+func TestMediaServerBrowse(t *testing.T) {
+	dir := t.TempDir()
+	lib := library.NewLibrary()
+	for i, title := range []string{"One", "Two"} {
+		path := filepath.Join(dir, title+".mp3")
+		os.WriteFile(path, []byte("ID3 "+title), 0644)
+		lib.AddTrack(&api.Track{ID: "t" + title, Title: title, Artist: "Band", Album: "Record", TrackNum: i + 1, FilePath: path})
+	}
+	ms := newMediaServer(lib, "Test Library")
+	srv := httptest.NewServer(ms)
+	defer srv.Close()
+
+	browse := func(id string) map[string]string {
+		t.Helper()
+		values, err := (&Renderer{}).call(srv.URL+"/ContentDirectory/control", contentDirectoryType, "Browse",
+			arg{"ObjectID", id}, arg{"BrowseFlag", "BrowseDirectChildren"}, arg{"Filter", "*"},
+			arg{"StartingIndex", "0"}, arg{"RequestedCount", "0"}, arg{"SortCriteria", ""})
+		if err != nil {
+			t.Fatalf("browse %s: %v", id, err)
+		}
+		return values
+	}
+	if got := browse("0")["TotalMatches"]; got != "3" {
+		t.Errorf("root has %s children", got)
+	}
+	if res := browse("artists")["Result"]; !strings.Contains(res, `id="artists/Band"`) {
+		t.Fatalf("artists = %s", res)
+	}
+	albums := browse("artists/Band")["Result"]
+	_, rest, _ := strings.Cut(albums, `<container id="`)
+	albumID, _, _ := strings.Cut(rest, `"`)
+	tracks := browse(albumID)
+	if tracks["NumberReturned"] != "2" || !strings.Contains(tracks["Result"], "<dc:title>Two</dc:title>") {
+		t.Fatalf("album %s = %v", albumID, tracks)
+	}
+
+	_, rest, _ = strings.Cut(tracks["Result"], "<res ")
+	_, rest, _ = strings.Cut(rest, ">")
+	url, _, _ := strings.Cut(rest, "</res>")
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "ID3 One" {
+		t.Errorf("streamed %q from %s", body, url)
+	}
+
+	if _, err := (&Renderer{}).call(srv.URL+"/ContentDirectory/control", contentDirectoryType, "Browse",
+		arg{"ObjectID", "albums/missing"}, arg{"BrowseFlag", "BrowseDirectChildren"}); err == nil ||
+		!strings.Contains(err.Error(), "701") {
+		t.Errorf("browsing a missing album: %v", err)
+	}
+}
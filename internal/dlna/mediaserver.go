@@ -0,0 +1,430 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/renderer"
+)
+
+// UPnP types the media server offers
+const (
+	mediaServerType       = "urn:schemas-upnp-org:device:MediaServer:1"
+	contentDirectoryType  = "urn:schemas-upnp-org:service:ContentDirectory:1"
+	connectionManagerType = "urn:schemas-upnp-org:service:ConnectionManager:1"
+)
+
+const (
+	// maxAge is how long, in seconds, devices may remember an
+	// advertisement
+	maxAge = 1800
+	// advertiseInterval is how often the server re-announces itself,
+	// well within maxAge
+	advertiseInterval = 10 * time.Minute
+)
+
+// serverHeader identifies the server in SSDP and HTTP responses
+var serverHeader = runtime.GOOS + "/1.0 UPnP/1.0 gtmpc/1.0"
+
+// MediaServer shares the library on the local network as a UPnP
+// MediaServer. Devices find it with SSDP, browse artists, albums and
+// tracks through its ContentDirectory, and stream the files over HTTP.
+type MediaServer struct {
+	lib      *library.Library
+	name     string
+	udn      string // unique device name, "uuid:..."
+	updateID string // ContentDirectory SystemUpdateID
+
+	ln   net.Listener
+	srv  *http.Server
+	ssdp *net.UDPConn
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewMediaServer serves lib under name, "gtmpc on <host>" if empty, on
+// port on every interface (0 picks a free port) and announces it on the
+// network
+func NewMediaServer(lib *library.Library, name string, port int) (*MediaServer, error) {
+	s := newMediaServer(lib, name)
+	ln, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(port)))
+	if err != nil {
+		return nil, fmt.Errorf("start media server: %w", err)
+	}
+	ssdp, err := net.ListenMulticastUDP("udp4", nil, ssdpAddr)
+	if err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("start media server: %w", err)
+	}
+	s.ln, s.ssdp = ln, ssdp
+	s.srv = &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	s.done = make(chan struct{})
+
+	go s.srv.Serve(ln)
+	s.wg.Add(2)
+	go s.answer()
+	go s.advertise()
+	return s, nil
+}
+
+// newMediaServer prepares a media server without touching the network
+func newMediaServer(lib *library.Library, name string) *MediaServer {
+	host, _ := os.Hostname()
+	if name == "" {
+		name = "gtmpc on " + host
+	}
+	// The UDN stays the same across restarts so devices keep their
+	// bookmarks into the server
+	sum := sha1.Sum([]byte(host + "\x00" + name))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return &MediaServer{
+		lib:      lib,
+		name:     name,
+		udn:      fmt.Sprintf("uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16]),
+		updateID: strconv.FormatInt(time.Now().Unix()&0x7fffffff, 10),
+	}
+}
+
+// Name returns the name the server is announced under
+func (s *MediaServer) Name() string {
+	return s.name
+}
+
+// Close says goodbye on the network and stops the server
+func (s *MediaServer) Close() error {
+	close(s.done)
+	s.notify("ssdp:byebye")
+	s.ssdp.Close()
+	s.wg.Wait()
+	return s.srv.Close()
+}
+
+// notificationTypes returns the types the server is announced as, each
+// advertised separately as SSDP requires
+func (s *MediaServer) notificationTypes() []string {
+	return []string{"upnp:rootdevice", s.udn, mediaServerType, contentDirectoryType, connectionManagerType}
+}
+
+// usn returns the unique service name of notification type nt
+func (s *MediaServer) usn(nt string) string {
+	if nt == s.udn {
+		return s.udn
+	}
+	return s.udn + "::" + nt
+}
+
+// location returns the description URL as reached from addr
+func (s *MediaServer) location(addr string) (string, error) {
+	host, err := renderer.LocalAddr(addr)
+	if err != nil {
+		return "", err
+	}
+	port := s.ln.Addr().(*net.TCPAddr).Port
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(port)) + "/description.xml", nil
+}
+
+// answer replies to M-SEARCH requests for the server until it is closed
+func (s *MediaServer) answer() {
+	defer s.wg.Done()
+	buf := make([]byte, 4096)
+	for {
+		n, from, err := s.ssdp.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buf[:n])))
+		if err != nil || req.Method != "M-SEARCH" || req.Header.Get("Man") != `"ssdp:discover"` {
+			continue
+		}
+		loc, err := s.location(from.String())
+		if err != nil {
+			continue
+		}
+		st := req.Header.Get("St")
+		for _, nt := range s.notificationTypes() {
+			if st != "ssdp:all" && st != nt {
+				continue
+			}
+			msg := fmt.Sprintf("HTTP/1.1 200 OK\r\nCACHE-CONTROL: max-age=%d\r\nEXT:\r\nLOCATION: %s\r\n"+
+				"SERVER: %s\r\nST: %s\r\nUSN: %s\r\n\r\n", maxAge, loc, serverHeader, nt, s.usn(nt))
+			s.ssdp.WriteToUDP([]byte(msg), from)
+		}
+	}
+}
+
+// advertise announces the server when it starts and periodically after
+// until it is closed
+func (s *MediaServer) advertise() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(advertiseInterval)
+	defer ticker.Stop()
+	for {
+		s.notify("ssdp:alive")
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// notify multicasts a NOTIFY of sub, "ssdp:alive" or "ssdp:byebye", for
+// every notification type
+func (s *MediaServer) notify(sub string) {
+	loc, err := s.location(ssdpAddr.String())
+	if err != nil {
+		return
+	}
+	for _, nt := range s.notificationTypes() {
+		msg := fmt.Sprintf("NOTIFY * HTTP/1.1\r\nHOST: %s\r\nNT: %s\r\nNTS: %s\r\nUSN: %s\r\n", ssdpAddr, nt, sub, s.usn(nt))
+		if sub == "ssdp:alive" {
+			msg += fmt.Sprintf("CACHE-CONTROL: max-age=%d\r\nLOCATION: %s\r\nSERVER: %s\r\n", maxAge, loc, serverHeader)
+		}
+		s.ssdp.WriteToUDP([]byte(msg+"\r\n"), ssdpAddr)
+	}
+}
+
+// ServeHTTP serves the description, the services and the library's files
+func (s *MediaServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Server", serverHeader)
+	path := r.URL.Path
+	switch {
+	case path == "/description.xml":
+		serveXML(w, s.description())
+	case path == "/ContentDirectory.xml":
+		serveXML(w, contentDirectorySCPD)
+	case path == "/ConnectionManager.xml":
+		serveXML(w, connectionManagerSCPD)
+	case path == "/ContentDirectory/control" && r.Method == http.MethodPost:
+		s.control(w, r, contentDirectoryType, s.contentDirectory)
+	case path == "/ConnectionManager/control" && r.Method == http.MethodPost:
+		s.control(w, r, connectionManagerType, connectionManager)
+	case strings.HasSuffix(path, "/event"):
+		subscribe(w, r)
+	case strings.HasPrefix(path, "/media/"):
+		id := strings.TrimPrefix(path, "/media/")
+		t, err := s.lib.GetTrack(strings.TrimSuffix(id, filepath.Ext(id)))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", renderer.ContentType(t.FilePath))
+		w.Header().Set("transferMode.dlna.org", "Streaming")
+		w.Header().Set("contentFeatures.dlna.org", "DLNA.ORG_OP=01")
+		serveFile(w, r, t.FilePath)
+	case strings.HasPrefix(path, "/art/"):
+		a, err := s.lib.GetAlbum(strings.TrimPrefix(path, "/art/"))
+		if err != nil || a.ArtPath == "" {
+			http.NotFound(w, r)
+			return
+		}
+		serveFile(w, r, a.ArtPath)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveXML writes an XML document
+func serveXML(w http.ResponseWriter, doc string) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	fmt.Fprint(w, doc)
+}
+
+// serveFile serves the file at path, with range requests for seeking
+func serveFile(w http.ResponseWriter, r *http.Request, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, "file unavailable", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "file unavailable", http.StatusNotFound)
+		return
+	}
+	http.ServeContent(w, r, path, info.ModTime(), f)
+}
+
+// subscribe accepts event subscriptions so devices that insist on one
+// carry on; no events are sent, as the content only changes between the
+// SystemUpdateIDs devices poll
+func subscribe(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "SUBSCRIBE":
+		sid := r.Header.Get("SID")
+		if sid == "" {
+			b := make([]byte, 16)
+			rand.Read(b)
+			sid = fmt.Sprintf("uuid:%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+		}
+		w.Header().Set("SID", sid)
+		w.Header().Set("TIMEOUT", "Second-"+strconv.Itoa(maxAge))
+	case "UNSUBSCRIBE":
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// description returns the device description
+func (s *MediaServer) description() string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="utf-8"?>` +
+		`<root xmlns="urn:schemas-upnp-org:device-1-0" xmlns:dlna="urn:schemas-dlna-org:device-1-0">` +
+		`<specVersion><major>1</major><minor>0</minor></specVersion><device>`)
+	element(&sb, "deviceType", mediaServerType)
+	element(&sb, "friendlyName", s.name)
+	element(&sb, "manufacturer", "gtmpc")
+	element(&sb, "modelName", "gtmpc")
+	element(&sb, "UDN", s.udn)
+	sb.WriteString(`<dlna:X_DLNADOC>DMS-1.50</dlna:X_DLNADOC><serviceList>`)
+	for _, svc := range []struct{ typ, name string }{
+		{contentDirectoryType, "ContentDirectory"},
+		{connectionManagerType, "ConnectionManager"},
+	} {
+		fmt.Fprintf(&sb, `<service><serviceType>%s</serviceType><serviceId>urn:upnp-org:serviceId:%[2]s</serviceId>`+
+			`<SCPDURL>/%[2]s.xml</SCPDURL><controlURL>/%[2]s/control</controlURL><eventSubURL>/%[2]s/event</eventSubURL></service>`,
+			svc.typ, svc.name)
+	}
+	sb.WriteString(`</serviceList></device></root>`)
+	return sb.String()
+}
+
+// upnpError is a UPnP action failure reported to the caller
+type upnpError struct {
+	code int
+	desc string
+}
+
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("%s (UPnP error %d)", e.desc, e.code)
+}
+
+// action handles a UPnP action of a service, returning the values of its
+// response in order. base is the server's URL as the caller reached it.
+type action func(name string, args map[string]string, base string) ([]arg, error)
+
+// control runs the SOAP action posted to a service and writes its response
+// or fault
+func (s *MediaServer) control(w http.ResponseWriter, r *http.Request, service string, handle action) {
+	_, name, _ := strings.Cut(strings.Trim(r.Header.Get("SOAPAction"), `"`), "#")
+	args, err := responseValues(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	out, err := handle(name, args, "http://"+r.Host)
+
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.Header().Set("EXT", "")
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body>`)
+	if err != nil {
+		ue, ok := err.(*upnpError)
+		if !ok {
+			ue = &upnpError{code: 501, desc: err.Error()}
+		}
+		fmt.Fprintf(&body, `<s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring>`+
+			`<detail><UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode>`+
+			`<errorDescription>%s</errorDescription></UPnPError></detail></s:Fault>`, ue.code, escape(ue.desc))
+		w.WriteHeader(http.StatusInternalServerError)
+	} else {
+		fmt.Fprintf(&body, `<u:%sResponse xmlns:u="%s">`, name, service)
+		for _, a := range out {
+			fmt.Fprintf(&body, "<%s>%s</%s>", a.name, escape(a.value), a.name)
+		}
+		fmt.Fprintf(&body, `</u:%sResponse>`, name)
+	}
+	body.WriteString(`</s:Body></s:Envelope>`)
+	fmt.Fprint(w, body.String())
+}
+
+// contentDirectory handles the ContentDirectory actions
+func (s *MediaServer) contentDirectory(name string, args map[string]string, base string) ([]arg, error) {
+	switch name {
+	case "Browse":
+		return s.browse(args, base)
+	case "GetSystemUpdateID":
+		return []arg{{"Id", s.updateID}}, nil
+	case "GetSearchCapabilities":
+		return []arg{{"SearchCaps", ""}}, nil
+	case "GetSortCapabilities":
+		return []arg{{"SortCaps", ""}}, nil
+	}
+	return nil, &upnpError{401, "Invalid Action"}
+}
+
+// browse answers Browse with the object itself or a page of its children
+func (s *MediaServer) browse(args map[string]string, base string) ([]arg, error) {
+	id := args["ObjectID"]
+	var list []object
+	total := 1
+	switch args["BrowseFlag"] {
+	case "BrowseMetadata":
+		o, ok := s.lookup(id)
+		if !ok {
+			return nil, &upnpError{701, "No such object"}
+		}
+		list = []object{o}
+	case "BrowseDirectChildren":
+		children, ok := s.children(id)
+		if !ok {
+			return nil, &upnpError{701, "No such object"}
+		}
+		total = len(children)
+		start, _ := strconv.Atoi(args["StartingIndex"])
+		count, _ := strconv.Atoi(args["RequestedCount"])
+		start = min(max(start, 0), total)
+		end := total
+		if count > 0 {
+			end = min(start+count, total)
+		}
+		list = children[start:end]
+	default:
+		return nil, &upnpError{402, "Invalid Args"}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(didlOpen)
+	for _, o := range list {
+		o.write(&sb, base)
+	}
+	sb.WriteString(didlClose)
+	return []arg{
+		{"Result", sb.String()},
+		{"NumberReturned", strconv.Itoa(len(list))},
+		{"TotalMatches", strconv.Itoa(total)},
+		{"UpdateID", s.updateID},
+	}, nil
+}
+
+// connectionManager handles the ConnectionManager actions; the server has
+// the one implicit connection every file is streamed over
+func connectionManager(name string, args map[string]string, base string) ([]arg, error) {
+	switch name {
+	case "GetProtocolInfo":
+		return []arg{{"Source", "http-get:*:audio/mpeg:*,http-get:*:audio/flac:*,http-get:*:audio/wav:*"}, {"Sink", ""}}, nil
+	case "GetCurrentConnectionIDs":
+		return []arg{{"ConnectionIDs", "0"}}, nil
+	case "GetCurrentConnectionInfo":
+		return []arg{{"RcsID", "-1"}, {"AVTransportID", "-1"}, {"ProtocolInfo", ""},
+			{"PeerConnectionManager", ""}, {"PeerConnectionID", "-1"}, {"Direction", "Output"}, {"Status", "OK"}}, nil
+	}
+	return nil, &upnpError{401, "Invalid Action"}
+}
@@ -0,0 +1,281 @@
+package dlna
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/renderer"
+)
+
+const (
+	// pollInterval is how often the renderer's transport state is read
+	pollInterval = 2 * time.Second
+	// maxPollFailures is how many polls in a row may fail before the
+	// renderer is given up
+	maxPollFailures = 3
+)
+
+// Renderer controls a media renderer through UPnP AVTransport and
+// RenderingControl. It is a renderer.Renderer; UPnP has no connection, so
+// the device is polled and dropped once it stops answering.
+type Renderer struct {
+	device    renderer.Device
+	server    *renderer.Server
+	transport string // AVTransport control URL
+	rendering string // RenderingControl control URL, "" if the device has none
+
+	mu    sync.Mutex
+	track *api.Track
+	state api.PlaybackState // as last polled
+	err   error
+	done  chan struct{}
+}
+
+// Open prepares to control d, loading tracks from srv
+func Open(ctx context.Context, d renderer.Device, srv *renderer.Server) (*Renderer, error) {
+	desc, err := fetchDescription(ctx, d.Location)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", d.Name, err)
+	}
+	transport, err := desc.controlURL(d.Location, avTransportType)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", d.Name, err)
+	}
+	rendering, _ := desc.controlURL(d.Location, renderingType)
+	r := &Renderer{
+		device:    d,
+		server:    srv,
+		transport: transport,
+		rendering: rendering,
+		done:      make(chan struct{}),
+	}
+	go r.poll()
+	return r, nil
+}
+
+// Device returns the renderer's device
+func (r *Renderer) Device() renderer.Device {
+	return r.device
+}
+
+// Play loads t and starts it
+func (r *Renderer) Play(t *api.Track) error {
+	m, err := renderer.Publish(r.server, t, r.device.Addr)
+	if err != nil {
+		return err
+	}
+	// Many renderers refuse a new URI while playing; a stopped one may
+	// refuse the Stop, which is fine
+	r.avTransport("Stop")
+	if _, err := r.avTransport("SetAVTransportURI",
+		arg{"CurrentURI", m.URL}, arg{"CurrentURIMetaData", didl(m)}); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.track = t
+	r.mu.Unlock()
+	return r.Resume()
+}
+
+// Resume plays the loaded track
+func (r *Renderer) Resume() error {
+	_, err := r.avTransport("Play", arg{"Speed", "1"})
+	return err
+}
+
+// Pause pauses the loaded track
+func (r *Renderer) Pause() error {
+	_, err := r.avTransport("Pause")
+	return err
+}
+
+// Stop stops the loaded track
+func (r *Renderer) Stop() error {
+	_, err := r.avTransport("Stop")
+	return err
+}
+
+// Seek moves the loaded track to pos
+func (r *Renderer) Seek(pos time.Duration) error {
+	_, err := r.avTransport("Seek", arg{"Unit", "REL_TIME"}, arg{"Target", formatTime(pos)})
+	return err
+}
+
+// SetVolume sets the renderer's master volume, from 0 to 1
+func (r *Renderer) SetVolume(level float64) error {
+	if r.rendering == "" {
+		return fmt.Errorf("%s has no volume control", r.device.Name)
+	}
+	volume := strconv.Itoa(int(min(max(level, 0), 1)*100 + 0.5))
+	_, err := r.call(r.rendering, renderingType, "SetVolume",
+		arg{"InstanceID", "0"}, arg{"Channel", "Master"}, arg{"DesiredVolume", volume})
+	return err
+}
+
+// GetState returns the loaded track and the transport state and position
+// as last polled
+func (r *Renderer) GetState() *api.PlaybackState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state := r.state
+	state.CurrentTrack = r.track
+	state.Output = r.device.Name
+	return &state
+}
+
+// Close stops playback and stops polling the renderer
+func (r *Renderer) Close() error {
+	select {
+	case <-r.done:
+		return nil
+	default:
+	}
+	err := r.Stop()
+	r.close(nil)
+	return err
+}
+
+// Done is closed when the renderer has stopped answering or was closed
+func (r *Renderer) Done() <-chan struct{} {
+	return r.done
+}
+
+// Err returns why the renderer was given up, nil if it was closed
+func (r *Renderer) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+func (r *Renderer) close(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	select {
+	case <-r.done:
+		return
+	default:
+	}
+	r.err = err
+	close(r.done)
+}
+
+// poll reads the transport state and position until the renderer is
+// closed or stops answering
+func (r *Renderer) poll() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	failures := 0
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		}
+		info, err := r.avTransport("GetTransportInfo")
+		if err != nil {
+			if failures++; failures >= maxPollFailures {
+				r.close(fmt.Errorf("%s stopped responding: %w", r.device.Name, err))
+				return
+			}
+			continue
+		}
+		failures = 0
+		pos, _ := r.avTransport("GetPositionInfo")
+
+		r.mu.Lock()
+		switch info["CurrentTransportState"] {
+		case "PLAYING", "TRANSITIONING":
+			r.state.Status = api.StatusPlaying
+		case "PAUSED_PLAYBACK":
+			r.state.Status = api.StatusPaused
+		default:
+			r.state.Status = api.StatusStopped
+		}
+		r.state.Position = parseTime(pos["RelTime"])
+		r.mu.Unlock()
+	}
+}
+
+// arg is a named argument of a UPnP action, in the order the action
+// declares them
+type arg struct {
+	name, value string
+}
+
+// avTransport calls an AVTransport action on instance 0
+func (r *Renderer) avTransport(action string, args ...arg) (map[string]string, error) {
+	return r.call(r.transport, avTransportType, action, append([]arg{{"InstanceID", "0"}}, args...)...)
+}
+
+// call invokes a UPnP action and returns the values of its response
+func (r *Renderer) call(controlURL, service, action string, args ...arg) (map[string]string, error) {
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>` +
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">` +
+		`<s:Body>`)
+	fmt.Fprintf(&body, `<u:%s xmlns:u="%s">`, action, service)
+	for _, a := range args {
+		fmt.Fprintf(&body, "<%s>%s</%s>", a.name, escape(a.value), a.name)
+	}
+	fmt.Fprintf(&body, `</u:%s></s:Body></s:Envelope>`, action)
+
+	req, err := http.NewRequest(http.MethodPost, controlURL, strings.NewReader(body.String()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, service, action))
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	values, err := responseValues(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", action, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if desc := values["errorDescription"]; desc != "" {
+			return nil, fmt.Errorf("%s: %s (UPnP error %s)", action, desc, values["errorCode"])
+		}
+		return nil, fmt.Errorf("%s: %s", action, resp.Status)
+	}
+	return values, nil
+}
+
+// responseValues collects the text of every element of a SOAP response
+// by local name; that is all the replies used here need
+func responseValues(body io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+	dec := xml.NewDecoder(io.LimitReader(body, 1<<20))
+	var name string
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return values, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse response: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name = t.Name.Local
+		case xml.CharData:
+			if name != "" {
+				values[name] += string(t)
+			}
+		case xml.EndElement:
+			name = ""
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package dlna
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Service descriptions of the media server, listing the actions it
+// answers and the state variables their arguments are typed by
+var (
+	contentDirectorySCPD = scpd([]scpdAction{
+		{"Browse", []string{"in ObjectID A_ARG_TYPE_ObjectID", "in BrowseFlag A_ARG_TYPE_BrowseFlag",
+			"in Filter A_ARG_TYPE_Filter", "in StartingIndex A_ARG_TYPE_Index", "in RequestedCount A_ARG_TYPE_Count",
+			"in SortCriteria A_ARG_TYPE_SortCriteria", "out Result A_ARG_TYPE_Result",
+			"out NumberReturned A_ARG_TYPE_Count", "out TotalMatches A_ARG_TYPE_Count", "out UpdateID A_ARG_TYPE_UpdateID"}},
+		{"GetSystemUpdateID", []string{"out Id SystemUpdateID"}},
+		{"GetSearchCapabilities", []string{"out SearchCaps SearchCapabilities"}},
+		{"GetSortCapabilities", []string{"out SortCaps SortCapabilities"}},
+	}, []string{
+		"A_ARG_TYPE_ObjectID string", "A_ARG_TYPE_BrowseFlag string", "A_ARG_TYPE_Filter string",
+		"A_ARG_TYPE_Index ui4", "A_ARG_TYPE_Count ui4", "A_ARG_TYPE_SortCriteria string",
+		"A_ARG_TYPE_Result string", "A_ARG_TYPE_UpdateID ui4", "SystemUpdateID ui4 evented",
+		"SearchCapabilities string", "SortCapabilities string",
+	})
+	connectionManagerSCPD = scpd([]scpdAction{
+		{"GetProtocolInfo", []string{"out Source SourceProtocolInfo", "out Sink SinkProtocolInfo"}},
+		{"GetCurrentConnectionIDs", []string{"out ConnectionIDs CurrentConnectionIDs"}},
+		{"GetCurrentConnectionInfo", []string{"in ConnectionID A_ARG_TYPE_ConnectionID",
+			"out RcsID A_ARG_TYPE_RcsID", "out AVTransportID A_ARG_TYPE_AVTransportID",
+			"out ProtocolInfo A_ARG_TYPE_ProtocolInfo", "out PeerConnectionManager A_ARG_TYPE_ConnectionManager",
+			"out PeerConnectionID A_ARG_TYPE_ConnectionID", "out Direction A_ARG_TYPE_Direction",
+			"out Status A_ARG_TYPE_ConnectionStatus"}},
+	}, []string{
+		"SourceProtocolInfo string evented", "SinkProtocolInfo string evented", "CurrentConnectionIDs string evented",
+		"A_ARG_TYPE_ConnectionID i4", "A_ARG_TYPE_RcsID i4", "A_ARG_TYPE_AVTransportID i4",
+		"A_ARG_TYPE_ProtocolInfo string", "A_ARG_TYPE_ConnectionManager string", "A_ARG_TYPE_Direction string",
+		"A_ARG_TYPE_ConnectionStatus string",
+	})
+)
+
+// scpdAction is an action and its arguments, each "in|out name variable"
+type scpdAction struct {
+	name string
+	args []string
+}
+
+// scpd builds a service description from its actions and state variables,
+// each "name type" with an optional "evented"
+func scpd(actions []scpdAction, vars []string) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="utf-8"?><scpd xmlns="urn:schemas-upnp-org:service-1-0">` +
+		`<specVersion><major>1</major><minor>0</minor></specVersion><actionList>`)
+	for _, a := range actions {
+		fmt.Fprintf(&sb, "<action><name>%s</name><argumentList>", a.name)
+		for _, arg := range a.args {
+			f := strings.Fields(arg)
+			fmt.Fprintf(&sb, "<argument><name>%s</name><direction>%s</direction>"+
+				"<relatedStateVariable>%s</relatedStateVariable></argument>", f[1], f[0], f[2])
+		}
+		sb.WriteString("</argumentList></action>")
+	}
+	sb.WriteString("</actionList><serviceStateTable>")
+	for _, v := range vars {
+		f := strings.Fields(v)
+		events := "no"
+		if len(f) > 2 {
+			events = "yes"
+		}
+		fmt.Fprintf(&sb, `<stateVariable sendEvents="%s"><name>%s</name><dataType>%s</dataType></stateVariable>`,
+			events, f[0], f[1])
+	}
+	sb.WriteString("</serviceStateTable></scpd>")
+	return sb.String()
+}
@@ -0,0 +1,335 @@
+// Package enrich proposes corrected metadata for tracks whose tags are
+// missing. Files are fingerprinted with Chromaprint's fpcalc and looked up
+// on AcoustID, which links them to MusicBrainz recordings; without fpcalc
+// or an AcoustID key, MusicBrainz is searched by title, artist and length
+// instead. Proposals are only suggestions: nothing is written until the
+// user accepts one.
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/library"
+)
+
+// UserAgent identifies the player to MusicBrainz, which requires one
+const UserAgent = "gtmpc/1.0 ( https://github.com/jscyril/gtmpc )"
+
+// requestInterval keeps lookups within MusicBrainz's one request per second
+const requestInterval = time.Second
+
+// requestTimeout bounds a single web service call
+const requestTimeout = 10 * time.Second
+
+// minScore is the lowest AcoustID or MusicBrainz confidence proposed
+const minScore = 0.5
+
+// durationSlack is how far a recording's length may be from the track's
+// for a MusicBrainz search to match it
+const durationSlack = 5 * time.Second
+
+// ErrNoMatch is returned when nothing matched a track well enough
+var ErrNoMatch = errors.New("no confident match")
+
+// Proposal is the metadata suggested for a library track
+type Proposal struct {
+	Track  *api.Track
+	Title  string
+	Artist string
+	Album  string
+	Year   int     // 0 if unknown
+	Score  float64 // match confidence, 0 to 1
+	Source string  // "acoustid" or "musicbrainz"
+}
+
+// Edit returns the tag changes accepting the proposal makes. Unknown
+// values never clear existing tags.
+func (p Proposal) Edit() library.TagEdit {
+	var e library.TagEdit
+	if p.Title != "" && p.Title != p.Track.Title {
+		e.Title = &p.Title
+	}
+	if p.Artist != "" && p.Artist != p.Track.Artist {
+		e.Artist = &p.Artist
+	}
+	if p.Album != "" && p.Album != p.Track.Album {
+		e.Album = &p.Album
+	}
+	if p.Year > 0 && p.Year != p.Track.Year {
+		e.Year = &p.Year
+	}
+	return e
+}
+
+// NeedsLookup reports whether a track's tags are missing or were filled
+// in with placeholders by the scanner
+func NeedsLookup(t *api.Track) bool {
+	base := filepath.Base(t.FilePath)
+	return t.Artist == "" || t.Artist == "Unknown Artist" || t.Album == "" ||
+		t.Album == "Unknown Album" || t.Title == "" || t.Title == base
+}
+
+// Client queries AcoustID and MusicBrainz
+type Client struct {
+	acoustIDKey string
+	http        *http.Client
+	acoustIDURL string
+	searchURL   string
+
+	mu   sync.Mutex
+	last time.Time // when the last request was sent
+}
+
+// NewClient creates a client. acoustIDKey is an AcoustID application key;
+// without it only the MusicBrainz title search is used.
+func NewClient(acoustIDKey string) *Client {
+	return &Client{
+		acoustIDKey: acoustIDKey,
+		http:        &http.Client{Timeout: requestTimeout},
+		acoustIDURL: "https://api.acoustid.org/v2/lookup",
+		searchURL:   "https://musicbrainz.org/ws/2/recording",
+	}
+}
+
+// Lookup proposes metadata for track, or returns ErrNoMatch
+func (c *Client) Lookup(ctx context.Context, track *api.Track) (Proposal, error) {
+	var p Proposal
+	var err error
+	if fp, dur, ferr := fingerprint(ctx, track.FilePath); c.acoustIDKey != "" && ferr == nil {
+		p, err = c.lookupAcoustID(ctx, fp, dur)
+	} else {
+		p, err = c.searchMusicBrainz(ctx, track)
+	}
+	if err != nil {
+		return Proposal{}, err
+	}
+	p.Track = track
+	if p.Score < minScore || p.Edit().IsZero() {
+		return Proposal{}, ErrNoMatch
+	}
+	return p, nil
+}
+
+// LookupAll proposes metadata for up to limit tracks, skipping those
+// without a confident match. It stops early when ctx is cancelled.
+func (c *Client) LookupAll(ctx context.Context, tracks []*api.Track, limit int) ([]Proposal, error) {
+	var out []Proposal
+	var lastErr error
+	for i, t := range tracks {
+		if i >= limit || ctx.Err() != nil {
+			break
+		}
+		p, err := c.Lookup(ctx, t)
+		if errors.Is(err, ErrNoMatch) {
+			continue
+		}
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		out = append(out, p)
+	}
+	if len(out) == 0 && lastErr != nil {
+		return nil, lastErr
+	}
+	return out, nil
+}
+
+// fingerprint runs fpcalc on a file
+func fingerprint(ctx context.Context, path string) (fp string, duration int, err error) {
+	bin, err := exec.LookPath("fpcalc")
+	if err != nil {
+		return "", 0, err
+	}
+	out, err := exec.CommandContext(ctx, bin, "-json", path).Output()
+	if err != nil {
+		return "", 0, fmt.Errorf("fpcalc: %w", err)
+	}
+	var res struct {
+		Duration    float64 `json:"duration"`
+		Fingerprint string  `json:"fingerprint"`
+	}
+	if err := json.Unmarshal(out, &res); err != nil {
+		return "", 0, fmt.Errorf("fpcalc output: %w", err)
+	}
+	return res.Fingerprint, int(res.Duration), nil
+}
+
+// get sends a rate-limited GET and decodes the JSON response into v
+func (c *Client) get(ctx context.Context, u string, v interface{}) error {
+	c.mu.Lock()
+	if wait := requestInterval - time.Since(c.last); wait > 0 {
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			c.mu.Unlock()
+			return ctx.Err()
+		}
+	}
+	c.last = time.Now()
+	c.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", req.URL.Host, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// lookupAcoustID matches a fingerprint to its best MusicBrainz recording
+func (c *Client) lookupAcoustID(ctx context.Context, fp string, duration int) (Proposal, error) {
+	q := url.Values{
+		"client":      {c.acoustIDKey},
+		"meta":        {"recordings releasegroups"},
+		"duration":    {strconv.Itoa(duration)},
+		"fingerprint": {fp},
+	}
+	var res struct {
+		Status  string `json:"status"`
+		Results []struct {
+			Score      float64 `json:"score"`
+			Recordings []struct {
+				Title   string `json:"title"`
+				Artists []struct {
+					Name string `json:"name"`
+				} `json:"artists"`
+				ReleaseGroups []struct {
+					Title string `json:"title"`
+					Type  string `json:"type"`
+				} `json:"releasegroups"`
+			} `json:"recordings"`
+		} `json:"results"`
+	}
+	if err := c.get(ctx, c.acoustIDURL+"?"+q.Encode(), &res); err != nil {
+		return Proposal{}, fmt.Errorf("acoustid lookup: %w", err)
+	}
+	for _, r := range res.Results {
+		for _, rec := range r.Recordings {
+			if rec.Title == "" {
+				continue
+			}
+			p := Proposal{Title: rec.Title, Score: r.Score, Source: "acoustid"}
+			var names []string
+			for _, a := range rec.Artists {
+				names = append(names, a.Name)
+			}
+			p.Artist = strings.Join(names, ", ")
+			// Prefer the album the recording appeared on over singles
+			for _, rg := range rec.ReleaseGroups {
+				if p.Album == "" || rg.Type == "Album" {
+					p.Album = rg.Title
+				}
+				if rg.Type == "Album" {
+					break
+				}
+			}
+			return p, nil
+		}
+	}
+	return Proposal{}, ErrNoMatch
+}
+
+// searchMusicBrainz finds the best recording matching a track's title,
+// and its artist and length where they are known
+func (c *Client) searchMusicBrainz(ctx context.Context, track *api.Track) (Proposal, error) {
+	query := searchQuery(track)
+	if query == "" {
+		return Proposal{}, ErrNoMatch
+	}
+	q := url.Values{
+		"query": {query},
+		"fmt":   {"json"},
+		"limit": {"1"},
+	}
+	var res struct {
+		Recordings []struct {
+			Title        string `json:"title"`
+			Score        int    `json:"score"`
+			ArtistCredit []struct {
+				Name       string `json:"name"`
+				JoinPhrase string `json:"joinphrase"`
+			} `json:"artist-credit"`
+			Releases []struct {
+				Title string `json:"title"`
+				Date  string `json:"date"`
+			} `json:"releases"`
+		} `json:"recordings"`
+	}
+	if err := c.get(ctx, c.searchURL+"?"+q.Encode(), &res); err != nil {
+		return Proposal{}, fmt.Errorf("musicbrainz search: %w", err)
+	}
+	if len(res.Recordings) == 0 {
+		return Proposal{}, ErrNoMatch
+	}
+	rec := res.Recordings[0]
+	p := Proposal{Title: rec.Title, Score: float64(rec.Score) / 100, Source: "musicbrainz"}
+	var artist strings.Builder
+	for _, a := range rec.ArtistCredit {
+		artist.WriteString(a.Name + a.JoinPhrase)
+	}
+	p.Artist = artist.String()
+	if len(rec.Releases) > 0 {
+		p.Album = rec.Releases[0].Title
+		if len(rec.Releases[0].Date) >= 4 {
+			p.Year, _ = strconv.Atoi(rec.Releases[0].Date[:4])
+		}
+	}
+	return p, nil
+}
+
+// searchQuery returns the MusicBrainz search for track, or "" without a
+// title. A title alone matches any recording of that name, so the artist
+// and the length narrow it down where the tags have them.
+func searchQuery(t *api.Track) string {
+	title := guessTitle(t)
+	if title == "" {
+		return ""
+	}
+	query := `recording:"` + strings.ReplaceAll(title, `"`, ``) + `"`
+	if t.Artist != "" && t.Artist != "Unknown Artist" {
+		query += ` AND artist:"` + strings.ReplaceAll(t.Artist, `"`, ``) + `"`
+	}
+	if t.Duration > durationSlack {
+		query += fmt.Sprintf(" AND dur:[%d TO %d]",
+			(t.Duration - durationSlack).Milliseconds(), (t.Duration + durationSlack).Milliseconds())
+	}
+	return query
+}
+
+// leadingNumber matches a track number prefix such as "01 - " or "3. "
+var leadingNumber = regexp.MustCompile(`^\d{1,3}\s*[-._)]?\s*`)
+
+// guessTitle returns the title to search for: the tagged one, or the file
+// name without its extension and track number
+func guessTitle(t *api.Track) string {
+	base := filepath.Base(t.FilePath)
+	if t.Title != "" && t.Title != base {
+		return t.Title
+	}
+	name := strings.TrimSuffix(base, filepath.Ext(base))
+	name = leadingNumber.ReplaceAllString(name, "")
+	return strings.TrimSpace(strings.NewReplacer("_", " ").Replace(name))
+}
@@ -0,0 +1,83 @@
+package enrich
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestGuessTitle verifies track numbers and extensions are stripped from file names
+func TestGuessTitle(t *testing.T) {
+	cases := map[string]string{
+		"/music/03 - Paranoid_Android.mp3": "Paranoid Android",
+		"/music/1. Airbag.flac":            "Airbag",
+		"/music/Karma Police.mp3":          "Karma Police",
+	}
+	for path, want := range cases {
+		// The scanner titles untagged files with their base name
+		track := &api.Track{FilePath: path, Title: filepath.Base(path)}
+		if got := guessTitle(track); got != want {
+			t.Errorf("guessTitle(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+// TestLookup_MusicBrainzSearch verifies a title search becomes a proposal for the tags that differ
+func TestLookup_MusicBrainzSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("query"); got != `recording:"Airbag"` {
+			t.Errorf("query = %q", got)
+		}
+		if r.Header.Get("User-Agent") != UserAgent {
+			t.Errorf("missing user agent")
+		}
+		w.Write([]byte(`{"recordings":[{"title":"Airbag","score":100,
+			"artist-credit":[{"name":"Radiohead","joinphrase":""}],
+			"releases":[{"title":"OK Computer","date":"1997-05-21"}]}]}`))
+	}))
+	defer srv.Close()
+
+	c := NewClient("")
+	c.searchURL = srv.URL
+	track := &api.Track{ID: "1", FilePath: "/music/01 Airbag.mp3", Title: "01 Airbag.mp3", Artist: "Unknown Artist"}
+	if !NeedsLookup(track) {
+		t.Fatal("placeholder tags should need a lookup")
+	}
+	p, err := c.Lookup(context.Background(), track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Artist != "Radiohead" || p.Album != "OK Computer" || p.Year != 1997 || p.Title != "Airbag" {
+		t.Errorf("unexpected proposal %+v", p)
+	}
+	edit := p.Edit()
+	if edit.Artist == nil || *edit.Artist != "Radiohead" || edit.Genre != nil {
+		t.Errorf("unexpected edit %+v", edit)
+	}
+}
+
+// TestSearchQuery verifies the artist and length narrow a title search
+// where the tags have them
+func TestSearchQuery(t *testing.T) {
+	tests := []struct {
+		track *api.Track
+		want  string
+	}{
+		{&api.Track{FilePath: "/music/Intro.mp3", Title: "Intro.mp3", Artist: "Unknown Artist"}, `recording:"Intro"`},
+		{&api.Track{FilePath: "/music/a.mp3", Title: `Say "Hi"`, Artist: "The xx"}, `recording:"Say Hi" AND artist:"The xx"`},
+		{&api.Track{FilePath: "/music/a.mp3", Title: "Intro", Artist: "The xx", Duration: 2*time.Minute + 7*time.Second},
+			`recording:"Intro" AND artist:"The xx" AND dur:[122000 TO 132000]`},
+		{&api.Track{FilePath: "/music/a.mp3", Title: "Intro", Duration: time.Second}, `recording:"Intro"`},
+		{&api.Track{FilePath: "/music/.mp3", Title: ".mp3", Artist: "The xx"}, ""},
+	}
+	for _, tt := range tests {
+		if got := searchQuery(tt.track); got != tt.want {
+			t.Errorf("searchQuery(%+v) = %q, want %q", *tt.track, got, tt.want)
+		}
+	}
+}
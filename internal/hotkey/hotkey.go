@@ -0,0 +1,128 @@
+// Package hotkey registers system-wide keyboard shortcuts, so playback can
+// be controlled while another window has the focus. Keys are grabbed from
+// the X server on Linux and the BSDs and registered with RegisterHotKey on
+// Windows. Wayland compositors and macOS do not let programs grab keys
+// this way; under Wayland the X grab only sees keys pressed in XWayland
+// windows.
+package hotkey
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Modifier is a set of modifier keys
+type Modifier uint8
+
+const (
+	Shift Modifier = 1 << iota
+	Ctrl
+	Alt
+	Super
+)
+
+// Hotkey is a key with the modifiers held with it
+type Hotkey struct {
+	Mods Modifier
+	Key  string // a name from the keys table, e.g. "p", "f9", "media_next"
+}
+
+// String formats h as Parse reads it
+func (h Hotkey) String() string {
+	var parts []string
+	for _, m := range []struct {
+		mod  Modifier
+		name string
+	}{{Ctrl, "ctrl"}, {Alt, "alt"}, {Shift, "shift"}, {Super, "super"}} {
+		if h.Mods&m.mod != 0 {
+			parts = append(parts, m.name)
+		}
+	}
+	return strings.Join(append(parts, h.Key), "+")
+}
+
+// key is the code of a key on each platform
+type key struct {
+	keysym uint32 // X11
+	vk     uint32 // Windows virtual-key code
+}
+
+// keys are the keys that can be bound, by name
+var keys = map[string]key{
+	"space":            {0x20, 0x20},
+	"left":             {0xff51, 0x25},
+	"up":               {0xff52, 0x26},
+	"right":            {0xff53, 0x27},
+	"down":             {0xff54, 0x28},
+	"home":             {0xff50, 0x24},
+	"end":              {0xff57, 0x23},
+	"pageup":           {0xff55, 0x21},
+	"pagedown":         {0xff56, 0x22},
+	"insert":           {0xff63, 0x2d},
+	"delete":           {0xffff, 0x2e},
+	"media_play_pause": {0x1008ff14, 0xb3},
+	"media_stop":       {0x1008ff15, 0xb2},
+	"media_prev":       {0x1008ff16, 0xb1},
+	"media_next":       {0x1008ff17, 0xb0},
+}
+
+func init() {
+	for c := 'a'; c <= 'z'; c++ {
+		keys[string(c)] = key{uint32(c), uint32(c - 'a' + 'A')}
+	}
+	for c := '0'; c <= '9'; c++ {
+		keys[string(c)] = key{uint32(c), uint32(c)}
+	}
+	for n := 1; n <= 24; n++ {
+		keys[fmt.Sprintf("f%d", n)] = key{0xffbe + uint32(n-1), 0x70 + uint32(n-1)}
+	}
+}
+
+// Parse reads a hotkey such as "ctrl+alt+p", "super+shift+right", "f9" or
+// "media_next". Modifiers are ctrl, alt, shift and super (also win, cmd);
+// case does not matter.
+func Parse(spec string) (Hotkey, error) {
+	var h Hotkey
+	parts := strings.Split(strings.ToLower(strings.TrimSpace(spec)), "+")
+	for i, p := range parts {
+		p = strings.TrimSpace(p)
+		if i == len(parts)-1 {
+			if _, ok := keys[p]; !ok {
+				return Hotkey{}, fmt.Errorf("hotkey %q: unknown key %q", spec, p)
+			}
+			h.Key = p
+			break
+		}
+		switch p {
+		case "ctrl", "control":
+			h.Mods |= Ctrl
+		case "alt":
+			h.Mods |= Alt
+		case "shift":
+			h.Mods |= Shift
+		case "super", "win", "cmd", "meta":
+			h.Mods |= Super
+		default:
+			return Hotkey{}, fmt.Errorf("hotkey %q: unknown modifier %q", spec, p)
+		}
+	}
+	return h, nil
+}
+
+// Binding names the action a hotkey triggers
+type Binding struct {
+	Action string
+	Hotkey Hotkey
+}
+
+// Listen registers the bindings and sends the action of each hotkey
+// pressed until ctx is done, when the keys are released again and the
+// channel closed. It fails if the platform has no way to grab keys or
+// another program already holds one of them.
+func Listen(ctx context.Context, bindings []Binding) (<-chan string, error) {
+	if len(bindings) == 0 {
+		return nil, fmt.Errorf("no hotkeys to register")
+	}
+	return listen(ctx, bindings)
+}
@@ -0,0 +1,25 @@
+package hotkey
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	for spec, want := range map[string]Hotkey{
+		"ctrl+alt+p":        {Ctrl | Alt, "p"},
+		"Super + Shift+F9":  {Super | Shift, "f9"},
+		"media_next":        {0, "media_next"},
+		"win+ctrl+pagedown": {Super | Ctrl, "pagedown"},
+	} {
+		got, err := Parse(spec)
+		if err != nil || got != want {
+			t.Errorf("Parse(%q) = %v, %v; want %v", spec, got, err, want)
+		}
+	}
+	if got := (Hotkey{Super | Ctrl | Shift, "right"}).String(); got != "ctrl+shift+super+right" {
+		t.Errorf("String = %q", got)
+	}
+	for _, spec := range []string{"", "ctrl+", "hyper+p", "ctrl+alt+f25"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) succeeded", spec)
+		}
+	}
+}
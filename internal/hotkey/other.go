@@ -0,0 +1,13 @@
+//go:build !linux && !freebsd && !netbsd && !openbsd && !dragonfly && !windows
+
+package hotkey
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+)
+
+func listen(ctx context.Context, bindings []Binding) (<-chan string, error) {
+	return nil, fmt.Errorf("global hotkeys are not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,102 @@
+//go:build windows
+
+package hotkey
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// Windows hotkey constants
+const (
+	modAlt      = 0x0001
+	modControl  = 0x0002
+	modShift    = 0x0004
+	modWin      = 0x0008
+	modNoRepeat = 0x4000
+	wmHotkey    = 0x0312
+	wmQuit      = 0x0012
+)
+
+var (
+	user32             = syscall.NewLazyDLL("user32.dll")
+	registerHotKey     = user32.NewProc("RegisterHotKey")
+	getMessage         = user32.NewProc("GetMessageW")
+	postThreadMessage  = user32.NewProc("PostThreadMessageW")
+	getCurrentThreadID = syscall.NewLazyDLL("kernel32.dll").NewProc("GetCurrentThreadId")
+)
+
+// msg is the Win32 MSG structure
+type msg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	x, y    int32
+}
+
+// listen registers the hotkeys for a thread and pumps its messages. Hotkeys
+// belong to the thread that registered them, so the goroutine stays on
+// its OS thread, and ending it releases them.
+func listen(ctx context.Context, bindings []Binding) (<-chan string, error) {
+	out := make(chan string, 4)
+	started := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		defer close(out)
+		for i, b := range bindings {
+			mods := uintptr(windowsModifiers(b.Hotkey.Mods) | modNoRepeat)
+			vk := uintptr(keys[b.Hotkey.Key].vk)
+			if r, _, err := registerHotKey.Call(0, uintptr(i+1), mods, vk); r == 0 {
+				started <- fmt.Errorf("global hotkeys: %s: %w", b.Hotkey, err)
+				return
+			}
+		}
+		thread, _, _ := getCurrentThreadID.Call()
+		stop := context.AfterFunc(ctx, func() { postThreadMessage.Call(thread, wmQuit, 0, 0) })
+		defer stop()
+		started <- nil
+
+		var m msg
+		for {
+			r, _, _ := getMessage.Call(uintptr(unsafe.Pointer(&m)), 0, 0, 0)
+			if int32(r) <= 0 { // WM_QUIT or an error
+				return
+			}
+			if m.message != wmHotkey || m.wParam < 1 || int(m.wParam) > len(bindings) {
+				continue
+			}
+			select {
+			case out <- bindings[m.wParam-1].Action:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// windowsModifiers converts modifiers to RegisterHotKey flags
+func windowsModifiers(m Modifier) uint32 {
+	var flags uint32
+	if m&Shift != 0 {
+		flags |= modShift
+	}
+	if m&Ctrl != 0 {
+		flags |= modControl
+	}
+	if m&Alt != 0 {
+		flags |= modAlt
+	}
+	if m&Super != 0 {
+		flags |= modWin
+	}
+	return flags
+}
@@ -0,0 +1,392 @@
+//go:build linux || freebsd || netbsd || openbsd || dragonfly
+
+package hotkey
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// X11 request opcodes, packet types and error codes used here
+const (
+	opGrabKey            = 33
+	opGetInputFocus      = 43
+	opGetKeyboardMapping = 101
+
+	packetError    = 0
+	packetReply    = 1
+	packetKeyPress = 2
+
+	errBadAccess = 10
+)
+
+// X11 modifier masks
+const (
+	xShift   = 0x01
+	xLock    = 0x02 // Caps Lock
+	xControl = 0x04
+	xMod1    = 0x08 // Alt
+	xMod2    = 0x10 // Num Lock on practically every keymap
+	xMod4    = 0x40 // Super
+)
+
+// repeatWindow is how soon after the last press of a key another counts as
+// the key being held down, so held keys fire once
+const repeatWindow = 250 * time.Millisecond
+
+// xconn is a connection to an X server speaking just enough of the core
+// protocol to grab keys on the root window
+type xconn struct {
+	conn       net.Conn
+	r          *bufio.Reader
+	root       uint32
+	minKeycode byte
+	maxKeycode byte
+	seq        uint16 // sequence number of the last request
+}
+
+// xgrab is a grabbed key and the action it triggers
+type xgrab struct {
+	keycode byte
+	mods    uint16
+	action  string
+}
+
+func listen(ctx context.Context, bindings []Binding) (<-chan string, error) {
+	c, err := dialX(os.Getenv("DISPLAY"))
+	if err != nil {
+		return nil, fmt.Errorf("global hotkeys: %w", err)
+	}
+	grabs, err := c.grab(bindings)
+	if err != nil {
+		c.conn.Close()
+		return nil, fmt.Errorf("global hotkeys: %w", err)
+	}
+
+	// The server drops the grabs when the connection closes
+	out := make(chan string, 4)
+	stop := context.AfterFunc(ctx, func() { c.conn.Close() })
+	go func() {
+		defer close(out)
+		defer stop()
+		c.run(ctx, grabs, out)
+	}()
+	return out, nil
+}
+
+// dialX connects to the X server of display, e.g. ":0" or "host:10.0"
+func dialX(display string) (*xconn, error) {
+	if display == "" {
+		return nil, errors.New("DISPLAY is not set, so there is no X server to grab keys from")
+	}
+	i := strings.LastIndex(display, ":")
+	if i < 0 {
+		return nil, fmt.Errorf("bad DISPLAY %q", display)
+	}
+	host := display[:i]
+	number, screenPart, _ := strings.Cut(display[i+1:], ".")
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return nil, fmt.Errorf("bad DISPLAY %q", display)
+	}
+	screen, _ := strconv.Atoi(screenPart)
+
+	var conn net.Conn
+	local := host == "" || host == "unix"
+	if local {
+		conn, err = net.Dial("unix", "/tmp/.X11-unix/X"+number)
+	} else {
+		conn, err = net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(6000+n)))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("connect to X server: %w", err)
+	}
+	c := &xconn{conn: conn, r: bufio.NewReader(conn)}
+	authName, authData := xauthority(local, host, number)
+	if err := c.setup(authName, authData, screen); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// xauthority returns the MIT-MAGIC-COOKIE-1 for the display from the
+// Xauthority file, or nothing if there is none
+func xauthority(local bool, host, number string) (string, []byte) {
+	path := os.Getenv("XAUTHORITY")
+	if path == "" {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, ".Xauthority")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil
+	}
+	if local {
+		host, _ = os.Hostname()
+	}
+	const familyLocal, familyWild = 256, 0xffff
+	r := bytes.NewReader(data)
+	field := func() []byte {
+		var n uint16
+		if binary.Read(r, binary.BigEndian, &n) != nil {
+			return nil
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil
+		}
+		return b
+	}
+	for {
+		var family uint16
+		if binary.Read(r, binary.BigEndian, &family) != nil {
+			return "", nil
+		}
+		addr, num, name, cookie := field(), field(), field(), field()
+		if cookie == nil {
+			return "", nil
+		}
+		hostMatches := family == familyWild || string(addr) == host || (!local && family != familyLocal)
+		if hostMatches && (len(num) == 0 || string(num) == number) && string(name) == "MIT-MAGIC-COOKIE-1" {
+			return string(name), cookie
+		}
+	}
+}
+
+// setup performs the connection handshake and finds the root window of
+// screen and the keyboard's keycode range
+func (c *xconn) setup(authName string, authData []byte, screen int) error {
+	var req []byte
+	req = append(req, 'l', 0)
+	req = binary.LittleEndian.AppendUint16(req, 11) // protocol 11.0
+	req = binary.LittleEndian.AppendUint16(req, 0)
+	req = binary.LittleEndian.AppendUint16(req, uint16(len(authName)))
+	req = binary.LittleEndian.AppendUint16(req, uint16(len(authData)))
+	req = append(req, 0, 0)
+	req = append(req, pad([]byte(authName))...)
+	req = append(req, pad(authData)...)
+	if _, err := c.conn.Write(req); err != nil {
+		return fmt.Errorf("X server handshake: %w", err)
+	}
+
+	head := make([]byte, 8)
+	if _, err := io.ReadFull(c.r, head); err != nil {
+		return fmt.Errorf("X server handshake: %w", err)
+	}
+	body := make([]byte, int(binary.LittleEndian.Uint16(head[6:]))*4)
+	if _, err := io.ReadFull(c.r, body); err != nil {
+		return fmt.Errorf("X server handshake: %w", err)
+	}
+	if head[0] != 1 {
+		reason := string(body[:min(int(head[1]), len(body))])
+		return fmt.Errorf("X server refused the connection: %s", strings.TrimSpace(reason))
+	}
+	if len(body) < 32 {
+		return errors.New("X server handshake: short reply")
+	}
+
+	vendorLen := int(binary.LittleEndian.Uint16(body[16:]))
+	screens, formats := int(body[20]), int(body[21])
+	c.minKeycode, c.maxKeycode = body[26], body[27]
+	off := 32 + (vendorLen+3)&^3 + 8*formats
+	if screen >= screens {
+		screen = 0
+	}
+	for i := 0; ; i++ {
+		if off+40 > len(body) {
+			return errors.New("X server handshake: short screen list")
+		}
+		if i == screen {
+			c.root = binary.LittleEndian.Uint32(body[off:])
+			return nil
+		}
+		depths := int(body[off+39])
+		off += 40
+		for range depths {
+			if off+8 > len(body) {
+				return errors.New("X server handshake: short depth list")
+			}
+			off += 8 + 24*int(binary.LittleEndian.Uint16(body[off+2:]))
+		}
+	}
+}
+
+// request sends a request and returns its sequence number
+func (c *xconn) request(opcode, data byte, body []byte) (uint16, error) {
+	body = pad(body)
+	req := []byte{opcode, data}
+	req = binary.LittleEndian.AppendUint16(req, uint16(1+len(body)/4))
+	req = append(req, body...)
+	if _, err := c.conn.Write(req); err != nil {
+		return 0, err
+	}
+	c.seq++
+	return c.seq, nil
+}
+
+// readPacket reads the next event, error or reply
+func (c *xconn) readPacket() ([]byte, error) {
+	p := make([]byte, 32)
+	if _, err := io.ReadFull(c.r, p); err != nil {
+		return nil, err
+	}
+	if p[0] == packetReply {
+		extra := make([]byte, int(binary.LittleEndian.Uint32(p[4:]))*4)
+		if _, err := io.ReadFull(c.r, extra); err != nil {
+			return nil, err
+		}
+		p = append(p, extra...)
+	}
+	return p, nil
+}
+
+// keycodes maps keysyms to the keycodes that produce them
+func (c *xconn) keycodes() (map[uint32]byte, error) {
+	count := c.maxKeycode - c.minKeycode + 1
+	if _, err := c.request(opGetKeyboardMapping, 0, []byte{c.minKeycode, count}); err != nil {
+		return nil, err
+	}
+	for {
+		p, err := c.readPacket()
+		if err != nil {
+			return nil, err
+		}
+		switch p[0] {
+		case packetError:
+			return nil, fmt.Errorf("read keyboard mapping: X error %d", p[1])
+		case packetReply:
+			perKey := int(p[1])
+			codes := make(map[uint32]byte)
+			for i := 0; i < int(count) && perKey > 0; i++ {
+				for j := range perKey {
+					at := 32 + (i*perKey+j)*4
+					if at+4 > len(p) {
+						break
+					}
+					sym := binary.LittleEndian.Uint32(p[at:])
+					if _, seen := codes[sym]; sym != 0 && !seen {
+						codes[sym] = c.minKeycode + byte(i)
+					}
+				}
+			}
+			return codes, nil
+		}
+	}
+}
+
+// grab grabs every binding's key on the root window, also with Caps Lock
+// and Num Lock on so those do not get in the way
+func (c *xconn) grab(bindings []Binding) ([]xgrab, error) {
+	codes, err := c.keycodes()
+	if err != nil {
+		return nil, err
+	}
+	var grabs []xgrab
+	owner := make(map[uint16]Hotkey) // request sequence number to hotkey
+	for _, b := range bindings {
+		code, ok := codes[keys[b.Hotkey.Key].keysym]
+		if !ok {
+			return nil, fmt.Errorf("%s is not on this keyboard", b.Hotkey)
+		}
+		mods := xModifiers(b.Hotkey.Mods)
+		grabs = append(grabs, xgrab{keycode: code, mods: mods, action: b.Action})
+		for _, locks := range []uint16{0, xLock, xMod2, xLock | xMod2} {
+			body := binary.LittleEndian.AppendUint32(nil, c.root)
+			body = binary.LittleEndian.AppendUint16(body, mods|locks)
+			body = append(body, code, 1, 1) // asynchronous pointer and keyboard
+			seq, err := c.request(opGrabKey, 1, body)
+			if err != nil {
+				return nil, err
+			}
+			owner[seq] = b.Hotkey
+		}
+	}
+
+	// A round trip flushes out any grab that failed
+	sync, err := c.request(opGetInputFocus, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		p, err := c.readPacket()
+		if err != nil {
+			return nil, err
+		}
+		seq := binary.LittleEndian.Uint16(p[2:])
+		switch {
+		case p[0] == packetError && p[1] == errBadAccess:
+			return nil, fmt.Errorf("%s is already taken by another program", owner[seq])
+		case p[0] == packetError:
+			return nil, fmt.Errorf("grab %s: X error %d", owner[seq], p[1])
+		case p[0] == packetReply && seq == sync:
+			return grabs, nil
+		}
+	}
+}
+
+// run sends the action of each grabbed key pressed until the connection
+// is closed
+func (c *xconn) run(ctx context.Context, grabs []xgrab, out chan<- string) {
+	lastPress := make(map[byte]time.Time)
+	for {
+		p, err := c.readPacket()
+		if err != nil {
+			return
+		}
+		if p[0]&0x7f != packetKeyPress {
+			continue
+		}
+		code := p[1]
+		mods := binary.LittleEndian.Uint16(p[28:]) &^ (xLock | xMod2)
+		now := time.Now()
+		held := now.Sub(lastPress[code]) < repeatWindow
+		lastPress[code] = now
+		if held {
+			continue
+		}
+		for _, g := range grabs {
+			if g.keycode == code && g.mods == mods {
+				select {
+				case out <- g.action:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}
+}
+
+// xModifiers converts modifiers to an X modifier mask
+func xModifiers(m Modifier) uint16 {
+	var mask uint16
+	if m&Shift != 0 {
+		mask |= xShift
+	}
+	if m&Ctrl != 0 {
+		mask |= xControl
+	}
+	if m&Alt != 0 {
+		mask |= xMod1
+	}
+	if m&Super != 0 {
+		mask |= xMod4
+	}
+	return mask
+}
+
+// pad pads b with zeros to a multiple of four bytes
+func pad(b []byte) []byte {
+	return append(b, make([]byte, (4-len(b)%4)%4)...)
+}
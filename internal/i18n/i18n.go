@@ -0,0 +1,162 @@
+// Package i18n translates the text of the UI. Strings are written in
+// English in the code and passed through T, which looks them up in the
+// catalog of the selected locale; text without a translation stays English.
+// Catalogs are JSON objects mapping the English text, format verbs and
+// all, to its translation. The built-in ones can be extended or overridden
+// by files named after the locale, e.g. "de.json", in a directory of the
+// user's.
+//
+// The TUI's text goes through T. Error messages, which come from all
+// layers of the player, log entries and the remote client's screens do
+// not, and stay English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed locales/*.json
+var builtin embed.FS
+
+var (
+	mu      sync.RWMutex
+	catalog map[string]string // English text -> translation; nil for English
+	current = "en"
+)
+
+// T translates format and, given args, formats them into it like
+// fmt.Sprintf
+func T(format string, args ...any) string {
+	mu.RLock()
+	if s, ok := catalog[format]; ok && s != "" {
+		format = s
+	}
+	mu.RUnlock()
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Locale returns the locale in use, "en" when untranslated
+func Locale() string {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Detect picks the locale: configured if set, otherwise the first of the
+// LC_ALL, LC_MESSAGES and LANG environment variables that is
+func Detect(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return "en"
+}
+
+// candidates turns a locale such as "pt_BR.UTF-8" into the catalog names
+// to try, most specific first: "pt-BR", "pt". The C and POSIX locales are
+// English.
+func candidates(locale string) []string {
+	locale, _, _ = strings.Cut(locale, ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	locale = strings.ReplaceAll(locale, "_", "-")
+	if locale == "" || locale == "C" || locale == "POSIX" {
+		return []string{"en"}
+	}
+	lang, region, ok := strings.Cut(locale, "-")
+	lang = strings.ToLower(lang)
+	if !ok {
+		return []string{lang}
+	}
+	return []string{lang + "-" + strings.ToUpper(region), lang}
+}
+
+// Load selects locale, merging the built-in catalog with one of the same
+// name in dir, if any; dir may be empty. A locale without any catalog is
+// an error and leaves the text English.
+func Load(locale, dir string) error {
+	names := candidates(locale)
+	merged := make(map[string]string)
+	found := ""
+	// The general catalog first, so the regional one overrides it
+	for i := len(names) - 1; i >= 0; i-- {
+		name := names[i]
+		if name == "en" {
+			found = name
+			continue
+		}
+		data, err := fs.ReadFile(builtin, "locales/"+name+".json")
+		if err == nil {
+			if err := merge(merged, data); err != nil {
+				return fmt.Errorf("built-in %s catalog: %w", name, err)
+			}
+			found = name
+		}
+		if dir == "" {
+			continue
+		}
+		path := filepath.Join(dir, name+".json")
+		data, err = os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := merge(merged, data); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		found = name
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	switch found {
+	case "":
+		catalog, current = nil, "en"
+		return fmt.Errorf("no translation for %q, there are %s", locale, strings.Join(Available(), ", "))
+	case "en":
+		catalog, current = nil, "en"
+	default:
+		catalog, current = merged, found
+	}
+	return nil
+}
+
+// merge adds the entries of the catalog in data to into
+func merge(into map[string]string, data []byte) error {
+	var entries map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+	for k, v := range entries {
+		into[k] = v
+	}
+	return nil
+}
+
+// Available lists the built-in locales, English included
+func Available() []string {
+	list := []string{"en"}
+	files, _ := fs.Glob(builtin, "locales/*.json")
+	for _, f := range files {
+		list = append(list, strings.TrimSuffix(filepath.Base(f), ".json"))
+	}
+	sort.Strings(list)
+	return list
+}
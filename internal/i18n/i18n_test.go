@@ -0,0 +1,150 @@
+package i18n
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		locale string
+		want   []string
+	}{
+		{"de", []string{"de"}},
+		{"de_DE.UTF-8", []string{"de-DE", "de"}},
+		{"pt-br", []string{"pt-BR", "pt"}},
+		{"sr_RS@latin", []string{"sr-RS", "sr"}},
+		{"C", []string{"en"}},
+		{"POSIX", []string{"en"}},
+		{"", []string{"en"}},
+	}
+	for _, tt := range tests {
+		if got := candidates(tt.locale); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("candidates(%q) = %v, want %v", tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Cleanup(func() { Load("en", "") })
+
+	if err := Load("de_DE.UTF-8", ""); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if Locale() != "de" {
+		t.Errorf("Locale() = %q, want de", Locale())
+	}
+	if got := T("Paused: %s", "Song"); got != "Pausiert: Song" {
+		t.Errorf("T = %q", got)
+	}
+	if got := T("Not in any catalog %d", 3); got != "Not in any catalog 3" {
+		t.Errorf("untranslated T = %q", got)
+	}
+
+	// A user catalog overrides the built-in one and adds locales
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "de.json"), []byte(`{"Stopped": "Angehalten"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "fr.json"), []byte(`{"Stopped": "Arrêté"}`), 0644)
+	if err := Load("de", dir); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := T("Stopped"); got != "Angehalten" {
+		t.Errorf("overridden T = %q", got)
+	}
+	if got := T("Muted"); got != "Stumm" {
+		t.Errorf("built-in T = %q", got)
+	}
+	if err := Load("fr_CA", dir); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got := T("Stopped"); got != "Arrêté" {
+		t.Errorf("user locale T = %q", got)
+	}
+
+	if err := Load("xx", dir); err == nil {
+		t.Error("Load of an unknown locale succeeded")
+	}
+	if Locale() != "en" || T("Stopped") != "Stopped" {
+		t.Errorf("unknown locale left %q", Locale())
+	}
+}
+
+func TestLoad_BadCatalog(t *testing.T) {
+	t.Cleanup(func() { Load("en", "") })
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "de.json"), []byte(`{`), 0644)
+	if err := Load("de", dir); err == nil {
+		t.Error("Load of a malformed catalog succeeded")
+	}
+}
+
+// TestCatalogsCoverUI checks that every built-in catalog translates the
+// text the TUI passes to T as a literal. The remote client's screens and
+// error messages are not translated.
+func TestCatalogsCoverUI(t *testing.T) {
+	var texts []string
+	err := filepath.WalkDir("../ui", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "screens" {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+		if err != nil {
+			return err
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) == 0 {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok || sel.Sel.Name != "T" {
+				return true
+			}
+			if pkg, ok := sel.X.(*ast.Ident); !ok || pkg.Name != "i18n" {
+				return true
+			}
+			if lit, ok := call.Args[0].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+				text, _ := strconv.Unquote(lit.Value)
+				texts = append(texts, text)
+			}
+			return true
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(texts) == 0 {
+		t.Fatal("no text found in the UI")
+	}
+
+	files, _ := fs.Glob(builtin, "locales/*.json")
+	for _, f := range files {
+		data, _ := fs.ReadFile(builtin, f)
+		var catalog map[string]string
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			t.Fatalf("%s: %v", f, err)
+		}
+		for _, text := range texts {
+			if catalog[text] == "" {
+				t.Errorf("%s does not translate %q", f, text)
+			}
+		}
+	}
+}
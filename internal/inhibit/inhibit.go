@@ -0,0 +1,98 @@
+// Package inhibit keeps the system from idling to sleep, and optionally the
+// screen from blanking and locking, while music plays. Each platform
+// provides its own mechanism: systemd-inhibit (or gnome-session-inhibit on
+// GNOME, which ignores logind's idle lock) on Linux, caffeinate on macOS
+// and SetThreadExecutionState on Windows.
+package inhibit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jscyril/golang_music_player/internal/logger"
+)
+
+// Reason is shown by the OS to explain who holds the inhibitor
+const Reason = "Playing music"
+
+// backend is a platform sleep inhibitor
+type backend interface {
+	acquire() error
+	release() error
+	// alive reports whether an acquired inhibitor is still in force
+	alive() bool
+}
+
+// Inhibitor holds or releases the platform idle/sleep inhibitor
+type Inhibitor struct {
+	mu      sync.Mutex
+	backend backend
+	held    bool
+}
+
+// New returns an inhibitor for the current platform. With screen set it
+// also keeps the display on, so the screen neither blanks nor locks.
+func New(screen bool) *Inhibitor {
+	return &Inhibitor{backend: newBackend(screen)}
+}
+
+// Set acquires the inhibitor when active is true and releases it otherwise.
+// Repeated calls with the same value are no-ops, unless the inhibitor was
+// lost meanwhile, e.g. its helper process was killed; then it is acquired
+// anew.
+func (i *Inhibitor) Set(active bool) error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.held && !i.backend.alive() {
+		logger.Debug("Sleep inhibitor lost")
+		i.backend.release()
+		i.held = false
+	}
+	if active == i.held {
+		return nil
+	}
+	if active {
+		if err := i.backend.acquire(); err != nil {
+			return err
+		}
+		logger.Debug("Sleep inhibitor acquired")
+	} else {
+		if err := i.backend.release(); err != nil {
+			return err
+		}
+		logger.Debug("Sleep inhibitor released")
+	}
+	i.held = active
+	return nil
+}
+
+// Release drops the inhibitor if held
+func (i *Inhibitor) Release() error {
+	return i.Set(false)
+}
+
+// Watch polls playing every interval and holds the inhibitor while it
+// reports true. The inhibitor is released when ctx is done.
+func (i *Inhibitor) Watch(ctx context.Context, interval time.Duration, playing func() bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	defer i.Release()
+
+	failed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := i.Set(playing()); err != nil {
+				// Log once; a missing tool will keep failing on every tick
+				if !failed {
+					logger.Warn("Sleep inhibitor unavailable: %v", err)
+				}
+				failed = true
+			}
+		}
+	}
+}
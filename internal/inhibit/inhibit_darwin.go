@@ -0,0 +1,14 @@
+//go:build darwin
+
+package inhibit
+
+// newBackend uses caffeinate, which holds IOKit power assertions
+// (-i idle sleep, -s system sleep on AC, -d display sleep, which also
+// starts the screen saver and lock) until it is killed
+func newBackend(screen bool) backend {
+	args := []string{"-i", "-s"}
+	if screen {
+		args = append(args, "-d")
+	}
+	return &execBackend{name: "caffeinate", args: args}
+}
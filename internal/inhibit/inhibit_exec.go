@@ -0,0 +1,58 @@
+//go:build linux || darwin
+
+package inhibit
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+// execBackend holds the inhibitor for as long as a helper process runs
+type execBackend struct {
+	name   string
+	args   []string
+	cmd    *exec.Cmd
+	exited chan struct{} // closed once cmd has exited
+}
+
+func (b *execBackend) acquire() error {
+	cmd := exec.Command(b.name, b.args...)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start %s: %w", b.name, err)
+	}
+	exited := make(chan struct{})
+	// Reap the process whenever it exits so it never lingers as a zombie
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	b.cmd, b.exited = cmd, exited
+	return nil
+}
+
+func (b *execBackend) release() error {
+	if b.cmd == nil || b.cmd.Process == nil {
+		return nil
+	}
+	err := b.cmd.Process.Kill()
+	b.cmd, b.exited = nil, nil
+	if err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return fmt.Errorf("stop %s: %w", b.name, err)
+	}
+	return nil
+}
+
+// alive reports whether the helper process is still running
+func (b *execBackend) alive() bool {
+	if b.exited == nil {
+		return false
+	}
+	select {
+	case <-b.exited:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,27 @@
+//go:build linux || darwin
+
+package inhibit
+
+import (
+	"testing"
+	"time"
+)
+
+// TestExecBackend_Exit verifies a helper that exits no longer counts as
+// holding the inhibitor
+func TestExecBackend_Exit(t *testing.T) {
+	b := &execBackend{name: "true"}
+	if err := b.acquire(); err != nil {
+		t.Skipf("no true command: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for b.alive() {
+		if time.Now().After(deadline) {
+			t.Fatal("exited helper still reported alive")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := b.release(); err != nil {
+		t.Errorf("release after exit: %v", err)
+	}
+}
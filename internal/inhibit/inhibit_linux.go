@@ -0,0 +1,42 @@
+//go:build linux
+
+package inhibit
+
+import "os/exec"
+
+// newBackend blocks sleep via logind for the life of a child process, and
+// with screen idle as well, which keeps desktops honouring logind's idle
+// lock (KDE, swayidle, xss-lock) from blanking and locking the screen.
+// GNOME only listens to its session manager, so there gnome-session-inhibit
+// holds both instead.
+func newBackend(screen bool) backend {
+	if !screen {
+		return systemdInhibit("sleep")
+	}
+	if _, err := exec.LookPath("gnome-session-inhibit"); err == nil {
+		return &execBackend{
+			name: "gnome-session-inhibit",
+			args: []string{
+				"--app-id", "gtmpc",
+				"--reason", Reason,
+				"--inhibit", "idle:suspend",
+				"sleep", "infinity",
+			},
+		}
+	}
+	return systemdInhibit("idle:sleep")
+}
+
+// systemdInhibit takes a logind inhibitor lock on what
+func systemdInhibit(what string) backend {
+	return &execBackend{
+		name: "systemd-inhibit",
+		args: []string{
+			"--what=" + what,
+			"--who=gtmpc",
+			"--why=" + Reason,
+			"--mode=block",
+			"sleep", "infinity",
+		},
+	}
+}
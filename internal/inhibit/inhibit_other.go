@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !windows
+
+package inhibit
+
+// noopBackend is used where no inhibit mechanism is known
+type noopBackend struct{}
+
+func newBackend(screen bool) backend { return noopBackend{} }
+
+func (noopBackend) acquire() error { return nil }
+func (noopBackend) release() error { return nil }
+func (noopBackend) alive() bool    { return true }
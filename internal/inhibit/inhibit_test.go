@@ -0,0 +1,31 @@
+package inhibit
+
+import "testing"
+
+// fakeBackend counts acquisitions and can lose its inhibitor
+type fakeBackend struct {
+	acquired int
+	held     bool
+}
+
+func (b *fakeBackend) acquire() error { b.acquired++; b.held = true; return nil }
+func (b *fakeBackend) release() error { b.held = false; return nil }
+func (b *fakeBackend) alive() bool    { return b.held }
+
+// TestSet_ReacquiresLostInhibitor verifies an inhibitor that went away,
+// e.g. a killed helper process, is taken again instead of counted as held
+func TestSet_ReacquiresLostInhibitor(t *testing.T) {
+	b := &fakeBackend{}
+	i := &Inhibitor{backend: b}
+
+	i.Set(true)
+	i.Set(true)
+	if b.acquired != 1 {
+		t.Fatalf("acquired %d times while held, want 1", b.acquired)
+	}
+	b.held = false
+	i.Set(true)
+	if b.acquired != 2 || !b.held {
+		t.Errorf("acquired %d times after losing it, want 2", b.acquired)
+	}
+}
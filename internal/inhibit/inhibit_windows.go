@@ -0,0 +1,58 @@
+//go:build windows
+
+package inhibit
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+const (
+	esContinuous      = 0x80000000
+	esSystemRequired  = 0x00000001
+	esDisplayRequired = 0x00000002
+)
+
+var setThreadExecutionState = syscall.NewLazyDLL("kernel32.dll").NewProc("SetThreadExecutionState")
+
+// winBackend calls SetThreadExecutionState. The state belongs to the calling
+// thread, so all calls are made from one goroutine locked to its OS thread.
+type winBackend struct {
+	flags    uintptr // held while acquired
+	requests chan uintptr
+	results  chan error
+}
+
+// newBackend keeps the system awake, and with screen the display on as
+// well, which also holds off the screen saver and lock
+func newBackend(screen bool) backend {
+	b := &winBackend{flags: esContinuous | esSystemRequired, requests: make(chan uintptr), results: make(chan error)}
+	if screen {
+		b.flags |= esDisplayRequired
+	}
+	go func() {
+		runtime.LockOSThread()
+		for flags := range b.requests {
+			if r, _, err := setThreadExecutionState.Call(flags); r == 0 {
+				b.results <- fmt.Errorf("SetThreadExecutionState: %w", err)
+				continue
+			}
+			b.results <- nil
+		}
+	}()
+	return b
+}
+
+func (b *winBackend) acquire() error {
+	b.requests <- b.flags
+	return <-b.results
+}
+
+func (b *winBackend) release() error {
+	b.requests <- esContinuous
+	return <-b.results
+}
+
+// alive is always true: the execution state lasts until it is changed
+func (b *winBackend) alive() bool { return true }
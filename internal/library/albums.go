@@ -0,0 +1,257 @@
+package library
+
+import (
+	"crypto/md5"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/dhowden/tag"
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/artwork"
+	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
+)
+
+// variousArtists is the artist of an album whose tracks name different ones
+const variousArtists = "Various Artists"
+
+// discDir matches folders that hold one disc of a multi-disc album
+var discDir = regexp.MustCompile(`(?i)^(cd|dis[ck])[ _-]?\d+$`)
+
+// albumDir returns the directory a track's album lives in, looking past a
+// per-disc folder such as "CD1" or "Disc 2"
+func albumDir(path string) string {
+	dir := filepath.Dir(path)
+	if discDir.MatchString(filepath.Base(dir)) {
+		return filepath.Dir(dir)
+	}
+	return dir
+}
+
+// albumIdentity returns the ID and name of the album a track belongs to.
+// Tracks with an album artist tag group by album name and album artist,
+// wherever their files are. Other tagged tracks group by album name and
+// directory, so compilations stay together while albums sharing a name
+// ("Greatest Hits") stay apart; untagged tracks group by directory.
+func albumIdentity(t *api.Track) (id, name string) {
+	dir := albumDir(t.FilePath)
+	name = t.Album
+	untagged := name == "" || name == unknownAlbum
+	if untagged {
+		name = filepath.Base(dir)
+	}
+	key := strings.ToLower(t.Album) + "\x00" + dir
+	if t.AlbumArtist != "" && !untagged {
+		key = strings.ToLower(t.Album) + "\x00artist\x00" + strings.ToLower(t.AlbumArtist)
+	}
+	hash := md5.Sum([]byte(key))
+	return fmt.Sprintf("album-%x", hash[:8]), name
+}
+
+// AlbumID returns the ID of the album a track belongs to, as listed by
+// Albums, whether or not the track is in the library
+func AlbumID(t *api.Track) string {
+	id, _ := albumIdentity(t)
+	return id
+}
+
+// albumArtist returns the artist a track is filed under: its album artist
+// if tagged, else its artist
+func albumArtist(t *api.Track) string {
+	if t.AlbumArtist != "" {
+		return t.AlbumArtist
+	}
+	return t.Artist
+}
+
+// readCompilation reports whether a file is flagged as part of a
+// compilation: the iTunes TCMP frame, a COMPILATION Vorbis comment or the
+// MP4 cpil atom, set to 1
+func readCompilation(m tag.Metadata) bool {
+	for name, v := range m.Raw() {
+		switch strings.ToLower(name) {
+		case "tcmp", "compilation", "cpil":
+			if isTrue(fmt.Sprint(v)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// linkAlbums moves tracks to their albums, creating albums as needed and
+// dropping ones left empty, then refreshes each album touched once.
+// Archived tracks belong to no album. Callers hold l.mu.
+func (l *Library) linkAlbums(tracks ...*api.Track) {
+	touched := make(map[string]bool)
+	for _, t := range tracks {
+		touched[l.detachAlbum(t.ID)] = true
+		if !l.Archived[t.ID] {
+			touched[l.attachAlbum(t)] = true
+		}
+	}
+	for id := range touched {
+		if a, ok := l.albums[id]; ok {
+			l.refreshAlbum(a)
+		}
+	}
+}
+
+// unlinkAlbum removes a track from its album. Callers hold l.mu.
+func (l *Library) unlinkAlbum(trackID string) {
+	if a, ok := l.albums[l.detachAlbum(trackID)]; ok {
+		l.refreshAlbum(a)
+	}
+}
+
+// attachAlbum adds a track to its album, creating the album if needed,
+// and returns the album's ID
+func (l *Library) attachAlbum(t *api.Track) string {
+	id, name := albumIdentity(t)
+	a, ok := l.albums[id]
+	if !ok {
+		a = &api.Album{ID: id, Name: name, ArtPath: artwork.Sidecar(albumDir(t.FilePath))}
+		if a.ArtPath == "" {
+			a.ArtPath = artwork.Sidecar(filepath.Dir(t.FilePath))
+		}
+		l.albums[id] = a
+	}
+	a.TrackIDs = append(a.TrackIDs, t.ID)
+	l.trackAlbum[t.ID] = id
+	return id
+}
+
+// detachAlbum removes a track from its album, dropping the album once it
+// is empty, and returns the album's ID or "" when the track had none
+func (l *Library) detachAlbum(trackID string) string {
+	id, ok := l.trackAlbum[trackID]
+	if !ok {
+		return ""
+	}
+	delete(l.trackAlbum, trackID)
+	a := l.albums[id]
+	a.TrackIDs = slices.DeleteFunc(a.TrackIDs, func(tid string) bool { return tid == trackID })
+	if len(a.TrackIDs) == 0 {
+		delete(l.albums, id)
+	}
+	return id
+}
+
+// refreshAlbum orders an album's tracks and recomputes its artist, year
+// and duration. The artist is the album artist tag, else the one artist
+// every track names, else "Various Artists". Callers hold l.mu.
+func (l *Library) refreshAlbum(a *api.Album) {
+	tracks := make([]*api.Track, 0, len(a.TrackIDs))
+	for _, id := range a.TrackIDs {
+		tracks = append(tracks, l.Tracks[id])
+	}
+	sort.SliceStable(tracks, func(i, j int) bool {
+		// Disc folders sort by path before track numbers restart
+		di, dj := filepath.Dir(tracks[i].FilePath), filepath.Dir(tracks[j].FilePath)
+		if di != dj {
+			return di < dj
+		}
+		if tracks[i].TrackNum != tracks[j].TrackNum {
+			return tracks[i].TrackNum < tracks[j].TrackNum
+		}
+		return tracks[i].FilePath < tracks[j].FilePath
+	})
+
+	a.Artist, a.Year, a.Duration = albumArtist(tracks[0]), 0, 0
+	for i, t := range tracks {
+		a.TrackIDs[i] = t.ID
+		a.Duration += t.Duration
+		if t.AlbumArtist == "" && (t.Compilation || t.Artist != a.Artist) {
+			a.Artist = variousArtists
+		}
+		if t.Year > 0 && (a.Year == 0 || t.Year < a.Year) {
+			a.Year = t.Year
+		}
+	}
+}
+
+// rebuildAlbums derives every album from the tracks. Callers hold l.mu.
+func (l *Library) rebuildAlbums() {
+	l.albums = make(map[string]*api.Album)
+	l.trackAlbum = make(map[string]string)
+	tracks := make([]*api.Track, 0, len(l.Tracks))
+	for _, t := range l.Tracks {
+		tracks = append(tracks, t)
+	}
+	l.linkAlbums(tracks...)
+}
+
+// cloneAlbum copies an album so callers cannot change the library's
+func cloneAlbum(a *api.Album) *api.Album {
+	c := *a
+	c.TrackIDs = slices.Clone(a.TrackIDs)
+	return &c
+}
+
+// Albums returns every album, sorted by artist, year and name
+func (l *Library) Albums() []*api.Album {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	albums := make([]*api.Album, 0, len(l.albums))
+	for _, a := range l.albums {
+		albums = append(albums, cloneAlbum(a))
+	}
+	sort.Slice(albums, func(i, j int) bool {
+		a, b := albums[i], albums[j]
+		if a.Artist != b.Artist {
+			return a.Artist < b.Artist
+		}
+		if a.Year != b.Year {
+			return a.Year < b.Year
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+	return albums
+}
+
+// GetAlbum returns an album by ID
+func (l *Library) GetAlbum(id string) (*api.Album, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	a, ok := l.albums[id]
+	if !ok {
+		return nil, playerrors.ErrAlbumNotFound
+	}
+	return cloneAlbum(a), nil
+}
+
+// TrackAlbum returns the album of the track with the given ID
+func (l *Library) TrackAlbum(trackID string) (*api.Album, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	id, ok := l.trackAlbum[trackID]
+	if !ok {
+		return nil, playerrors.ErrAlbumNotFound
+	}
+	return cloneAlbum(l.albums[id]), nil
+}
+
+// AlbumTracks returns the tracks of an album in order
+func (l *Library) AlbumTracks(id string) []*api.Track {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	a, ok := l.albums[id]
+	if !ok {
+		return nil
+	}
+	tracks := make([]*api.Track, 0, len(a.TrackIDs))
+	for _, tid := range a.TrackIDs {
+		tracks = append(tracks, l.Tracks[tid])
+	}
+	return tracks
+}
@@ -0,0 +1,92 @@
+package library
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestAlbums verifies albums group compilations and disc folders, order
+// their tracks and follow archiving and removal
+func TestAlbums(t *testing.T) {
+	lib := NewLibrary()
+	lib.AddTracks([]*api.Track{
+		{ID: "a2", Title: "Two", Artist: "A", Album: "Hits", Year: 1999, TrackNum: 1, Duration: time.Minute, FilePath: "/m/Hits/CD2/01.mp3"},
+		{ID: "a1", Title: "One", Artist: "B", Album: "Hits", Year: 1998, TrackNum: 1, Duration: time.Minute, FilePath: "/m/Hits/CD1/01.mp3"},
+		{ID: "b1", Title: "Other", Artist: "C", Album: "Hits", FilePath: "/m/C/Hits/01.mp3"},
+	})
+
+	albums := lib.Albums()
+	if len(albums) != 2 {
+		t.Fatalf("got %d albums, want 2", len(albums))
+	}
+	hits, err := lib.TrackAlbum("a1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits.Artist != variousArtists || hits.Year != 1998 || hits.Duration != 2*time.Minute {
+		t.Errorf("album = %+v, want Various Artists, 1998, 2m", hits)
+	}
+	if len(hits.TrackIDs) != 2 || hits.TrackIDs[0] != "a1" {
+		t.Errorf("track IDs = %v, want [a1 a2]", hits.TrackIDs)
+	}
+
+	lib.SetArchived("a1", true)
+	if hits, _ = lib.GetAlbum(hits.ID); hits.Artist != "A" || len(hits.TrackIDs) != 1 {
+		t.Errorf("after archiving a1 album = %+v", hits)
+	}
+	lib.RemoveTrack("a2")
+	if _, err := lib.GetAlbum(hits.ID); err == nil {
+		t.Error("album of removed tracks still listed")
+	}
+}
+
+// TestArtistAlbums verifies artists count their tracks and list the albums
+// they appear on oldest first, compilations included
+func TestArtistAlbums(t *testing.T) {
+	lib := NewLibrary()
+	lib.AddTracks([]*api.Track{
+		{ID: "new", Artist: "A", Album: "Later", Year: 2005, Duration: time.Minute, FilePath: "/m/A/Later/01.mp3"},
+		{ID: "old", Artist: "A", Album: "First", Year: 1990, Duration: time.Minute, FilePath: "/m/A/First/01.mp3"},
+		{ID: "comp", Artist: "A", Album: "Mix", Year: 2000, Duration: time.Minute, FilePath: "/m/Mix/01.mp3"},
+		{ID: "other", Artist: "B", Album: "Mix", Year: 2000, FilePath: "/m/Mix/02.mp3"},
+	})
+
+	ar, err := lib.GetArtist("A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ar.AlbumCount != 3 || ar.TrackCount != 3 || ar.Duration != 3*time.Minute {
+		t.Errorf("artist = %+v, want 3 albums, 3 tracks, 3m", ar)
+	}
+	var names []string
+	for _, a := range lib.ArtistAlbums("A") {
+		names = append(names, a.Name)
+	}
+	if len(names) != 3 || names[0] != "First" || names[1] != "Mix" || names[2] != "Later" {
+		t.Errorf("albums = %v, want [First Mix Later]", names)
+	}
+	if tracks := lib.Discography("A"); len(tracks) != 3 || tracks[1].ID != "comp" {
+		t.Errorf("discography has %d tracks, want old, comp, new", len(tracks))
+	}
+}
+
+// TestAlbumArtist verifies the album artist tag groups an album across
+// folders and the compilation flag credits it to Various Artists
+func TestAlbumArtist(t *testing.T) {
+	lib := NewLibrary()
+	lib.AddTracks([]*api.Track{
+		{ID: "x1", Artist: "X feat. Y", AlbumArtist: "X", Album: "Split", FilePath: "/m/X/Split/01.mp3"},
+		{ID: "x2", Artist: "X", AlbumArtist: "X", Album: "Split", FilePath: "/m/Downloads/02.mp3"},
+		{ID: "c1", Artist: "P", Album: "Hits", Compilation: true, FilePath: "/m/Hits/01.mp3"},
+	})
+
+	split, _ := lib.TrackAlbum("x1")
+	if split == nil || split.Artist != "X" || len(split.TrackIDs) != 2 {
+		t.Errorf("album artist album = %+v, want X with 2 tracks", split)
+	}
+	if hits, _ := lib.TrackAlbum("c1"); hits == nil || hits.Artist != variousArtists {
+		t.Errorf("compilation album = %+v, want %s", hits, variousArtists)
+	}
+}
@@ -0,0 +1,102 @@
+package library
+
+import (
+	"sort"
+
+	"github.com/jscyril/golang_music_player/api"
+	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
+)
+
+// artist aggregates the tracks tagged with name, or returns nil when
+// there are none. Callers hold l.mu.
+func (l *Library) artist(name string) *api.Artist {
+	ar := &api.Artist{Name: name}
+	albums := make(map[string]*api.Album)
+	for _, id := range l.artistIndex[name] {
+		t, ok := l.Tracks[id]
+		if !ok || l.Archived[id] {
+			continue
+		}
+		ar.TrackCount++
+		ar.Duration += t.Duration
+		if albumID, ok := l.trackAlbum[id]; ok {
+			albums[albumID] = l.albums[albumID]
+		}
+	}
+	if ar.TrackCount == 0 {
+		return nil
+	}
+
+	sorted := make([]*api.Album, 0, len(albums))
+	for _, a := range albums {
+		sorted = append(sorted, a)
+	}
+	sortChronologically(sorted)
+	for _, a := range sorted {
+		ar.AlbumIDs = append(ar.AlbumIDs, a.ID)
+	}
+	ar.AlbumCount = len(ar.AlbumIDs)
+	return ar
+}
+
+// sortChronologically orders albums oldest first, those without a year
+// last, then by name
+func sortChronologically(albums []*api.Album) {
+	sort.Slice(albums, func(i, j int) bool {
+		a, b := albums[i], albums[j]
+		if (a.Year == 0) != (b.Year == 0) {
+			return b.Year == 0
+		}
+		if a.Year != b.Year {
+			return a.Year < b.Year
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.ID < b.ID
+	})
+}
+
+// Artists returns every artist with their counts, sorted by name
+func (l *Library) Artists() []*api.Artist {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	artists := make([]*api.Artist, 0, len(l.artistIndex))
+	for name := range l.artistIndex {
+		if ar := l.artist(name); ar != nil {
+			artists = append(artists, ar)
+		}
+	}
+	sort.Slice(artists, func(i, j int) bool { return artists[i].Name < artists[j].Name })
+	return artists
+}
+
+// GetArtist returns an artist by name
+func (l *Library) GetArtist(name string) (*api.Artist, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	ar := l.artist(name)
+	if ar == nil {
+		return nil, playerrors.ErrArtistNotFound
+	}
+	return ar, nil
+}
+
+// ArtistAlbums returns the albums an artist appears on, including
+// compilations, oldest first
+func (l *Library) ArtistAlbums(name string) []*api.Album {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	ar := l.artist(name)
+	if ar == nil {
+		return nil
+	}
+	albums := make([]*api.Album, len(ar.AlbumIDs))
+	for i, id := range ar.AlbumIDs {
+		albums[i] = cloneAlbum(l.albums[id])
+	}
+	return albums
+}
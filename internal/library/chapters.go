@@ -0,0 +1,170 @@
+package library
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/dhowden/tag"
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// readChapters extracts chapter marks from ID3v2 CHAP frames (MP3) or
+// CHAPTERnnn/CHAPTERnnnNAME Vorbis comments (FLAC). Chapters are returned
+// in start order with every End filled in; duration closes the last one.
+func readChapters(m tag.Metadata, duration time.Duration) []api.Chapter {
+	var chapters []api.Chapter
+	switch m.Format() {
+	case tag.ID3v2_3, tag.ID3v2_4:
+		chapters = id3Chapters(m)
+	case tag.VORBIS:
+		chapters = vorbisChapters(m)
+	}
+	if len(chapters) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start })
+	for i := range chapters {
+		if chapters[i].Title == "" {
+			chapters[i].Title = fmt.Sprintf("Chapter %d", i+1)
+		}
+		if i+1 < len(chapters) && (chapters[i].End <= chapters[i].Start || chapters[i].End > chapters[i+1].Start) {
+			chapters[i].End = chapters[i+1].Start
+		}
+	}
+	if last := &chapters[len(chapters)-1]; last.End <= last.Start {
+		last.End = duration
+	}
+	return chapters
+}
+
+// id3Chapters parses CHAP frames, which the tag library hands back as raw
+// bytes under "CHAP", "CHAP_0", "CHAP_1", ...
+func id3Chapters(m tag.Metadata) []api.Chapter {
+	synchsafe := m.Format() == tag.ID3v2_4
+	var chapters []api.Chapter
+	for name, v := range m.Raw() {
+		if name != "CHAP" && !strings.HasPrefix(name, "CHAP_") {
+			continue
+		}
+		b, ok := v.([]byte)
+		if !ok {
+			continue
+		}
+		if ch, ok := parseCHAP(b, synchsafe); ok {
+			chapters = append(chapters, ch)
+		}
+	}
+	return chapters
+}
+
+// parseCHAP decodes a CHAP frame body: a null-terminated element ID, start
+// and end times in milliseconds, two byte offsets, then embedded sub-frames
+// of which only TIT2 (the chapter title) is used
+func parseCHAP(b []byte, synchsafe bool) (api.Chapter, bool) {
+	nul := strings.IndexByte(string(b), 0)
+	if nul < 0 || len(b) < nul+1+16 {
+		return api.Chapter{}, false
+	}
+	b = b[nul+1:]
+	ch := api.Chapter{
+		Start: time.Duration(binary.BigEndian.Uint32(b[0:4])) * time.Millisecond,
+		End:   time.Duration(binary.BigEndian.Uint32(b[4:8])) * time.Millisecond,
+	}
+	b = b[16:]
+
+	for len(b) >= 10 {
+		id := string(b[0:4])
+		size := int(binary.BigEndian.Uint32(b[4:8]))
+		if synchsafe {
+			size = int(b[4])<<21 | int(b[5])<<14 | int(b[6])<<7 | int(b[7])
+		}
+		b = b[10:]
+		if size > len(b) {
+			break
+		}
+		if id == "TIT2" {
+			ch.Title = decodeID3Text(b[:size])
+		}
+		b = b[size:]
+	}
+	return ch, true
+}
+
+// decodeID3Text decodes an ID3v2 text frame body (encoding byte + text)
+func decodeID3Text(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	enc, b := b[0], b[1:]
+	switch enc {
+	case 1, 2: // UTF-16 with BOM, UTF-16BE
+		order := binary.ByteOrder(binary.BigEndian)
+		if len(b) >= 2 && b[0] == 0xFF && b[1] == 0xFE {
+			order, b = binary.LittleEndian, b[2:]
+		} else if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+			b = b[2:]
+		}
+		u := make([]uint16, 0, len(b)/2)
+		for i := 0; i+1 < len(b); i += 2 {
+			u = append(u, order.Uint16(b[i:]))
+		}
+		return strings.TrimRight(string(utf16.Decode(u)), "\x00")
+	case 3: // UTF-8
+		return strings.TrimRight(string(b), "\x00")
+	default: // ISO-8859-1
+		r := make([]rune, 0, len(b))
+		for _, c := range b {
+			r = append(r, rune(c))
+		}
+		return strings.TrimRight(string(r), "\x00")
+	}
+}
+
+// vorbisChapters reads the CHAPTERnnn=HH:MM:SS.mmm / CHAPTERnnnNAME
+// convention used by FLAC and Ogg audiobooks
+func vorbisChapters(m tag.Metadata) []api.Chapter {
+	var chapters []api.Chapter
+	raw := m.Raw()
+	for key, v := range raw {
+		num, ok := strings.CutPrefix(key, "chapter")
+		if !ok || len(num) != 3 {
+			continue
+		}
+		ts, _ := v.(string)
+		start, err := parseChapterTime(ts)
+		if err != nil {
+			continue
+		}
+		title, _ := raw[key+"name"].(string)
+		chapters = append(chapters, api.Chapter{Title: title, Start: start})
+	}
+	return chapters
+}
+
+// parseChapterTime parses "HH:MM:SS.mmm" (fractional part optional)
+func parseChapterTime(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("bad chapter time %q", s)
+	}
+	h, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("bad chapter time %q", s)
+	}
+	mins, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("bad chapter time %q", s)
+	}
+	secs, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil || h < 0 || mins < 0 || !(secs >= 0) {
+		return 0, fmt.Errorf("bad chapter time %q", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(mins)*time.Minute +
+		time.Duration(secs*float64(time.Second)), nil
+}
@@ -0,0 +1,169 @@
+package library
+
+import (
+	"encoding/binary"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/dhowden/tag"
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// rawTags is tag metadata that only has a format and raw frames
+type rawTags struct {
+	tag.Metadata
+	format tag.Format
+	raw    map[string]interface{}
+}
+
+func (m rawTags) Format() tag.Format          { return m.format }
+func (m rawTags) Raw() map[string]interface{} { return m.raw }
+
+// chapFrame builds a CHAP frame body starting at start ms and ending at
+// end ms, followed by sub-frames
+func chapFrame(id string, start, end uint32, sub ...[]byte) []byte {
+	b := append([]byte(id), 0)
+	b = binary.BigEndian.AppendUint32(b, start)
+	b = binary.BigEndian.AppendUint32(b, end)
+	b = append(b, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+	for _, s := range sub {
+		b = append(b, s...)
+	}
+	return b
+}
+
+// subFrame builds an embedded frame with a plain or synchsafe size
+func subFrame(id string, body []byte, synchsafe bool) []byte {
+	b := []byte(id)
+	n := uint32(len(body))
+	if synchsafe {
+		b = append(b, byte(n>>21&0x7F), byte(n>>14&0x7F), byte(n>>7&0x7F), byte(n&0x7F))
+	} else {
+		b = binary.BigEndian.AppendUint32(b, n)
+	}
+	return append(append(b, 0, 0), body...)
+}
+
+// TestParseCHAP verifies CHAP frames give their times and TIT2 title in
+// every text encoding, and that malformed frames are rejected or read as
+// far as they go
+func TestParseCHAP(t *testing.T) {
+	long := make([]byte, 200)
+	long[0] = 3
+	copy(long[1:], "Long")
+	tests := []struct {
+		name      string
+		frame     []byte
+		synchsafe bool
+		want      api.Chapter
+		ok        bool
+	}{
+		{"latin-1 title", chapFrame("ch0", 0, 61500, subFrame("TIT2", []byte("\x00Caf\xe9"), false)), false,
+			api.Chapter{Title: "Café", End: 61500 * time.Millisecond}, true},
+		{"utf-8 title", chapFrame("ch1", 61500, 0, subFrame("TIT2", []byte("\x03Kapitel Zwei\x00"), false)), false,
+			api.Chapter{Title: "Kapitel Zwei", Start: 61500 * time.Millisecond}, true},
+		{"utf-16 title", chapFrame("ch2", 1000, 2000, subFrame("TIT2", []byte("\x01\xff\xfeO\x00n\x00e\x00"), false)), false,
+			api.Chapter{Title: "One", Start: time.Second, End: 2 * time.Second}, true},
+		{"utf-16be title", chapFrame("ch3", 0, 0, subFrame("TIT2", []byte("\x02\x00H\x00i"), false)), false,
+			api.Chapter{Title: "Hi"}, true},
+		{"other frames first", chapFrame("ch4", 5, 6,
+			subFrame("APIC", []byte("\x00image/png\x00\x03\x00data"), false),
+			subFrame("TIT2", []byte("\x03Third"), false)), false,
+			api.Chapter{Title: "Third", Start: 5 * time.Millisecond, End: 6 * time.Millisecond}, true},
+		{"synchsafe size", chapFrame("ch5", 0, 0, subFrame("TIT2", long, true)), true,
+			api.Chapter{Title: "Long"}, true},
+		{"no title", chapFrame("ch6", 7000, 8000), false,
+			api.Chapter{Start: 7 * time.Second, End: 8 * time.Second}, true},
+		{"sub-frame overruns", append(chapFrame("ch7", 1000, 0), []byte("TIT2\x00\x00\x00\x50\x00\x00\x03Cut")...), false,
+			api.Chapter{Start: time.Second}, true},
+		{"short sub-frame header", append(chapFrame("ch8", 1000, 0), "TIT2"...), false,
+			api.Chapter{Start: time.Second}, true},
+		{"empty title frame", chapFrame("ch9", 0, 0, subFrame("TIT2", nil, false)), false, api.Chapter{}, true},
+		{"no element ID terminator", []byte("chapter"), false, api.Chapter{}, false},
+		{"times cut off", append([]byte("ch\x00"), 0, 0, 0, 1, 0, 0), false, api.Chapter{}, false},
+		{"empty", nil, false, api.Chapter{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCHAP(tt.frame, tt.synchsafe)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("parseCHAP = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// TestParseChapterTime verifies HH:MM:SS with and without a fraction, and
+// rejects other shapes
+func TestParseChapterTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"00:00:00.000", 0, true},
+		{"01:02:03.500", time.Hour + 2*time.Minute + 3500*time.Millisecond, true},
+		{" 00:10:05 ", 10*time.Minute + 5*time.Second, true},
+		{"12:00:00.25", 12*time.Hour + 250*time.Millisecond, true},
+		{"10:05", 0, false},
+		{"1:2:3:4", 0, false},
+		{"aa:00:00", 0, false},
+		{"00:bb:00", 0, false},
+		{"00:00:cc", 0, false},
+		{"-1:00:00", 0, false},
+		{"00:00:NaN", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseChapterTime(tt.in)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseChapterTime(%q) = %v, %v; want %v, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestVorbisChapters verifies CHAPTERnnn comments with their NAME become
+// chapters, and that other keys and bad times are skipped
+func TestVorbisChapters(t *testing.T) {
+	m := rawTags{format: tag.VORBIS, raw: map[string]interface{}{
+		"chapter001":     "00:00:00.000",
+		"chapter001name": "Opening",
+		"chapter002":     "00:12:30.250",
+		"chapter003":     "later",
+		"chapter0004":    "00:20:00",
+		"chapters":       "00:30:00",
+		"title":          "The Book",
+	}}
+	got := vorbisChapters(m)
+	slices.SortFunc(got, func(a, b api.Chapter) int { return int(a.Start - b.Start) })
+	want := []api.Chapter{
+		{Title: "Opening"},
+		{Start: 12*time.Minute + 30250*time.Millisecond},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("vorbisChapters = %+v, want %+v", got, want)
+	}
+}
+
+// TestReadChapters verifies chapters are sorted, named when untitled, and
+// each ends where the next starts, the last at the track's end
+func TestReadChapters(t *testing.T) {
+	m := rawTags{format: tag.ID3v2_3, raw: map[string]interface{}{
+		"CHAP":   chapFrame("b", 60000, 0, subFrame("TIT2", []byte("\x03Second"), false)),
+		"CHAP_0": chapFrame("a", 0, 90000),
+		"CHAP_1": []byte("broken"),
+		"TIT2":   "Book",
+	}}
+	got := readChapters(m, 5*time.Minute)
+	want := []api.Chapter{
+		{Title: "Chapter 1", Start: 0, End: time.Minute},
+		{Title: "Second", Start: time.Minute, End: 5 * time.Minute},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("readChapters = %+v, want %+v", got, want)
+	}
+	if got := readChapters(rawTags{format: tag.MP4, raw: m.raw}, time.Minute); got != nil {
+		t.Errorf("chapters read from an MP4 = %+v", got)
+	}
+}
@@ -0,0 +1,254 @@
+package library
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// ExportedTrack is one track of a library export: its metadata plus the
+// per-track state kept outside the tags
+type ExportedTrack struct {
+	api.Track
+	Plays         int       `json:"plays"`
+	LastPlayed    time.Time `json:"last_played,omitempty"`
+	Archived      bool      `json:"archived,omitempty"`
+	ShuffleBanned bool      `json:"shuffle_banned,omitempty"`
+	UserTags      []string  `json:"user_tags,omitempty"`
+	Rating        int       `json:"rating,omitempty"` // stars out of 5
+}
+
+// csvHeader names the columns of a CSV export, in order
+var csvHeader = []string{
+	"id", "title", "artist", "album", "album_artist", "compilation", "genre", "year", "track_number",
+	"duration_seconds", "bitrate", "sample_rate", "channels", "codec", "file_size",
+	"file_path", "plays", "last_played", "archived", "shuffle_banned", "user_tags", "rating",
+}
+
+// Export returns every track with its play count and flags, sorted by
+// artist, album and track number
+func (l *Library) Export() []ExportedTrack {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	type playStat struct {
+		plays int
+		last  time.Time
+	}
+	stats := make(map[string]playStat)
+	if l.history != nil {
+		for _, rec := range l.history.Records() {
+			st := stats[rec.TrackID]
+			st.plays += rec.count()
+			if rec.PlayedAt.After(st.last) {
+				st.last = rec.PlayedAt
+			}
+			stats[rec.TrackID] = st
+		}
+	}
+
+	tracks := make([]*api.Track, 0, len(l.Tracks))
+	for _, t := range l.Tracks {
+		tracks = append(tracks, t)
+	}
+	sortTracks(tracks)
+
+	out := make([]ExportedTrack, len(tracks))
+	for i, t := range tracks {
+		st := stats[t.ID]
+		out[i] = ExportedTrack{
+			Track:         *t,
+			Plays:         st.plays,
+			LastPlayed:    st.last,
+			Archived:      l.Archived[t.ID],
+			ShuffleBanned: l.ShuffleBanned[t.ID],
+			UserTags:      slices.Clone(l.UserTags[t.ID]),
+			Rating:        l.Ratings[t.ID],
+		}
+		out[i].CoverArt = nil
+	}
+	return out
+}
+
+// ExportJSON writes the library to path as a JSON array of ExportedTrack
+func (l *Library) ExportJSON(path string) error {
+	data, err := json.MarshalIndent(l.Export(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal export: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("write export: %w", err)
+	}
+	return nil
+}
+
+// ExportCSV writes the library to path as CSV with a header row, for
+// spreadsheets
+func (l *Library) ExportCSV(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create export: %w", err)
+	}
+	if err := writeCSV(f, l.Export()); err != nil {
+		f.Close()
+		return fmt.Errorf("write export: %w", err)
+	}
+	return f.Close()
+}
+
+func writeCSV(w io.Writer, tracks []ExportedTrack) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, t := range tracks {
+		last := ""
+		if !t.LastPlayed.IsZero() {
+			last = t.LastPlayed.UTC().Format(time.RFC3339)
+		}
+		cw.Write([]string{
+			t.ID, t.Title, t.Artist, t.Album, t.AlbumArtist, strconv.FormatBool(t.Compilation), t.Genre,
+			strconv.Itoa(t.Year), strconv.Itoa(t.TrackNum),
+			strconv.FormatFloat(t.Duration.Seconds(), 'f', 3, 64),
+			strconv.Itoa(t.Bitrate), strconv.Itoa(t.SampleRate), strconv.Itoa(t.Channels),
+			t.Codec, strconv.FormatInt(t.FileSize, 10),
+			t.FilePath, strconv.Itoa(t.Plays), last,
+			strconv.FormatBool(t.Archived), strconv.FormatBool(t.ShuffleBanned),
+			strings.Join(t.UserTags, ", "), strconv.Itoa(t.Rating),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// readCSV parses a CSV export. Columns are matched by header name, so
+// spreadsheets may reorder or drop them; only file_path is required.
+func readCSV(r io.Reader) ([]ExportedTrack, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	col := make(map[string]int)
+	for i, name := range rows[0] {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := col["file_path"]; !ok {
+		return nil, fmt.Errorf("missing file_path column")
+	}
+
+	var out []ExportedTrack
+	for _, row := range rows[1:] {
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		var t ExportedTrack
+		t.ID, t.Title, t.Artist, t.Album, t.Genre = get("id"), get("title"), get("artist"), get("album"), get("genre")
+		t.AlbumArtist = get("album_artist")
+		t.Compilation, _ = strconv.ParseBool(get("compilation"))
+		t.FilePath = get("file_path")
+		t.Year, _ = strconv.Atoi(get("year"))
+		t.TrackNum, _ = strconv.Atoi(get("track_number"))
+		if secs, err := strconv.ParseFloat(get("duration_seconds"), 64); err == nil {
+			t.Duration = time.Duration(secs * float64(time.Second))
+		}
+		t.Plays, _ = strconv.Atoi(get("plays"))
+		t.LastPlayed, _ = time.Parse(time.RFC3339, get("last_played"))
+		t.Archived, _ = strconv.ParseBool(get("archived"))
+		t.ShuffleBanned, _ = strconv.ParseBool(get("shuffle_banned"))
+		t.Rating, _ = strconv.Atoi(get("rating"))
+		for _, tag := range strings.Split(get("user_tags"), ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				t.UserTags = append(t.UserTags, tag)
+			}
+		}
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// Import adds the tracks of a .json or .csv export to the library and
+// restores the play counts, archived and shuffle-banned flags, user tags
+// and ratings set in it. Tracks already in the library keep their metadata.
+// Entries whose file does not exist on this machine are skipped and
+// counted. Play counts go into the play history the way ImportPlays puts
+// them, once per track, so importing again does not count them twice.
+func (l *Library) Import(path string) (added, skipped int, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, fmt.Errorf("open import: %w", err)
+	}
+	defer f.Close()
+
+	var tracks []ExportedTrack
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.NewDecoder(f).Decode(&tracks)
+	case ".csv":
+		tracks, err = readCSV(f)
+	default:
+		return 0, 0, fmt.Errorf("import library: unsupported format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("parse import: %w", err)
+	}
+
+	for _, t := range tracks {
+		if t.FilePath == "" {
+			skipped++
+			continue
+		}
+		if _, err := os.Stat(t.FilePath); err != nil {
+			skipped++
+			continue
+		}
+		track := t.Track
+		track.ID = generateTrackID(track.FilePath)
+		if track.Title == "" {
+			track.Title = strings.TrimSuffix(filepath.Base(track.FilePath), filepath.Ext(track.FilePath))
+		}
+
+		l.mu.RLock()
+		_, exists := l.Tracks[track.ID]
+		l.mu.RUnlock()
+		if !exists {
+			l.normalizeGenre(&track)
+			l.AddTrack(&track)
+			added++
+		}
+		if t.Archived {
+			l.SetArchived(track.ID, true)
+		}
+		if t.ShuffleBanned {
+			l.SetShuffleBanned(track.ID, true)
+		}
+		for _, tag := range t.UserTags {
+			l.AddUserTag([]string{track.ID}, tag)
+		}
+		if t.Rating > 0 {
+			l.SetRating(track.ID, t.Rating)
+		}
+		if _, err := l.ImportPlays(&track, t.Plays, t.LastPlayed); err != nil {
+			return added, skipped, fmt.Errorf("import plays: %w", err)
+		}
+	}
+
+	l.mu.RLock()
+	l.publish(api.EventLibraryChanged, nil)
+	l.mu.RUnlock()
+	return added, skipped, nil
+}
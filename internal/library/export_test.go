@@ -0,0 +1,91 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestExportImport_RoundTrip verifies a JSON and a CSV export bring a
+// track's metadata, play count and flags over to another library, that
+// importing again does not count plays twice, and that entries without a
+// file here are skipped
+func TestExportImport_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	song := filepath.Join(dir, "song.mp3")
+	if err := os.WriteFile(song, []byte("audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	last := time.Date(2024, 3, 9, 18, 30, 0, 0, time.UTC)
+
+	src := NewLibrary()
+	history, err := OpenHistory(filepath.Join(dir, "src-history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src.SetHistory(history)
+	track := &api.Track{
+		ID: generateTrackID(song), Title: "Teardrop", Artist: "Massive Attack", Album: "Mezzanine",
+		Year: 1998, TrackNum: 3, Duration: 5*time.Minute + 29*time.Second, FilePath: song,
+	}
+	src.AddTrack(track)
+	src.AddTrack(&api.Track{ID: "gone", Title: "Elsewhere", FilePath: filepath.Join(dir, "missing.mp3")})
+	for _, at := range []time.Time{last.Add(-time.Hour), last} {
+		if err := src.RecordPlay(PlayRecord{TrackID: track.ID, PlayedAt: at, Completed: true}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src.SetArchived(track.ID, true)
+	src.SetShuffleBanned(track.ID, true)
+	src.AddUserTag([]string{track.ID}, "rainy day")
+	src.SetRating(track.ID, 4)
+
+	for _, ext := range []string{".json", ".csv"} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "export"+ext)
+			export := src.ExportJSON
+			if ext == ".csv" {
+				export = src.ExportCSV
+			}
+			if err := export(path); err != nil {
+				t.Fatal(err)
+			}
+
+			dst := NewLibrary()
+			history, err := OpenHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			dst.SetHistory(history)
+			for range 2 {
+				if _, _, err := dst.Import(path); err != nil {
+					t.Fatal(err)
+				}
+			}
+			added, skipped, err := dst.Import(path)
+			if err != nil || added != 0 || skipped != 1 {
+				t.Errorf("import again = %d added, %d skipped, %v; want 0, 1", added, skipped, err)
+			}
+
+			got := dst.Export()
+			if len(got) != 1 {
+				t.Fatalf("imported %d tracks, want 1", len(got))
+			}
+			g := got[0]
+			if g.ID != track.ID || g.Title != track.Title || g.Artist != track.Artist || g.Album != track.Album ||
+				g.Year != track.Year || g.TrackNum != track.TrackNum || g.Duration != track.Duration {
+				t.Errorf("metadata = %+v, want %+v", g.Track, *track)
+			}
+			if g.Plays != 2 || !g.LastPlayed.Equal(last) {
+				t.Errorf("plays = %d last played %v, want 2 at %v", g.Plays, g.LastPlayed, last)
+			}
+			if !g.Archived || !g.ShuffleBanned || g.Rating != 4 || !slices.Equal(g.UserTags, []string{"rainy day"}) {
+				t.Errorf("flags = archived %v banned %v rating %d tags %v", g.Archived, g.ShuffleBanned, g.Rating, g.UserTags)
+			}
+		})
+	}
+}
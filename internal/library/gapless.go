@@ -0,0 +1,56 @@
+package library
+
+import (
+	"strings"
+
+	"github.com/dhowden/tag"
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// unknownAlbum is the album given to tracks without an album tag
+const unknownAlbum = "Unknown Album"
+
+// readGapless reports whether a file is tagged for gapless playback: a
+// GAPLESS or ITUNESGAPLESS Vorbis comment, or an ID3 TXXX/COMM frame
+// described as "GAPLESS" or "iTunPGAP", set to 1
+func readGapless(m tag.Metadata) bool {
+	for name, v := range m.Raw() {
+		switch v := v.(type) {
+		case string:
+			if (name == "gapless" || name == "itunesgapless") && isTrue(v) {
+				return true
+			}
+		case *tag.Comm:
+			if strings.EqualFold(v.Description, "GAPLESS") || strings.EqualFold(v.Description, "iTunPGAP") {
+				if isTrue(v.Text) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func isTrue(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "1", "true", "yes":
+		return true
+	}
+	return false
+}
+
+// GaplessPair reports whether next should follow prev without a crossfade:
+// either is tagged gapless, or next is the following track of the same album
+// (live albums and DJ mixes run their tracks into each other)
+func GaplessPair(prev, next *api.Track) bool {
+	if prev == nil || next == nil {
+		return false
+	}
+	if prev.Gapless || next.Gapless {
+		return true
+	}
+	if prev.Album == "" || prev.Album == unknownAlbum || !strings.EqualFold(prev.Album, next.Album) {
+		return false
+	}
+	return prev.TrackNum > 0 && next.TrackNum == prev.TrackNum+1
+}
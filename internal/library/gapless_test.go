@@ -0,0 +1,67 @@
+package library
+
+import (
+	"testing"
+
+	"github.com/dhowden/tag"
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestReadGapless verifies the Vorbis comments and ID3 frames that mark a
+// file gapless, and that other values and descriptions do not
+func TestReadGapless(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  map[string]interface{}
+		want bool
+	}{
+		{"vorbis", map[string]interface{}{"gapless": "1"}, true},
+		{"itunes vorbis", map[string]interface{}{"itunesgapless": " Yes "}, true},
+		{"vorbis off", map[string]interface{}{"gapless": "0"}, false},
+		{"other comment", map[string]interface{}{"comment": "gapless"}, false},
+		{"txxx", map[string]interface{}{"TXXX": &tag.Comm{Description: "GAPLESS", Text: "true"}}, true},
+		{"itunpgap", map[string]interface{}{"COMM_0": &tag.Comm{Description: "iTunPGAP", Text: "1"}}, true},
+		{"frame off", map[string]interface{}{"TXXX": &tag.Comm{Description: "iTunPGAP", Text: "0"}}, false},
+		{"other frame", map[string]interface{}{"TXXX": &tag.Comm{Description: "MOOD", Text: "1"}}, false},
+		{"untagged", map[string]interface{}{"title": "Song"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readGapless(rawTags{raw: tt.raw}); got != tt.want {
+				t.Errorf("readGapless = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGaplessPair verifies a gapless tag on either track, or consecutive
+// tracks of the same named album, join without a crossfade
+func TestGaplessPair(t *testing.T) {
+	track := func(album string, num int, gapless bool) *api.Track {
+		return &api.Track{Album: album, TrackNum: num, Gapless: gapless}
+	}
+	tests := []struct {
+		name       string
+		prev, next *api.Track
+		want       bool
+	}{
+		{"next on album", track("Live at Leeds", 3, false), track("live at leeds", 4, false), true},
+		{"skipped track", track("Live at Leeds", 3, false), track("Live at Leeds", 5, false), false},
+		{"backwards", track("Live at Leeds", 4, false), track("Live at Leeds", 3, false), false},
+		{"other album", track("Live at Leeds", 3, false), track("Tommy", 4, false), false},
+		{"no album", track("", 1, false), track("", 2, false), false},
+		{"unknown album", track(unknownAlbum, 1, false), track(unknownAlbum, 2, false), false},
+		{"untracked", track("Mix", 0, false), track("Mix", 1, false), false},
+		{"prev tagged", track("A", 1, true), track("B", 7, false), true},
+		{"next tagged", track("A", 1, false), track("B", 7, true), true},
+		{"no prev", nil, track("A", 1, true), false},
+		{"no next", track("A", 1, true), nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GaplessPair(tt.prev, tt.next); got != tt.want {
+				t.Errorf("GaplessPair = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,238 @@
+package library
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jscyril/golang_music_player/internal/store"
+)
+
+// PlayRecord is one entry of the play history: a track that was loaded
+// into the player and how much of it was heard before it was replaced
+type PlayRecord struct {
+	TrackID   string        `json:"track_id"`
+	FilePath  string        `json:"file_path"`
+	Title     string        `json:"title"`
+	Artist    string        `json:"artist"`
+	PlayedAt  time.Time     `json:"played_at"`
+	Played    time.Duration `json:"played"`
+	Duration  time.Duration `json:"duration"`
+	Completed bool          `json:"completed"`
+
+	// Plays is how many plays an entry imported from another player stands
+	// for; 0 for the player's own entries, which are one play each
+	Plays int `json:"plays,omitempty"`
+}
+
+// count returns how many plays the record stands for
+func (r PlayRecord) count() int {
+	return max(r.Plays, 1)
+}
+
+// EarlySkip reports whether the track was abandoned within the first
+// fraction (0..1) of its length
+func (r PlayRecord) EarlySkip(fraction float64) bool {
+	if r.Completed || r.Duration <= 0 {
+		return false
+	}
+	return r.Played < time.Duration(float64(r.Duration)*fraction)
+}
+
+// History is an append-only play log stored as JSON lines
+type History struct {
+	path    string
+	mu      sync.RWMutex
+	records []PlayRecord
+
+	// The skip rule, set from the config with SetSkipRule; none is set
+	// until then and no play counts as skipped
+	skipFraction float64
+	skipCount    int
+}
+
+// OpenHistory loads the play log at path, creating an empty one if the
+// file does not exist. Malformed lines are skipped.
+func OpenHistory(path string) (*History, error) {
+	h := &History{path: path}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return h, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open history: %w", err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var rec PlayRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			continue
+		}
+		h.records = append(h.records, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	return h, nil
+}
+
+// Append adds a record to the log and writes it to disk
+func (h *History) Append(rec PlayRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal play record: %w", err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, rec)
+
+	// A read-only instance keeps its plays in memory only
+	if h.path == "" || store.ReadOnly() {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open history: %w", err)
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("write history: %w", err)
+	}
+	return f.Close()
+}
+
+// Records returns a copy of the log in the order it was written
+func (h *History) Records() []PlayRecord {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	out := make([]PlayRecord, len(h.records))
+	copy(out, h.records)
+	return out
+}
+
+// hasImported reports whether the log has plays of the track imported from
+// another player
+func (h *History) hasImported(trackID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, rec := range h.records {
+		if rec.TrackID == trackID && rec.Plays > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Since returns the records played at or after t, newest first
+func (h *History) Since(t time.Time) []PlayRecord {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var out []PlayRecord
+	for i := len(h.records) - 1; i >= 0; i-- {
+		if !h.records[i].PlayedAt.Before(t) {
+			out = append(out, h.records[i])
+		}
+	}
+	return out
+}
+
+// SetSkipRule sets what counts as frequently skipped: abandoned within the
+// first fraction (0..1) of playback at least count times. A fraction out
+// of range or a count below 1 leaves that part as it was.
+func (h *History) SetSkipRule(fraction float64, count int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if fraction > 0 && fraction <= 1 {
+		h.skipFraction = fraction
+	}
+	if count > 0 {
+		h.skipCount = count
+	}
+}
+
+// SkipStat summarizes how often a track is abandoned early
+type SkipStat struct {
+	TrackID string
+	Title   string
+	Artist  string
+	Plays   int
+	Skips   int
+}
+
+// FrequentlySkipped returns tracks that meet the skip rule, most-skipped first
+func (h *History) FrequentlySkipped() []SkipStat {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	byID := make(map[string]*SkipStat)
+	for _, rec := range h.records {
+		st := byID[rec.TrackID]
+		if st == nil {
+			st = &SkipStat{TrackID: rec.TrackID}
+			byID[rec.TrackID] = st
+		}
+		st.Title, st.Artist = rec.Title, rec.Artist
+		st.Plays += rec.count()
+		if rec.EarlySkip(h.skipFraction) {
+			st.Skips++
+		}
+	}
+
+	var out []SkipStat
+	for _, st := range byID {
+		if st.Skips > 0 && st.Skips >= h.skipCount {
+			out = append(out, *st)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Skips != out[j].Skips {
+			return out[i].Skips > out[j].Skips
+		}
+		return out[i].Title < out[j].Title
+	})
+	return out
+}
+
+// PlayStat summarizes one track's entries in the play log
+type PlayStat struct {
+	Plays      int
+	Completed  int
+	Skips      int
+	LastPlayed time.Time
+}
+
+// Stats returns the play counts of the track with the given ID
+func (h *History) Stats(trackID string) PlayStat {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var st PlayStat
+	for _, rec := range h.records {
+		if rec.TrackID != trackID {
+			continue
+		}
+		st.Plays += rec.count()
+		if rec.Completed {
+			st.Completed += rec.count()
+		}
+		if rec.EarlySkip(h.skipFraction) {
+			st.Skips++
+		}
+		if rec.PlayedAt.After(st.LastPlayed) {
+			st.LastPlayed = rec.PlayedAt
+		}
+	}
+	return st
+}
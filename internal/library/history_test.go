@@ -0,0 +1,113 @@
+package library
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestPlayRecord_EarlySkip verifies a play is an early skip only when it
+// was abandoned before the fraction of a known length
+func TestPlayRecord_EarlySkip(t *testing.T) {
+	tests := []struct {
+		name string
+		rec  PlayRecord
+		want bool
+	}{
+		{"abandoned early", PlayRecord{Played: 30 * time.Second, Duration: 4 * time.Minute}, true},
+		{"abandoned at the fraction", PlayRecord{Played: 48 * time.Second, Duration: 4 * time.Minute}, false},
+		{"abandoned late", PlayRecord{Played: 3 * time.Minute, Duration: 4 * time.Minute}, false},
+		{"completed", PlayRecord{Played: 0, Duration: 4 * time.Minute, Completed: true}, false},
+		{"unknown length", PlayRecord{Played: time.Second}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.rec.EarlySkip(0.2); got != tt.want {
+			t.Errorf("%s: EarlySkip = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if (PlayRecord{Played: time.Second, Duration: time.Minute}).EarlySkip(0) {
+		t.Error("no play is an early skip with a zero fraction")
+	}
+}
+
+// TestHistory_FrequentlySkipped verifies tracks are listed once they meet
+// the skip rule, most skipped first, and not before a rule is set
+func TestHistory_FrequentlySkipped(t *testing.T) {
+	h, err := OpenHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	play := func(id, title string, played time.Duration) {
+		t.Helper()
+		rec := PlayRecord{TrackID: id, Title: title, PlayedAt: time.Now(), Played: played, Duration: 5 * time.Minute}
+		if err := h.Append(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for range 3 {
+		play("a", "Annoying", 10*time.Second)
+		play("b", "Boring", 20*time.Second)
+	}
+	play("a", "Annoying", 5*time.Second)
+	play("b", "Boring", 5*time.Minute)
+	play("c", "Catchy", 10*time.Second)
+	play("c", "Catchy", 4*time.Minute)
+	h.Append(PlayRecord{TrackID: "c", Title: "Catchy", Plays: 40, Duration: 5 * time.Minute, Completed: true})
+
+	if got := h.FrequentlySkipped(); len(got) != 0 {
+		t.Errorf("skipped tracks without a rule = %+v", got)
+	}
+
+	h.SetSkipRule(0.2, 3)
+	got := h.FrequentlySkipped()
+	if len(got) != 2 || got[0].TrackID != "a" || got[1].TrackID != "b" {
+		t.Fatalf("frequently skipped = %+v, want a then b", got)
+	}
+	if got[0].Skips != 4 || got[0].Plays != 4 || got[1].Skips != 3 || got[1].Plays != 4 {
+		t.Errorf("counts = %+v", got)
+	}
+	if st := h.Stats("c"); st.Plays != 42 || st.Skips != 1 {
+		t.Errorf("stats of c = %+v, want 42 plays and 1 skip", st)
+	}
+
+	// Out of range values leave the rule alone
+	h.SetSkipRule(1.5, 0)
+	if got := h.FrequentlySkipped(); len(got) != 2 {
+		t.Errorf("rule changed by invalid values: %+v", got)
+	}
+	h.SetSkipRule(0.02, 1)
+	if got := h.FrequentlySkipped(); len(got) != 1 || got[0].TrackID != "a" || got[0].Skips != 1 {
+		t.Errorf("frequently skipped under a stricter rule = %+v, want a once", got)
+	}
+}
+
+// TestLibrary_ShuffleExcluded verifies banned, archived and broken tracks
+// are left out of shuffles
+func TestLibrary_ShuffleExcluded(t *testing.T) {
+	lib := NewLibrary()
+	tracks := []*api.Track{
+		{ID: "ok", FilePath: "/m/ok.mp3"},
+		{ID: "banned", FilePath: "/m/banned.mp3"},
+		{ID: "archived", FilePath: "/m/archived.mp3"},
+		{ID: "broken", FilePath: "/m/broken.mp3"},
+	}
+	lib.AddTracks(tracks)
+	lib.SetShuffleBanned("banned", true)
+	lib.SetArchived("archived", true)
+	if err := lib.SetBroken("broken", "decode failed"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tr := range tracks {
+		if got, want := lib.ShuffleExcluded(tr), tr.ID != "ok"; got != want {
+			t.Errorf("ShuffleExcluded(%s) = %v, want %v", tr.ID, got, want)
+		}
+	}
+
+	lib.SetShuffleBanned("banned", false)
+	lib.SetBroken("broken", "")
+	if lib.ShuffleExcluded(tracks[1]) || lib.ShuffleExcluded(tracks[3]) {
+		t.Error("lifted exclusions still apply")
+	}
+}
@@ -3,42 +3,83 @@ package library
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/store"
 	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
 )
 
+// LibraryVersion is the schema version of library.json
+const LibraryVersion = 1
+
+// libraryMigrations upgrade older library.json files, see store.Migration
+var libraryMigrations = map[int]store.Migration{}
+
 // Library represents the entire music collection
 type Library struct {
+	Version     int                   `json:"version"`
 	Tracks      map[string]*api.Track `json:"tracks"`
 	ScanPaths   []string              `json:"scan_paths"`
 	LastScanned time.Time             `json:"last_scanned"`
 	TotalTracks int                   `json:"total_tracks"`
 
+	// ShuffleBanned holds IDs of tracks left out when a queue is shuffled
+	ShuffleBanned map[string]bool `json:"shuffle_banned,omitempty"`
+
+	// Archived holds IDs of tracks hidden from listings, search and shuffle
+	// without being deleted, so their stats and playlists survive
+	Archived map[string]bool `json:"archived,omitempty"`
+
+	// UserTags holds the user's own labels of tracks ("workout", "chill")
+	// by track ID, kept apart from the file tags so rescans keep them
+	UserTags map[string][]string `json:"user_tags,omitempty"`
+
+	// Broken holds why tracks failed to play the last time they were
+	// tried, by track ID, until they play again
+	Broken map[string]string `json:"broken,omitempty"`
+
+	// Ratings holds stars out of 5 by track ID, as imported from other
+	// players
+	Ratings map[string]int `json:"ratings,omitempty"`
+
 	// Secondary indices for efficient queries
 	artistIndex map[string][]string
 	albumIndex  map[string][]string
 	genreIndex  map[string][]string
 
-	mu      sync.RWMutex
-	scanner *Scanner
+	userTagIndex map[string][]string // label -> track IDs
+
+	albums     map[string]*api.Album // album ID -> album, see linkAlbum
+	trackAlbum map[string]string     // track ID -> album ID
+
+	mu       sync.RWMutex
+	scanner  *Scanner
+	taxonomy *Taxonomy
+	history  *History
+	pub      api.Publisher
 }
 
 // NewLibrary creates a new empty library
 func NewLibrary() *Library {
 	return &Library{
-		Tracks:      make(map[string]*api.Track),
-		artistIndex: make(map[string][]string),
-		albumIndex:  make(map[string][]string),
-		genreIndex:  make(map[string][]string),
-		scanner:     NewScanner(4),
+		Version:      LibraryVersion,
+		Tracks:       make(map[string]*api.Track),
+		artistIndex:  make(map[string][]string),
+		albumIndex:   make(map[string][]string),
+		genreIndex:   make(map[string][]string),
+		userTagIndex: make(map[string][]string),
+		albums:       make(map[string]*api.Album),
+		trackAlbum:   make(map[string]string),
+		scanner:      NewScanner(4),
 	}
 }
 
@@ -46,19 +87,52 @@ func NewLibrary() *Library {
 func (l *Library) AddTrack(track *api.Track) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
+	l.addTrack(track)
+	l.linkAlbums(track)
+}
+
+// AddTracks adds several tracks under a single lock
+func (l *Library) AddTracks(tracks []*api.Track) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, track := range tracks {
+		l.addTrack(track)
+	}
+	l.linkAlbums(tracks...)
+}
 
+// addTrack adds a track and updates indices, but not albums: callers link
+// the tracks they add with linkAlbums. Callers hold l.mu.
+func (l *Library) addTrack(track *api.Track) {
+	// A rescan re-adds known tracks; drop their old index entries
+	if old, exists := l.Tracks[track.ID]; exists {
+		l.unindexTrack(old)
+	}
 	l.Tracks[track.ID] = track
 	l.TotalTracks = len(l.Tracks)
+	l.indexTrack(track)
+}
 
-	// Update indices
+// indexTrack adds a track to the artist, album and genre indices, under
+// each of its genres. Callers hold l.mu.
+func (l *Library) indexTrack(track *api.Track) {
 	if track.Artist != "" {
 		l.artistIndex[track.Artist] = append(l.artistIndex[track.Artist], track.ID)
 	}
 	if track.Album != "" {
 		l.albumIndex[track.Album] = append(l.albumIndex[track.Album], track.ID)
 	}
-	if track.Genre != "" {
-		l.genreIndex[track.Genre] = append(l.genreIndex[track.Genre], track.ID)
+	for _, genre := range SplitGenres(track.Genre) {
+		l.genreIndex[genre] = append(l.genreIndex[genre], track.ID)
+	}
+}
+
+// unindexTrack removes a track from the indices. Callers hold l.mu.
+func (l *Library) unindexTrack(track *api.Track) {
+	l.removeFromIndex(l.artistIndex, track.Artist, track.ID)
+	l.removeFromIndex(l.albumIndex, track.Album, track.ID)
+	for _, genre := range SplitGenres(track.Genre) {
+		l.removeFromIndex(l.genreIndex, genre, track.ID)
 	}
 }
 
@@ -80,21 +154,13 @@ func (l *Library) GetAllTracks() []*api.Track {
 	defer l.mu.RUnlock()
 
 	tracks := make([]*api.Track, 0, len(l.Tracks))
-	for _, track := range l.Tracks {
-		tracks = append(tracks, track)
-	}
-
-	// Sort by artist, then album, then track number
-	sort.Slice(tracks, func(i, j int) bool {
-		if tracks[i].Artist != tracks[j].Artist {
-			return tracks[i].Artist < tracks[j].Artist
-		}
-		if tracks[i].Album != tracks[j].Album {
-			return tracks[i].Album < tracks[j].Album
+	for id, track := range l.Tracks {
+		if !l.Archived[id] {
+			tracks = append(tracks, track)
 		}
-		return tracks[i].TrackNum < tracks[j].TrackNum
-	})
+	}
 
+	sortTracks(tracks)
 	return tracks
 }
 
@@ -110,7 +176,7 @@ func (l *Library) GetTracksByArtist(artist string) []*api.Track {
 
 	tracks := make([]*api.Track, 0, len(trackIDs))
 	for _, id := range trackIDs {
-		if track, ok := l.Tracks[id]; ok {
+		if track, ok := l.Tracks[id]; ok && !l.Archived[id] {
 			tracks = append(tracks, track)
 		}
 	}
@@ -129,13 +195,136 @@ func (l *Library) GetTracksByAlbum(album string) []*api.Track {
 
 	tracks := make([]*api.Track, 0, len(trackIDs))
 	for _, id := range trackIDs {
-		if track, ok := l.Tracks[id]; ok {
+		if track, ok := l.Tracks[id]; ok && !l.Archived[id] {
 			tracks = append(tracks, track)
 		}
 	}
 	return tracks
 }
 
+// AlbumOf returns the album track belongs to, in track order: the tracks
+// tagged with its album by the same album artist (or artist, when there is
+// none) or in the same directory, so
+// albums sharing a name ("Greatest Hits") stay apart while compilations
+// stay together. A track without an album tag gets its directory.
+func (l *Library) AlbumOf(track *api.Track) []*api.Track {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	dir := filepath.Dir(track.FilePath)
+	var tracks []*api.Track
+	if track.Album == "" || track.Album == unknownAlbum {
+		for id, t := range l.Tracks {
+			if !l.Archived[id] && filepath.Dir(t.FilePath) == dir {
+				tracks = append(tracks, t)
+			}
+		}
+	} else {
+		for _, id := range l.albumIndex[track.Album] {
+			t, ok := l.Tracks[id]
+			if ok && !l.Archived[id] && (albumArtist(t) == albumArtist(track) || filepath.Dir(t.FilePath) == dir) {
+				tracks = append(tracks, t)
+			}
+		}
+	}
+	sort.SliceStable(tracks, func(i, j int) bool {
+		if tracks[i].TrackNum != tracks[j].TrackNum {
+			return tracks[i].TrackNum < tracks[j].TrackNum
+		}
+		return tracks[i].FilePath < tracks[j].FilePath
+	})
+	return tracks
+}
+
+// Discography returns an artist's tracks album by album, oldest first, each
+// album in track order. Compilations contribute only the artist's tracks.
+func (l *Library) Discography(artist string) []*api.Track {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	ar := l.artist(artist)
+	if ar == nil {
+		return nil
+	}
+	tracks := make([]*api.Track, 0, ar.TrackCount)
+	for _, id := range ar.AlbumIDs {
+		for _, tid := range l.albums[id].TrackIDs {
+			if t := l.Tracks[tid]; t.Artist == artist {
+				tracks = append(tracks, t)
+			}
+		}
+	}
+	return tracks
+}
+
+// SetTaxonomy sets the genre hierarchy used for genre browsing and scan-time
+// genre mapping
+func (l *Library) SetTaxonomy(t *Taxonomy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.taxonomy = t
+}
+
+// Taxonomy returns the genre hierarchy, creating an empty one if unset
+func (l *Library) Taxonomy() *Taxonomy {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.taxonomy == nil {
+		l.taxonomy = NewTaxonomy()
+	}
+	return l.taxonomy
+}
+
+// normalizeGenre applies the taxonomy's mapping rules to a scanned track
+func (l *Library) normalizeGenre(track *api.Track) {
+	l.mu.RLock()
+	t := l.taxonomy
+	l.mu.RUnlock()
+	if t != nil {
+		track.Genre = t.CanonicalList(track.Genre)
+	}
+}
+
+// GetGenres returns all unique genres found in the library
+func (l *Library) GetGenres() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	genres := make([]string, 0, len(l.genreIndex))
+	for genre := range l.genreIndex {
+		genres = append(genres, genre)
+	}
+	sort.Strings(genres)
+	return genres
+}
+
+// GetTracksByGenre returns tracks in a genre or any of its sub-genres
+func (l *Library) GetTracksByGenre(genre string) []*api.Track {
+	genres := l.Taxonomy().Descendants(genre)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	// A track tagged with a genre and its sub-genre is listed once
+	var tracks []*api.Track
+	seen := make(map[string]bool)
+	for _, g := range genres {
+		for _, id := range l.genreIndex[g] {
+			if track, ok := l.Tracks[id]; ok && !l.Archived[id] && !seen[id] {
+				seen[id] = true
+				tracks = append(tracks, track)
+			}
+		}
+	}
+	sortTracks(tracks)
+	return tracks
+}
+
+// GenreTree returns the genre hierarchy merged with the library's genres
+func (l *Library) GenreTree() []GenreNode {
+	return l.Taxonomy().Tree(l.GetGenres())
+}
+
 // GetArtists returns all unique artists
 func (l *Library) GetArtists() []string {
 	l.mu.RLock()
@@ -162,30 +351,27 @@ func (l *Library) GetAlbums() []string {
 	return albums
 }
 
-// Search searches tracks by query string (matches title and artist)
+// Search searches tracks by query string (matches title, artist and
+// album), narrowed and ordered by any field terms, see ParseQuery
 func (l *Library) Search(query string) []*api.Track {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
-	query = strings.ToLower(query)
+	q := ParseQuery(query)
 	results := make([]*api.Track, 0, 10)
 
-	for _, track := range l.Tracks {
-		titleMatch := strings.Contains(strings.ToLower(track.Title), query)
-		artistMatch := strings.Contains(strings.ToLower(track.Artist), query)
-		albumMatch := strings.Contains(strings.ToLower(track.Album), query)
-
-		if titleMatch || artistMatch || albumMatch {
+	for id, track := range l.Tracks {
+		if l.Archived[id] {
+			continue
+		}
+		if q.Match(track) {
 			results = append(results, track)
 		}
 	}
 
-	// Sort by relevance (title matches first)
-	sort.Slice(results, func(i, j int) bool {
-		iTitle := strings.Contains(strings.ToLower(results[i].Title), query)
-		jTitle := strings.Contains(strings.ToLower(results[j].Title), query)
-		return iTitle && !jTitle
-	})
+	// Best matches first, then by any sort term
+	sortTracks(results)
+	q.Rank(results)
 
 	return results
 }
@@ -201,12 +387,18 @@ func (l *Library) RemoveTrack(id string) error {
 	}
 
 	// Remove from indices
-	l.removeFromIndex(l.artistIndex, track.Artist, id)
-	l.removeFromIndex(l.albumIndex, track.Album, id)
-	l.removeFromIndex(l.genreIndex, track.Genre, id)
+	l.unindexTrack(track)
+	l.unlinkAlbum(id)
+	for _, tag := range slices.Clone(l.UserTags[id]) {
+		l.dropUserTag(id, tag)
+	}
 
 	delete(l.Tracks, id)
+	delete(l.Archived, id)
+	delete(l.Broken, id)
+	delete(l.Ratings, id)
 	l.TotalTracks = len(l.Tracks)
+	l.publish(api.EventLibraryChanged, id)
 	return nil
 }
 
@@ -230,29 +422,111 @@ func (l *Library) removeFromIndex(index map[string][]string, key, trackID string
 	}
 }
 
-// Scan scans the configured paths and adds tracks to the library
-func (l *Library) Scan(ctx context.Context, paths []string) error {
+// scanBatchSize is how many scanned tracks are added to the library at once
+const scanBatchSize = 256
+
+// SetScanWorkers sets how many files a scan reads at once; 0 or less uses
+// the default. Call it before scanning.
+func (l *Library) SetScanWorkers(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.scanner = NewScanner(n)
+	l.scanner.SetPublisher(l.pub)
+}
+
+// maxScanErrors caps the errors a ScanReport keeps; Failed counts them all
+const maxScanErrors = 500
+
+// ScanReport summarizes a library scan
+type ScanReport struct {
+	Tracks    int                     // tracks read and added
+	Failed    int                     // files and directories that could not be read
+	Errors    []*playerrors.ScanError // the first maxScanErrors failures
+	Elapsed   time.Duration
+	Cancelled bool
+}
+
+// Summary describes the scan in one line
+func (r ScanReport) Summary() string {
+	var sb strings.Builder
+	if r.Cancelled {
+		fmt.Fprintf(&sb, "Scan cancelled after %d tracks", r.Tracks)
+	} else {
+		fmt.Fprintf(&sb, "Scanned %d tracks in %s", r.Tracks, r.Elapsed.Round(time.Second))
+	}
+	if r.Failed > 0 {
+		fmt.Fprintf(&sb, ", %d unreadable", r.Failed)
+	}
+	return sb.String()
+}
+
+// Scan scans the configured paths and adds tracks to the library. The
+// scanner publishes its progress; cancelling ctx keeps the tracks read so
+// far and returns a report marked Cancelled along with ctx.Err().
+func (l *Library) Scan(ctx context.Context, paths []string) (ScanReport, error) {
+	l.mu.Lock()
 	l.ScanPaths = paths
-	tracks, errors := l.scanner.Scan(ctx, paths)
+	l.mu.Unlock()
+	report, err := l.scan(ctx, paths)
+	if !report.Cancelled {
+		l.mu.Lock()
+		l.LastScanned = time.Now()
+		l.mu.Unlock()
+	}
+	return report, err
+}
+
+// ScanDir adds the tracks under a single directory, e.g. one picked in the
+// file browser, without changing the configured scan paths
+func (l *Library) ScanDir(ctx context.Context, dir string) (ScanReport, error) {
+	return l.scan(ctx, []string{dir})
+}
+
+// scan reads paths into the library and reports on it
+func (l *Library) scan(ctx context.Context, paths []string) (ScanReport, error) {
+	start := time.Now()
+	l.mu.RLock()
+	taxonomy := l.taxonomy
+	scanner := l.scanner
+	l.mu.RUnlock()
+	tracks, errs := scanner.Scan(ctx, paths)
 
-	// Collect errors
-	var scanErrors []error
+	var report ScanReport
+	collected := make(chan struct{})
 	go func() {
-		for err := range errors {
-			scanErrors = append(scanErrors, err)
+		defer close(collected)
+		for err := range errs {
+			report.Failed++
+			if se, ok := err.(*playerrors.ScanError); ok && len(report.Errors) < maxScanErrors {
+				report.Errors = append(report.Errors, se)
+			}
 		}
 	}()
 
-	// Add tracks to library
+	// Add tracks in batches so readers are not stalled by a write lock
+	// taken for every file
+	batch := make([]*api.Track, 0, scanBatchSize)
+	added := 0
 	for track := range tracks {
-		l.AddTrack(track)
+		if taxonomy != nil {
+			track.Genre = taxonomy.CanonicalList(track.Genre)
+		}
+		added++
+		if batch = append(batch, track); len(batch) == scanBatchSize {
+			l.AddTracks(batch)
+			batch = batch[:0]
+		}
 	}
+	l.AddTracks(batch)
+	<-collected
+	report.Tracks = added
+	report.Elapsed = time.Since(start)
+	report.Cancelled = ctx.Err() != nil
 
 	l.mu.Lock()
-	l.LastScanned = time.Now()
-	l.mu.Unlock()
-
-	return nil
+	defer l.mu.Unlock()
+	l.publish(api.EventLibraryChanged, nil)
+	return report, ctx.Err()
 }
 
 // Clear removes all tracks from the library
@@ -264,10 +538,13 @@ func (l *Library) Clear() {
 	l.artistIndex = make(map[string][]string)
 	l.albumIndex = make(map[string][]string)
 	l.genreIndex = make(map[string][]string)
+	l.albums = make(map[string]*api.Album)
+	l.trackAlbum = make(map[string]string)
 	l.TotalTracks = 0
 }
 
-// Save persists the library to a JSON file
+// Save persists the library to a JSON file. The file is replaced
+// atomically and the previous one kept as a backup.
 func (l *Library) Save(path string) error {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
@@ -276,31 +553,23 @@ func (l *Library) Save(path string) error {
 	if err != nil {
 		return fmt.Errorf("marshal library: %w", err)
 	}
-
-	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return fmt.Errorf("create directory: %w", err)
-	}
-
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := store.WriteFile(path, data); err != nil {
 		return fmt.Errorf("write library file: %w", err)
 	}
-
 	return nil
 }
 
-// LoadLibrary loads a library from a JSON file (or returns empty if not exists)
+// LoadLibrary loads a library from a JSON file (or returns empty if not
+// exists). Older file versions are migrated, and a damaged file is
+// replaced by its backup.
 func LoadLibrary(path string) (*Library, error) {
-	data, err := os.ReadFile(path)
-	if os.IsNotExist(err) {
+	var lib Library
+	err := store.Load(path, LibraryVersion, libraryMigrations, &lib)
+	if errors.Is(err, os.ErrNotExist) {
 		return NewLibrary(), nil // First run, return empty library
 	}
 	if err != nil {
-		return nil, fmt.Errorf("read library file: %w", err)
-	}
-
-	var lib Library
-	if err := json.Unmarshal(data, &lib); err != nil {
-		return nil, fmt.Errorf("unmarshal library: %w", err)
+		return nil, fmt.Errorf("load library file: %w", err)
 	}
 
 	// Initialize non-exported fields
@@ -319,18 +588,259 @@ func (l *Library) rebuildIndices() {
 	l.genreIndex = make(map[string][]string)
 
 	for _, track := range l.Tracks {
-		if track.Artist != "" {
-			l.artistIndex[track.Artist] = append(l.artistIndex[track.Artist], track.ID)
+		l.indexTrack(track)
+	}
+	l.rebuildUserTagIndex()
+	l.rebuildAlbums()
+
+	l.TotalTracks = len(l.Tracks)
+}
+
+// SetPublisher makes the library announce track additions, removals and
+// scan progress. Call it before the library is shared.
+func (l *Library) SetPublisher(p api.Publisher) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.pub = p
+	l.scanner.SetPublisher(p)
+}
+
+// publish sends an event if a publisher is attached. Callers hold l.mu;
+// publishing never blocks.
+func (l *Library) publish(t api.EventType, payload interface{}) {
+	if l.pub != nil {
+		l.pub.Publish(api.AudioEvent{Type: t, Payload: payload})
+	}
+}
+
+// SetHistory attaches the play log used by RecordPlay and skip detection
+func (l *Library) SetHistory(h *History) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.history = h
+}
+
+// RecordPlay appends a record to the play log, if one is attached
+func (l *Library) RecordPlay(rec PlayRecord) error {
+	l.mu.RLock()
+	h := l.history
+	l.mu.RUnlock()
+	if h == nil {
+		return nil
+	}
+	return h.Append(rec)
+}
+
+// ImportPlays records plays of track counted by another player, the last
+// of them at last (zero if unknown), as one entry of the play log. A track
+// that already has imported plays keeps them, so importing again does not
+// count them twice. It reports whether the plays were recorded.
+func (l *Library) ImportPlays(track *api.Track, plays int, last time.Time) (bool, error) {
+	l.mu.RLock()
+	h := l.history
+	l.mu.RUnlock()
+	if h == nil || plays <= 0 {
+		return false, nil
+	}
+	if h.hasImported(track.ID) {
+		return false, nil
+	}
+	return true, h.Append(PlayRecord{
+		TrackID:   track.ID,
+		FilePath:  track.FilePath,
+		Title:     track.Title,
+		Artist:    track.Artist,
+		PlayedAt:  last,
+		Played:    track.Duration,
+		Duration:  track.Duration,
+		Completed: true,
+		Plays:     plays,
+	})
+}
+
+// GetHistory returns the play log entries since t, newest first. A zero t
+// returns the whole log.
+func (l *Library) GetHistory(since time.Time) []PlayRecord {
+	l.mu.RLock()
+	h := l.history
+	l.mu.RUnlock()
+	if h == nil {
+		return nil
+	}
+	return h.Since(since)
+}
+
+// PlayStats returns the play log's counts for a track, zero when no log
+// is attached
+func (l *Library) PlayStats(id string) PlayStat {
+	l.mu.RLock()
+	h := l.history
+	l.mu.RUnlock()
+	if h == nil {
+		return PlayStat{}
+	}
+	return h.Stats(id)
+}
+
+// FrequentlySkipped returns library tracks that meet the play log's skip rule
+func (l *Library) FrequentlySkipped() []SkipStat {
+	l.mu.RLock()
+	h := l.history
+	l.mu.RUnlock()
+	if h == nil {
+		return nil
+	}
+
+	stats := h.FrequentlySkipped()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	out := stats[:0]
+	for _, st := range stats {
+		if _, ok := l.Tracks[st.TrackID]; ok && !l.Archived[st.TrackID] {
+			out = append(out, st)
 		}
-		if track.Album != "" {
-			l.albumIndex[track.Album] = append(l.albumIndex[track.Album], track.ID)
+	}
+	return out
+}
+
+// SetShuffleBanned includes or excludes a track from shuffled queues
+func (l *Library) SetShuffleBanned(id string, banned bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !banned {
+		delete(l.ShuffleBanned, id)
+		return
+	}
+	if l.ShuffleBanned == nil {
+		l.ShuffleBanned = make(map[string]bool)
+	}
+	l.ShuffleBanned[id] = true
+}
+
+// IsShuffleBanned reports whether a track is left out of shuffled queues
+func (l *Library) IsShuffleBanned(track *api.Track) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.ShuffleBanned[track.ID]
+}
+
+// ShuffleExcluded reports whether a track is left out of shuffled queues:
+// banned from shuffle, archived or broken
+func (l *Library) ShuffleExcluded(track *api.Track) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, broken := l.Broken[track.ID]
+	return l.ShuffleBanned[track.ID] || l.Archived[track.ID] || broken
+}
+
+// SetBroken marks a track as failing to play, for reason, or clears the
+// mark when reason is empty
+func (l *Library) SetBroken(id, reason string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.Tracks[id]; !ok {
+		return playerrors.ErrTrackNotFound
+	}
+	if old, ok := l.Broken[id]; ok == (reason != "") && old == reason {
+		return nil
+	}
+	if reason == "" {
+		delete(l.Broken, id)
+	} else {
+		if l.Broken == nil {
+			l.Broken = make(map[string]string)
 		}
-		if track.Genre != "" {
-			l.genreIndex[track.Genre] = append(l.genreIndex[track.Genre], track.ID)
+		l.Broken[id] = reason
+	}
+	l.publish(api.EventLibraryChanged, id)
+	return nil
+}
+
+// BrokenReason returns why a track failed to play, or "" if it is not
+// marked broken
+func (l *Library) BrokenReason(id string) string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.Broken[id]
+}
+
+// SetRating rates a track from 1 to 5 stars, or clears its rating with 0
+func (l *Library) SetRating(id string, stars int) error {
+	if stars < 0 || stars > 5 {
+		return fmt.Errorf("rating %d is not between 0 and 5 stars", stars)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.Tracks[id]; !ok {
+		return playerrors.ErrTrackNotFound
+	}
+	if stars == 0 {
+		delete(l.Ratings, id)
+		return nil
+	}
+	if l.Ratings == nil {
+		l.Ratings = make(map[string]int)
+	}
+	l.Ratings[id] = stars
+	return nil
+}
+
+// Rating returns a track's stars out of 5, 0 if it is unrated
+func (l *Library) Rating(id string) int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.Ratings[id]
+}
+
+// SetArchived hides a track from listings, search and shuffle, or brings
+// it back
+func (l *Library) SetArchived(id string, archived bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.Tracks[id]; !ok {
+		return playerrors.ErrTrackNotFound
+	}
+	if !archived {
+		delete(l.Archived, id)
+	} else {
+		if l.Archived == nil {
+			l.Archived = make(map[string]bool)
 		}
+		l.Archived[id] = true
 	}
+	l.linkAlbums(l.Tracks[id])
+	l.publish(api.EventLibraryChanged, id)
+	return nil
+}
 
-	l.TotalTracks = len(l.Tracks)
+// GetArchivedTracks returns the archived tracks
+func (l *Library) GetArchivedTracks() []*api.Track {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var tracks []*api.Track
+	for id := range l.Archived {
+		if track, ok := l.Tracks[id]; ok {
+			tracks = append(tracks, track)
+		}
+	}
+	sortTracks(tracks)
+	return tracks
+}
+
+// sortTracks orders tracks by album artist (or artist), then album, then
+// track number, so compilations are not scattered among their artists
+func sortTracks(tracks []*api.Track) {
+	sort.Slice(tracks, func(i, j int) bool {
+		ai, aj := albumArtist(tracks[i]), albumArtist(tracks[j])
+		if ai != aj {
+			return ai < aj
+		}
+		if tracks[i].Album != tracks[j].Album {
+			return tracks[i].Album < tracks[j].Album
+		}
+		return tracks[i].TrackNum < tracks[j].TrackNum
+	})
 }
 
 // AddFile adds a single file from any location to the library
@@ -339,6 +849,53 @@ func (l *Library) AddFile(filePath string) (*api.Track, error) {
 	if err != nil {
 		return nil, fmt.Errorf("scan file: %w", err)
 	}
+	l.normalizeGenre(track)
 	l.AddTrack(track)
+	l.mu.RLock()
+	l.publish(api.EventLibraryChanged, track.ID)
+	l.mu.RUnlock()
+	return track, nil
+}
+
+// ResolvePath returns the library track for a file path, adding the file to
+// the library if it is not there yet. Paths are compared in absolute form.
+func (l *Library) ResolvePath(filePath string) (*api.Track, error) {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("resolve path: %w", err)
+	}
+	if track := l.findPath(abs); track != nil {
+		return track, nil
+	}
+	return l.AddFile(abs)
+}
+
+// LookupPath returns the library track for a file path or, for a file
+// outside the library, a track read from the file without adding it
+func (l *Library) LookupPath(filePath string) (*api.Track, error) {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("resolve path: %w", err)
+	}
+	if track := l.findPath(abs); track != nil {
+		return track, nil
+	}
+	track, err := l.scanner.ScanFile(abs)
+	if err != nil {
+		return nil, fmt.Errorf("scan file: %w", err)
+	}
+	l.normalizeGenre(track)
 	return track, nil
 }
+
+// findPath returns the track whose file is at the absolute path abs
+func (l *Library) findPath(abs string) *api.Track {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, track := range l.Tracks {
+		if trackAbs, err := filepath.Abs(track.FilePath); err == nil && trackAbs == abs {
+			return track
+		}
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package library
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// PlayCount is an artist or album with how often it was played
+type PlayCount struct {
+	Label string
+	Plays int
+}
+
+// DayListening is how long music played on one day
+type DayListening struct {
+	Day      time.Time // local midnight
+	Listened time.Duration
+}
+
+// Listening summarizes the play log for the statistics dashboard. Plays
+// abandoned early by the skip rule do not count towards the top artists
+// and albums. Plays imported from other players, which carry no dates of
+// their own, only count towards the albums.
+type Listening struct {
+	ArtistsWeek  []PlayCount    // top artists of the last 7 days, most played first
+	ArtistsMonth []PlayCount    // top artists of the last 30 days
+	Albums       []PlayCount    // most played albums of all time
+	Days         []DayListening // listening time per day, oldest first, ending today
+}
+
+// Listening summarizes the play log up to now, with the listening time of
+// the last days days
+func (l *Library) Listening(now time.Time, days int) Listening {
+	l.mu.RLock()
+	h := l.history
+	l.mu.RUnlock()
+	if h == nil {
+		return Listening{}
+	}
+	out, byTrack := h.listening(now, days)
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	albums := make(map[string]int)
+	for id, n := range byTrack {
+		if albumID, ok := l.trackAlbum[id]; ok {
+			albums[albumID] += n
+		}
+	}
+	for id, n := range albums {
+		a := l.albums[id]
+		label := a.Name
+		if a.Artist != "" {
+			label = a.Artist + " – " + a.Name
+		}
+		out.Albums = append(out.Albums, PlayCount{Label: label, Plays: n})
+	}
+	sortPlayCounts(out.Albums)
+	return out
+}
+
+// listening computes the dated parts of Listening and the all-time play
+// counts by track ID
+func (h *History) listening(now time.Time, days int) (Listening, map[string]int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	now = now.Local()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	var out Listening
+	dayIndex := make(map[string]int, days)
+	for i := days - 1; i >= 0; i-- {
+		day := today.AddDate(0, 0, -i)
+		dayIndex[day.Format(time.DateOnly)] = len(out.Days)
+		out.Days = append(out.Days, DayListening{Day: day})
+	}
+	week, month := now.AddDate(0, 0, -7), now.AddDate(0, 0, -30)
+
+	byTrack := make(map[string]int)
+	weekArtists, monthArtists := make(artistCounter), make(artistCounter)
+	for _, rec := range h.records {
+		skipped := rec.EarlySkip(h.skipFraction)
+		if !skipped {
+			byTrack[rec.TrackID] += rec.count()
+		}
+		if rec.Plays > 0 || rec.PlayedAt.After(now) {
+			continue
+		}
+		if i, ok := dayIndex[rec.PlayedAt.Local().Format(time.DateOnly)]; ok {
+			out.Days[i].Listened += rec.Played
+		}
+		if skipped || rec.Artist == "" {
+			continue
+		}
+		if rec.PlayedAt.After(month) {
+			monthArtists.add(rec.Artist)
+			if rec.PlayedAt.After(week) {
+				weekArtists.add(rec.Artist)
+			}
+		}
+	}
+	out.ArtistsWeek = weekArtists.counts()
+	out.ArtistsMonth = monthArtists.counts()
+	return out, byTrack
+}
+
+// artistCounter counts plays by artist, ignoring case; the first spelling
+// seen is the one shown
+type artistCounter map[string]*PlayCount
+
+func (c artistCounter) add(artist string) {
+	key := strings.ToLower(artist)
+	if pc, ok := c[key]; ok {
+		pc.Plays++
+		return
+	}
+	c[key] = &PlayCount{Label: artist, Plays: 1}
+}
+
+func (c artistCounter) counts() []PlayCount {
+	out := make([]PlayCount, 0, len(c))
+	for _, pc := range c {
+		out = append(out, *pc)
+	}
+	sortPlayCounts(out)
+	return out
+}
+
+// sortPlayCounts orders counts most played first, then by label
+func sortPlayCounts(counts []PlayCount) {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Plays != counts[j].Plays {
+			return counts[i].Plays > counts[j].Plays
+		}
+		return counts[i].Label < counts[j].Label
+	})
+}
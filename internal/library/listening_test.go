@@ -0,0 +1,66 @@
+package library
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestListening verifies the dashboard's daily totals, top artists and
+// albums, leaving out early skips and dating imported plays nowhere
+func TestListening(t *testing.T) {
+	lib := NewLibrary()
+	lib.AddTracks([]*api.Track{
+		{ID: "ok1", Title: "Airbag", Artist: "Radiohead", Album: "OK Computer", AlbumArtist: "Radiohead", Duration: 4 * time.Minute, FilePath: "/m/ok/01.mp3"},
+		{ID: "ok2", Title: "Lucky", Artist: "Radiohead", Album: "OK Computer", AlbumArtist: "Radiohead", Duration: 4 * time.Minute, FilePath: "/m/ok/02.mp3"},
+		{ID: "mz1", Title: "Teardrop", Artist: "Massive Attack", Album: "Mezzanine", AlbumArtist: "Massive Attack", Duration: 5 * time.Minute, FilePath: "/m/mz/01.mp3"},
+	})
+	h, err := OpenHistory("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetSkipRule(0.2, 3)
+	lib.SetHistory(h)
+
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.Local)
+	play := func(id, artist string, at time.Time, played time.Duration, completed bool) {
+		t.Helper()
+		if err := lib.RecordPlay(PlayRecord{TrackID: id, Artist: artist, PlayedAt: at, Played: played, Duration: 4 * time.Minute, Completed: completed}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	play("ok1", "Radiohead", now.Add(-time.Hour), 4*time.Minute, true)
+	play("ok2", "radiohead", now.Add(-2*time.Hour), 4*time.Minute, true)
+	play("mz1", "Massive Attack", now.Add(-time.Hour), 10*time.Second, false) // early skip
+	play("mz1", "Massive Attack", now.AddDate(0, 0, -1), 5*time.Minute, true)
+	play("mz1", "Massive Attack", now.AddDate(0, 0, -10), 5*time.Minute, true)
+	play("mz1", "Massive Attack", now.AddDate(0, 0, -20), 5*time.Minute, true)
+	if _, err := lib.ImportPlays(lib.Tracks["mz1"], 50, now.AddDate(0, 0, -2)); err != nil {
+		t.Fatal(err)
+	}
+
+	l := lib.Listening(now, 7)
+	if len(l.Days) != 7 || !l.Days[6].Day.Equal(time.Date(2026, 10, 15, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("days = %+v", l.Days)
+	}
+	if got, want := l.Days[6].Listened, 8*time.Minute+10*time.Second; got != want {
+		t.Errorf("listened today = %v, want %v", got, want)
+	}
+	if got := l.Days[5].Listened; got != 5*time.Minute {
+		t.Errorf("listened yesterday = %v, want 5m", got)
+	}
+	if got := l.Days[4].Listened; got != 0 {
+		t.Errorf("imported plays dated to %v", l.Days[4].Day)
+	}
+
+	if len(l.ArtistsWeek) != 2 || l.ArtistsWeek[0] != (PlayCount{"Radiohead", 2}) || l.ArtistsWeek[1] != (PlayCount{"Massive Attack", 1}) {
+		t.Errorf("artists of the week = %+v", l.ArtistsWeek)
+	}
+	if len(l.ArtistsMonth) != 2 || l.ArtistsMonth[0] != (PlayCount{"Massive Attack", 3}) {
+		t.Errorf("artists of the month = %+v", l.ArtistsMonth)
+	}
+	if len(l.Albums) != 2 || l.Albums[0] != (PlayCount{"Massive Attack – Mezzanine", 53}) || l.Albums[1].Plays != 2 {
+		t.Errorf("albums = %+v", l.Albums)
+	}
+}
@@ -11,9 +11,9 @@ import (
 	"github.com/dhowden/tag"
 	"github.com/faiface/beep"
 	"github.com/faiface/beep/flac"
-	"github.com/faiface/beep/mp3"
 	"github.com/faiface/beep/wav"
 	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/audio/mp3trim"
 )
 
 // MetadataReader extracts metadata from audio files
@@ -35,42 +35,49 @@ func (r *MetadataReader) Read(filePath string) (*api.Track, error) {
 	// Generate unique ID from file path
 	id := generateTrackID(filePath)
 
-	// Try to read metadata tags
-	metadata, err := tag.ReadFrom(file)
-	if err != nil {
-		// If no tags, compute duration from the audio stream and return basic track info.
-		file.Seek(0, 0)
-		duration := computeAudioDuration(filePath, file)
-		return &api.Track{
-			ID:        id,
-			Title:     filepath.Base(filePath),
-			Duration:  duration,
-			FilePath:  filePath,
-			CreatedAt: time.Now(),
-		}, nil
-	}
-
-	// Compute duration by decoding the audio stream.
-	// Seek back to the start first (tag.ReadFrom may have advanced the cursor).
+	var size int64
+	if info, err := file.Stat(); err == nil {
+		size = info.Size()
+	}
+
+	// Try to read metadata tags, then measure the audio stream. Seek back
+	// to the start first (tag.ReadFrom may have advanced the cursor).
+	metadata, tagErr := tag.ReadFrom(file)
 	file.Seek(0, 0)
-	duration := computeAudioDuration(filePath, file)
+	stream := probeStream(filePath, file, size)
 
 	track := &api.Track{
-		ID:        id,
-		Title:     getOrDefault(metadata.Title(), filepath.Base(filePath)),
-		Artist:    getOrDefault(metadata.Artist(), "Unknown Artist"),
-		Album:     getOrDefault(metadata.Album(), "Unknown Album"),
-		Genre:     getOrDefault(metadata.Genre(), ""),
-		Year:      metadata.Year(),
-		Duration:  duration,
-		FilePath:  filePath,
-		CreatedAt: time.Now(),
+		ID:         id,
+		Title:      filepath.Base(filePath),
+		Duration:   stream.duration,
+		Bitrate:    stream.bitrate,
+		SampleRate: stream.sampleRate,
+		Channels:   stream.channels,
+		Codec:      codecOf(filePath),
+		FileSize:   size,
+		FilePath:   filePath,
+		CreatedAt:  time.Now(),
+	}
+	if tagErr != nil {
+		// No tags: the basic track info above is all there is
+		return track, nil
 	}
 
+	track.Title = getOrDefault(metadata.Title(), track.Title)
+	track.Artist = getOrDefault(metadata.Artist(), "Unknown Artist")
+	track.Album = getOrDefault(metadata.Album(), unknownAlbum)
+	track.AlbumArtist = strings.TrimSpace(metadata.AlbumArtist())
+	track.Compilation = readCompilation(metadata)
+	track.Genre = getOrDefault(metadata.Genre(), "")
+	track.Year = metadata.Year()
+
 	// Get track number
 	trackNum, _ := metadata.Track()
 	track.TrackNum = trackNum
 
+	track.Chapters = readChapters(metadata, track.Duration)
+	track.Gapless = readGapless(metadata)
+
 	return track, nil
 }
 
@@ -94,6 +101,67 @@ func (r *MetadataReader) ReadCoverArt(filePath string) ([]byte, error) {
 	return nil, nil
 }
 
+// FileDetails is what a file says about itself, read on demand for the
+// track details panel. The stream fields repeat those of api.Track so the
+// panel is current for tracks scanned before they were recorded.
+type FileDetails struct {
+	Format     string // container, e.g. "MP3" or "FLAC"
+	TagFormat  string // e.g. "ID3v2.4" or "VORBIS"; empty when untagged
+	SampleRate int
+	Channels   int
+	BitDepth   int // bits per sample, 0 for lossy formats
+	Bitrate    int // average kbit/s
+	Size       int64
+	ModTime    time.Time
+
+	Composer string
+	Comment  string
+	Tracks   int // tracks on the album
+	Disc     int
+	Discs    int
+}
+
+// ReadDetails reads the stream format, file size and the tags that Read
+// does not keep
+func (r *MetadataReader) ReadDetails(filePath string) (*FileDetails, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat file: %w", err)
+	}
+	details := &FileDetails{
+		Format:  strings.ToUpper(strings.TrimPrefix(filepath.Ext(filePath), ".")),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+
+	if metadata, err := tag.ReadFrom(file); err == nil {
+		details.TagFormat = string(metadata.Format())
+		details.Composer = metadata.Composer()
+		details.Comment = metadata.Comment()
+		_, details.Tracks = metadata.Track()
+		details.Disc, details.Discs = metadata.Disc()
+	}
+
+	file.Seek(0, 0)
+	stream := probeStream(filePath, file, details.Size)
+	if stream.sampleRate == 0 {
+		return details, fmt.Errorf("read stream of %s: not a supported audio file", filepath.Base(filePath))
+	}
+	details.SampleRate = stream.sampleRate
+	details.Channels = stream.channels
+	details.Bitrate = stream.bitrate
+	if codecOf(filePath) != "MP3" {
+		details.BitDepth = stream.precision * 8
+	}
+	return details, nil
+}
+
 // generateTrackID creates a unique ID for a track based on its file path
 func generateTrackID(filePath string) string {
 	hash := md5.Sum([]byte(filePath))
@@ -108,36 +176,75 @@ func getOrDefault(value, defaultValue string) string {
 	return value
 }
 
-// computeAudioDuration decodes the audio file to determine its total duration.
-// r must be seeked to position 0 before calling. Returns 0 on any error.
-func computeAudioDuration(filePath string, r interface {
+// streamInfo is what decoding a file's audio stream tells about it; each
+// field is 0 when unknown
+type streamInfo struct {
+	duration   time.Duration
+	bitrate    int // average kbit/s
+	sampleRate int
+	channels   int
+	precision  int // bytes per sample as decoded
+}
+
+// probeStream decodes the audio stream of a file of size bytes. r must be
+// at position 0; the decoder closes it.
+func probeStream(filePath string, r audioSource, size int64) streamInfo {
+	format, length, err := decodeStream(filePath, r)
+	if err != nil {
+		return streamInfo{}
+	}
+	info := streamInfo{
+		sampleRate: int(format.SampleRate),
+		channels:   format.NumChannels,
+		precision:  format.Precision,
+	}
+	if format.SampleRate > 0 && length > 0 {
+		info.duration = format.SampleRate.D(length)
+		info.bitrate = int(float64(size) * 8 / info.duration.Seconds() / 1000)
+	}
+	return info
+}
+
+// codecOf names the encoding of a supported file from its extension
+func codecOf(filePath string) string {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".mp3":
+		return "MP3"
+	case ".flac":
+		return "FLAC"
+	case ".wav":
+		return "PCM"
+	}
+	return ""
+}
+
+// audioSource is what the beep decoders read from
+type audioSource interface {
 	Read([]byte) (int, error)
 	Seek(int64, int) (int64, error)
 	Close() error
-}) time.Duration {
-	ext := strings.ToLower(filepath.Ext(filePath))
+}
 
+// decodeStream decodes the audio stream of filePath from r and returns its
+// format and length in samples
+func decodeStream(filePath string, r audioSource) (beep.Format, int, error) {
 	var streamer beep.StreamSeekCloser
 	var format beep.Format
 	var err error
 
-	switch ext {
+	switch ext := strings.ToLower(filepath.Ext(filePath)); ext {
 	case ".mp3":
-		streamer, format, err = mp3.Decode(r)
+		streamer, format, err = mp3trim.Decode(r)
 	case ".wav":
 		streamer, format, err = wav.Decode(r)
 	case ".flac":
 		streamer, format, err = flac.Decode(r)
 	default:
-		return 0
+		return beep.Format{}, 0, fmt.Errorf("unsupported format %q", ext)
 	}
 	if err != nil {
-		return 0
+		return beep.Format{}, 0, fmt.Errorf("decode: %w", err)
 	}
 	defer streamer.Close()
-
-	if format.SampleRate <= 0 || streamer.Len() <= 0 {
-		return 0
-	}
-	return format.SampleRate.D(streamer.Len())
+	return format, streamer.Len(), nil
 }
@@ -0,0 +1,54 @@
+package library
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeWAV writes secs seconds of silence as 16-bit mono PCM at rate
+func writeWAV(t *testing.T, path string, rate, secs int) {
+	t.Helper()
+	dataLen := rate * 2 * secs
+	b := make([]byte, 44+dataLen)
+	copy(b[0:], "RIFF")
+	binary.LittleEndian.PutUint32(b[4:], uint32(36+dataLen))
+	copy(b[8:], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(b[16:], 16)
+	binary.LittleEndian.PutUint16(b[20:], 1) // PCM
+	binary.LittleEndian.PutUint16(b[22:], 1)
+	binary.LittleEndian.PutUint32(b[24:], uint32(rate))
+	binary.LittleEndian.PutUint32(b[28:], uint32(rate*2))
+	binary.LittleEndian.PutUint16(b[32:], 2)
+	binary.LittleEndian.PutUint16(b[34:], 16)
+	copy(b[36:], "data")
+	binary.LittleEndian.PutUint32(b[40:], uint32(dataLen))
+	if err := os.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMetadataReader_Bitrate guards the average bitrate the bitrate column
+// and the transcoding check rely on. It was once taken from a Stat of the
+// file after the decoder had closed it, and always came out as 0.
+func TestMetadataReader_Bitrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tone.wav")
+	writeWAV(t, path, 8000, 2)
+
+	track, err := NewMetadataReader().Read(path)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if track.Duration != 2*time.Second {
+		t.Errorf("Duration = %s, want 2s", track.Duration)
+	}
+	// (44 + 32000 bytes) * 8 / 2s
+	if track.Bitrate != 128 {
+		t.Errorf("Bitrate = %d, want 128", track.Bitrate)
+	}
+	if track.FileSize != 32044 || track.SampleRate != 8000 || track.Channels != 1 || track.Codec != "PCM" {
+		t.Errorf("stream = %d bytes, %d Hz, %d channels, %q", track.FileSize, track.SampleRate, track.Channels, track.Codec)
+	}
+}
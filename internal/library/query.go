@@ -0,0 +1,374 @@
+package library
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// Query is a parsed search: free text matched against title, artist and
+// album, plus field terms such as "artist:radiohead", "year:1995..2003",
+// "bitrate<192" or "sort:-samplerate". A leading "-" negates a term or
+// excludes a word, and double quotes keep spaces in a value.
+type Query struct {
+	Text    string // lower-cased
+	exclude []string
+	terms   []queryTerm
+	sortBy  string
+	desc    bool
+}
+
+// queryTerm compares one field of a track with a value or range
+type queryTerm struct {
+	field  string
+	op     string
+	num    float64 // lower bound of a range
+	max    float64 // upper bound of a range
+	text   string
+	negate bool
+}
+
+// textFields are compared as text: ':' looks for the value inside the
+// field, '=' and '!=' compare the whole field
+var textFields = []string{"artist", "album", "title", "genre", "codec"}
+
+// numericFields are compared as numbers, with '<', '>', ranges and so on
+var numericFields = []string{"year", "bitrate", "samplerate", "channels", "size"}
+
+// queryOps are the comparisons of a term, longest first so "<=" is not
+// read as "<"
+var queryOps = []string{"<=", ">=", "!=", "<", ">", "=", ":"}
+
+// ParseQuery splits query into free text and field terms. Numbers are in
+// kbit/s for bitrate, Hz for samplerate (or kHz below 1000, so
+// "samplerate>44.1" works) and MB for size; "lo..hi" is an inclusive range
+// and either end may be left out. Words that are not valid terms are kept
+// as text.
+func ParseQuery(query string) Query {
+	var q Query
+	var text []string
+	for _, word := range splitQuery(strings.ToLower(query)) {
+		if q.parseTerm(word) {
+			continue
+		}
+		if w, ok := strings.CutPrefix(word, "-"); ok && w != "" {
+			q.exclude = append(q.exclude, w)
+			continue
+		}
+		text = append(text, word)
+	}
+	q.Text = strings.Join(text, " ")
+	return q
+}
+
+// splitQuery splits s at spaces outside double quotes and drops the quotes
+func splitQuery(s string) []string {
+	var words []string
+	var word strings.Builder
+	quoted := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case unicode.IsSpace(r) && !quoted:
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		default:
+			word.WriteRune(r)
+		}
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// parseTerm adds word to q if it is a field or sort term
+func (q *Query) parseTerm(word string) bool {
+	if field, ok := strings.CutPrefix(word, "sort:"); ok {
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+		if !slices.Contains(textFields, field) && !slices.Contains(numericFields, field) {
+			return false
+		}
+		q.sortBy, q.desc = field, desc
+		return true
+	}
+
+	negate := strings.HasPrefix(word, "-")
+	word = strings.TrimPrefix(word, "-")
+	end := strings.IndexFunc(word, func(r rune) bool { return r < 'a' || r > 'z' })
+	if end <= 0 {
+		return false
+	}
+	field, rest := word[:end], word[end:]
+	for _, op := range queryOps {
+		value, ok := strings.CutPrefix(rest, op)
+		if !ok {
+			continue
+		}
+		term := queryTerm{field: field, op: op, text: value, negate: negate}
+		switch {
+		case value == "":
+			return false
+		case slices.Contains(textFields, field):
+			if op != "=" && op != ":" && op != "!=" {
+				return false
+			}
+		case slices.Contains(numericFields, field):
+			if !term.parseNumber(value) {
+				return false
+			}
+		default:
+			return false
+		}
+		q.terms = append(q.terms, term)
+		return true
+	}
+	return false
+}
+
+// parseNumber reads the value of a numeric term, a number or a "lo..hi"
+// range, in the units of ParseQuery
+func (term *queryTerm) parseNumber(value string) bool {
+	number := func(s string) (float64, bool) {
+		n, err := strconv.ParseFloat(strings.TrimSuffix(s, "k"), 64)
+		if err != nil {
+			return 0, false
+		}
+		if term.field == "samplerate" && n < 1000 {
+			n *= 1000
+		}
+		return n, true
+	}
+
+	lo, hi, isRange := strings.Cut(value, "..")
+	if !isRange {
+		n, ok := number(value)
+		term.num = n
+		return ok
+	}
+	if term.op != ":" && term.op != "=" || lo == "" && hi == "" {
+		return false
+	}
+	term.op, term.num, term.max = "..", math.Inf(-1), math.Inf(1)
+	var ok bool
+	if lo != "" {
+		if term.num, ok = number(lo); !ok {
+			return false
+		}
+	}
+	if hi != "" {
+		if term.max, ok = number(hi); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Filters reports whether q has field terms, excluded words or a sort
+// term, which only the library can apply
+func (q Query) Filters() bool {
+	return len(q.terms) > 0 || len(q.exclude) > 0 || q.sortBy != ""
+}
+
+// Empty reports whether q matches every track in its stored order
+func (q Query) Empty() bool {
+	return q.Text == "" && !q.Filters()
+}
+
+// Match reports whether t contains q's text, none of its excluded words,
+// and meets its terms. Tracks whose numeric field is unknown never meet a
+// term on it, unless it is negated.
+func (q Query) Match(t *api.Track) bool {
+	if q.Text != "" && !containsText(t, q.Text) {
+		return false
+	}
+	for _, w := range q.exclude {
+		if containsText(t, w) {
+			return false
+		}
+	}
+	for _, term := range q.terms {
+		if term.match(t) == term.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// Relevance tiers of a track for a query's text, best first
+const (
+	MatchTitle       = iota // the title is the text
+	MatchTitlePrefix        // the title starts with the text
+	MatchTitleText          // the title contains the text
+	MatchArtist             // only the artist contains the text
+	MatchAlbum              // only the album contains the text
+	NoMatch
+)
+
+// Relevance returns the tier t falls in for q's text. Every track is a
+// MatchTitle when q has no text.
+func (q Query) Relevance(t *api.Track) int {
+	if q.Text == "" {
+		return MatchTitle
+	}
+	title := strings.ToLower(t.Title)
+	switch {
+	case title == q.Text:
+		return MatchTitle
+	case strings.HasPrefix(title, q.Text):
+		return MatchTitlePrefix
+	case strings.Contains(title, q.Text):
+		return MatchTitleText
+	case strings.Contains(strings.ToLower(t.Artist), q.Text):
+		return MatchArtist
+	case strings.Contains(strings.ToLower(t.Album), q.Text):
+		return MatchAlbum
+	}
+	return NoMatch
+}
+
+// Rank orders tracks by relevance to q's text, keeping the existing order
+// within a tier, then by q's sort term if it has one
+func (q Query) Rank(tracks []*api.Track) {
+	if q.Text != "" {
+		tiers := make(map[*api.Track]int, len(tracks))
+		for _, t := range tracks {
+			tiers[t] = q.Relevance(t)
+		}
+		sort.SliceStable(tracks, func(i, j int) bool { return tiers[tracks[i]] < tiers[tracks[j]] })
+	}
+	q.Sort(tracks)
+}
+
+// containsText reports whether the title, artist or album of t contains s
+func containsText(t *api.Track, s string) bool {
+	return strings.Contains(strings.ToLower(t.Title), s) ||
+		strings.Contains(strings.ToLower(t.Artist), s) ||
+		strings.Contains(strings.ToLower(t.Album), s)
+}
+
+func (term queryTerm) match(t *api.Track) bool {
+	if slices.Contains(textFields, term.field) {
+		return term.matchText(t)
+	}
+	v := fieldValue(t, term.field)
+	if v == 0 {
+		return false
+	}
+	switch term.op {
+	case "..":
+		return v >= term.num && v <= term.max
+	case "<":
+		return v < term.num
+	case "<=":
+		return v <= term.num
+	case ">":
+		return v > term.num
+	case ">=":
+		return v >= term.num
+	case "!=":
+		return v != term.num
+	}
+	return v == term.num
+}
+
+// matchText compares a text field; a genre matches if any of the track's
+// genres does
+func (term queryTerm) matchText(t *api.Track) bool {
+	values := []string{fieldText(t, term.field)}
+	if term.field == "genre" {
+		values = SplitGenres(t.Genre)
+	}
+	for _, v := range values {
+		v = strings.ToLower(v)
+		switch term.op {
+		case ":":
+			if strings.Contains(v, term.text) {
+				return true
+			}
+		case "=":
+			if v == term.text {
+				return true
+			}
+		case "!=":
+			if v == term.text {
+				return false
+			}
+		}
+	}
+	return term.op == "!="
+}
+
+// fieldText returns a text field of t
+func fieldText(t *api.Track, field string) string {
+	switch field {
+	case "artist":
+		return t.Artist
+	case "album":
+		return t.Album
+	case "title":
+		return t.Title
+	case "genre":
+		return t.Genre
+	case "codec":
+		return t.Codec
+	}
+	return ""
+}
+
+// fieldValue returns a numeric field of t in the units of ParseQuery, 0
+// when unknown
+func fieldValue(t *api.Track, field string) float64 {
+	switch field {
+	case "year":
+		return float64(t.Year)
+	case "bitrate":
+		return float64(t.Bitrate)
+	case "samplerate":
+		return float64(t.SampleRate)
+	case "channels":
+		return float64(t.Channels)
+	case "size":
+		return float64(t.FileSize) / (1 << 20)
+	}
+	return 0
+}
+
+// Sort orders tracks by q's sort term, if it has one. Tracks missing the
+// field go last either way.
+func (q Query) Sort(tracks []*api.Track) {
+	if q.sortBy == "" {
+		return
+	}
+	text := slices.Contains(textFields, q.sortBy)
+	sort.SliceStable(tracks, func(i, j int) bool {
+		a, b := tracks[i], tracks[j]
+		if text {
+			va, vb := strings.ToLower(fieldText(a, q.sortBy)), strings.ToLower(fieldText(b, q.sortBy))
+			if (va == "") != (vb == "") {
+				return vb == ""
+			}
+			if q.desc {
+				return va > vb
+			}
+			return va < vb
+		}
+		va, vb := fieldValue(a, q.sortBy), fieldValue(b, q.sortBy)
+		if (va == 0) != (vb == 0) {
+			return vb == 0
+		}
+		if q.desc {
+			return va > vb
+		}
+		return va < vb
+	})
+}
@@ -0,0 +1,86 @@
+package library
+
+import (
+	"testing"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestParseQuery verifies quality terms are split from the text, filter
+// on the scanned fields and sort with unknown quality last
+func TestParseQuery(t *testing.T) {
+	q := ParseQuery("Blue bitrate<192 codec:mp3 sort:-samplerate")
+	if q.Text != "blue" {
+		t.Errorf("text = %q, want %q", q.Text, "blue")
+	}
+
+	low := &api.Track{Title: "Blue Train", Bitrate: 128, Codec: "MP3", SampleRate: 44100}
+	high := &api.Track{Title: "Blue in Green", Bitrate: 320, Codec: "MP3", SampleRate: 48000}
+	unknown := &api.Track{Title: "Blue Moon", Codec: "MP3"}
+	if !q.Match(low) || q.Match(high) || q.Match(unknown) {
+		t.Errorf("match low/high/unknown = %v/%v/%v, want true/false/false",
+			q.Match(low), q.Match(high), q.Match(unknown))
+	}
+
+	tracks := []*api.Track{unknown, low, high}
+	q.Sort(tracks)
+	if tracks[0] != high || tracks[1] != low || tracks[2] != unknown {
+		t.Errorf("sort:-samplerate gave %s, %s, %s", tracks[0].Title, tracks[1].Title, tracks[2].Title)
+	}
+
+	if q := ParseQuery("size:big"); q.Text != "size:big" || q.Filters() {
+		t.Errorf("size:big parsed as %+v, want it kept as text", q)
+	}
+	if q := ParseQuery("samplerate>=96"); !q.Match(&api.Track{SampleRate: 96000}) {
+		t.Error("samplerate>=96 did not match a 96000 Hz track")
+	}
+}
+
+// TestQueryFields verifies field terms, year ranges, negation and quoted
+// values combine
+func TestQueryFields(t *testing.T) {
+	q := ParseQuery(`artist:radiohead year:1995..2003 genre:rock -live`)
+	if q.Text != "" || !q.Filters() {
+		t.Fatalf("parsed as %+v, want only filters", q)
+	}
+	tracks := map[string]*api.Track{
+		"bends":  {Title: "The Bends", Artist: "Radiohead", Genre: "Alternative Rock; Britpop", Year: 1995},
+		"live":   {Title: "Creep (Live)", Artist: "Radiohead", Genre: "Rock", Year: 1998},
+		"kid a":  {Title: "Idioteque", Artist: "Radiohead", Genre: "Electronic", Year: 2000},
+		"later":  {Title: "Daydreaming", Artist: "Radiohead", Genre: "Rock", Year: 2016},
+		"nodate": {Title: "Nude", Artist: "Radiohead", Genre: "Rock"},
+	}
+	for name, tr := range tracks {
+		if got, want := q.Match(tr), name == "bends"; got != want {
+			t.Errorf("%s: match = %v, want %v", name, got, want)
+		}
+	}
+
+	if q := ParseQuery(`artist:"pink floyd" -genre=rock year:..1975`); !q.Match(&api.Track{Artist: "Pink Floyd", Genre: "Progressive Rock", Year: 1973}) {
+		t.Error("quoted artist with a negated exact genre did not match")
+	}
+	if q := ParseQuery("year:..."); q.Filters() {
+		t.Errorf("year:... parsed as a term: %+v", q)
+	}
+}
+
+// TestRank verifies exact titles rank above title prefixes, substrings
+// and artist or album matches, and every track ties without text
+func TestRank(t *testing.T) {
+	album := &api.Track{Title: "Intro", Album: "Blue"}
+	artist := &api.Track{Title: "So What", Artist: "Blue Note"}
+	within := &api.Track{Title: "Kind of Blue"}
+	prefix := &api.Track{Title: "Blue Train"}
+	exact := &api.Track{Title: "Blue"}
+	tracks := []*api.Track{album, artist, within, prefix, exact}
+
+	ParseQuery("blue").Rank(tracks)
+	for i, want := range []*api.Track{exact, prefix, within, artist, album} {
+		if tracks[i] != want {
+			t.Errorf("rank %d = %q, want %q", i, tracks[i].Title, want.Title)
+		}
+	}
+	if tier := ParseQuery("year:1959").Relevance(within); tier != MatchTitle {
+		t.Errorf("relevance without text = %d, want MatchTitle", tier)
+	}
+}
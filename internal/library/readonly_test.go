@@ -0,0 +1,87 @@
+package library
+
+import (
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/store"
+)
+
+// snapshotDir returns the contents of the files under dir by path
+func snapshotDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		files[path] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// TestReadOnly_LeavesDataDir verifies a read-only instance changes none of
+// the library's files: the library, the play history and the genre
+// taxonomy. Plays are still kept in memory.
+func TestReadOnly_LeavesDataDir(t *testing.T) {
+	dir := t.TempDir()
+	libraryPath := filepath.Join(dir, "library.json")
+	lib := NewLibrary()
+	lib.AddTrack(&api.Track{ID: "a", Title: "One", Genre: "Rock", FilePath: "/music/a.mp3"})
+	if err := lib.Save(libraryPath); err != nil {
+		t.Fatal(err)
+	}
+	history, err := OpenHistory(filepath.Join(dir, "history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	lib.SetHistory(history)
+	if err := lib.RecordPlay(PlayRecord{TrackID: "a", PlayedAt: time.Now(), Completed: true}); err != nil {
+		t.Fatal(err)
+	}
+	taxonomy, err := LoadTaxonomy(filepath.Join(dir, "genres.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := taxonomy.SetParent("Rock", "Guitar music"); err != nil {
+		t.Fatal(err)
+	}
+	if err := taxonomy.Save(); err != nil {
+		t.Fatal(err)
+	}
+	before := snapshotDir(t, dir)
+
+	store.SetReadOnly(true)
+	t.Cleanup(func() { store.SetReadOnly(false) })
+
+	if err := lib.RecordPlay(PlayRecord{TrackID: "a", PlayedAt: time.Now()}); err != nil {
+		t.Errorf("RecordPlay = %v", err)
+	}
+	if n := len(history.Records()); n != 2 {
+		t.Errorf("%d plays in memory, want 2", n)
+	}
+	lib.AddTrack(&api.Track{ID: "b", Title: "Two", FilePath: "/music/b.mp3"})
+	if err := lib.Save(libraryPath); err == nil {
+		t.Error("library saved while read-only")
+	}
+	if err := taxonomy.SetParent("Rock", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := taxonomy.Save(); err == nil {
+		t.Error("taxonomy saved while read-only")
+	}
+
+	if after := snapshotDir(t, dir); !maps.Equal(before, after) {
+		t.Errorf("data directory changed while read-only:\nbefore %v\nafter  %v", before, after)
+	}
+}
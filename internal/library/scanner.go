@@ -6,16 +6,22 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/jscyril/golang_music_player/api"
 	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
 )
 
+// scanProgressInterval is how often a running scan publishes its progress
+const scanProgressInterval = 200 * time.Millisecond
+
 // Scanner scans directories concurrently using a worker pool
 type Scanner struct {
 	workers    int
 	formats    []string
 	metaReader *MetadataReader
+	pub        api.Publisher
 }
 
 // NewScanner creates a new file scanner
@@ -30,6 +36,34 @@ func NewScanner(workers int) *Scanner {
 	}
 }
 
+// SetPublisher makes scans publish EventScanProgress. Call it before
+// scanning.
+func (s *Scanner) SetPublisher(p api.Publisher) {
+	s.pub = p
+}
+
+// scanState counts the work of one scan for progress events
+type scanState struct {
+	discovered atomic.Int64
+	processed  atomic.Int64
+	errors     atomic.Int64
+	mu         sync.Mutex
+	current    string
+	lastErr    string
+}
+
+func (st *scanState) snapshot() api.ScanProgress {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return api.ScanProgress{
+		Discovered: int(st.discovered.Load()),
+		Processed:  int(st.processed.Load()),
+		Errors:     int(st.errors.Load()),
+		Current:    st.current,
+		LastError:  st.lastErr,
+	}
+}
+
 // SupportedFormats returns list of supported audio formats
 func (s *Scanner) SupportedFormats() []string {
 	return s.formats
@@ -46,13 +80,27 @@ func (s *Scanner) isSupported(filePath string) bool {
 	return false
 }
 
-// Scan scans directories concurrently and returns channels for results and errors
+// Scan scans directories concurrently and returns channels for results and
+// errors. Both channels must be drained until they are closed.
 func (s *Scanner) Scan(ctx context.Context, paths []string) (<-chan *api.Track, <-chan error) {
-	tracks := make(chan *api.Track, 100)
+	// Tracks wait in small buffers so a slow consumer holds back the
+	// workers, and the walk in turn, instead of queueing up a whole library
+	tracks := make(chan *api.Track, s.workers*2)
 	errors := make(chan error, 10)
-	files := make(chan string, 100)
+	files := make(chan string, s.workers*4)
 
 	var wg sync.WaitGroup
+	st := &scanState{}
+	report := func(err *playerrors.ScanError) {
+		st.errors.Add(1)
+		st.mu.Lock()
+		st.lastErr = err.Error()
+		st.mu.Unlock()
+		select {
+		case errors <- err:
+		case <-ctx.Done():
+		}
+	}
 
 	// Start file discovery goroutine
 	go func() {
@@ -66,10 +114,7 @@ func (s *Scanner) Scan(ctx context.Context, paths []string) (<-chan *api.Track,
 
 			err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
 				if err != nil {
-					select {
-					case errors <- &playerrors.ScanError{Path: p, Err: err}:
-					default:
-					}
+					report(&playerrors.ScanError{Path: p, Err: err})
 					return nil
 				}
 
@@ -80,6 +125,7 @@ func (s *Scanner) Scan(ctx context.Context, paths []string) (<-chan *api.Track,
 				}
 
 				if !d.IsDir() && s.isSupported(p) {
+					st.discovered.Add(1)
 					select {
 					case files <- p:
 					case <-ctx.Done():
@@ -90,10 +136,7 @@ func (s *Scanner) Scan(ctx context.Context, paths []string) (<-chan *api.Track,
 			})
 
 			if err != nil && err != context.Canceled {
-				select {
-				case errors <- &playerrors.ScanError{Path: path, Err: err}:
-				default:
-				}
+				report(&playerrors.ScanError{Path: path, Err: err})
 			}
 		}
 	}()
@@ -111,11 +154,12 @@ func (s *Scanner) Scan(ctx context.Context, paths []string) (<-chan *api.Track,
 				}
 
 				track, err := s.metaReader.Read(filePath)
+				st.processed.Add(1)
+				st.mu.Lock()
+				st.current = filePath
+				st.mu.Unlock()
 				if err != nil {
-					select {
-					case errors <- &playerrors.ScanError{Path: filePath, Err: err}:
-					default:
-					}
+					report(&playerrors.ScanError{Path: filePath, Err: err})
 					continue
 				}
 
@@ -128,9 +172,37 @@ func (s *Scanner) Scan(ctx context.Context, paths []string) (<-chan *api.Track,
 		}()
 	}
 
+	// Publish progress until the workers finish; the final event goes out
+	// before the results channel closes
+	finished := make(chan struct{})
+	reported := make(chan struct{})
+	if s.pub == nil {
+		close(reported)
+	} else {
+		go func() {
+			defer close(reported)
+			ticker := time.NewTicker(scanProgressInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					s.pub.Publish(api.AudioEvent{Type: api.EventScanProgress, Payload: st.snapshot()})
+				case <-finished:
+					p := st.snapshot()
+					p.Done = true
+					p.Cancelled = ctx.Err() != nil
+					s.pub.Publish(api.AudioEvent{Type: api.EventScanProgress, Payload: p})
+					return
+				}
+			}
+		}()
+	}
+
 	// Close channels when done
 	go func() {
 		wg.Wait()
+		close(finished)
+		<-reported
 		close(tracks)
 		close(errors)
 	}()
@@ -0,0 +1,64 @@
+package library
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// recorder collects published events
+type recorder struct {
+	mu     sync.Mutex
+	events []api.AudioEvent
+}
+
+func (r *recorder) Publish(e api.AudioEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+// TestScan_PublishesProgress verifies a scan ends with a Done event counting
+// every file found, and reports paths it could not read
+func TestScan_PublishesProgress(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.mp3", "b.flac", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("not audio"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rec := &recorder{}
+	lib := NewLibrary()
+	lib.SetPublisher(rec)
+	missing := filepath.Join(dir, "missing")
+	report, err := lib.Scan(context.Background(), []string{dir, missing})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Tracks+report.Failed != 3 || len(report.Errors) != report.Failed {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if len(report.Errors) == 0 || report.Errors[len(report.Errors)-1].Path != missing {
+		t.Errorf("missing directory not reported: %+v", report.Errors)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	var last *api.ScanProgress
+	for _, e := range rec.events {
+		if p, ok := e.Payload.(api.ScanProgress); ok && e.Type == api.EventScanProgress {
+			last = &p
+		}
+	}
+	if last == nil || !last.Done {
+		t.Fatalf("no final progress event in %+v", rec.events)
+	}
+	if last.Discovered != 2 || last.Processed != 2 || last.Errors != report.Failed || last.Cancelled {
+		t.Errorf("unexpected final progress %+v", *last)
+	}
+}
@@ -0,0 +1,71 @@
+package library
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// GenreCount is a genre with the number of tracks tagged with it
+type GenreCount struct {
+	Genre  string
+	Tracks int
+}
+
+// Summary describes the size of the library as a whole. Archived tracks
+// are left out.
+type Summary struct {
+	Tracks   int
+	Albums   int
+	Artists  int
+	Duration time.Duration
+	Size     int64        // bytes on disk
+	Genres   []GenreCount // most tracks first
+	Archived int
+}
+
+// Summarize adds up the library's tracks
+func (l *Library) Summarize() Summary {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var s Summary
+	artists := make(map[string]bool)
+	albums := make(map[string]bool)
+	for id, t := range l.Tracks {
+		if l.Archived[id] {
+			s.Archived++
+			continue
+		}
+		s.Tracks++
+		s.Duration += t.Duration
+		s.Size += t.FileSize
+		if t.Artist != "" {
+			artists[strings.ToLower(t.Artist)] = true
+		}
+		if id, ok := l.trackAlbum[id]; ok {
+			albums[id] = true
+		}
+	}
+	s.Artists = len(artists)
+	s.Albums = len(albums)
+
+	for genre, ids := range l.genreIndex {
+		n := 0
+		for _, id := range ids {
+			if !l.Archived[id] {
+				n++
+			}
+		}
+		if n > 0 {
+			s.Genres = append(s.Genres, GenreCount{Genre: genre, Tracks: n})
+		}
+	}
+	sort.Slice(s.Genres, func(i, j int) bool {
+		if s.Genres[i].Tracks != s.Genres[j].Tracks {
+			return s.Genres[i].Tracks > s.Genres[j].Tracks
+		}
+		return s.Genres[i].Genre < s.Genres[j].Genre
+	})
+	return s
+}
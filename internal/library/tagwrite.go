@@ -0,0 +1,475 @@
+package library
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// ErrTagsUnsupported is returned for files whose tags cannot be written;
+// edits to them are kept in the library only
+var ErrTagsUnsupported = errors.New("writing tags is not supported for this format")
+
+// TagEdit is a set of tag changes. Nil fields are left as they are; an
+// empty string or zero number clears the tag.
+type TagEdit struct {
+	Title    *string
+	Artist   *string
+	Album    *string
+	Genre    *string
+	Year     *int
+	TrackNum *int
+}
+
+// IsZero reports whether the edit changes nothing
+func (e TagEdit) IsZero() bool {
+	return e.Title == nil && e.Artist == nil && e.Album == nil && e.Genre == nil &&
+		e.Year == nil && e.TrackNum == nil
+}
+
+// apply copies the edited fields into track
+func (e TagEdit) apply(track *api.Track) {
+	if e.Title != nil {
+		track.Title = *e.Title
+	}
+	if e.Artist != nil {
+		track.Artist = *e.Artist
+	}
+	if e.Album != nil {
+		track.Album = *e.Album
+	}
+	if e.Genre != nil {
+		track.Genre = *e.Genre
+	}
+	if e.Year != nil {
+		track.Year = *e.Year
+	}
+	if e.TrackNum != nil {
+		track.TrackNum = *e.TrackNum
+	}
+}
+
+// WriteTags writes edit into the tags of the file at path: ID3v2 for MP3,
+// Vorbis comments for FLAC. Other formats return ErrTagsUnsupported. The
+// file is replaced in one step, so a failed write leaves it untouched.
+func WriteTags(path string, edit TagEdit) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	var out []byte
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		out, err = writeID3(data, edit)
+	case ".flac":
+		out, err = writeVorbisComments(data, edit)
+	default:
+		return ErrTagsUnsupported
+	}
+	if err != nil {
+		return err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("stat file: %w", err)
+	}
+	tmp := path + ".tagtmp"
+	if err := os.WriteFile(tmp, out, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("write tags: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("replace file: %w", err)
+	}
+	return nil
+}
+
+// id3Padding is the free space left in a rewritten ID3 tag
+const id3Padding = 1024
+
+// id3Frame is a raw frame of an existing tag, kept byte for byte
+type id3Frame struct {
+	id  string
+	raw []byte // header and body
+}
+
+// writeID3 replaces the edited text frames of the file's ID3v2 tag,
+// keeping every other frame. Files without a tag get an ID3v2.3 one.
+func writeID3(data []byte, edit TagEdit) ([]byte, error) {
+	version := byte(3)
+	var frames []id3Frame
+	audio := data
+
+	if len(data) >= 10 && string(data[:3]) == "ID3" {
+		version = data[3]
+		flags := data[5]
+		size := syncsafe(data[6:10])
+		end := 10 + size
+		if flags&0x10 != 0 {
+			end += 10 // footer
+		}
+		if end > len(data) {
+			return nil, fmt.Errorf("ID3 tag is truncated")
+		}
+		if version < 3 || version > 4 || flags&0x80 != 0 {
+			return nil, fmt.Errorf("ID3v2.%d tag layout: %w", version, ErrTagsUnsupported)
+		}
+		body := data[10 : 10+size]
+		if flags&0x40 != 0 { // extended header
+			if len(body) < 4 {
+				return nil, fmt.Errorf("ID3 extended header is truncated")
+			}
+			ext := int(binary.BigEndian.Uint32(body[:4])) + 4
+			if version == 4 {
+				ext = syncsafe(body[:4])
+			}
+			if ext > len(body) {
+				return nil, fmt.Errorf("ID3 extended header is truncated")
+			}
+			body = body[ext:]
+		}
+		// A frame that cannot be read would take every frame after it
+		// with it, cover art included, so the file is left alone
+		for len(body) >= 10 && body[0] != 0 {
+			id := string(body[:4])
+			if !validFrameID(id) {
+				return nil, fmt.Errorf("ID3 frame ID %q: %w", id, ErrTagsUnsupported)
+			}
+			n := int(binary.BigEndian.Uint32(body[4:8]))
+			if version == 4 {
+				// Some taggers write plain sizes in ID3v2.4, which are
+				// ambiguous once a byte has its high bit set
+				if !isSyncsafe(body[4:8]) {
+					return nil, fmt.Errorf("ID3 frame %s has a size that is not syncsafe: %w", id, ErrTagsUnsupported)
+				}
+				n = syncsafe(body[4:8])
+			}
+			if 10+n > len(body) {
+				return nil, fmt.Errorf("ID3 frame %s runs past the end of the tag: %w", id, ErrTagsUnsupported)
+			}
+			frames = append(frames, id3Frame{id: id, raw: body[:10+n]})
+			body = body[10+n:]
+		}
+		audio = data[end:]
+	}
+
+	yearID := "TYER"
+	if version == 4 {
+		yearID = "TDRC"
+	}
+	set := make(map[string]string)
+	if edit.Title != nil {
+		set["TIT2"] = *edit.Title
+	}
+	if edit.Artist != nil {
+		set["TPE1"] = *edit.Artist
+	}
+	if edit.Album != nil {
+		set["TALB"] = *edit.Album
+	}
+	if edit.Genre != nil {
+		set["TCON"] = *edit.Genre
+	}
+	if edit.Year != nil {
+		set[yearID] = number(*edit.Year)
+	}
+	if edit.TrackNum != nil {
+		set["TRCK"] = number(*edit.TrackNum)
+		// Keep the album's track count of "3/12"
+		for _, f := range frames {
+			if f.id == "TRCK" && *edit.TrackNum > 0 {
+				if _, total, ok := strings.Cut(id3Text(f.raw[10:]), "/"); ok {
+					set["TRCK"] += "/" + total
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, f := range frames {
+		if _, replaced := set[f.id]; replaced || (edit.Year != nil && (f.id == "TYER" || f.id == "TDRC")) {
+			continue
+		}
+		buf.Write(f.raw)
+	}
+	for _, id := range []string{"TIT2", "TPE1", "TALB", "TCON", yearID, "TRCK"} {
+		if value, ok := set[id]; ok && value != "" {
+			buf.Write(id3TextFrame(version, id, value))
+		}
+	}
+	buf.Write(make([]byte, id3Padding))
+
+	out := make([]byte, 0, 10+buf.Len()+len(audio))
+	out = append(out, 'I', 'D', '3', version, 0, 0)
+	out = append(out, putSyncsafe(buf.Len())...)
+	out = append(out, buf.Bytes()...)
+	return append(out, audio...), nil
+}
+
+// id3TextFrame encodes a text frame: UTF-8 in ID3v2.4, UTF-16 with a byte
+// order mark in ID3v2.3
+func id3TextFrame(version byte, id, value string) []byte {
+	var body []byte
+	if version == 4 {
+		body = append([]byte{3}, value...)
+	} else {
+		body = []byte{1, 0xFF, 0xFE}
+		for _, u := range utf16.Encode([]rune(value)) {
+			body = append(body, byte(u), byte(u>>8))
+		}
+	}
+	frame := append([]byte(id), 0, 0, 0, 0, 0, 0)
+	if version == 4 {
+		copy(frame[4:8], putSyncsafe(len(body)))
+	} else {
+		binary.BigEndian.PutUint32(frame[4:8], uint32(len(body)))
+	}
+	return append(frame, body...)
+}
+
+// id3Text decodes the body of a text frame well enough to read numbers
+func id3Text(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	enc, s := body[0], body[1:]
+	if enc == 1 || enc == 2 {
+		var order binary.ByteOrder = binary.BigEndian
+		if enc == 1 && len(s) >= 2 {
+			if s[0] == 0xFF && s[1] == 0xFE {
+				order = binary.LittleEndian
+			}
+			s = s[2:]
+		}
+		var units []uint16
+		for i := 0; i+1 < len(s); i += 2 {
+			units = append(units, order.Uint16(s[i:]))
+		}
+		return strings.TrimRight(string(utf16.Decode(units)), "\x00")
+	}
+	return strings.TrimRight(string(s), "\x00")
+}
+
+// validFrameID reports whether id is made of the capital letters and
+// digits frame IDs use
+func validFrameID(id string) bool {
+	for _, c := range []byte(id) {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// isSyncsafe reports whether b holds a syncsafe integer, with the high bit
+// of every byte clear
+func isSyncsafe(b []byte) bool {
+	return (b[0]|b[1]|b[2]|b[3])&0x80 == 0
+}
+
+func syncsafe(b []byte) int {
+	return int(b[0])<<21 | int(b[1])<<14 | int(b[2])<<7 | int(b[3])
+}
+
+func putSyncsafe(n int) []byte {
+	return []byte{byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}
+}
+
+// number formats a tag number, with 0 meaning no value
+func number(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// flacVorbisComment is the metadata block type holding FLAC tags
+const flacVorbisComment = 4
+
+// writeVorbisComments replaces the edited fields in a FLAC file's Vorbis
+// comment block, keeping other comments and metadata blocks
+func writeVorbisComments(data []byte, edit TagEdit) ([]byte, error) {
+	if len(data) < 4 || string(data[:4]) != "fLaC" {
+		return nil, fmt.Errorf("not a FLAC file")
+	}
+
+	type block struct {
+		typ  byte
+		body []byte
+	}
+	var blocks []block
+	pos := 4
+	for {
+		if pos+4 > len(data) {
+			return nil, fmt.Errorf("FLAC metadata is truncated")
+		}
+		last := data[pos]&0x80 != 0
+		typ := data[pos] & 0x7F
+		n := int(data[pos+1])<<16 | int(data[pos+2])<<8 | int(data[pos+3])
+		if pos+4+n > len(data) {
+			return nil, fmt.Errorf("FLAC metadata is truncated")
+		}
+		blocks = append(blocks, block{typ, data[pos+4 : pos+4+n]})
+		pos += 4 + n
+		if last {
+			break
+		}
+	}
+	audio := data[pos:]
+
+	vendor := "gtmpc"
+	var comments []string
+	at := -1
+	for i, b := range blocks {
+		if b.typ != flacVorbisComment {
+			continue
+		}
+		var err error
+		if vendor, comments, err = parseVorbisComments(b.body); err != nil {
+			return nil, err
+		}
+		at = i
+		break
+	}
+
+	set := make(map[string]string)
+	if edit.Title != nil {
+		set["TITLE"] = *edit.Title
+	}
+	if edit.Artist != nil {
+		set["ARTIST"] = *edit.Artist
+	}
+	if edit.Album != nil {
+		set["ALBUM"] = *edit.Album
+	}
+	if edit.Genre != nil {
+		set["GENRE"] = *edit.Genre
+	}
+	if edit.Year != nil {
+		set["DATE"] = number(*edit.Year)
+	}
+	if edit.TrackNum != nil {
+		set["TRACKNUMBER"] = number(*edit.TrackNum)
+	}
+
+	var kept []string
+	for _, c := range comments {
+		key, _, _ := strings.Cut(c, "=")
+		if _, replaced := set[strings.ToUpper(key)]; !replaced {
+			kept = append(kept, c)
+		}
+	}
+	for _, key := range []string{"TITLE", "ARTIST", "ALBUM", "GENRE", "DATE", "TRACKNUMBER"} {
+		if value, ok := set[key]; ok && value != "" {
+			kept = append(kept, key+"="+value)
+		}
+	}
+
+	var body bytes.Buffer
+	binary.Write(&body, binary.LittleEndian, uint32(len(vendor)))
+	body.WriteString(vendor)
+	binary.Write(&body, binary.LittleEndian, uint32(len(kept)))
+	for _, c := range kept {
+		binary.Write(&body, binary.LittleEndian, uint32(len(c)))
+		body.WriteString(c)
+	}
+	if body.Len() >= 1<<24 {
+		return nil, fmt.Errorf("FLAC comments too large")
+	}
+	comment := block{flacVorbisComment, body.Bytes()}
+	if at >= 0 {
+		blocks[at] = comment
+	} else {
+		// After STREAMINFO, which must come first
+		blocks = append(blocks[:1], append([]block{comment}, blocks[1:]...)...)
+	}
+
+	out := make([]byte, 0, len(data)+body.Len())
+	out = append(out, "fLaC"...)
+	for i, b := range blocks {
+		head := b.typ
+		if i == len(blocks)-1 {
+			head |= 0x80
+		}
+		n := len(b.body)
+		out = append(out, head, byte(n>>16), byte(n>>8), byte(n))
+		out = append(out, b.body...)
+	}
+	return append(out, audio...), nil
+}
+
+// parseVorbisComments splits a Vorbis comment block into its vendor string
+// and KEY=value comments
+func parseVorbisComments(b []byte) (vendor string, comments []string, err error) {
+	read := func() (string, bool) {
+		if len(b) < 4 {
+			return "", false
+		}
+		n := int(binary.LittleEndian.Uint32(b))
+		if n > len(b)-4 {
+			return "", false
+		}
+		s := string(b[4 : 4+n])
+		b = b[4+n:]
+		return s, true
+	}
+	var ok bool
+	if vendor, ok = read(); !ok || len(b) < 4 {
+		return "", nil, fmt.Errorf("FLAC comment block is malformed")
+	}
+	count := int(binary.LittleEndian.Uint32(b))
+	b = b[4:]
+	for range count {
+		c, ok := read()
+		if !ok {
+			return "", nil, fmt.Errorf("FLAC comment block is malformed")
+		}
+		comments = append(comments, c)
+	}
+	return vendor, comments, nil
+}
+
+// EditTags applies edit to the tracks with the given IDs: to their files'
+// tags, then to the library and its indices. Tracks whose files cannot be
+// written are still updated in the library; their errors are joined in the
+// result.
+func (l *Library) EditTags(ids []string, edit TagEdit) error {
+	if edit.IsZero() {
+		return nil
+	}
+	var errs []error
+	for _, id := range ids {
+		l.mu.RLock()
+		track, ok := l.Tracks[id]
+		path := ""
+		if ok {
+			path = track.FilePath
+		}
+		l.mu.RUnlock()
+		if !ok {
+			continue
+		}
+		if err := WriteTags(path, edit); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(path), err))
+		}
+
+		l.mu.Lock()
+		l.unindexTrack(track)
+		edit.apply(track)
+		l.indexTrack(track)
+		l.linkAlbums(track)
+		l.publish(api.EventLibraryChanged, id)
+		l.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,118 @@
+package library
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/dhowden/tag"
+)
+
+func ptr[T any](v T) *T { return &v }
+
+func TestWriteID3_KeepsOtherFrames(t *testing.T) {
+	// An ID3v2.3 tag with a title, a "3/12" track and a comment frame
+	var frames []byte
+	frames = append(frames, id3TextFrame(3, "TIT2", "Old")...)
+	frames = append(frames, id3TextFrame(3, "TRCK", "3/12")...)
+	frames = append(frames, id3TextFrame(3, "TXXX", "\x00keep")...)
+	src := append([]byte{'I', 'D', '3', 3, 0, 0}, putSyncsafe(len(frames))...)
+	src = append(src, frames...)
+	src = append(src, 0xFF, 0xFB, 0x90, 0x00) // start of the audio
+
+	out, err := writeID3(src, TagEdit{Title: ptr("Neue Straße"), Album: ptr("Album"), TrackNum: ptr(4)})
+	if err != nil {
+		t.Fatalf("writeID3: %v", err)
+	}
+	m, err := tag.ReadFrom(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	if m.Title() != "Neue Straße" || m.Album() != "Album" {
+		t.Errorf("title, album = %q, %q", m.Title(), m.Album())
+	}
+	if n, total := m.Track(); n != 4 || total != 12 {
+		t.Errorf("track = %d/%d, want 4/12", n, total)
+	}
+	if _, ok := m.Raw()["TXXX"]; !ok {
+		t.Error("TXXX frame was dropped")
+	}
+	if !bytes.HasSuffix(out, []byte{0xFF, 0xFB, 0x90, 0x00}) {
+		t.Error("audio data was not kept")
+	}
+}
+
+// TestWriteID3_MalformedFrames verifies that a tag with a frame that
+// cannot be read is refused instead of rewritten without the frames after it
+func TestWriteID3_MalformedFrames(t *testing.T) {
+	tag := func(version byte, frames ...[]byte) []byte {
+		body := bytes.Join(frames, nil)
+		src := append([]byte{'I', 'D', '3', version, 0, 0}, putSyncsafe(len(body))...)
+		src = append(src, body...)
+		return append(src, 0xFF, 0xFB, 0x90, 0x00)
+	}
+	apic := func(version byte) []byte { return id3TextFrame(version, "APIC", "\x00image/jpeg\x00cover") }
+	oversized := func(version byte) []byte {
+		f := id3TextFrame(version, "TIT2", "Title")
+		binary.BigEndian.PutUint32(f[4:8], 500)
+		return f
+	}
+	// iTunes writes ID3v2.4 frame sizes as plain integers
+	plainSize := func() []byte {
+		f := append([]byte("TIT2"), 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint32(f[4:8], 200)
+		return append(f, append([]byte{3}, bytes.Repeat([]byte("a"), 199)...)...)
+	}
+
+	for name, src := range map[string][]byte{
+		"v2.3 frame past the end": tag(3, oversized(3), apic(3)),
+		"v2.4 frame past the end": tag(4, oversized(4), apic(4)),
+		"v2.4 plain frame size":   tag(4, plainSize(), apic(4)),
+		"garbage frame ID":        tag(3, []byte("ti\x002\x00\x00\x00\x01\x00\x00x"), apic(3)),
+	} {
+		if _, err := writeID3(src, TagEdit{Title: ptr("New")}); !errors.Is(err, ErrTagsUnsupported) {
+			t.Errorf("%s: err = %v, want ErrTagsUnsupported", name, err)
+		}
+	}
+
+	// Padding after the last frame is not a frame
+	padded := tag(3, id3TextFrame(3, "TIT2", "Old"), apic(3), make([]byte, 64))
+	if _, err := writeID3(padded, TagEdit{Title: ptr("New")}); err != nil {
+		t.Errorf("padded tag: %v", err)
+	}
+}
+
+func TestWriteVorbisComments(t *testing.T) {
+	streamInfo := make([]byte, 34)
+	var comment bytes.Buffer
+	put := func(s string) {
+		binary.Write(&comment, binary.LittleEndian, uint32(len(s)))
+		comment.WriteString(s)
+	}
+	put("vendor")
+	binary.Write(&comment, binary.LittleEndian, uint32(2))
+	put("TITLE=Old")
+	put("COMMENT=keep")
+
+	src := []byte("fLaC")
+	src = append(src, 0, 0, 0, 34)
+	src = append(src, streamInfo...)
+	src = append(src, 0x80|flacVorbisComment, 0, 0, byte(comment.Len()))
+	src = append(src, comment.Bytes()...)
+
+	out, err := writeVorbisComments(src, TagEdit{Title: ptr("New"), Year: ptr(1999)})
+	if err != nil {
+		t.Fatalf("writeVorbisComments: %v", err)
+	}
+	m, err := tag.ReadFrom(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	if m.Title() != "New" || m.Year() != 1999 {
+		t.Errorf("title, year = %q, %d", m.Title(), m.Year())
+	}
+	if m.Raw()["comment"] != "keep" {
+		t.Errorf("comment = %v, want keep", m.Raw()["comment"])
+	}
+}
@@ -0,0 +1,269 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jscyril/golang_music_player/internal/store"
+)
+
+// GenreRule rewrites tag genres during scanning. Match is a case-insensitive
+// glob ("*deep*house*") tested against the raw genre tag.
+type GenreRule struct {
+	Match string `json:"match"`
+	Genre string `json:"genre"`
+}
+
+// GenreNode is one row of the flattened genre tree
+type GenreNode struct {
+	Name  string
+	Depth int
+}
+
+// Taxonomy is a user-defined genre hierarchy (Electronic ▸ House ▸ Deep House)
+// plus rules that map tag spellings onto it
+type Taxonomy struct {
+	Parents map[string]string `json:"parents"` // genre -> parent genre
+	Rules   []GenreRule       `json:"rules"`
+
+	path string
+	mu   sync.RWMutex
+}
+
+// NewTaxonomy creates an empty taxonomy
+func NewTaxonomy() *Taxonomy {
+	return &Taxonomy{Parents: make(map[string]string)}
+}
+
+// LoadTaxonomy reads a taxonomy file, returning an empty one if it does not exist
+func LoadTaxonomy(path string) (*Taxonomy, error) {
+	t := NewTaxonomy()
+	t.path = path
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read taxonomy file: %w", err)
+	}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("unmarshal taxonomy: %w", err)
+	}
+	if t.Parents == nil {
+		t.Parents = make(map[string]string)
+	}
+	return t, nil
+}
+
+// Save writes the taxonomy back to the file it was loaded from
+func (t *Taxonomy) Save() error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal taxonomy: %w", err)
+	}
+	if err := store.WriteFile(t.path, data); err != nil {
+		return fmt.Errorf("write taxonomy file: %w", err)
+	}
+	return nil
+}
+
+// Canonical maps a raw tag genre to its taxonomy name: the first matching
+// rule wins, otherwise a case-insensitive match against known genres
+// normalizes the spelling
+func (t *Taxonomy) Canonical(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return raw
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	lower := strings.ToLower(raw)
+	for _, r := range t.Rules {
+		if ok, _ := path.Match(strings.ToLower(r.Match), lower); ok {
+			return r.Genre
+		}
+	}
+	return t.spellingLocked(raw)
+}
+
+// GenreSeparator joins the genres of a track with several
+const GenreSeparator = "; "
+
+// SplitGenres splits a genre tag holding several genres, separated by
+// ';', '/' or ',', dropping blanks and case-insensitive repeats
+func SplitGenres(s string) []string {
+	var genres []string
+	for _, g := range strings.FieldsFunc(s, func(r rune) bool { return r == ';' || r == '/' || r == ',' }) {
+		g = strings.TrimSpace(g)
+		if g != "" && !slices.ContainsFunc(genres, func(h string) bool { return strings.EqualFold(g, h) }) {
+			genres = append(genres, g)
+		}
+	}
+	return genres
+}
+
+// CanonicalList maps each genre of a multi-genre tag with Canonical and
+// joins them with GenreSeparator
+func (t *Taxonomy) CanonicalList(raw string) string {
+	var genres []string
+	for _, g := range SplitGenres(raw) {
+		g = t.Canonical(g)
+		if !slices.ContainsFunc(genres, func(h string) bool { return strings.EqualFold(g, h) }) {
+			genres = append(genres, g)
+		}
+	}
+	return strings.Join(genres, GenreSeparator)
+}
+
+// SetParent places genre under parent; an empty parent makes it a root.
+// Genres already in the taxonomy are matched in any spelling. Moving a
+// genre under its own descendant is rejected.
+func (t *Taxonomy) SetParent(genre, parent string) error {
+	genre, parent = strings.TrimSpace(genre), strings.TrimSpace(parent)
+	if genre == "" {
+		return fmt.Errorf("set genre parent: empty genre")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	genre, parent = t.spellingLocked(genre), t.spellingLocked(parent)
+
+	if parent == "" {
+		delete(t.Parents, genre)
+		return nil
+	}
+	// A hand-edited file may already hold a cycle; seen ends the walk there
+	seen := make(map[string]bool)
+	for p := parent; p != "" && !seen[p]; p = t.Parents[p] {
+		if strings.EqualFold(p, genre) {
+			return fmt.Errorf("set genre parent: %q is inside %q", parent, genre)
+		}
+		seen[p] = true
+	}
+	t.Parents[genre] = parent
+	return nil
+}
+
+// Ancestors returns the chain of parents from the genre's parent up to its root
+func (t *Taxonomy) Ancestors(genre string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var out []string
+	seen := map[string]bool{genre: true}
+	for p := t.Parents[genre]; p != "" && !seen[p]; p = t.Parents[p] {
+		out = append(out, p)
+		seen[p] = true
+	}
+	return out
+}
+
+// Descendants returns genre and every genre beneath it
+func (t *Taxonomy) Descendants(genre string) []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	children := t.childrenLocked()
+	out := []string{genre}
+	seen := map[string]bool{genre: true}
+	for i := 0; i < len(out); i++ {
+		for _, c := range children[out[i]] {
+			if !seen[c] {
+				seen[c] = true
+				out = append(out, c)
+			}
+		}
+	}
+	return out
+}
+
+// Tree flattens the hierarchy depth-first, including any extra genres
+// (e.g. those found in the library) that the taxonomy does not mention
+func (t *Taxonomy) Tree(extra []string) []GenreNode {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	children := t.childrenLocked()
+	all := make(map[string]bool)
+	for _, g := range extra {
+		if g != "" {
+			all[g] = true
+		}
+	}
+	for child, parent := range t.Parents {
+		all[child] = true
+		all[parent] = true
+	}
+
+	var roots []string
+	for g := range all {
+		if _, ok := t.Parents[g]; !ok {
+			roots = append(roots, g)
+		}
+	}
+	sortFold(roots)
+
+	var out []GenreNode
+	seen := make(map[string]bool)
+	var walk func(name string, depth int)
+	walk = func(name string, depth int) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		out = append(out, GenreNode{Name: name, Depth: depth})
+		for _, c := range children[name] {
+			walk(c, depth+1)
+		}
+	}
+	for _, r := range roots {
+		walk(r, 0)
+	}
+	return out
+}
+
+// spellingLocked returns the spelling the taxonomy knows genre by, or
+// genre itself if it is new; callers hold mu
+func (t *Taxonomy) spellingLocked(genre string) string {
+	for child, parent := range t.Parents {
+		if strings.EqualFold(child, genre) {
+			return child
+		}
+		if strings.EqualFold(parent, genre) {
+			return parent
+		}
+	}
+	return genre
+}
+
+// childrenLocked builds a parent -> sorted children map; callers hold mu
+func (t *Taxonomy) childrenLocked() map[string][]string {
+	children := make(map[string][]string)
+	for child, parent := range t.Parents {
+		children[parent] = append(children[parent], child)
+	}
+	for _, c := range children {
+		sortFold(c)
+	}
+	return children
+}
+
+func sortFold(s []string) {
+	sort.Slice(s, func(i, j int) bool { return strings.ToLower(s[i]) < strings.ToLower(s[j]) })
+}
@@ -0,0 +1,138 @@
+package library
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// electronic returns a taxonomy of Electronic > House > Deep House and
+// Electronic > Techno, with a rule for a spelling of Deep House
+func electronic(t *testing.T) *Taxonomy {
+	t.Helper()
+	tax := NewTaxonomy()
+	for _, p := range [][2]string{{"House", "Electronic"}, {"Deep House", "House"}, {"Techno", "Electronic"}} {
+		if err := tax.SetParent(p[0], p[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tax.Rules = []GenreRule{{Match: "*deep*house*", Genre: "Deep House"}}
+	return tax
+}
+
+// TestTaxonomy_Canonical verifies rules come first, then the spelling of
+// a known genre, and that other genres are only trimmed
+func TestTaxonomy_Canonical(t *testing.T) {
+	tax := electronic(t)
+	tests := []struct{ raw, want string }{
+		{"Deep-House Classics", "Deep House"},
+		{"DEEP HOUSE", "Deep House"},
+		{"  house ", "House"},
+		{"electronic", "Electronic"},
+		{" Jazz ", "Jazz"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := tax.Canonical(tt.raw); got != tt.want {
+			t.Errorf("Canonical(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+	if got := tax.CanonicalList("house; Jazz / HOUSE"); got != "House; Jazz" {
+		t.Errorf("CanonicalList = %q, want %q", got, "House; Jazz")
+	}
+}
+
+// TestTaxonomy_SetParentRejectsCycles verifies a genre cannot be put under
+// itself or anything beneath it, in any spelling, and that a rejected move
+// changes nothing
+func TestTaxonomy_SetParentRejectsCycles(t *testing.T) {
+	tax := electronic(t)
+	for _, p := range [][2]string{
+		{"Electronic", "Electronic"},
+		{"Electronic", "House"},
+		{"Electronic", "Deep House"},
+		{"house", "deep house"},
+	} {
+		if err := tax.SetParent(p[0], p[1]); err == nil {
+			t.Errorf("SetParent(%q, %q) made a cycle", p[0], p[1])
+		}
+	}
+	if got := tax.Ancestors("Deep House"); !slices.Equal(got, []string{"House", "Electronic"}) {
+		t.Errorf("Ancestors after rejected moves = %v", got)
+	}
+
+	// Moving sideways and back to the root is fine
+	if err := tax.SetParent("Deep House", "Techno"); err != nil {
+		t.Errorf("move under a sibling: %v", err)
+	}
+	if err := tax.SetParent("House", ""); err != nil || tax.Ancestors("House") != nil {
+		t.Errorf("move to the root: %v, ancestors %v", err, tax.Ancestors("House"))
+	}
+	if err := tax.SetParent(" ", "Electronic"); err == nil {
+		t.Error("an empty genre was accepted")
+	}
+
+	// A cycle already in a loaded file does not hang the check
+	tax.Parents = map[string]string{"A": "B", "B": "A"}
+	if err := tax.SetParent("C", "A"); err != nil {
+		t.Errorf("SetParent under an existing cycle: %v", err)
+	}
+}
+
+// TestTaxonomy_Descendants verifies a genre comes with everything beneath
+// it, breadth first and sorted
+func TestTaxonomy_Descendants(t *testing.T) {
+	tax := electronic(t)
+	tests := []struct {
+		genre string
+		want  []string
+	}{
+		{"Electronic", []string{"Electronic", "House", "Techno", "Deep House"}},
+		{"House", []string{"House", "Deep House"}},
+		{"Deep House", []string{"Deep House"}},
+		{"Jazz", []string{"Jazz"}},
+	}
+	for _, tt := range tests {
+		if got := tax.Descendants(tt.genre); !slices.Equal(got, tt.want) {
+			t.Errorf("Descendants(%q) = %v, want %v", tt.genre, got, tt.want)
+		}
+	}
+}
+
+// TestTaxonomy_Tree verifies the tree lists roots sorted, each followed by
+// its children one level deeper, with extra genres as roots
+func TestTaxonomy_Tree(t *testing.T) {
+	tax := electronic(t)
+	got := tax.Tree([]string{"jazz", "House", ""})
+	want := []GenreNode{
+		{"Electronic", 0},
+		{"House", 1},
+		{"Deep House", 2},
+		{"Techno", 1},
+		{"jazz", 0},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Tree = %v, want %v", got, want)
+	}
+}
+
+// TestTaxonomy_SaveLoad verifies the hierarchy and rules survive a save
+func TestTaxonomy_SaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "genres.json")
+	tax, err := LoadTaxonomy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tax.SetParent("House", "Electronic")
+	tax.Rules = []GenreRule{{Match: "hip*hop", Genre: "Hip-Hop"}}
+	if err := tax.Save(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadTaxonomy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Parents["House"] != "Electronic" || loaded.Canonical("hip hop") != "Hip-Hop" {
+		t.Errorf("loaded %+v", loaded)
+	}
+}
@@ -0,0 +1,131 @@
+package library
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/jscyril/golang_music_player/api"
+	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
+)
+
+// NormalizeUserTag returns tag as the library stores it: trimmed and
+// lower-cased. Commas are not allowed, they separate tags in input.
+func NormalizeUserTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" {
+		return "", fmt.Errorf("empty tag")
+	}
+	if strings.Contains(tag, ",") {
+		return "", fmt.Errorf("tag %q contains a comma", tag)
+	}
+	return tag, nil
+}
+
+// AddUserTag tags tracks with a label of the user's, e.g. "workout"
+func (l *Library) AddUserTag(ids []string, tag string) error {
+	tag, err := NormalizeUserTag(tag)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range ids {
+		if _, ok := l.Tracks[id]; !ok {
+			return playerrors.ErrTrackNotFound
+		}
+		if slices.Contains(l.UserTags[id], tag) {
+			continue
+		}
+		if l.UserTags == nil {
+			l.UserTags = make(map[string][]string)
+		}
+		l.UserTags[id] = append(l.UserTags[id], tag)
+		sort.Strings(l.UserTags[id])
+		l.userTagIndex[tag] = append(l.userTagIndex[tag], id)
+		l.publish(api.EventLibraryChanged, id)
+	}
+	return nil
+}
+
+// RemoveUserTag removes a label from tracks; tracks without it are skipped
+func (l *Library) RemoveUserTag(ids []string, tag string) error {
+	tag, err := NormalizeUserTag(tag)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, id := range ids {
+		if !slices.Contains(l.UserTags[id], tag) {
+			continue
+		}
+		l.dropUserTag(id, tag)
+		l.publish(api.EventLibraryChanged, id)
+	}
+	return nil
+}
+
+// dropUserTag removes tag from a track and the index. Callers hold l.mu.
+func (l *Library) dropUserTag(id, tag string) {
+	l.UserTags[id] = slices.DeleteFunc(l.UserTags[id], func(t string) bool { return t == tag })
+	if len(l.UserTags[id]) == 0 {
+		delete(l.UserTags, id)
+	}
+	l.removeFromIndex(l.userTagIndex, tag, id)
+}
+
+// UserTagsOf returns a track's labels, sorted
+func (l *Library) UserTagsOf(id string) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return slices.Clone(l.UserTags[id])
+}
+
+// AllUserTags returns every label in use on a listed track, sorted
+func (l *Library) AllUserTags() []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	tags := make([]string, 0, len(l.userTagIndex))
+	for tag, ids := range l.userTagIndex {
+		if slices.ContainsFunc(ids, func(id string) bool { return !l.Archived[id] }) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// GetTracksByUserTag returns the tracks carrying a label
+func (l *Library) GetTracksByUserTag(tag string) []*api.Track {
+	tag, err := NormalizeUserTag(tag)
+	if err != nil {
+		return nil
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	var tracks []*api.Track
+	for _, id := range l.userTagIndex[tag] {
+		if track, ok := l.Tracks[id]; ok && !l.Archived[id] {
+			tracks = append(tracks, track)
+		}
+	}
+	sortTracks(tracks)
+	return tracks
+}
+
+// rebuildUserTagIndex indexes the stored labels. Callers hold l.mu.
+func (l *Library) rebuildUserTagIndex() {
+	l.userTagIndex = make(map[string][]string)
+	for id, tags := range l.UserTags {
+		for _, tag := range tags {
+			l.userTagIndex[tag] = append(l.userTagIndex[tag], id)
+		}
+	}
+}
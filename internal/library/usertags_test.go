@@ -0,0 +1,45 @@
+package library
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestGenresAndUserTags verifies multi-genre tags index every genre once
+// and user tags are normalized, indexed and dropped with their tracks
+func TestGenresAndUserTags(t *testing.T) {
+	if got := SplitGenres("Rock; Pop/rock, Jazz ;"); !slices.Equal(got, []string{"Rock", "Pop", "Jazz"}) {
+		t.Errorf("SplitGenres = %q", got)
+	}
+
+	lib := NewLibrary()
+	lib.AddTracks([]*api.Track{
+		{ID: "a", Title: "A", Genre: "Rock; Jazz", FilePath: "/m/a.mp3"},
+		{ID: "b", Title: "B", Genre: "Jazz", FilePath: "/m/b.mp3"},
+	})
+	if got := lib.GetTracksByGenre("Jazz"); len(got) != 2 {
+		t.Errorf("got %d jazz tracks, want 2", len(got))
+	}
+
+	if err := lib.AddUserTag([]string{"a", "b"}, " Workout "); err != nil {
+		t.Fatal(err)
+	}
+	lib.AddUserTag([]string{"a"}, "chill")
+	if err := lib.AddUserTag([]string{"a"}, "a,b"); err == nil {
+		t.Error("tag with a comma accepted")
+	}
+	if got := lib.UserTagsOf("a"); !slices.Equal(got, []string{"chill", "workout"}) {
+		t.Errorf("tags of a = %q", got)
+	}
+	if got := lib.GetTracksByUserTag("WORKOUT"); len(got) != 2 {
+		t.Errorf("got %d workout tracks, want 2", len(got))
+	}
+
+	lib.RemoveUserTag([]string{"b"}, "workout")
+	lib.RemoveTrack("a")
+	if got := lib.AllUserTags(); len(got) != 0 {
+		t.Errorf("tags left after removal = %q", got)
+	}
+}
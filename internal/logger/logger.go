@@ -1,11 +1,20 @@
+// Package logger is the player's log, built on log/slog. Records go to a
+// size-rotated file in the data directory, to an in-memory buffer of the
+// latest entries for the UI's log viewer and, while a console is set, the
+// warnings and errors to the terminal as well. The printf-style functions
+// cover most uses; Slog returns a *slog.Logger for structured records.
 package logger
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -30,217 +39,335 @@ func (l Level) String() string {
 	return "UNKNOWN"
 }
 
+// ParseLevel reads a level name such as "debug" or "warn"
+func ParseLevel(s string) (Level, error) {
+	for i, name := range levelNames {
+		if strings.EqualFold(s, name) || (name == "WARN" && strings.EqualFold(s, "warning")) {
+			return Level(i), nil
+		}
+	}
+	return INFO, fmt.Errorf("unknown log level %q", s)
+}
+
+// slogLevel maps l onto slog's levels, FATAL above slog.LevelError
+func (l Level) slogLevel() slog.Level {
+	return slog.Level(4 * (int(l) - 1))
+}
+
+// fromSlog maps a slog level back, rounding down
+func fromSlog(l slog.Level) Level {
+	switch {
+	case l < slog.LevelInfo:
+		return DEBUG
+	case l < slog.LevelWarn:
+		return INFO
+	case l < slog.LevelError:
+		return WARN
+	case l < FATAL.slogLevel():
+		return ERROR
+	}
+	return FATAL
+}
+
 const (
 	maxLogSize    = 5 * 1024 * 1024 // 5 MB
 	maxLogBackups = 1
+	recentSize    = 1000 // entries kept for Recent
 )
 
-// Logger is a file-based logger with rotation support.
-type Logger struct {
-	mu       sync.Mutex
-	file     *os.File
-	writer   io.Writer
-	path     string
-	level    Level
-	isClosed bool
-}
+// FileName is the log file Init writes in its directory
+const FileName = "player.log"
 
-var (
-	globalLogger *Logger
-	globalMu     sync.RWMutex
-)
+// Entry is a logged record as the log viewer shows it
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Source  string // file:line of the call
+	Message string // the message with any attributes appended
+}
 
-// Init initializes the global logger. Must be called before any Log calls.
-// logDir is the directory where log files will be written (e.g. ~/.config/gtmpc/logs/).
-// level sets the minimum log level to write.
+// state is the global logger. Before Init only the recent entries and the
+// console get records.
+var state = struct {
+	mu      sync.Mutex
+	level   Level
+	file    *rotatingFile
+	text    slog.Handler // writes to file; nil without one
+	console io.Writer
+	recent  []Entry // ring buffer, oldest at next once full
+	next    int
+}{level: INFO}
+
+// Init opens the log file in logDir and logs records of level and above
+// to it, starting with those logged before. The file is rotated to
+// FileName.1 beyond 5 MB.
 func Init(logDir string, level Level) error {
 	if err := os.MkdirAll(logDir, 0755); err != nil {
 		return fmt.Errorf("create log dir: %w", err)
 	}
-
-	logPath := filepath.Join(logDir, "gtmpc.log")
-
-	l, err := newLogger(logPath, level)
+	f, err := openRotating(filepath.Join(logDir, FileName))
 	if err != nil {
 		return err
 	}
 
-	globalMu.Lock()
-	globalLogger = l
-	globalMu.Unlock()
+	state.mu.Lock()
+	if state.file != nil {
+		state.file.Close()
+	}
+	state.level = level
+	state.file = f
+	state.text = slog.NewTextHandler(f, &slog.HandlerOptions{
+		AddSource:   true,
+		Level:       DEBUG.slogLevel(), // filtered by handler.Enabled
+		ReplaceAttr: replaceAttr,
+	})
+	// Entries from before Init, e.g. config warnings, go in the file too
+	for _, e := range state.recent {
+		if e.Level >= level {
+			state.text.Handle(context.Background(), slog.NewRecord(e.Time, e.Level.slogLevel(), e.Message, 0))
+		}
+	}
+	state.mu.Unlock()
 
-	l.Info("Logger initialized (log_dir=%s, level=%s)", logDir, level)
+	slog.SetDefault(Slog())
+	Info("Logger initialized (log_dir=%s, level=%s)", logDir, level)
 	return nil
 }
 
-// Close closes the global logger.
+// Close closes the log file
 func Close() {
-	globalMu.Lock()
-	defer globalMu.Unlock()
-	if globalLogger != nil {
-		globalLogger.close()
-		globalLogger = nil
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.file != nil {
+		state.file.Close()
+		state.file, state.text = nil, nil
 	}
 }
 
 // GetLogPath returns the path of the current log file, or empty string if not initialized.
 func GetLogPath() string {
-	globalMu.RLock()
-	defer globalMu.RUnlock()
-	if globalLogger != nil {
-		return globalLogger.path
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if state.file != nil {
+		return state.file.path
 	}
 	return ""
 }
 
-func newLogger(path string, level Level) (*Logger, error) {
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return nil, fmt.Errorf("open log file: %w", err)
-	}
+// SetConsole also writes warnings and errors to w, as "Warning: …" and
+// "Error: …" lines, until it is called with nil. The player sets it to
+// stderr until the UI takes over the terminal, where stray writes would
+// garble the screen.
+func SetConsole(w io.Writer) {
+	state.mu.Lock()
+	state.console = w
+	state.mu.Unlock()
+}
 
-	return &Logger{
-		file:   file,
-		writer: file,
-		path:   path,
-		level:  level,
-	}, nil
+// Recent returns the latest entries, oldest first
+func Recent() []Entry {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	out := make([]Entry, 0, len(state.recent))
+	out = append(out, state.recent[state.next:]...)
+	return append(out, state.recent[:state.next]...)
 }
 
-func (l *Logger) close() {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-	if l.file != nil && !l.isClosed {
-		l.file.Close()
-		l.isClosed = true
-	}
+// Slog returns a structured logger writing to the log
+func Slog() *slog.Logger {
+	return slog.New(handler{})
 }
 
-func (l *Logger) log(level Level, format string, args ...interface{}) {
-	if level < l.level {
-		return
+// handler passes records on to the file, the recent entries and the
+// console
+type handler struct {
+	prefix string // group names, dot-separated, before attribute keys
+	attrs  string // attributes added with WithAttrs, formatted
+	// ops repeat the WithAttrs and WithGroup calls on the file's handler
+	ops []func(slog.Handler) slog.Handler
+}
+
+func (h handler) Enabled(_ context.Context, l slog.Level) bool {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	return fromSlog(l) >= state.level
+}
+
+func (h handler) Handle(ctx context.Context, r slog.Record) error {
+	e := Entry{Time: r.Time, Level: fromSlog(r.Level), Message: r.Message + h.attrs}
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		e.Source = filepath.Base(frame.File) + ":" + strconv.Itoa(frame.Line)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		e.Message += h.format(a)
+		return true
+	})
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+	if len(state.recent) < recentSize {
+		state.recent = append(state.recent, e)
+	} else {
+		state.recent[state.next] = e
+		state.next = (state.next + 1) % recentSize
+	}
+	if state.console != nil && e.Level >= WARN {
+		prefix := "Warning"
+		if e.Level >= ERROR {
+			prefix = "Error"
+		}
+		fmt.Fprintf(state.console, "%s: %s\n", prefix, e.Message)
 	}
+	if state.text == nil {
+		return nil
+	}
+	text := state.text
+	for _, op := range h.ops {
+		text = op(text)
+	}
+	return text.Handle(ctx, r)
+}
 
-	l.mu.Lock()
-	defer l.mu.Unlock()
+// format formats an attribute as the log viewer shows it
+func (h handler) format(a slog.Attr) string {
+	return fmt.Sprintf(" %s%s=%v", h.prefix, a.Key, a.Value)
+}
 
-	if l.isClosed {
-		return
+func (h handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	for _, a := range attrs {
+		h.attrs += h.format(a)
 	}
+	h.ops = append(h.ops[:len(h.ops):len(h.ops)], func(t slog.Handler) slog.Handler { return t.WithAttrs(attrs) })
+	return h
+}
+
+func (h handler) WithGroup(name string) slog.Handler {
+	h.prefix += name + "."
+	h.ops = append(h.ops[:len(h.ops):len(h.ops)], func(t slog.Handler) slog.Handler { return t.WithGroup(name) })
+	return h
+}
 
-	// Rotate if needed
-	if info, err := l.file.Stat(); err == nil && info.Size() > maxLogSize {
-		l.rotate()
+// replaceAttr names the FATAL level and shortens sources to file:line
+func replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	switch a.Key {
+	case slog.LevelKey:
+		if l, ok := a.Value.Any().(slog.Level); ok {
+			a.Value = slog.StringValue(fromSlog(l).String())
+		}
+	case slog.SourceKey:
+		if src, ok := a.Value.Any().(*slog.Source); ok {
+			if src.File == "" {
+				return slog.Attr{} // replayed from before Init
+			}
+			a.Value = slog.StringValue(filepath.Base(src.File) + ":" + strconv.Itoa(src.Line))
+		}
 	}
+	return a
+}
 
-	ts := time.Now().Format("2006-01-02 15:04:05.000")
-	msg := fmt.Sprintf(format, args...)
+// rotatingFile is the log file, moved aside once it grows past
+// maxLogSize
+type rotatingFile struct {
+	path string
+	file *os.File
+}
 
-	// Get caller info (skip 3 frames: log -> Debug/Info/etc -> caller)
-	_, file, line, ok := runtime.Caller(2)
-	caller := "???"
-	if ok {
-		caller = fmt.Sprintf("%s:%d", filepath.Base(file), line)
+func openRotating(path string) (*rotatingFile, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open log file: %w", err)
 	}
+	return &rotatingFile{path: path, file: file}, nil
+}
 
-	entry := fmt.Sprintf("[%s] %-5s %s | %s\n", ts, level, caller, msg)
-	l.writer.Write([]byte(entry))
+// Write writes one record; callers hold state.mu
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	if info, err := f.file.Stat(); err == nil && info.Size() > maxLogSize {
+		f.rotate()
+	}
+	return f.file.Write(p)
+}
+
+func (f *rotatingFile) Close() error {
+	return f.file.Close()
 }
 
-func (l *Logger) rotate() {
-	l.file.Close()
+func (f *rotatingFile) rotate() {
+	f.file.Close()
 
 	// Remove oldest backup
 	for i := maxLogBackups; i > 0; i-- {
-		old := fmt.Sprintf("%s.%d", l.path, i)
+		old := fmt.Sprintf("%s.%d", f.path, i)
 		if i == maxLogBackups {
 			os.Remove(old)
 		}
 		if i > 1 {
-			prev := fmt.Sprintf("%s.%d", l.path, i-1)
+			prev := fmt.Sprintf("%s.%d", f.path, i-1)
 			os.Rename(prev, old)
 		}
 	}
 
 	// Move current to .1
-	os.Rename(l.path, l.path+".1")
+	os.Rename(f.path, f.path+".1")
 
 	// Open new file
-	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
-		// Can't do much here — write to stderr as a last resort
-		fmt.Fprintf(os.Stderr, "logger: failed to rotate: %v\n", err)
+		// Keep writing to the moved file rather than nowhere
+		file, _ = os.OpenFile(f.path+".1", os.O_WRONLY|os.O_APPEND, 0644)
+		if file == nil {
+			file, _ = os.Open(os.DevNull)
+		}
+	}
+	f.file = file
+}
+
+// log records a printf-style message as logged by the caller of the
+// function calling it
+func log(level Level, format string, args ...interface{}) {
+	h := handler{}
+	if !h.Enabled(context.Background(), level.slogLevel()) {
 		return
 	}
-	l.file = file
-	l.writer = file
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // skip Callers, log and Debug/Info/etc
+	r := slog.NewRecord(time.Now(), level.slogLevel(), fmt.Sprintf(format, args...), pcs[0])
+	h.Handle(context.Background(), r)
 }
 
 // --- Global convenience functions ---
 
 // Debug logs at DEBUG level.
 func Debug(format string, args ...interface{}) {
-	globalMu.RLock()
-	l := globalLogger
-	globalMu.RUnlock()
-	if l != nil {
-		l.log(DEBUG, format, args...)
-	}
+	log(DEBUG, format, args...)
 }
 
 // Info logs at INFO level.
 func Info(format string, args ...interface{}) {
-	globalMu.RLock()
-	l := globalLogger
-	globalMu.RUnlock()
-	if l != nil {
-		l.log(INFO, format, args...)
-	}
+	log(INFO, format, args...)
 }
 
 // Warn logs at WARN level.
 func Warn(format string, args ...interface{}) {
-	globalMu.RLock()
-	l := globalLogger
-	globalMu.RUnlock()
-	if l != nil {
-		l.log(WARN, format, args...)
-	}
+	log(WARN, format, args...)
 }
 
 // Error logs at ERROR level.
 func Error(format string, args ...interface{}) {
-	globalMu.RLock()
-	l := globalLogger
-	globalMu.RUnlock()
-	if l != nil {
-		l.log(ERROR, format, args...)
-	}
+	log(ERROR, format, args...)
 }
 
 // Fatal logs at FATAL level. Does NOT call os.Exit — the caller decides what to do.
 func Fatal(format string, args ...interface{}) {
-	globalMu.RLock()
-	l := globalLogger
-	globalMu.RUnlock()
-	if l != nil {
-		l.log(FATAL, format, args...)
-	}
-}
-
-// Info logs at INFO level (method on Logger).
-func (l *Logger) Info(format string, args ...interface{}) {
-	l.log(INFO, format, args...)
+	log(FATAL, format, args...)
 }
 
 // WritePanic writes a recovered panic value and stack trace to the log file.
 // This is intended to be called from a deferred recovery handler.
 func WritePanic(r interface{}) {
-	globalMu.RLock()
-	l := globalLogger
-	globalMu.RUnlock()
-
-	if l == nil {
+	path := GetLogPath()
+	if path == "" {
 		// Logger not initialized — dump to stderr
 		fmt.Fprintf(os.Stderr, "PANIC: %v\n", r)
 		return
@@ -251,8 +378,8 @@ func WritePanic(r interface{}) {
 	n := runtime.Stack(buf, false)
 	stack := string(buf[:n])
 
-	l.log(FATAL, "PANIC: %v\n%s", r, stack)
+	log(FATAL, "PANIC: %v\n%s", r, stack)
 
 	// Also try to write to stderr in case terminal is still readable
-	fmt.Fprintf(os.Stderr, "PANIC (see log at %s): %v\n", l.path, r)
+	fmt.Fprintf(os.Stderr, "PANIC (see log at %s): %v\n", path, r)
 }
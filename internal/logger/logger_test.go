@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLogger verifies records reach the console, the recent entries and
+// the file, including those logged before Init, and that the level
+// filters them
+func TestLogger(t *testing.T) {
+	var console bytes.Buffer
+	SetConsole(&console)
+	defer SetConsole(nil)
+
+	Warn("early %d", 1)
+	dir := t.TempDir()
+	if err := Init(dir, INFO); err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+	Debug("hidden")
+	Info("info")
+	Slog().With("track", "a.mp3").Error("decode failed", "pos", 3)
+
+	if got := console.String(); got != "Warning: early 1\nError: decode failed track=a.mp3 pos=3\n" {
+		t.Errorf("console = %q", got)
+	}
+	var msgs []string
+	for _, e := range Recent() {
+		msgs = append(msgs, e.Level.String()+" "+e.Message)
+	}
+	if got := strings.Join(msgs, ", "); !strings.HasPrefix(got, "WARN early 1, INFO Logger initialized") ||
+		!strings.HasSuffix(got, "INFO info, ERROR decode failed track=a.mp3 pos=3") {
+		t.Errorf("recent = %s", got)
+	}
+	if e := Recent()[len(Recent())-2]; e.Source != "logger_test.go:26" {
+		t.Errorf("source = %q", e.Source)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, FileName))
+	for _, want := range []string{`level=WARN msg="early 1"`, `level=ERROR source=logger_test.go:27 msg="decode failed" track=a.mp3 pos=3`} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("log file lacks %s:\n%s", want, data)
+		}
+	}
+	if bytes.Contains(data, []byte("hidden")) {
+		t.Errorf("debug record written at INFO level:\n%s", data)
+	}
+}
@@ -0,0 +1,188 @@
+package migrate
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readITunes reads the property list iTunes and the Music app export as
+// "iTunes Library.xml": a Tracks dictionary keyed by track ID and a
+// Playlists array whose items refer to those IDs
+func readITunes(path string) (Data, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Data{}, err
+	}
+	defer f.Close()
+
+	root, err := decodePlist(xml.NewDecoder(f))
+	if err != nil {
+		return Data{}, err
+	}
+	lib, ok := root.(map[string]any)
+	if !ok {
+		return Data{}, fmt.Errorf("not an iTunes library")
+	}
+
+	var data Data
+	paths := make(map[int64]string) // track ID -> path
+	tracks, _ := lib["Tracks"].(map[string]any)
+	for _, v := range tracks {
+		t, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		loc, _ := t["Location"].(string)
+		if loc == "" {
+			continue // streams and items in the cloud only
+		}
+		e := Entry{Path: pathFromURL(loc)}
+		id, _ := t["Track ID"].(int64)
+		paths[id] = e.Path
+		if n, ok := t["Play Count"].(int64); ok {
+			e.Plays = int(n)
+		}
+		e.LastPlayed, _ = t["Play Date UTC"].(time.Time)
+		// A computed rating is the album's, not the track's own
+		if r, ok := t["Rating"].(int64); ok && t["Rating Computed"] != true {
+			e.Rating = int(r+10) / 20 // 0 to 100 in steps of 20
+		}
+		data.Entries = append(data.Entries, e)
+	}
+
+	lists, _ := lib["Playlists"].([]any)
+	for _, v := range lists {
+		p, ok := v.(map[string]any)
+		if !ok {
+			continue
+		}
+		// The whole library, built-in lists like "Music" and folders
+		if p["Master"] == true || p["Distinguished Kind"] != nil || p["Folder"] == true {
+			continue
+		}
+		name, _ := p["Name"].(string)
+		pl := Playlist{Name: name}
+		items, _ := p["Playlist Items"].([]any)
+		for _, it := range items {
+			item, _ := it.(map[string]any)
+			id, _ := item["Track ID"].(int64)
+			if path, ok := paths[id]; ok {
+				pl.Paths = append(pl.Paths, path)
+			}
+		}
+		if name != "" && len(pl.Paths) > 0 {
+			data.Playlists = append(data.Playlists, pl)
+		}
+	}
+	return data, nil
+}
+
+// decodePlist decodes an XML property list into maps, slices, strings,
+// int64s, float64s, bools, times and byte strings
+func decodePlist(d *xml.Decoder) (any, error) {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if start.Name.Local == "plist" {
+				continue
+			}
+			return plistValue(d, start)
+		}
+	}
+}
+
+// plistValue decodes the element start opens
+func plistValue(d *xml.Decoder, start xml.StartElement) (any, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := make(map[string]any)
+		var key string
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch tok := tok.(type) {
+			case xml.EndElement:
+				return dict, nil
+			case xml.StartElement:
+				if tok.Name.Local == "key" {
+					if key, err = plistText(d); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				v, err := plistValue(d, tok)
+				if err != nil {
+					return nil, err
+				}
+				dict[key] = v
+			}
+		}
+	case "array":
+		var list []any
+		for {
+			tok, err := d.Token()
+			if err != nil {
+				return nil, err
+			}
+			switch tok := tok.(type) {
+			case xml.EndElement:
+				return list, nil
+			case xml.StartElement:
+				v, err := plistValue(d, tok)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+		}
+	case "true", "false":
+		if err := d.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+
+	text, err := plistText(d)
+	if err != nil {
+		return nil, err
+	}
+	switch start.Name.Local {
+	case "integer":
+		return strconv.ParseInt(strings.TrimSpace(text), 10, 64)
+	case "real":
+		return strconv.ParseFloat(strings.TrimSpace(text), 64)
+	case "date":
+		return time.Parse(time.RFC3339, strings.TrimSpace(text))
+	}
+	return text, nil // string and data
+}
+
+// plistText reads the text of the element just opened, up to its end
+func plistText(d *xml.Decoder) (string, error) {
+	var sb strings.Builder
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return "", io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return "", err
+		}
+		switch tok := tok.(type) {
+		case xml.CharData:
+			sb.Write(tok)
+		case xml.EndElement:
+			return sb.String(), nil
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Package migrate brings listening data over from other players: play
+// counts, last-played times, ratings and playlists from iTunes, Rhythmbox,
+// Clementine and MPD's sticker database. Files are matched by path, so the
+// music must be where the other player found it, or under one of the
+// music directories for players that store relative paths.
+package migrate
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/playlist"
+	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
+)
+
+// Sources lists the players data can be imported from
+var Sources = []string{"itunes", "rhythmbox", "clementine", "mpd"}
+
+// Entry is what another player knew about one file
+type Entry struct {
+	Path       string // absolute, or relative to a music directory
+	Plays      int
+	LastPlayed time.Time // zero if unknown
+	Rating     int       // stars out of 5, 0 if unrated
+}
+
+// Playlist is a playlist of another player, as file paths
+type Playlist struct {
+	Name  string
+	Paths []string
+}
+
+// Data is everything read from another player
+type Data struct {
+	Source    string
+	Entries   []Entry
+	Playlists []Playlist
+}
+
+// Report counts what an import brought over
+type Report struct {
+	Tracks    int // entries matched to library tracks
+	Missing   int // entries whose file was not found
+	Plays     int // tracks whose play counts were recorded
+	Ratings   int
+	Playlists int
+}
+
+// Read reads the data of the player named source from path: iTunes'
+// "iTunes Library.xml", Rhythmbox's rhythmdb.xml (with the playlists.xml
+// next to it), Clementine's clementine.db or MPD's sticker database
+func Read(source, path string) (Data, error) {
+	var data Data
+	var err error
+	switch strings.ToLower(source) {
+	case "itunes":
+		data, err = readITunes(path)
+	case "rhythmbox":
+		data, err = readRhythmbox(path)
+	case "clementine":
+		data, err = readClementine(path)
+	case "mpd":
+		data, err = readMPDStickers(path)
+	default:
+		return Data{}, fmt.Errorf("unknown player %q, expected one of %s", source, strings.Join(Sources, ", "))
+	}
+	if err != nil {
+		return Data{}, fmt.Errorf("read %s data: %w", source, err)
+	}
+	data.Source = source
+	return data, nil
+}
+
+// Apply records the plays and ratings of data in lib and creates its
+// playlists in playlists, which may be nil to skip them. Files outside
+// the library are added to it. Relative paths are looked for under roots.
+// Tracks that already have imported plays keep them, but playlists are
+// created again on every run.
+func Apply(data Data, lib *library.Library, playlists *playlist.Manager, roots []string) (Report, error) {
+	var report Report
+	for _, e := range data.Entries {
+		track, err := lib.ResolvePath(locate(e.Path, roots))
+		if err != nil {
+			report.Missing++
+			continue
+		}
+		report.Tracks++
+		recorded, err := lib.ImportPlays(track, e.Plays, e.LastPlayed)
+		if err != nil {
+			return report, err
+		}
+		if recorded {
+			report.Plays++
+		}
+		if e.Rating > 0 && lib.Rating(track.ID) == 0 {
+			if err := lib.SetRating(track.ID, min(e.Rating, 5)); err == nil {
+				report.Ratings++
+			}
+		}
+	}
+
+	if playlists == nil {
+		return report, nil
+	}
+	for _, p := range data.Playlists {
+		pl, err := playlists.Create(p.Name, "Imported from "+data.Source)
+		if err != nil {
+			return report, err
+		}
+		for _, path := range p.Paths {
+			track, err := lib.ResolvePath(locate(path, roots))
+			if err != nil {
+				continue
+			}
+			err = playlists.AddTrack(pl.ID, track)
+			if err != nil && !errors.Is(err, playerrors.ErrDuplicateTrack) {
+				return report, err
+			}
+		}
+		report.Playlists++
+	}
+	return report, nil
+}
+
+// locate returns path if it is absolute, or the first file it names under
+// roots
+func locate(path string, roots []string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	for _, root := range roots {
+		p := filepath.Join(root, filepath.FromSlash(path))
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+	return path
+}
+
+// pathFromURL turns a file:// URL such as iTunes' and Rhythmbox's
+// locations into a path; anything else is returned as it is
+func pathFromURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "file" {
+		return s
+	}
+	p := u.Path
+	// file:///C:/Music/x.mp3
+	if runtime.GOOS == "windows" && len(p) > 2 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p)
+}
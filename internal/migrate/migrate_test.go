@@ -0,0 +1,185 @@
+package migrate
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const itunesLibrary = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Major Version</key><integer>1</integer>
+	<key>Tracks</key>
+	<dict>
+		<key>101</key>
+		<dict>
+			<key>Track ID</key><integer>101</integer>
+			<key>Name</key><string>Airbag</string>
+			<key>Play Count</key><integer>12</integer>
+			<key>Play Date UTC</key><date>2024-03-01T20:15:00Z</date>
+			<key>Rating</key><integer>80</integer>
+			<key>Location</key><string>file:///Users/me/Music/Radiohead/01%20Airbag.mp3</string>
+		</dict>
+		<key>102</key>
+		<dict>
+			<key>Track ID</key><integer>102</integer>
+			<key>Rating</key><integer>60</integer>
+			<key>Rating Computed</key><true/>
+			<key>Location</key><string>file:///Users/me/Music/Radiohead/02%20Paranoid%20Android.mp3</string>
+		</dict>
+		<key>103</key>
+		<dict>
+			<key>Track ID</key><integer>103</integer>
+			<key>Name</key><string>Internet radio</string>
+		</dict>
+	</dict>
+	<key>Playlists</key>
+	<array>
+		<dict>
+			<key>Name</key><string>Library</string>
+			<key>Master</key><true/>
+			<key>Playlist Items</key>
+			<array><dict><key>Track ID</key><integer>101</integer></dict></array>
+		</dict>
+		<dict>
+			<key>Name</key><string>Music</string>
+			<key>Distinguished Kind</key><integer>4</integer>
+		</dict>
+		<dict>
+			<key>Name</key><string>Favourites</string>
+			<key>Playlist Items</key>
+			<array>
+				<dict><key>Track ID</key><integer>102</integer></dict>
+				<dict><key>Track ID</key><integer>101</integer></dict>
+			</array>
+		</dict>
+	</array>
+</dict>
+</plist>`
+
+// TestReadITunes verifies tracks, ratings and playlists are read from a library XML
+func TestReadITunes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "iTunes Library.xml")
+	if err := os.WriteFile(path, []byte(itunesLibrary), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Read("itunes", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(data.Entries), data.Entries)
+	}
+	byPath := make(map[string]Entry)
+	for _, e := range data.Entries {
+		byPath[e.Path] = e
+	}
+	airbag := byPath[filepath.FromSlash("/Users/me/Music/Radiohead/01 Airbag.mp3")]
+	if airbag.Plays != 12 || airbag.Rating != 4 {
+		t.Errorf("Airbag = %+v, want 12 plays and 4 stars", airbag)
+	}
+	if want := time.Date(2024, 3, 1, 20, 15, 0, 0, time.UTC); !airbag.LastPlayed.Equal(want) {
+		t.Errorf("last played = %v, want %v", airbag.LastPlayed, want)
+	}
+	if e := byPath[filepath.FromSlash("/Users/me/Music/Radiohead/02 Paranoid Android.mp3")]; e.Rating != 0 {
+		t.Errorf("computed rating imported: %+v", e)
+	}
+	if len(data.Playlists) != 1 || data.Playlists[0].Name != "Favourites" || len(data.Playlists[0].Paths) != 2 {
+		t.Fatalf("playlists = %+v", data.Playlists)
+	}
+	if data.Playlists[0].Paths[1] != airbag.Path {
+		t.Errorf("playlist order = %v", data.Playlists[0].Paths)
+	}
+}
+
+// TestReadRhythmbox verifies songs are read from rhythmdb.xml and static playlists from playlists.xml
+func TestReadRhythmbox(t *testing.T) {
+	dir := t.TempDir()
+	db := `<?xml version="1.0" standalone="yes"?>
+<rhythmdb version="2.0">
+  <entry type="song">
+    <title>Airbag</title>
+    <location>file:///home/me/Music/Airbag.ogg</location>
+    <play-count>3</play-count>
+    <last-played>1700000000</last-played>
+    <rating>5</rating>
+  </entry>
+  <entry type="iradio">
+    <location>http://radio.example/stream</location>
+  </entry>
+  <entry type="song">
+    <location>file:///home/me/Music/Lucky.ogg</location>
+  </entry>
+</rhythmdb>`
+	lists := `<?xml version="1.0"?>
+<rhythmdb-playlists>
+  <playlist name="My Top Rated" type="automatic"><conjunction/></playlist>
+  <playlist name="Evening" type="static">
+    <location>file:///home/me/Music/Lucky.ogg</location>
+    <location>file:///home/me/Music/Airbag.ogg</location>
+  </playlist>
+</rhythmdb-playlists>`
+	if err := os.WriteFile(filepath.Join(dir, "rhythmdb.xml"), []byte(db), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "playlists.xml"), []byte(lists), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Read("rhythmbox", filepath.Join(dir, "rhythmdb.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(data.Entries))
+	}
+	e := data.Entries[0]
+	if e.Path != filepath.FromSlash("/home/me/Music/Airbag.ogg") || e.Plays != 3 || e.Rating != 5 || e.LastPlayed.Unix() != 1700000000 {
+		t.Errorf("entry = %+v", e)
+	}
+	if !data.Entries[1].LastPlayed.IsZero() {
+		t.Errorf("never played entry has last played %v", data.Entries[1].LastPlayed)
+	}
+	if len(data.Playlists) != 1 || data.Playlists[0].Name != "Evening" || len(data.Playlists[0].Paths) != 2 {
+		t.Errorf("playlists = %+v", data.Playlists)
+	}
+}
+
+// TestReadMPDStickers verifies sticker rows are grouped per song
+func TestReadMPDStickers(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+	path := filepath.Join(t.TempDir(), "sticker.sql")
+	setup := `CREATE TABLE sticker (type VARCHAR, uri VARCHAR, name VARCHAR, value VARCHAR);
+INSERT INTO sticker VALUES ('song', 'Radiohead/Airbag.flac', 'rating', '7');
+INSERT INTO sticker VALUES ('song', 'Radiohead/Airbag.flac', 'playCount', '9');
+INSERT INTO sticker VALUES ('song', 'Radiohead/Lucky.flac', 'lastPlayed', '1700000000');
+INSERT INTO sticker VALUES ('song', 'Radiohead/Lucky.flac', 'other', 'x');`
+	if out, err := exec.Command("sqlite3", path, setup).CombinedOutput(); err != nil {
+		t.Fatalf("create database: %v: %s", err, out)
+	}
+	data, err := Read("mpd", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2: %+v", len(data.Entries), data.Entries)
+	}
+	if e := data.Entries[0]; e.Path != "Radiohead/Airbag.flac" || e.Rating != 4 || e.Plays != 9 {
+		t.Errorf("Airbag = %+v", e)
+	}
+	if e := data.Entries[1]; e.LastPlayed.Unix() != 1700000000 {
+		t.Errorf("Lucky = %+v", e)
+	}
+}
+
+// TestRead_UnknownPlayer verifies an unknown source is refused
+func TestRead_UnknownPlayer(t *testing.T) {
+	if _, err := Read("winamp", "x"); err == nil {
+		t.Error("expected an error for an unknown player")
+	}
+}
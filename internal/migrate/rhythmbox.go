@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"encoding/xml"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// readRhythmbox reads Rhythmbox's rhythmdb.xml at path and the
+// playlists.xml next to it, if there is one. Only static playlists are
+// taken; automatic ones are queries of Rhythmbox's own.
+func readRhythmbox(path string) (Data, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Data{}, err
+	}
+	defer f.Close()
+
+	var db struct {
+		Entries []struct {
+			Type       string `xml:"type,attr"`
+			Location   string `xml:"location"`
+			PlayCount  int    `xml:"play-count"`
+			LastPlayed int64  `xml:"last-played"` // Unix time
+			Rating     int    `xml:"rating"`      // stars
+		} `xml:"entry"`
+	}
+	if err := xml.NewDecoder(f).Decode(&db); err != nil {
+		return Data{}, err
+	}
+	var data Data
+	for _, e := range db.Entries {
+		if e.Type != "song" || e.Location == "" {
+			continue
+		}
+		entry := Entry{Path: pathFromURL(e.Location), Plays: e.PlayCount, Rating: e.Rating}
+		if e.LastPlayed > 0 {
+			entry.LastPlayed = time.Unix(e.LastPlayed, 0)
+		}
+		data.Entries = append(data.Entries, entry)
+	}
+
+	pf, err := os.Open(filepath.Join(filepath.Dir(path), "playlists.xml"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return data, nil
+	}
+	if err != nil {
+		return Data{}, err
+	}
+	defer pf.Close()
+	var lists struct {
+		Playlists []struct {
+			Name      string   `xml:"name,attr"`
+			Type      string   `xml:"type,attr"`
+			Locations []string `xml:"location"`
+		} `xml:"playlist"`
+	}
+	if err := xml.NewDecoder(pf).Decode(&lists); err != nil {
+		return Data{}, err
+	}
+	for _, p := range lists.Playlists {
+		if p.Type != "static" || len(p.Locations) == 0 {
+			continue
+		}
+		pl := Playlist{Name: p.Name}
+		for _, loc := range p.Locations {
+			pl.Paths = append(pl.Paths, pathFromURL(loc))
+		}
+		data.Playlists = append(data.Playlists, pl)
+	}
+	return data, nil
+}
@@ -0,0 +1,126 @@
+package migrate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// query runs a read-only query on the SQLite database at path with the
+// sqlite3 command-line tool and decodes its rows into v
+func query(path, sql string, v any) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	bin, err := exec.LookPath("sqlite3")
+	if err != nil {
+		return fmt.Errorf("reading SQLite databases needs the sqlite3 command: %w", err)
+	}
+	out, err := exec.Command(bin, "-readonly", "-json", path, sql).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return fmt.Errorf("sqlite3: %s", exitErr.Stderr)
+		}
+		return fmt.Errorf("sqlite3: %w", err)
+	}
+	if len(out) == 0 {
+		return nil // no rows
+	}
+	return json.Unmarshal(out, v)
+}
+
+// readClementine reads Clementine's (and Strawberry's) clementine.db. Its
+// filenames are file:// URLs, lastplayed is Unix time and -1 if never,
+// and ratings run from 0 to 1 with -1 for unrated.
+func readClementine(path string) (Data, error) {
+	var songs []struct {
+		Filename   string  `json:"filename"`
+		PlayCount  int     `json:"playcount"`
+		LastPlayed int64   `json:"lastplayed"`
+		Rating     float64 `json:"rating"`
+	}
+	err := query(path, `SELECT CAST(filename AS TEXT) AS filename, playcount, lastplayed, rating
+		FROM songs WHERE unavailable = 0`, &songs)
+	if err != nil {
+		return Data{}, err
+	}
+	var data Data
+	for _, s := range songs {
+		e := Entry{Path: pathFromURL(s.Filename), Plays: s.PlayCount}
+		if s.LastPlayed > 0 {
+			e.LastPlayed = time.Unix(s.LastPlayed, 0)
+		}
+		if s.Rating > 0 {
+			e.Rating = int(s.Rating*5 + 0.5)
+		}
+		data.Entries = append(data.Entries, e)
+	}
+
+	// Playlist items either point at a library song or carry their own file
+	var items []struct {
+		Playlist string `json:"name"`
+		Filename string `json:"filename"`
+	}
+	err = query(path, `SELECT p.name, CAST(COALESCE(s.filename, i.filename) AS TEXT) AS filename
+		FROM playlist_items i
+		JOIN playlists p ON p.ROWID = i.playlist
+		LEFT JOIN songs s ON i.type = 'Library' AND s.ROWID = i.library_id
+		WHERE p.special_type IS NULL OR p.special_type = ''
+		ORDER BY p.ROWID, i.ROWID`, &items)
+	if err != nil {
+		return Data{}, err
+	}
+	for _, it := range items {
+		if it.Filename == "" {
+			continue
+		}
+		if n := len(data.Playlists); n == 0 || data.Playlists[n-1].Name != it.Playlist {
+			data.Playlists = append(data.Playlists, Playlist{Name: it.Playlist})
+		}
+		pl := &data.Playlists[len(data.Playlists)-1]
+		pl.Paths = append(pl.Paths, pathFromURL(it.Filename))
+	}
+	return data, nil
+}
+
+// readMPDStickers reads MPD's sticker database. Song URIs are relative to
+// MPD's music directory; ratings are out of 10, as clients such as ncmpcpp
+// and myMPD store them, and playCount and lastPlayed are myMPD's.
+func readMPDStickers(path string) (Data, error) {
+	var stickers []struct {
+		URI   string `json:"uri"`
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	err := query(path, `SELECT uri, name, value FROM sticker
+		WHERE type = 'song' AND name IN ('rating', 'playCount', 'lastPlayed')
+		ORDER BY uri`, &stickers)
+	if err != nil {
+		return Data{}, err
+	}
+	var data Data
+	for _, s := range stickers {
+		if n := len(data.Entries); n == 0 || data.Entries[n-1].Path != s.URI {
+			data.Entries = append(data.Entries, Entry{Path: s.URI})
+		}
+		e := &data.Entries[len(data.Entries)-1]
+		n, err := strconv.ParseInt(s.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+		switch s.Name {
+		case "rating":
+			e.Rating = int(n+1) / 2
+		case "playCount":
+			e.Plays = int(n)
+		case "lastPlayed":
+			e.LastPlayed = time.Unix(n, 0)
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,27 @@
+// Package notify shows desktop notifications through the platform's
+// notification tool: notify-send on Linux and osascript on macOS.
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+)
+
+// AppName is the application the notifications are shown for
+const AppName = "gtmpc"
+
+// ErrUnsupported is returned where no notification tool is known
+var ErrUnsupported = errors.New("desktop notifications are not supported on this platform")
+
+// Send shows a notification with a title and body
+func Send(title, body string) error {
+	name, args := command(title, body)
+	if name == "" {
+		return ErrUnsupported
+	}
+	if out, err := exec.Command(name, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", name, err, out)
+	}
+	return nil
+}
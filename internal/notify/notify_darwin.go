@@ -0,0 +1,11 @@
+//go:build darwin
+
+package notify
+
+import "strconv"
+
+// command builds an AppleScript "display notification" call
+func command(title, body string) (string, []string) {
+	script := "display notification " + strconv.Quote(body) + " with title " + strconv.Quote(title)
+	return "osascript", []string{"-e", script}
+}
@@ -0,0 +1,8 @@
+//go:build linux
+
+package notify
+
+// command builds a notify-send call (libnotify)
+func command(title, body string) (string, []string) {
+	return "notify-send", []string{"--app-name=" + AppName, title, body}
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package notify
+
+// command has no notification tool to call here
+func command(title, body string) (string, []string) {
+	return "", nil
+}
@@ -0,0 +1,314 @@
+package playlist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TrackResolver looks tracks up in the library: by the file path of an
+// imported playlist entry, and by the IDs playlists are saved with
+type TrackResolver interface {
+	ResolvePath(path string) (*api.Track, error)
+	GetTrack(id string) (*api.Track, error)
+}
+
+// entry is one item read from a playlist file
+type entry struct {
+	path     string
+	title    string
+	artist   string
+	album    string
+	duration time.Duration
+}
+
+// document is a parsed playlist file. Only XSPF carries playlist-level
+// metadata; for other formats those fields are empty.
+type document struct {
+	title      string
+	creator    string
+	annotation string
+	entries    []entry
+}
+
+// SetResolver sets the library imported entries are matched against and
+// saved track IDs are resolved in. Without a resolver, imported tracks carry
+// only the path and playlist title, and are saved as their paths.
+func (m *Manager) SetResolver(r TrackResolver) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resolver = r
+}
+
+// Import creates a playlist from an .m3u, .m3u8, .pls or .xspf file. Relative entries
+// are resolved against the playlist file's directory; entries that cannot be
+// resolved are skipped and counted in the returned skipped value.
+func (m *Manager) Import(path string) (*api.Playlist, int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, fmt.Errorf("open playlist: %w", err)
+	}
+	defer f.Close()
+
+	var doc document
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".m3u", ".m3u8":
+		doc.entries, err = parseM3U(f)
+	case ".pls":
+		doc.entries, err = parsePLS(f)
+	case ".xspf":
+		doc, err = parseXSPF(f)
+	default:
+		return nil, 0, fmt.Errorf("import playlist: unsupported format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	m.mu.RLock()
+	resolver := m.resolver
+	m.mu.RUnlock()
+
+	baseDir := filepath.Dir(path)
+	tracks := make([]api.Track, 0, len(doc.entries))
+	skipped := 0
+	for _, e := range doc.entries {
+		p := e.path
+		if strings.HasPrefix(p, "file://") {
+			p = fileURIToPath(p)
+		}
+		if !strings.Contains(p, "://") && !filepath.IsAbs(p) {
+			p = filepath.Join(baseDir, filepath.FromSlash(p))
+		}
+
+		if resolver != nil {
+			track, err := resolver.ResolvePath(p)
+			if err != nil {
+				skipped++
+				continue
+			}
+			tracks = append(tracks, *track)
+			continue
+		}
+
+		title := e.title
+		if title == "" {
+			title = strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+		}
+		tracks = append(tracks, api.Track{
+			ID:       p,
+			Title:    title,
+			Artist:   e.artist,
+			Album:    e.album,
+			FilePath: p,
+			Duration: e.duration,
+		})
+	}
+
+	name := doc.title
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	description := doc.annotation
+	if description == "" {
+		description = "Imported from " + filepath.Base(path)
+	}
+	playlist, err := m.Create(name, description)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	live := m.playlists[playlist.ID]
+	live.Creator = doc.creator
+	live.Tracks = tracks
+	if err := m.savePlaylist(live); err != nil {
+		return nil, 0, err
+	}
+	return snapshot(live), skipped, nil
+}
+
+// ImportM3U creates a playlist from an M3U/M3U8 file
+func (m *Manager) ImportM3U(path string) (*api.Playlist, int, error) {
+	return m.Import(path)
+}
+
+// Export writes a playlist as .m3u/.m3u8 (extended M3U), .pls or .xspf,
+// chosen by the extension of path
+func (m *Manager) Export(playlistID, path string) error {
+	playlist, err := m.GetByID(playlistID)
+	if err != nil {
+		return err
+	}
+
+	var write func(io.Writer, *api.Playlist) error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".m3u", ".m3u8":
+		write = writeM3U
+	case ".pls":
+		write = writePLS
+	case ".xspf":
+		write = writeXSPF
+	default:
+		return fmt.Errorf("export playlist: unsupported format %q", filepath.Ext(path))
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create playlist file: %w", err)
+	}
+	if err := write(f, playlist); err != nil {
+		f.Close()
+		return fmt.Errorf("write playlist file: %w", err)
+	}
+	return f.Close()
+}
+
+// ExportM3U writes a playlist as an extended M3U file
+func (m *Manager) ExportM3U(playlistID, path string) error {
+	return m.Export(playlistID, path)
+}
+
+// parseM3U reads plain and extended M3U. #EXTINF lines provide the
+// duration and "Artist - Title" of the following entry.
+func parseM3U(r io.Reader) ([]entry, error) {
+	var entries []entry
+	var pending entry
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(sc.Text(), "\ufeff"))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "#EXTINF:"):
+			info := strings.TrimPrefix(line, "#EXTINF:")
+			secs, title, _ := strings.Cut(info, ",")
+			if n, err := strconv.Atoi(strings.TrimSpace(secs)); err == nil && n > 0 {
+				pending.duration = time.Duration(n) * time.Second
+			}
+			pending.artist, pending.title = splitDisplayTitle(strings.TrimSpace(title))
+		case strings.HasPrefix(line, "#"):
+		default:
+			pending.path = line
+			entries = append(entries, pending)
+			pending = entry{}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read m3u: %w", err)
+	}
+	return entries, nil
+}
+
+// parsePLS reads the INI-style PLS format (FileN/TitleN/LengthN keys)
+func parsePLS(r io.Reader) ([]entry, error) {
+	byIndex := make(map[int]*entry)
+	maxIndex := 0
+
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(sc.Text()), "=")
+		if !ok {
+			continue
+		}
+		var field string
+		for _, f := range []string{"File", "Title", "Length"} {
+			if strings.HasPrefix(key, f) {
+				field = f
+				break
+			}
+		}
+		if field == "" {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimPrefix(key, field))
+		if err != nil || n < 1 {
+			continue
+		}
+		e := byIndex[n]
+		if e == nil {
+			e = &entry{}
+			byIndex[n] = e
+		}
+		if n > maxIndex {
+			maxIndex = n
+		}
+		switch field {
+		case "File":
+			e.path = value
+		case "Title":
+			e.artist, e.title = splitDisplayTitle(value)
+		case "Length":
+			if secs, err := strconv.Atoi(value); err == nil && secs > 0 {
+				e.duration = time.Duration(secs) * time.Second
+			}
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read pls: %w", err)
+	}
+
+	entries := make([]entry, 0, len(byIndex))
+	for i := 1; i <= maxIndex; i++ {
+		if e := byIndex[i]; e != nil && e.path != "" {
+			entries = append(entries, *e)
+		}
+	}
+	return entries, nil
+}
+
+// displayTitle returns "Artist - Title", or just the title
+func displayTitle(t api.Track) string {
+	if t.Artist == "" {
+		return t.Title
+	}
+	return t.Artist + " - " + t.Title
+}
+
+// splitDisplayTitle splits "Artist - Title" as displayTitle writes it;
+// text without the separator is all title
+func splitDisplayTitle(s string) (artist, title string) {
+	if a, t, ok := strings.Cut(s, " - "); ok && strings.TrimSpace(a) != "" && strings.TrimSpace(t) != "" {
+		return strings.TrimSpace(a), strings.TrimSpace(t)
+	}
+	return "", s
+}
+
+func writeM3U(w io.Writer, playlist *api.Playlist) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "#EXTM3U")
+	for _, t := range playlist.Tracks {
+		secs := -1
+		if t.Duration > 0 {
+			secs = int(t.Duration.Seconds())
+		}
+		fmt.Fprintf(bw, "#EXTINF:%d,%s\n", secs, displayTitle(t))
+		fmt.Fprintln(bw, t.FilePath)
+	}
+	return bw.Flush()
+}
+
+func writePLS(w io.Writer, playlist *api.Playlist) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "[playlist]")
+	for i, t := range playlist.Tracks {
+		secs := -1
+		if t.Duration > 0 {
+			secs = int(t.Duration.Seconds())
+		}
+		fmt.Fprintf(bw, "File%d=%s\n", i+1, t.FilePath)
+		fmt.Fprintf(bw, "Title%d=%s\n", i+1, displayTitle(t))
+		fmt.Fprintf(bw, "Length%d=%d\n", i+1, secs)
+	}
+	fmt.Fprintf(bw, "NumberOfEntries=%d\n", len(playlist.Tracks))
+	fmt.Fprintln(bw, "Version=2")
+	return bw.Flush()
+}
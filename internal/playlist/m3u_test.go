@@ -0,0 +1,177 @@
+package playlist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestParseM3U verifies plain and extended M3U: #EXTINF gives the
+// duration, artist and title of the entry after it, and other comments
+// and blank lines are skipped
+func TestParseM3U(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []entry
+	}{
+		{"plain", "a.mp3\r\n\r\n/music/b.flac\r\n", []entry{{path: "a.mp3"}, {path: "/music/b.flac"}}},
+		{"extended", "\ufeff#EXTM3U\n#EXTINF:329,Massive Attack - Teardrop\nmezzanine/03.mp3\n",
+			[]entry{{path: "mezzanine/03.mp3", artist: "Massive Attack", title: "Teardrop", duration: 329 * time.Second}}},
+		{"unknown length", "#EXTINF:-1,Radio\nhttp://example.com/stream\n",
+			[]entry{{path: "http://example.com/stream", title: "Radio"}}},
+		{"title only", "#EXTINF:61,Intro -\nintro.mp3\n", []entry{{path: "intro.mp3", title: "Intro -", duration: 61 * time.Second}}},
+		{"info without entry", "#EXTINF:10,Lost\n#EXTVLCOPT:x\n", nil},
+		{"info applies once", "#EXTINF:10,First\none.mp3\ntwo.mp3\n",
+			[]entry{{path: "one.mp3", title: "First", duration: 10 * time.Second}, {path: "two.mp3"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseM3U(strings.NewReader(tt.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("entry %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestParsePLS verifies PLS entries are put in the order of their numbers,
+// whatever order the keys come in, and that entries without a file and
+// malformed keys are dropped
+func TestParsePLS(t *testing.T) {
+	in := `[playlist]
+Title2=Björk - Jóga
+File2=homogenic/02.flac
+Length2=305
+File1=first.mp3
+Length1=-1
+Title3=No file
+File10=ten.mp3
+File0=zero.mp3
+FileX=bad.mp3
+NumberOfEntries=4
+Version=2
+`
+	got, err := parsePLS(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []entry{
+		{path: "first.mp3"},
+		{path: "homogenic/02.flac", artist: "Björk", title: "Jóga", duration: 305 * time.Second},
+		{path: "ten.mp3"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+// TestImport_RelativePaths verifies relative entries are taken from the
+// playlist file's directory while absolute paths, file URIs and URLs are
+// kept
+func TestImport_RelativePaths(t *testing.T) {
+	dir := t.TempDir()
+	lists := filepath.Join(dir, "lists")
+	if err := os.Mkdir(lists, 0755); err != nil {
+		t.Fatal(err)
+	}
+	abs := filepath.Join(dir, "abs.mp3")
+	m3u := strings.Join([]string{
+		"#EXTM3U",
+		"../music/up.mp3",
+		"sub/down.mp3",
+		abs,
+		"file://" + filepath.ToSlash(filepath.Join(dir, "uri.mp3")),
+		"http://example.com/live",
+	}, "\n")
+	path := filepath.Join(lists, "Road Trip.m3u8")
+	if err := os.WriteFile(path, []byte(m3u), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManager(t.TempDir())
+	pl, skipped, err := m.Import(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped != 0 || pl.Name != "Road Trip" {
+		t.Errorf("imported %q with %d skipped", pl.Name, skipped)
+	}
+	want := []string{
+		filepath.Join(dir, "music", "up.mp3"),
+		filepath.Join(lists, "sub", "down.mp3"),
+		abs,
+		filepath.Join(dir, "uri.mp3"),
+		"http://example.com/live",
+	}
+	if len(pl.Tracks) != len(want) {
+		t.Fatalf("got %d tracks, want %d", len(pl.Tracks), len(want))
+	}
+	for i, p := range want {
+		if pl.Tracks[i].FilePath != p {
+			t.Errorf("track %d path = %q, want %q", i, pl.Tracks[i].FilePath, p)
+		}
+	}
+	if pl.Tracks[0].Title != "up" {
+		t.Errorf("title without #EXTINF = %q, want the file name", pl.Tracks[0].Title)
+	}
+}
+
+// TestExportImport_RoundTrip verifies a playlist exported as M3U, M3U8 and
+// PLS imports with the same files, titles, artists and durations
+func TestExportImport_RoundTrip(t *testing.T) {
+	m := NewManager(t.TempDir())
+	pl, err := m.Create("Evening", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracks := []*api.Track{
+		{ID: "1", Title: "Teardrop", Artist: "Massive Attack", Duration: 329 * time.Second, FilePath: "/music/teardrop.mp3"},
+		{ID: "2", Title: "Untitled", FilePath: "/music/untitled.flac"},
+		{ID: "3", Title: "Jóga", Artist: "Björk", Duration: 305 * time.Second, FilePath: "/music/Björk/jóga.flac"},
+	}
+	for _, tr := range tracks {
+		if err := m.AddTrack(pl.ID, tr); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, ext := range []string{".m3u", ".m3u8", ".pls"} {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "evening"+ext)
+			if err := m.Export(pl.ID, path); err != nil {
+				t.Fatal(err)
+			}
+			got, skipped, err := NewManager(t.TempDir()).Import(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if skipped != 0 || len(got.Tracks) != len(tracks) {
+				t.Fatalf("imported %d tracks with %d skipped, want %d", len(got.Tracks), skipped, len(tracks))
+			}
+			for i, want := range tracks {
+				g := got.Tracks[i]
+				if g.FilePath != want.FilePath || g.Title != want.Title || g.Artist != want.Artist || g.Duration != want.Duration {
+					t.Errorf("track %d = %+v, want %+v", i, g, *want)
+				}
+			}
+		})
+	}
+}
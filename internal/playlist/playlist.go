@@ -3,20 +3,99 @@ package playlist
 import (
 	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/logger"
+	"github.com/jscyril/golang_music_player/internal/store"
 	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
 )
 
-// Manager handles playlist CRUD operations with JSON persistence
+// PlaylistVersion is the schema version of playlist files
+const PlaylistVersion = 2
+
+// playlistMigrations upgrade older playlist files, see store.Migration
+var playlistMigrations = map[int]store.Migration{
+	1: migrateTrackCopies,
+}
+
+// migrateTrackCopies turns the track copies version 1 embedded into the
+// track IDs they were taken from. What the copies said is kept as missing
+// entries, so tracks the library no longer has can still be shown and
+// played; the next save keeps only those the library lacks.
+func migrateTrackCopies(doc map[string]json.RawMessage) error {
+	var tracks []struct {
+		ID string `json:"id"`
+		trackRef
+	}
+	if raw, ok := doc["tracks"]; ok {
+		if err := json.Unmarshal(raw, &tracks); err != nil {
+			return fmt.Errorf("tracks: %w", err)
+		}
+	}
+	ids := make([]string, len(tracks))
+	missing := make(map[string]trackRef)
+	for i, t := range tracks {
+		ids[i] = t.ID
+		if t.FilePath != "" || t.Title != "" {
+			missing[t.ID] = t.trackRef
+		}
+	}
+	raw, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+	doc["track_ids"] = raw
+	if len(missing) > 0 {
+		if doc["missing"], err = json.Marshal(missing); err != nil {
+			return err
+		}
+	}
+	delete(doc, "tracks")
+	return nil
+}
+
+// playlistFile is a playlist as saved on disk
+type playlistFile struct {
+	Version int `json:"version"`
+	*api.Playlist
+
+	// Missing remembers the tracks the library did not have when the
+	// playlist was saved, by track ID
+	Missing map[string]trackRef `json:"missing,omitempty"`
+}
+
+// trackRef is what a playlist keeps of a track the library does not have
+type trackRef struct {
+	Title    string `json:"title,omitempty"`
+	Artist   string `json:"artist,omitempty"`
+	FilePath string `json:"file_path,omitempty"`
+}
+
+// Manager handles playlist CRUD operations with JSON persistence.
+//
+// Playlists handed out are copies, and their track slices are never
+// changed in place: changes and refreshes replace the slice. Callers can
+// keep pointers to the tracks, as the queue does, while the playlist
+// changes underneath.
 type Manager struct {
 	playlists map[string]*api.Playlist
 	basePath  string
+	resolver  TrackResolver
+	pub       api.Publisher
 	mu        sync.RWMutex
+
+	// Duplicate tracks are refused in playlists preventing them, see
+	// SetDuplicatePolicy
+	preventDuplicates bool
+	duplicateRules    map[string]bool // playlist ID or name -> prevent
+	saveRules         func(rules map[string]bool) error
 }
 
 // NewManager creates a new playlist manager
@@ -27,6 +106,64 @@ func NewManager(basePath string) *Manager {
 	}
 }
 
+// SetPublisher makes the manager announce every saved or deleted playlist
+func (m *Manager) SetPublisher(p api.Publisher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pub = p
+}
+
+// SetDuplicatePolicy sets whether AddTrack refuses a track the playlist
+// already holds: prevent for all playlists, overridden by rules, which
+// maps playlist IDs or names to whether they prevent duplicates. A rule
+// keyed by name follows the playlist when it is renamed, and save, if not
+// nil, is called with the rules changed.
+func (m *Manager) SetDuplicatePolicy(prevent bool, rules map[string]bool, save func(rules map[string]bool) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.preventDuplicates = prevent
+	m.duplicateRules = rules
+	m.saveRules = save
+}
+
+// preventsDuplicates reports whether playlist refuses duplicate tracks.
+// Callers hold m.mu.
+func (m *Manager) preventsDuplicates(playlist *api.Playlist) bool {
+	if prevent, ok := m.duplicateRules[playlist.ID]; ok {
+		return prevent
+	}
+	if prevent, ok := m.duplicateRules[playlist.Name]; ok {
+		return prevent
+	}
+	return m.preventDuplicates
+}
+
+// renameRule moves the duplicate rule keyed by a playlist's old name to
+// its new one. Callers hold m.mu.
+func (m *Manager) renameRule(oldName, newName string) {
+	prevent, ok := m.duplicateRules[oldName]
+	if !ok || oldName == newName {
+		return
+	}
+	rules := maps.Clone(m.duplicateRules)
+	delete(rules, oldName)
+	rules[newName] = prevent
+	m.duplicateRules = rules
+	if m.saveRules != nil {
+		if err := m.saveRules(rules); err != nil {
+			logger.Warn("save duplicate rule for playlist %q: %v", newName, err)
+		}
+	}
+}
+
+// publishChange announces a playlist change. Callers hold m.mu; publishing
+// never blocks.
+func (m *Manager) publishChange(id string) {
+	if m.pub != nil {
+		m.pub.Publish(api.AudioEvent{Type: api.EventPlaylistChanged, Payload: id})
+	}
+}
+
 // Create creates a new playlist
 func (m *Manager) Create(name, description string) (*api.Playlist, error) {
 	m.mu.Lock()
@@ -51,7 +188,14 @@ func (m *Manager) Create(name, description string) (*api.Playlist, error) {
 		return nil, err
 	}
 
-	return playlist, nil
+	return snapshot(playlist), nil
+}
+
+// snapshot copies a playlist for a caller. The track slice is shared,
+// which is safe since the manager never changes one in place.
+func snapshot(playlist *api.Playlist) *api.Playlist {
+	cp := *playlist
+	return &cp
 }
 
 // GetByID returns a playlist by its ID
@@ -63,7 +207,7 @@ func (m *Manager) GetByID(id string) (*api.Playlist, error) {
 	if !exists {
 		return nil, playerrors.ErrPlaylistNotFound
 	}
-	return playlist, nil
+	return snapshot(playlist), nil
 }
 
 // GetAll returns all playlists
@@ -73,11 +217,63 @@ func (m *Manager) GetAll() []*api.Playlist {
 
 	playlists := make([]*api.Playlist, 0, len(m.playlists))
 	for _, p := range m.playlists {
-		playlists = append(playlists, p)
+		playlists = append(playlists, snapshot(p))
 	}
 	return playlists
 }
 
+// Refresh updates the tracks of every playlist from the library, picking
+// up tag edits and rescans. Tracks the library no longer has keep what
+// they were last seen as.
+func (m *Manager) Refresh() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.playlists {
+		p.Tracks = m.refreshed(p.Tracks)
+	}
+}
+
+// refreshed returns tracks as the library has them now, in a new slice.
+// Callers hold m.mu.
+func (m *Manager) refreshed(tracks []api.Track) []api.Track {
+	out := slices.Clone(tracks)
+	if m.resolver == nil {
+		return out
+	}
+	for i, t := range out {
+		if track, err := m.resolver.GetTrack(t.ID); err == nil {
+			out[i] = *track
+		}
+	}
+	return out
+}
+
+// resolve fills in the tracks of a playlist read from disk from its track
+// IDs. An ID the library does not have stands for a track it had when the
+// playlist was saved, kept in missing, or for one known only by its path
+// (from an import without a library). Callers hold m.mu.
+func (m *Manager) resolve(playlist *api.Playlist, missing map[string]trackRef) {
+	tracks := make([]api.Track, len(playlist.TrackIDs))
+	for i, id := range playlist.TrackIDs {
+		tracks[i] = api.Track{ID: id, Title: id}
+		if filepath.IsAbs(id) || strings.Contains(id, "://") {
+			tracks[i].FilePath = id
+			tracks[i].Title = strings.TrimSuffix(filepath.Base(id), filepath.Ext(id))
+		}
+		if ref, ok := missing[id]; ok {
+			if ref.FilePath != "" {
+				tracks[i].FilePath = ref.FilePath
+				tracks[i].Title = strings.TrimSuffix(filepath.Base(ref.FilePath), filepath.Ext(ref.FilePath))
+			}
+			if ref.Title != "" {
+				tracks[i].Title = ref.Title
+			}
+			tracks[i].Artist = ref.Artist
+		}
+	}
+	playlist.Tracks = m.refreshed(tracks)
+}
+
 // Update updates a playlist's name and description
 func (m *Manager) Update(id, name, description string) error {
 	m.mu.Lock()
@@ -88,6 +284,7 @@ func (m *Manager) Update(id, name, description string) error {
 		return playerrors.ErrPlaylistNotFound
 	}
 
+	m.renameRule(playlist.Name, name)
 	playlist.Name = name
 	playlist.Description = description
 	playlist.UpdatedAt = time.Now()
@@ -106,15 +303,18 @@ func (m *Manager) Delete(id string) error {
 
 	// Delete file
 	path := filepath.Join(m.basePath, id+".json")
-	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+	if err := store.Remove(path); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("delete playlist file: %w", err)
 	}
 
 	delete(m.playlists, id)
+	m.publishChange(id)
 	return nil
 }
 
-// AddTrack adds a track to a playlist
+// AddTrack adds a track to a playlist. A track the playlist already holds
+// is refused with ErrDuplicateTrack if the playlist prevents duplicates,
+// and added with a warning otherwise.
 func (m *Manager) AddTrack(playlistID string, track *api.Track) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -124,12 +324,66 @@ func (m *Manager) AddTrack(playlistID string, track *api.Track) error {
 		return playerrors.ErrPlaylistNotFound
 	}
 
-	playlist.Tracks = append(playlist.Tracks, *track)
+	if containsTrack(playlist, track.ID) {
+		if m.preventsDuplicates(playlist) {
+			return playerrors.ErrDuplicateTrack
+		}
+		logger.Warn("%q is already in playlist %q, adding it again", track.Title, playlist.Name)
+	}
+
+	playlist.Tracks = append(slices.Clip(playlist.Tracks), *track)
 	playlist.UpdatedAt = time.Now()
 
 	return m.savePlaylist(playlist)
 }
 
+// Deduplicate removes the repeats of tracks a playlist holds more than
+// once, keeping the first of each, and returns how many it removed
+func (m *Manager) Deduplicate(playlistID string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	playlist, exists := m.playlists[playlistID]
+	if !exists {
+		return 0, playerrors.ErrPlaylistNotFound
+	}
+
+	seen := make(map[string]bool, len(playlist.Tracks))
+	var kept []api.Track
+	for _, t := range playlist.Tracks {
+		if seen[t.ID] {
+			continue
+		}
+		seen[t.ID] = true
+		kept = append(kept, t)
+	}
+	removed := len(playlist.Tracks) - len(kept)
+	if removed == 0 {
+		return 0, nil
+	}
+	playlist.Tracks = kept
+	playlist.UpdatedAt = time.Now()
+	return removed, m.savePlaylist(playlist)
+}
+
+// Contains reports whether the playlist holds the track with trackID
+func (m *Manager) Contains(playlistID, trackID string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	playlist, exists := m.playlists[playlistID]
+	return exists && containsTrack(playlist, trackID)
+}
+
+// containsTrack reports whether playlist holds the track with id
+func containsTrack(playlist *api.Playlist, id string) bool {
+	for _, t := range playlist.Tracks {
+		if t.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
 // RemoveTrack removes a track from a playlist
 func (m *Manager) RemoveTrack(playlistID, trackID string) error {
 	m.mu.Lock()
@@ -140,39 +394,77 @@ func (m *Manager) RemoveTrack(playlistID, trackID string) error {
 		return playerrors.ErrPlaylistNotFound
 	}
 
-	found := false
-	for i, t := range playlist.Tracks {
-		if t.ID == trackID {
-			playlist.Tracks = append(playlist.Tracks[:i], playlist.Tracks[i+1:]...)
-			found = true
-			break
-		}
+	i := slices.IndexFunc(playlist.Tracks, func(t api.Track) bool { return t.ID == trackID })
+	if i < 0 {
+		return playerrors.ErrTrackNotFound
 	}
+	playlist.Tracks = slices.Concat(playlist.Tracks[:i], playlist.Tracks[i+1:])
 
-	if !found {
-		return playerrors.ErrTrackNotFound
+	playlist.UpdatedAt = time.Now()
+	return m.savePlaylist(playlist)
+}
+
+// MoveTrack moves the track at index from to index to within a playlist
+func (m *Manager) MoveTrack(playlistID string, from, to int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	playlist, exists := m.playlists[playlistID]
+	if !exists {
+		return playerrors.ErrPlaylistNotFound
+	}
+
+	n := len(playlist.Tracks)
+	if from < 0 || from >= n || to < 0 || to >= n {
+		return fmt.Errorf("move track %d to %d: index out of bounds", from, to)
+	}
+	if from == to {
+		return nil
 	}
 
+	tracks := slices.Clone(playlist.Tracks)
+	track := tracks[from]
+	if from < to {
+		copy(tracks[from:to], tracks[from+1:to+1])
+	} else {
+		copy(tracks[to+1:from+1], tracks[to:from])
+	}
+	tracks[to] = track
+	playlist.Tracks = tracks
 	playlist.UpdatedAt = time.Now()
+
 	return m.savePlaylist(playlist)
 }
 
-// savePlaylist saves a playlist to disk
+// savePlaylist saves a playlist to disk, atomically and keeping the
+// previous file as a backup. Only the IDs of its tracks are saved, with
+// the title, artist and path of those the library does not have.
 func (m *Manager) savePlaylist(playlist *api.Playlist) error {
-	if err := os.MkdirAll(m.basePath, 0755); err != nil {
-		return fmt.Errorf("create playlist directory: %w", err)
+	playlist.TrackIDs = make([]string, len(playlist.Tracks))
+	missing := make(map[string]trackRef)
+	for i, t := range playlist.Tracks {
+		playlist.TrackIDs[i] = t.ID
+		if t.FilePath == "" || t.FilePath == t.ID {
+			continue // nothing to keep, or the ID is the path
+		}
+		if m.resolver != nil {
+			if _, err := m.resolver.GetTrack(t.ID); err == nil {
+				continue
+			}
+		}
+		missing[t.ID] = trackRef{Title: t.Title, Artist: t.Artist, FilePath: t.FilePath}
 	}
-
-	data, err := json.MarshalIndent(playlist, "", "  ")
+	data, err := json.MarshalIndent(playlistFile{Version: PlaylistVersion, Playlist: playlist, Missing: missing}, "", "  ")
 	if err != nil {
 		return fmt.Errorf("marshal playlist: %w", err)
 	}
 
 	path := filepath.Join(m.basePath, playlist.ID+".json")
-	if err := os.WriteFile(path, data, 0644); err != nil {
+	if err := store.WriteFile(path, data); err != nil {
 		return fmt.Errorf("write playlist file: %w", err)
 	}
 
+	m.publishChange(playlist.ID)
 	return nil
 }
 
@@ -196,15 +488,12 @@ func (m *Manager) LoadAll() error {
 		}
 
 		path := filepath.Join(m.basePath, entry.Name())
-		data, err := os.ReadFile(path)
-		if err != nil {
-			continue // Skip files we can't read
-		}
-
 		var playlist api.Playlist
-		if err := json.Unmarshal(data, &playlist); err != nil {
-			continue // Skip invalid JSON
+		file := playlistFile{Playlist: &playlist}
+		if err := store.Load(path, PlaylistVersion, playlistMigrations, &file); err != nil {
+			continue // Skip files we can't read, even from their backup
 		}
+		m.resolve(&playlist, file.Missing)
 
 		m.playlists[playlist.ID] = &playlist
 	}
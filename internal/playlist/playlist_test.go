@@ -0,0 +1,255 @@
+package playlist
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jscyril/golang_music_player/api"
+	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
+)
+
+func TestManager_AddTrackDuplicates(t *testing.T) {
+	m := NewManager(t.TempDir())
+	mix, err := m.Create("Mix", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	strict, err := m.Create("Strict", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]bool
+	m.SetDuplicatePolicy(false, map[string]bool{"Strict": true}, func(rules map[string]bool) error {
+		saved = rules
+		return nil
+	})
+
+	track := &api.Track{ID: "a", Title: "A"}
+	for _, id := range []string{mix.ID, strict.ID} {
+		if err := m.AddTrack(id, track); err != nil {
+			t.Fatalf("AddTrack: %v", err)
+		}
+	}
+	if err := m.AddTrack(mix.ID, track); err != nil {
+		t.Errorf("duplicate in a playlist allowing them: %v", err)
+	}
+	if err := m.AddTrack(strict.ID, track); !errors.Is(err, playerrors.ErrDuplicateTrack) {
+		t.Errorf("duplicate in a playlist preventing them: err = %v", err)
+	}
+	mix, _ = m.GetByID(mix.ID)
+	strict, _ = m.GetByID(strict.ID)
+	if len(mix.Tracks) != 2 || len(strict.Tracks) != 1 {
+		t.Errorf("got %d and %d tracks, want 2 and 1", len(mix.Tracks), len(strict.Tracks))
+	}
+	if !m.Contains(mix.ID, "a") || m.Contains(mix.ID, "b") {
+		t.Error("Contains disagrees with the tracks added")
+	}
+
+	// The rule follows the playlist when it is renamed
+	if err := m.Update(strict.ID, "Strict renamed", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddTrack(strict.ID, track); !errors.Is(err, playerrors.ErrDuplicateTrack) {
+		t.Errorf("duplicate after rename: err = %v", err)
+	}
+	if len(saved) != 1 || !saved["Strict renamed"] {
+		t.Errorf("saved rules = %v", saved)
+	}
+
+	// and a rule can name the playlist by ID
+	m.SetDuplicatePolicy(false, map[string]bool{mix.ID: true}, nil)
+	if err := m.AddTrack(mix.ID, track); !errors.Is(err, playerrors.ErrDuplicateTrack) {
+		t.Errorf("duplicate with a rule by ID: err = %v", err)
+	}
+}
+
+func TestManager_Deduplicate(t *testing.T) {
+	m := NewManager(t.TempDir())
+	pl, err := m.Create("Mix", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "a", "c", "b", "a"} {
+		if err := m.AddTrack(pl.ID, &api.Track{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	before, _ := m.GetByID(pl.ID)
+	removed, err := m.Deduplicate(pl.ID)
+	if err != nil {
+		t.Fatalf("Deduplicate: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("removed = %d, want 3", removed)
+	}
+	pl, _ = m.GetByID(pl.ID)
+	var ids string
+	for _, tr := range pl.Tracks {
+		ids += tr.ID
+	}
+	if ids != "abc" {
+		t.Errorf("tracks = %q, want abc", ids)
+	}
+	ids = ""
+	for _, tr := range before.Tracks {
+		ids += tr.ID
+	}
+	if ids != "abacba" {
+		t.Errorf("copy taken before changed to %q", ids)
+	}
+
+	if removed, _ := m.Deduplicate(pl.ID); removed != 0 {
+		t.Errorf("second pass removed %d", removed)
+	}
+	if _, err := m.Deduplicate("missing"); !errors.Is(err, playerrors.ErrPlaylistNotFound) {
+		t.Errorf("missing playlist: err = %v", err)
+	}
+}
+
+// fakeLibrary resolves track IDs from a map
+type fakeLibrary map[string]*api.Track
+
+func (l fakeLibrary) ResolvePath(path string) (*api.Track, error) {
+	return nil, playerrors.ErrTrackNotFound
+}
+
+func (l fakeLibrary) GetTrack(id string) (*api.Track, error) {
+	if t, ok := l[id]; ok {
+		return t, nil
+	}
+	return nil, playerrors.ErrTrackNotFound
+}
+
+func TestManager_TrackReferences(t *testing.T) {
+	dir := t.TempDir()
+	lib := fakeLibrary{"a": {ID: "a", Title: "Old title"}}
+
+	// A version 1 file embeds track copies
+	v1 := `{"version": 1, "id": "pl", "name": "Mix", "tracks": [
+		{"id": "a", "title": "Stale"},
+		{"id": "gone", "title": "Removed", "artist": "Someone", "file_path": "/music/gone.mp3"},
+		{"id": "/music/b.mp3", "title": "Imported"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "pl.json"), []byte(v1), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManager(dir)
+	m.SetResolver(lib)
+	if err := m.LoadAll(); err != nil {
+		t.Fatal(err)
+	}
+	pl, err := m.GetByID("pl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []api.Track{
+		{ID: "a", Title: "Old title"},
+		{ID: "gone", Title: "Removed", Artist: "Someone", FilePath: "/music/gone.mp3"},
+		{ID: "/music/b.mp3", Title: "Imported", FilePath: "/music/b.mp3"},
+	}
+	if len(pl.Tracks) != len(want) {
+		t.Fatalf("got %d tracks, want %d", len(pl.Tracks), len(want))
+	}
+	for i := range want {
+		got := pl.Tracks[i]
+		if got.ID != want[i].ID || got.Title != want[i].Title || got.Artist != want[i].Artist || got.FilePath != want[i].FilePath {
+			t.Errorf("track %d = %+v, want %+v", i, pl.Tracks[i], want[i])
+		}
+	}
+
+	// Tag edits in the library show on refresh without touching the
+	// playlist, and leave tracks already handed out alone
+	lib["a"] = &api.Track{ID: "a", Title: "New title"}
+	m.Refresh()
+	if pl, _ := m.GetByID("pl"); pl.Tracks[0].Title != "New title" {
+		t.Errorf("title after edit = %q", pl.Tracks[0].Title)
+	}
+	if pl.Tracks[0].Title != "Old title" {
+		t.Errorf("refresh changed a playlist handed out: title = %q", pl.Tracks[0].Title)
+	}
+
+	// Saving writes only the IDs
+	if err := m.AddTrack("pl", lib["a"]); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "pl.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved map[string]json.RawMessage
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := saved["tracks"]; ok {
+		t.Error("saved file still embeds tracks")
+	}
+	var ids []string
+	json.Unmarshal(saved["track_ids"], &ids)
+	if got := strings.Join(ids, ","); got != "a,gone,/music/b.mp3,a" {
+		t.Errorf("track_ids = %s", got)
+	}
+	// with what is known of the tracks the library lacks, and the tracks
+	// whose ID is their path need nothing more
+	var missing map[string]trackRef
+	json.Unmarshal(saved["missing"], &missing)
+	if len(missing) != 1 || missing["gone"] != (trackRef{Title: "Removed", Artist: "Someone", FilePath: "/music/gone.mp3"}) {
+		t.Errorf("missing = %+v", missing)
+	}
+
+	// which survive a reload
+	m = NewManager(dir)
+	m.SetResolver(lib)
+	if err := m.LoadAll(); err != nil {
+		t.Fatal(err)
+	}
+	if pl, _ := m.GetByID("pl"); pl.Tracks[1].Title != "Removed" || pl.Tracks[1].FilePath != "/music/gone.mp3" {
+		t.Errorf("missing track after reload = %+v", pl.Tracks[1])
+	}
+}
+
+func TestManager_ChangesLeaveCopiesAlone(t *testing.T) {
+	m := NewManager(t.TempDir())
+	pl, err := m.Create("Mix", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c"} {
+		if err := m.AddTrack(pl.ID, &api.Track{ID: id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, _ := m.GetByID(pl.ID)
+	first := &before.Tracks[0] // as the queue holds them
+
+	if err := m.AddTrack(pl.ID, &api.Track{ID: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.MoveTrack(pl.ID, 0, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.RemoveTrack(pl.ID, "b"); err != nil {
+		t.Fatal(err)
+	}
+	m.Refresh()
+
+	var ids string
+	for _, tr := range before.Tracks {
+		ids += tr.ID
+	}
+	if ids != "abc" || first.ID != "a" {
+		t.Errorf("copy changed to %q, first track %q", ids, first.ID)
+	}
+	after, _ := m.GetByID(pl.ID)
+	ids = ""
+	for _, tr := range after.Tracks {
+		ids += tr.ID
+	}
+	if ids != "cad" {
+		t.Errorf("tracks = %q, want cad", ids)
+	}
+}
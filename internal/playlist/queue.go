@@ -3,7 +3,9 @@ package playlist
 import (
 	"errors"
 	"math/rand"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/jscyril/golang_music_player/api"
 )
@@ -15,6 +17,10 @@ type Queue struct {
 	repeatMode api.RepeatMode
 	shuffle    bool
 	original   []*api.Track // Original order before shuffle
+	exclude    func(*api.Track) bool
+	consume    bool // played tracks are removed as the queue moves on
+	party      bool // play actions append to the queue instead of replacing it
+	pub        api.Publisher
 	mu         sync.RWMutex
 }
 
@@ -28,24 +34,115 @@ func NewQueue() *Queue {
 	}
 }
 
+// SetPublisher makes the queue announce tracks being added, removed or
+// reordered as EventQueueChanged
+func (q *Queue) SetPublisher(p api.Publisher) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pub = p
+}
+
+// publishChange announces a change to the tracks. Callers hold q.mu;
+// publishing never blocks.
+func (q *Queue) publishChange() {
+	if q.pub != nil {
+		q.pub.Publish(api.AudioEvent{Type: api.EventQueueChanged})
+	}
+}
+
 // Add adds tracks to the end of the queue
 func (q *Queue) Add(tracks ...*api.Track) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 	q.tracks = append(q.tracks, tracks...)
+	if q.original != nil {
+		q.original = append(q.original, tracks...)
+	}
+	q.publishChange()
+}
+
+// InsertNext puts tracks right after the current track, so they play next.
+// In a shuffled queue they also follow it in the order Unshuffle restores.
+func (q *Queue) InsertNext(tracks ...*api.Track) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	at := 0
+	if len(q.tracks) > 0 {
+		at = q.index + 1
+	}
+	if q.original != nil {
+		orig := len(q.original)
+		if len(q.tracks) > 0 {
+			current := q.tracks[q.index]
+			for i, t := range q.original {
+				if t.ID == current.ID {
+					orig = i + 1
+					break
+				}
+			}
+		}
+		q.original = slices.Insert(q.original, orig, tracks...)
+	}
+	q.tracks = slices.Insert(q.tracks, at, tracks...)
+	q.publishChange()
 }
 
 // Set replaces the entire queue with new tracks
 func (q *Queue) Set(tracks []*api.Track) {
 	q.mu.Lock()
 	defer q.mu.Unlock()
+	q.set(tracks)
+	q.publishChange()
+}
 
+// set replaces the tracks without announcing it. Callers hold q.mu.
+func (q *Queue) set(tracks []*api.Track) {
 	q.tracks = make([]*api.Track, len(tracks))
 	copy(q.tracks, tracks)
 	q.original = nil
 	q.index = 0
 }
 
+// SetFrom replaces the queue with tracks and makes start the current track.
+// A start not among tracks leaves the first track current.
+func (q *Queue) SetFrom(tracks []*api.Track, start *api.Track) {
+	q.Set(tracks)
+	if start == nil {
+		return
+	}
+	for i, t := range tracks {
+		if t.ID == start.ID {
+			q.JumpTo(i)
+			return
+		}
+	}
+}
+
+// SetShuffled replaces the queue with tracks in random order, starting from
+// a random track that Shuffle does not leave out, if there is one
+func (q *Queue) SetShuffled(tracks []*api.Track) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	defer q.publishChange()
+
+	q.set(tracks)
+	if len(tracks) <= 1 {
+		return
+	}
+	starts := make([]int, 0, len(tracks))
+	for i, t := range tracks {
+		if q.exclude == nil || !q.exclude(t) {
+			starts = append(starts, i)
+		}
+	}
+	q.index = rand.Intn(len(tracks))
+	if len(starts) > 0 {
+		q.index = starts[rand.Intn(len(starts))]
+	}
+	q.shuffleTracks()
+}
+
 // Clear removes all tracks from the queue
 func (q *Queue) Clear() {
 	q.mu.Lock()
@@ -54,6 +151,7 @@ func (q *Queue) Clear() {
 	q.tracks = make([]*api.Track, 0)
 	q.original = nil
 	q.index = 0
+	q.publishChange()
 }
 
 // Current returns the current track
@@ -67,56 +165,133 @@ func (q *Queue) Current() *api.Track {
 	return q.tracks[q.index]
 }
 
-// Next moves to the next track and returns it
+// Next moves to the next track and returns it. In consume mode the track
+// moved away from is removed from the queue.
 func (q *Queue) Next() *api.Track {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if len(q.tracks) == 0 {
-		return nil
+	i := q.nextIndex()
+	if i < 0 {
+		return nil // End of queue
 	}
-
-	switch q.repeatMode {
-	case api.RepeatOne:
-		// Stay on current track
-		return q.tracks[q.index]
-	case api.RepeatAll:
-		q.index = (q.index + 1) % len(q.tracks)
-	default: // RepeatNone
-		if q.index < len(q.tracks)-1 {
-			q.index++
-		} else {
-			return nil // End of queue
+	if q.consume {
+		q.removeAt(q.index)
+		if i > q.index {
+			i--
 		}
+		q.publishChange()
 	}
-
+	q.index = i
 	return q.tracks[q.index]
 }
 
+// Consume removes the current track when consume mode is on. Players call
+// it when playback runs off the end of the queue, where Next has nowhere
+// to move and so keeps the last track.
+func (q *Queue) Consume() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.consume && q.index < len(q.tracks) {
+		q.removeAt(q.index)
+		q.publishChange()
+	}
+}
+
+// PeekNext returns the track Next would move to, without moving
+func (q *Queue) PeekNext() *api.Track {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if i := q.nextIndex(); i >= 0 {
+		return q.tracks[i]
+	}
+	return nil
+}
+
 // Previous moves to the previous track and returns it
 func (q *Queue) Previous() *api.Track {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if len(q.tracks) == 0 {
+	i := q.previousIndex()
+	if i < 0 {
 		return nil
 	}
+	q.index = i
+	return q.tracks[q.index]
+}
 
-	switch q.repeatMode {
-	case api.RepeatOne:
-		return q.tracks[q.index]
-	case api.RepeatAll:
-		q.index--
-		if q.index < 0 {
-			q.index = len(q.tracks) - 1
+// PeekPrevious returns the track Previous would move to, without moving
+func (q *Queue) PeekPrevious() *api.Track {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if i := q.previousIndex(); i >= 0 {
+		return q.tracks[i]
+	}
+	return nil
+}
+
+// Upcoming returns up to n tracks that follow the current one in queue
+// order, wrapping around to the start under RepeatAll. Repeat-one is not
+// applied: the list is what skipping forward would reach.
+func (q *Queue) Upcoming(n int) []*api.Track {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	var out []*api.Track
+	for i := 1; len(out) < n && i < len(q.tracks); i++ {
+		j := q.index + i
+		if j >= len(q.tracks) {
+			if q.repeatMode != api.RepeatAll {
+				break
+			}
+			j -= len(q.tracks)
 		}
+		out = append(out, q.tracks[j])
+	}
+	return out
+}
+
+// nextIndex returns the index Next moves to, or -1 at the end of the
+// queue. Consume mode never stays on the current track, so repeat-one acts
+// as no repeat there. Callers hold q.mu.
+func (q *Queue) nextIndex() int {
+	if len(q.tracks) == 0 {
+		return -1
+	}
+	switch {
+	case q.repeatMode == api.RepeatOne && !q.consume:
+		return q.index
+	case q.repeatMode == api.RepeatAll:
+		if q.consume && len(q.tracks) == 1 {
+			return -1
+		}
+		return (q.index + 1) % len(q.tracks)
 	default:
-		if q.index > 0 {
-			q.index--
+		if q.index < len(q.tracks)-1 {
+			return q.index + 1
 		}
+		return -1
 	}
+}
 
-	return q.tracks[q.index]
+// previousIndex returns the index Previous moves to, or -1 for an empty
+// queue. Without repeat the first track stays put. Callers hold q.mu.
+func (q *Queue) previousIndex() int {
+	if len(q.tracks) == 0 {
+		return -1
+	}
+	switch q.repeatMode {
+	case api.RepeatOne:
+		return q.index
+	case api.RepeatAll:
+		return (q.index - 1 + len(q.tracks)) % len(q.tracks)
+	default:
+		return max(q.index-1, 0)
+	}
 }
 
 // JumpTo jumps to a specific index
@@ -141,7 +316,22 @@ func (q *Queue) Remove(index int) error {
 		return errors.New("index out of bounds")
 	}
 
+	q.removeAt(index)
+	q.publishChange()
+	return nil
+}
+
+// removeAt drops the track at index, from the shuffle's original order as
+// well, and keeps the current index valid. Callers hold q.mu.
+func (q *Queue) removeAt(index int) {
+	track := q.tracks[index]
 	q.tracks = append(q.tracks[:index], q.tracks[index+1:]...)
+	for i, t := range q.original {
+		if t.ID == track.ID {
+			q.original = append(q.original[:i], q.original[i+1:]...)
+			break
+		}
+	}
 
 	// Adjust current index if needed
 	if q.index > index {
@@ -149,10 +339,45 @@ func (q *Queue) Remove(index int) error {
 	} else if q.index >= len(q.tracks) && len(q.tracks) > 0 {
 		q.index = len(q.tracks) - 1
 	}
+}
+
+// Move moves the track at index from to index to, keeping the current
+// track current even if its position changes
+func (q *Queue) Move(from, to int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if from < 0 || from >= len(q.tracks) || to < 0 || to >= len(q.tracks) {
+		return errors.New("index out of bounds")
+	}
+	if from == to {
+		return nil
+	}
 
+	track := q.tracks[from]
+	q.tracks = append(q.tracks[:from], q.tracks[from+1:]...)
+	q.tracks = append(q.tracks[:to], append([]*api.Track{track}, q.tracks[to:]...)...)
+
+	switch {
+	case q.index == from:
+		q.index = to
+	case from < q.index && to >= q.index:
+		q.index--
+	case from > q.index && to <= q.index:
+		q.index++
+	}
+	q.publishChange()
 	return nil
 }
 
+// SetShuffleExclude sets a filter for tracks that Shuffle leaves out of the
+// shuffled order. The current track is always kept; Unshuffle restores all.
+func (q *Queue) SetShuffleExclude(exclude func(*api.Track) bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.exclude = exclude
+}
+
 // Shuffle shuffles the queue (Fisher-Yates algorithm)
 func (q *Queue) Shuffle() {
 	q.mu.Lock()
@@ -161,6 +386,13 @@ func (q *Queue) Shuffle() {
 	if len(q.tracks) <= 1 {
 		return
 	}
+	q.shuffleTracks()
+	q.publishChange()
+}
+
+// shuffleTracks shuffles at least two tracks, keeping the current one
+// first. Callers hold q.mu.
+func (q *Queue) shuffleTracks() {
 
 	// Save original order if not already shuffled
 	if q.original == nil {
@@ -171,6 +403,16 @@ func (q *Queue) Shuffle() {
 	// Get current track to keep it at position 0
 	currentTrack := q.tracks[q.index]
 
+	if q.exclude != nil {
+		kept := make([]*api.Track, 0, len(q.tracks))
+		for _, track := range q.tracks {
+			if track == currentTrack || !q.exclude(track) {
+				kept = append(kept, track)
+			}
+		}
+		q.tracks = kept
+	}
+
 	// Shuffle all tracks
 	n := len(q.tracks)
 	for i := n - 1; i > 0; i-- {
@@ -211,6 +453,7 @@ func (q *Queue) Unshuffle() {
 			break
 		}
 	}
+	q.publishChange()
 }
 
 // SetRepeatMode sets the repeat mode
@@ -234,6 +477,49 @@ func (q *Queue) IsShuffled() bool {
 	return q.shuffle
 }
 
+// SetConsume turns consume mode on or off
+func (q *Queue) SetConsume(on bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.consume = on
+}
+
+// IsConsume returns whether played tracks are removed from the queue
+func (q *Queue) IsConsume() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.consume
+}
+
+// SetParty turns party mode on or off
+func (q *Queue) SetParty(on bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.party = on
+}
+
+// IsParty returns whether play actions should append to the queue rather
+// than replace it
+func (q *Queue) IsParty() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	return q.party
+}
+
+// FillState copies the queue, its index, repeat mode and shuffle, consume
+// and party flags into state under a single lock
+func (q *Queue) FillState(state *api.PlaybackState) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	state.Queue = make([]*api.Track, len(q.tracks))
+	copy(state.Queue, q.tracks)
+	state.QueueIndex = q.index
+	state.Repeat = q.repeatMode
+	state.Shuffle = q.shuffle
+	state.Consume = q.consume
+	state.Party = q.party
+}
+
 // GetAll returns a copy of all tracks in the queue
 func (q *Queue) GetAll() []*api.Track {
 	q.mu.RLock()
@@ -262,11 +548,7 @@ func (q *Queue) Index() int {
 func (q *Queue) HasNext() bool {
 	q.mu.RLock()
 	defer q.mu.RUnlock()
-
-	if q.repeatMode == api.RepeatAll || q.repeatMode == api.RepeatOne {
-		return len(q.tracks) > 0
-	}
-	return q.index < len(q.tracks)-1
+	return q.nextIndex() >= 0
 }
 
 // HasPrevious returns true if there's a previous track
@@ -279,3 +561,52 @@ func (q *Queue) HasPrevious() bool {
 	}
 	return q.index > 0
 }
+
+// SavePoint is a snapshot of the queue to come back to after a listening
+// detour, e.g. before queueing a whole other album
+type SavePoint struct {
+	tracks   []*api.Track
+	original []*api.Track
+	index    int
+	shuffle  bool
+
+	// Position is how far into the current track playback was
+	Position time.Duration
+}
+
+// Current returns the track that was current at the save point
+func (sp *SavePoint) Current() *api.Track {
+	if sp.index < 0 || sp.index >= len(sp.tracks) {
+		return nil
+	}
+	return sp.tracks[sp.index]
+}
+
+// SavePoint snapshots the order, shuffle state and current entry
+func (q *Queue) SavePoint(pos time.Duration) *SavePoint {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	sp := &SavePoint{index: q.index, shuffle: q.shuffle, Position: pos}
+	sp.tracks = append([]*api.Track(nil), q.tracks...)
+	if q.original != nil {
+		sp.original = append([]*api.Track(nil), q.original...)
+	}
+	return sp
+}
+
+// Restore puts the queue back as it was at sp. The repeat mode is left
+// as it is now.
+func (q *Queue) Restore(sp *SavePoint) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.tracks = append([]*api.Track(nil), sp.tracks...)
+	q.original = nil
+	if sp.original != nil {
+		q.original = append([]*api.Track(nil), sp.original...)
+	}
+	q.index = sp.index
+	q.shuffle = sp.shuffle
+	q.publishChange()
+}
@@ -0,0 +1,143 @@
+package playlist
+
+import (
+	"testing"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// ids lists the IDs of the queue's tracks in order
+func ids(q *Queue) []string {
+	var out []string
+	for _, t := range q.GetAll() {
+		out = append(out, t.ID)
+	}
+	return out
+}
+
+// TestQueue_Consume verifies played tracks leave the queue as it advances,
+// under repeat-all and at the end of the queue
+func TestQueue_Consume(t *testing.T) {
+	q := NewQueue()
+	q.Set([]*api.Track{{ID: "a"}, {ID: "b"}, {ID: "c"}})
+	q.SetConsume(true)
+	q.SetRepeatMode(api.RepeatAll)
+	q.JumpTo(2)
+
+	if next := q.Next(); next == nil || next.ID != "a" {
+		t.Fatalf("Next wrapped to %v, want a", next)
+	}
+	if got := ids(q); len(got) != 2 || got[0] != "a" || got[1] != "b" {
+		t.Errorf("queue after wrap = %v, want [a b]", got)
+	}
+
+	q.SetRepeatMode(api.RepeatOne)
+	if next := q.Next(); next == nil || next.ID != "b" {
+		t.Fatalf("Next under repeat-one = %v, want b", next)
+	}
+	if q.Next() != nil {
+		t.Error("Next past the last track should return nil")
+	}
+	q.Consume()
+	if q.Len() != 0 {
+		t.Errorf("queue after Consume = %v, want empty", ids(q))
+	}
+}
+
+// TestQueue_InsertNext verifies inserted tracks play right after the
+// current one
+func TestQueue_InsertNext(t *testing.T) {
+	q := NewQueue()
+	q.InsertNext(&api.Track{ID: "x"})
+	if got := ids(q); len(got) != 1 || q.Current().ID != "x" {
+		t.Fatalf("insert into empty queue = %v", got)
+	}
+
+	q.Set([]*api.Track{{ID: "a"}, {ID: "b"}, {ID: "c"}})
+	q.JumpTo(1)
+	q.InsertNext(&api.Track{ID: "x"}, &api.Track{ID: "y"})
+	if got := ids(q); len(got) != 5 || got[2] != "x" || got[3] != "y" || got[4] != "c" {
+		t.Errorf("queue = %v, want [a b x y c]", got)
+	}
+	if next := q.Next(); next == nil || next.ID != "x" {
+		t.Errorf("Next = %v, want x", next)
+	}
+}
+
+// TestQueue_SetShuffledSkipsExcluded verifies a shuffled queue starts on a
+// track that shuffling does not leave out
+func TestQueue_SetShuffledSkipsExcluded(t *testing.T) {
+	tracks := []*api.Track{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}
+	q := NewQueue()
+	q.SetShuffleExclude(func(t *api.Track) bool { return t.ID != "c" })
+	for range 50 {
+		q.SetShuffled(tracks)
+		if got := ids(q); len(got) != 1 || got[0] != "c" {
+			t.Fatalf("shuffled queue = %v, want only c", got)
+		}
+	}
+
+	q.SetShuffleExclude(func(*api.Track) bool { return true })
+	q.SetShuffled(tracks)
+	if q.Current() == nil || q.Len() != 1 {
+		t.Errorf("with every track excluded the queue = %v, want the start alone", ids(q))
+	}
+}
+
+// TestQueue_ShuffleExclude verifies Shuffle leaves excluded tracks out but
+// keeps the current one, and Unshuffle brings them all back in order
+func TestQueue_ShuffleExclude(t *testing.T) {
+	q := NewQueue()
+	q.Set([]*api.Track{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}, {ID: "e"}})
+	q.SetShuffleExclude(func(t *api.Track) bool { return t.ID == "b" || t.ID == "d" })
+	q.JumpTo(1)
+	q.Shuffle()
+
+	got := ids(q)
+	if len(got) != 4 || got[0] != "b" || q.Current().ID != "b" {
+		t.Fatalf("shuffled = %v, want the current b first and d left out", got)
+	}
+	for _, id := range got {
+		if id == "d" {
+			t.Errorf("excluded track in the shuffle: %v", got)
+		}
+	}
+
+	q.Unshuffle()
+	if got := ids(q); len(got) != 5 || got[3] != "d" || q.Current().ID != "b" {
+		t.Errorf("unshuffled = %v at %s, want all five at b", got, q.Current().ID)
+	}
+
+	q.SetShuffleExclude(nil)
+	q.Shuffle()
+	if q.Len() != 5 {
+		t.Errorf("shuffle without an exclude kept %d of 5", q.Len())
+	}
+}
+
+// changes counts the queue change events published to it
+type changes int
+
+func (c *changes) Publish(e api.AudioEvent) {
+	if e.Type == api.EventQueueChanged {
+		*c++
+	}
+}
+
+// TestQueue_PublishesChanges verifies edits to the tracks are announced
+// once each, and moving through the queue is not
+func TestQueue_PublishesChanges(t *testing.T) {
+	var got changes
+	q := NewQueue()
+	q.SetPublisher(&got)
+
+	q.SetShuffled([]*api.Track{{ID: "a"}, {ID: "b"}, {ID: "c"}})
+	q.Add(&api.Track{ID: "d"})
+	q.Move(3, 1)
+	q.Remove(0)
+	q.Next()
+	q.JumpTo(0)
+	if got != 4 {
+		t.Errorf("published %d changes, want 4", got)
+	}
+}
@@ -0,0 +1,181 @@
+package playlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/store"
+)
+
+// SavedQueueVersion is the schema version of saved queue files
+const SavedQueueVersion = 1
+
+// savedQueueMigrations upgrade older saved queue files, see store.Migration
+var savedQueueMigrations = map[int]store.Migration{}
+
+// SavedQueue is a queue written to disk: its order, shuffle, repeat and
+// consume/party state, and where playback was in the current track
+type SavedQueue struct {
+	Version  int            `json:"version"`
+	Name     string         `json:"name"`
+	Tracks   []api.Track    `json:"tracks"`
+	Original []api.Track    `json:"original,omitempty"` // order before shuffling
+	Index    int            `json:"index"`
+	Shuffled bool           `json:"shuffled"`
+	Repeat   api.RepeatMode `json:"repeat"`
+	Consume  bool           `json:"consume,omitempty"`
+	Party    bool           `json:"party,omitempty"`
+	Position time.Duration  `json:"position"`
+	SavedAt  time.Time      `json:"saved_at"`
+}
+
+// Saved captures the queue under name; pos is how far into the current
+// track playback is
+func (q *Queue) Saved(name string, pos time.Duration) *SavedQueue {
+	sp := q.SavePoint(pos)
+	sq := &SavedQueue{
+		Version:  SavedQueueVersion,
+		Name:     name,
+		Index:    sp.index,
+		Shuffled: sp.shuffle,
+		Repeat:   q.GetRepeatMode(),
+		Consume:  q.IsConsume(),
+		Party:    q.IsParty(),
+		Position: pos,
+		SavedAt:  time.Now(),
+	}
+	for _, t := range sp.tracks {
+		sq.Tracks = append(sq.Tracks, *t)
+	}
+	for _, t := range sp.original {
+		sq.Original = append(sq.Original, *t)
+	}
+	return sq
+}
+
+// Load replaces the queue with a saved one, including its repeat, consume
+// and party modes
+func (q *Queue) Load(sq *SavedQueue) {
+	sp := &SavePoint{index: sq.Index, shuffle: sq.Shuffled, Position: sq.Position}
+	for i := range sq.Tracks {
+		sp.tracks = append(sp.tracks, &sq.Tracks[i])
+	}
+	for i := range sq.Original {
+		sp.original = append(sp.original, &sq.Original[i])
+	}
+	if sp.index < 0 || sp.index >= len(sp.tracks) {
+		sp.index = 0
+	}
+	q.Restore(sp)
+	q.SetRepeatMode(sq.Repeat)
+	q.SetConsume(sq.Consume)
+	q.SetParty(sq.Party)
+}
+
+// SaveQueueFile writes a saved queue to path
+func SaveQueueFile(path string, sq *SavedQueue) error {
+	data, err := json.MarshalIndent(sq, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal queue: %w", err)
+	}
+	if err := store.WriteFile(path, data); err != nil {
+		return fmt.Errorf("write queue file: %w", err)
+	}
+	return nil
+}
+
+// LoadQueueFile reads a saved queue. An error wrapping os.ErrNotExist means
+// there is none.
+func LoadQueueFile(path string) (*SavedQueue, error) {
+	var sq SavedQueue
+	if err := store.Load(path, SavedQueueVersion, savedQueueMigrations, &sq); err != nil {
+		return nil, fmt.Errorf("load queue file: %w", err)
+	}
+	return &sq, nil
+}
+
+// QueueStore keeps named saved queues, one file each in a directory
+type QueueStore struct {
+	dir string
+}
+
+// NewQueueStore creates a store saving into dir
+func NewQueueStore(dir string) *QueueStore {
+	return &QueueStore{dir: dir}
+}
+
+// path returns the file a queue name is saved in
+func (s *QueueStore) path(name string) string {
+	return filepath.Join(s.dir, url.PathEscape(name)+".json")
+}
+
+// Save writes sq under its name, replacing a queue saved with that name
+func (s *QueueStore) Save(sq *SavedQueue) error {
+	if strings.TrimSpace(sq.Name) == "" {
+		return errors.New("save queue: empty name")
+	}
+	return SaveQueueFile(s.path(sq.Name), sq)
+}
+
+// Load reads the queue saved under name
+func (s *QueueStore) Load(name string) (*SavedQueue, error) {
+	return LoadQueueFile(s.path(name))
+}
+
+// Delete removes the queue saved under name
+func (s *QueueStore) Delete(name string) error {
+	if err := store.Remove(s.path(name)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("delete saved queue: %w", err)
+	}
+	return nil
+}
+
+// List returns the saved queues, most recently saved first. Unreadable
+// files are skipped.
+func (s *QueueStore) List() ([]*SavedQueue, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read queue directory: %w", err)
+	}
+	var out []*SavedQueue
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		sq, err := LoadQueueFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			continue
+		}
+		out = append(out, sq)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SavedAt.After(out[j].SavedAt) })
+	return out, nil
+}
+
+// SaveTo saves the queue in s under name; pos is how far into the current
+// track playback is
+func (q *Queue) SaveTo(s *QueueStore, name string, pos time.Duration) error {
+	return s.Save(q.Saved(name, pos))
+}
+
+// LoadFrom replaces the queue with the one saved in s under name and
+// returns where playback was in its current track
+func (q *Queue) LoadFrom(s *QueueStore, name string) (time.Duration, error) {
+	sq, err := s.Load(name)
+	if err != nil {
+		return 0, err
+	}
+	q.Load(sq)
+	return sq.Position, nil
+}
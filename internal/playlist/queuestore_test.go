@@ -0,0 +1,37 @@
+package playlist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestQueueStore_RoundTrip verifies a named queue comes back with its order,
+// current entry, repeat mode and position
+func TestQueueStore_RoundTrip(t *testing.T) {
+	s := NewQueueStore(t.TempDir())
+	q := NewQueue()
+	q.Set([]*api.Track{{ID: "a"}, {ID: "b"}, {ID: "c"}})
+	q.JumpTo(1)
+	q.SetRepeatMode(api.RepeatAll)
+	if err := q.SaveTo(s, "road trip/2", 42*time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewQueue()
+	pos, err := restored.LoadFrom(s, "road trip/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos != 42*time.Second || restored.Len() != 3 || restored.Current().ID != "b" ||
+		restored.GetRepeatMode() != api.RepeatAll {
+		t.Errorf("restored pos %v, %d tracks, current %v, repeat %v",
+			pos, restored.Len(), restored.Current(), restored.GetRepeatMode())
+	}
+
+	list, err := s.List()
+	if err != nil || len(list) != 1 || list[0].Name != "road trip/2" {
+		t.Errorf("List() = %v, %v", list, err)
+	}
+}
@@ -0,0 +1,72 @@
+package playlist
+
+import (
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/store"
+)
+
+// TestReadOnly_LeavesDataDir verifies a read-only instance neither writes
+// nor deletes playlists and saved queues
+func TestReadOnly_LeavesDataDir(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManager(filepath.Join(dir, "playlists"))
+	mix, err := m.Create("Mix", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	queues := NewQueueStore(filepath.Join(dir, "queues"))
+	q := NewQueue()
+	q.Set([]*api.Track{{ID: "a"}})
+	if err := q.SaveTo(queues, "evening", 0); err != nil {
+		t.Fatal(err)
+	}
+	before := snapshotDir(t, dir)
+
+	store.SetReadOnly(true)
+	t.Cleanup(func() { store.SetReadOnly(false) })
+
+	if _, err := m.Create("Other", ""); !errors.Is(err, store.ErrReadOnly) {
+		t.Errorf("Create = %v, want ErrReadOnly", err)
+	}
+	if err := m.AddTrack(mix.ID, &api.Track{ID: "a"}); !errors.Is(err, store.ErrReadOnly) {
+		t.Errorf("AddTrack = %v, want ErrReadOnly", err)
+	}
+	if err := m.Delete(mix.ID); !errors.Is(err, store.ErrReadOnly) {
+		t.Errorf("Delete = %v, want ErrReadOnly", err)
+	}
+	if err := q.SaveTo(queues, "evening", 0); !errors.Is(err, store.ErrReadOnly) {
+		t.Errorf("saving a queue = %v, want ErrReadOnly", err)
+	}
+	if err := queues.Delete("evening"); !errors.Is(err, store.ErrReadOnly) {
+		t.Errorf("deleting a queue = %v, want ErrReadOnly", err)
+	}
+
+	if after := snapshotDir(t, dir); !maps.Equal(before, after) {
+		t.Errorf("data directory changed while read-only:\nbefore %v\nafter  %v", before, after)
+	}
+}
+
+// snapshotDir returns the contents of the files under dir by path
+func snapshotDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		files[path] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
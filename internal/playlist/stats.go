@@ -0,0 +1,175 @@
+package playlist
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/library"
+)
+
+// Count is a label with the number of tracks that carry it
+type Count struct {
+	Label string
+	Count int
+}
+
+// Stats summarizes the contents of a playlist
+type Stats struct {
+	TrackCount int
+	Duration   time.Duration
+	Artists    int
+	Albums     int
+	Genres     []Count // most common first; untagged tracks count as "Unknown"
+	Decades    []Count // chronological, e.g. "1990s"; undated tracks count as "Unknown"
+}
+
+// Overlap is a track that appears in more than one playlist
+type Overlap struct {
+	Track     api.Track
+	Playlists []string // names of the playlists containing the track
+}
+
+// ComputeStats builds statistics for a playlist
+func ComputeStats(playlist *api.Playlist) Stats {
+	tracks := make([]*api.Track, len(playlist.Tracks))
+	for i := range playlist.Tracks {
+		tracks[i] = &playlist.Tracks[i]
+	}
+	return TrackStats(tracks)
+}
+
+// TrackStats builds statistics for a list of tracks such as the queue
+func TrackStats(tracks []*api.Track) Stats {
+	stats := Stats{TrackCount: len(tracks)}
+	artists := make(map[string]bool)
+	albums := make(map[string]bool)
+	genres := make(map[string]int)
+	decades := make(map[string]int)
+
+	for _, t := range tracks {
+		stats.Duration += t.Duration
+		if t.Artist != "" {
+			artists[strings.ToLower(t.Artist)] = true
+		}
+		if t.Album != "" {
+			albums[library.AlbumID(t)] = true
+		}
+
+		tagged := library.SplitGenres(t.Genre)
+		if len(tagged) == 0 {
+			tagged = []string{"Unknown"}
+		}
+		for _, genre := range tagged {
+			genres[genre]++
+		}
+
+		decade := "Unknown"
+		if t.Year > 0 {
+			decade = fmtDecade(t.Year)
+		}
+		decades[decade]++
+	}
+
+	stats.Artists = len(artists)
+	stats.Albums = len(albums)
+	stats.Genres = sortedCounts(genres, func(a, b Count) bool {
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Label < b.Label
+	})
+	stats.Decades = sortedCounts(decades, func(a, b Count) bool {
+		// "Unknown" sorts after every decade
+		if (a.Label == "Unknown") != (b.Label == "Unknown") {
+			return b.Label == "Unknown"
+		}
+		return a.Label < b.Label
+	})
+	return stats
+}
+
+// Summary gives the length and variety of the tracks in one line, e.g.
+// "3h 42m, 54 tracks, 12 artists, 9 albums"
+func (s Stats) Summary() string {
+	length := fmt.Sprintf("%dm", int(s.Duration.Minutes()))
+	if s.Duration >= time.Hour {
+		length = fmt.Sprintf("%dh %02dm", int(s.Duration.Hours()), int(s.Duration.Minutes())%60)
+	}
+	count := func(n int, noun string) string {
+		if n == 1 {
+			return "1 " + noun
+		}
+		return fmt.Sprintf("%d %ss", n, noun)
+	}
+	return strings.Join([]string{
+		length, count(s.TrackCount, "track"), count(s.Artists, "artist"), count(s.Albums, "album"),
+	}, ", ")
+}
+
+// Stats returns statistics for the playlist with the given ID
+func (m *Manager) Stats(playlistID string) (Stats, error) {
+	playlist, err := m.GetByID(playlistID)
+	if err != nil {
+		return Stats{}, err
+	}
+	return ComputeStats(playlist), nil
+}
+
+// Overlaps reports tracks found in two or more playlists, most shared first.
+// Tracks are matched by ID, so the same file is recognized across playlists.
+func (m *Manager) Overlaps() []Overlap {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	byTrack := make(map[string]*Overlap)
+	for _, pl := range m.playlists {
+		seen := make(map[string]bool)
+		for _, t := range pl.Tracks {
+			if seen[t.ID] {
+				continue // duplicates inside one playlist are not overlap
+			}
+			seen[t.ID] = true
+			o := byTrack[t.ID]
+			if o == nil {
+				o = &Overlap{Track: t}
+				byTrack[t.ID] = o
+			}
+			o.Playlists = append(o.Playlists, pl.Name)
+		}
+	}
+
+	var out []Overlap
+	for _, o := range byTrack {
+		if len(o.Playlists) > 1 {
+			sort.Strings(o.Playlists)
+			out = append(out, *o)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if len(out[i].Playlists) != len(out[j].Playlists) {
+			return len(out[i].Playlists) > len(out[j].Playlists)
+		}
+		if out[i].Track.Artist != out[j].Track.Artist {
+			return out[i].Track.Artist < out[j].Track.Artist
+		}
+		return out[i].Track.Title < out[j].Track.Title
+	})
+	return out
+}
+
+// fmtDecade formats a year as its decade, e.g. 1994 -> "1990s"
+func fmtDecade(year int) string {
+	return fmt.Sprintf("%ds", year/10*10)
+}
+
+func sortedCounts(m map[string]int, less func(a, b Count) bool) []Count {
+	out := make([]Count, 0, len(m))
+	for label, n := range m {
+		out = append(out, Count{Label: label, Count: n})
+	}
+	sort.Slice(out, func(i, j int) bool { return less(out[i], out[j]) })
+	return out
+}
@@ -0,0 +1,28 @@
+package playlist
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestTrackStats_Summary verifies the duration and distinct artist and album counts
+func TestTrackStats_Summary(t *testing.T) {
+	tracks := []*api.Track{
+		{Title: "Airbag", Artist: "Radiohead", Album: "OK Computer", AlbumArtist: "Radiohead", Duration: 284 * time.Second},
+		{Title: "Lucky", Artist: "radiohead", Album: "OK Computer", AlbumArtist: "Radiohead", Duration: 259 * time.Second},
+		{Title: "Teardrop", Artist: "Massive Attack", Album: "Mezzanine", AlbumArtist: "Massive Attack", Duration: 2*time.Hour + 31*time.Minute},
+		{Title: "Untagged", FilePath: "/music/untagged.mp3"},
+	}
+	st := TrackStats(tracks)
+	if st.TrackCount != 4 || st.Artists != 2 || st.Albums != 2 {
+		t.Errorf("stats = %+v, want 4 tracks, 2 artists, 2 albums", st)
+	}
+	if got, want := st.Summary(), "2h 40m, 4 tracks, 2 artists, 2 albums"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+	if got, want := TrackStats(tracks[1:2]).Summary(), "4m, 1 track, 1 artist, 1 album"; got != want {
+		t.Errorf("Summary() = %q, want %q", got, want)
+	}
+}
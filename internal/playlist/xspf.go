@@ -0,0 +1,115 @@
+package playlist
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// xspfNamespace is the XML namespace of XSPF version 1
+const xspfNamespace = "http://xspf.org/ns/0/"
+
+// xspfPlaylist mirrors the subset of XSPF (https://xspf.org) used here
+type xspfPlaylist struct {
+	XMLName    xml.Name    `xml:"playlist"`
+	Version    string      `xml:"version,attr"`
+	Xmlns      string      `xml:"xmlns,attr"`
+	Title      string      `xml:"title,omitempty"`
+	Creator    string      `xml:"creator,omitempty"`
+	Annotation string      `xml:"annotation,omitempty"`
+	Date       string      `xml:"date,omitempty"`
+	Tracks     []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location   []string `xml:"location"`
+	Title      string   `xml:"title,omitempty"`
+	Creator    string   `xml:"creator,omitempty"`
+	Album      string   `xml:"album,omitempty"`
+	Annotation string   `xml:"annotation,omitempty"`
+	TrackNum   int      `xml:"trackNum,omitempty"`
+	Duration   int64    `xml:"duration,omitempty"` // milliseconds
+}
+
+// parseXSPF reads an XSPF document; the first location of each track is used
+func parseXSPF(r io.Reader) (document, error) {
+	var pl xspfPlaylist
+	if err := xml.NewDecoder(r).Decode(&pl); err != nil {
+		return document{}, fmt.Errorf("read xspf: %w", err)
+	}
+
+	doc := document{title: pl.Title, creator: pl.Creator, annotation: pl.Annotation}
+	for _, t := range pl.Tracks {
+		if len(t.Location) == 0 {
+			continue
+		}
+		doc.entries = append(doc.entries, entry{
+			path:     strings.TrimSpace(t.Location[0]),
+			title:    t.Title,
+			artist:   t.Creator,
+			album:    t.Album,
+			duration: time.Duration(t.Duration) * time.Millisecond,
+		})
+	}
+	return doc, nil
+}
+
+// writeXSPF writes a playlist as XSPF with file:// locations
+func writeXSPF(w io.Writer, playlist *api.Playlist) error {
+	pl := xspfPlaylist{
+		Version:    "1",
+		Xmlns:      xspfNamespace,
+		Title:      playlist.Name,
+		Creator:    playlist.Creator,
+		Annotation: playlist.Description,
+		Date:       playlist.UpdatedAt.Format(time.RFC3339),
+	}
+	for _, t := range playlist.Tracks {
+		pl.Tracks = append(pl.Tracks, xspfTrack{
+			Location: []string{pathToFileURI(t.FilePath)},
+			Title:    t.Title,
+			Creator:  t.Artist,
+			Album:    t.Album,
+			TrackNum: t.TrackNum,
+			Duration: t.Duration.Milliseconds(),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(pl); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// pathToFileURI converts a local path to a file:// URI; URLs pass through
+func pathToFileURI(p string) string {
+	if strings.Contains(p, "://") {
+		return p
+	}
+	if abs, err := filepath.Abs(p); err == nil {
+		p = abs
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(p)}
+	return u.String()
+}
+
+// fileURIToPath converts a file:// URI back to a local path
+func fileURIToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
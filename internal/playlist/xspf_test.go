@@ -0,0 +1,136 @@
+package playlist
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseXSPF verifies the playlist's title, creator and annotation and
+// each track's first location and metadata are read, and tracks without a
+// location are dropped
+func TestParseXSPF(t *testing.T) {
+	in := `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <title>Late Night</title>
+  <creator>Ann</creator>
+  <annotation>For the drive home</annotation>
+  <trackList>
+    <track>
+      <location> file:///music/Massive%20Attack/teardrop.mp3 </location>
+      <location>http://example.com/teardrop.mp3</location>
+      <title>Teardrop</title>
+      <creator>Massive Attack</creator>
+      <album>Mezzanine</album>
+      <duration>329500</duration>
+    </track>
+    <track>
+      <title>Nowhere</title>
+    </track>
+    <track>
+      <location>relative/song.ogg</location>
+    </track>
+  </trackList>
+</playlist>`
+	doc, err := parseXSPF(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.title != "Late Night" || doc.creator != "Ann" || doc.annotation != "For the drive home" {
+		t.Errorf("playlist = %q by %q, %q", doc.title, doc.creator, doc.annotation)
+	}
+	want := []entry{
+		{path: "file:///music/Massive%20Attack/teardrop.mp3", title: "Teardrop", artist: "Massive Attack",
+			album: "Mezzanine", duration: 329500 * time.Millisecond},
+		{path: "relative/song.ogg"},
+	}
+	if len(doc.entries) != len(want) {
+		t.Fatalf("got %+v, want %+v", doc.entries, want)
+	}
+	for i := range want {
+		if doc.entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, doc.entries[i], want[i])
+		}
+	}
+
+	if _, err := parseXSPF(strings.NewReader("<playlist><trackList>")); err == nil {
+		t.Error("expected an error for a truncated document")
+	}
+}
+
+// TestXSPF_RoundTrip verifies an imported XSPF playlist exports and
+// imports again with its metadata, and with paths that need escaping in a
+// file URI
+func TestXSPF_RoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	in := `<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <title>Björk &amp; friends</title>
+  <creator>Ann</creator>
+  <annotation>Mixed for a rainy day</annotation>
+  <trackList>
+    <track>
+      <location>Homogenic/02 Jóga.flac</location>
+      <title>Jóga</title>
+      <creator>Björk</creator>
+      <album>Homogenic</album>
+      <duration>305000</duration>
+    </track>
+    <track>
+      <location>file:///music/100%25%20Hits/one%23two.mp3</location>
+      <title>One #2</title>
+    </track>
+    <track>
+      <location>http://example.com/radio?id=7</location>
+      <title>Radio</title>
+    </track>
+  </trackList>
+</playlist>`
+	src := filepath.Join(dir, "mix.xspf")
+	if err := os.WriteFile(src, []byte(in), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewManager(t.TempDir())
+	first, skipped, err := m.Import(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if skipped != 0 || first.Name != "Björk & friends" || first.Creator != "Ann" || first.Description != "Mixed for a rainy day" {
+		t.Fatalf("imported %+v with %d skipped", first, skipped)
+	}
+	wantPaths := []string{
+		filepath.Join(dir, "Homogenic", "02 Jóga.flac"),
+		filepath.FromSlash("/music/100% Hits/one#two.mp3"),
+		"http://example.com/radio?id=7",
+	}
+	for i, p := range wantPaths {
+		if first.Tracks[i].FilePath != p {
+			t.Errorf("track %d path = %q, want %q", i, first.Tracks[i].FilePath, p)
+		}
+	}
+
+	out := filepath.Join(t.TempDir(), "again.xspf")
+	if err := m.Export(first.ID, out); err != nil {
+		t.Fatal(err)
+	}
+	again, _, err := NewManager(t.TempDir()).Import(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Name != first.Name || again.Creator != first.Creator || again.Description != first.Description {
+		t.Errorf("round trip = %q by %q, %q", again.Name, again.Creator, again.Description)
+	}
+	if len(again.Tracks) != len(first.Tracks) {
+		t.Fatalf("round trip has %d tracks, want %d", len(again.Tracks), len(first.Tracks))
+	}
+	for i, want := range first.Tracks {
+		g := again.Tracks[i]
+		if g.FilePath != want.FilePath || g.Title != want.Title || g.Artist != want.Artist ||
+			g.Album != want.Album || g.Duration != want.Duration {
+			t.Errorf("track %d = %+v, want %+v", i, g, want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package renderer
+
+import (
+	"context"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/logger"
+	"github.com/jscyril/golang_music_player/pkg/events"
+)
+
+// seekTolerance is how far the local position may move from where
+// playback alone would have taken it before the device is sent a seek
+const seekTolerance = 2 * time.Second
+
+// Follow makes r play what the local player plays, as reported on bus
+// and by state, until ctx is done or the connection ends. Track changes,
+// play, pause, stop and seeks are passed on. It returns why the
+// connection ended, or nil when ctx was cancelled.
+//
+// The device is sent the position the player has decoded, not the one it
+// reports as heard: a delay set for the cast output stands for the device
+// taking that long to play what it is sent, so what it plays is heard
+// where the player says.
+func Follow(ctx context.Context, r Renderer, bus *events.EventBus, state func() *api.PlaybackState) error {
+	sub := bus.SubscribeWith(events.Policy{
+		Types:    []api.EventType{api.EventTrackStarted, api.EventStateChange, api.EventPositionUpdate},
+		Buffer:   64,
+		Lossless: true,
+	})
+	defer bus.Unsubscribe(sub)
+
+	f := follower{r: r}
+	f.sync(state())
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.Done():
+			return r.Err()
+		case ev := <-sub:
+			switch ev.Type {
+			case api.EventTrackStarted:
+				f.sync(state())
+			case api.EventStateChange:
+				if st, ok := ev.Payload.(*api.PlaybackState); ok {
+					f.sync(st)
+				}
+			case api.EventPositionUpdate:
+				if p, ok := ev.Payload.(api.ProgressPayload); ok {
+					f.mark(p.Position + p.OutputDelay)
+				}
+			}
+		}
+	}
+}
+
+// follower is what the device was last told to do
+type follower struct {
+	r      Renderer
+	track  string // ID of the loaded track, "" if none
+	status api.PlayerStatus
+	pos    time.Duration // local position at
+	at     time.Time
+}
+
+// mark records the local position
+func (f *follower) mark(pos time.Duration) {
+	f.pos, f.at = pos, time.Now()
+}
+
+// expected returns where the device should be if nothing but playback
+// moved it since the last mark
+func (f *follower) expected() time.Duration {
+	if f.status != api.StatusPlaying {
+		return f.pos
+	}
+	return f.pos + time.Since(f.at)
+}
+
+// sync brings the device in line with st. Streams are not sent, since
+// the device cannot reach the server's stream with the player's token.
+func (f *follower) sync(st *api.PlaybackState) {
+	t := st.CurrentTrack
+	if t == nil || t.FilePath == "" || st.Status == api.StatusStopped {
+		if f.track != "" {
+			f.warn("stop", f.r.Stop())
+		}
+		f.track, f.status = "", api.StatusStopped
+		return
+	}
+
+	pos := st.Position + st.OutputDelay
+	if t.ID != f.track {
+		f.track, f.status = t.ID, api.StatusPlaying
+		f.mark(pos)
+		if err := f.r.Play(t); err != nil {
+			f.warn("play "+t.Title, err)
+			return
+		}
+		// Joining a track part way, e.g. when playback is first sent
+		if pos > seekTolerance {
+			f.warn("seek", f.r.Seek(pos))
+		}
+	} else if drift := pos - f.expected(); drift > seekTolerance || drift < -seekTolerance {
+		f.warn("seek", f.r.Seek(pos))
+	}
+	f.mark(pos)
+	if st.Status != f.status {
+		f.status = st.Status
+		if st.Status == api.StatusPlaying {
+			f.warn("resume", f.r.Resume())
+		} else {
+			f.warn("pause", f.r.Pause())
+		}
+	}
+}
+
+// warn logs a failed command; the device carries on with the next one
+func (f *follower) warn(what string, err error) {
+	if err != nil {
+		logger.Warn("%s %s: %s: %v", f.r.Device().Kind, f.r.Device().Name, what, err)
+	}
+}
@@ -0,0 +1,53 @@
+package renderer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// recorder is a Renderer that records the commands it is sent
+type recorder struct {
+	calls []string
+	done  chan struct{}
+}
+
+func (r *recorder) record(format string, args ...interface{}) error {
+	r.calls = append(r.calls, fmt.Sprintf(format, args...))
+	return nil
+}
+
+func (r *recorder) Play(t *api.Track) error      { return r.record("play %s", t.ID) }
+func (r *recorder) Pause() error                 { return r.record("pause") }
+func (r *recorder) Resume() error                { return r.record("resume") }
+func (r *recorder) Stop() error                  { return r.record("stop") }
+func (r *recorder) Seek(p time.Duration) error   { return r.record("seek %v", p) }
+func (r *recorder) SetVolume(float64) error      { return nil }
+func (r *recorder) GetState() *api.PlaybackState { return &api.PlaybackState{} }
+func (r *recorder) Device() Device               { return Device{Name: "TV", Kind: "test"} }
+func (r *recorder) Close() error                 { return nil }
+func (r *recorder) Done() <-chan struct{}        { return r.done }
+func (r *recorder) Err() error                   { return nil }
+
+// TestFollow_SendsDecodedPosition verifies the device is sent the position
+// the player decoded, ahead of the one heard by the output delay, so the
+// device's own delay brings it in line
+func TestFollow_SendsDecodedPosition(t *testing.T) {
+	r := &recorder{}
+	f := follower{r: r}
+	track := &api.Track{ID: "1", FilePath: "/music/a.mp3"}
+
+	f.sync(&api.PlaybackState{CurrentTrack: track, Status: api.StatusPlaying,
+		Position: 30 * time.Second, OutputDelay: 2 * time.Second})
+	f.sync(&api.PlaybackState{CurrentTrack: track, Status: api.StatusPlaying,
+		Position: 90 * time.Second})
+	f.sync(&api.PlaybackState{CurrentTrack: track, Status: api.StatusPaused,
+		Position: 89 * time.Second, OutputDelay: time.Second})
+
+	want := []string{"play 1", "seek 32s", "seek 1m30s", "pause"}
+	if fmt.Sprint(r.calls) != fmt.Sprint(want) {
+		t.Errorf("calls = %v, want %v", r.calls, want)
+	}
+}
@@ -0,0 +1,62 @@
+// Package renderer sends playback to devices on the network, such as
+// Chromecasts and DLNA renderers. The file being played is served from
+// this machine, and the device follows the local player's track changes,
+// play, pause and seeks.
+package renderer
+
+import (
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// Device is a renderer found on the network
+type Device struct {
+	Name     string // friendly name, e.g. "Living Room TV"
+	Model    string
+	Kind     string // protocol, e.g. "Chromecast" or "DLNA"
+	Addr     string // host:port the device is reached at
+	Location string // description URL, for protocols that have one
+}
+
+// Renderer is a connection to a device that plays tracks. Play loads a
+// track from the file server and starts it; the other transport controls
+// act on the loaded track, and GetState reports what the device last said
+// it was doing.
+type Renderer interface {
+	api.Player
+	Device() Device
+	// Close stops playback on the device and disconnects
+	Close() error
+	// Done is closed when the connection has ended; Err then says why,
+	// or is nil after Close
+	Done() <-chan struct{}
+	Err() error
+}
+
+// Media is a track as published for a device
+type Media struct {
+	URL         string
+	ContentType string
+	Title       string
+	Artist      string
+	Album       string
+	Duration    time.Duration
+}
+
+// Publish makes t the file srv serves and describes it for the device at
+// addr
+func Publish(srv *Server, t *api.Track, addr string) (Media, error) {
+	url, err := srv.Publish(t.FilePath, addr)
+	if err != nil {
+		return Media{}, err
+	}
+	return Media{
+		URL:         url,
+		ContentType: ContentType(t.FilePath),
+		Title:       t.Title,
+		Artist:      t.Artist,
+		Album:       t.Album,
+		Duration:    t.Duration,
+	}, nil
+}
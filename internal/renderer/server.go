@@ -0,0 +1,124 @@
+package renderer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server serves the file a device is playing. Only the most recently
+// published file is reachable, under a random path, so the rest of the
+// disk is never exposed.
+type Server struct {
+	ln   net.Listener
+	srv  *http.Server
+	mu   sync.Mutex
+	path string // file being served
+	key  string // random path segment of its URL
+}
+
+// NewServer listens on port on every interface; 0 picks a free port
+func NewServer(port int) (*Server, error) {
+	ln, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(port)))
+	if err != nil {
+		return nil, fmt.Errorf("start renderer file server: %w", err)
+	}
+	s := &Server{ln: ln}
+	s.srv = &http.Server{Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	go s.srv.Serve(ln)
+	return s, nil
+}
+
+// Publish makes path the file served and returns its URL for a device at
+// deviceAddr, using the local address that reaches the device
+func (s *Server) Publish(path, deviceAddr string) (string, error) {
+	host, err := LocalAddr(deviceAddr)
+	if err != nil {
+		return "", err
+	}
+	key := make([]byte, 16)
+	rand.Read(key)
+
+	s.mu.Lock()
+	s.path, s.key = path, hex.EncodeToString(key)
+	s.mu.Unlock()
+
+	port := s.ln.Addr().(*net.TCPAddr).Port
+	u := url.URL{
+		Scheme: "http",
+		Host:   net.JoinHostPort(host, strconv.Itoa(port)),
+		Path:   "/" + s.key + "/" + filepath.Base(path),
+	}
+	return u.String(), nil
+}
+
+// ServeHTTP serves the published file, with range requests for seeking
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	path, key := s.path, s.key
+	s.mu.Unlock()
+
+	got, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if key == "" || got != key {
+		http.NotFound(w, r)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, "file unavailable", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "file unavailable", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType(path))
+	// The receiver's web player loads media from another origin
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
+
+// Close stops the server
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+// ContentType returns the MIME type a receiver expects for the audio
+// file at path
+func ContentType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		return "audio/mpeg"
+	case ".flac":
+		return "audio/flac"
+	case ".wav":
+		return "audio/wav"
+	}
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// LocalAddr returns the IP of the interface this machine uses to reach
+// addr. No packets are sent.
+func LocalAddr(addr string) (string, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return "", fmt.Errorf("find local address for %s: %w", addr, err)
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
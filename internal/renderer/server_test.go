@@ -0,0 +1,47 @@
+package renderer
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestServerPublish verifies only the published file is served
+func TestServerPublish(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song.mp3")
+	os.WriteFile(path, []byte("ID3 data"), 0644)
+
+	srv, err := NewServer(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	url, err := srv.Publish(path, "127.0.0.1:8009")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ID3 data" || resp.Header.Get("Content-Type") != "audio/mpeg" {
+		t.Errorf("got %q as %s", body, resp.Header.Get("Content-Type"))
+	}
+
+	port := srv.ln.Addr().(*net.TCPAddr).Port
+	resp, err = http.Get("http://" + net.JoinHostPort("127.0.0.1", strconv.Itoa(port)) + "/guess/song.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unpublished path answered %d", resp.StatusCode)
+	}
+}
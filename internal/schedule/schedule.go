@@ -0,0 +1,236 @@
+// Package schedule works out when alarms go off and when quiet hours
+// begin. It keeps no timers of its own: the UI, or the player running
+// without it, calls Update on each tick and acts on what it returns, so a
+// schedule only runs while the player does.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// missedGrace is how late an alarm may still go off, e.g. when the machine
+// wakes from suspend just after it was due; later ones are dropped
+const missedGrace = 10 * time.Minute
+
+// Clock is a time of day, in local time
+type Clock struct {
+	Hour, Minute int
+}
+
+// ParseClock reads a 24-hour time such as "07:30" or "7:30"
+func ParseClock(s string) (Clock, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(s), ":")
+	hour, err1 := strconv.Atoi(h)
+	minute, err2 := strconv.Atoi(m)
+	if !ok || err1 != nil || err2 != nil || hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return Clock{}, fmt.Errorf("time %q: want HH:MM", s)
+	}
+	return Clock{hour, minute}, nil
+}
+
+// String formats c as ParseClock reads it
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", c.Hour, c.Minute)
+}
+
+// on is c on the day of t
+func (c Clock) on(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), c.Hour, c.Minute, 0, 0, t.Location())
+}
+
+func (c Clock) minutes() int {
+	return c.Hour*60 + c.Minute
+}
+
+// Days is a set of weekdays; the empty set means every day
+type Days uint8
+
+var dayNames = map[string]Days{
+	"weekdays": 1<<time.Monday | 1<<time.Tuesday | 1<<time.Wednesday | 1<<time.Thursday | 1<<time.Friday,
+	"weekends": 1<<time.Saturday | 1<<time.Sunday,
+	"daily":    0,
+}
+
+func init() {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		dayNames[name] = 1 << d
+		dayNames[name[:3]] = 1 << d
+	}
+}
+
+// ParseDays reads day names such as "mon", "friday", "weekdays",
+// "weekends" or "daily"; no names at all mean every day
+func ParseDays(names []string) (Days, error) {
+	var days Days
+	for _, name := range names {
+		d, ok := dayNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return 0, fmt.Errorf("unknown day %q", name)
+		}
+		if d == 0 {
+			return 0, nil
+		}
+		days |= d
+	}
+	return days, nil
+}
+
+// Has reports whether d is one of the days
+func (days Days) Has(d time.Weekday) bool {
+	return days == 0 || days&(1<<d) != 0
+}
+
+// String lists the days, e.g. "weekdays" or "Mon, Wed"
+func (days Days) String() string {
+	switch days {
+	case 0, 1<<7 - 1:
+		return "every day"
+	case dayNames["weekdays"]:
+		return "weekdays"
+	case dayNames["weekends"]:
+		return "weekends"
+	}
+	var names []string
+	for _, d := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		if days&(1<<d) != 0 {
+			names = append(names, d.String()[:3])
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// Alarm starts a playlist at a time of day, fading the volume in over Ramp
+type Alarm struct {
+	Name     string
+	At       Clock
+	Days     Days
+	Playlist string  // playlist name or ID
+	Shuffle  bool    // shuffle the playlist
+	Volume   float64 // volume to ramp up to, 0 to 1; 0 keeps the current one
+	Ramp     time.Duration
+	Disabled bool
+}
+
+// Label is the alarm's name, or its time if it has none
+func (a Alarm) Label() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return a.At.String()
+}
+
+// Next returns when the alarm next goes off after t
+func (a Alarm) Next(t time.Time) time.Time {
+	for i := 0; i <= 7; i++ {
+		at := a.At.on(t.AddDate(0, 0, i))
+		if at.After(t) && a.Days.Has(at.Weekday()) {
+			return at
+		}
+	}
+	return time.Time{}
+}
+
+// Ramp fades the volume in from silence to To over Length, from Start
+type Ramp struct {
+	To     float64
+	Start  time.Time
+	Length time.Duration // 0 when no ramp is running
+}
+
+// Running reports whether the ramp is still to reach its volume
+func (r Ramp) Running() bool {
+	return r.Length > 0
+}
+
+// Volume returns the volume at t and whether the ramp has reached To
+func (r Ramp) Volume(t time.Time) (volume float64, done bool) {
+	if r.Length <= 0 || t.Sub(r.Start) >= r.Length {
+		return r.To, true
+	}
+	return r.To * max(float64(t.Sub(r.Start))/float64(r.Length), 0), false
+}
+
+// Quiet is a period in which nothing should play. A period ending before
+// it starts runs past midnight; Days are the days it starts on.
+type Quiet struct {
+	Start, End Clock
+	Days       Days
+}
+
+// Active reports whether t falls within the quiet hours
+func (q Quiet) Active(t time.Time) bool {
+	start, end := q.Start.on(t), q.End.on(t)
+	if q.Start.minutes() < q.End.minutes() {
+		return q.Days.Has(t.Weekday()) && !t.Before(start) && t.Before(end)
+	}
+	if !t.Before(start) && q.Days.Has(t.Weekday()) {
+		return true
+	}
+	return t.Before(end) && q.Days.Has(t.AddDate(0, 0, -1).Weekday())
+}
+
+// String describes the period, e.g. "22:00–07:00 weekdays"
+func (q Quiet) String() string {
+	return fmt.Sprintf("%s–%s %s", q.Start, q.End, q.Days)
+}
+
+// Scheduler tells alarms and quiet hours apart from one tick to the next
+type Scheduler struct {
+	Alarms []Alarm
+	Quiet  []Quiet
+
+	last  time.Time // time of the previous Update
+	quiet bool      // quiet hours were active at the previous Update
+}
+
+// New creates a scheduler for the alarms and quiet hours
+func New(alarms []Alarm, quiet []Quiet) *Scheduler {
+	return &Scheduler{Alarms: alarms, Quiet: quiet}
+}
+
+// Update returns the alarms due since the previous call and whether quiet
+// hours began in the meantime. The first call only notes the time, so
+// starting the player neither sets off an alarm nor stops it for quiet
+// hours already under way. Alarms more than a few minutes late are skipped.
+func (s *Scheduler) Update(now time.Time) (due []Alarm, quietBegan bool) {
+	quiet := s.QuietNow(now)
+	if s.last.IsZero() || now.Before(s.last) {
+		s.last, s.quiet = now, quiet
+		return nil, false
+	}
+	for _, a := range s.Alarms {
+		if a.Disabled {
+			continue
+		}
+		if at := a.Next(s.last); !at.IsZero() && !at.After(now) && now.Sub(at) <= missedGrace {
+			due = append(due, a)
+		}
+	}
+	quietBegan = quiet && !s.quiet
+	s.last, s.quiet = now, quiet
+	return due, quietBegan
+}
+
+// Waiting reports whether any alarm is still to go off
+func (s *Scheduler) Waiting() bool {
+	for _, a := range s.Alarms {
+		if !a.Disabled {
+			return true
+		}
+	}
+	return false
+}
+
+// QuietNow reports whether any quiet hours are active at t
+func (s *Scheduler) QuietNow(t time.Time) bool {
+	for _, q := range s.Quiet {
+		if q.Active(t) {
+			return true
+		}
+	}
+	return false
+}
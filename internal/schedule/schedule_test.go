@@ -0,0 +1,151 @@
+package schedule
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func at(day, hour, minute int) time.Time {
+	// October 2026: the 12th is a Monday
+	return time.Date(2026, 10, day, hour, minute, 0, 0, time.Local)
+}
+
+func TestParse(t *testing.T) {
+	if c, err := ParseClock("7:05"); err != nil || c != (Clock{7, 5}) || c.String() != "07:05" {
+		t.Errorf("ParseClock = %v, %v", c, err)
+	}
+	for _, s := range []string{"", "7", "24:00", "07:60", "seven:30"} {
+		if _, err := ParseClock(s); err == nil {
+			t.Errorf("ParseClock(%q) succeeded", s)
+		}
+	}
+	for names, want := range map[string]string{
+		"weekdays":        "weekdays",
+		"sat,Sunday":      "weekends",
+		"mon,wed":         "Mon, Wed",
+		"daily":           "every day",
+		"weekdays,sat,su": "",
+	} {
+		days, err := ParseDays(strings.Split(names, ","))
+		if want == "" {
+			if err == nil {
+				t.Errorf("ParseDays(%q) succeeded", names)
+			}
+			continue
+		}
+		if err != nil || days.String() != want {
+			t.Errorf("ParseDays(%q) = %v, %v; want %s", names, days, err, want)
+		}
+	}
+}
+
+func TestAlarm_Next(t *testing.T) {
+	weekdays, _ := ParseDays([]string{"weekdays"})
+	a := Alarm{At: Clock{7, 0}, Days: weekdays}
+	// Friday evening: next is Monday morning
+	if got := a.Next(at(16, 20, 0)); !got.Equal(at(19, 7, 0)) {
+		t.Errorf("Next = %v, want Monday 07:00", got)
+	}
+	// Exactly at the alarm time it is already past
+	if got := a.Next(at(13, 7, 0)); !got.Equal(at(14, 7, 0)) {
+		t.Errorf("Next = %v, want Wednesday 07:00", got)
+	}
+}
+
+func TestQuiet_Active(t *testing.T) {
+	fri, _ := ParseDays([]string{"fri"})
+	night := Quiet{Start: Clock{22, 0}, End: Clock{7, 0}, Days: fri}
+	for _, tc := range []struct {
+		t    time.Time
+		want bool
+	}{
+		{at(16, 21, 59), false},
+		{at(16, 22, 0), true},
+		{at(17, 6, 59), true}, // Saturday morning, started Friday
+		{at(17, 7, 0), false},
+		{at(17, 23, 0), false},
+		{at(15, 23, 0), false},
+	} {
+		if got := night.Active(tc.t); got != tc.want {
+			t.Errorf("Active(%v) = %v, want %v", tc.t, got, tc.want)
+		}
+	}
+	lunch := Quiet{Start: Clock{12, 0}, End: Clock{13, 0}}
+	if !lunch.Active(at(18, 12, 30)) || lunch.Active(at(18, 13, 0)) {
+		t.Error("lunch hour wrong")
+	}
+}
+
+func TestScheduler_Update(t *testing.T) {
+	s := New([]Alarm{
+		{Name: "wake", At: Clock{7, 0}},
+		{Name: "off", At: Clock{7, 0}, Disabled: true},
+	}, []Quiet{{Start: Clock{22, 0}, End: Clock{6, 0}}})
+
+	if due, quiet := s.Update(at(12, 23, 0)); due != nil || quiet {
+		t.Fatalf("first update = %v, %v", due, quiet)
+	}
+	if due, quiet := s.Update(at(13, 6, 59)); len(due) != 0 || quiet {
+		t.Fatalf("before the alarm = %v, %v", due, quiet)
+	}
+	due, _ := s.Update(at(13, 7, 0))
+	if len(due) != 1 || due[0].Name != "wake" {
+		t.Fatalf("due = %v, want wake", due)
+	}
+	if due, _ := s.Update(at(13, 7, 1)); len(due) != 0 {
+		t.Fatalf("alarm went off twice: %v", due)
+	}
+
+	// Waking from suspend an hour after the alarm does not set it off
+	s.Update(at(14, 6, 0))
+	if due, _ := s.Update(at(14, 8, 0)); len(due) != 0 {
+		t.Errorf("missed alarm went off: %v", due)
+	}
+
+	if _, quiet := s.Update(at(14, 22, 0)); !quiet {
+		t.Error("quiet hours did not begin")
+	}
+	if _, quiet := s.Update(at(14, 22, 1)); quiet {
+		t.Error("quiet hours began twice")
+	}
+}
+
+// TestRamp verifies the volume rises evenly to its target and stays there
+func TestRamp(t *testing.T) {
+	start := at(13, 7, 0)
+	r := Ramp{To: 0.8, Start: start, Length: time.Minute}
+	tests := []struct {
+		after time.Duration
+		want  float64
+		done  bool
+	}{
+		{-time.Second, 0, false},
+		{0, 0, false},
+		{30 * time.Second, 0.4, false},
+		{time.Minute, 0.8, true},
+		{time.Hour, 0.8, true},
+	}
+	for _, tt := range tests {
+		v, done := r.Volume(start.Add(tt.after))
+		if math.Abs(v-tt.want) > 1e-9 || done != tt.done {
+			t.Errorf("after %v: %v, %v; want %v, %v", tt.after, v, done, tt.want, tt.done)
+		}
+	}
+	if v, done := (Ramp{To: 0.5}).Volume(start); v != 0.5 || !done {
+		t.Errorf("ramp of no length = %v, %v; want 0.5, true", v, done)
+	}
+}
+
+// TestScheduler_Waiting verifies only enabled alarms keep a player waiting
+func TestScheduler_Waiting(t *testing.T) {
+	s := New([]Alarm{{At: Clock{7, 0}, Disabled: true}}, nil)
+	if s.Waiting() {
+		t.Error("waiting for a disabled alarm")
+	}
+	s.Alarms = append(s.Alarms, Alarm{At: Clock{8, 0}})
+	if !s.Waiting() {
+		t.Error("not waiting for an enabled alarm")
+	}
+}
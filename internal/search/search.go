@@ -0,0 +1,273 @@
+// Package search fans a query out to pluggable providers (the local
+// library, playlists, remote backends, ...), then merges and ranks the
+// results so the UI can show a single list labeled by where each track came
+// from. New providers are registered with Federated.Register; the UI needs
+// no changes to show their results.
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/library"
+)
+
+// LocalSourceName is the label used for results from the local library
+const LocalSourceName = "local"
+
+// DefaultTimeout bounds how long a single source may take to answer
+const DefaultTimeout = 2 * time.Second
+
+// Source is a searchable collection of tracks. Its matches are ranked with
+// Score; providers that rank their own hits implement Provider instead.
+type Source interface {
+	Name() string
+	Search(ctx context.Context, query string) ([]*api.Track, error)
+}
+
+// Provider is a pluggable search backend. Name labels its results.
+type Provider interface {
+	Name() string
+	Find(ctx context.Context, query string) ([]Hit, error)
+}
+
+// Hit is one match from a provider. Score uses the same scale as Score so
+// hits from every provider rank against each other; Context says why the
+// track matched when that isn't visible from its tags, e.g. "in Road Trip".
+type Hit struct {
+	Track   *api.Track
+	Score   int
+	Context string
+}
+
+// Streamer is implemented by providers whose tracks are streamed from a
+// server instead of being local files
+type Streamer interface {
+	StreamURL(trackID string) string
+	Token() string
+}
+
+// Result is a single ranked search hit
+type Result struct {
+	Track   *api.Track
+	Source  string
+	Score   int
+	Context string
+	Remote  bool // the track must be streamed from its source
+}
+
+// scored adapts a Source to a Provider, ranking its tracks with Score
+type scored struct {
+	Source
+}
+
+func (s scored) Find(ctx context.Context, query string) ([]Hit, error) {
+	tracks, err := s.Search(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	hits := make([]Hit, len(tracks))
+	for i, t := range tracks {
+		hits[i] = Hit{Track: t, Score: Score(t, query)}
+	}
+	return hits, nil
+}
+
+// SourceError records a failure (or timeout) from one source
+type SourceError struct {
+	Source string
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("search source %s: %v", e.Source, e.Err)
+}
+
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+type sourceEntry struct {
+	provider Provider
+	impl     any // the registered value, checked for optional interfaces
+	timeout  time.Duration
+}
+
+// Federated is the registry of search providers and searches them
+// concurrently
+type Federated struct {
+	sources []sourceEntry
+	mu      sync.RWMutex
+}
+
+// NewFederated creates a searcher over the given sources using DefaultTimeout
+func NewFederated(sources ...Source) *Federated {
+	f := &Federated{}
+	for _, src := range sources {
+		f.Add(src, DefaultTimeout)
+	}
+	return f
+}
+
+// Add registers a source with its own timeout (DefaultTimeout if <= 0)
+func (f *Federated) Add(src Source, timeout time.Duration) {
+	f.register(scored{src}, src, timeout)
+}
+
+// Register adds a provider with its own timeout (DefaultTimeout if <= 0)
+func (f *Federated) Register(p Provider, timeout time.Duration) {
+	f.register(p, p, timeout)
+}
+
+func (f *Federated) register(p Provider, impl any, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sources = append(f.sources, sourceEntry{provider: p, impl: impl, timeout: timeout})
+}
+
+// StreamerFor returns the named provider if its tracks are streamed, or nil
+func (f *Federated) StreamerFor(name string) Streamer {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, e := range f.sources {
+		if e.provider.Name() == name {
+			st, _ := e.impl.(Streamer)
+			return st
+		}
+	}
+	return nil
+}
+
+// HasRemote reports whether any provider besides the local library is
+// registered, i.e. whether a query needs more than local filtering
+func (f *Federated) HasRemote() bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, e := range f.sources {
+		if e.provider.Name() != LocalSourceName {
+			return true
+		}
+	}
+	return false
+}
+
+// Search queries every source concurrently, each bounded by its own timeout.
+// Results from sources that fail or time out are omitted and reported in the
+// returned error slice; the remaining results are merged and ranked.
+func (f *Federated) Search(ctx context.Context, query string) ([]Result, []error) {
+	f.mu.RLock()
+	sources := make([]sourceEntry, len(f.sources))
+	copy(sources, f.sources)
+	f.mu.RUnlock()
+
+	type sourceResult struct {
+		name   string
+		remote bool
+		hits   []Hit
+		err    error
+	}
+
+	out := make(chan sourceResult, len(sources))
+	var wg sync.WaitGroup
+
+	for _, entry := range sources {
+		wg.Add(1)
+		go func(entry sourceEntry) {
+			defer wg.Done()
+			sctx, cancel := context.WithTimeout(ctx, entry.timeout)
+			defer cancel()
+
+			hits, err := entry.provider.Find(sctx, query)
+			if err == nil && sctx.Err() != nil {
+				err = sctx.Err()
+			}
+			_, remote := entry.impl.(Streamer)
+			out <- sourceResult{name: entry.provider.Name(), remote: remote, hits: hits, err: err}
+		}(entry)
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	var results []Result
+	var errs []error
+	for r := range out {
+		if r.err != nil {
+			errs = append(errs, &SourceError{Source: r.name, Err: r.err})
+			continue
+		}
+		for _, h := range r.hits {
+			results = append(results, Result{
+				Track:   h.Track,
+				Source:  r.name,
+				Score:   h.Score,
+				Context: h.Context,
+				Remote:  r.remote,
+			})
+		}
+	}
+
+	Rank(results)
+	return dedupe(results), errs
+}
+
+// dedupe drops repeated local tracks (e.g. found both in the library and in
+// a playlist), keeping the best-ranked hit
+func dedupe(results []Result) []Result {
+	seen := make(map[string]bool, len(results))
+	out := results[:0]
+	for _, r := range results {
+		if !r.Remote {
+			if seen[r.Track.ID] {
+				continue
+			}
+			seen[r.Track.ID] = true
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Rank sorts results by score (best first). Local results win ties so the
+// user's own files come before remote copies of the same song.
+func Rank(results []Result) {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		iLocal := results[i].Source == LocalSourceName
+		jLocal := results[j].Source == LocalSourceName
+		if iLocal != jLocal {
+			return iLocal
+		}
+		if results[i].Track.Artist != results[j].Track.Artist {
+			return results[i].Track.Artist < results[j].Track.Artist
+		}
+		return results[i].Track.Title < results[j].Track.Title
+	})
+}
+
+// Score rates how well a track matches a query; 0 means no match. An
+// exact title scores 100, then a title prefix, a title substring, the
+// artist and the album 20 less each.
+func Score(t *api.Track, query string) int {
+	q := library.ParseQuery(query)
+	if q.Text == "" {
+		return 1
+	}
+	return (library.NoMatch - q.Relevance(t)) * 20
+}
+
+// Matches reports whether a track matches the query in title, artist or album
+func Matches(t *api.Track, query string) bool {
+	return Score(t, query) > 0
+}
@@ -0,0 +1,124 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// stubSource returns fixed tracks after an optional delay
+type stubSource struct {
+	name   string
+	tracks []*api.Track
+	delay  time.Duration
+}
+
+func (s *stubSource) Name() string { return s.name }
+
+func (s *stubSource) Search(ctx context.Context, query string) ([]*api.Track, error) {
+	select {
+	case <-time.After(s.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var out []*api.Track
+	for _, t := range s.tracks {
+		if Matches(t, query) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// TestFederatedMergesAndRanks verifies results from all sources are merged by score
+func TestFederatedMergesAndRanks(t *testing.T) {
+	local := &stubSource{name: LocalSourceName, tracks: []*api.Track{
+		{ID: "l1", Title: "Blue Monday", Artist: "New Order"},
+	}}
+	remote := &stubSource{name: "peer", tracks: []*api.Track{
+		{ID: "r1", Title: "Blue", Artist: "Joni Mitchell"},
+		{ID: "r2", Title: "Something", Artist: "Blue Oyster Cult"},
+	}}
+
+	f := NewFederated(local, remote)
+	results, errs := f.Search(context.Background(), "blue")
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+
+	wantOrder := []string{"r1", "l1", "r2"} // exact title, prefix, artist match
+	for i, id := range wantOrder {
+		if results[i].Track.ID != id {
+			t.Errorf("results[%d] = %s, want %s", i, results[i].Track.ID, id)
+		}
+	}
+	if results[0].Source != "peer" {
+		t.Errorf("expected first result labeled 'peer', got %q", results[0].Source)
+	}
+}
+
+// TestFederatedPerSourceTimeout verifies a slow source is dropped without blocking others
+func TestFederatedPerSourceTimeout(t *testing.T) {
+	fast := &stubSource{name: LocalSourceName, tracks: []*api.Track{{ID: "a", Title: "Song"}}}
+	slow := &stubSource{name: "slow", tracks: []*api.Track{{ID: "b", Title: "Song"}}, delay: time.Second}
+
+	f := &Federated{}
+	f.Add(fast, time.Second)
+	f.Add(slow, 20*time.Millisecond)
+
+	start := time.Now()
+	results, errs := f.Search(context.Background(), "song")
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("search took %v, slow source should have timed out", elapsed)
+	}
+
+	if len(results) != 1 || results[0].Track.ID != "a" {
+		t.Errorf("expected only the fast result, got %+v", results)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], context.DeadlineExceeded) {
+		t.Errorf("expected one deadline error, got %v", errs)
+	}
+}
+
+// scoredProvider ranks its own hits, as a lyrics or playlist index would
+type scoredProvider struct {
+	hits []Hit
+}
+
+func (p *scoredProvider) Name() string { return "lyrics" }
+
+func (p *scoredProvider) Find(ctx context.Context, query string) ([]Hit, error) {
+	return p.hits, nil
+}
+
+// TestFederatedProviders verifies provider scores and context are kept and local duplicates dropped
+func TestFederatedProviders(t *testing.T) {
+	song := &api.Track{ID: "l1", Title: "Something"}
+	local := &stubSource{name: LocalSourceName, tracks: []*api.Track{song}}
+	lyrics := &scoredProvider{hits: []Hit{
+		{Track: &api.Track{ID: "l2", Title: "Yesterday"}, Score: 90, Context: "…love was such an easy game…"},
+		{Track: song, Score: 10},
+	}}
+
+	f := NewFederated(local)
+	f.Register(lyrics, 0)
+	results, _ := f.Search(context.Background(), "some")
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	if results[0].Track.ID != "l2" || results[0].Context == "" {
+		t.Errorf("expected the lyrics hit with context first, got %+v", results[0])
+	}
+	if results[1].Source != LocalSourceName {
+		t.Errorf("expected the duplicate to keep its better local hit, got %q", results[1].Source)
+	}
+	if f.StreamerFor("lyrics") != nil {
+		t.Error("lyrics provider should not stream")
+	}
+}
@@ -0,0 +1,189 @@
+package search
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/playlist"
+	"github.com/jscyril/golang_music_player/pkg/apiclient"
+)
+
+// LibrarySource searches the local library
+type LibrarySource struct {
+	lib *library.Library
+}
+
+// NewLibrarySource wraps a library as a search source
+func NewLibrarySource(lib *library.Library) *LibrarySource {
+	return &LibrarySource{lib: lib}
+}
+
+// Name returns the local source label
+func (s *LibrarySource) Name() string {
+	return LocalSourceName
+}
+
+// Search matches the query against title, artist and album
+func (s *LibrarySource) Search(ctx context.Context, query string) ([]*api.Track, error) {
+	return s.lib.Search(query), nil
+}
+
+// PlaylistSourceName labels results found through playlists
+const PlaylistSourceName = "playlists"
+
+// PlaylistSource finds tracks through the playlists they are in: a query
+// matching a playlist's name returns the playlist's tracks
+type PlaylistSource struct {
+	pm *playlist.Manager
+}
+
+// NewPlaylistSource creates a provider over the saved playlists
+func NewPlaylistSource(pm *playlist.Manager) *PlaylistSource {
+	return &PlaylistSource{pm: pm}
+}
+
+// Name returns the playlist source label
+func (s *PlaylistSource) Name() string {
+	return PlaylistSourceName
+}
+
+// Find returns the tracks of playlists whose name contains the query. They
+// rank between artist and album matches, an exact name a little higher.
+func (s *PlaylistSource) Find(ctx context.Context, query string) ([]Hit, error) {
+	q := strings.ToLower(strings.TrimSpace(query))
+	if q == "" {
+		return nil, nil
+	}
+	var hits []Hit
+	for _, pl := range s.pm.GetAll() {
+		name := strings.ToLower(pl.Name)
+		if !strings.Contains(name, q) {
+			continue
+		}
+		score := 30
+		if name == q {
+			score = 50
+		}
+		for i := range pl.Tracks {
+			hits = append(hits, Hit{Track: &pl.Tracks[i], Score: score, Context: "in " + pl.Name})
+		}
+	}
+	return hits, nil
+}
+
+// remoteCacheTTL is how long a remote track listing is reused between keystrokes
+const remoteCacheTTL = 5 * time.Minute
+
+// RemoteSource searches a gtmpc server's library over its REST API.
+// The server has no search endpoint, so the track list is fetched once,
+// cached, and filtered client-side.
+type RemoteSource struct {
+	name   string
+	client *apiclient.APIClient
+
+	mu        sync.Mutex
+	tracks    []*api.Track
+	fetchedAt time.Time
+}
+
+// NewRemoteSource creates a remote source; token may be empty for open servers
+func NewRemoteSource(name, baseURL, token string) *RemoteSource {
+	client := apiclient.NewAPIClient(baseURL)
+	client.SetToken(token)
+	return &RemoteSource{name: name, client: client}
+}
+
+// SetStreamBitrate asks the server for MP3 streams at kbps kbit/s, for
+// slow links; 0 takes the files as the server sends them
+func (s *RemoteSource) SetStreamBitrate(kbps int) {
+	s.client.StreamFormat = ""
+	if kbps > 0 {
+		// MP3 is the lossy format the player decodes from a stream
+		s.client.StreamFormat = "mp3"
+	}
+	s.client.StreamBitrate = kbps
+}
+
+// Name returns the configured source label
+func (s *RemoteSource) Name() string {
+	return s.name
+}
+
+// Search fetches (or reuses) the remote listing and filters it by query
+func (s *RemoteSource) Search(ctx context.Context, query string) ([]*api.Track, error) {
+	tracks, err := s.listing(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*api.Track, 0, 10)
+	for _, t := range tracks {
+		if Matches(t, query) {
+			results = append(results, t)
+		}
+	}
+	return results, nil
+}
+
+// StreamURL returns the URL to stream a remote track from
+func (s *RemoteSource) StreamURL(trackID string) string {
+	return s.client.StreamURL(trackID)
+}
+
+// Token returns the bearer token used for this source
+func (s *RemoteSource) Token() string {
+	return s.client.Token
+}
+
+// listing returns the cached track list, refreshing it when stale.
+// The HTTP call runs in its own goroutine so ctx cancellation is honored
+// even though apiclient has no context support.
+func (s *RemoteSource) listing(ctx context.Context) ([]*api.Track, error) {
+	s.mu.Lock()
+	if s.tracks != nil && time.Since(s.fetchedAt) < remoteCacheTTL {
+		tracks := s.tracks
+		s.mu.Unlock()
+		return tracks, nil
+	}
+	s.mu.Unlock()
+
+	type fetchResult struct {
+		resp *apiclient.TrackListResponse
+		err  error
+	}
+	done := make(chan fetchResult, 1)
+	go func() {
+		resp, err := s.client.GetTracks()
+		done <- fetchResult{resp: resp, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-done:
+		if r.err != nil {
+			return nil, r.err
+		}
+		tracks := make([]*api.Track, 0, len(r.resp.Tracks))
+		for _, t := range r.resp.Tracks {
+			tracks = append(tracks, &api.Track{
+				ID:       t.ID,
+				Title:    t.Title,
+				Artist:   t.Artist,
+				Album:    t.Album,
+				Duration: time.Duration(t.DurationSeconds) * time.Second,
+				FilePath: s.client.StreamURL(t.ID),
+			})
+		}
+
+		s.mu.Lock()
+		s.tracks = tracks
+		s.fetchedAt = time.Now()
+		s.mu.Unlock()
+		return tracks, nil
+	}
+}
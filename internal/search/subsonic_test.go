@@ -0,0 +1,97 @@
+package search
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// checkAuth fails the test unless q signs in as user with a salted token
+// of password
+func checkAuth(t *testing.T, q url.Values, user, password string) {
+	t.Helper()
+	sum := md5.Sum([]byte(password + q.Get("s")))
+	if q.Get("u") != user || q.Get("s") == "" || q.Get("t") != hex.EncodeToString(sum[:]) {
+		t.Errorf("bad credentials in %v", q)
+	}
+	if q.Get("p") != "" || strings.Contains(q.Encode(), password) {
+		t.Errorf("password sent in %v", q)
+	}
+}
+
+// TestSubsonicSearch verifies songs are read from search3 and stream with
+// signed URLs
+func TestSubsonicSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rest/search3.view" {
+			http.NotFound(w, r)
+			return
+		}
+		q := r.URL.Query()
+		checkAuth(t, q, "ann", "sesame")
+		if q.Get("query") != "blue" || q.Get("f") != "json" {
+			t.Errorf("unexpected query %v", q)
+		}
+		w.Write([]byte(`{"subsonic-response":{"status":"ok","version":"1.16.1","searchResult3":{"song":[
+			{"id":"s1","title":"Blue Monday","artist":"New Order","album":"Substance","genre":"Synth-pop","duration":448}]}}}`))
+	}))
+	defer srv.Close()
+
+	src := NewSubsonicSource("navidrome", srv.URL+"/", "ann", "sesame")
+	src.SetStreamBitrate(96)
+	tracks, err := src.Search(context.Background(), " blue ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tracks) != 1 {
+		t.Fatalf("expected 1 track, got %d", len(tracks))
+	}
+	got := tracks[0]
+	if got.ID != "s1" || got.Title != "Blue Monday" || got.Artist != "New Order" || got.Duration != 448*time.Second {
+		t.Errorf("unexpected track %+v", got)
+	}
+
+	stream, err := url.Parse(src.StreamURL("s1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream.Path != "/rest/stream.view" || stream.Query().Get("id") != "s1" || stream.Query().Get("maxBitRate") != "96" {
+		t.Errorf("unexpected stream URL %s", stream)
+	}
+	checkAuth(t, stream.Query(), "ann", "sesame")
+	if src.Token() != "" {
+		t.Error("a Subsonic source should need no bearer token")
+	}
+	if NewFederated(src).StreamerFor("navidrome") == nil {
+		t.Error("a Subsonic source should stream")
+	}
+}
+
+// TestSubsonicErrors verifies failed answers are reported as errors
+func TestSubsonicErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("query") == "down" {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"subsonic-response":{"status":"failed","error":{"code":40,"message":"Wrong username or password"}}}`))
+	}))
+	defer srv.Close()
+
+	src := NewSubsonicSource("navidrome", srv.URL, "ann", "wrong")
+	if _, err := src.Search(context.Background(), "blue"); err == nil || !strings.Contains(err.Error(), "Wrong username") {
+		t.Errorf("expected the server's error, got %v", err)
+	}
+	if _, err := src.Search(context.Background(), "down"); err == nil {
+		t.Error("expected an error for a failed request")
+	}
+	if tracks, err := src.Search(context.Background(), "  "); err != nil || tracks != nil {
+		t.Errorf("empty query: got %v, %v", tracks, err)
+	}
+}
@@ -0,0 +1,203 @@
+// Package status shares the now-playing state with scripts and status bars
+// (polybar, waybar, tmux). The running player keeps a snapshot file up to
+// date, and `player status` reads and formats it. The file stands in for
+// a control socket: it is read-only, and commands still go through the
+// media keys, MPRIS or the HTTP API.
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/logger"
+	"github.com/jscyril/golang_music_player/internal/webhook"
+	"github.com/jscyril/golang_music_player/pkg/events"
+)
+
+// FileName is the snapshot file, see Path
+const FileName = "status.json"
+
+// Path returns where the snapshot file of the player using dataDir and
+// profile ("" for none) is kept. Status bars run from any directory, so
+// it lives in $XDG_RUNTIME_DIR when that is set, and otherwise in the
+// data directory made absolute, where a relative data_dir is only found
+// from the directory the player was started in.
+func Path(dataDir, profile string) string {
+	name := FileName
+	if profile != "" {
+		name = "status-" + profile + ".json"
+	}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "gtmpc", name)
+	}
+	if abs, err := filepath.Abs(dataDir); err == nil {
+		dataDir = abs
+	}
+	return filepath.Join(dataDir, FileName)
+}
+
+// DefaultFormat is the output of `player status` without --format or --json
+const DefaultFormat = "{artist} - {title} [{position}/{duration}]"
+
+// NowPlayingFormat is the output of `player now-playing` without --format,
+// and the terminal title
+const NowPlayingFormat = "{icon} {artist} – {title}"
+
+// Info is the now-playing state as printed by --json
+type Info struct {
+	Status   string  `json:"status"`
+	Artist   string  `json:"artist"`
+	Title    string  `json:"title"`
+	Album    string  `json:"album"`
+	Position float64 `json:"position"` // seconds
+	Duration float64 `json:"duration"` // seconds, 0 if unknown
+	Volume   int     `json:"volume"`   // percent
+	Output   string  `json:"output"`
+}
+
+// Write saves snap to path, replacing the previous snapshot in one step so
+// readers never see a partial file
+func Write(path string, snap *api.Snapshot) error {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("encode status: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("write status: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write status: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Read loads the snapshot at path. A missing file means no player is
+// running and yields a stopped state.
+func Read(path string) (*api.Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &api.Snapshot{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read status: %w", err)
+	}
+	var snap api.Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("parse status %s: %w", filepath.Base(path), err)
+	}
+	return &snap, nil
+}
+
+// InfoAt describes snap at now. The file is only rewritten when playback
+// changes, so a playing track's position is advanced by the time since the
+// snapshot was taken.
+func InfoAt(snap *api.Snapshot, now time.Time) Info {
+	info := Info{
+		Status: webhook.StatusName(snap.Status),
+		Volume: int(snap.Volume*100 + 0.5),
+		Output: snap.Output,
+	}
+	t := snap.CurrentTrack
+	if t == nil {
+		return info
+	}
+	pos := snap.Position
+	if snap.Status == api.StatusPlaying && !snap.TakenAt.IsZero() {
+		pos += now.Sub(snap.TakenAt)
+	}
+	if t.Duration > 0 && pos > t.Duration {
+		pos = t.Duration
+	}
+	info.Artist, info.Title, info.Album = t.Artist, t.Title, t.Album
+	info.Position, info.Duration = pos.Seconds(), t.Duration.Seconds()
+	return info
+}
+
+// Format expands the {status}, {icon}, {artist}, {title}, {album},
+// {position}, {duration}, {volume} and {output} placeholders in tmpl. Other text,
+// including unknown placeholders, is kept as is. Nothing is printed while
+// stopped, so bars hide the module.
+func Format(tmpl string, info Info) string {
+	if info.Title == "" && info.Status == "stopped" {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{status}", info.Status,
+		"{icon}", icon(info.Status),
+		"{artist}", info.Artist,
+		"{title}", info.Title,
+		"{album}", info.Album,
+		"{position}", clock(info.Position),
+		"{duration}", clock(info.Duration),
+		"{volume}", fmt.Sprint(info.Volume),
+		"{output}", info.Output,
+	).Replace(tmpl)
+}
+
+// icon returns the symbol of a status name
+func icon(status string) string {
+	switch status {
+	case "playing":
+		return "▶"
+	case "paused":
+		return "⏸"
+	}
+	return "■"
+}
+
+// clock formats seconds as m:ss, or h:mm:ss from an hour
+func clock(secs float64) string {
+	s := int(secs)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// seekSlack is how far the reported position may drift from the one
+// extrapolated from the file before the file is rewritten
+const seekSlack = time.Second
+
+// Publish keeps the snapshot at path current until ctx is cancelled, then
+// removes it. It is rewritten on playback changes and after seeks;
+// snapshot supplies its contents.
+func Publish(ctx context.Context, bus *events.EventBus, path string, snapshot func() *api.Snapshot) {
+	ch := bus.SubscribeWith(events.Policy{Types: []api.EventType{
+		api.EventTrackStarted, api.EventTrackEnded, api.EventStateChange, api.EventPositionUpdate,
+	}})
+	defer bus.Unsubscribe(ch)
+	defer os.Remove(path)
+
+	var last *api.Snapshot
+	write := func() {
+		last = snapshot()
+		if err := Write(path, last); err != nil {
+			logger.Warn("Failed to update status file: %v", err)
+		}
+	}
+	write()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			if p, isProgress := ev.Payload.(api.ProgressPayload); isProgress {
+				want := InfoAt(last, time.Now()).Position
+				if d := p.Position.Seconds() - want; d > -seekSlack.Seconds() && d < seekSlack.Seconds() {
+					continue
+				}
+			}
+			write()
+		}
+	}
+}
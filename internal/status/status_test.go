@@ -0,0 +1,98 @@
+package status
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/pkg/events"
+)
+
+func TestFormat_AdvancesPlayingPosition(t *testing.T) {
+	taken := time.Now()
+	snap := &api.Snapshot{
+		PlaybackState: api.PlaybackState{
+			CurrentTrack: &api.Track{Artist: "Artist", Title: "Song", Duration: 3*time.Minute + 5*time.Second},
+			Status:       api.StatusPlaying,
+			Position:     70 * time.Second,
+			Volume:       0.5,
+		},
+		TakenAt: taken,
+	}
+
+	info := InfoAt(snap, taken.Add(5*time.Second))
+	if got, want := Format(DefaultFormat+" {volume}% {unknown}", info), "Artist - Song [1:15/3:05] 50% {unknown}"; got != want {
+		t.Errorf("Format = %q, want %q", got, want)
+	}
+
+	snap.Status = api.StatusPaused
+	if got := InfoAt(snap, taken.Add(time.Hour)).Position; got != 70 {
+		t.Errorf("paused position = %v, want 70", got)
+	}
+}
+
+func TestFormat_Stopped(t *testing.T) {
+	if got := Format(DefaultFormat, InfoAt(&api.Snapshot{}, time.Now())); got != "" {
+		t.Errorf("Format = %q, want empty", got)
+	}
+}
+
+func TestShowTitle(t *testing.T) {
+	bus := events.NewEventBus()
+	var mu sync.Mutex
+	snap := &api.Snapshot{}
+	snapshot := func() *api.Snapshot {
+		mu.Lock()
+		defer mu.Unlock()
+		s := *snap
+		return &s
+	}
+	var out strings.Builder
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ShowTitle(ctx, bus, &out, snapshot)
+	}()
+
+	time.Sleep(20 * time.Millisecond) // let ShowTitle subscribe
+	mu.Lock()
+	snap.CurrentTrack = &api.Track{Artist: "Band", Title: "Song\x07"}
+	snap.Status = api.StatusPlaying
+	mu.Unlock()
+	bus.Publish(api.AudioEvent{Type: api.EventTrackStarted})
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	want := "\x1b[22;0t\x1b]2;gtmpc\x07\x1b]2;▶ Band – Song\x07\x1b[23;0t"
+	if got := out.String(); got != want {
+		t.Errorf("title output = %q, want %q", got, want)
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv("XDG_RUNTIME_DIR", "/run/user/1000")
+	if got := Path("./data", ""); got != "/run/user/1000/gtmpc/status.json" {
+		t.Errorf("Path = %q", got)
+	}
+	if got := Path("./data", "work"); got != "/run/user/1000/gtmpc/status-work.json" {
+		t.Errorf("Path with a profile = %q", got)
+	}
+
+	// Without a runtime directory a relative data directory is made
+	// absolute, so the path does not depend on where it is used
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := Path("./data", ""), filepath.Join(wd, "data", FileName); got != want {
+		t.Errorf("Path = %q, want %q", got, want)
+	}
+}
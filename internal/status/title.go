@@ -0,0 +1,67 @@
+package status
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/pkg/events"
+)
+
+// idleTitle is the terminal title while nothing is loaded
+const idleTitle = "gtmpc"
+
+// ShowTitle keeps the terminal's window title showing the current track
+// in NowPlayingFormat, e.g. "▶ Artist – Title", until ctx is cancelled,
+// then restores the title the terminal had. Inside tmux this sets the
+// pane title, which tmux can show in its status line or pass on to the
+// outer terminal.
+func ShowTitle(ctx context.Context, bus *events.EventBus, w io.Writer, snapshot func() *api.Snapshot) {
+	ch := bus.SubscribeWith(events.Policy{Types: []api.EventType{
+		api.EventTrackStarted, api.EventTrackEnded, api.EventStateChange,
+	}})
+	defer bus.Unsubscribe(ch)
+
+	// Save the current title on the terminal's title stack, restored on
+	// the way out; terminals without one ignore both
+	io.WriteString(w, "\x1b[22;0t")
+	defer io.WriteString(w, "\x1b[23;0t")
+
+	var last string
+	update := func() {
+		title := Format(NowPlayingFormat, InfoAt(snapshot(), time.Now()))
+		if title == "" {
+			title = idleTitle
+		}
+		if title != last {
+			fmt.Fprintf(w, "\x1b]2;%s\x07", sanitize(title))
+			last = title
+		}
+	}
+	update()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case _, ok := <-ch:
+			if !ok {
+				return
+			}
+			update()
+		}
+	}
+}
+
+// sanitize drops control characters from s, so a tag cannot end the
+// title sequence early or send its own
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0) {
+			return -1
+		}
+		return r
+	}, s)
+}
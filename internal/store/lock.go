@@ -0,0 +1,85 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// LockFileName is the lock file LockDir creates in a data directory
+const LockFileName = "gtmpc.lock"
+
+// ErrLocked is returned by LockDir when another process holds the lock
+var ErrLocked = errors.New("data directory is in use by another instance")
+
+// ErrReadOnly is returned by WriteFile and Remove after SetReadOnly(true)
+var ErrReadOnly = errors.New("read-only: another instance owns the data directory")
+
+var readOnly atomic.Bool
+
+// SetReadOnly makes every later WriteFile and Remove fail with ErrReadOnly,
+// for an instance that could not lock its data directory. Files kept
+// outside this package check ReadOnly themselves.
+func SetReadOnly(on bool) {
+	readOnly.Store(on)
+}
+
+// ReadOnly reports whether writes are turned off by SetReadOnly
+func ReadOnly() bool {
+	return readOnly.Load()
+}
+
+// DirLock is an exclusive lock on a data directory, held until Unlock or
+// the process exits
+type DirLock struct {
+	f *os.File
+}
+
+// LockDir takes the lock on dir without waiting for it. If another
+// process holds it, the error wraps ErrLocked and names that process's
+// pid. The operating system drops the lock when the process dies, so a
+// crash never leaves the directory locked.
+func LockDir(dir string) (*DirLock, error) {
+	path := filepath.Join(dir, LockFileName)
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		if !errors.Is(err, ErrLocked) {
+			return nil, fmt.Errorf("lock %s: %w", path, err)
+		}
+		if pid := holder(path); pid != 0 {
+			return nil, fmt.Errorf("%w (pid %d)", ErrLocked, pid)
+		}
+		return nil, err
+	}
+	// The pid is only informational, for the error above
+	f.Truncate(0)
+	f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return &DirLock{f: f}, nil
+}
+
+// Unlock releases the lock
+func (l *DirLock) Unlock() error {
+	if l == nil {
+		return nil
+	}
+	unlockFile(l.f)
+	return l.f.Close()
+}
+
+// holder reads the pid written to the lock file at path, or 0
+func holder(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
@@ -0,0 +1,173 @@
+// Package store persists the player's JSON data files. Writes go to a
+// temporary file that replaces the old one in a single rename, so a crash
+// mid-save leaves either the old or the new file, and the previous version
+// is kept as a .bak copy. Files carry a schema version; older versions are
+// upgraded on load by migration functions.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/jscyril/golang_music_player/internal/logger"
+)
+
+// BackupSuffix is appended to a file's path to name its backup
+const BackupSuffix = ".bak"
+
+// ErrNewerVersion is returned for files written by a newer version of the
+// player. They are left alone rather than loaded from an older backup.
+var ErrNewerVersion = errors.New("file was written by a newer version")
+
+// Migration upgrades a decoded document by one version, from the version
+// it is registered under to the next. It edits the top-level fields in doc.
+type Migration func(doc map[string]json.RawMessage) error
+
+// WriteFile replaces path with data atomically. The file being replaced
+// becomes path+BackupSuffix.
+func WriteFile(path string, data []byte) error {
+	if ReadOnly() {
+		return ErrReadOnly
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	f, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	tmp := f.Name()
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, 0644)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write %s: %w", filepath.Base(path), err)
+	}
+
+	if err := backup(path); err != nil {
+		logger.Warn("Failed to back up %s: %v", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("replace %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// backup keeps the current contents of path as its backup. A hard link
+// leaves path in place, so there is no moment without either file.
+func backup(path string) error {
+	bak := path + BackupSuffix
+	if err := os.Remove(bak); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	err := os.Link(path, bak)
+	if err == nil || os.IsNotExist(err) {
+		return nil
+	}
+	// Filesystems without hard links get a copy
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.Create(bak)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// Remove deletes path and its backup
+func Remove(path string) error {
+	if ReadOnly() {
+		return ErrReadOnly
+	}
+	os.Remove(path + BackupSuffix)
+	return os.Remove(path)
+}
+
+// Load decodes the JSON file at path into v after upgrading it to version
+// current. migrations[n] upgrades version n to n+1; files without a
+// "version" field are version 1. If the file is missing, unreadable or
+// damaged, its backup is loaded instead. An error wrapping os.ErrNotExist
+// means neither exists.
+func Load(path string, current int, migrations map[int]Migration, v interface{}) error {
+	err := load(path, current, migrations, v)
+	if err == nil || errors.Is(err, ErrNewerVersion) {
+		return err
+	}
+	bakErr := load(path+BackupSuffix, current, migrations, v)
+	if bakErr != nil {
+		if os.IsNotExist(bakErr) {
+			return err
+		}
+		return fmt.Errorf("%w (backup: %v)", err, bakErr)
+	}
+	logger.Warn("Loaded %s from its backup: %v", path, err)
+	return nil
+}
+
+func load(path string, current int, migrations map[int]Migration, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	data, err = Migrate(data, current, migrations)
+	if err != nil {
+		return fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decode %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// Migrate upgrades a JSON object to version current and returns it with
+// its "version" field set
+func Migrate(data []byte, current int, migrations map[int]Migration) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	version := 1
+	if raw, ok := doc["version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, fmt.Errorf("decode version: %w", err)
+		}
+	}
+	if version > current {
+		return nil, fmt.Errorf("version %d: %w", version, ErrNewerVersion)
+	}
+	if version == current {
+		return data, nil
+	}
+	for ; version < current; version++ {
+		m, ok := migrations[version]
+		if !ok {
+			continue // nothing changed in the layout
+		}
+		if err := m(doc); err != nil {
+			return nil, fmt.Errorf("migrate from version %d: %w", version, err)
+		}
+	}
+	doc["version"], _ = json.Marshal(current)
+	return json.Marshal(doc)
+}
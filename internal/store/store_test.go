@@ -0,0 +1,110 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type doc struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+}
+
+// TestWriteFile_KeepsBackup verifies each write leaves the previous file as
+// the backup and no temporary files behind
+func TestWriteFile_KeepsBackup(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	for _, body := range []string{`{"name":"one"}`, `{"name":"two"}`} {
+		if err := WriteFile(path, []byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"name":"two"}` {
+		t.Errorf("file = %s", data)
+	}
+	if data, _ := os.ReadFile(path + BackupSuffix); string(data) != `{"name":"one"}` {
+		t.Errorf("backup = %s", data)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("unexpected files %v", entries)
+	}
+}
+
+// TestLoad_FallsBackAndMigrates verifies a damaged file is replaced by its
+// backup, which is upgraded to the current version
+func TestLoad_FallsBackAndMigrates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	os.WriteFile(path+BackupSuffix, []byte(`{"title":"old"}`), 0644)
+	os.WriteFile(path, []byte(`{"name":`), 0644)
+
+	migrations := map[int]Migration{
+		1: func(d map[string]json.RawMessage) error {
+			d["name"] = d["title"]
+			delete(d, "title")
+			return nil
+		},
+	}
+	var got doc
+	if err := Load(path, 2, migrations, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "old" || got.Version != 2 {
+		t.Errorf("got %+v", got)
+	}
+
+	os.WriteFile(path, []byte(`{"version":3}`), 0644)
+	if err := Load(path, 2, migrations, &got); !errors.Is(err, ErrNewerVersion) {
+		t.Errorf("newer file: err = %v", err)
+	}
+	if err := Load(filepath.Join(dir, "none.json"), 2, nil, &got); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: err = %v", err)
+	}
+}
+
+// TestLockDir verifies a second lock on a directory fails until the first
+// is released
+func TestLockDir(t *testing.T) {
+	dir := t.TempDir()
+	first, err := LockDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LockDir(dir); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second lock: err = %v, want ErrLocked", err)
+	}
+	if err := first.Unlock(); err != nil {
+		t.Fatal(err)
+	}
+	again, err := LockDir(dir)
+	if err != nil {
+		t.Fatalf("lock after unlock: %v", err)
+	}
+	again.Unlock()
+}
+
+// TestReadOnly verifies a read-only instance neither writes nor removes
+// files
+func TestReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := WriteFile(path, []byte(`{"name":"one"}`)); err != nil {
+		t.Fatal(err)
+	}
+	SetReadOnly(true)
+	t.Cleanup(func() { SetReadOnly(false) })
+
+	if err := WriteFile(path, []byte(`{"name":"two"}`)); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("WriteFile = %v, want ErrReadOnly", err)
+	}
+	if err := Remove(path); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Remove = %v, want ErrReadOnly", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"name":"one"}` {
+		t.Errorf("file = %s", data)
+	}
+}
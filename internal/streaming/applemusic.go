@@ -0,0 +1,141 @@
+package streaming
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AppleMusic exports to an Apple Music library through the Apple Music
+// API. It needs a developer token, a JWT signed with a MusicKit key, and a
+// user token the account granted to the same developer. Songs are searched
+// in the storefront of the account's country.
+type AppleMusic struct {
+	developerToken string
+	userToken      string
+	storefront     string
+	http           *http.Client
+	apiURL         string
+}
+
+// NewAppleMusic creates an Apple Music exporter. storefront is a country
+// code such as "us" or "de"; empty means "us".
+func NewAppleMusic(developerToken, userToken, storefront string) *AppleMusic {
+	if storefront == "" {
+		storefront = "us"
+	}
+	return &AppleMusic{
+		developerToken: developerToken,
+		userToken:      userToken,
+		storefront:     strings.ToLower(storefront),
+		http:           &http.Client{Timeout: requestTimeout},
+		apiURL:         "https://api.music.apple.com/v1",
+	}
+}
+
+func (a *AppleMusic) Name() string { return "Apple Music" }
+
+// Search looks for the track in the storefront's catalog
+func (a *AppleMusic) Search(ctx context.Context, artist, title string) ([]Candidate, error) {
+	q := url.Values{
+		"term":  {strings.TrimSpace(artist + " " + title)},
+		"types": {"songs"},
+		"limit": {fmt.Sprint(maxCandidates)},
+	}
+	var res struct {
+		Results struct {
+			Songs struct {
+				Data []struct {
+					ID         string `json:"id"`
+					Attributes struct {
+						Name             string `json:"name"`
+						ArtistName       string `json:"artistName"`
+						AlbumName        string `json:"albumName"`
+						DurationInMillis int64  `json:"durationInMillis"`
+					} `json:"attributes"`
+				} `json:"data"`
+			} `json:"songs"`
+		} `json:"results"`
+	}
+	path := "/catalog/" + url.PathEscape(a.storefront) + "/search?" + q.Encode()
+	if err := a.do(ctx, http.MethodGet, path, nil, &res); err != nil {
+		return nil, fmt.Errorf("apple music search: %w", err)
+	}
+	var out []Candidate
+	for _, song := range res.Results.Songs.Data {
+		at := song.Attributes
+		out = append(out, Candidate{
+			ID:       song.ID,
+			Title:    at.Name,
+			Artists:  []string{at.ArtistName},
+			Album:    at.AlbumName,
+			Duration: time.Duration(at.DurationInMillis) * time.Millisecond,
+		})
+	}
+	return out, nil
+}
+
+// CreatePlaylist creates the playlist in the account's library. Library
+// playlists have no web address until shared from the Music app, so the
+// returned one is always empty.
+func (a *AppleMusic) CreatePlaylist(ctx context.Context, name, description string, ids []string) (string, error) {
+	type song struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}
+	songs := make([]song, len(ids))
+	for i, id := range ids {
+		songs[i] = song{ID: id, Type: "songs"}
+	}
+	body := map[string]any{
+		"attributes":    map[string]string{"name": name, "description": description},
+		"relationships": map[string]any{"tracks": map[string]any{"data": songs}},
+	}
+	if err := a.do(ctx, http.MethodPost, "/me/library/playlists", body, nil); err != nil {
+		return "", fmt.Errorf("apple music create playlist: %w", err)
+	}
+	return "", nil
+}
+
+// do sends a request to the API with body as JSON, if not nil, and decodes
+// the JSON response into v, if not nil
+func (a *AppleMusic) do(ctx context.Context, method, path string, body, v any) error {
+	if a.developerToken == "" || a.userToken == "" {
+		return ErrNotConfigured
+	}
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.apiURL+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.developerToken)
+	req.Header.Set("Music-User-Token", a.userToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := a.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", req.URL.Host, resp.Status)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,194 @@
+package streaming
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// spotifyBatch is the most tracks Spotify adds to a playlist per request
+const spotifyBatch = 100
+
+// Spotify exports to a Spotify account through the Web API. It holds an
+// app's client ID and secret and a refresh token the account granted the
+// app with the playlist-modify-private scope, and trades the token for
+// access tokens as needed.
+type Spotify struct {
+	clientID     string
+	clientSecret string
+	refreshToken string
+	http         *http.Client
+	apiURL       string
+	tokenURL     string
+
+	mu      sync.Mutex
+	access  string
+	expires time.Time
+}
+
+// NewSpotify creates a Spotify exporter
+func NewSpotify(clientID, clientSecret, refreshToken string) *Spotify {
+	return &Spotify{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		refreshToken: refreshToken,
+		http:         &http.Client{Timeout: requestTimeout},
+		apiURL:       "https://api.spotify.com/v1",
+		tokenURL:     "https://accounts.spotify.com/api/token",
+	}
+}
+
+func (s *Spotify) Name() string { return "Spotify" }
+
+// Search looks for the track by title and artist, and by title alone if
+// that finds nothing
+func (s *Spotify) Search(ctx context.Context, artist, title string) ([]Candidate, error) {
+	queries := []string{fmt.Sprintf("track:%s artist:%s", title, artist), title}
+	if artist == "" {
+		queries = queries[1:]
+	}
+	for _, query := range queries {
+		q := url.Values{"q": {query}, "type": {"track"}, "limit": {fmt.Sprint(maxCandidates)}}
+		var res struct {
+			Tracks struct {
+				Items []struct {
+					URI        string `json:"uri"`
+					Name       string `json:"name"`
+					DurationMS int64  `json:"duration_ms"`
+					Artists    []struct {
+						Name string `json:"name"`
+					} `json:"artists"`
+					Album struct {
+						Name string `json:"name"`
+					} `json:"album"`
+				} `json:"items"`
+			} `json:"tracks"`
+		}
+		if err := s.do(ctx, http.MethodGet, "/search?"+q.Encode(), nil, &res); err != nil {
+			return nil, fmt.Errorf("spotify search: %w", err)
+		}
+		var out []Candidate
+		for _, it := range res.Tracks.Items {
+			c := Candidate{ID: it.URI, Title: it.Name, Album: it.Album.Name, Duration: time.Duration(it.DurationMS) * time.Millisecond}
+			for _, a := range it.Artists {
+				c.Artists = append(c.Artists, a.Name)
+			}
+			out = append(out, c)
+		}
+		if len(out) > 0 {
+			return out, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreatePlaylist creates a private, collaborative playlist in the account,
+// so the friends it is shared with can add to it
+func (s *Spotify) CreatePlaylist(ctx context.Context, name, description string, ids []string) (string, error) {
+	var me struct {
+		ID string `json:"id"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/me", nil, &me); err != nil {
+		return "", fmt.Errorf("spotify account: %w", err)
+	}
+	var created struct {
+		ID           string `json:"id"`
+		ExternalURLs struct {
+			Spotify string `json:"spotify"`
+		} `json:"external_urls"`
+	}
+	body := map[string]any{"name": name, "description": description, "public": false, "collaborative": true}
+	if err := s.do(ctx, http.MethodPost, "/users/"+url.PathEscape(me.ID)+"/playlists", body, &created); err != nil {
+		return "", fmt.Errorf("spotify create playlist: %w", err)
+	}
+	for start := 0; start < len(ids); start += spotifyBatch {
+		batch := ids[start:min(start+spotifyBatch, len(ids))]
+		if err := s.do(ctx, http.MethodPost, "/playlists/"+created.ID+"/tracks", map[string]any{"uris": batch}, nil); err != nil {
+			return created.ExternalURLs.Spotify, fmt.Errorf("spotify add tracks: %w", err)
+		}
+	}
+	return created.ExternalURLs.Spotify, nil
+}
+
+// token returns a current access token, refreshing it when it expires
+func (s *Spotify) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.refreshToken == "" || s.clientID == "" {
+		return "", ErrNotConfigured
+	}
+	if s.access != "" && time.Now().Before(s.expires) {
+		return s.access, nil
+	}
+
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {s.refreshToken}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.SetBasicAuth(s.clientID, s.clientSecret)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("spotify token: %s", resp.Status)
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return "", fmt.Errorf("spotify token: %w", err)
+	}
+	s.access = tok.AccessToken
+	// Renew a minute early so a token does not run out mid-export
+	s.expires = time.Now().Add(time.Duration(tok.ExpiresIn)*time.Second - time.Minute)
+	return s.access, nil
+}
+
+// do sends a request to the Web API with body as JSON, if not nil, and
+// decodes the JSON response into v, if not nil
+func (s *Spotify) do(ctx context.Context, method, path string, body, v any) error {
+	token, err := s.token(ctx)
+	if err != nil {
+		return err
+	}
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.apiURL+path, r)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := s.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", req.URL.Host, resp.Status)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,229 @@
+// Package streaming recreates local playlists on streaming services, so
+// they can be shared with people who do not have the files. Each track is
+// searched for by artist and title and the results are scored with a fuzzy
+// match; the user reviews the matches, weak ones especially, before the
+// playlist is created on the service.
+package streaming
+
+import (
+	"context"
+	"errors"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// requestTimeout bounds a single web service call
+const requestTimeout = 15 * time.Second
+
+// MinScore is the lowest match score taken without review; weaker matches
+// are listed but left out until the user picks them
+const MinScore = 0.75
+
+// maxCandidates is how many search results are kept per track
+const maxCandidates = 5
+
+// ErrNotConfigured is returned by a service missing its credentials
+var ErrNotConfigured = errors.New("streaming account not configured")
+
+// Candidate is a song found on a service
+type Candidate struct {
+	ID       string // the service's ID or URI, what CreatePlaylist takes
+	Title    string
+	Artists  []string
+	Album    string
+	Duration time.Duration // 0 if unknown
+}
+
+// Service is a streaming service playlists can be exported to
+type Service interface {
+	// Name is the service's display name, e.g. "Spotify"
+	Name() string
+	// Search returns songs that may be the one with artist and title
+	Search(ctx context.Context, artist, title string) ([]Candidate, error)
+	// CreatePlaylist creates a playlist of the songs with ids, in order,
+	// and returns its web address, or "" if it has none
+	CreatePlaylist(ctx context.Context, name, description string, ids []string) (string, error)
+}
+
+// Match pairs a local track with the songs found for it, best first
+type Match struct {
+	Track      api.Track
+	Candidates []Candidate
+	Scores     []float64 // of Candidates, 0 to 1
+	Chosen     int       // index into Candidates, -1 for none
+}
+
+// Best returns the chosen candidate, or nil when the track is left out
+func (m Match) Best() *Candidate {
+	if m.Chosen < 0 || m.Chosen >= len(m.Candidates) {
+		return nil
+	}
+	return &m.Candidates[m.Chosen]
+}
+
+// Score returns the score of the chosen candidate, 0 if none is
+func (m Match) Score() float64 {
+	if m.Chosen < 0 || m.Chosen >= len(m.Scores) {
+		return 0
+	}
+	return m.Scores[m.Chosen]
+}
+
+// MatchAll searches svc for every track. Confident matches are chosen;
+// weaker ones keep their candidates but choose none. It stops early when
+// ctx is cancelled, and fails only if every search did.
+func MatchAll(ctx context.Context, svc Service, tracks []api.Track) ([]Match, error) {
+	matches := make([]Match, 0, len(tracks))
+	var lastErr error
+	failed := 0
+	for _, t := range tracks {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		found, err := svc.Search(ctx, t.Artist, t.Title)
+		if err != nil {
+			lastErr = err
+			failed++
+		}
+		matches = append(matches, rank(t, found))
+	}
+	if failed > 0 && failed == len(tracks) {
+		return nil, lastErr
+	}
+	return matches, nil
+}
+
+// rank scores the candidates for t and sorts them best first
+func rank(t api.Track, found []Candidate) Match {
+	m := Match{Track: t, Chosen: -1}
+	type scored struct {
+		c     Candidate
+		score float64
+	}
+	list := make([]scored, len(found))
+	for i, c := range found {
+		list[i] = scored{c, Similarity(t, c)}
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].score > list[j].score })
+	for i, s := range list {
+		if i == maxCandidates {
+			break
+		}
+		m.Candidates = append(m.Candidates, s.c)
+		m.Scores = append(m.Scores, s.score)
+	}
+	if len(m.Scores) > 0 && m.Scores[0] >= MinScore {
+		m.Chosen = 0
+	}
+	return m
+}
+
+// Similarity scores how likely c is the recording t, from 0 to 1. Titles
+// weigh more than artists; a length off by more than a few seconds lowers
+// the score.
+func Similarity(t api.Track, c Candidate) float64 {
+	title := similar(normalize(t.Title), normalize(c.Title))
+	artist := 0.0
+	want := normalize(t.Artist)
+	for _, a := range append(c.Artists, strings.Join(c.Artists, " ")) {
+		artist = math.Max(artist, similar(want, normalize(a)))
+	}
+	score := 0.6*title + 0.4*artist
+	if t.Duration > 0 && c.Duration > 0 {
+		off := (t.Duration - c.Duration).Abs()
+		if off > 5*time.Second {
+			score *= math.Max(0.5, 1-off.Seconds()/120)
+		}
+	}
+	return score
+}
+
+// decoration matches the parts of a title that differ between releases of
+// the same recording: "(Remastered 2011)", "[Live]", "- Radio Edit" and
+// featured artists
+var decoration = regexp.MustCompile(`(?i)\s*[(\[][^)\]]*[)\]]|\s+-\s+.*$|\s+(feat\.?|ft\.|featuring)\s.*$`)
+
+// normalize lowercases s and strips decoration, punctuation and accents
+func normalize(s string) string {
+	s = decoration.ReplaceAllString(s, "")
+	s = strings.ReplaceAll(s, "&", " and ")
+	var sb strings.Builder
+	space := true
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(unfold(r))
+			space = false
+		case !space:
+			sb.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// accents maps common accented letters to their plain ones
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ñ", "n", "ç", "c", "ý", "y", "ÿ", "y",
+)
+
+func unfold(r rune) rune {
+	if r < 0x80 {
+		return r
+	}
+	if s := accents.Replace(string(r)); s != string(r) {
+		return rune(s[0])
+	}
+	return r
+}
+
+// similar is the Dice coefficient of the letter pairs of a and b: 1 for
+// equal strings, near 0 for unrelated ones, and forgiving of typos and
+// word order
+func similar(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	pa, pb := bigrams(a), bigrams(b)
+	if len(pa) == 0 || len(pb) == 0 {
+		return 0
+	}
+	counts := make(map[string]int, len(pa))
+	for _, p := range pa {
+		counts[p]++
+	}
+	common := 0
+	for _, p := range pb {
+		if counts[p] > 0 {
+			counts[p]--
+			common++
+		}
+	}
+	return 2 * float64(common) / float64(len(pa)+len(pb))
+}
+
+// bigrams returns the pairs of adjacent letters in each word of s
+func bigrams(s string) []string {
+	var out []string
+	for _, word := range strings.Fields(s) {
+		r := []rune(word)
+		if len(r) == 1 {
+			out = append(out, word)
+		}
+		for i := 0; i+1 < len(r); i++ {
+			out = append(out, string(r[i:i+2]))
+		}
+	}
+	return out
+}
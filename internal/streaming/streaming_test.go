@@ -0,0 +1,132 @@
+package streaming
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestNormalize verifies release decorations, punctuation and accents are dropped
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"Paranoid Android (Remastered 2009)": "paranoid android",
+		"Hey Ya! - Radio Edit":               "hey ya",
+		"Crazy in Love (feat. Jay-Z)":        "crazy in love",
+		"Señorita [Live]":                    "senorita",
+		"Simon & Garfunkel":                  "simon and garfunkel",
+		"Get Lucky ft. Pharrell Williams":    "get lucky",
+		"  Don't   Stop Me Now  ":            "don t stop me now",
+		"Beyoncé":                            "beyonce",
+	}
+	for in, want := range cases {
+		if got := normalize(in); got != want {
+			t.Errorf("normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+// fakeService answers searches from a map of title to candidates
+type fakeService map[string][]Candidate
+
+func (f fakeService) Name() string { return "Fake" }
+
+func (f fakeService) Search(ctx context.Context, artist, title string) ([]Candidate, error) {
+	return f[title], nil
+}
+
+func (f fakeService) CreatePlaylist(ctx context.Context, name, description string, ids []string) (string, error) {
+	return "", nil
+}
+
+// TestMatchAll verifies confident matches are chosen and weak ones left for review
+func TestMatchAll(t *testing.T) {
+	svc := fakeService{
+		"Airbag": {
+			{ID: "cover", Title: "Airbag", Artists: []string{"Some Tribute Band"}},
+			{ID: "orig", Title: "Airbag - Remastered", Artists: []string{"Radiohead"}, Duration: 284 * time.Second},
+		},
+		"Lucky": {
+			{ID: "other", Title: "Lucky Star", Artists: []string{"Madonna"}},
+		},
+	}
+	tracks := []api.Track{
+		{Title: "Airbag", Artist: "Radiohead", Duration: 284 * time.Second},
+		{Title: "Lucky", Artist: "Radiohead"},
+		{Title: "Nothing found", Artist: "Nobody"},
+	}
+	matches, err := MatchAll(context.Background(), svc, tracks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 3 {
+		t.Fatalf("got %d matches, want 3", len(matches))
+	}
+	if best := matches[0].Best(); best == nil || best.ID != "orig" {
+		t.Errorf("Airbag matched %+v, want orig", best)
+	}
+	if matches[1].Best() != nil || len(matches[1].Candidates) != 1 {
+		t.Errorf("weak match chosen: %+v", matches[1])
+	}
+	if matches[2].Best() != nil || len(matches[2].Candidates) != 0 {
+		t.Errorf("no-result match = %+v", matches[2])
+	}
+}
+
+// TestSpotify_CreatePlaylist verifies the token refresh and the calls creating a playlist
+func TestSpotify_CreatePlaylist(t *testing.T) {
+	var added []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "client" || secret != "secret" {
+			t.Errorf("basic auth = %q/%q", id, secret)
+		}
+		if r.FormValue("refresh_token") != "refresh" {
+			t.Errorf("refresh_token = %q", r.FormValue("refresh_token"))
+		}
+		w.Write([]byte(`{"access_token":"access","expires_in":3600}`))
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			t.Errorf("authorization = %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"id":"alice"}`))
+	})
+	mux.HandleFunc("/users/alice/playlists", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["name"] != "Road trip" || body["collaborative"] != true || body["public"] != false {
+			t.Errorf("create body = %v", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id":"pl1","external_urls":{"spotify":"https://open.spotify.com/playlist/pl1"}}`))
+	})
+	mux.HandleFunc("/playlists/pl1/tracks", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			URIs []string `json:"uris"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		added = append(added, body.URIs...)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	s := NewSpotify("client", "secret", "refresh")
+	s.apiURL, s.tokenURL = srv.URL, srv.URL+"/token"
+	link, err := s.CreatePlaylist(context.Background(), "Road trip", "", []string{"spotify:track:1", "spotify:track:2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link != "https://open.spotify.com/playlist/pl1" {
+		t.Errorf("link = %q", link)
+	}
+	if len(added) != 2 || added[0] != "spotify:track:1" {
+		t.Errorf("added = %v", added)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build linux
+
+package sysevents
+
+import (
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// platformBootClock reads CLOCK_BOOTTIME, which unlike the monotonic clock
+// keeps counting while the machine is suspended
+func platformBootClock() func() (time.Duration, bool) {
+	return func() (time.Duration, bool) {
+		var ts unix.Timespec
+		if err := unix.ClockGettime(unix.CLOCK_BOOTTIME, &ts); err != nil {
+			return 0, false
+		}
+		return time.Duration(ts.Nano()), true
+	}
+}
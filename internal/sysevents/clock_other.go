@@ -0,0 +1,11 @@
+//go:build !linux
+
+package sysevents
+
+import "time"
+
+// platformBootClock has no clock counting suspended time off Linux; resume
+// is detected from the monotonic clock alone
+func platformBootClock() func() (time.Duration, bool) {
+	return nil
+}
@@ -0,0 +1,33 @@
+//go:build linux
+
+package sysevents
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// platformDevices lists ALSA cards from /proc/asound/cards. Removing a USB
+// or Bluetooth headset drops its card; analog jack sensing on a built-in
+// card is not visible here.
+func platformDevices() func() ([]string, error) {
+	return func() ([]string, error) {
+		f, err := os.Open("/proc/asound/cards")
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		var cards []string
+		sc := bufio.NewScanner(f)
+		for sc.Scan() {
+			// Card lines look like " 0 [PCH            ]: HDA-Intel - HDA Intel PCH";
+			// the indented line after each one is a longer description
+			if _, desc, ok := strings.Cut(sc.Text(), "]: "); ok {
+				cards = append(cards, strings.TrimSpace(desc))
+			}
+		}
+		return cards, sc.Err()
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package sysevents
+
+// platformDevices has no device listing off Linux; only resume is detected
+func platformDevices() func() ([]string, error) {
+	return nil
+}
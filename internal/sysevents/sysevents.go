@@ -0,0 +1,152 @@
+// Package sysevents watches for system events that should affect playback:
+// resuming from suspend and audio devices (USB/Bluetooth headsets, DACs)
+// disappearing or coming back.
+package sysevents
+
+import (
+	"context"
+	"slices"
+	"time"
+)
+
+// Kind identifies a system event
+type Kind int
+
+const (
+	Resumed Kind = iota // the machine woke from suspend
+	DeviceRemoved
+	DeviceAdded
+)
+
+// Event is a single detected system event
+type Event struct {
+	Kind   Kind
+	Device string // device name for DeviceRemoved/DeviceAdded
+}
+
+// suspendThreshold is how much longer the boot clock must have run than
+// the monotonic clock between polls before the gap is treated as a suspend
+const suspendThreshold = 5 * time.Second
+
+// clocks is one reading of the monotonic clock, which stops while the
+// machine is suspended, and of the boot clock, which keeps counting.
+// Neither moves when the wall clock is set, by NTP or by hand.
+type clocks struct {
+	mono    time.Duration
+	boot    time.Duration
+	hasBoot bool // the platform has a boot clock
+}
+
+// Watcher polls for suspend/resume and audio device changes
+type Watcher struct {
+	Interval time.Duration
+	// listDevices returns the current audio devices; nil disables device events
+	listDevices func() ([]string, error)
+	// readClocks reads the clocks resume is detected by
+	readClocks func() clocks
+}
+
+// NewWatcher creates a watcher using the platform's device listing
+func NewWatcher() *Watcher {
+	start := time.Now()
+	bootClock := platformBootClock()
+	return &Watcher{
+		Interval:    time.Second,
+		listDevices: platformDevices(),
+		readClocks: func() clocks {
+			c := clocks{mono: time.Since(start)}
+			if bootClock != nil {
+				c.boot, c.hasBoot = bootClock()
+			}
+			return c
+		},
+	}
+}
+
+// pollState is what a poll compares the next one against
+type pollState struct {
+	started bool
+	clocks  clocks
+	devices []string
+	known   bool // devices has been listed successfully
+}
+
+// Run polls until ctx is done, sending events on the returned channel.
+// Suspend itself cannot be observed from a sleeping process, so it is
+// reported as Resumed as soon as the process runs again.
+func (w *Watcher) Run(ctx context.Context) <-chan Event {
+	out := make(chan Event, 8)
+	go func() {
+		defer close(out)
+
+		ticker := time.NewTicker(w.Interval)
+		defer ticker.Stop()
+
+		var st pollState
+		w.poll(&st)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, ev := range w.poll(&st) {
+					send(ctx, out, ev)
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// poll reads the clocks and devices and returns what changed since the
+// previous poll. The first poll only records them. A failed device listing
+// counts as no change, so it never reports devices as removed.
+func (w *Watcher) poll(st *pollState) []Event {
+	var events []Event
+	c := w.readClocks()
+	if st.started && suspended(st.clocks, c, w.Interval) {
+		events = append(events, Event{Kind: Resumed})
+	}
+	st.started, st.clocks = true, c
+
+	if w.listDevices == nil {
+		return events
+	}
+	current, err := w.listDevices()
+	if err != nil {
+		return events
+	}
+	if st.known {
+		for _, d := range st.devices {
+			if !slices.Contains(current, d) {
+				events = append(events, Event{Kind: DeviceRemoved, Device: d})
+			}
+		}
+		for _, d := range current {
+			if !slices.Contains(st.devices, d) {
+				events = append(events, Event{Kind: DeviceAdded, Device: d})
+			}
+		}
+	}
+	st.devices, st.known = current, true
+	return events
+}
+
+// suspended reports whether the machine slept between two readings: the
+// boot clock ran on while the monotonic clock stood still. Without a boot
+// clock it falls back to the monotonic gap running well past the poll
+// interval, which catches suspend only where that clock counts it.
+func suspended(prev, now clocks, interval time.Duration) bool {
+	mono := now.mono - prev.mono
+	if prev.hasBoot && now.hasBoot {
+		return (now.boot-prev.boot)-mono > suspendThreshold
+	}
+	return mono-interval > suspendThreshold
+}
+
+func send(ctx context.Context, out chan<- Event, ev Event) {
+	select {
+	case out <- ev:
+	case <-ctx.Done():
+	}
+}
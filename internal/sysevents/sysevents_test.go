@@ -0,0 +1,72 @@
+package sysevents
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSuspended(t *testing.T) {
+	s := time.Second
+	for _, tc := range []struct {
+		name      string
+		prev, now clocks
+		want      bool
+	}{
+		{"regular poll", clocks{0, 100 * s, true}, clocks{s, 101 * s, true}, false},
+		{"suspended a minute", clocks{0, 100 * s, true}, clocks{s, 161 * s, true}, true},
+		{"just under the threshold", clocks{0, 100 * s, true}, clocks{s, 106 * s, true}, false},
+		{"no boot clock, on time", clocks{mono: 0}, clocks{mono: s}, false},
+		{"no boot clock, long gap", clocks{mono: 0}, clocks{mono: time.Minute}, true},
+	} {
+		if got := suspended(tc.prev, tc.now, time.Second); got != tc.want {
+			t.Errorf("%s: suspended = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestPoll_Devices(t *testing.T) {
+	devices := []string{"HDA Intel PCH", "USB Headset"}
+	var listErr error
+	w := &Watcher{
+		Interval:    time.Second,
+		listDevices: func() ([]string, error) { return devices, listErr },
+		readClocks:  func() clocks { return clocks{} },
+	}
+	var st pollState
+	if evs := w.poll(&st); len(evs) != 0 {
+		t.Fatalf("first poll = %v", evs)
+	}
+
+	// A failed listing is no change, not every device gone
+	listErr = errors.New("busy")
+	if evs := w.poll(&st); len(evs) != 0 {
+		t.Fatalf("poll with a listing error = %v", evs)
+	}
+
+	listErr = nil
+	devices = []string{"HDA Intel PCH"}
+	evs := w.poll(&st)
+	if len(evs) != 1 || evs[0] != (Event{Kind: DeviceRemoved, Device: "USB Headset"}) {
+		t.Fatalf("unplug = %v", evs)
+	}
+	devices = []string{"HDA Intel PCH", "USB Headset"}
+	evs = w.poll(&st)
+	if len(evs) != 1 || evs[0] != (Event{Kind: DeviceAdded, Device: "USB Headset"}) {
+		t.Fatalf("replug = %v", evs)
+	}
+}
+
+func TestPoll_FirstListingFails(t *testing.T) {
+	listErr := errors.New("no sound cards")
+	w := &Watcher{
+		listDevices: func() ([]string, error) { return []string{"USB DAC"}, listErr },
+		readClocks:  func() clocks { return clocks{} },
+	}
+	var st pollState
+	w.poll(&st)
+	listErr = nil
+	if evs := w.poll(&st); len(evs) != 0 {
+		t.Errorf("devices present all along reported as added: %v", evs)
+	}
+}
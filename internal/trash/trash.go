@@ -0,0 +1,28 @@
+// Package trash moves files to the desktop's trash, where they can still
+// be restored: the freedesktop.org trash on Linux and the Finder's on
+// macOS.
+package trash
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnsupported is returned where no trash is known
+var ErrUnsupported = errors.New("moving files to the trash is not supported on this platform")
+
+// Supported reports whether Move can work on this platform
+func Supported() bool {
+	return supported
+}
+
+// Move moves the file at path to the trash
+func Move(path string) error {
+	if !supported {
+		return ErrUnsupported
+	}
+	if err := move(path); err != nil {
+		return fmt.Errorf("move %s to the trash: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+//go:build darwin
+
+package trash
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const supported = true
+
+// move asks the Finder to delete path, which moves it to the trash with
+// "Put Back" information
+func move(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(abs); err != nil {
+		return err
+	}
+	script := "tell application \"Finder\" to delete POSIX file " + strconv.Quote(abs)
+	if out, err := exec.Command("osascript", "-e", script).CombinedOutput(); err != nil {
+		return fmt.Errorf("osascript: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+//go:build linux
+
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const supported = true
+
+// move puts path in the home trash of the freedesktop.org trash spec,
+// with an info file saying where it came from so file managers can restore
+// it. Files on another file system than the home trash are handed to gio,
+// which knows their drive's trash directory.
+func move(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Lstat(abs); err != nil {
+		return err
+	}
+
+	dir := homeTrash()
+	files, info := filepath.Join(dir, "files"), filepath.Join(dir, "info")
+	for _, d := range []string{files, info} {
+		if err := os.MkdirAll(d, 0o700); err != nil {
+			return err
+		}
+	}
+
+	base := filepath.Base(abs)
+	ext := filepath.Ext(base)
+	for i := 1; ; i++ {
+		name := base
+		if i > 1 {
+			name = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(base, ext), i, ext)
+		}
+		// Creating the info file exclusively claims the name
+		infoPath := filepath.Join(info, name+".trashinfo")
+		f, err := os.OpenFile(infoPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+		if errors.Is(err, fs.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(f, "[Trash Info]\nPath=%s\nDeletionDate=%s\n",
+			(&url.URL{Path: abs}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+		if err = errors.Join(err, f.Close()); err != nil {
+			os.Remove(infoPath)
+			return err
+		}
+
+		err = os.Rename(abs, filepath.Join(files, name))
+		if err == nil {
+			return nil
+		}
+		os.Remove(infoPath)
+		if errors.Is(err, syscall.EXDEV) {
+			return gioTrash(abs)
+		}
+		return err
+	}
+}
+
+// homeTrash returns the trash directory of the user's home
+func homeTrash() string {
+	data := os.Getenv("XDG_DATA_HOME")
+	if data == "" {
+		home, _ := os.UserHomeDir()
+		data = filepath.Join(home, ".local", "share")
+	}
+	return filepath.Join(data, "Trash")
+}
+
+// gioTrash moves a file to the trash with GLib's gio tool
+func gioTrash(path string) error {
+	if _, err := exec.LookPath("gio"); err != nil {
+		return errors.New("the file is on another drive than the trash, and gio is not installed")
+	}
+	if out, err := exec.Command("gio", "trash", "--", path).CombinedOutput(); err != nil {
+		return fmt.Errorf("gio trash: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+//go:build linux
+
+package trash
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMove verifies files land in the home trash under free names, with
+// info files recording their escaped original path
+func TestMove(t *testing.T) {
+	data := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", data)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bad rip.mp3")
+
+	for range 2 {
+		if err := os.WriteFile(path, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := Move(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file still at %s", path)
+	}
+
+	trash := filepath.Join(data, "Trash")
+	for _, name := range []string{"bad rip.mp3", "bad rip.2.mp3"} {
+		if _, err := os.Stat(filepath.Join(trash, "files", name)); err != nil {
+			t.Errorf("trashed file: %v", err)
+		}
+	}
+	info, err := os.ReadFile(filepath.Join(trash, "info", "bad rip.mp3.trashinfo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Path=" + strings.ReplaceAll(path, " ", "%20") + "\n"; !strings.Contains(string(info), want) {
+		t.Errorf("info file %q lacks %q", info, want)
+	}
+
+	if err := Move(path); err == nil {
+		t.Error("moving a missing file succeeded")
+	}
+}
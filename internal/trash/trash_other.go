@@ -0,0 +1,10 @@
+//go:build !linux && !darwin
+
+package trash
+
+const supported = false
+
+// move has no trash to move to here
+func move(path string) error {
+	return ErrUnsupported
+}
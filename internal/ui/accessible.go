@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+)
+
+// updateAnnouncement words the playback change state makes, if any, for
+// the announcement line. trackID is the ID of state's track.
+func (m *Model) updateAnnouncement(state *api.PlaybackState, trackID string) {
+	track := state.CurrentTrack
+	switch {
+	case state.Status == api.StatusPlaying && trackID != m.lastTrack && track != nil:
+		m.announcement = i18n.T("Now playing: %s", track.Title)
+		if track.Artist != "" {
+			m.announcement = i18n.T("Now playing: %s by %s", track.Title, track.Artist)
+		}
+	case state.Status == m.lastStatus:
+	case state.Status == api.StatusPlaying && track != nil:
+		m.announcement = i18n.T("Resumed: %s", track.Title)
+	case state.Status == api.StatusPaused && track != nil:
+		m.announcement = i18n.T("Paused: %s", track.Title)
+	case state.Status == api.StatusStopped:
+		m.announcement = i18n.T("Stopped")
+	}
+}
+
+// monochrome removes the colors from the SGR escape sequences in s,
+// keeping bold, underline and the like. A background color becomes reverse
+// video, so selected rows and menu entries stay visible.
+func monochrome(s string) string {
+	var sb strings.Builder
+	for {
+		i := strings.Index(s, "\x1b[")
+		if i < 0 {
+			sb.WriteString(s)
+			return sb.String()
+		}
+		// Other sequences, such as cursor movement, pass through
+		end := i + 2 + strings.IndexFunc(s[i+2:], func(r rune) bool { return r != ';' && r != ':' && (r < '0' || r > '9') })
+		if end < i+2 || s[end] != 'm' {
+			sb.WriteString(s[:i+2])
+			s = s[i+2:]
+			continue
+		}
+		sb.WriteString(s[:i])
+		params := s[i+2 : end]
+		s = s[end+1:]
+		if params == "" {
+			sb.WriteString("\x1b[m")
+			continue
+		}
+		if kept := sgrWithoutColor(strings.Split(params, ";")); len(kept) > 0 {
+			sb.WriteString("\x1b[" + strings.Join(kept, ";") + "m")
+		}
+	}
+}
+
+// sgrWithoutColor returns the SGR parameters with foreground and underline
+// colors dropped and background colors turned into reverse video. Colors
+// come as 38;5;n and 38;2;r;g;b over several parameters, or as 38:5:n and
+// 38:2::r:g:b in one.
+func sgrWithoutColor(params []string) []string {
+	var kept []string
+	for i := 0; i < len(params); i++ {
+		code, _, sub := strings.Cut(params[i], ":")
+		n, err := strconv.Atoi(code)
+		switch {
+		case err != nil:
+			kept = append(kept, params[i])
+		case n == 38 || n == 48 || n == 58:
+			if !sub && i+1 < len(params) && params[i+1] == "5" {
+				i += 2
+			} else if !sub && i+1 < len(params) && params[i+1] == "2" {
+				i += 4
+			}
+			if n == 48 {
+				kept = append(kept, "7")
+			}
+		case n >= 30 && n <= 39, n >= 90 && n <= 97:
+		case n >= 40 && n <= 47, n >= 100 && n <= 107:
+			kept = append(kept, "7")
+		case n == 49:
+			kept = append(kept, "27")
+		case n == 59:
+		default:
+			kept = append(kept, params[i])
+		}
+	}
+	return kept
+}
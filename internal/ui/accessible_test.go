@@ -0,0 +1,38 @@
+package ui
+
+import "testing"
+
+// TestMonochrome verifies colors are taken out of SGR sequences in both
+// the semicolon and the colon form, while attributes and other escape
+// sequences stay
+func TestMonochrome(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain text", "Björk ▶", "Björk ▶"},
+		{"reset", "\x1b[mx\x1b[0m", "\x1b[mx\x1b[0m"},
+		{"basic foreground", "\x1b[1;31mx", "\x1b[1mx"},
+		{"only a color", "\x1b[32mx\x1b[39m", "x"},
+		{"bright foreground", "\x1b[4;91mx", "\x1b[4mx"},
+		{"basic background", "\x1b[44mx\x1b[49m", "\x1b[7mx\x1b[27m"},
+		{"256 colors", "\x1b[38;5;212;1mx", "\x1b[1mx"},
+		{"256 background", "\x1b[48;5;236mx", "\x1b[7mx"},
+		{"true color", "\x1b[38;2;124;58;237;3mx", "\x1b[3mx"},
+		{"true color background", "\x1b[1;48;2;31;41;55mx", "\x1b[1;7mx"},
+		{"colon 256 colors", "\x1b[38:5:212;1mx", "\x1b[1mx"},
+		{"colon true color", "\x1b[38:2::124:58:237mx", "x"},
+		{"colon background", "\x1b[48:5:236;4mx", "\x1b[7;4mx"},
+		{"underline color", "\x1b[4:3;58:5:196mx\x1b[59m", "\x1b[4:3mx"},
+		{"cursor movement", "\x1b[2Ax\x1b[10;5H", "\x1b[2Ax\x1b[10;5H"},
+		{"cut off", "x\x1b[38;5", "x\x1b[38;5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := monochrome(tt.in); got != tt.want {
+				t.Errorf("monochrome(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
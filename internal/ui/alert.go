@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Alert is how the terminal signals an event to someone not looking at it,
+// e.g. when the player runs in a background tmux pane
+type Alert int
+
+const (
+	AlertNone  Alert = iota
+	AlertBell        // ring the terminal bell (tmux marks the window)
+	AlertFlash       // briefly invert the tab bar
+	AlertBoth
+)
+
+// flashDuration is how long a visual flash lasts
+const flashDuration = 250 * time.Millisecond
+
+// flashDoneMsg ends a visual flash
+type flashDoneMsg struct{}
+
+// ParseAlert parses "bell", "flash", "both" or "" / "none"
+func ParseAlert(s string) (Alert, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "none", "off":
+		return AlertNone, nil
+	case "bell":
+		return AlertBell, nil
+	case "flash":
+		return AlertFlash, nil
+	case "both":
+		return AlertBoth, nil
+	}
+	return AlertNone, fmt.Errorf("unknown alert %q (want bell, flash or both)", s)
+}
+
+// bellOutput is the program's output. A bell is not written from Update,
+// where it would race the renderer and could land inside a frame, but
+// ahead of the renderer's next write.
+type bellOutput struct {
+	*os.File
+	ringing atomic.Bool
+}
+
+// ring asks for a bell with the next write
+func (o *bellOutput) ring() {
+	o.ringing.Store(true)
+}
+
+// Write writes p, after a bell if one was asked for
+func (o *bellOutput) Write(p []byte) (int, error) {
+	if o.ringing.Swap(false) {
+		if _, err := o.File.Write([]byte("\a")); err != nil {
+			return 0, err
+		}
+	}
+	return o.File.Write(p)
+}
+
+// alert signals an event and returns the command that ends a flash
+func (m *Model) alert(a Alert) tea.Cmd {
+	if (a == AlertBell || a == AlertBoth) && m.bell != nil {
+		m.bell.ring()
+	}
+	if a == AlertFlash || a == AlertBoth {
+		m.flashing = true
+		return tea.Tick(flashDuration, func(time.Time) tea.Msg { return flashDoneMsg{} })
+	}
+	return nil
+}
+
+// pendingAlerts raises the alerts for an error shown or a track started
+// since the last check
+func (m *Model) pendingAlerts() tea.Cmd {
+	var cmds []tea.Cmd
+	if m.err != nil && !errors.Is(m.err, m.alertedErr) {
+		cmds = append(cmds, m.alert(m.errorAlert))
+	}
+	m.alertedErr = m.err
+	if m.trackChanged {
+		m.trackChanged = false
+		cmds = append(cmds, m.alert(m.trackAlert))
+	}
+	return tea.Batch(cmds...)
+}
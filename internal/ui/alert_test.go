@@ -0,0 +1,35 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestBellOutput verifies a bell is written once, ahead of the next frame
+// rather than when it is asked for
+func TestBellOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	out := &bellOutput{File: f}
+	m := &Model{bell: out}
+	out.Write([]byte("one"))
+	m.alert(AlertBell)
+	m.alert(AlertBoth)
+	if data, _ := os.ReadFile(path); string(data) != "one" {
+		t.Errorf("bell written outside a frame: %q", data)
+	}
+	out.Write([]byte("two"))
+	out.Write([]byte("three"))
+	if data, _ := os.ReadFile(path); string(data) != "one\atwothree" {
+		t.Errorf("output = %q, want one bell before the second frame", data)
+	}
+	if !m.flashing {
+		t.Error("AlertBoth should flash too")
+	}
+}
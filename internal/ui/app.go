@@ -2,17 +2,44 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/artwork"
 	"github.com/jscyril/golang_music_player/internal/audio"
+	"github.com/jscyril/golang_music_player/internal/audiobook"
+	"github.com/jscyril/golang_music_player/internal/crash"
+	"github.com/jscyril/golang_music_player/internal/enrich"
+	"github.com/jscyril/golang_music_player/internal/i18n"
 	"github.com/jscyril/golang_music_player/internal/library"
 	"github.com/jscyril/golang_music_player/internal/logger"
+	"github.com/jscyril/golang_music_player/internal/notify"
 	"github.com/jscyril/golang_music_player/internal/playlist"
+	"github.com/jscyril/golang_music_player/internal/schedule"
+	"github.com/jscyril/golang_music_player/internal/search"
+	"github.com/jscyril/golang_music_player/internal/streaming"
+	"github.com/jscyril/golang_music_player/internal/trash"
+	"github.com/jscyril/golang_music_player/internal/ui/components"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+	"github.com/jscyril/golang_music_player/internal/ui/keymap"
 	"github.com/jscyril/golang_music_player/internal/ui/views"
+	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
+	"github.com/jscyril/golang_music_player/pkg/events"
+	"github.com/jscyril/golang_music_player/pkg/stats"
+	"github.com/muesli/termenv"
 )
 
 // ViewType represents the current active view
@@ -22,8 +49,14 @@ const (
 	ViewPlayer ViewType = iota
 	ViewLibrary
 	ViewPlaylist
+	ViewQueue
+	ViewHistory
+	ViewStats
 )
 
+// viewCount is the number of tabs
+const viewCount = 6
+
 // Model is the main bubbletea model
 type Model struct {
 	// Dimensions
@@ -33,21 +66,86 @@ type Model struct {
 	// Current view
 	activeView ViewType
 
+	// Split layout: the library and queue views side by side, the active
+	// one of them focused. splitPercent is the left pane's share.
+	split        bool
+	splitPercent int
+
 	// Views
 	playerView   views.PlayerView
+	nowPlaying   views.NowPlayingBar
 	libraryView  views.LibraryView
 	playlistView views.PlaylistView
+	queueView    views.QueueView
+	historyView  views.HistoryView
+	statsView    views.DashboardView
 
 	// Components
 	audioEngine     *audio.AudioEngine
 	library         *library.Library
 	playlistManager *playlist.Manager
 	queue           *playlist.Queue
+	searcher        *search.Federated
+	books           *audiobook.Store
+	marks           *audiobook.Bookmarks
+	markPopup       views.BookmarkPopup
+	accents         *artwork.Cache
+	keys            *keymap.Map
+	events          <-chan api.AudioEvent
+	errorAlert      Alert
+	crossfade       time.Duration
+	trackAlert      Alert
+	upNext          time.Duration
+	upNextNotify    bool
+	libraryPath     string
+	enricher        *enrich.Client
+	streaming       []streaming.Service
+	scanPaths       []string
+	scanOnStart     bool
+	addMusicDir     func(path string) error
+	saveBookmarks   func(bookmarks []string) error
+	skipConfirm     map[string]bool // shared with the views that ask
+	saveSkipConfirm func(actions []string) error
+	scanCancel      context.CancelFunc  // stops the running scan; nil when idle
+	scanReport      *library.ScanReport // result of the last scan, shown in the status line
+	queueFile       string
+	readOnly        bool
+	queues          *playlist.QueueStore
+	queueResume     *queueResume
+	levels          api.LevelsProvider // live output levels for the player meters
+	bus             *events.EventBus   // nil when only the engine's events are followed
+	cast            *castState
+	log             *logViewer
+	sessions        *stats.Stats // listening sessions since the UI started
+	schedules       *schedulesState
+	accessible      bool
+	noColor         bool
+	announcement    string // last playback change, shown when accessible
 
 	// State
-	ctx    context.Context
-	cancel context.CancelFunc
-	err    error
+	ctx        context.Context
+	cancel     context.CancelFunc
+	err        error
+	notice     string // a warning for the status line, until the next key
+	lastTrack  string // ID of the last announced track
+	lastStatus api.PlayerStatus
+
+	// Play history entry for the track currently loaded
+	logTrack *api.Track
+	logStart time.Time
+	logPos   time.Duration
+
+	accentPath string // file whose album-art accent the player view shows
+	fadedFrom  string // ID of the track a crossfade has already left
+	announced  string // ID of the track whose successor was last announced
+	savePoint  *playlist.SavePoint
+	showHelp   bool
+	metering   bool // a levelsTickMsg is pending
+
+	alertedErr   error // last error an alert was raised for
+	trackChanged bool  // a new track started since the last alert check
+	flashing     bool
+	bell         *bellOutput // the program's output; nil rings no bell
 
 	// Styles
 	tabStyle       lipgloss.Style
@@ -58,16 +156,223 @@ type Model struct {
 // TickMsg is sent periodically to update the UI
 type TickMsg time.Time
 
+// levelsTickMsg redraws the player's level meters
+type levelsTickMsg struct{}
+
+// levelsInterval is how often the level meters redraw
+const levelsInterval = 80 * time.Millisecond
+
 // StateUpdateMsg is sent when playback state changes
 type StateUpdateMsg struct {
 	State *api.PlaybackState
 }
 
 // TrackEndedMsg is sent when a track finishes playing
-type TrackEndedMsg struct{}
+type TrackEndedMsg struct {
+	Track *api.Track // nil for HTTP streams
+}
+
+// searchDebounceMsg fires after the user pauses typing a search query
+type searchDebounceMsg struct {
+	query string
+}
+
+// SearchResultsMsg carries merged results from all search sources
+type SearchResultsMsg struct {
+	Query   string
+	Results []search.Result
+	Errors  []error
+}
+
+// searchDebounce is how long to wait after a keystroke before querying remote sources
+const searchDebounce = 250 * time.Millisecond
+
+// engineErrorMsg carries an error reported by the audio engine
+type engineErrorMsg struct {
+	err error
+}
+
+// libraryChangedMsg reports tracks added, removed or archived elsewhere
+type libraryChangedMsg struct{}
+
+// scanProgressMsg carries the progress of a library scan
+type scanProgressMsg struct {
+	progress api.ScanProgress
+}
+
+// scanDoneMsg reports that a library scan returned
+type scanDoneMsg struct {
+	report library.ScanReport
+	err    error
+}
+
+// playlistChangedMsg reports a playlist saved or deleted elsewhere
+type playlistChangedMsg struct{}
+
+// metadataLookupLimit caps the tracks looked up at once; MusicBrainz
+// allows one request per second
+const metadataLookupLimit = 50
+
+// queueResume is where a restored queue's current track was left. It is
+// handed to the engine once, the next time that track starts.
+type queueResume struct {
+	mu sync.Mutex
+	id string
+	at time.Duration
+}
+
+func (r *queueResume) set(track *api.Track, at time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.id, r.at = "", 0
+	if track != nil {
+		r.id, r.at = track.ID, at
+	}
+}
+
+func (r *queueResume) take(track *api.Track) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.id == "" || track.ID != r.id {
+		return 0
+	}
+	at := r.at
+	r.id, r.at = "", 0
+	return at
+}
+
+// shareMatchesMsg carries the songs a streaming service matched to the
+// tracks of a playlist being exported
+type shareMatchesMsg struct {
+	items []streaming.Match
+	err   error
+}
+
+// shareCreatedMsg reports a playlist created on a streaming service
+type shareCreatedMsg struct {
+	service string
+	name    string
+	link    string
+	err     error
+}
+
+// proposalsMsg carries the results of a metadata lookup
+type proposalsMsg struct {
+	items []enrich.Proposal
+	err   error
+}
+
+// trackDetailsMsg carries what was read from a track's file for the
+// details overlay
+type trackDetailsMsg struct {
+	id      string
+	details *library.FileDetails
+	err     error
+}
+
+// accentMsg carries the album-art accent computed for a track file
+type accentMsg struct {
+	path  string
+	color string
+}
+
+// Options holds the optional collaborators of the UI. Nil fields disable
+// the corresponding feature.
+type Options struct {
+	Searcher *search.Federated
+	Books    *audiobook.Store
+	Marks    *audiobook.Bookmarks // positions bookmarked in tracks; nil disables them
+	Accents  *artwork.Cache
+	Keys     *keymap.Map // nil uses the default bindings
+
+	// Bus is the event bus shared by the engine, library and playlists.
+	// Nil listens to the engine's own events only.
+	Bus *events.EventBus
+
+	Queue *playlist.Queue // startup queue, played right away; nil starts empty
+
+	// QueueFile keeps the queue across sessions: it is saved there on quit
+	// and, without a startup Queue, restored from it (but not played)
+	QueueFile string
+	Queues    *playlist.QueueStore // named saved queues; nil disables them
+
+	LibraryPath string // where the library is saved after tag edits; empty skips saving
+
+	// ReadOnly tells the user another instance owns the data directory, so
+	// nothing here is saved, and keeps the queue from being saved on quit
+	ReadOnly bool
+
+	Enricher *enrich.Client // online metadata lookup; nil disables it
+
+	// Streaming are the services playlists can be exported to
+	Streaming []streaming.Service
+
+	// ScanPaths are the music directories rescanned from the library view.
+	// ScanOnStart scans them as soon as the UI is up.
+	ScanPaths   []string
+	ScanOnStart bool
+
+	// AddMusicDir saves a directory added from the file browser with "A"
+	// to the configured music directories; nil only adds it for the session
+	AddMusicDir func(path string) error
+
+	// Bookmarks are the file browser's bookmarked folders; SaveBookmarks
+	// stores them when they change, nil keeps changes for the session
+	Bookmarks     []string
+	SaveBookmarks func(bookmarks []string) error
+
+	// Schedule sets off alarms and quiet hours while the UI runs; nil has
+	// none. SaveAlarm stores an alarm switched on or off in the schedules
+	// overlay, by its index in Schedule.Alarms; nil keeps it for the session.
+	Schedule  *schedule.Scheduler
+	SaveAlarm func(i int, disabled bool) error
+
+	// SkipConfirm lists the actions asked about no more; SaveSkipConfirm
+	// stores the list when "don't ask again" is chosen, nil keeps it for
+	// the session
+	SkipConfirm     []string
+	SaveSkipConfirm func(actions []string) error
+
+	// SplitPane starts with the library and queue side by side;
+	// SplitPercent is the library's share of the width (0 is half)
+	SplitPane    bool
+	SplitPercent int
+
+	// Columns of the library, queue and playlist track lists; nil keeps
+	// components.DefaultColumns
+	LibraryColumns  []components.Column
+	QueueColumns    []components.Column
+	PlaylistColumns []components.Column
+
+	// Crossfade overlaps consecutive tracks by this long; 0 disables it.
+	// Gapless album tracks are never crossfaded.
+	Crossfade time.Duration
+
+	// UpNext announces the next queued track this long before the end of
+	// the current one; 0 disables it. UpNextNotify also sends a desktop
+	// notification.
+	UpNext       time.Duration
+	UpNextNotify bool
+
+	ErrorAlert Alert // signalled when an error is shown
+	TrackAlert Alert // signalled when a new track starts
+
+	// CastPort is the port files are served to cast devices on; 0 picks
+	// a free one
+	CastPort int
+
+	// Accessible announces playback changes as plain text on a line of
+	// their own and draws the UI with ASCII glyphs instead of emoji and
+	// box drawing
+	Accessible bool
+
+	// NoColor draws the UI in ASCII without colors; what is highlighted
+	// with a background shows in reverse video instead
+	NoColor bool
+}
 
 // NewModel creates a new application model
-func NewModel(engine *audio.AudioEngine, lib *library.Library, plManager *playlist.Manager) Model {
+func NewModel(engine *audio.AudioEngine, lib *library.Library, plManager *playlist.Manager, opts Options) Model {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	m := Model{
@@ -78,6 +383,39 @@ func NewModel(engine *audio.AudioEngine, lib *library.Library, plManager *playli
 		library:         lib,
 		playlistManager: plManager,
 		queue:           playlist.NewQueue(),
+		searcher:        opts.Searcher,
+		books:           opts.Books,
+		marks:           opts.Marks,
+		accents:         opts.Accents,
+		keys:            opts.Keys,
+		errorAlert:      opts.ErrorAlert,
+		crossfade:       opts.Crossfade,
+		trackAlert:      opts.TrackAlert,
+		upNext:          opts.UpNext,
+		upNextNotify:    opts.UpNextNotify,
+		split:           opts.SplitPane,
+		splitPercent:    opts.SplitPercent,
+		libraryPath:     opts.LibraryPath,
+		enricher:        opts.Enricher,
+		streaming:       opts.Streaming,
+		scanPaths:       opts.ScanPaths,
+		scanOnStart:     opts.ScanOnStart,
+		addMusicDir:     opts.AddMusicDir,
+		saveBookmarks:   opts.SaveBookmarks,
+		skipConfirm:     make(map[string]bool),
+		saveSkipConfirm: opts.SaveSkipConfirm,
+		queueFile:       opts.QueueFile,
+		readOnly:        opts.ReadOnly,
+		queues:          opts.Queues,
+		queueResume:     &queueResume{},
+		levels:          engine,
+		bus:             opts.Bus,
+		cast:            &castState{port: opts.CastPort},
+		accessible:      opts.Accessible,
+		noColor:         opts.NoColor,
+		log:             &logViewer{},
+		sessions:        stats.New(),
+		schedules:       &schedulesState{sched: opts.Schedule, save: opts.SaveAlarm},
 		ctx:             ctx,
 		cancel:          cancel,
 		tabStyle: lipgloss.NewStyle().
@@ -94,26 +432,124 @@ func NewModel(engine *audio.AudioEngine, lib *library.Library, plManager *playli
 			MarginBottom(1),
 	}
 
+	if m.keys == nil {
+		m.keys = keymap.Default()
+	}
+	if opts.Bus != nil {
+		// Library and playlist changes arrive in bursts during scans and
+		// imports; a larger buffer keeps them from crowding out playback
+		m.events = opts.Bus.SubscribeWith(events.Policy{Buffer: 64})
+	} else {
+		m.events = engine.Events()
+	}
+	if opts.Queue != nil {
+		m.queue = opts.Queue
+	}
+	m.queue.SetShuffleExclude(lib.ShuffleExcluded)
+	if opts.Bus != nil {
+		m.queue.SetPublisher(opts.Bus)
+	}
+	engine.SetQueue(m.queue)
+	books, resume := m.books, m.queueResume
+	engine.SetResume(func(track *api.Track) time.Duration {
+		if at := resume.take(track); at > 0 {
+			return at
+		}
+		return books.Position(track)
+	})
+
 	// Initialize views
 	m.playerView = views.NewPlayerView(m.width, m.height/3)
-	m.libraryView = views.NewLibraryView(m.width, m.height-10)
-	m.playlistView = views.NewPlaylistView(m.width, m.height-10)
+	m.nowPlaying = views.NewNowPlayingBar(m.width)
+	if m.splitPercent == 0 {
+		m.splitPercent = 50
+	}
+	m.splitPercent = min(max(m.splitPercent, splitMinPercent), 100-splitMinPercent)
+	m.libraryView = views.NewLibraryView(m.width, m.contentHeight())
+	m.playlistView = views.NewPlaylistView(m.width, m.contentHeight())
+	m.queueView = views.NewQueueView(m.width, m.contentHeight())
+	m.historyView = views.NewHistoryView(m.width, m.contentHeight())
+	m.statsView = views.NewDashboardView(m.width, m.contentHeight())
+	m.libraryView.TrackList.Columns = opts.LibraryColumns
+	m.queueView.TrackList.Columns = opts.QueueColumns
+	m.playlistView.TrackList.Columns = opts.PlaylistColumns
 
 	// Load library tracks into view
 	m.libraryView.SetTracks(lib.GetAllTracks())
+	m.libraryView.SetGenreTree(lib.GenreTree())
+	m.libraryView.SetUserTags(lib.AllUserTags())
+	m.libraryView.SetBookmarks(opts.Bookmarks)
+	for _, action := range opts.SkipConfirm {
+		m.skipConfirm[action] = true
+	}
+	m.libraryView.SkipConfirm = m.skipConfirm
+	m.libraryView.Trash = trash.Supported()
+	m.playlistView.SkipConfirm = m.skipConfirm
+	for _, svc := range m.streaming {
+		m.playlistView.Services = append(m.playlistView.Services, svc.Name())
+	}
 
 	// Load playlists
-	m.playlistView.SetPlaylists(plManager.GetAll())
+	m.refreshPlaylists()
+	m.refreshHistoryView()
+
+	if opts.Queue != nil {
+		if track := m.queue.Current(); track != nil {
+			m.activeView = ViewPlayer
+			m.play(track)
+		}
+	} else if m.queueFile != "" {
+		m.restoreQueue()
+	}
+	m.refreshQueueView()
 
 	return m
 }
 
+// restoreQueue loads the queue of the last session, ready to resume where
+// it was left
+func (m *Model) restoreQueue() {
+	sq, err := playlist.LoadQueueFile(m.queueFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err != nil {
+		logger.Warn("Failed to restore the queue: %v", err)
+		return
+	}
+	m.queue.Load(sq)
+	m.queueResume.set(m.queue.Current(), sq.Position)
+	logger.Info("Restored queue of %d track(s)", m.queue.Len())
+}
+
+// saveQueue writes the queue to QueueFile with the position in the track
+// playing now
+func (m *Model) saveQueue() {
+	if m.queueFile == "" || m.readOnly {
+		return
+	}
+	if err := playlist.SaveQueueFile(m.queueFile, m.queue.Saved("", m.queuePosition())); err != nil {
+		logger.Error("Failed to save the queue: %v", err)
+	}
+}
+
+// queuePosition is how far into the queue's current track playback is
+func (m *Model) queuePosition() time.Duration {
+	state := m.audioEngine.GetState()
+	current := m.queue.Current()
+	if current == nil || state.CurrentTrack == nil || state.CurrentTrack.ID != current.ID {
+		return 0
+	}
+	return state.Position
+}
+
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(
-		tickCmd(),
-		m.listenForEvents(),
-	)
+	cmds := []tea.Cmd{tickCmd(), m.listenForEvents()}
+	if m.scanOnStart {
+		cmds = append(cmds, func() tea.Msg { return views.RescanMsg{} })
+	}
+	return tea.Batch(cmds...)
 }
 
 // tickCmd returns a command that ticks every 500ms
@@ -123,23 +559,57 @@ func tickCmd() tea.Cmd {
 	})
 }
 
-// listenForEvents returns a command that listens for audio events
+// listenForEvents returns a command that waits for the next event the UI
+// reacts to
 func (m Model) listenForEvents() tea.Cmd {
 	return func() tea.Msg {
-		select {
-		case event := <-m.audioEngine.Events():
-			switch event.Type {
-			case api.EventStateChange, api.EventTrackStarted, api.EventPositionUpdate:
-				return StateUpdateMsg{State: m.audioEngine.GetState()}
-			case api.EventTrackEnded:
-				return TrackEndedMsg{}
-			case api.EventError:
-				return StateUpdateMsg{State: m.audioEngine.GetState()}
-			}
-		case <-m.ctx.Done():
-			return nil
+		for {
+			select {
+			case event, ok := <-m.events:
+				if !ok {
+					return nil
+				}
+				switch event.Type {
+				case api.EventStateChange, api.EventTrackStarted, api.EventPositionUpdate, api.EventBuffering:
+					return StateUpdateMsg{State: m.snapshot()}
+				case api.EventTrackEnded:
+					track, _ := event.Payload.(*api.Track)
+					return TrackEndedMsg{Track: track}
+				case api.EventError:
+					if err, ok := event.Payload.(error); ok {
+						return engineErrorMsg{err: err}
+					}
+					return StateUpdateMsg{State: m.snapshot()}
+				case api.EventTrackFailed:
+					if f, ok := event.Payload.(api.TrackFailure); ok && f.Track != nil {
+						if f.Skipped {
+							return engineErrorMsg{err: fmt.Errorf("skipped %q: %w", f.Track.Title, f.Err)}
+						}
+						return engineErrorMsg{err: fmt.Errorf("cannot play %q: %w", f.Track.Title, f.Err)}
+					}
+				case api.EventLibraryChanged:
+					return libraryChangedMsg{}
+				case api.EventScanProgress:
+					if p, ok := event.Payload.(api.ScanProgress); ok {
+						return scanProgressMsg{progress: p}
+					}
+				case api.EventPlaylistChanged:
+					return playlistChangedMsg{}
+				}
+			case <-m.ctx.Done():
+				return nil
+			}
 		}
-		return nil
+	}
+}
+
+// federatedSearch returns a command that queries every search source
+func (m Model) federatedSearch(query string) tea.Cmd {
+	searcher := m.searcher
+	ctx := m.ctx
+	return func() tea.Msg {
+		results, errs := searcher.Search(ctx, query)
+		return SearchResultsMsg{Query: query, Results: results, Errors: errs}
 	}
 }
 
@@ -155,234 +625,1695 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case TickMsg:
 		// Update playback state
-		state := m.audioEngine.GetState()
-		m.playerView.SetState(state)
-		cmds = append(cmds, tickCmd())
+		m.setState(m.snapshot())
+		m.rememberPosition()
+		m.refreshQueueView()
+		m.log.refresh()
+		m.checkSchedule(time.Now())
+		cmds = append(cmds, tickCmd(), m.accentCmd(), m.pendingAlerts())
+		if !m.metering && m.meterLevels() {
+			m.metering = true
+			cmds = append(cmds, tea.Tick(levelsInterval, func(time.Time) tea.Msg { return levelsTickMsg{} }))
+		}
+
+	case levelsTickMsg:
+		// Fast redraws only run while the meters are on screen; the next
+		// TickMsg starts them again
+		m.metering = m.meterLevels()
+		if m.metering {
+			m.playerView.SetLevels(m.levels.Levels())
+			cmds = append(cmds, tea.Tick(levelsInterval, func(time.Time) tea.Msg { return levelsTickMsg{} }))
+		} else {
+			m.playerView.ClearLevels()
+		}
 
 	case StateUpdateMsg:
-		m.playerView.SetState(msg.State)
+		m.setState(msg.State)
+		cmds = append(cmds, m.listenForEvents(), m.accentCmd(), m.pendingAlerts())
+
+	case libraryChangedMsg:
+		// Playlists show tracks as the library has them
+		m.playlistManager.Refresh()
+		m.refreshPlaylists()
+		// Rebuilding the list would discard a search in progress
+		if !m.libraryView.Searching && m.libraryView.SearchBar.Value == "" {
+			m.libraryView.SetGenreFilter(m.libraryView.GenreFilter, m.filteredTracks())
+		}
+		m.libraryView.SetGenreTree(m.library.GenreTree())
+		m.libraryView.SetUserTags(m.library.AllUserTags())
 		cmds = append(cmds, m.listenForEvents())
 
-	case TrackEndedMsg:
-		// Auto-advance to next track (handled inside Update for thread safety)
-		logger.Debug("TrackEndedMsg received, advancing to next track")
-		if next := m.queue.Next(); next != nil {
-			logger.Info("Auto-advancing to next track: %q", next.Title)
-			m.audioEngine.Play(next)
-		} else {
-			logger.Info("Queue exhausted, no next track")
+	case scanProgressMsg:
+		if m.scanCancel != nil {
+			m.libraryView.SetScanProgress(msg.progress)
 		}
-		state := m.audioEngine.GetState()
-		m.playerView.SetState(state)
 		cmds = append(cmds, m.listenForEvents())
 
-	case views.FileAddedMsg:
-		// Add file to library
-		logger.Info("Adding file to library: %s", msg.Path)
-		track, err := m.library.AddFile(msg.Path)
-		if err != nil {
-			logger.Error("Failed to add file %s: %v", msg.Path, err)
-			m.err = err
-		} else {
-			logger.Info("Added track: %q by %s", track.Title, track.Artist)
-			// Update the library view with the new track
-			m.libraryView.AddTrack(track)
+	case views.RescanMsg:
+		switch {
+		case m.scanCancel != nil:
+		case len(m.scanPaths) == 0:
+			m.err = fmt.Errorf("no music directories configured")
+		default:
+			var ctx context.Context
+			ctx, m.scanCancel = context.WithCancel(m.ctx)
+			m.libraryView.SetScanProgress(api.ScanProgress{})
+			cmds = append(cmds, m.scanLibrary(ctx))
 		}
 
-	case tea.KeyMsg:
-		// If library view is in search mode, pass keys directly to it
-		// (except for critical global keys like quit)
-		if m.activeView == ViewLibrary && (m.libraryView.Searching || m.libraryView.Browsing) {
-			switch msg.String() {
-			case "ctrl+c":
-				m.cancel()
-				return m, tea.Quit
-			default:
-				m.libraryView, _ = m.libraryView.Update(msg)
-				return m, tea.Batch(cmds...)
+	case views.AddDirectoryMsg:
+		if m.scanCancel != nil {
+			m.err = fmt.Errorf("a library scan is already running")
+			break
+		}
+		if msg.Remember {
+			m.rememberMusicDir(msg.Path)
+		}
+		logger.Info("Adding directory %s to the library", msg.Path)
+		var ctx context.Context
+		ctx, m.scanCancel = context.WithCancel(m.ctx)
+		m.libraryView.SetScanProgress(api.ScanProgress{})
+		cmds = append(cmds, m.scanDir(ctx, msg.Path))
+
+	case components.BookmarksChangedMsg:
+		if m.saveBookmarks != nil {
+			if err := m.saveBookmarks(msg.Bookmarks); err != nil {
+				logger.Error("Failed to save bookmarks: %v", err)
+				m.err = err
 			}
 		}
 
-		// Global keybindings (only active when not searching)
-		switch msg.String() {
-		case "q", "ctrl+c":
-			m.cancel()
-			return m, tea.Quit
+	case components.DontAskMsg:
+		m.skipConfirm[msg.Action] = true
+		if m.saveSkipConfirm != nil {
+			actions := slices.Sorted(maps.Keys(m.skipConfirm))
+			if err := m.saveSkipConfirm(actions); err != nil {
+				logger.Error("Failed to save confirmation settings: %v", err)
+				m.err = err
+			}
+		}
 
-		case "1":
-			m.activeView = ViewPlayer
-		case "2":
-			m.activeView = ViewLibrary
-		case "3":
-			m.activeView = ViewPlaylist
+	case views.ShowScanErrorsMsg:
+		if m.scanReport == nil {
+			m.err = fmt.Errorf("no library scan has run yet")
+		} else {
+			m.libraryView.OpenScanErrors(m.scanReport.Errors, m.scanReport.Failed)
+		}
 
-		case "tab":
-			m.activeView = (m.activeView + 1) % 3
+	case views.CancelScanMsg:
+		if m.scanCancel != nil {
+			m.scanCancel()
+		}
 
-		case " ": // Space - play/pause
-			state := m.audioEngine.GetState()
-			if state.Status == api.StatusPlaying {
-				logger.Debug("User paused playback")
-				m.audioEngine.Pause()
-			} else if state.Status == api.StatusPaused {
-				logger.Debug("User resumed playback")
-				m.audioEngine.Resume()
-			} else if m.queue.Current() != nil {
-				logger.Debug("User started playback from stopped state")
-				m.audioEngine.Play(m.queue.Current())
+	case scanDoneMsg:
+		m.scanCancel = nil
+		m.libraryView.SetScanProgress(api.ScanProgress{Done: true})
+		if msg.err != nil && !errors.Is(msg.err, context.Canceled) {
+			logger.Error("Library scan failed: %v", msg.err)
+			m.err = msg.err
+		}
+		for _, e := range msg.report.Errors {
+			logger.Warn("%v", e)
+		}
+		logger.Info("%s", msg.report.Summary())
+		m.scanReport = &msg.report
+		if m.libraryPath != "" {
+			if err := m.library.Save(m.libraryPath); err != nil {
+				logger.Error("Failed to save library: %v", err)
+				m.err = err
 			}
+		}
 
-		case "s": // Stop
-			logger.Debug("User stopped playback")
-			m.audioEngine.Stop()
+	case playlistChangedMsg:
+		m.refreshPlaylists()
+		cmds = append(cmds, m.listenForEvents())
 
-		case "n": // Next
-			if next := m.queue.Next(); next != nil {
-				logger.Info("User skipped to next track: %q", next.Title)
-				m.audioEngine.Play(next)
+	case castDevicesMsg:
+		if m.cast.picking {
+			note := i18n.T("No devices found")
+			if msg.err != nil {
+				logger.Warn("Cast discovery: %v", msg.err)
+				note = i18n.T("Search failed")
 			}
+			m.cast.devices = msg.devices
+			m.cast.menu = components.NewMenu(i18n.T("Cast to"), m.castItems(note))
+		}
 
-		case "p": // Previous (only in player view)
-			if m.activeView == ViewPlayer {
-				if prev := m.queue.Previous(); prev != nil {
-					m.audioEngine.Play(prev)
-				}
-			}
+	case castStartedMsg:
+		if msg.err != nil {
+			logger.Error("Failed to start casting: %v", msg.err)
+			m.cast.connecting = ""
+			m.err = msg.err
+		} else {
+			cmds = append(cmds, m.startCast(msg.session))
+		}
 
-		case "right": // Seek forward 5 seconds
-			state := m.audioEngine.GetState()
-			if state.Status == api.StatusPlaying || state.Status == api.StatusPaused {
-				newPos := state.Position + 5*time.Second
-				if state.CurrentTrack != nil && newPos > state.CurrentTrack.Duration {
-					newPos = state.CurrentTrack.Duration
-				}
-				m.audioEngine.Seek(newPos)
-			}
+	case castEndedMsg:
+		m.castEnded(msg)
 
-		case "left": // Seek backward 5 seconds
-			state := m.audioEngine.GetState()
-			if state.Status == api.StatusPlaying || state.Status == api.StatusPaused {
-				newPos := state.Position - 5*time.Second
-				if newPos < 0 {
-					newPos = 0
-				}
-				m.audioEngine.Seek(newPos)
-			}
+	case engineErrorMsg:
+		m.err = msg.err
+		m.setState(m.snapshot())
+		cmds = append(cmds, m.listenForEvents(), m.pendingAlerts())
 
-		case "+", "=": // Volume up
-			state := m.audioEngine.GetState()
-			newVol := state.Volume + 0.1
-			if newVol > 1 {
-				newVol = 1
-			}
-			m.audioEngine.SetVolume(newVol)
+	case flashDoneMsg:
+		m.flashing = false
 
-		case "-": // Volume down
-			state := m.audioEngine.GetState()
-			newVol := state.Volume - 0.1
-			if newVol < 0 {
-				newVol = 0
-			}
-			m.audioEngine.SetVolume(newVol)
+	case accentMsg:
+		if msg.path == m.accentPath {
+			m.setAccent(msg.color)
+		}
 
-		case "r": // Toggle repeat
-			mode := m.queue.GetRepeatMode()
-			newMode := (mode + 1) % 3
-			m.queue.SetRepeatMode(newMode)
+	case TrackEndedMsg:
+		// The engine advances the queue itself; only bookkeeping is left
+		logger.Debug("TrackEndedMsg received")
+		if msg.Track != nil && m.logTrack != nil && m.logTrack.ID == msg.Track.ID {
+			m.logPlay(true)
+		}
+		m.books.Finish(msg.Track)
+		m.setState(m.snapshot())
+		cmds = append(cmds, m.listenForEvents())
 
-		case "S": // Toggle shuffle
-			if m.queue.IsShuffled() {
-				m.queue.Unshuffle()
-			} else {
-				m.queue.Shuffle()
+	case views.SearchChangedMsg:
+		// Only fan out when remote sources exist; local filtering is already
+		// live. Field terms stay local: remote servers only match plain text.
+		if m.searcher != nil && m.searcher.HasRemote() && msg.Query != "" &&
+			!library.ParseQuery(msg.Query).Filters() {
+			query := msg.Query
+			cmds = append(cmds, tea.Tick(searchDebounce, func(time.Time) tea.Msg {
+				return searchDebounceMsg{query: query}
+			}))
+		}
+
+	case searchDebounceMsg:
+		if msg.query == m.libraryView.SearchBar.Value {
+			cmds = append(cmds, m.federatedSearch(msg.query))
+		}
+
+	case SearchResultsMsg:
+		for _, err := range msg.Errors {
+			logger.Warn("Search: %v", err)
+		}
+		m.libraryView.SetSearchResults(msg.Query, msg.Results)
+
+	case views.QueueMoveMsg:
+		if err := m.queue.Move(msg.From, msg.To); err != nil {
+			logger.Warn("Queue move %d->%d: %v", msg.From, msg.To, err)
+		}
+		m.refreshQueueView()
+
+	case views.QueueRemoveMsg:
+		if err := m.queue.Remove(msg.Index); err != nil {
+			logger.Warn("Queue remove %d: %v", msg.Index, err)
+		}
+		m.refreshQueueView()
+
+	case views.ShowSavedQueuesMsg:
+		if m.queues == nil {
+			break
+		}
+		saved, err := m.queues.List()
+		if err != nil {
+			logger.Error("Failed to list saved queues: %v", err)
+			m.err = err
+			break
+		}
+		// The picker lists playlists; saved queues fit by name and tracks
+		var entries []*api.Playlist
+		for _, sq := range saved {
+			entries = append(entries, &api.Playlist{ID: sq.Name, Name: sq.Name, Tracks: sq.Tracks})
+		}
+		m.queueView.OpenSaved(entries, msg.Save)
+
+	case views.SaveQueueMsg:
+		if err := m.queue.SaveTo(m.queues, msg.Name, m.queuePosition()); err != nil {
+			logger.Error("Failed to save queue %q: %v", msg.Name, err)
+			m.err = err
+			break
+		}
+		logger.Info("Saved queue as %q", msg.Name)
+
+	case views.LoadQueueMsg:
+		m.rememberPosition()
+		pos, err := m.queue.LoadFrom(m.queues, msg.Name)
+		if err != nil {
+			logger.Error("Failed to load queue %q: %v", msg.Name, err)
+			m.err = err
+			break
+		}
+		m.queueResume.set(m.queue.Current(), pos)
+		m.afterQueueSet()
+
+	case views.AddToPlaylistMsg:
+		m.addToPlaylist(msg)
+
+	case views.TrackInfoMsg:
+		m.libraryView.OpenInfo(msg.Track, m.library.PlayStats(msg.Track.ID))
+		m.libraryView.Info.UserTags = m.library.UserTagsOf(msg.Track.ID)
+		m.libraryView.Info.Broken = m.library.BrokenReason(msg.Track.ID)
+		m.libraryView.Info.Rating = m.library.Rating(msg.Track.ID)
+		if !m.libraryView.IsRemote(msg.Track) {
+			cmds = append(cmds, readDetails(msg.Track))
+		}
+
+	case trackDetailsMsg:
+		m.libraryView.SetInfoDetails(msg.id, msg.details, msg.err)
+
+	case views.ShowLibraryStatsMsg:
+		m.libraryView.OpenStats(m.library.Summarize())
+
+	case views.ShowSkippedMsg:
+		m.libraryView.OpenSkipped(m.library.FrequentlySkipped(), func(id string) bool {
+			return m.library.IsShuffleBanned(&api.Track{ID: id})
+		})
+
+	case views.ShuffleBanMsg:
+		m.library.SetShuffleBanned(msg.TrackID, msg.Banned)
+		logger.Info("Shuffle ban for %s set to %v", msg.TrackID, msg.Banned)
+
+	case views.RemoveTracksMsg:
+		m.removeTracks(msg.TrackIDs)
+
+	case views.DeleteFilesMsg:
+		var deleted []string
+		for _, id := range msg.TrackIDs {
+			track, err := m.library.GetTrack(id)
+			if err == nil {
+				err = deleteFile(track.FilePath)
 			}
+			if err != nil {
+				logger.Error("Failed to delete the file of track %s: %v", id, err)
+				m.err = err
+				continue
+			}
+			logger.Info("Deleted %s", track.FilePath)
+			deleted = append(deleted, id)
+		}
+		m.removeTracks(deleted)
+		if m.libraryPath != "" && len(deleted) > 0 {
+			if err := m.library.Save(m.libraryPath); err != nil {
+				logger.Error("Failed to save library: %v", err)
+				m.err = err
+			}
+		}
 
-		case "enter":
-			// Play selected track
-			var track *api.Track
-			switch m.activeView {
-			case ViewLibrary:
-				track = m.libraryView.SelectedTrack()
-				if track != nil {
-					// Set queue to all library tracks starting from selected
-					tracks := m.library.GetAllTracks()
-					m.queue.Set(tracks)
-					for i, t := range tracks {
-						if t.ID == track.ID {
-							m.queue.JumpTo(i)
-							break
-						}
-					}
-				}
-			case ViewPlaylist:
-				track = m.playlistView.SelectedTrack()
-				if track != nil {
-					// Set queue to playlist tracks
-					pl := m.playlistView.SelectedPlaylist()
-					if pl != nil {
-						tracks := make([]*api.Track, len(pl.Tracks))
-						for i := range pl.Tracks {
-							tracks[i] = &pl.Tracks[i]
-						}
-						m.queue.Set(tracks)
-						for i, t := range tracks {
-							if t.ID == track.ID {
-								m.queue.JumpTo(i)
-								break
-							}
-						}
-					}
-				}
+	case views.EditTagsMsg:
+		if err := m.library.EditTags(msg.TrackIDs, msg.Edit); err != nil {
+			logger.Error("Failed to write tags: %v", err)
+			m.err = fmt.Errorf("tags saved in the library only: %w", err)
+		}
+		logger.Info("Edited tags of %d track(s)", len(msg.TrackIDs))
+		if m.libraryPath != "" {
+			if err := m.library.Save(m.libraryPath); err != nil {
+				logger.Error("Failed to save library: %v", err)
+				m.err = err
+			}
+		}
+		m.libraryView.SetGenreFilter(m.libraryView.GenreFilter, m.filteredTracks())
+		m.libraryView.SetGenreTree(m.library.GenreTree())
+
+	case views.UserTagMsg:
+		for _, tag := range msg.Add {
+			if err := m.library.AddUserTag(msg.TrackIDs, tag); err != nil {
+				logger.Warn("Failed to add tag %q: %v", tag, err)
+				m.err = err
+			}
+		}
+		for _, tag := range msg.Remove {
+			if err := m.library.RemoveUserTag(msg.TrackIDs, tag); err != nil {
+				logger.Warn("Failed to remove tag %q: %v", tag, err)
+				m.err = err
 			}
-			if track != nil {
-				logger.Info("User selected track: %q by %s", track.Title, track.Artist)
-				m.audioEngine.Play(track)
+		}
+		logger.Info("Tagged %d track(s): +%v -%v", len(msg.TrackIDs), msg.Add, msg.Remove)
+		if m.libraryPath != "" {
+			if err := m.library.Save(m.libraryPath); err != nil {
+				logger.Error("Failed to save library: %v", err)
+				m.err = err
 			}
+		}
+		m.libraryView.SetGenreFilter(m.libraryView.GenreFilter, m.filteredTracks())
+		m.libraryView.SetUserTags(m.library.AllUserTags())
 
+	case views.LookupMetadataMsg:
+		switch {
+		case m.enricher == nil:
+			m.err = fmt.Errorf("metadata lookup is off; enable metadata_lookup in the config")
+		case len(msg.Tracks) == 0:
+			m.err = fmt.Errorf("no tracks with missing tags to look up")
 		default:
-			// Pass to active view
-			switch m.activeView {
-			case ViewLibrary:
-				m.libraryView, _ = m.libraryView.Update(msg)
-			case ViewPlaylist:
-				m.playlistView, _ = m.playlistView.Update(msg)
+			tracks := msg.Tracks
+			if len(tracks) > metadataLookupLimit {
+				tracks = tracks[:metadataLookupLimit]
 			}
+			m.libraryView.OpenReview(len(tracks))
+			cmds = append(cmds, m.lookupMetadata(tracks))
 		}
 
-	case tea.MouseMsg:
-		// Handle click-to-seek on progress bar
-		if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
-			state := m.audioEngine.GetState()
-			if state.Status == api.StatusPlaying || state.Status == api.StatusPaused {
-				// The progress bar row is at a fixed offset from the top:
-				// tab bar (1) + newline gap (1) + player border top (1) + padding (1)
-				// + status/title (1) + artist (1) + album (1) + blank (1) = row 8 (0-indexed: 7)
-				progressRow := 1 + m.playerView.ProgressBarRow() // tab + player offset
-				if msg.Y == progressRow {
-					// Border left (1) + padding left (2) = 3 chars offset
-					barOffsetX := 3
-					seekPos := m.playerView.ProgressBarClickSeek(msg.X, barOffsetX)
-					m.audioEngine.Seek(seekPos)
-				}
+	case proposalsMsg:
+		if msg.err != nil {
+			logger.Warn("Metadata lookup failed: %v", msg.err)
+		}
+		m.libraryView.Review.SetProposals(msg.items, msg.err)
+
+	case views.ShowArchivedMsg:
+		m.libraryView.OpenArchived(m.library.GetArchivedTracks())
+
+	case views.ArchiveMsg:
+		for _, id := range msg.TrackIDs {
+			if err := m.library.SetArchived(id, msg.Archived); err != nil {
+				logger.Error("Failed to archive track %s: %v", id, err)
+				m.err = err
 			}
 		}
-	}
+		logger.Info("Set archived=%v for %d track(s)", msg.Archived, len(msg.TrackIDs))
+		m.libraryView.SetGenreFilter(m.libraryView.GenreFilter, m.filteredTracks())
 
-	return m, tea.Batch(cmds...)
-}
+	case views.EnqueueMsg:
+		var tracks []*api.Track
+		for _, t := range msg.Tracks {
+			if m.libraryView.IsRemote(t) {
+				logger.Warn("Skipping remote track %q: the queue only holds local files", t.Title)
+				continue
+			}
+			tracks = append(tracks, t)
+		}
+		m.queue.Add(tracks...)
+		logger.Info("Enqueued %d track(s)", len(tracks))
+		m.refreshQueueView()
+
+	case views.SeekMsg:
+		logger.Info("User scrubbed to %v", msg.Position.Round(time.Second))
+		m.seek(msg.Position)
+
+	case views.BookmarkAddMsg:
+		m.editBookmarks(func(track *api.Track) error { return m.marks.Add(track, msg.Name, msg.Position) })
+
+	case views.BookmarkRemoveMsg:
+		m.editBookmarks(func(track *api.Track) error { return m.marks.Remove(track, msg.Bookmark.Name, msg.Bookmark.Position) })
+
+	case views.PlaySelectedMsg:
+		m.playSelected()
+
+	case views.PlayNextMsg:
+		m.queue.InsertNext(msg.Tracks...)
+		logger.Info("Queued %d track(s) to play next", len(msg.Tracks))
+		m.refreshQueueView()
+
+	case views.GoToAlbumMsg:
+		album, err := m.library.TrackAlbum(msg.Track.ID)
+		if err != nil {
+			m.err = err
+			break
+		}
+		label := i18n.T("Album: %s", album.Name)
+		if album.Artist != "" {
+			label += " " + glyphs.Separator.String() + " " + album.Artist
+		}
+		if album.Year > 0 {
+			label += fmt.Sprintf(" (%d)", album.Year)
+		}
+		m.libraryView.Narrow(label, m.library.AlbumTracks(album.ID))
+
+	case views.GoToArtistMsg:
+		artist, err := m.library.GetArtist(msg.Track.Artist)
+		if err != nil {
+			m.err = err
+			break
+		}
+		mins := int(artist.Duration.Minutes())
+		label := fmt.Sprintf(glyphs.Text(i18n.T("Artist: %s · %d albums · %d tracks · %dh %02dm")), artist.Name,
+			artist.AlbumCount, artist.TrackCount, mins/60, mins%60)
+		m.libraryView.Narrow(label, m.library.Discography(artist.Name))
+
+	case views.PlayAlbumMsg:
+		m.playList(m.library.AlbumOf(msg.Track), nil)
+
+	case views.PlayArtistMsg:
+		m.playList(m.library.Discography(msg.Track.Artist), nil)
+
+	case views.ShuffleAllMsg:
+		tracks := m.library.GetAllTracks()
+		if m.queue.IsParty() {
+			kept := tracks[:0]
+			for _, t := range tracks {
+				if !m.library.ShuffleExcluded(t) {
+					kept = append(kept, t)
+				}
+			}
+			rand.Shuffle(len(kept), func(i, j int) { kept[i], kept[j] = kept[j], kept[i] })
+			m.partyAdd(kept...)
+			break
+		}
+		m.queue.SetShuffled(tracks)
+		m.afterQueueSet()
+
+	case views.GenreFilterMsg:
+		m.libraryView.SetGenreFilter(msg.Genre, m.tracksUnder(msg.Genre))
+
+	case views.GenreParentMsg:
+		taxonomy := m.library.Taxonomy()
+		if err := taxonomy.SetParent(msg.Genre, msg.Parent); err != nil {
+			logger.Warn("Genre taxonomy: %v", err)
+			m.err = err
+		} else if err := taxonomy.Save(); err != nil {
+			logger.Error("Failed to save genre taxonomy: %v", err)
+			m.err = err
+		}
+		m.libraryView.SetGenreTree(m.library.GenreTree())
+
+	case views.PlaylistCreateMsg:
+		pl, err := m.playlistManager.Create(msg.Name, "")
+		if err != nil {
+			logger.Error("Failed to create playlist %q: %v", msg.Name, err)
+			m.err = err
+		} else {
+			logger.Info("Created playlist %q", pl.Name)
+			m.refreshPlaylists()
+			m.playlistView.SelectByID(pl.ID)
+		}
+
+	case views.PlaylistUpdateMsg:
+		if err := m.playlistManager.Update(msg.ID, msg.Name, msg.Description); err != nil {
+			logger.Error("Failed to update playlist %s: %v", msg.ID, err)
+			m.err = err
+		} else {
+			logger.Info("Updated playlist %s: %q", msg.ID, msg.Name)
+			m.refreshPlaylists()
+			m.playlistView.SelectByID(msg.ID)
+		}
+
+	case views.PlaylistMoveTrackMsg:
+		if err := m.playlistManager.MoveTrack(msg.ID, msg.From, msg.To); err != nil {
+			logger.Warn("Playlist move %d->%d: %v", msg.From, msg.To, err)
+		}
+		if pl, err := m.playlistManager.GetByID(msg.ID); err == nil {
+			m.playlistView.RefreshCurrent(pl)
+		}
+
+	case views.PlaylistImportMsg:
+		pl, skipped, err := m.playlistManager.Import(expandHome(msg.Path))
+		if err != nil {
+			logger.Error("Failed to import playlist %s: %v", msg.Path, err)
+			m.err = err
+		} else {
+			logger.Info("Imported playlist %q with %d tracks (%d skipped)", pl.Name, len(pl.Tracks), skipped)
+			m.refreshPlaylists()
+			m.playlistView.SelectByID(pl.ID)
+		}
+
+	case views.PlaylistExportMsg:
+		if err := m.playlistManager.Export(msg.ID, expandHome(msg.Path)); err != nil {
+			logger.Error("Failed to export playlist %s: %v", msg.ID, err)
+			m.err = err
+		} else {
+			logger.Info("Exported playlist %s to %s", msg.ID, msg.Path)
+		}
+
+	case views.PlaylistStatsMsg:
+		if pl, err := m.playlistManager.GetByID(msg.ID); err == nil {
+			st, _ := m.playlistManager.Stats(msg.ID)
+			m.playlistView.ShowStats(pl.Name, st)
+		}
+
+	case views.PlaylistOverlapMsg:
+		m.playlistView.ShowOverlaps(m.playlistManager.Overlaps())
+
+	case views.PlaylistDedupeMsg:
+		removed, err := m.playlistManager.Deduplicate(msg.ID)
+		if err != nil {
+			logger.Error("Failed to deduplicate playlist %s: %v", msg.ID, err)
+			m.err = err
+		} else {
+			logger.Info("Removed %d repeated track(s) from playlist %s", removed, msg.ID)
+			m.refreshPlaylists()
+		}
+
+	case views.PlaylistShareMsg:
+		svc := m.streamingService(msg.Service)
+		pl, err := m.playlistManager.GetByID(msg.ID)
+		switch {
+		case svc == nil:
+			logger.Warn("Export playlist: %v", streaming.ErrNotConfigured)
+			m.err = streaming.ErrNotConfigured
+		case err != nil:
+			logger.Error("Failed to export playlist %s: %v", msg.ID, err)
+			m.err = err
+		default:
+			m.playlistView.OpenShare(svc.Name(), pl)
+			cmds = append(cmds, m.matchStreaming(svc, pl.Tracks))
+		}
+
+	case shareMatchesMsg:
+		if msg.err != nil {
+			logger.Warn("Streaming search failed: %v", msg.err)
+		}
+		m.playlistView.Share.SetMatches(msg.items, msg.err)
+
+	case views.ShareCreateMsg:
+		if svc := m.streamingService(msg.Service); svc != nil {
+			cmds = append(cmds, m.createStreamingPlaylist(svc, msg))
+		}
+
+	case shareCreatedMsg:
+		if msg.err != nil {
+			logger.Error("Failed to create playlist %q on %s: %v", msg.name, msg.service, msg.err)
+			m.err = msg.err
+		} else if msg.link != "" {
+			logger.Info("Created playlist %q on %s: %s", msg.name, msg.service, msg.link)
+		} else {
+			logger.Info("Created playlist %q on %s", msg.name, msg.service)
+		}
+
+	case views.PlaylistDeleteMsg:
+		if err := m.playlistManager.Delete(msg.ID); err != nil {
+			logger.Error("Failed to delete playlist %s: %v", msg.ID, err)
+			m.err = err
+		} else {
+			logger.Info("Deleted playlist %s", msg.ID)
+			m.refreshPlaylists()
+		}
+
+	case views.FileAddedMsg:
+		// Add the files to the library, reporting the first failure. Files
+		// that do not decode are turned away here rather than failing when
+		// their turn comes in the queue.
+		var failed []error
+		for _, path := range msg.Paths {
+			logger.Info("Adding file to library: %s", path)
+			if _, err := audio.Probe(path); err != nil {
+				logger.Error("Rejected file %s: %v", path, err)
+				failed = append(failed, err)
+				continue
+			}
+			track, err := m.library.AddFile(path)
+			if err != nil {
+				logger.Error("Failed to add file %s: %v", path, err)
+				failed = append(failed, err)
+				continue
+			}
+			logger.Info("Added track: %q by %s", track.Title, track.Artist)
+			// Update the library view with the new track
+			m.libraryView.AddTrack(track)
+		}
+		m.libraryView.SetGenreTree(m.library.GenreTree())
+		switch {
+		case len(failed) == 1:
+			m.err = failed[0]
+		case len(failed) > 1:
+			m.err = fmt.Errorf("%d of %d files could not be added, first: %w", len(failed), len(msg.Paths), failed[0])
+		}
+
+	case tea.KeyMsg:
+		key := msg.String()
+		if key == "ctrl+c" {
+			return m, m.quit()
+		}
+		m.notice = ""
+
+		// Any key closes the help overlay
+		if m.showHelp {
+			m.showHelp = false
+			return m, tea.Batch(cmds...)
+		}
+
+		// The log viewer takes every key
+		if m.log.open {
+			m.updateLog(msg)
+			return m, tea.Batch(cmds...)
+		}
+
+		// So does the schedules overlay
+		if m.schedules.open {
+			m.updateSchedules(msg)
+			return m, tea.Batch(cmds...)
+		}
+
+		// The cast picker takes every key
+		if m.cast.picking {
+			cmds = append(cmds, m.updateCastPicker(msg))
+			return m, tea.Batch(cmds...)
+		}
+
+		// So does the bookmark popup
+		if m.markPopup.Open {
+			var cmd tea.Cmd
+			m.markPopup, cmd = m.markPopup.Update(msg)
+			cmds = append(cmds, cmd)
+			return m, tea.Batch(cmds...)
+		}
+
+		// Search input and overlays in the library get every key; in marking
+		// mode the library's own bindings are still translated
+		if m.activeView == ViewLibrary && m.libraryView.Capturing() {
+			if m.libraryView.CommandMode() {
+				if a := m.keys.Resolve(keymap.Library, key); a != "" && m.keys.ScopeOf(a) == keymap.Library {
+					msg = m.keys.ViewKey(a)
+				} else if m.keys.Shadowed(keymap.Library, key) {
+					return m, tea.Batch(cmds...)
+				}
+			}
+			cmds = append(cmds, m.updateView(msg))
+			return m, tea.Batch(cmds...)
+		}
+
+		// The saved queue picker takes every key
+		if m.activeView == ViewQueue && m.queueView.Capturing() {
+			cmds = append(cmds, m.updateView(msg))
+			return m, tea.Batch(cmds...)
+		}
+
+		// Enter and Esc commit or cancel a scrub preview in the player
+		if m.activeView == ViewPlayer && m.playerView.Scrubbing() && (key == "enter" || key == "esc") {
+			cmds = append(cmds, m.updateView(msg))
+			return m, tea.Batch(cmds...)
+		}
+
+		// The history search takes every key
+		if m.activeView == ViewHistory && m.historyView.Capturing() {
+			cmds = append(cmds, m.updateView(msg))
+			return m, tea.Batch(cmds...)
+		}
+
+		// Playlist prompts and reports take every key
+		if m.activeView == ViewPlaylist && (m.playlistView.Prompting() || m.playlistView.Report != "") {
+			cmds = append(cmds, m.updateView(msg))
+			return m, tea.Batch(cmds...)
+		}
+
+		// View bindings shadow global ones
+		scope := m.scope()
+		action := m.keys.Resolve(scope, key)
+		if action != "" && m.keys.ScopeOf(action) != keymap.Global {
+			viewKey := m.keys.ViewKey(action)
+			if scope != keymap.Playlist || m.playlistView.HandlesKey(viewKey.String()) {
+				cmds = append(cmds, m.updateView(viewKey))
+				return m, tea.Batch(cmds...)
+			}
+			action = m.keys.Global(key)
+		}
+
+		switch action {
+		case keymap.Quit:
+			return m, m.quit()
+
+		case keymap.Help:
+			m.showHelp = true
+		case keymap.Log:
+			m.openLog()
+		case keymap.Schedules:
+			m.openSchedules()
+
+		case keymap.ViewPlayer:
+			m.activeView = ViewPlayer
+		case keymap.ViewLibrary:
+			m.activeView = ViewLibrary
+		case keymap.ViewPlaylist:
+			m.activeView = ViewPlaylist
+		case keymap.ViewQueue:
+			m.activeView = ViewQueue
+			m.refreshQueueView()
+		case keymap.ViewHistory:
+			m.activeView = ViewHistory
+			m.refreshHistoryView()
+		case keymap.ViewStats:
+			m.activeView = ViewStats
+			m.refreshStatsView()
+
+		case keymap.NextView:
+			m.activeView = (m.activeView + 1) % viewCount
+			m.refreshQueueView()
+			m.refreshHistoryView()
+			m.refreshStatsView()
+
+		case keymap.SplitPane:
+			m.split = !m.split
+			if m.split && m.activeView != ViewQueue {
+				m.activeView = ViewLibrary
+			}
+			m.refreshQueueView()
+			m.updateViewSizes()
+		case keymap.FocusPane:
+			if m.splitShown() {
+				if m.activeView == ViewLibrary {
+					m.activeView = ViewQueue
+					m.refreshQueueView()
+				} else {
+					m.activeView = ViewLibrary
+				}
+			}
+		case keymap.SplitGrow, keymap.SplitShrink:
+			if m.split {
+				step := splitStep
+				if action == keymap.SplitShrink {
+					step = -step
+				}
+				m.splitPercent = min(max(m.splitPercent+step, splitMinPercent), 100-splitMinPercent)
+				m.updateViewSizes()
+			}
+
+		case keymap.PlayPause:
+			state := m.audioEngine.GetState()
+			if state.Status == api.StatusPlaying {
+				logger.Debug("User paused playback")
+				m.audioEngine.Pause()
+			} else if state.Status == api.StatusPaused {
+				logger.Debug("User resumed playback")
+				m.audioEngine.Resume()
+			} else if m.queue.Current() != nil {
+				logger.Debug("User started playback from stopped state")
+				m.play(m.queue.Current())
+			}
+
+		case keymap.Stop:
+			logger.Debug("User stopped playback")
+			m.audioEngine.Stop()
+
+		case keymap.Next:
+			logger.Info("User skipped to next track")
+			m.rememberPosition()
+			m.audioEngine.Next()
+
+		case keymap.Previous: // only in player view
+			if m.activeView == ViewPlayer {
+				m.rememberPosition()
+				m.audioEngine.Previous()
+			}
+
+		case keymap.SeekForward: // 5 seconds
+			state := m.audioEngine.GetState()
+			if state.Status == api.StatusPlaying || state.Status == api.StatusPaused {
+				newPos := state.Position + 5*time.Second
+				if state.CurrentTrack != nil && newPos > state.CurrentTrack.Duration {
+					newPos = state.CurrentTrack.Duration
+				}
+				m.seek(newPos)
+			}
+
+		case keymap.SeekBack: // 5 seconds
+			state := m.audioEngine.GetState()
+			if state.Status == api.StatusPlaying || state.Status == api.StatusPaused {
+				newPos := state.Position - 5*time.Second
+				if newPos < 0 {
+					newPos = 0
+				}
+				m.seek(newPos)
+			}
+
+		case keymap.ChapterNext:
+			state := m.audioEngine.GetState()
+			if state.CurrentTrack != nil {
+				if pos, ok := audiobook.NextChapter(state.CurrentTrack.Chapters, state.Position); ok {
+					m.seek(pos)
+				}
+			}
+
+		case keymap.ChapterPrev: // or restart the current one
+			state := m.audioEngine.GetState()
+			if state.CurrentTrack != nil {
+				if pos, ok := audiobook.PrevChapter(state.CurrentTrack.Chapters, state.Position); ok {
+					m.seek(pos)
+				}
+			}
+
+		case keymap.BookmarkAdd:
+			state := m.audioEngine.GetState()
+			if m.marks != nil && state.CurrentTrack != nil && state.Status != api.StatusStopped {
+				m.markPopup.ShowName(state.Position)
+			}
+
+		case keymap.BookmarkList:
+			state := m.audioEngine.GetState()
+			if m.marks != nil && state.CurrentTrack != nil && state.Status != api.StatusStopped {
+				m.markPopup.ShowList(state.CurrentTrack.Title, m.marks.List(state.CurrentTrack), state.Position)
+			}
+
+		case keymap.BookmarkNext:
+			state := m.audioEngine.GetState()
+			if pos, ok := audiobook.NextBookmark(m.marks.List(state.CurrentTrack), state.Position); ok {
+				m.seek(pos)
+			}
+
+		case keymap.BookmarkPrev:
+			state := m.audioEngine.GetState()
+			if pos, ok := audiobook.PrevBookmark(m.marks.List(state.CurrentTrack), state.Position); ok {
+				m.seek(pos)
+			}
+
+		case keymap.VolumeUp:
+			m.stepVolume(0.1)
+
+		case keymap.VolumeDown:
+			m.stepVolume(-0.1)
+
+		case keymap.VolumeUpFine:
+			m.stepVolume(0.01)
+
+		case keymap.VolumeDownFine:
+			m.stepVolume(-0.01)
+
+		case keymap.Mute:
+			m.audioEngine.SetMuted(!m.audioEngine.GetState().Muted)
+
+		case keymap.Output:
+			// While casting the output stays on the cast device
+			sinks := slices.DeleteFunc(m.audioEngine.Sinks(), func(s string) bool { return s == audio.CastSinkName })
+			if len(sinks) > 1 && m.cast.session == nil {
+				current := m.audioEngine.GetState().Output
+				next := sinks[0]
+				for i, name := range sinks {
+					if name == current {
+						next = sinks[(i+1)%len(sinks)]
+						break
+					}
+				}
+				logger.Info("User switched output to %s", next)
+				m.audioEngine.SwitchSink(next)
+			}
+
+		case keymap.Cast:
+			cmds = append(cmds, m.openCast())
+
+		case keymap.Repeat:
+			mode := m.queue.GetRepeatMode()
+			newMode := (mode + 1) % 3
+			m.queue.SetRepeatMode(newMode)
+
+		case keymap.SavePoint:
+			m.setSavePoint()
+
+		case keymap.ReturnToSave:
+			m.returnToSavePoint()
+
+		case keymap.Shuffle:
+			if m.queue.IsShuffled() {
+				m.queue.Unshuffle()
+			} else {
+				m.queue.Shuffle()
+			}
+
+		case keymap.Consume:
+			m.queue.SetConsume(!m.queue.IsConsume())
+			logger.Info("Consume mode set to %v", m.queue.IsConsume())
+
+		case keymap.Party:
+			m.queue.SetParty(!m.queue.IsParty())
+			logger.Info("Party mode set to %v", m.queue.IsParty())
+
+		default:
+			if key == "enter" {
+				m.playSelected()
+			} else if !m.keys.Shadowed(scope, key) {
+				cmds = append(cmds, m.updateView(msg))
+			}
+		}
+
+	case tea.MouseMsg:
+		// Right-click on a library track opens its context menu
+		if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonRight && m.activeView == ViewLibrary {
+			if left, _ := m.paneWidths(); !m.splitShown() || msg.X < left {
+				if row := m.libraryView.RowAt(msg.Y - lipgloss.Height(m.renderTabs())); row >= 0 {
+					m.libraryView.TrackList.Select(row)
+					m.libraryView.OpenMenu()
+				}
+			}
+		}
+
+		// Handle click-to-seek on progress bar
+		if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft && m.activeView == ViewPlayer {
+			state := m.audioEngine.GetState()
+			if state.Status == api.StatusPlaying || state.Status == api.StatusPaused {
+				// The progress bar row is at a fixed offset from the top:
+				// tab bar (1) + newline gap (1) + player border top (1) + padding (1)
+				// + status/title (1) + artist (1) + album (1) + blank (1) = row 8 (0-indexed: 7)
+				progressRow := 1 + m.playerView.ProgressBarRow() // tab + player offset
+				if msg.Y == progressRow {
+					// Border left (1) + padding left (2) = 3 chars offset
+					barOffsetX := 3
+					seekPos := m.playerView.ProgressBarClickSeek(msg.X, barOffsetX)
+					m.seek(seekPos)
+				}
+			}
+		}
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+// contentHeight is the height left to the library, playlist, queue and
+// history views by the tab bar, the now-playing bar and the status line
+func (m *Model) contentHeight() int {
+	return m.height - 2 - views.NowPlayingHeight
+}
+
+// splitMinWidth is the narrowest terminal the split layout is shown in;
+// below it only the focused pane is
+const splitMinWidth = 100
+
+// splitStep and splitMinPercent bound resizing the split, in percent of
+// the width
+const (
+	splitStep       = 5
+	splitMinPercent = 25
+)
+
+// splitShown reports whether the library and queue are drawn side by side
+func (m *Model) splitShown() bool {
+	return m.split && m.width >= splitMinWidth &&
+		(m.activeView == ViewLibrary || m.activeView == ViewQueue)
+}
+
+// paneWidths returns the widths of the library and queue views: the
+// split's shares when it fits, otherwise the full width for both
+func (m *Model) paneWidths() (int, int) {
+	if !m.split || m.width < splitMinWidth {
+		return m.width, m.width
+	}
+	left := m.width * m.splitPercent / 100
+	return left, m.width - left
+}
 
 // updateViewSizes updates view dimensions
 func (m *Model) updateViewSizes() {
+	height := m.contentHeight()
+	left, right := m.paneWidths()
 	m.playerView.Width = m.width
 	m.playerView.Height = 10
-	m.libraryView.Width = m.width
-	m.libraryView.Height = m.height - 12
+	m.nowPlaying.Width = m.width
+	m.libraryView.Width = left
+	m.libraryView.Height = height
+	m.libraryView.TrackList.Width = left - 8
+	m.libraryView.SearchBar.Width = left - 10
+	m.libraryView.TrackList.Height = height - 8
 	m.playlistView.Width = m.width
-	m.playlistView.Height = m.height - 12
+	m.playlistView.Height = height
+	m.playlistView.TrackList.Width = m.width - 8
+	m.playlistView.TrackList.Height = height - 8
+	m.queueView.Width = right
+	m.queueView.Height = height
+	m.queueView.TrackList.Width = right - 8
+	m.queueView.TrackList.Height = height - 8
+	m.historyView.Width = m.width
+	m.historyView.Height = height
+	m.statsView.Width = m.width
+	m.statsView.Height = height
+}
+
+// setAccent recolors the player view and the now-playing bar
+func (m *Model) setAccent(color string) {
+	m.playerView.SetAccent(color)
+	m.nowPlaying.SetAccent(color)
+}
+
+// setState shows a new playback state
+func (m *Model) setState(state *api.PlaybackState) {
+	m.playerView.SetState(state)
+	if state != nil {
+		m.playerView.SetBookmarks(m.marks.List(state.CurrentTrack))
+	}
+	m.nowPlaying.SetState(state)
+	if state == nil {
+		return
+	}
+
+	trackID := ""
+	if state.CurrentTrack != nil {
+		trackID = state.CurrentTrack.ID
+	}
+	if state.Status == api.StatusPlaying && trackID != m.lastTrack {
+		m.trackChanged = true
+	}
+	if m.lastStatus == api.StatusPlaying && state.Status != api.StatusPlaying {
+		m.saveResume()
+	}
+	if m.accessible {
+		m.updateAnnouncement(state, trackID)
+	}
+
+	m.trackSession(state, trackID)
+
+	// Play history: close the entry once its track is replaced or stopped
+	if m.logTrack != nil && (trackID != m.logTrack.ID || state.Status == api.StatusStopped) {
+		m.logPlay(false)
+	}
+	if m.logTrack == nil && state.Status == api.StatusPlaying && state.CurrentTrack != nil {
+		m.logTrack, m.logStart, m.logPos = state.CurrentTrack, time.Now(), 0
+	}
+	if m.logTrack != nil && trackID == m.logTrack.ID && state.Status != api.StatusStopped {
+		m.logPos = state.Position
+	}
+	m.lastTrack = trackID
+	m.lastStatus = state.Status
+	m.announceNext(state)
+	m.maybeCrossfade(state)
+}
+
+// trackSession keeps the listening sessions in step with playback: a new
+// track opens one, pausing and resuming stop and restart its clock, and
+// stopping closes it
+func (m *Model) trackSession(state *api.PlaybackState, trackID string) {
+	switch {
+	case state.Status == api.StatusPlaying && state.CurrentTrack != nil && (trackID != m.lastTrack || m.lastStatus == api.StatusStopped):
+		t := state.CurrentTrack
+		m.sessions.StartSession(t.ID, t.Title, t.Artist, t.Duration)
+	case state.Status == api.StatusStopped && m.lastStatus != api.StatusStopped:
+		m.sessions.Ended()
+	case state.Status == api.StatusPaused && m.lastStatus == api.StatusPlaying:
+		m.sessions.Paused()
+	case state.Status == api.StatusPlaying && m.lastStatus == api.StatusPaused:
+		m.sessions.Resumed()
+	}
+}
+
+// announceNext shows the next queued track in the player during the last
+// m.upNext of the current one, with a desktop notification once per track
+func (m *Model) announceNext(state *api.PlaybackState) {
+	m.playerView.UpNext = nil
+	cur := state.CurrentTrack
+	if m.upNext <= 0 || state.Status != api.StatusPlaying || cur == nil || cur.Duration <= 0 {
+		return
+	}
+	if state.Position < cur.Duration-m.upNext {
+		return
+	}
+	if q := m.queue.Current(); q == nil || q.ID != cur.ID {
+		return
+	}
+	next := m.queue.PeekNext()
+	if next == nil || next.ID == cur.ID {
+		return
+	}
+	m.playerView.UpNext = next
+	if m.announced == cur.ID {
+		return
+	}
+	m.announced = cur.ID
+	if m.upNextNotify {
+		title := next.Title
+		if next.Artist != "" {
+			title = next.Artist + " " + glyphs.Dash.String() + " " + next.Title
+		}
+		go func() {
+			if err := notify.Send(i18n.T("Up next"), title); err != nil {
+				logger.Debug("Up next notification failed: %v", err)
+			}
+		}()
+	}
+}
+
+// maybeCrossfade starts the next queued track early, fading across the end
+// of the current one, unless the two belong together gaplessly
+func (m *Model) maybeCrossfade(state *api.PlaybackState) {
+	if m.crossfade <= 0 || state.Status != api.StatusPlaying || state.CurrentTrack == nil {
+		return
+	}
+	cur := state.CurrentTrack
+	if m.fadedFrom != "" && cur.ID != m.fadedFrom {
+		m.fadedFrom = ""
+	}
+	if cur.ID == m.fadedFrom || cur.Duration < 3*m.crossfade || state.Position < cur.Duration-m.crossfade {
+		return
+	}
+	if q := m.queue.Current(); q == nil || q.ID != cur.ID {
+		return
+	}
+	next := m.queue.PeekNext()
+	if next == nil || next.ID == cur.ID || library.GaplessPair(cur, next) || m.books.Applies(cur) || m.books.Applies(next) {
+		return
+	}
+	m.fadedFrom = cur.ID
+	m.queue.Next()
+	logger.Info("Crossfading into %q", next.Title)
+	m.audioEngine.CrossfadeTo(next, m.crossfade)
+}
+
+// logPlay writes the play history entry for the loaded track. A track
+// that got within a couple of seconds of its end counts as completed even
+// if the end event was missed.
+func (m *Model) logPlay(completed bool) {
+	t := m.logTrack
+	if t == nil {
+		return
+	}
+	m.logTrack = nil
+	// A crossfade leaves the track up to m.crossfade before its end
+	if t.Duration > 0 && m.logPos >= t.Duration-m.crossfade-2*time.Second {
+		completed = true
+	}
+	rec := library.PlayRecord{
+		TrackID:   t.ID,
+		FilePath:  t.FilePath,
+		Title:     t.Title,
+		Artist:    t.Artist,
+		PlayedAt:  m.logStart,
+		Played:    m.logPos,
+		Duration:  t.Duration,
+		Completed: completed,
+	}
+	if err := m.library.RecordPlay(rec); err != nil {
+		logger.Error("Failed to record play of %q: %v", t.Title, err)
+	}
+	m.refreshHistoryView()
+	m.refreshStatsView()
+}
+
+// quit records what was playing and stops the program
+func (m *Model) quit() tea.Cmd {
+	m.logPlay(false)
+	m.saveResume()
+	m.saveQueue()
+	m.stopCast()
+	m.cancel()
+	return tea.Quit
+}
+
+// removeTracks removes tracks from the library and refreshes the view
+func (m *Model) removeTracks(ids []string) {
+	var removed []string
+	for _, id := range ids {
+		if err := m.library.RemoveTrack(id); err != nil {
+			logger.Error("Failed to remove track %s: %v", id, err)
+			m.err = err
+			continue
+		}
+		m.library.SetShuffleBanned(id, false)
+		removed = append(removed, id)
+	}
+	if err := m.marks.Forget(removed); err != nil {
+		logger.Error("Failed to save bookmarks: %v", err)
+		m.err = err
+	}
+	logger.Info("Removed %d track(s) from library", len(removed))
+	m.libraryView.SetGenreFilter(m.libraryView.GenreFilter, m.filteredTracks())
+	m.libraryView.SetGenreTree(m.library.GenreTree())
+	m.libraryView.SetUserTags(m.library.AllUserTags())
+}
+
+// deleteFile moves a file to the trash, or deletes it where there is none
+func deleteFile(path string) error {
+	if trash.Supported() {
+		return trash.Move(path)
+	}
+	return os.Remove(path)
+}
+
+// filteredTracks returns the library tracks under the current genre filter
+func (m *Model) filteredTracks() []*api.Track {
+	return m.tracksUnder(m.libraryView.GenreFilter)
+}
+
+// tracksUnder returns the tracks of a genre, or of a user tag when filter
+// is "#tag"; an empty filter returns every track
+func (m *Model) tracksUnder(filter string) []*api.Track {
+	if filter == "" {
+		return m.library.GetAllTracks()
+	}
+	if tag, ok := strings.CutPrefix(filter, "#"); ok {
+		return m.library.GetTracksByUserTag(tag)
+	}
+	return m.library.GetTracksByGenre(filter)
+}
+
+// accentCmd updates the player accent when the track changes. Colors not
+// yet cached are extracted in the background.
+func (m *Model) accentCmd() tea.Cmd {
+	if m.accents == nil {
+		return nil
+	}
+	path := ""
+	if state := m.playerView.State; state != nil && state.CurrentTrack != nil {
+		path = state.CurrentTrack.FilePath
+	}
+	if path == m.accentPath {
+		return nil
+	}
+	m.accentPath = path
+	if path == "" {
+		m.setAccent("")
+		return nil
+	}
+	if color, ok := m.accents.Cached(path); ok {
+		m.setAccent(color)
+		return nil
+	}
+	cache := m.accents
+	return func() tea.Msg {
+		return accentMsg{path: path, color: cache.Accent(path)}
+	}
+}
+
+// readDetails returns a command that reads the file details of track
+func readDetails(track *api.Track) tea.Cmd {
+	id, path := track.ID, track.FilePath
+	return func() tea.Msg {
+		details, err := library.NewMetadataReader().ReadDetails(path)
+		return trackDetailsMsg{id: id, details: details, err: err}
+	}
+}
+
+// scanDir returns a command that scans a single directory into the library
+// until ctx is cancelled
+func (m Model) scanDir(ctx context.Context, dir string) tea.Cmd {
+	lib := m.library
+	return func() tea.Msg {
+		report, err := lib.ScanDir(ctx, dir)
+		return scanDoneMsg{report: report, err: err}
+	}
+}
+
+// rememberMusicDir adds dir to the directories "R" rescans and, through
+// addMusicDir, to the config
+func (m *Model) rememberMusicDir(dir string) {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+	for _, p := range m.scanPaths {
+		if abs, err := filepath.Abs(p); err == nil && abs == dir {
+			return
+		}
+	}
+	m.scanPaths = append(m.scanPaths, dir)
+	if m.addMusicDir != nil {
+		if err := m.addMusicDir(dir); err != nil {
+			logger.Error("Failed to save music directory %s: %v", dir, err)
+			m.err = err
+			return
+		}
+	}
+	logger.Info("Added %s to the music directories", dir)
+}
+
+// scanLibrary returns a command that scans the music directories until
+// ctx is cancelled
+func (m Model) scanLibrary(ctx context.Context) tea.Cmd {
+	lib := m.library
+	paths := m.scanPaths
+	return func() tea.Msg {
+		report, err := lib.Scan(ctx, paths)
+		return scanDoneMsg{report: report, err: err}
+	}
+}
+
+// lookupMetadata returns a command that looks up tracks online
+func (m Model) lookupMetadata(tracks []*api.Track) tea.Cmd {
+	client := m.enricher
+	ctx := m.ctx
+	return func() tea.Msg {
+		items, err := client.LookupAll(ctx, tracks, len(tracks))
+		return proposalsMsg{items: items, err: err}
+	}
+}
+
+// streamingService returns the configured streaming service named name, or
+// nil
+func (m Model) streamingService(name string) streaming.Service {
+	for _, svc := range m.streaming {
+		if svc.Name() == name {
+			return svc
+		}
+	}
+	return nil
+}
+
+// matchStreaming returns a command that searches svc for tracks
+func (m Model) matchStreaming(svc streaming.Service, tracks []api.Track) tea.Cmd {
+	ctx := m.ctx
+	tracks = slices.Clone(tracks)
+	return func() tea.Msg {
+		items, err := streaming.MatchAll(ctx, svc, tracks)
+		return shareMatchesMsg{items: items, err: err}
+	}
+}
+
+// createStreamingPlaylist returns a command that creates the reviewed
+// playlist on svc
+func (m Model) createStreamingPlaylist(svc streaming.Service, msg views.ShareCreateMsg) tea.Cmd {
+	ctx := m.ctx
+	return func() tea.Msg {
+		link, err := svc.CreatePlaylist(ctx, msg.Name, msg.Description, msg.IDs)
+		return shareCreatedMsg{service: svc.Name(), name: msg.Name, link: link, err: err}
+	}
+}
+
+// play starts a track. An audiobook being left keeps its position, and an
+// audiobook being started resumes where it was last stopped (the engine
+// seeks there, see SetResume).
+func (m *Model) play(track *api.Track) {
+	m.rememberPosition()
+	m.audioEngine.Play(track)
+}
+
+// rememberPosition records the playback position if an audiobook is loaded
+func (m *Model) rememberPosition() {
+	state := m.audioEngine.GetState()
+	if state.Status == api.StatusPlaying || state.Status == api.StatusPaused {
+		m.books.Remember(state.CurrentTrack, state.Position)
+	}
+}
+
+// editBookmarks changes the bookmarks of the current track with edit and
+// shows the result
+func (m *Model) editBookmarks(edit func(track *api.Track) error) {
+	track := m.audioEngine.GetState().CurrentTrack
+	if track == nil {
+		return
+	}
+	if err := edit(track); err != nil {
+		logger.Error("Failed to save bookmarks: %v", err)
+		m.err = err
+	}
+	m.playerView.SetBookmarks(m.marks.List(track))
+}
+
+// saveResume persists audiobook resume positions
+func (m *Model) saveResume() {
+	m.rememberPosition()
+	if err := m.books.Save(); err != nil {
+		logger.Error("Failed to save resume positions: %v", err)
+	}
+}
+
+// snapshot returns the playback state together with the queue
+func (m Model) snapshot() *api.PlaybackState {
+	return &m.audioEngine.Snapshot(m.queue).PlaybackState
+}
+
+// expandHome replaces a leading ~ with the user's home directory
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
+
+// refreshPlaylists reloads playlists into every view that shows them
+func (m *Model) refreshPlaylists() {
+	playlists := m.playlistManager.GetAll()
+	m.playlistView.SetPlaylists(playlists)
+	m.libraryView.SetPlaylists(playlists)
+}
+
+// addToPlaylist adds tracks to an existing playlist or a newly created one.
+// Remote search results are skipped since playlists only hold local files.
+func (m *Model) addToPlaylist(msg views.AddToPlaylistMsg) {
+	playlistID := msg.PlaylistID
+	if msg.NewName != "" {
+		pl, err := m.playlistManager.Create(msg.NewName, "")
+		if err != nil {
+			logger.Error("Failed to create playlist %q: %v", msg.NewName, err)
+			m.err = err
+			return
+		}
+		logger.Info("Created playlist %q", pl.Name)
+		playlistID = pl.ID
+	}
+
+	added, repeated, refused := 0, 0, 0
+	for _, track := range msg.Tracks {
+		if m.libraryView.IsRemote(track) {
+			logger.Warn("Skipping remote track %q: playlists only hold local files", track.Title)
+			continue
+		}
+		again := m.playlistManager.Contains(playlistID, track.ID)
+		err := m.playlistManager.AddTrack(playlistID, track)
+		if errors.Is(err, playerrors.ErrDuplicateTrack) {
+			logger.Warn("Skipping %q: already in the playlist", track.Title)
+			refused++
+			continue
+		}
+		if err != nil {
+			logger.Error("Failed to add %q to playlist: %v", track.Title, err)
+			m.err = err
+			break
+		}
+		added++
+		if again {
+			repeated++
+		}
+	}
+	logger.Info("Added %d track(s) to playlist %s", added, playlistID)
+	switch {
+	case refused > 0:
+		m.notice = i18n.T("%d track(s) already in the playlist were not added", refused)
+	case repeated > 0:
+		m.notice = i18n.T("%d track(s) were already in the playlist and are now in it twice", repeated)
+	}
+	m.refreshPlaylists()
+}
+
+// playList replaces the queue with tracks and plays from start, or from the
+// first track if start is nil
+func (m *Model) playList(tracks []*api.Track, start *api.Track) {
+	if m.queue.IsParty() {
+		m.partyAdd(tracks...)
+		return
+	}
+	m.queue.SetFrom(tracks, start)
+	m.afterQueueSet()
+}
+
+// partyAdd appends tracks to the queue in party mode, where play actions
+// never replace it. Playback starts at the first of them when nothing is
+// playing.
+func (m *Model) partyAdd(tracks ...*api.Track) {
+	if len(tracks) == 0 {
+		return
+	}
+	first := m.queue.Len()
+	m.queue.Add(tracks...)
+	logger.Info("Party mode: appended %d track(s)", len(tracks))
+	if m.audioEngine.GetState().Status == api.StatusStopped {
+		m.queue.JumpTo(first)
+		m.play(tracks[0])
+	}
+	m.refreshQueueView()
+}
+
+// afterQueueSet shows a replaced queue and plays its current track
+func (m *Model) afterQueueSet() {
+	m.refreshQueueView()
+	if track := m.queue.Current(); track != nil {
+		logger.Info("Playing %d queued track(s) from %q", m.queue.Len(), track.Title)
+		m.play(track)
+	}
+}
+
+// playSelected plays the track under the cursor of the active view, setting
+// the queue to the list it was picked from
+func (m *Model) playSelected() {
+	var track *api.Track
+	switch m.activeView {
+	case ViewLibrary:
+		track = m.libraryView.SelectedTrack()
+		if track != nil && m.libraryView.IsRemote(track) {
+			// Remote search result: stream it instead of touching the local queue
+			source := m.libraryView.SourceOf(track)
+			if src := m.searcher.StreamerFor(source); src != nil {
+				logger.Info("User selected remote track: %q from %s", track.Title, source)
+				if err := m.audioEngine.PlayFromURL(src.StreamURL(track.ID), src.Token()); err != nil {
+					logger.Error("Failed to stream %q: %v", track.Title, err)
+					m.err = err
+				}
+			}
+			return
+		}
+		if track != nil && m.queue.IsParty() {
+			m.partyAdd(track)
+			return
+		}
+		if track != nil {
+			// Set queue to the listed library tracks (all, or the genre) starting from selected
+			m.queue.SetFrom(m.libraryView.AllTracks, track)
+		}
+	case ViewPlaylist:
+		track = m.playlistView.SelectedTrack()
+		if track != nil && m.queue.IsParty() {
+			m.partyAdd(track)
+			return
+		}
+		if track != nil {
+			// Set queue to playlist tracks
+			pl := m.playlistView.SelectedPlaylist()
+			if pl != nil {
+				tracks := make([]*api.Track, len(pl.Tracks))
+				for i := range pl.Tracks {
+					tracks[i] = &pl.Tracks[i]
+				}
+				m.queue.SetFrom(tracks, track)
+			}
+		}
+	case ViewQueue:
+		if err := m.queue.JumpTo(m.queueView.SelectedIndex()); err == nil {
+			track = m.queue.Current()
+		}
+	case ViewHistory:
+		if rec, ok := m.historyView.SelectedRecord(); ok {
+			track = m.playAgain(rec)
+		}
+	}
+	if track != nil {
+		logger.Info("User selected track: %q by %s", track.Title, track.Artist)
+		m.play(track)
+	}
+	m.refreshQueueView()
+}
+
+// seek jumps within the current track, noting the jump in the session's
+// listening statistics
+func (m *Model) seek(pos time.Duration) {
+	m.sessions.Seeked(m.audioEngine.GetState().Position, pos)
+	m.audioEngine.Seek(pos)
+}
+
+// stepVolume changes the volume by delta, kept on whole percents
+func (m *Model) stepVolume(delta float64) {
+	m.schedules.ramp = schedule.Ramp{} // turning the volume ends an alarm's fade-in
+	vol := m.audioEngine.GetState().Volume + delta
+	m.audioEngine.SetVolume(min(max(math.Round(vol*100)/100, 0), 1))
+}
+
+// meterLevels reports whether the player's level meters are on screen and
+// moving
+func (m *Model) meterLevels() bool {
+	if m.levels == nil || m.activeView != ViewPlayer {
+		return false
+	}
+	state := m.playerView.State
+	return state != nil && state.Status == api.StatusPlaying
+}
+
+// updateView passes a message to the active view
+func (m *Model) updateView(msg tea.Msg) tea.Cmd {
+	var cmd tea.Cmd
+	switch m.activeView {
+	case ViewPlayer:
+		m.playerView, cmd = m.playerView.Update(msg)
+	case ViewLibrary:
+		m.libraryView, cmd = m.libraryView.Update(msg)
+	case ViewPlaylist:
+		m.playlistView, cmd = m.playlistView.Update(msg)
+	case ViewQueue:
+		m.queueView, cmd = m.queueView.Update(msg)
+	case ViewHistory:
+		m.historyView, cmd = m.historyView.Update(msg)
+	case ViewStats:
+		m.statsView, cmd = m.statsView.Update(msg)
+	}
+	return cmd
+}
+
+// scope returns the key binding scope of the active view
+func (m *Model) scope() keymap.Scope {
+	switch m.activeView {
+	case ViewPlayer:
+		return keymap.Player
+	case ViewLibrary:
+		return keymap.Library
+	case ViewPlaylist:
+		return keymap.Playlist
+	case ViewQueue:
+		return keymap.Queue
+	case ViewHistory:
+		return keymap.History
+	case ViewStats:
+		return keymap.Stats
+	}
+	return keymap.Global
+}
+
+// refreshHistoryView reloads the history tab from the play log
+func (m *Model) refreshHistoryView() {
+	m.historyView.SetRecords(m.library.GetHistory(time.Time{}))
+}
+
+// refreshStatsView recomputes the statistics tab from the play log while
+// it is shown
+func (m *Model) refreshStatsView() {
+	if m.activeView == ViewStats {
+		m.statsView.SetListening(m.library.Listening(time.Now(), views.DashboardDays))
+		m.statsView.Session = m.sessions.Summary()
+	}
+}
+
+// playAgain adds a track from the play history to the end of the queue
+// and returns it for playing. Tracks that were re-imported under a new ID
+// are found by file path.
+func (m *Model) playAgain(rec library.PlayRecord) *api.Track {
+	track, err := m.library.GetTrack(rec.TrackID)
+	if err != nil && rec.FilePath != "" {
+		track, err = m.library.ResolvePath(rec.FilePath)
+	}
+	if err != nil {
+		logger.Error("Cannot play %q again: %v", rec.Title, err)
+		m.err = fmt.Errorf("play again: %w", err)
+		return nil
+	}
+	logger.Info("Playing %q again from history", track.Title)
+	m.queue.Add(track)
+	m.queue.JumpTo(m.queue.Len() - 1)
+	return track
+}
+
+// refreshQueueView syncs the queue tab with the playback queue
+func (m *Model) refreshQueueView() {
+	m.queueView.SetQueue(m.queue.GetAll(), m.queue.Index())
+	if sp := m.savePoint; sp != nil {
+		m.queueView.SetSavePoint(sp.Current(), sp.Position)
+	} else {
+		m.queueView.SetSavePoint(nil, 0)
+	}
+}
+
+// setSavePoint remembers the queue and the position in the current track
+func (m *Model) setSavePoint() {
+	if m.queue.Current() == nil {
+		return
+	}
+	var pos time.Duration
+	state := m.audioEngine.GetState()
+	if state.CurrentTrack != nil && state.CurrentTrack.ID == m.queue.Current().ID && state.Status != api.StatusStopped {
+		pos = state.Position
+	}
+	m.savePoint = m.queue.SavePoint(pos)
+	logger.Info("Queue save point set at %q (%s)", m.queue.Current().Title, pos)
+	m.refreshQueueView()
+}
+
+// returnToSavePoint restores the queue from the save point and resumes the
+// track that was playing there, from where it was
+func (m *Model) returnToSavePoint() {
+	sp := m.savePoint
+	if sp == nil {
+		return
+	}
+	m.savePoint = nil
+	m.queue.Restore(sp)
+	if track := sp.Current(); track != nil {
+		logger.Info("Returning to save point at %q (%s)", track.Title, sp.Position)
+		state := m.audioEngine.GetState()
+		if state.CurrentTrack == nil || state.CurrentTrack.ID != track.ID || state.Status == api.StatusStopped {
+			m.play(track)
+		}
+		if sp.Position > 0 {
+			m.seek(sp.Position)
+		}
+	}
+	m.refreshQueueView()
 }
 
 // View renders the UI
 func (m Model) View() string {
+	s := m.render()
+	if m.noColor {
+		s = monochrome(s)
+	}
+	return s
+}
+
+// render draws the UI
+func (m Model) render() string {
 	var sb string
 
 	// Header with tabs
@@ -390,17 +2321,61 @@ func (m Model) View() string {
 	sb += "\n"
 
 	// Main content
-	switch m.activeView {
-	case ViewPlayer:
+	switch {
+	case m.showHelp:
+		sb += m.renderHelp()
+	case m.log.open:
+		sb += m.renderLog()
+	case m.schedules.open:
+		sb += m.renderSchedules()
+	case m.cast.picking:
+		sb += m.cast.menu.View()
+	case m.markPopup.Open:
+		sb += m.markPopup.View()
+	case m.activeView == ViewPlayer:
 		sb += m.playerView.View()
-	case ViewLibrary:
-		sb += m.playerView.View()
-		sb += "\n"
+	case m.splitShown():
+		sb += m.renderSplit()
+	case m.activeView == ViewLibrary:
 		sb += m.libraryView.View()
-	case ViewPlaylist:
-		sb += m.playerView.View()
-		sb += "\n"
+	case m.activeView == ViewPlaylist:
 		sb += m.playlistView.View()
+	case m.activeView == ViewQueue:
+		sb += m.queueView.View()
+	case m.activeView == ViewHistory:
+		sb += m.historyView.View()
+	case m.activeView == ViewStats:
+		sb += m.statsView.View()
+	}
+
+	// Footer: the now-playing bar everywhere but the player view, then
+	// the status line, kept at the bottom of the screen
+	var footer []string
+	if m.accessible && m.announcement != "" {
+		footer = append(footer, m.announcement)
+	}
+	if m.activeView != ViewPlayer {
+		footer = append(footer, m.nowPlaying.View())
+	}
+	if m.readOnly {
+		footer = append(footer, lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render(i18n.T("Read-only: another instance owns the data directory")))
+	}
+	if m.notice != "" {
+		footer = append(footer, lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render(m.notice))
+	}
+	if n := m.log.unseen; n > 0 && !m.log.open {
+		status := i18n.T("%d new warning(s)  [%s] Show log", n, m.keys.KeysFor(keymap.Log))
+		footer = append(footer, lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render(status))
+	}
+	if status := m.castStatus(); status != "" {
+		footer = append(footer, lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(status))
+	}
+	if m.scanReport != nil {
+		status := m.scanReport.Summary()
+		if m.scanReport.Failed > 0 {
+			status += "  " + i18n.T("[E] Show errors")
+		}
+		footer = append(footer, lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(status))
 	}
 
 	// Error display
@@ -408,19 +2383,64 @@ func (m Model) View() string {
 		errorStyle := lipgloss.NewStyle().
 			Foreground(lipgloss.Color("196")).
 			Bold(true)
-		sb += "\n" + errorStyle.Render(fmt.Sprintf("Error: %v", m.err))
+		footer = append(footer, errorStyle.Render(i18n.T("Error: %v", m.err)))
+	}
+
+	if len(footer) == 0 {
+		return sb
 	}
+	bottom := strings.Join(footer, "\n")
+	gap := max(m.height-lipgloss.Height(sb)-lipgloss.Height(bottom), 0)
+	return sb + strings.Repeat("\n", gap+1) + bottom
+}
 
-	return sb
+// renderSplit draws the library and queue views side by side, the border
+// of the focused one highlighted
+func (m Model) renderSplit() string {
+	focus := lipgloss.Color("212")
+	library, queue := m.libraryView, m.queueView
+	if m.activeView == ViewLibrary {
+		library.BorderStyle = library.BorderStyle.BorderForeground(focus)
+	} else {
+		queue.BorderStyle = queue.BorderStyle.BorderForeground(focus)
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, library.View(), queue.View())
+}
+
+// renderHelp lists the global key bindings and those of the active view
+func (m Model) renderHelp() string {
+	keyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Bold(true)
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	var sb strings.Builder
+	section := func(scope keymap.Scope) {
+		bindings := m.keys.Bindings(scope)
+		if len(bindings) == 0 {
+			return
+		}
+		sb.WriteString(m.headerStyle.Render(i18n.T(scope.String())))
+		sb.WriteString("\n")
+		for _, b := range bindings {
+			sb.WriteString(fmt.Sprintf("  %s %s\n", keyStyle.Render(fmt.Sprintf("%-12s", m.keys.KeysFor(b.Action))), i18n.T(b.Help)))
+		}
+		sb.WriteString("\n")
+	}
+	section(m.scope())
+	section(keymap.Global)
+	sb.WriteString(dim.Render(i18n.T("Press any key to close")))
+	return sb.String()
 }
 
 // renderTabs renders the tab bar
 func (m Model) renderTabs() string {
-	tabs := []string{"[1] Player", "[2] Library", "[3] Playlist"}
+	tabs := []string{"Player", "Library", "Playlist", "Queue", "History", "Stats"}
 
 	var rendered []string
-	for i, tab := range tabs {
-		if ViewType(i) == m.activeView {
+	for i, name := range tabs {
+		tab := fmt.Sprintf("[%d] %s", i+1, i18n.T(name))
+		if m.flashing {
+			rendered = append(rendered, m.tabStyle.Reverse(true).Render(tab))
+		} else if ViewType(i) == m.activeView {
 			rendered = append(rendered, m.activeTabStyle.Render(tab))
 		} else {
 			rendered = append(rendered, m.tabStyle.Render(tab))
@@ -431,11 +2451,37 @@ func (m Model) renderTabs() string {
 }
 
 // Run starts the bubbletea program
-func Run(engine *audio.AudioEngine, lib *library.Library, plManager *playlist.Manager) error {
+func Run(engine *audio.AudioEngine, lib *library.Library, plManager *playlist.Manager, opts Options) error {
 	logger.Info("Starting UI")
-	model := NewModel(engine, lib, plManager)
-	p := tea.NewProgram(model, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	glyphs.UseASCII(opts.Accessible || opts.NoColor)
+	if opts.NoColor {
+		// Under NO_COLOR lipgloss would drop bold and reverse video along
+		// with the colors, and selections would disappear; View strips the
+		// colors itself
+		lipgloss.SetColorProfile(termenv.ANSI)
+	}
+	model := NewModel(engine, lib, plManager, opts)
+	model.bell = &bellOutput{File: os.Stdout}
+	p := tea.NewProgram(crashGuard{model}, tea.WithAltScreen(), tea.WithMouseCellMotion(), tea.WithOutput(model.bell))
+
+	// A crash keeps the queue for the next start and gives the terminal
+	// back before the report is printed
+	crash.AddState("queue", func() any { return model.queue.Saved("", model.queuePosition()) })
+	crash.OnExit(model.saveQueue)
+	var exited atomic.Bool
+	crash.OnExit(func() {
+		if !exited.Load() {
+			p.ReleaseTerminal()
+		}
+	})
+
 	_, err := p.Run()
+	exited.Store(true)
+	if errors.Is(err, tea.ErrProgramPanic) {
+		// Bubble Tea caught this one itself, in a command run in sequence,
+		// and printed its stack
+		crash.Exit("panic in a UI command, see the stack trace above", nil)
+	}
 	if err != nil {
 		logger.Error("UI exited with error: %v", err)
 	} else {
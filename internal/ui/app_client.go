@@ -6,6 +6,7 @@ package ui
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -158,12 +159,13 @@ func (a ClientApp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.queueIdx = msg.Index
 		// Record the play event in session stats
 		a.stats.RecordPlay(msg.Track.ID, msg.Track.Title, msg.Track.Artist, msg.Track.Album, msg.Track.DurationSeconds)
+		a.stats.StartSession(msg.Track.ID, msg.Track.Title, msg.Track.Artist, time.Duration(msg.Track.DurationSeconds)*time.Second)
 		// Start playback via HTTP streaming
 		streamURL := a.client.StreamURL(msg.Track.ID)
 		if err := playHTTPTrack(a.engine, streamURL, a.client.Token); err != nil {
 			// Non-fatal: stay on library but show error in player
 		}
-		a.playerScreen = screens.NewPlayerScreen(a.engine, a.client, msg.Track, msg.AllTracks, msg.Index, a.width, a.height)
+		a.playerScreen = screens.NewPlayerScreen(a.engine, a.client, a.stats, msg.Track, msg.AllTracks, msg.Index, a.width, a.height)
 		a.screen = ClientScreenPlayer
 		return a, a.playerScreen.Init()
 
@@ -175,9 +177,10 @@ func (a ClientApp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if len(a.allTracks) > 0 {
 			track := a.allTracks[a.queueIdx]
 			a.stats.RecordPlay(track.ID, track.Title, track.Artist, track.Album, track.DurationSeconds)
+			a.stats.StartSession(track.ID, track.Title, track.Artist, time.Duration(track.DurationSeconds)*time.Second)
 			streamURL := a.client.StreamURL(track.ID)
 			playHTTPTrack(a.engine, streamURL, a.client.Token)
-			a.playerScreen = screens.NewPlayerScreen(a.engine, a.client, track, a.allTracks, a.queueIdx, a.width, a.height)
+			a.playerScreen = screens.NewPlayerScreen(a.engine, a.client, a.stats, track, a.allTracks, a.queueIdx, a.width, a.height)
 		}
 		return a, a.playerScreen.Init()
 
@@ -186,9 +189,10 @@ func (a ClientApp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			a.queueIdx--
 			track := a.allTracks[a.queueIdx]
 			a.stats.RecordPlay(track.ID, track.Title, track.Artist, track.Album, track.DurationSeconds)
+			a.stats.StartSession(track.ID, track.Title, track.Artist, time.Duration(track.DurationSeconds)*time.Second)
 			streamURL := a.client.StreamURL(track.ID)
 			playHTTPTrack(a.engine, streamURL, a.client.Token)
-			a.playerScreen = screens.NewPlayerScreen(a.engine, a.client, track, a.allTracks, a.queueIdx, a.width, a.height)
+			a.playerScreen = screens.NewPlayerScreen(a.engine, a.client, a.stats, track, a.allTracks, a.queueIdx, a.width, a.height)
 			return a, a.playerScreen.Init()
 		}
 		// Already first track — go back to library
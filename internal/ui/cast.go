@@ -0,0 +1,259 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jscyril/golang_music_player/internal/audio"
+	"github.com/jscyril/golang_music_player/internal/cast"
+	"github.com/jscyril/golang_music_player/internal/dlna"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/logger"
+	"github.com/jscyril/golang_music_player/internal/renderer"
+	"github.com/jscyril/golang_music_player/internal/ui/components"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// castDiscoverWait is how long the cast picker listens for devices
+const castDiscoverWait = 3 * time.Second
+
+// castState is the cast picker and the device the player follows
+type castState struct {
+	port   int
+	server *renderer.Server // started on first use
+
+	session    renderer.Renderer
+	stop       context.CancelFunc
+	output     string // output to return to when casting stops
+	connecting string // device being connected to
+
+	picking bool
+	devices []renderer.Device
+	menu    components.Menu
+}
+
+// castDevicesMsg carries the devices found for the cast picker
+type castDevicesMsg struct {
+	devices []renderer.Device
+	err     error
+}
+
+// castStartedMsg reports a connection to a device
+type castStartedMsg struct {
+	session renderer.Renderer
+	err     error
+}
+
+// castEndedMsg reports that a device stopped following the player
+type castEndedMsg struct {
+	session renderer.Renderer
+	err     error
+}
+
+// castDiscoverers look for each kind of device
+var castDiscoverers = []func(context.Context, time.Duration) ([]renderer.Device, error){
+	cast.Discover,
+	dlna.Discover,
+}
+
+// openCast shows the cast picker and looks for Chromecasts and DLNA
+// renderers at the same time
+func (m *Model) openCast() tea.Cmd {
+	if m.bus == nil {
+		return nil
+	}
+	c := m.cast
+	c.picking, c.devices = true, nil
+	c.menu = components.NewMenu(i18n.T("Cast to"), m.castItems(glyphs.Text(i18n.T("Searching…"))))
+	ctx := m.ctx
+	return func() tea.Msg {
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		var msg castDevicesMsg
+		for _, discover := range castDiscoverers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				devices, err := discover(ctx, castDiscoverWait)
+				mu.Lock()
+				defer mu.Unlock()
+				msg.devices = append(msg.devices, devices...)
+				if msg.err == nil {
+					msg.err = err
+				}
+			}()
+		}
+		wg.Wait()
+		sort.SliceStable(msg.devices, func(i, j int) bool {
+			return strings.ToLower(msg.devices[i].Name) < strings.ToLower(msg.devices[j].Name)
+		})
+		return msg
+	}
+}
+
+// connectCast connects to d with the protocol of its kind
+func connectCast(ctx context.Context, d renderer.Device, srv *renderer.Server) (renderer.Renderer, error) {
+	switch d.Kind {
+	case cast.Kind:
+		return cast.Dial(ctx, d, srv)
+	case dlna.Kind:
+		return dlna.Open(ctx, d, srv)
+	}
+	return nil, fmt.Errorf("unknown device kind %q", d.Kind)
+}
+
+// castItems lists the found devices, or a disabled note when there are
+// none, then the picker's actions
+func (m *Model) castItems(note string) []components.MenuItem {
+	var items []components.MenuItem
+	for i, d := range m.cast.devices {
+		label := fmt.Sprintf("%s (%s)", d.Name, d.Kind)
+		if m.cast.session != nil && m.cast.session.Device().Addr == d.Addr {
+			label += " " + i18n.T("(casting)")
+		}
+		key := ""
+		if i < 9 {
+			key = strconv.Itoa(i + 1)
+		}
+		items = append(items, components.MenuItem{ID: strconv.Itoa(i), Label: label, Key: key})
+	}
+	if len(items) == 0 {
+		items = append(items, components.MenuItem{Label: note, Disabled: true})
+	}
+	items = append(items, components.MenuItem{ID: "rescan", Label: i18n.T("Search again"), Key: "r"})
+	if m.cast.session != nil {
+		items = append(items, components.MenuItem{ID: "stop", Label: i18n.T("Stop casting"), Key: "s"})
+	}
+	return items
+}
+
+// updateCastPicker handles a key while the cast picker is open
+func (m *Model) updateCastPicker(msg tea.KeyMsg) tea.Cmd {
+	c := m.cast
+	var result components.MenuResult
+	c.menu, result = c.menu.Update(msg)
+	if !result.Done {
+		return nil
+	}
+	c.picking = false
+	switch result.ID {
+	case "":
+		return nil
+	case "rescan":
+		return m.openCast()
+	case "stop":
+		m.stopCast()
+		return nil
+	}
+	i, _ := strconv.Atoi(result.ID)
+	device := c.devices[i]
+	if c.session != nil && c.session.Device().Addr == device.Addr {
+		return nil
+	}
+	if c.server == nil {
+		srv, err := renderer.NewServer(c.port)
+		if err != nil {
+			logger.Error("Cast to %s: %v", device.Name, err)
+			m.err = err
+			return nil
+		}
+		c.server = srv
+	}
+	c.connecting = device.Name
+	ctx, srv := m.ctx, c.server
+	return func() tea.Msg {
+		s, err := connectCast(ctx, device, srv)
+		return castStartedMsg{session: s, err: err}
+	}
+}
+
+// startCast routes playback to the silent cast output and has s follow
+// the player. The returned command reports when s stops following.
+func (m *Model) startCast(s renderer.Renderer) tea.Cmd {
+	c := m.cast
+	c.connecting = ""
+	// Registered at startup when the config sets its trim
+	if !slices.Contains(m.audioEngine.Sinks(), audio.CastSinkName) {
+		m.audioEngine.RegisterSink(audio.NewNullSink(audio.CastSinkName))
+	}
+
+	// Moving to another device keeps the output to return to
+	if c.session != nil {
+		c.stop()
+		c.session.Close()
+		c.session = nil
+	} else {
+		c.output = m.audioEngine.GetState().Output
+	}
+	if err := m.audioEngine.SwitchSink(audio.CastSinkName); err != nil {
+		m.err = err
+		s.Close()
+		return nil
+	}
+	logger.Info("Casting to %s %s (%s)", s.Device().Kind, s.Device().Name, s.Device().Addr)
+
+	ctx, stop := context.WithCancel(m.ctx)
+	c.session, c.stop = s, stop
+	bus, state := m.bus, m.audioEngine.GetState
+	return func() tea.Msg {
+		return castEndedMsg{session: s, err: renderer.Follow(ctx, s, bus, state)}
+	}
+}
+
+// castEnded handles the end of a session the user did not stop, e.g. when
+// the device went away or another app took it over
+func (m *Model) castEnded(msg castEndedMsg) {
+	if msg.session != m.cast.session {
+		return // already stopped or replaced
+	}
+	name := msg.session.Device().Name
+	m.endCast()
+	if msg.err != nil {
+		logger.Error("Casting to %s ended: %v", name, msg.err)
+		m.err = fmt.Errorf("casting to %s ended: %w", name, msg.err)
+	}
+}
+
+// stopCast stops casting and plays locally again
+func (m *Model) stopCast() {
+	if m.cast.session == nil {
+		return
+	}
+	logger.Info("Stopped casting to %s", m.cast.session.Device().Name)
+	m.endCast()
+}
+
+// endCast closes the session, if any, and switches back to the output
+// used before casting
+func (m *Model) endCast() {
+	c := m.cast
+	if c.session == nil {
+		return
+	}
+	c.stop()
+	if err := c.session.Close(); err != nil {
+		logger.Warn("Closing cast session: %v", err)
+	}
+	c.session, c.stop = nil, nil
+	if c.output != "" && c.output != audio.CastSinkName && slices.Contains(m.audioEngine.Sinks(), c.output) {
+		m.audioEngine.SwitchSink(c.output)
+	}
+}
+
+// castStatus describes casting for the status line; empty when idle
+func (m Model) castStatus() string {
+	switch {
+	case m.cast.connecting != "":
+		return fmt.Sprintf(glyphs.Text(i18n.T("Connecting to %s…")), m.cast.connecting)
+	case m.cast.session != nil:
+		return i18n.T("Casting to %s", m.cast.session.Device().Name)
+	}
+	return ""
+}
@@ -0,0 +1,203 @@
+package components
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// Column is one field of a TrackList row, named as in the config
+type Column string
+
+const (
+	ColIndex    Column = "index" // position in the list
+	ColTrack    Column = "track" // track number tag
+	ColTitle    Column = "title"
+	ColArtist   Column = "artist"
+	ColAlbum    Column = "album"
+	ColYear     Column = "year"
+	ColDuration Column = "duration"
+	ColFormat   Column = "format" // file type and average bitrate
+	ColBitrate  Column = "bitrate"
+	ColRate     Column = "samplerate"
+	ColChannels Column = "channels"
+	ColCodec    Column = "codec"
+	ColSize     Column = "size"
+)
+
+// DefaultColumns are shown by track lists with no columns configured
+var DefaultColumns = []Column{ColIndex, ColTitle, ColArtist, ColAlbum, ColDuration}
+
+// columnSpec describes how a column is laid out. Columns with a width keep
+// it; the others share the space left over in proportion to their weight.
+type columnSpec struct {
+	header string
+	width  int
+	weight int
+	right  bool
+}
+
+var columnSpecs = map[Column]columnSpec{
+	ColIndex:    {header: "#", width: 4, right: true},
+	ColTrack:    {header: "Trk", width: 3, right: true},
+	ColTitle:    {header: "Title", weight: 3},
+	ColArtist:   {header: "Artist", weight: 2},
+	ColAlbum:    {header: "Album", weight: 2},
+	ColYear:     {header: "Year", width: 4, right: true},
+	ColDuration: {header: "Time", width: 6, right: true},
+	ColFormat:   {header: "Format", width: 10},
+	ColBitrate:  {header: "kbps", width: 5, right: true},
+	ColRate:     {header: "Rate", width: 6, right: true},
+	ColChannels: {header: "Ch", width: 2, right: true},
+	ColCodec:    {header: "Codec", width: 5},
+	ColSize:     {header: "Size", width: 7, right: true},
+}
+
+// dropOrder is the order columns are hidden in when the list is too narrow
+// to give every text column minTextWidth; the title is never hidden
+var dropOrder = []Column{ColAlbum, ColSize, ColChannels, ColRate, ColCodec, ColFormat, ColBitrate, ColYear, ColTrack, ColArtist, ColIndex, ColDuration}
+
+// minTextWidth is the narrowest a title, artist or album column gets
+const minTextWidth = 8
+
+// ParseColumns reads column names from the config. An empty list returns
+// nil, which keeps DefaultColumns.
+func ParseColumns(names []string) ([]Column, error) {
+	var cols []Column
+	for _, name := range names {
+		c := Column(strings.ToLower(strings.TrimSpace(name)))
+		if _, ok := columnSpecs[c]; !ok {
+			return nil, fmt.Errorf("unknown track list column %q", name)
+		}
+		if slices.Contains(cols, c) {
+			return nil, fmt.Errorf("track list column %q is listed twice", name)
+		}
+		cols = append(cols, c)
+	}
+	return cols, nil
+}
+
+// layoutColumns fits cols into width cells, one cell apart. It returns the
+// columns that are shown and their widths.
+func layoutColumns(cols []Column, width int) ([]Column, []int) {
+	cols = slices.Clone(cols)
+	for {
+		fixed, weights, minWeight := len(cols)-1, 0, 0
+		for _, c := range cols {
+			spec := columnSpecs[c]
+			fixed += spec.width
+			weights += spec.weight
+			if spec.weight > 0 && (minWeight == 0 || spec.weight < minWeight) {
+				minWeight = spec.weight
+			}
+		}
+		free := width - fixed
+		fits := free >= 0
+		if weights > 0 {
+			fits = free*minWeight/weights >= minTextWidth
+		}
+		if fits || !dropColumn(&cols) {
+			return cols, columnWidths(cols, free, weights)
+		}
+	}
+}
+
+// dropColumn removes the first column of dropOrder found in cols, reporting
+// false when there is nothing left to drop
+func dropColumn(cols *[]Column) bool {
+	for _, c := range dropOrder {
+		if i := slices.Index(*cols, c); i >= 0 && len(*cols) > 1 {
+			*cols = slices.Delete(*cols, i, i+1)
+			return true
+		}
+	}
+	return false
+}
+
+// columnWidths shares free cells among the weighted columns; the widest
+// share takes the remainder
+func columnWidths(cols []Column, free, weights int) []int {
+	widths := make([]int, len(cols))
+	widest, used := -1, 0
+	for i, c := range cols {
+		spec := columnSpecs[c]
+		if spec.weight == 0 {
+			widths[i] = spec.width
+			continue
+		}
+		widths[i] = max(free*spec.weight/weights, 1)
+		used += widths[i]
+		if widest < 0 || spec.weight > columnSpecs[cols[widest]].weight {
+			widest = i
+		}
+	}
+	if widest >= 0 && free > used {
+		widths[widest] += free - used
+	}
+	return widths
+}
+
+// columnText returns what column c shows for the track at list index i
+func columnText(c Column, t *api.Track, i int) string {
+	switch c {
+	case ColIndex:
+		return strconv.Itoa(i + 1)
+	case ColTrack:
+		if t.TrackNum > 0 {
+			return strconv.Itoa(t.TrackNum)
+		}
+	case ColTitle:
+		return t.Title
+	case ColArtist:
+		return t.Artist
+	case ColAlbum:
+		return t.Album
+	case ColYear:
+		if t.Year > 0 {
+			return strconv.Itoa(t.Year)
+		}
+	case ColDuration:
+		if t.Duration > 0 {
+			return formatDuration(t.Duration)
+		}
+	case ColFormat:
+		format := strings.ToUpper(strings.TrimPrefix(filepath.Ext(t.FilePath), "."))
+		if t.Bitrate > 0 {
+			format += fmt.Sprintf(" %dk", t.Bitrate)
+		}
+		return format
+	case ColBitrate:
+		if t.Bitrate > 0 {
+			return strconv.Itoa(t.Bitrate)
+		}
+	case ColRate:
+		if t.SampleRate > 0 {
+			return strconv.FormatFloat(float64(t.SampleRate)/1000, 'f', -1, 64) + "k"
+		}
+	case ColChannels:
+		if t.Channels > 0 {
+			return strconv.Itoa(t.Channels)
+		}
+	case ColCodec:
+		return t.Codec
+	case ColSize:
+		if t.FileSize > 0 {
+			return fmt.Sprintf("%.1fM", float64(t.FileSize)/(1<<20))
+		}
+	}
+	return ""
+}
+
+// fitCell truncates s to width cells and pads it out to exactly width
+func fitCell(s string, width int, right bool) string {
+	s = truncate(s, width)
+	pad := strings.Repeat(" ", max(width-textWidth(s), 0))
+	if right {
+		return pad + s
+	}
+	return s + pad
+}
@@ -0,0 +1,35 @@
+package components
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestLayoutColumns verifies text columns share the free width and that
+// columns are hidden in dropOrder when the list gets narrow
+func TestLayoutColumns(t *testing.T) {
+	cols, widths := layoutColumns(DefaultColumns, 100)
+	if !slices.Equal(cols, DefaultColumns) {
+		t.Fatalf("at 100 cells shown %v, want %v", cols, DefaultColumns)
+	}
+	total := len(widths) - 1
+	for _, w := range widths {
+		total += w
+	}
+	if total != 100 {
+		t.Errorf("widths %v add up to %d cells, want 100", widths, total)
+	}
+
+	cols, _ = layoutColumns(DefaultColumns, 40)
+	if want := []Column{ColIndex, ColTitle, ColArtist, ColDuration}; !slices.Equal(cols, want) {
+		t.Errorf("at 40 cells shown %v, want %v", cols, want)
+	}
+	cols, _ = layoutColumns(DefaultColumns, 10)
+	if want := []Column{ColTitle}; !slices.Equal(cols, want) {
+		t.Errorf("at 10 cells shown %v, want %v", cols, want)
+	}
+
+	if _, err := ParseColumns([]string{"title", "bpm"}); err == nil {
+		t.Error("ParseColumns accepted an unknown column")
+	}
+}
@@ -0,0 +1,89 @@
+package components
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+)
+
+// Actions a Confirm asks about, as named in the config's skip_confirm
+const (
+	ConfirmRemoveTracks   = "remove_tracks"
+	ConfirmDeletePlaylist = "delete_playlist"
+	ConfirmDeleteFiles    = "delete_files" // always asked
+)
+
+// DontAskMsg asks the app to stop confirming Action from now on
+type DontAskMsg struct {
+	Action string
+}
+
+// Confirm is a yes/no question asked before a destructive action. "y"
+// accepts, "n", Esc or Enter decline, and "a" accepts and stops asking
+// about the same Action unless AskAlways is set.
+type Confirm struct {
+	Action        string
+	Question      string
+	AskAlways     bool // no "don't ask again", e.g. before deleting files
+	QuestionStyle lipgloss.Style
+	HelpStyle     lipgloss.Style
+}
+
+// ConfirmResult is the outcome of a Confirm. Accepted and DontAsk are only
+// meaningful once Done is true.
+type ConfirmResult struct {
+	Done     bool
+	Accepted bool
+	DontAsk  bool
+	Action   string
+}
+
+// NewConfirm creates the question asked before action
+func NewConfirm(action, question string) Confirm {
+	return Confirm{
+		Action:        action,
+		Question:      question,
+		QuestionStyle: lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true),
+		HelpStyle:     lipgloss.NewStyle().Foreground(lipgloss.Color("240")),
+	}
+}
+
+// Update handles a key. Keys other than the answers are ignored.
+func (c Confirm) Update(msg tea.KeyMsg) (Confirm, ConfirmResult) {
+	result := ConfirmResult{Done: true, Action: c.Action}
+	switch msg.String() {
+	case "y", "Y":
+		result.Accepted = true
+	case "a", "A":
+		if c.AskAlways {
+			return c, ConfirmResult{}
+		}
+		result.Accepted, result.DontAsk = true, true
+	case "n", "N", "esc", "enter":
+	default:
+		return c, ConfirmResult{}
+	}
+	return c, result
+}
+
+// Then returns cmd if the question was accepted, together with a
+// DontAskMsg if the user asked not to be asked again, and nil otherwise
+func (r ConfirmResult) Then(cmd tea.Cmd) tea.Cmd {
+	if !r.Accepted {
+		return nil
+	}
+	if r.DontAsk {
+		action := r.Action
+		return tea.Batch(cmd, func() tea.Msg { return DontAskMsg{Action: action} })
+	}
+	return cmd
+}
+
+// View renders the question and its keys on one line
+func (c Confirm) View() string {
+	help := i18n.T("[y] Yes  [n] No  [a] Yes, don't ask again")
+	if c.AskAlways {
+		help = i18n.T("[y] Yes  [n] No")
+	}
+	return c.QuestionStyle.Render(c.Question) + "  " + c.HelpStyle.Render(help)
+}
@@ -0,0 +1,39 @@
+package components
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestConfirm verifies the answers of a Confirm and that only "don't ask
+// again" reports DontAskMsg
+func TestConfirm(t *testing.T) {
+	c := NewConfirm(ConfirmDeletePlaylist, "Delete?")
+	answer := func(key string) ConfirmResult {
+		_, r := c.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+		return r
+	}
+
+	if r := answer("x"); r.Done {
+		t.Error("unrelated key answered the question")
+	}
+	if r := answer("n"); !r.Done || r.Accepted || r.Then(func() tea.Msg { return nil }) != nil {
+		t.Errorf("n = %+v, want declined", r)
+	}
+	if r := answer("y"); !r.Accepted || r.DontAsk {
+		t.Errorf("y = %+v, want accepted once", r)
+	}
+
+	r := answer("a")
+	if !r.Accepted || !r.DontAsk {
+		t.Fatalf("a = %+v, want accepted for good", r)
+	}
+	batch, ok := r.Then(func() tea.Msg { return nil })().(tea.BatchMsg)
+	if !ok || len(batch) != 2 {
+		t.Fatalf("accepting for good did not batch the action with DontAskMsg")
+	}
+	if msg, ok := batch[1]().(DontAskMsg); !ok || msg.Action != ConfirmDeletePlaylist {
+		t.Errorf("second command sent %#v, want DontAskMsg{%q}", batch[1](), ConfirmDeletePlaylist)
+	}
+}
@@ -1,22 +1,59 @@
 package components
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
 )
 
 // FileEntry represents a file or directory in the browser
 type FileEntry struct {
-	Name  string
-	Path  string
-	IsDir bool
+	Name    string
+	Path    string
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
 }
 
+// FileSort is the order of the browser's entries. Directories always come
+// before files.
+type FileSort int
+
+const (
+	SortByName FileSort = iota
+	SortByTime          // newest first
+	SortBySize          // largest first; directories stay by name
+)
+
+// String names the order for the browser's status line
+func (s FileSort) String() string {
+	switch s {
+	case SortByTime:
+		return "modified"
+	case SortBySize:
+		return "size"
+	}
+	return "name"
+}
+
+// BookmarksChangedMsg is sent when a bookmark is added or removed, so the
+// list can be saved
+type BookmarksChangedMsg struct {
+	Bookmarks []string
+}
+
+// maxBookmarks is how many bookmarks the number keys reach
+const maxBookmarks = 9
+
 // FileBrowser is a component for navigating the filesystem
 type FileBrowser struct {
 	Width       int
@@ -28,6 +65,16 @@ type FileBrowser struct {
 	Extensions  []string // Supported file extensions
 	Err         error
 
+	ShowHidden bool     // list dot files and directories
+	SortBy     FileSort // order of entries within directories and files
+	Bookmarks  []string // folders reached with the number keys
+
+	// PathInput is where a path is typed after ":"
+	PathInput SearchInput
+
+	// marked holds the paths of files marked with Space, across folders
+	marked map[string]bool
+
 	// Styles
 	DirStyle      lipgloss.Style
 	FileStyle     lipgloss.Style
@@ -42,6 +89,7 @@ func NewFileBrowser(startPath string, width, height int) FileBrowser {
 		Width:      width,
 		Height:     height,
 		Extensions: []string{".mp3", ".wav", ".flac"},
+		PathInput:  NewSearchInput(width - 10),
 		DirStyle: lipgloss.NewStyle().
 			Foreground(lipgloss.Color("33")).
 			Bold(true),
@@ -55,7 +103,7 @@ func NewFileBrowser(startPath string, width, height int) FileBrowser {
 			Foreground(lipgloss.Color("212")).
 			Bold(true),
 		BorderStyle: lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
+			Border(glyphs.RoundedBorder()).
 			BorderForeground(lipgloss.Color("62")).
 			Padding(1, 2),
 	}
@@ -70,10 +118,128 @@ func NewFileBrowser(startPath string, width, height int) FileBrowser {
 		}
 	}
 
+	fb.PathInput.Prompt = "Go to: "
+	fb.PathInput.Style = lipgloss.NewStyle()
+	fb.PathInput.FocusStyle = lipgloss.NewStyle()
+	fb.PathInput.Placeholder = i18n.T("path, ~ for home")
 	fb.Navigate(startPath)
 	return fb
 }
 
+// Marked returns the marked files in the order they sort by path
+func (fb FileBrowser) Marked() []string {
+	paths := make([]string, 0, len(fb.marked))
+	for p := range fb.marked {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ClearMarks unmarks every file
+func (fb *FileBrowser) ClearMarks() {
+	fb.marked = nil
+}
+
+// toggleMark marks or unmarks the selected file and moves down, so Space
+// can be held to mark a run of files. Directories cannot be marked.
+func (fb *FileBrowser) toggleMark() {
+	entry := fb.SelectedEntry()
+	if entry == nil || entry.IsDir {
+		return
+	}
+	if fb.marked[entry.Path] {
+		delete(fb.marked, entry.Path)
+	} else {
+		if fb.marked == nil {
+			fb.marked = make(map[string]bool)
+		}
+		fb.marked[entry.Path] = true
+	}
+	if fb.Selected < len(fb.Entries)-1 {
+		fb.Selected++
+		fb.ensureVisible()
+	}
+}
+
+// Typing reports whether a path is being typed; every key then belongs to
+// the browser
+func (fb FileBrowser) Typing() bool {
+	return fb.PathInput.Focused
+}
+
+// Reload lists the current directory again, keeping the selected entry
+// selected when it is still there
+func (fb *FileBrowser) Reload() {
+	var name string
+	if entry := fb.SelectedEntry(); entry != nil {
+		name = entry.Name
+	}
+	fb.Navigate(fb.CurrentPath)
+	for i, e := range fb.Entries {
+		if e.Name == name {
+			fb.Selected = i
+			fb.ensureVisible()
+			break
+		}
+	}
+}
+
+// Reveal opens the folder holding the file at path with the file selected
+func (fb *FileBrowser) Reveal(path string) {
+	path = filepath.Clean(path)
+	fb.Navigate(filepath.Dir(path))
+	for i, e := range fb.Entries {
+		if e.Path == path {
+			fb.Selected = i
+			fb.ensureVisible()
+			break
+		}
+	}
+}
+
+// goTo navigates to a typed path: "~" expands to the home directory and
+// relative paths start from the current directory. A path that is not a
+// directory is reported in Err and leaves the browser where it was.
+func (fb *FileBrowser) goTo(path string) {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, path[1:])
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(fb.CurrentPath, path)
+	}
+	info, err := os.Stat(path)
+	if err == nil && !info.IsDir() {
+		err = fmt.Errorf("%s is not a directory", path)
+	}
+	if err != nil {
+		fb.Err = err
+		return
+	}
+	fb.Navigate(filepath.Clean(path))
+}
+
+// toggleBookmark adds the current directory to the bookmarks, or removes
+// it when it is already there
+func (fb *FileBrowser) toggleBookmark() tea.Cmd {
+	if i := slices.Index(fb.Bookmarks, fb.CurrentPath); i >= 0 {
+		fb.Bookmarks = slices.Delete(slices.Clone(fb.Bookmarks), i, i+1)
+	} else if len(fb.Bookmarks) < maxBookmarks {
+		fb.Bookmarks = append(slices.Clone(fb.Bookmarks), fb.CurrentPath)
+	} else {
+		fb.Err = fmt.Errorf("only %d bookmarks fit; remove one first", maxBookmarks)
+		return nil
+	}
+	bookmarks := slices.Clone(fb.Bookmarks)
+	return func() tea.Msg { return BookmarksChangedMsg{Bookmarks: bookmarks} }
+}
+
 // Navigate changes to the specified directory
 func (fb *FileBrowser) Navigate(path string) {
 	fb.CurrentPath = path
@@ -103,53 +269,93 @@ func (fb *FileBrowser) Navigate(path string) {
 	var dirs, files []FileEntry
 
 	for _, entry := range entries {
-		// Skip hidden files
-		if strings.HasPrefix(entry.Name(), ".") {
+		// Skip hidden files unless asked for
+		if !fb.ShowHidden && strings.HasPrefix(entry.Name(), ".") {
 			continue
 		}
 
-		fullPath := filepath.Join(path, entry.Name())
+		fe := FileEntry{
+			Name:  entry.Name(),
+			Path:  filepath.Join(path, entry.Name()),
+			IsDir: entry.IsDir(),
+		}
+		if info, err := entry.Info(); err == nil {
+			fe.Size, fe.ModTime = info.Size(), info.ModTime()
+		}
 
-		if entry.IsDir() {
-			dirs = append(dirs, FileEntry{
-				Name:  entry.Name(),
-				Path:  fullPath,
-				IsDir: true,
-			})
+		if fe.IsDir {
+			dirs = append(dirs, fe)
 		} else {
 			// Only show supported audio files
 			ext := strings.ToLower(filepath.Ext(entry.Name()))
-			for _, supportedExt := range fb.Extensions {
-				if ext == supportedExt {
-					files = append(files, FileEntry{
-						Name:  entry.Name(),
-						Path:  fullPath,
-						IsDir: false,
-					})
-					break
-				}
+			if slices.Contains(fb.Extensions, ext) {
+				files = append(files, fe)
 			}
 		}
 	}
 
-	// Sort directories and files alphabetically
-	sort.Slice(dirs, func(i, j int) bool {
-		return strings.ToLower(dirs[i].Name) < strings.ToLower(dirs[j].Name)
-	})
-	sort.Slice(files, func(i, j int) bool {
-		return strings.ToLower(files[i].Name) < strings.ToLower(files[j].Name)
-	})
+	fb.sortEntries(dirs)
+	fb.sortEntries(files)
 
 	// Add directories first, then files
 	fb.Entries = append(fb.Entries, dirs...)
 	fb.Entries = append(fb.Entries, files...)
 }
 
+// sortEntries orders entries by SortBy, falling back to the name
+func (fb *FileBrowser) sortEntries(entries []FileEntry) {
+	byName := func(a, b FileEntry) bool {
+		return strings.ToLower(a.Name) < strings.ToLower(b.Name)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		switch {
+		case fb.SortBy == SortByTime && !a.ModTime.Equal(b.ModTime):
+			return a.ModTime.After(b.ModTime)
+		case fb.SortBy == SortBySize && !a.IsDir && a.Size != b.Size:
+			return a.Size > b.Size
+		}
+		return byName(a, b)
+	})
+}
+
 // Update handles input messages
 func (fb FileBrowser) Update(msg tea.Msg) (FileBrowser, tea.Cmd) {
+	if fb.Typing() {
+		if msg, ok := msg.(tea.KeyMsg); ok {
+			switch msg.String() {
+			case "esc":
+				fb.PathInput.Blur()
+			case "enter":
+				fb.PathInput.Blur()
+				fb.goTo(fb.PathInput.Value)
+			default:
+				fb.PathInput, _ = fb.PathInput.Update(msg)
+			}
+		}
+		return fb, nil
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		switch msg.String() {
+		switch key := msg.String(); key {
+		case ".":
+			fb.ShowHidden = !fb.ShowHidden
+			fb.Reload()
+		case "s":
+			fb.SortBy = (fb.SortBy + 1) % 3
+			fb.Reload()
+		case ":":
+			fb.PathInput.SetValue(fb.CurrentPath + string(filepath.Separator))
+			fb.PathInput.Focus()
+		case " ":
+			fb.toggleMark()
+		case "b":
+			return fb, fb.toggleBookmark()
+		case "1", "2", "3", "4", "5", "6", "7", "8", "9":
+			if i := int(key[0] - '1'); i < len(fb.Bookmarks) {
+				fb.goTo(fb.Bookmarks[i])
+			}
 		case "up", "k":
 			if fb.Selected > 0 {
 				fb.Selected--
@@ -218,9 +424,18 @@ func (fb *FileBrowser) EnterSelected() string {
 	return entry.Path
 }
 
+// SelectedDir returns the directory to add for the selection: the selected
+// directory itself, or the one being shown when a file or ".." is selected
+func (fb *FileBrowser) SelectedDir() string {
+	if entry := fb.SelectedEntry(); entry != nil && entry.IsDir && entry.Name != ".." {
+		return entry.Path
+	}
+	return fb.CurrentPath
+}
+
 // visibleHeight returns the number of visible items
 func (fb *FileBrowser) visibleHeight() int {
-	h := fb.Height - 6 // Account for border, path, help
+	h := fb.Height - 7 // Account for border, path, status, help
 	if h < 1 {
 		return 1
 	}
@@ -241,14 +456,35 @@ func (fb *FileBrowser) ensureVisible() {
 func (fb FileBrowser) View() string {
 	var sb strings.Builder
 
-	// Current path
-	sb.WriteString(fb.PathStyle.Render("📁 " + fb.CurrentPath))
+	// Current path, or the path being typed
+	if fb.Typing() {
+		sb.WriteString(fb.PathInput.View())
+	} else {
+		sb.WriteString(fb.PathStyle.Render(glyphs.Icon(glyphs.Folder) + fb.CurrentPath))
+	}
+	sb.WriteString("\n")
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	status := i18n.T("Sort: %s", i18n.T(fb.SortBy.String()))
+	if fb.ShowHidden {
+		status += "  " + i18n.T("Hidden: shown")
+	}
+	if n := len(fb.marked); n > 0 {
+		status += "  " + i18n.T("%d marked", n)
+	}
+	if len(fb.Bookmarks) > 0 {
+		marks := make([]string, len(fb.Bookmarks))
+		for i, b := range fb.Bookmarks {
+			marks[i] = fmt.Sprintf("%d %s", i+1, filepath.Base(b))
+		}
+		status += "  " + glyphs.Star.String() + " " + strings.Join(marks, "  ")
+	}
+	sb.WriteString(dim.Render(status))
 	sb.WriteString("\n\n")
 
 	// Error display
 	if fb.Err != nil {
 		errorStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
-		sb.WriteString(errorStyle.Render("Error: " + fb.Err.Error()))
+		sb.WriteString(errorStyle.Render(i18n.T("Error: %v", fb.Err)))
 		sb.WriteString("\n")
 	}
 
@@ -263,17 +499,17 @@ func (fb FileBrowser) View() string {
 		entry := fb.Entries[i]
 
 		var line string
-		if entry.IsDir {
-			line = "📂 " + entry.Name
-		} else {
-			line = "🎵 " + entry.Name
+		switch {
+		case entry.IsDir:
+			line = glyphs.OpenFolder.String() + " " + entry.Name
+		case fb.marked[entry.Path]:
+			line = glyphs.Done.String() + " " + entry.Name
+		default:
+			line = glyphs.Icon(glyphs.Music) + entry.Name
 		}
 
 		// Truncate if too long
-		maxWidth := fb.Width - 10
-		if len(line) > maxWidth {
-			line = line[:maxWidth-3] + "..."
-		}
+		line = truncate(line, fb.Width-10)
 
 		if i == fb.Selected {
 			sb.WriteString(fb.SelectedStyle.Render(line))
@@ -299,13 +535,13 @@ func (fb FileBrowser) View() string {
 	}
 	countStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 	sb.WriteString(countStyle.Render(
-		strings.Repeat("─", 20) + "\n" +
-			"Files: " + string(rune('0'+fileCount/100%10)) + string(rune('0'+fileCount/10%10)) + string(rune('0'+fileCount%10))))
+		glyphs.Repeat(glyphs.Line, 20) + "\n" +
+			i18n.T("Files: %03d", fileCount%1000)))
 
 	// Help text
 	sb.WriteString("\n\n")
 	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-	sb.WriteString(helpStyle.Render("[Enter] Open/Add  [Backspace] Up  [~] Home  [Esc] Cancel"))
+	sb.WriteString(helpStyle.Render(i18n.T("[Enter] Open/Add  [Space] Mark  [a] Add folder  [A] Add marked / as music dir  [Backspace] Up  [~] Home  [:] Go to  [.] Hidden  [s] Sort  [b] Bookmark  [1-9] Jump  [Esc] Cancel")))
 
 	return fb.BorderStyle.Width(fb.Width - 4).Render(sb.String())
 }
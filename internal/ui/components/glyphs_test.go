@@ -0,0 +1,49 @@
+package components
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// TestASCIIGlyphs verifies components draw with ASCII glyphs when those
+// are in use, and that the text of tracks is shown as it is
+func TestASCIIGlyphs(t *testing.T) {
+	glyphs.UseASCII(true)
+	t.Cleanup(func() { glyphs.UseASCII(false) })
+
+	const title = "Étoile ★ → ♪"
+	list := NewTrackList(10, 80)
+	list.SetItems([]*api.Track{
+		{ID: "1", Title: title, Artist: "Sigur Rós"},
+		{ID: "2", Title: "Plain", Artist: "Someone"},
+	})
+	list.ActiveIndex = 0
+	list.StartMarking()
+	list.ToggleMark()
+
+	bar := NewProgressBar(40)
+	bar.SetProgress(30*time.Second, 2*time.Minute)
+	bar.Marks = []time.Duration{time.Minute}
+
+	menu := NewMenu("Actions", []MenuItem{{ID: "a", Label: "Play", Key: "p"}})
+
+	listView := list.View()
+	if !strings.Contains(listView, title) || !strings.Contains(listView, "Sigur Rós") {
+		t.Errorf("track text was changed:\n%s", listView)
+	}
+	if !strings.Contains(listView, "* > ") {
+		t.Errorf("marked, playing row has no ASCII mark:\n%s", listView)
+	}
+	rest := strings.NewReplacer(title, "", "Sigur Rós", "").Replace(listView)
+	for name, view := range map[string]string{"list": rest, "progress bar": bar.View(), "menu": menu.View()} {
+		if i := strings.IndexFunc(view, func(r rune) bool { return r >= utf8.RuneSelf }); i >= 0 {
+			r, _ := utf8.DecodeRuneInString(view[i:])
+			t.Errorf("%s drawn with %q in ASCII:\n%s", name, r, view)
+		}
+	}
+}
@@ -7,6 +7,8 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
 )
 
 // TrackList represents a scrollable list of tracks
@@ -17,20 +19,31 @@ type TrackList struct {
 	Width         int
 	Offset        int
 	Title         string
-	ShowNumbers   bool
+	Summary       string                // shown dimmed after the title, e.g. the total duration
+	Columns       []Column              // fields shown per row; nil uses DefaultColumns
+	Labels        map[*api.Track]string // optional per-track suffix (e.g. search source)
+	Highlight     string                // search text emphasized in title, artist and album cells
+	ActiveIndex   int                   // index of the playing item, marked with ▶ (-1 for none)
+	marked        []*api.Track          // marked tracks in marking order; survives SetItems
+	Marking       bool                  // marking mode: space marks, v starts a visual range
+	anchor        int                   // start of the visual range, -1 when none
 	SelectedStyle lipgloss.Style
 	NormalStyle   lipgloss.Style
+	HeaderStyle   lipgloss.Style
 	TitleStyle    lipgloss.Style
+	MatchStyle    lipgloss.Style // added to the row style for highlighted text
 }
 
 // NewTrackList creates a new track list
 func NewTrackList(height, width int) TrackList {
 	return TrackList{
-		Items:    make([]*api.Track, 0),
-		Selected: 0,
-		Height:   height,
-		Width:    width,
-		Offset:   0,
+		Items:       make([]*api.Track, 0),
+		Selected:    0,
+		Height:      height,
+		Width:       width,
+		Offset:      0,
+		ActiveIndex: -1,
+		anchor:      -1,
 		SelectedStyle: lipgloss.NewStyle().
 			Background(lipgloss.Color("62")).
 			Foreground(lipgloss.Color("230")).
@@ -38,11 +51,17 @@ func NewTrackList(height, width int) TrackList {
 			Padding(0, 1),
 		NormalStyle: lipgloss.NewStyle().
 			Padding(0, 1),
+		HeaderStyle: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("240")).
+			Padding(0, 1),
 		TitleStyle: lipgloss.NewStyle().
 			Bold(true).
 			Foreground(lipgloss.Color("212")).
 			MarginBottom(1),
-		ShowNumbers: true,
+		MatchStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("214")).
+			Bold(true),
 	}
 }
 
@@ -51,6 +70,148 @@ func (l *TrackList) SetItems(items []*api.Track) {
 	l.Items = items
 	l.Selected = 0
 	l.Offset = 0
+	l.anchor = -1
+}
+
+// Select moves the selection to index, clamped to the list bounds
+func (l *TrackList) Select(index int) {
+	if index >= len(l.Items) {
+		index = len(l.Items) - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	l.Selected = index
+	l.ensureVisible()
+}
+
+// ToggleMark marks or unmarks the selected track
+func (l *TrackList) ToggleMark() {
+	track := l.SelectedItem()
+	if track == nil {
+		return
+	}
+	for i, t := range l.marked {
+		if t.ID == track.ID {
+			l.marked = append(l.marked[:i:i], l.marked[i+1:]...)
+			return
+		}
+	}
+	l.marked = append(l.marked, track)
+}
+
+// IsMarked reports whether the track with the given ID is marked
+func (l *TrackList) IsMarked(id string) bool {
+	for _, t := range l.marked {
+		if t.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkedItems returns the marked tracks in the order they were marked
+func (l *TrackList) MarkedItems() []*api.Track {
+	out := make([]*api.Track, len(l.marked))
+	copy(out, l.marked)
+	return out
+}
+
+// Targets returns the tracks an action applies to: the marked tracks
+// followed by any unmarked ones in the open visual range, or the selected
+// track if there are none. It leaves the marks and the range as they are.
+func (l *TrackList) Targets() []*api.Track {
+	targets := l.MarkedItems()
+	if l.anchor >= 0 {
+		from, to := l.visualRange()
+		for i := from; i <= to && i < len(l.Items); i++ {
+			if !l.IsMarked(l.Items[i].ID) {
+				targets = append(targets, l.Items[i])
+			}
+		}
+	}
+	if len(targets) == 0 {
+		if track := l.SelectedItem(); track != nil {
+			targets = append(targets, track)
+		}
+	}
+	return targets
+}
+
+// ClearMarks unmarks every track
+func (l *TrackList) ClearMarks() {
+	l.marked = nil
+	l.anchor = -1
+}
+
+// mark marks a track if it is not marked already
+func (l *TrackList) mark(track *api.Track) {
+	if !l.IsMarked(track.ID) {
+		l.marked = append(l.marked, track)
+	}
+}
+
+// StartMarking enters marking mode
+func (l *TrackList) StartMarking() {
+	l.Marking = true
+}
+
+// StopMarking leaves marking mode and clears all marks
+func (l *TrackList) StopMarking() {
+	l.Marking = false
+	l.ClearMarks()
+}
+
+// InVisual reports whether a visual range is being selected
+func (l *TrackList) InVisual() bool {
+	return l.anchor >= 0
+}
+
+// ToggleVisual starts a visual range at the cursor, or marks every track
+// between the anchor and the cursor if a range is already open
+func (l *TrackList) ToggleVisual() {
+	if l.anchor < 0 {
+		if len(l.Items) > 0 {
+			l.anchor = l.Selected
+		}
+		return
+	}
+	l.CommitVisual()
+}
+
+// CommitVisual marks the open visual range, if any, and closes it
+func (l *TrackList) CommitVisual() {
+	if l.anchor < 0 {
+		return
+	}
+	from, to := l.visualRange()
+	for i := from; i <= to && i < len(l.Items); i++ {
+		l.mark(l.Items[i])
+	}
+	l.anchor = -1
+}
+
+// CancelVisual closes the visual range without marking it
+func (l *TrackList) CancelVisual() {
+	l.anchor = -1
+}
+
+// visualRange returns the open range in ascending order
+func (l *TrackList) visualRange() (int, int) {
+	if l.anchor < l.Selected {
+		return l.anchor, l.Selected
+	}
+	return l.Selected, l.anchor
+}
+
+// showsMarked reports whether row i is marked or inside the visual range
+func (l *TrackList) showsMarked(i int) bool {
+	if l.anchor >= 0 {
+		if from, to := l.visualRange(); i >= from && i <= to {
+			return true
+		}
+	}
+	return l.IsMarked(l.Items[i].ID)
 }
 
 // Update handles messages for the track list
@@ -58,6 +219,15 @@ func (l TrackList) Update(msg tea.Msg) (TrackList, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.String() {
+		case " ":
+			if l.Marking {
+				l.ToggleMark()
+				l.MoveDown()
+			}
+		case "v":
+			if l.Marking {
+				l.ToggleVisual()
+			}
 		case "up", "k":
 			l.MoveUp()
 		case "down", "j":
@@ -97,7 +267,7 @@ func (l *TrackList) MoveDown() {
 
 // PageUp moves selection up by a page
 func (l *TrackList) PageUp() {
-	l.Selected -= l.Height - 2
+	l.Selected -= l.visibleRows()
 	if l.Selected < 0 {
 		l.Selected = 0
 	}
@@ -106,19 +276,21 @@ func (l *TrackList) PageUp() {
 
 // PageDown moves selection down by a page
 func (l *TrackList) PageDown() {
-	l.Selected += l.Height - 2
+	l.Selected += l.visibleRows()
 	if l.Selected >= len(l.Items) {
 		l.Selected = len(l.Items) - 1
 	}
 	l.ensureVisible()
 }
 
+// visibleRows returns how many tracks fit below the title and header
+func (l *TrackList) visibleRows() int {
+	return max(l.Height-3, 1)
+}
+
 // ensureVisible ensures the selected item is visible
 func (l *TrackList) ensureVisible() {
-	visibleHeight := l.Height - 2 // Account for title and border
-	if visibleHeight < 1 {
-		visibleHeight = 1
-	}
+	visibleHeight := l.visibleRows()
 
 	if l.Selected < l.Offset {
 		l.Offset = l.Selected
@@ -127,6 +299,19 @@ func (l *TrackList) ensureVisible() {
 	}
 }
 
+// RowAt returns the index of the track drawn on line y of the list, or -1
+// when y is the title, the header or past the last track
+func (l *TrackList) RowAt(y int) int {
+	if l.Title != "" {
+		y -= lipgloss.Height(l.titleView())
+	}
+	y-- // column header
+	if y < 0 || y >= l.visibleRows() || l.Offset+y >= len(l.Items) {
+		return -1
+	}
+	return l.Offset + y
+}
+
 // SelectedItem returns the currently selected track
 func (l *TrackList) SelectedItem() *api.Track {
 	if l.Selected >= 0 && l.Selected < len(l.Items) {
@@ -135,53 +320,123 @@ func (l *TrackList) SelectedItem() *api.Track {
 	return nil
 }
 
+// renderRow renders line in style with the byte ranges in matches
+// highlighted. Each piece is rendered on its own so the row's background
+// carries on past a highlight.
+func (l TrackList) renderRow(style lipgloss.Style, line string, matches [][2]int) string {
+	if len(matches) == 0 {
+		return style.Render(line)
+	}
+	_, right, _, left := style.GetPadding()
+	base := style.UnsetPadding()
+	match := l.MatchStyle.Inherit(base)
+
+	var sb strings.Builder
+	sb.WriteString(base.Render(strings.Repeat(" ", left)))
+	last := 0
+	for _, m := range matches {
+		sb.WriteString(base.Render(line[last:m[0]]))
+		sb.WriteString(match.Render(line[m[0]:m[1]]))
+		last = m[1]
+	}
+	sb.WriteString(base.Render(line[last:]))
+	sb.WriteString(base.Render(strings.Repeat(" ", right)))
+	return sb.String()
+}
+
+// titleView renders the title line with the summary next to it
+func (l TrackList) titleView() string {
+	if l.Summary == "" {
+		return l.TitleStyle.Render(l.Title)
+	}
+	dim := l.HeaderStyle.UnsetBold().UnsetPadding()
+	return l.TitleStyle.Render(l.Title + dim.Render("  "+l.Summary))
+}
+
 // View renders the track list
 func (l TrackList) View() string {
 	var sb strings.Builder
 
 	// Title
 	if l.Title != "" {
-		sb.WriteString(l.TitleStyle.Render(l.Title))
+		sb.WriteString(l.titleView())
 		sb.WriteString("\n")
 	}
 
 	if len(l.Items) == 0 {
-		sb.WriteString(l.NormalStyle.Render("No tracks"))
+		sb.WriteString(l.NormalStyle.Render(i18n.T("No tracks")))
 		return sb.String()
 	}
 
 	// Calculate visible range
-	visibleHeight := l.Height - 2
-	if visibleHeight < 1 {
-		visibleHeight = 1
-	}
+	visibleHeight := l.visibleRows()
 
 	end := l.Offset + visibleHeight
 	if end > len(l.Items) {
 		end = len(l.Items)
 	}
 
+	// Lay the columns out after the gutter, which holds the ▶ of the
+	// playing track and, while marking, the ● of marked ones
+	gutter := 2
+	showMarks := len(l.marked) > 0 || l.Marking
+	if showMarks {
+		gutter += 2
+	}
+	cols := l.Columns
+	if len(cols) == 0 {
+		cols = DefaultColumns
+	}
+	cols, widths := layoutColumns(cols, l.Width-2-gutter)
+
+	cells := make([]string, len(cols))
+	for c, col := range cols {
+		spec := columnSpecs[col]
+		cells[c] = fitCell(i18n.T(spec.header), widths[c], spec.right)
+	}
+	sb.WriteString(l.HeaderStyle.Render(strings.Repeat(" ", gutter) + strings.Join(cells, " ")))
+	sb.WriteString("\n")
+
 	// Render visible items
 	for i := l.Offset; i < end; i++ {
 		track := l.Items[i]
-		var line string
 
-		if l.ShowNumbers {
-			line = fmt.Sprintf("%3d. %s - %s", i+1, truncate(track.Artist, 20), truncate(track.Title, 30))
+		var prefix string
+		if showMarks {
+			if l.showsMarked(i) {
+				prefix = glyphs.Dot.String() + " "
+			} else {
+				prefix = "  "
+			}
+		}
+		if i == l.ActiveIndex {
+			prefix += glyphs.Playing.String() + " "
 		} else {
-			line = fmt.Sprintf("%s - %s", truncate(track.Artist, 20), truncate(track.Title, 35))
+			prefix += "  "
 		}
 
-		// Truncate to width
-		if len(line) > l.Width-2 {
-			line = line[:l.Width-5] + "..."
+		var matches [][2]int // byte ranges of line to highlight
+		start := len(prefix)
+		for c, col := range cols {
+			text := columnText(col, track, i)
+			if label := l.Labels[track]; col == ColTitle && label != "" {
+				text += " [" + label + "]"
+			}
+			cells[c] = fitCell(text, widths[c], columnSpecs[col].right)
+			if l.Highlight != "" && (col == ColTitle || col == ColArtist || col == ColAlbum) {
+				if at := indexFold(cells[c], l.Highlight); at >= 0 {
+					matches = append(matches, [2]int{start + at, start + at + len(l.Highlight)})
+				}
+			}
+			start += len(cells[c]) + 1
 		}
+		line := prefix + strings.Join(cells, " ")
 
+		style := l.NormalStyle
 		if i == l.Selected {
-			sb.WriteString(l.SelectedStyle.Render(line))
-		} else {
-			sb.WriteString(l.NormalStyle.Render(line))
+			style = l.SelectedStyle
 		}
+		sb.WriteString(l.renderRow(style, line, matches))
 
 		if i < end-1 {
 			sb.WriteString("\n")
@@ -196,11 +451,3 @@ func (l TrackList) View() string {
 
 	return sb.String()
 }
-
-// truncate truncates a string to the specified length
-func truncate(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
-	}
-	return s[:maxLen-3] + "..."
-}
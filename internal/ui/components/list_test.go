@@ -0,0 +1,146 @@
+package components
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestLabelsPerListedTrack verifies a label belongs to the listed track,
+// not to every track with its ID, as two sources may share IDs
+func TestLabelsPerListedTrack(t *testing.T) {
+	local := &api.Track{ID: "42", Title: "Local copy"}
+	remote := &api.Track{ID: "42", Title: "Remote copy"}
+	list := NewTrackList(10, 100)
+	list.SetItems([]*api.Track{local, remote})
+	list.Labels = map[*api.Track]string{remote: "navidrome"}
+
+	for _, line := range strings.Split(list.View(), "\n") {
+		labeled := strings.Contains(line, "[navidrome]")
+		if strings.Contains(line, "Local copy") && labeled {
+			t.Errorf("local track got the remote label: %q", line)
+		}
+		if strings.Contains(line, "Remote copy") && !labeled {
+			t.Errorf("remote track lost its label: %q", line)
+		}
+	}
+}
+
+// tracks returns n tracks with IDs "0" to "n-1"
+func tracks(n int) []*api.Track {
+	out := make([]*api.Track, n)
+	for i := range out {
+		out[i] = &api.Track{ID: strconv.Itoa(i), Title: "Track " + strconv.Itoa(i)}
+	}
+	return out
+}
+
+// ids returns the IDs of tracks
+func ids(tracks []*api.Track) []string {
+	out := make([]string, len(tracks))
+	for i, t := range tracks {
+		out[i] = t.ID
+	}
+	return out
+}
+
+// TestMarking verifies marks toggle, keep their order and are cleared on
+// leaving marking mode
+func TestMarking(t *testing.T) {
+	list := NewTrackList(10, 100)
+	list.SetItems(tracks(4))
+	list.StartMarking()
+	if !list.Marking {
+		t.Fatal("StartMarking should enter marking mode")
+	}
+	for _, i := range []int{2, 0, 3} {
+		list.Select(i)
+		list.ToggleMark()
+	}
+	list.Select(0)
+	list.ToggleMark()
+	if got := ids(list.MarkedItems()); !slices.Equal(got, []string{"2", "3"}) {
+		t.Errorf("marked = %v, want [2 3]", got)
+	}
+	if !list.IsMarked("3") || list.IsMarked("0") {
+		t.Error("IsMarked disagrees with the marks")
+	}
+
+	list.StopMarking()
+	if list.Marking || len(list.MarkedItems()) != 0 {
+		t.Error("StopMarking should leave marking mode and clear the marks")
+	}
+}
+
+// TestVisualRange verifies a range marks every track between the anchor
+// and the cursor in either direction, once, and that cancelling it marks
+// nothing
+func TestVisualRange(t *testing.T) {
+	list := NewTrackList(10, 100)
+	list.SetItems(tracks(6))
+	list.StartMarking()
+	list.Select(1)
+	list.ToggleMark()
+
+	list.Select(3)
+	list.ToggleVisual()
+	if !list.InVisual() {
+		t.Fatal("ToggleVisual should open a range")
+	}
+	list.Select(0)
+	list.ToggleVisual()
+	if list.InVisual() {
+		t.Error("a second ToggleVisual should close the range")
+	}
+	if got := ids(list.MarkedItems()); !slices.Equal(got, []string{"1", "0", "2", "3"}) {
+		t.Errorf("marked = %v, want [1 0 2 3]", got)
+	}
+
+	list.Select(5)
+	list.ToggleVisual()
+	list.Select(4)
+	list.CancelVisual()
+	list.CommitVisual()
+	if list.InVisual() || list.IsMarked("4") || list.IsMarked("5") {
+		t.Error("a cancelled range should mark nothing")
+	}
+
+	empty := NewTrackList(10, 100)
+	empty.ToggleVisual()
+	if empty.InVisual() {
+		t.Error("an empty list should not open a range")
+	}
+}
+
+// TestTargets verifies Targets takes the marks and the open range, or the
+// selected track, without changing either
+func TestTargets(t *testing.T) {
+	list := NewTrackList(10, 100)
+	list.SetItems(tracks(5))
+	list.Select(2)
+	if got := ids(list.Targets()); !slices.Equal(got, []string{"2"}) {
+		t.Errorf("targets without marks = %v, want the selected track", got)
+	}
+
+	list.StartMarking()
+	list.Select(4)
+	list.ToggleMark()
+	list.ToggleVisual()
+	list.Select(3)
+	for range 2 {
+		if got := ids(list.Targets()); !slices.Equal(got, []string{"4", "3"}) {
+			t.Errorf("targets = %v, want [4 3]", got)
+		}
+	}
+	if !list.InVisual() || list.IsMarked("3") {
+		t.Error("Targets should leave the range open and unmarked")
+	}
+
+	empty := NewTrackList(10, 100)
+	if got := ids(empty.Targets()); len(got) != 0 {
+		t.Errorf("targets of an empty list = %v", got)
+	}
+}
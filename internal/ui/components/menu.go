@@ -0,0 +1,125 @@
+package components
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// MenuItem is one entry of a Menu. Key is the shortcut shown next to the
+// label, which also picks the entry while the menu is open; Disabled
+// entries are shown dimmed and cannot be chosen.
+type MenuItem struct {
+	ID       string
+	Label    string
+	Key      string
+	Disabled bool
+}
+
+// Menu is a small popup list of actions, such as the context menu of a track
+type Menu struct {
+	Title       string
+	Items       []MenuItem
+	Selected    int
+	BorderStyle lipgloss.Style
+	TitleStyle  lipgloss.Style
+}
+
+// MenuResult is the outcome of a menu interaction. ID is the chosen
+// entry's when Done is true and Cancelled is false.
+type MenuResult struct {
+	Done      bool
+	Cancelled bool
+	ID        string
+}
+
+// NewMenu creates a menu with the first enabled entry selected
+func NewMenu(title string, items []MenuItem) Menu {
+	m := Menu{
+		Title: title,
+		Items: items,
+		BorderStyle: lipgloss.NewStyle().
+			Border(glyphs.RoundedBorder()).
+			BorderForeground(lipgloss.Color("212")).
+			Padding(0, 1),
+		TitleStyle: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("212")),
+	}
+	m.Selected = m.step(-1, 1)
+	return m
+}
+
+// step returns the next enabled entry from index from in direction dir,
+// or from itself when there is none
+func (m Menu) step(from, dir int) int {
+	for i := from + dir; i >= 0 && i < len(m.Items); i += dir {
+		if !m.Items[i].Disabled {
+			return i
+		}
+	}
+	return max(from, 0)
+}
+
+// Update handles key input and reports whether an entry was chosen
+func (m Menu) Update(msg tea.Msg) (Menu, MenuResult) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, MenuResult{}
+	}
+
+	switch key.String() {
+	case "esc", "q":
+		return m, MenuResult{Done: true, Cancelled: true}
+	case "up", "k":
+		m.Selected = m.step(m.Selected, -1)
+	case "down", "j":
+		m.Selected = m.step(m.Selected, 1)
+	case "enter":
+		if m.Selected < len(m.Items) && !m.Items[m.Selected].Disabled {
+			return m, MenuResult{Done: true, ID: m.Items[m.Selected].ID}
+		}
+	default:
+		for _, item := range m.Items {
+			if item.Key == key.String() && !item.Disabled {
+				return m, MenuResult{Done: true, ID: item.ID}
+			}
+		}
+	}
+	return m, MenuResult{}
+}
+
+// menuMaxTitle caps how much of a long title widens the menu
+const menuMaxTitle = 40
+
+// View renders the menu as a box just wide enough for its entries
+func (m Menu) View() string {
+	width := min(textWidth(m.Title), menuMaxTitle)
+	for _, item := range m.Items {
+		width = max(width, textWidth(item.Label)+4)
+	}
+
+	selectedStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230")).
+		Bold(true)
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	var sb strings.Builder
+	sb.WriteString(m.TitleStyle.Render(truncate(m.Title, width)))
+	for i, item := range m.Items {
+		line := fitCell(item.Label, width-4, false) + " " + fitCell(item.Key, 3, true)
+		sb.WriteString("\n")
+		switch {
+		case item.Disabled:
+			sb.WriteString(dimStyle.Render(line))
+		case i == m.Selected:
+			sb.WriteString(selectedStyle.Render(line))
+		default:
+			sb.WriteString(line)
+		}
+	}
+	return m.BorderStyle.Render(sb.String())
+}
@@ -0,0 +1,165 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// newPlaylistLabel is the synthetic last entry that creates a playlist
+const newPlaylistLabel = "+ Create new…"
+
+// PlaylistPicker is an overlay for choosing (or creating) a target playlist
+type PlaylistPicker struct {
+	Playlists   []*api.Playlist
+	Selected    int
+	Naming      bool // true while typing a name for a new playlist
+	NoCreate    bool // true to offer only the listed entries
+	NameInput   SearchInput
+	Title       string
+	Width       int
+	BorderStyle lipgloss.Style
+	TitleStyle  lipgloss.Style
+}
+
+// PickerResult is the outcome of a picker interaction. Exactly one of
+// PlaylistID or NewName is set when Done is true and Cancelled is false.
+type PickerResult struct {
+	Done       bool
+	Cancelled  bool
+	PlaylistID string
+	NewName    string
+}
+
+// NewPlaylistPicker creates a picker over the given playlists
+func NewPlaylistPicker(playlists []*api.Playlist, width int) PlaylistPicker {
+	input := NewSearchInput(width - 8)
+	input.Prompt = i18n.T("Name: ")
+	input.Placeholder = i18n.T("New playlist name")
+
+	return PlaylistPicker{
+		Playlists: playlists,
+		NameInput: input,
+		Title:     i18n.T("Add to playlist"),
+		Width:     width,
+		BorderStyle: lipgloss.NewStyle().
+			Border(glyphs.RoundedBorder()).
+			BorderForeground(lipgloss.Color("212")).
+			Padding(0, 2),
+		TitleStyle: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("212")),
+	}
+}
+
+// Update handles key input and reports whether a choice was made
+func (p PlaylistPicker) Update(msg tea.Msg) (PlaylistPicker, PickerResult) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, PickerResult{}
+	}
+
+	if p.Naming {
+		switch key.String() {
+		case "esc":
+			p.Naming = false
+			p.NameInput.Blur()
+			p.NameInput.Clear()
+		case "enter":
+			name := strings.TrimSpace(p.NameInput.Value)
+			if name != "" {
+				return p, PickerResult{Done: true, NewName: name}
+			}
+		default:
+			p.NameInput, _ = p.NameInput.Update(msg)
+		}
+		return p, PickerResult{}
+	}
+
+	switch key.String() {
+	case "esc", "q":
+		return p, PickerResult{Done: true, Cancelled: true}
+	case "up", "k":
+		if p.Selected > 0 {
+			p.Selected--
+		}
+	case "down", "j":
+		// The extra slot after the last playlist is "create new"
+		if p.Selected < p.lastIndex() {
+			p.Selected++
+		}
+	case "enter":
+		if p.Selected < len(p.Playlists) {
+			return p, PickerResult{Done: true, PlaylistID: p.Playlists[p.Selected].ID}
+		}
+		if p.NoCreate {
+			return p, PickerResult{}
+		}
+		p.Naming = true
+		p.NameInput.Focus()
+	}
+	return p, PickerResult{}
+}
+
+// lastIndex is the last selectable entry
+func (p PlaylistPicker) lastIndex() int {
+	if p.NoCreate {
+		return max(len(p.Playlists)-1, 0)
+	}
+	return len(p.Playlists)
+}
+
+// View renders the picker
+func (p PlaylistPicker) View() string {
+	var sb strings.Builder
+
+	sb.WriteString(p.TitleStyle.Render(p.Title))
+	sb.WriteString("\n\n")
+
+	selectedStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230")).
+		Bold(true).
+		Padding(0, 1)
+	normalStyle := lipgloss.NewStyle().Padding(0, 1)
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	for i, pl := range p.Playlists {
+		line := fmt.Sprintf("%s %s", pl.Name, dimStyle.Render(i18n.T("(%d tracks)", len(pl.Tracks))))
+		if i == p.Selected {
+			sb.WriteString(selectedStyle.Render(line))
+		} else {
+			sb.WriteString(normalStyle.Render(line))
+		}
+		sb.WriteString("\n")
+	}
+	if p.NoCreate {
+		if len(p.Playlists) == 0 {
+			sb.WriteString(dimStyle.Render(i18n.T("Nothing to choose from")))
+			sb.WriteString("\n")
+		}
+	} else if p.Selected == len(p.Playlists) {
+		sb.WriteString(selectedStyle.Render(glyphs.Text(i18n.T(newPlaylistLabel))))
+		sb.WriteString("\n")
+	} else {
+		sb.WriteString(normalStyle.Render(glyphs.Text(i18n.T(newPlaylistLabel))))
+		sb.WriteString("\n")
+	}
+
+	if p.Naming {
+		sb.WriteString("\n")
+		sb.WriteString(p.NameInput.View())
+		sb.WriteString("\n")
+		sb.WriteString(dimStyle.Render(i18n.T("[Enter] Create  [Esc] Back")))
+	} else {
+		sb.WriteString("\n")
+		sb.WriteString(dimStyle.Render(glyphs.Text(i18n.T("[Enter] Choose  [↑↓] Navigate  [Esc] Cancel"))))
+	}
+
+	return p.BorderStyle.Width(p.Width - 4).Render(sb.String())
+}
@@ -7,6 +7,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
 )
 
 // ProgressBar represents a progress bar component
@@ -22,6 +23,16 @@ type ProgressBar struct {
 	EmptyStyle  lipgloss.Style
 	HeadStyle   lipgloss.Style
 
+	// Preview is a second cursor for scrubbing; it is drawn only while
+	// Previewing, and the time display then shows it instead of Current
+	Preview      time.Duration
+	Previewing   bool
+	PreviewStyle lipgloss.Style
+
+	// Marks are positions, such as bookmarks, drawn as ticks on the bar
+	Marks     []time.Duration
+	MarkStyle lipgloss.Style
+
 	// Layout info for click-to-seek (set during View)
 	barWidth  int
 	timeWidth int
@@ -30,14 +41,16 @@ type ProgressBar struct {
 // NewProgressBar creates a new progress bar
 func NewProgressBar(width int) ProgressBar {
 	return ProgressBar{
-		Width:       width,
-		BarChar:     "━",
-		EmptyChar:   "─",
-		ShowTime:    true,
-		Style:       lipgloss.NewStyle(),
-		FilledStyle: lipgloss.NewStyle().Foreground(lipgloss.Color("212")),
-		EmptyStyle:  lipgloss.NewStyle().Foreground(lipgloss.Color("240")),
-		HeadStyle:   lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Bold(true),
+		Width:        width,
+		BarChar:      glyphs.HeavyLine.String(),
+		EmptyChar:    glyphs.Line.String(),
+		ShowTime:     true,
+		Style:        lipgloss.NewStyle(),
+		FilledStyle:  lipgloss.NewStyle().Foreground(lipgloss.Color("212")),
+		EmptyStyle:   lipgloss.NewStyle().Foreground(lipgloss.Color("240")),
+		HeadStyle:    lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Bold(true),
+		PreviewStyle: lipgloss.NewStyle().Foreground(lipgloss.Color("228")).Bold(true),
+		MarkStyle:    lipgloss.NewStyle().Foreground(lipgloss.Color("214")),
 	}
 }
 
@@ -52,6 +65,26 @@ func (p *ProgressBar) SetProgress(current, total time.Duration) {
 	p.Total = total
 }
 
+// SetPreview shows the scrub cursor at pos, clamped to the track
+func (p *ProgressBar) SetPreview(pos time.Duration) {
+	p.Preview = min(max(pos, 0), p.Total)
+	p.Previewing = true
+}
+
+// ClearPreview hides the scrub cursor
+func (p *ProgressBar) ClearPreview() {
+	p.Previewing = false
+}
+
+// cell returns the bar cell for a position, given the bar width
+func (p ProgressBar) cell(pos time.Duration) int {
+	if p.Total <= 0 {
+		return 0
+	}
+	c := int(float64(p.barWidth) * float64(pos) / float64(p.Total))
+	return min(max(c, 0), p.barWidth-1)
+}
+
 // BarWidth returns the computed bar width (available after View is called)
 func (p ProgressBar) BarWidth() int {
 	return p.barWidth
@@ -79,15 +112,6 @@ func (p ProgressBar) HandleClick(clickX, barOffsetX int) time.Duration {
 func (p *ProgressBar) View() string {
 	var sb strings.Builder
 
-	// Calculate progress percentage
-	var percent float64
-	if p.Total > 0 {
-		percent = float64(p.Current) / float64(p.Total)
-	}
-	if percent > 1 {
-		percent = 1
-	}
-
 	// Calculate bar segments
 	// Time display takes "MM:SS/MM:SS " = 12 chars + 2 spaces = 14
 	p.timeWidth = 14
@@ -96,27 +120,43 @@ func (p *ProgressBar) View() string {
 		p.barWidth = 10
 	}
 
-	headPos := int(float64(p.barWidth) * percent)
-	if headPos >= p.barWidth {
-		headPos = p.barWidth - 1
+	headPos := p.cell(p.Current)
+	previewPos := -1
+	if p.Previewing {
+		previewPos = p.cell(p.Preview)
 	}
 
-	filled := headPos
-	empty := p.barWidth - headPos - 1
-
-	// Build progress bar with seek head
-	filledBar := p.FilledStyle.Render(strings.Repeat(p.BarChar, filled))
-	head := p.HeadStyle.Render("●")
-	emptyBar := p.EmptyStyle.Render(strings.Repeat(p.EmptyChar, empty))
+	marks := make(map[int]bool, len(p.Marks))
+	if p.Total > 0 {
+		for _, m := range p.Marks {
+			marks[p.cell(m)] = true
+		}
+	}
 
-	sb.WriteString(filledBar)
-	sb.WriteString(head)
-	sb.WriteString(emptyBar)
+	// Build progress bar with seek head, the scrub cursor, if any, and ticks
+	for i := 0; i < p.barWidth; i++ {
+		switch {
+		case i == previewPos:
+			sb.WriteString(p.PreviewStyle.Render(glyphs.Diamond.String()))
+		case i == headPos:
+			sb.WriteString(p.HeadStyle.Render(glyphs.Dot.String()))
+		case marks[i]:
+			sb.WriteString(p.MarkStyle.Render(glyphs.HeavyBar.String()))
+		case i < headPos:
+			sb.WriteString(p.FilledStyle.Render(p.BarChar))
+		default:
+			sb.WriteString(p.EmptyStyle.Render(p.EmptyChar))
+		}
+	}
 
 	// Add time display
 	if p.ShowTime {
 		sb.WriteString(" ")
-		sb.WriteString(formatDuration(p.Current))
+		if p.Previewing {
+			sb.WriteString(p.PreviewStyle.Render(formatDuration(p.Preview)))
+		} else {
+			sb.WriteString(formatDuration(p.Current))
+		}
 		sb.WriteString("/")
 		sb.WriteString(formatDuration(p.Total))
 	}
@@ -1,8 +1,14 @@
 package components
 
 import (
+	"slices"
+	"unicode/utf8"
+
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+	"github.com/mattn/go-runewidth"
 )
 
 // SearchInput represents a search input component
@@ -20,15 +26,15 @@ type SearchInput struct {
 // NewSearchInput creates a new search input
 func NewSearchInput(width int) SearchInput {
 	return SearchInput{
-		Placeholder: "Search...",
+		Placeholder: i18n.T("Search..."),
 		Width:       width,
-		Prompt:      "🔍 ",
+		Prompt:      glyphs.Icon(glyphs.Search),
 		Style: lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
+			Border(glyphs.RoundedBorder()).
 			BorderForeground(lipgloss.Color("240")).
 			Padding(0, 1),
 		FocusStyle: lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
+			Border(glyphs.RoundedBorder()).
 			BorderForeground(lipgloss.Color("212")).
 			Padding(0, 1),
 	}
@@ -47,7 +53,7 @@ func (s *SearchInput) Blur() {
 // SetValue sets the input value
 func (s *SearchInput) SetValue(value string) {
 	s.Value = value
-	s.CursorPos = len(value)
+	s.CursorPos = utf8.RuneCountInString(value)
 }
 
 // Clear clears the input
@@ -56,44 +62,48 @@ func (s *SearchInput) Clear() {
 	s.CursorPos = 0
 }
 
-// Update handles messages for the search input
+// Update handles messages for the search input. CursorPos counts runes, so
+// editing never splits a multi-byte character.
 func (s SearchInput) Update(msg tea.Msg) (SearchInput, tea.Cmd) {
 	if !s.Focused {
 		return s, nil
 	}
 
+	value := []rune(s.Value)
+	s.CursorPos = min(max(s.CursorPos, 0), len(value))
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch msg.Type {
 		case tea.KeyBackspace:
-			if len(s.Value) > 0 && s.CursorPos > 0 {
-				s.Value = s.Value[:s.CursorPos-1] + s.Value[s.CursorPos:]
+			if s.CursorPos > 0 {
+				value = append(value[:s.CursorPos-1], value[s.CursorPos:]...)
 				s.CursorPos--
 			}
 		case tea.KeyDelete:
-			if s.CursorPos < len(s.Value) {
-				s.Value = s.Value[:s.CursorPos] + s.Value[s.CursorPos+1:]
+			if s.CursorPos < len(value) {
+				value = append(value[:s.CursorPos], value[s.CursorPos+1:]...)
 			}
 		case tea.KeyLeft:
 			if s.CursorPos > 0 {
 				s.CursorPos--
 			}
 		case tea.KeyRight:
-			if s.CursorPos < len(s.Value) {
+			if s.CursorPos < len(value) {
 				s.CursorPos++
 			}
 		case tea.KeyHome:
 			s.CursorPos = 0
 		case tea.KeyEnd:
-			s.CursorPos = len(s.Value)
+			s.CursorPos = len(value)
 		case tea.KeyRunes:
-			// Insert character at cursor position
-			char := string(msg.Runes)
-			s.Value = s.Value[:s.CursorPos] + char + s.Value[s.CursorPos:]
-			s.CursorPos += len(char)
+			// Insert characters at cursor position
+			value = append(value[:s.CursorPos], append(slices.Clone(msg.Runes), value[s.CursorPos:]...)...)
+			s.CursorPos += len(msg.Runes)
 		}
 	}
 
+	s.Value = string(value)
 	return s, nil
 }
 
@@ -101,24 +111,24 @@ func (s SearchInput) Update(msg tea.Msg) (SearchInput, tea.Cmd) {
 func (s SearchInput) View() string {
 	var content string
 
+	// Cells left for the value inside the border and padding
+	avail := s.Width - 4 - textWidth(s.Prompt)
+
 	if s.Value == "" && !s.Focused {
-		content = s.Prompt + lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(s.Placeholder)
-	} else {
-		// Show value with cursor
-		if s.Focused {
-			before := s.Value[:s.CursorPos]
-			after := s.Value[s.CursorPos:]
-			cursor := lipgloss.NewStyle().Background(lipgloss.Color("212")).Render(" ")
-			content = s.Prompt + before + cursor + after
-		} else {
-			content = s.Prompt + s.Value
+		content = s.Prompt + lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(truncate(s.Placeholder, avail))
+	} else if s.Focused {
+		// Show value with cursor, scrolled so the cursor stays in view
+		value := []rune(s.Value)
+		pos := min(max(s.CursorPos, 0), len(value))
+		before, after := value[:pos], string(value[pos:])
+		for len(before) > 0 && textWidth(string(before))+1 > avail {
+			before = before[1:]
 		}
-	}
-
-	// Truncate if too long
-	maxWidth := s.Width - 4
-	if len(content) > maxWidth {
-		content = content[:maxWidth]
+		rest := max(avail-textWidth(string(before))-1, 0)
+		cursor := lipgloss.NewStyle().Background(lipgloss.Color("212")).Render(" ")
+		content = s.Prompt + string(before) + cursor + runewidth.Truncate(after, rest, "")
+	} else {
+		content = s.Prompt + truncate(s.Value, avail)
 	}
 
 	if s.Focused {
@@ -0,0 +1,36 @@
+package components
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// textWidth returns the number of terminal cells s takes up. CJK and most
+// emoji take two cells, combining marks none.
+func textWidth(s string) int {
+	return runewidth.StringWidth(s)
+}
+
+// truncate shortens s to fit in width cells, ending it with "..." when it
+// had to be cut. It never splits a rune or a wide character.
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if width < 4 {
+		return runewidth.Truncate(s, width, "")
+	}
+	return runewidth.Truncate(s, width, "...")
+}
+
+// indexFold returns the byte offset in s of the lower-case text sub,
+// ignoring the case of s, or -1. Strings whose length changes when
+// lower-cased are not searched.
+func indexFold(s, sub string) int {
+	lower := strings.ToLower(s)
+	if len(lower) != len(s) {
+		return -1
+	}
+	return strings.Index(lower, sub)
+}
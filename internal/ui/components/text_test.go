@@ -0,0 +1,52 @@
+package components
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TestTruncate verifies truncation counts terminal cells, not bytes, and
+// never cuts a character in half
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{"Björk", 10, "Björk"},
+		{"Sigur Rós - Ágætis byrjun", 12, "Sigur Rós..."},
+		{"坂本龍一 - 戦場のメリークリスマス", 12, "坂本龍一 ..."},
+		{"Beyoncé", 3, "Bey"},
+	}
+	for _, tt := range tests {
+		got := truncate(tt.in, tt.width)
+		if got != tt.want {
+			t.Errorf("truncate(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+		if w := textWidth(got); w > tt.width {
+			t.Errorf("truncate(%q, %d) is %d cells wide", tt.in, tt.width, w)
+		}
+	}
+}
+
+// TestSearchInput_Runes verifies editing moves over whole characters
+func TestSearchInput_Runes(t *testing.T) {
+	s := NewSearchInput(40)
+	s.Focus()
+	s.SetValue("東京é")
+
+	key := func(k tea.KeyType) {
+		s, _ = s.Update(tea.KeyMsg{Type: k})
+	}
+	key(tea.KeyLeft)
+	key(tea.KeyBackspace)
+	if s.Value != "東é" || s.CursorPos != 1 {
+		t.Fatalf("after backspace: %q at %d, want %q at 1", s.Value, s.CursorPos, "東é")
+	}
+	s, _ = s.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("京都")})
+	key(tea.KeyDelete)
+	if s.Value != "東京都" || s.CursorPos != 3 {
+		t.Errorf("after insert and delete: %q at %d, want %q at 3", s.Value, s.CursorPos, "東京都")
+	}
+}
@@ -0,0 +1,54 @@
+package ui
+
+import (
+	"runtime/debug"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jscyril/golang_music_player/internal/crash"
+)
+
+// crashGuard wraps the model so a panic in it, or in a command it
+// returns, leaves a crash report rather than Bubble Tea's stack trace
+type crashGuard struct {
+	Model
+}
+
+func (g crashGuard) Init() tea.Cmd {
+	defer guard()
+	return guardCmd(g.Model.Init())
+}
+
+func (g crashGuard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	defer guard()
+	m, cmd := g.Model.Update(msg)
+	return crashGuard{m.(Model)}, guardCmd(cmd)
+}
+
+func (g crashGuard) View() string {
+	defer guard()
+	return g.Model.View()
+}
+
+// guard reports a panic of the caller, deferred
+func guard() {
+	if r := recover(); r != nil {
+		crash.Exit(r, debug.Stack())
+	}
+}
+
+// guardCmd runs cmd, and the commands of a batch it returns, under guard
+func guardCmd(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		defer guard()
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for i := range batch {
+				batch[i] = guardCmd(batch[i])
+			}
+		}
+		return msg
+	}
+}
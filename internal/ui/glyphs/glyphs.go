@@ -0,0 +1,264 @@
+// Package glyphs holds the symbols the TUI draws with. There are two sets:
+// emoji, symbols and box drawing, and ASCII stand-ins for screen readers
+// and terminals without the fonts. The set is chosen once at startup, like
+// the message catalog, and views look their glyphs up as they render, so
+// track titles and other text of the user's are never rewritten.
+package glyphs
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Glyph names a symbol of the UI
+type Glyph int
+
+const (
+	Playing Glyph = iota
+	Paused
+	Stopped
+	Buffering
+	Volume
+	Muted
+	RepeatOne
+	RepeatAll
+	Shuffle
+	Consume
+	Party
+
+	Note
+	Music
+	Queue
+	Playlist
+	Stats
+	Folder
+	OpenFolder
+	Search
+	Archive
+	Genre
+	History
+	Lookup
+	Scan
+	Edit
+	Share
+	Info
+	Warning
+
+	Done
+	Failed
+	Off
+	Skipped
+	SavePoint
+	Star
+	NoStar
+
+	Dot
+	Ring
+	Diamond
+	Pointer
+	Block
+	LevelOn
+	LevelOff
+	HeavyLine
+	Line
+	HeavyBar
+
+	Up
+	Down
+	Left
+	Right
+	PlusMinus
+	Separator
+	Dash
+	LongDash
+	Ellipsis
+
+	count
+)
+
+// symbols is the full set. Some symbols are drawn narrower than the cell
+// pair most terminals give them; a title icon among them carries a second
+// space in Icon.
+var symbols = [count]string{
+	Playing:   "▶",
+	Paused:    "⏸",
+	Stopped:   "⏹",
+	Buffering: "⏳",
+	Volume:    "🔊",
+	Muted:     "🔇",
+	RepeatOne: "🔂",
+	RepeatAll: "🔁",
+	Shuffle:   "🔀",
+	Consume:   "✂",
+	Party:     "🎉",
+
+	Note:       "♪",
+	Music:      "🎵",
+	Queue:      "🎶",
+	Playlist:   "📋",
+	Stats:      "📊",
+	Folder:     "📁",
+	OpenFolder: "📂",
+	Search:     "🔍",
+	Archive:    "🗄",
+	Genre:      "🏷",
+	History:    "🕘",
+	Lookup:     "🔎",
+	Scan:       "⟳",
+	Edit:       "✎",
+	Share:      "🔗",
+	Info:       "ℹ",
+	Warning:    "⚠",
+
+	Done:      "✓",
+	Failed:    "✗",
+	Off:       "⊘",
+	Skipped:   "⏭",
+	SavePoint: "⚑",
+	Star:      "★",
+	NoStar:    "☆",
+
+	Dot:       "●",
+	Ring:      "○",
+	Diamond:   "◆",
+	Pointer:   "▸",
+	Block:     "█",
+	LevelOn:   "▮",
+	LevelOff:  "▯",
+	HeavyLine: "━",
+	Line:      "─",
+	HeavyBar:  "┃",
+
+	Up:        "↑",
+	Down:      "↓",
+	Left:      "←",
+	Right:     "→",
+	PlusMinus: "±",
+	Separator: "·",
+	Dash:      "–",
+	LongDash:  "—",
+	Ellipsis:  "…",
+}
+
+// plain is the ASCII set. Glyphs that only decorate have none; those that
+// carry meaning get a stand-in.
+var plain = [count]string{
+	Playing:   ">",
+	Paused:    "||",
+	Stopped:   "[]",
+	Buffering: "...",
+
+	OpenFolder: "[dir]",
+	Warning:    "!",
+
+	Done:      "+",
+	Failed:    "x",
+	Off:       "x",
+	Skipped:   "-",
+	SavePoint: "!",
+	Star:      "*",
+	NoStar:    ".",
+
+	Dot:       "*",
+	Ring:      "o",
+	Diamond:   "#",
+	Pointer:   ">",
+	Block:     "#",
+	LevelOn:   "#",
+	LevelOff:  ".",
+	HeavyLine: "=",
+	Line:      "-",
+	HeavyBar:  "|",
+
+	Up:        "^",
+	Down:      "v",
+	Left:      "<-",
+	Right:     "->",
+	PlusMinus: "+/-",
+	Separator: "-",
+	Dash:      "-",
+	LongDash:  "-",
+	Ellipsis:  "...",
+}
+
+// narrow are the icons drawn in one cell, which Icon pads with a second
+// space
+var narrow = map[Glyph]bool{Archive: true, Genre: true, Info: true, Skipped: true}
+
+// inText are the glyphs the UI's own text is written with: arrows in key
+// help, punctuation in translations
+var inText = []Glyph{Up, Down, Left, Right, PlusMinus, Separator, Dash, LongDash, Ellipsis}
+
+var (
+	ascii     atomic.Bool
+	plainText = func() *strings.Replacer {
+		var pairs []string
+		for _, g := range inText {
+			pairs = append(pairs, symbols[g], plain[g])
+		}
+		return strings.NewReplacer(pairs...)
+	}()
+)
+
+// UseASCII selects the ASCII set, or the full one
+func UseASCII(on bool) {
+	ascii.Store(on)
+}
+
+// ASCII reports whether the ASCII set is in use
+func ASCII() bool {
+	return ascii.Load()
+}
+
+// String returns the glyph in the set in use; empty for a decorative glyph
+// in ASCII
+func (g Glyph) String() string {
+	if ascii.Load() {
+		return plain[g]
+	}
+	return symbols[g]
+}
+
+// Icon returns g followed by a space, to put in front of a title or label.
+// Icons only decorate: in ASCII there are none.
+func Icon(g Glyph) string {
+	if ascii.Load() {
+		return ""
+	}
+	if narrow[g] {
+		return symbols[g] + "  "
+	}
+	return symbols[g] + " "
+}
+
+// Repeat returns g count times, for bars and meters
+func Repeat(g Glyph, count int) string {
+	return strings.Repeat(g.String(), max(count, 0))
+}
+
+// Text puts the arrows and punctuation of text the UI writes itself, such
+// as key help and translated messages, into the set in use
+func Text(s string) string {
+	if !ascii.Load() {
+		return s
+	}
+	return plainText.Replace(s)
+}
+
+// RoundedBorder returns lipgloss' rounded border, or its ASCII one
+func RoundedBorder() lipgloss.Border {
+	if ascii.Load() {
+		return lipgloss.ASCIIBorder()
+	}
+	return lipgloss.RoundedBorder()
+}
+
+// NormalBorder returns lipgloss' normal border, or its ASCII one
+func NormalBorder() lipgloss.Border {
+	if ascii.Load() {
+		return lipgloss.ASCIIBorder()
+	}
+	return lipgloss.NormalBorder()
+}
@@ -0,0 +1,95 @@
+package glyphs
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// useASCII switches to the ASCII set for the rest of the test
+func useASCII(t *testing.T) {
+	t.Helper()
+	UseASCII(true)
+	t.Cleanup(func() { UseASCII(false) })
+}
+
+// isASCII reports whether s has no characters beyond ASCII
+func isASCII(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return r >= utf8.RuneSelf }) < 0
+}
+
+// TestSets verifies every glyph has a symbol and that the ASCII set is
+// ASCII
+func TestSets(t *testing.T) {
+	for g := Glyph(0); g < count; g++ {
+		if symbols[g] == "" {
+			t.Errorf("glyph %d has no symbol", g)
+		}
+		if !isASCII(plain[g]) {
+			t.Errorf("glyph %d is %q in the ASCII set", g, plain[g])
+		}
+	}
+}
+
+// TestString verifies glyphs come from the set in use when they are
+// looked up, not when they were named
+func TestString(t *testing.T) {
+	g := Playing
+	if got := g.String(); got != "▶" {
+		t.Errorf("Playing = %q, want ▶", got)
+	}
+	useASCII(t)
+	if got := g.String(); got != ">" {
+		t.Errorf("Playing in ASCII = %q, want >", got)
+	}
+	if got := Repeat(Star, 3) + Repeat(NoStar, 2); got != "***.." {
+		t.Errorf("rating in ASCII = %q, want ***..", got)
+	}
+	if got := Repeat(Block, -1); got != "" {
+		t.Errorf("Repeat(Block, -1) = %q, want empty", got)
+	}
+}
+
+// TestIcon verifies icons are padded, narrow ones by two spaces, and
+// left out in ASCII
+func TestIcon(t *testing.T) {
+	if got := Icon(Music); got != "🎵 " {
+		t.Errorf("Icon(Music) = %q", got)
+	}
+	if got := Icon(Archive); got != "🗄  " {
+		t.Errorf("Icon(Archive) = %q", got)
+	}
+	useASCII(t)
+	for g := Glyph(0); g < count; g++ {
+		if got := Icon(g); got != "" {
+			t.Errorf("Icon(%d) in ASCII = %q, want none", g, got)
+		}
+	}
+}
+
+// TestText verifies the arrows and punctuation of UI text are swapped in
+// ASCII and left alone otherwise
+func TestText(t *testing.T) {
+	const help = "[↑↓] Navigate  [←/→] Seek ±5s  3 tracks · 2 albums  Searching…"
+	if got := Text(help); got != help {
+		t.Errorf("Text changed %q to %q with the full set", help, got)
+	}
+	useASCII(t)
+	want := "[^v] Navigate  [<-/->] Seek +/-5s  3 tracks - 2 albums  Searching..."
+	if got := Text(help); got != want {
+		t.Errorf("Text(%q) = %q, want %q", help, got, want)
+	}
+}
+
+// TestBorders verifies the borders switch to lipgloss' ASCII one
+func TestBorders(t *testing.T) {
+	if RoundedBorder().TopLeft != "╭" || NormalBorder().TopLeft != "┌" {
+		t.Error("full set borders are not box drawing")
+	}
+	useASCII(t)
+	for _, b := range []string{RoundedBorder().TopLeft, RoundedBorder().Top, NormalBorder().Left} {
+		if !isASCII(b) {
+			t.Errorf("ASCII border drawn with %q", b)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+// Package keymap resolves configured key bindings to UI actions
+package keymap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jscyril/golang_music_player/internal/config"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// Scope is where a binding applies. Bindings in a view scope take
+// precedence over global ones while that view is active.
+type Scope int
+
+const (
+	Global Scope = iota
+	Player
+	Library
+	Playlist
+	Queue
+	History
+	Stats
+)
+
+// String returns the scope's display name
+func (s Scope) String() string {
+	switch s {
+	case Player:
+		return "Player"
+	case Library:
+		return "Library"
+	case Playlist:
+		return "Playlists"
+	case Queue:
+		return "Queue"
+	case History:
+		return "History"
+	case Stats:
+		return "Stats"
+	}
+	return "Global"
+}
+
+// Action names a bindable command, e.g. "play_pause" or "library.mark"
+type Action string
+
+const (
+	Quit           Action = "quit"
+	Help           Action = "help"
+	Log            Action = "log"
+	Schedules      Action = "schedules"
+	ViewPlayer     Action = "view_player"
+	ViewLibrary    Action = "view_library"
+	ViewPlaylist   Action = "view_playlist"
+	ViewQueue      Action = "view_queue"
+	ViewHistory    Action = "view_history"
+	ViewStats      Action = "view_stats"
+	NextView       Action = "next_view"
+	SplitPane      Action = "split_pane"
+	FocusPane      Action = "focus_pane"
+	SplitGrow      Action = "split_grow"
+	SplitShrink    Action = "split_shrink"
+	PlayPause      Action = "play_pause"
+	Stop           Action = "stop"
+	Next           Action = "next"
+	Previous       Action = "previous"
+	SeekForward    Action = "seek_forward"
+	SeekBack       Action = "seek_back"
+	ChapterNext    Action = "chapter_next"
+	ChapterPrev    Action = "chapter_prev"
+	BookmarkAdd    Action = "bookmark_add"
+	BookmarkList   Action = "bookmark_list"
+	BookmarkNext   Action = "bookmark_next"
+	BookmarkPrev   Action = "bookmark_prev"
+	VolumeUp       Action = "volume_up"
+	VolumeDown     Action = "volume_down"
+	VolumeUpFine   Action = "volume_up_fine"
+	VolumeDownFine Action = "volume_down_fine"
+	Mute           Action = "mute"
+	Output         Action = "output"
+	Cast           Action = "cast"
+	Repeat         Action = "repeat"
+	Shuffle        Action = "shuffle"
+	Consume        Action = "consume"
+	Party          Action = "party"
+	SavePoint      Action = "save_point"
+	ReturnToSave   Action = "return_to_save"
+)
+
+// Binding is one action with its keys. The first default key is the one
+// the owning view handles; other keys are translated to it.
+type Binding struct {
+	Action   Action
+	Scope    Scope
+	Help     string
+	Defaults []string
+	Keys     []string
+}
+
+// defaultBindings lists every bindable action in help order
+func defaultBindings() []Binding {
+	b := func(a Action, scope Scope, help string, keys ...string) Binding {
+		return Binding{Action: a, Scope: scope, Help: help, Defaults: keys}
+	}
+	return []Binding{
+		b(PlayPause, Global, "Play / pause", " "),
+		b(Stop, Global, "Stop", "s"),
+		b(Next, Global, "Next track", "n"),
+		b(Previous, Global, "Previous track (player view)", "p"),
+		b(SeekForward, Global, "Seek forward 5s", "right"),
+		b(SeekBack, Global, "Seek back 5s", "left"),
+		b(ChapterNext, Global, "Next chapter", "]"),
+		b(ChapterPrev, Global, "Previous chapter", "["),
+		b(BookmarkAdd, Global, "Bookmark the current position", "ctrl+b"),
+		b(BookmarkList, Global, "List the current track's bookmarks", "'"),
+		b(BookmarkNext, Global, "Next bookmark", "}"),
+		b(BookmarkPrev, Global, "Previous bookmark", "{"),
+		b(VolumeUp, Global, "Volume up 10%", "="),
+		b(VolumeDown, Global, "Volume down 10%", "-"),
+		b(VolumeUpFine, Global, "Volume up 1%", "+"),
+		b(VolumeDownFine, Global, "Volume down 1%", "_"),
+		b(Mute, Global, "Mute / unmute", "m"),
+		b(Output, Global, "Cycle audio output", "o"),
+		b(Cast, Global, "Cast to a Chromecast or DLNA renderer", "ctrl+o"),
+		b(Repeat, Global, "Cycle repeat mode", "r"),
+		b(Shuffle, Global, "Toggle shuffle", "S"),
+		b(Consume, Global, "Toggle consume (remove played tracks)", "C"),
+		b(Party, Global, "Toggle party mode (append instead of replace)", "Y"),
+		b(SavePoint, Global, "Set a queue save point", "b"),
+		b(ReturnToSave, Global, "Return to the save point", "B"),
+		b(ViewPlayer, Global, "Player view", "1"),
+		b(ViewLibrary, Global, "Library view", "2", "l"),
+		b(ViewPlaylist, Global, "Playlist view", "3", "P"),
+		b(ViewQueue, Global, "Queue view", "4"),
+		b(ViewHistory, Global, "History view", "5"),
+		b(ViewStats, Global, "Listening statistics view", "6"),
+		b(NextView, Global, "Next view", "tab"),
+		b(SplitPane, Global, "Show library and queue side by side", "|"),
+		b(FocusPane, Global, "Switch pane (split layout)", "ctrl+w"),
+		b(SplitGrow, Global, "Widen the left pane", ">"),
+		b(SplitShrink, Global, "Narrow the left pane", "<"),
+		b(Help, Global, "Show key bindings", "?"),
+		b(Log, Global, "Show the log", "L"),
+		b(Schedules, Global, "Alarms and quiet hours", "ctrl+t"),
+		b(Quit, Global, "Quit", "q"),
+
+		b("player.chapters", Player, "Show / hide chapters", "c"),
+		b("player.scrub_back", Player, "Scrub preview back (Enter seeks, Esc cancels)", ","),
+		b("player.scrub_forward", Player, "Scrub preview forward", "."),
+
+		b("library.search", Library, "Search", "/"),
+		b("library.menu", Library, "Track menu", "."),
+		b("library.info", Library, "Track details", "i"),
+		b("library.add_files", Library, "Add files", "a"),
+		b("library.mark", Library, "Mark track", "m"),
+		b("library.visual", Library, "Mark a range", "v"),
+		b("library.enqueue", Library, "Enqueue marked or selected", "e"),
+		b("library.add_to_playlist", Library, "Add to playlist", "P"),
+		b("library.remove", Library, "Remove marked from library", "D"),
+		b("library.delete_files", Library, "Delete files of marked or selected", "ctrl+d"),
+		b("library.genres", Library, "Browse genres", "g"),
+		b("library.skipped", Library, "Frequently skipped tracks", "F"),
+		b("library.stats", Library, "Library totals and top genres", "T"),
+		b("library.archive", Library, "Archive marked or selected", "A"),
+		b("library.archived", Library, "Archived tracks", "Z"),
+		b("library.edit_tags", Library, "Edit tags of marked or selected", "t"),
+		b("library.user_tags", Library, "Label marked or selected with your own tags", "#"),
+		b("library.lookup_metadata", Library, "Suggest tags from MusicBrainz", "M"),
+		b("library.rescan", Library, "Rescan music directories", "R"),
+		b("library.scan_errors", Library, "Errors of the last scan", "E"),
+		b("library.play_album", Library, "Play the selected track's album", "L"),
+		b("library.play_artist", Library, "Play everything by the selected artist", "I"),
+		b("library.shuffle_all", Library, "Shuffle the whole library", "X"),
+
+		b("playlist.create", Playlist, "Create playlist", "c"),
+		b("playlist.rename", Playlist, "Rename playlist", "r"),
+		b("playlist.describe", Playlist, "Edit description", "e"),
+		b("playlist.delete", Playlist, "Delete playlist", "d"),
+		b("playlist.import", Playlist, "Import playlist file", "i"),
+		b("playlist.export", Playlist, "Export playlist file", "x"),
+		b("playlist.stats", Playlist, "Playlist stats", "I"),
+		b("playlist.overlaps", Playlist, "Tracks in several playlists", "O"),
+		b("playlist.dedupe", Playlist, "Remove repeated tracks", "u"),
+
+		b("queue.remove", Queue, "Remove entry", "d", "delete"),
+		b("queue.save", Queue, "Save the queue under a name", "w"),
+		b("queue.load", Queue, "Load a saved queue", "O"),
+
+		b("history.search", History, "Search", "/"),
+
+		b("stats.period", Stats, "Top artists of the week or the month", "w"),
+	}
+}
+
+// Map resolves keys to actions per scope
+type Map struct {
+	bindings []Binding
+	byKey    map[Scope]map[string]Action
+	canon    map[Scope]map[string]bool // default keys views handle themselves
+}
+
+// Default returns the built-in bindings
+func Default() *Map {
+	m, _ := New(nil)
+	return m
+}
+
+// New builds a map from the defaults with overrides applied. overrides maps
+// action names to replacement keys; "space" may be used for the space bar.
+// An unknown action, two actions sharing a key in one scope, or a view
+// action taking a key bound globally is an error.
+func New(overrides map[string][]string) (*Map, error) {
+	m := &Map{bindings: defaultBindings()}
+	index := make(map[Action]int, len(m.bindings))
+	for i := range m.bindings {
+		index[m.bindings[i].Action] = i
+		m.bindings[i].Keys = m.bindings[i].Defaults
+	}
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		i, ok := index[Action(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown action %q", name)
+		}
+		var keys []string
+		for _, k := range overrides[name] {
+			if k = normalize(k); k != "" {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("action %q has no keys", name)
+		}
+		m.bindings[i].Keys = keys
+	}
+
+	if err := m.build(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// FromConfig builds a map from the config's key bindings. Each named
+// KeyMap field replaces the default key it stands for, so other keys of the
+// same action (such as "=" for volume up or "2" for the library) are kept;
+// Bindings entries replace all keys of an action.
+func FromConfig(km config.KeyMap) (*Map, error) {
+	overrides := make(map[string][]string)
+	replace := func(a Action, old, key string) {
+		if key == "" || normalize(key) == old {
+			return
+		}
+		for _, b := range defaultBindings() {
+			if b.Action != a {
+				continue
+			}
+			keys := make([]string, len(b.Defaults))
+			for i, d := range b.Defaults {
+				keys[i] = d
+				if d == old {
+					keys[i] = key
+				}
+			}
+			overrides[string(a)] = keys
+		}
+	}
+	replace(PlayPause, " ", km.PlayPause)
+	replace(Stop, "s", km.Stop)
+	replace(Next, "n", km.Next)
+	replace(Previous, "p", km.Previous)
+	// "+" was the volume-up default before it became the fine step
+	if km.VolumeUp == "+" {
+		km.VolumeUp = "="
+	}
+	replace(VolumeUp, "=", km.VolumeUp)
+	replace(VolumeDown, "-", km.VolumeDown)
+	replace(SeekForward, "right", km.SeekForward)
+	replace(SeekBack, "left", km.SeekBack)
+	replace(Quit, "q", km.Quit)
+	replace("library.search", "/", km.Search)
+	replace(ViewLibrary, "l", km.Library)
+	replace(ViewPlaylist, "P", km.Playlist)
+	for name, keys := range km.Bindings {
+		overrides[name] = keys
+	}
+	return New(overrides)
+}
+
+// build indexes the bindings and checks for conflicts
+func (m *Map) build() error {
+	m.byKey = make(map[Scope]map[string]Action)
+	m.canon = make(map[Scope]map[string]bool)
+	for _, b := range m.bindings {
+		if m.byKey[b.Scope] == nil {
+			m.byKey[b.Scope] = make(map[string]Action)
+			m.canon[b.Scope] = make(map[string]bool)
+		}
+		for _, k := range b.Defaults {
+			m.canon[b.Scope][k] = true
+		}
+		for _, k := range b.Keys {
+			if k == "ctrl+c" {
+				return fmt.Errorf("%s: ctrl+c is reserved for quitting", b.Action)
+			}
+			if other, ok := m.byKey[b.Scope][k]; ok && other != b.Action {
+				return fmt.Errorf("key %q is bound to both %s and %s", display(k), other, b.Action)
+			}
+			m.byKey[b.Scope][k] = b.Action
+		}
+	}
+	return m.checkShadowing()
+}
+
+// checkShadowing rejects a view binding that hides a global one, since the
+// global action would silently stop working in that view. The few default
+// keys views take over on purpose, such as "m" marking in the library
+// rather than muting, are allowed as long as neither side is rebound.
+func (m *Map) checkShadowing() error {
+	defaults := make(map[Action]map[string]bool, len(m.bindings))
+	for _, b := range m.bindings {
+		defaults[b.Action] = make(map[string]bool, len(b.Defaults))
+		for _, k := range b.Defaults {
+			defaults[b.Action][k] = true
+		}
+	}
+	for _, b := range m.bindings {
+		if b.Scope == Global {
+			continue
+		}
+		for _, k := range b.Keys {
+			global, ok := m.byKey[Global][k]
+			if !ok || defaults[b.Action][k] && defaults[global][k] {
+				continue
+			}
+			return fmt.Errorf("key %q is bound to %s, which the %s view hides with %s",
+				display(k), global, b.Scope, b.Action)
+		}
+	}
+	return nil
+}
+
+// Resolve returns the action bound to key in scope, falling back to the
+// global bindings, or "" if the key is unbound
+func (m *Map) Resolve(scope Scope, key string) Action {
+	if a, ok := m.byKey[scope][key]; ok {
+		return a
+	}
+	return m.byKey[Global][key]
+}
+
+// Global returns the global action bound to key, or ""
+func (m *Map) Global(key string) Action {
+	return m.byKey[Global][key]
+}
+
+// ScopeOf returns the scope an action belongs to
+func (m *Map) ScopeOf(a Action) Scope {
+	for _, b := range m.bindings {
+		if b.Action == a {
+			return b.Scope
+		}
+	}
+	return Global
+}
+
+// ViewKey returns the key message a view handles for one of its actions,
+// i.e. its first default key, whatever the action is bound to
+func (m *Map) ViewKey(a Action) tea.KeyMsg {
+	for _, b := range m.bindings {
+		if b.Action == a {
+			return keyMsg(b.Defaults[0])
+		}
+	}
+	return tea.KeyMsg{}
+}
+
+// Shadowed reports whether key is one of a view's built-in keys whose
+// action has been rebound to something else, so the view must not see it
+func (m *Map) Shadowed(scope Scope, key string) bool {
+	if _, bound := m.byKey[scope][key]; bound {
+		return false
+	}
+	return m.canon[scope][key]
+}
+
+// Bindings returns the bindings of a scope in help order
+func (m *Map) Bindings(scope Scope) []Binding {
+	var out []Binding
+	for _, b := range m.bindings {
+		if b.Scope == scope {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// KeysFor returns the keys of an action formatted for display, e.g. "+ / ="
+func (m *Map) KeysFor(a Action) string {
+	for _, b := range m.bindings {
+		if b.Action == a {
+			names := make([]string, len(b.Keys))
+			for i, k := range b.Keys {
+				names[i] = display(k)
+			}
+			return strings.Join(names, " / ")
+		}
+	}
+	return ""
+}
+
+// normalize maps config spellings onto bubbletea key strings
+func normalize(k string) string {
+	switch strings.ToLower(k) {
+	case "space":
+		return " "
+	case "esc", "escape":
+		return "esc"
+	}
+	return k
+}
+
+// display renders a key string for help text
+func display(k string) string {
+	switch k {
+	case " ":
+		return "space"
+	case "right":
+		return glyphs.Right.String()
+	case "left":
+		return glyphs.Left.String()
+	}
+	return k
+}
+
+// keyMsg builds the key message bubbletea would send for a key string
+func keyMsg(k string) tea.KeyMsg {
+	switch k {
+	case " ":
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	case "delete":
+		return tea.KeyMsg{Type: tea.KeyDelete}
+	case "tab":
+		return tea.KeyMsg{Type: tea.KeyTab}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
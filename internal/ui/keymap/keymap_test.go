@@ -0,0 +1,65 @@
+package keymap
+
+import (
+	"testing"
+
+	"github.com/jscyril/golang_music_player/internal/config"
+)
+
+// TestFromConfig verifies overrides resolve, shadow the built-in key and conflicts are rejected
+func TestFromConfig(t *testing.T) {
+	km := config.GetDefaultConfig().KeyBindings
+	km.PlayPause = "space"
+	km.Bindings = map[string][]string{"library.mark": {"x"}}
+	m, err := FromConfig(km)
+	if err != nil {
+		t.Fatalf("FromConfig: %v", err)
+	}
+	if a := m.Resolve(Library, "x"); a != "library.mark" {
+		t.Errorf("Resolve(x) = %q, want library.mark", a)
+	}
+	if got := m.ViewKey("library.mark").String(); got != "m" {
+		t.Errorf("ViewKey = %q, want m", got)
+	}
+	if !m.Shadowed(Library, "m") {
+		t.Error("m should be shadowed once library.mark is rebound")
+	}
+	if a := m.Resolve(Library, " "); a != PlayPause {
+		t.Errorf("Resolve(space) = %q, want %s", a, PlayPause)
+	}
+
+	km.Stop = "n"
+	if _, err := FromConfig(km); err == nil {
+		t.Error("expected a conflict between stop and next")
+	}
+	if _, err := New(map[string][]string{"no_such_action": {"z"}}); err == nil {
+		t.Error("expected an unknown action error")
+	}
+}
+
+// TestShadowingGlobal verifies a view binding may not hide a global one,
+// except for the views' own default keys
+func TestShadowingGlobal(t *testing.T) {
+	if _, err := New(nil); err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+	tests := []struct {
+		name      string
+		overrides map[string][]string
+	}{
+		{"view takes a global key", map[string][]string{"library.mark": {"n"}}},
+		{"global takes a view key", map[string][]string{string(Stop): {"m"}}},
+	}
+	for _, tt := range tests {
+		if _, err := New(tt.overrides); err == nil {
+			t.Errorf("%s: expected a shadowing error", tt.name)
+		}
+	}
+	m, err := New(map[string][]string{string(Mute): {"alt+m"}})
+	if err != nil {
+		t.Fatalf("moving mute off m: %v", err)
+	}
+	if a := m.Resolve(Library, "m"); a != "library.mark" {
+		t.Errorf("Resolve(Library, m) = %q, want library.mark", a)
+	}
+}
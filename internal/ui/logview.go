@@ -0,0 +1,133 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/logger"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// logViewer shows the latest log entries, for finding out why a file did
+// not play or scan
+type logViewer struct {
+	open     bool
+	entries  []logger.Entry // shown, oldest first
+	offset   int            // entries scrolled back from the newest
+	problems bool           // warnings and errors only
+	seen     time.Time      // when the viewer was last closed
+	unseen   int            // warnings and errors logged since
+}
+
+// refresh counts the problems not seen yet and reloads the entries,
+// unless they are scrolled back and would move under the reader
+func (v *logViewer) refresh() {
+	all := logger.Recent()
+	v.unseen = 0
+	for _, e := range all {
+		if e.Level >= logger.WARN && e.Time.After(v.seen) {
+			v.unseen++
+		}
+	}
+	if !v.open || v.offset > 0 {
+		return
+	}
+	v.entries = v.entries[:0]
+	for _, e := range all {
+		if e.Level >= logger.WARN || !v.problems {
+			v.entries = append(v.entries, e)
+		}
+	}
+}
+
+// openLog shows the log viewer at the newest entries
+func (m *Model) openLog() {
+	m.log.open, m.log.offset = true, 0
+	m.log.refresh()
+}
+
+// updateLog handles a key while the log viewer is open
+func (m *Model) updateLog(msg tea.KeyMsg) {
+	v := m.log
+	page := max(m.contentHeight()-3, 1)
+	switch msg.String() {
+	case "up", "k":
+		v.offset++
+	case "down", "j":
+		v.offset--
+	case "pgup", "ctrl+u":
+		v.offset += page
+	case "pgdown", "ctrl+d":
+		v.offset -= page
+	case "g", "home":
+		v.offset = len(v.entries)
+	case "G", "end":
+		v.offset = 0
+	case "w":
+		v.problems = !v.problems
+		v.offset = 0
+		v.refresh()
+	case "esc", "q", "L":
+		v.open = false
+		v.seen = time.Now()
+		v.unseen = 0
+		return
+	}
+	v.offset = min(max(v.offset, 0), max(len(v.entries)-1, 0))
+}
+
+// renderLog renders the log viewer, newest entries at the bottom
+func (m Model) renderLog() string {
+	v := m.log
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	levelStyles := map[logger.Level]lipgloss.Style{
+		logger.DEBUG: dim,
+		logger.INFO:  lipgloss.NewStyle().Foreground(lipgloss.Color("39")),
+		logger.WARN:  lipgloss.NewStyle().Foreground(lipgloss.Color("214")),
+		logger.ERROR: lipgloss.NewStyle().Foreground(lipgloss.Color("196")),
+		logger.FATAL: lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true),
+	}
+
+	var sb strings.Builder
+	title := i18n.T("Log")
+	if v.problems {
+		title += " " + i18n.T("(warnings and errors)")
+	}
+	sb.WriteString(m.headerStyle.Render(title))
+	if path := logger.GetLogPath(); path != "" {
+		sb.WriteString(dim.Render("  " + path))
+	}
+	sb.WriteString("\n")
+
+	rows := max(m.contentHeight()-3, 1)
+	end := len(v.entries) - v.offset
+	start := max(end-rows, 0)
+	if len(v.entries) == 0 {
+		sb.WriteString(dim.Render(i18n.T("Nothing logged yet")) + "\n")
+		rows--
+	}
+	for _, e := range v.entries[start:end] {
+		msg, _, multi := strings.Cut(e.Message, "\n")
+		if multi {
+			msg += " " + glyphs.Ellipsis.String()
+		}
+		line := fmt.Sprintf("%s %s %s %s",
+			e.Time.Format("15:04:05"),
+			levelStyles[e.Level].Render(fmt.Sprintf("%-5s", e.Level)),
+			dim.Render(e.Source),
+			msg)
+		if m.width > 0 {
+			line = lipgloss.NewStyle().MaxWidth(m.width).Render(line)
+		}
+		sb.WriteString(line + "\n")
+	}
+	for i := end - start; i < rows; i++ {
+		sb.WriteString("\n")
+	}
+	sb.WriteString(dim.Render(glyphs.Text(i18n.T("↑/↓ scroll  g/G oldest/newest  w warnings only  Esc close"))))
+	return sb.String()
+}
@@ -0,0 +1,207 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/logger"
+	"github.com/jscyril/golang_music_player/internal/schedule"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// schedulesState holds the alarms and quiet hours, the overlay listing
+// them and the volume ramp of an alarm going off
+type schedulesState struct {
+	sched *schedule.Scheduler // nil without any
+	save  func(i int, disabled bool) error
+
+	open     bool
+	selected int
+
+	ramp schedule.Ramp
+}
+
+// checkSchedule sets off the alarms due and stops playback when quiet
+// hours begin; called on every tick
+func (m *Model) checkSchedule(now time.Time) {
+	m.stepRamp(now)
+	s := m.schedules.sched
+	if s == nil {
+		return
+	}
+	due, quiet := s.Update(now)
+	if quiet && m.audioEngine.GetState().Status != api.StatusStopped {
+		logger.Info("Quiet hours began, stopping playback")
+		m.schedules.ramp = schedule.Ramp{}
+		m.audioEngine.Stop()
+	}
+	// Alarms due at once would only replace each other's queue
+	if len(due) > 0 {
+		m.startAlarm(due[0], now)
+	}
+}
+
+// startAlarm replaces the queue with the alarm's playlist, even in party
+// mode, and plays it, fading in if the alarm has a ramp
+func (m *Model) startAlarm(a schedule.Alarm, now time.Time) {
+	var pl *api.Playlist
+	for _, p := range m.playlistManager.GetAll() {
+		if p.ID == a.Playlist || strings.EqualFold(p.Name, a.Playlist) {
+			pl = p
+			break
+		}
+	}
+	if pl == nil || len(pl.Tracks) == 0 {
+		m.err = fmt.Errorf("alarm %s: no playlist %q or it is empty", a.Label(), a.Playlist)
+		logger.Warn("%v", m.err)
+		return
+	}
+	tracks := make([]*api.Track, len(pl.Tracks))
+	for i := range pl.Tracks {
+		tracks[i] = &pl.Tracks[i]
+	}
+	if a.Shuffle {
+		m.queue.SetShuffled(tracks)
+	} else {
+		m.queue.Set(tracks)
+	}
+
+	volume := a.Volume
+	if volume == 0 {
+		volume = m.audioEngine.GetState().Volume
+	}
+	m.schedules.ramp = schedule.Ramp{}
+	if a.Ramp > 0 {
+		m.schedules.ramp = schedule.Ramp{To: volume, Start: now, Length: a.Ramp}
+		volume = 0
+	}
+	m.audioEngine.SetVolume(volume)
+	logger.Info("Alarm %s: playing %q", a.Label(), pl.Name)
+	m.afterQueueSet()
+}
+
+// stepRamp moves the volume along a running ramp
+func (m *Model) stepRamp(now time.Time) {
+	r := &m.schedules.ramp
+	if !r.Running() {
+		return
+	}
+	volume, done := r.Volume(now)
+	m.audioEngine.SetVolume(volume)
+	if done {
+		*r = schedule.Ramp{}
+	}
+}
+
+// openSchedules shows the alarms and quiet hours
+func (m *Model) openSchedules() {
+	m.schedules.open = true
+}
+
+// updateSchedules handles a key while the schedules overlay is open
+func (m *Model) updateSchedules(msg tea.KeyMsg) {
+	v := m.schedules
+	var alarms []schedule.Alarm
+	if v.sched != nil {
+		alarms = v.sched.Alarms
+	}
+	switch msg.String() {
+	case "up", "k":
+		v.selected--
+	case "down", "j":
+		v.selected++
+	case " ":
+		if v.selected < len(alarms) {
+			a := &alarms[v.selected]
+			a.Disabled = !a.Disabled
+			if v.save != nil {
+				if err := v.save(v.selected, a.Disabled); err != nil {
+					m.err = fmt.Errorf("save alarm: %w", err)
+				}
+			}
+		}
+	case "enter":
+		if v.selected < len(alarms) {
+			v.open = false
+			m.startAlarm(alarms[v.selected], time.Now())
+			return
+		}
+	case "esc", "q", "ctrl+t":
+		v.open = false
+		return
+	}
+	v.selected = min(max(v.selected, 0), max(len(alarms)-1, 0))
+}
+
+// renderSchedules lists the alarms with when they next go off, then the
+// quiet hours
+func (m Model) renderSchedules() string {
+	v := m.schedules
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	selected := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	now := time.Now()
+
+	var alarms []schedule.Alarm
+	var quiet []schedule.Quiet
+	if v.sched != nil {
+		alarms, quiet = v.sched.Alarms, v.sched.Quiet
+	}
+
+	var sb strings.Builder
+	sb.WriteString(m.headerStyle.Render(i18n.T("Alarms")))
+	sb.WriteString("\n")
+	if len(alarms) == 0 {
+		sb.WriteString(dim.Render(i18n.T("No alarms; add them under schedule.alarms in the config")) + "\n")
+	}
+	for i, a := range alarms {
+		mark, next := glyphs.Done.String(), i18n.T("next %s", a.Next(now).Format("Mon Jan 2 15:04"))
+		if a.Disabled {
+			mark, next = glyphs.Off.String(), i18n.T("off")
+		}
+		line := fmt.Sprintf("%s %s %-10s %-16.16s %-20.20s %s", mark, a.At, a.Days, a.Name, a.Playlist, next)
+		var extra []string
+		if a.Shuffle {
+			extra = append(extra, i18n.T("shuffled"))
+		}
+		if a.Ramp > 0 {
+			extra = append(extra, i18n.T("fades in over %ds", int(a.Ramp.Seconds())))
+		}
+		if a.Volume > 0 {
+			extra = append(extra, i18n.T("volume %d%%", int(a.Volume*100+0.5)))
+		}
+		if len(extra) > 0 {
+			line += dim.Render(", " + strings.Join(extra, ", "))
+		}
+		if i == v.selected {
+			line = selected.Render("> ") + line
+		} else {
+			line = "  " + line
+		}
+		if m.width > 0 {
+			line = lipgloss.NewStyle().MaxWidth(m.width).Render(line)
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(m.headerStyle.Render(i18n.T("Quiet hours")))
+	sb.WriteString("\n")
+	if len(quiet) == 0 {
+		sb.WriteString(dim.Render(i18n.T("None; add them under schedule.quiet_hours in the config")) + "\n")
+	}
+	for _, q := range quiet {
+		line := "  " + q.String()
+		if q.Active(now) {
+			line += selected.Render("  " + i18n.T("(now)"))
+		}
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(dim.Render(glyphs.Text(i18n.T("↑/↓ select  Space on/off  Enter try now  Esc close"))))
+	return sb.String()
+}
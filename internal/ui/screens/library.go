@@ -13,6 +13,7 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"github.com/jscyril/golang_music_player/internal/ui/styles"
 	"github.com/jscyril/golang_music_player/pkg/apiclient"
+	"github.com/mattn/go-runewidth"
 )
 
 // PlayTrackMsg is sent when the user selects a track to play.
@@ -280,13 +281,10 @@ func libraryColWidths(w int) [3]int {
 	return [3]int{t, t, t}
 }
 
-// truncate shortens a string to max length with ellipsis.
+// truncate shortens a string to max terminal cells with ellipsis.
 func truncate(s string, max int) string {
-	if len(s) <= max {
-		return s
-	}
 	if max < 4 {
-		return s[:max]
+		return runewidth.Truncate(s, max, "")
 	}
-	return s[:max-3] + "..."
+	return runewidth.Truncate(s, max, "...")
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/jscyril/golang_music_player/internal/audio"
 	"github.com/jscyril/golang_music_player/internal/ui/styles"
 	"github.com/jscyril/golang_music_player/pkg/apiclient"
+	"github.com/jscyril/golang_music_player/pkg/stats"
 )
 
 // BackToLibraryMsg is sent when the user exits the player.
@@ -29,18 +30,22 @@ type PlayerTickMsg time.Time
 type PlayerScreen struct {
 	engine    *audio.AudioEngine
 	client    *apiclient.APIClient
+	stats     *stats.Stats
 	track     *apiclient.Track
 	allTracks []apiclient.Track
 	queueIdx  int
 	width     int
 	height    int
+	started   bool // the track has been seen playing
 }
 
 // NewPlayerScreen creates a new player screen for the given track.
-func NewPlayerScreen(engine *audio.AudioEngine, client *apiclient.APIClient, track apiclient.Track, all []apiclient.Track, idx int, width, height int) PlayerScreen {
+// Pause, resume and seek actions are recorded in st's current session.
+func NewPlayerScreen(engine *audio.AudioEngine, client *apiclient.APIClient, st *stats.Stats, track apiclient.Track, all []apiclient.Track, idx int, width, height int) PlayerScreen {
 	return PlayerScreen{
 		engine:    engine,
 		client:    client,
+		stats:     st,
 		track:     &track,
 		allTracks: all,
 		queueIdx:  idx,
@@ -69,6 +74,17 @@ func (s PlayerScreen) Update(msg tea.Msg) (PlayerScreen, tea.Cmd) {
 		s.height = msg.Height
 
 	case PlayerTickMsg:
+		// Close the session once the stream stops or runs out, so the
+		// listening time does not keep counting
+		switch s.engine.GetState().Status {
+		case 1: // StatusPlaying
+			s.started = true
+		case 0: // StatusStopped
+			if s.started {
+				s.stats.Ended()
+				s.started = false
+			}
+		}
 		return s, playerTickCmd()
 
 	case tea.KeyMsg:
@@ -78,8 +94,10 @@ func (s PlayerScreen) Update(msg tea.Msg) (PlayerScreen, tea.Cmd) {
 			switch state.Status {
 			case 1: // StatusPlaying
 				s.engine.Pause()
+				s.stats.Paused()
 			case 2: // StatusPaused
 				s.engine.Resume()
+				s.stats.Resumed()
 			}
 		case "n":
 			return s, func() tea.Msg { return NextTrackMsg{} }
@@ -102,6 +120,7 @@ func (s PlayerScreen) Update(msg tea.Msg) (PlayerScreen, tea.Cmd) {
 		case "right":
 			state := s.engine.GetState()
 			s.engine.Seek(state.Position + 5*time.Second)
+			s.stats.Seeked(state.Position, state.Position+5*time.Second)
 		case "left":
 			state := s.engine.GetState()
 			newPos := state.Position - 5*time.Second
@@ -109,6 +128,7 @@ func (s PlayerScreen) Update(msg tea.Msg) (PlayerScreen, tea.Cmd) {
 				newPos = 0
 			}
 			s.engine.Seek(newPos)
+			s.stats.Seeked(state.Position, newPos)
 		case "q", "esc":
 			return s, func() tea.Msg { return BackToLibraryMsg{} }
 		}
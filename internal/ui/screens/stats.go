@@ -66,6 +66,8 @@ func (s StatsScreen) View() string {
 		{"Songs Played", fmt.Sprintf("%d", sum.TracksPlayed)},
 		{"Songs Liked", fmt.Sprintf("♥  %d", sum.TracksLiked)},
 		{"Listen Time", sum.FormattedTime},
+		{"Time Heard", sum.FormattedListened},
+		{"Songs Skipped", fmt.Sprintf("%d", sum.TracksSkipped)},
 		{"Top Artist", emptyOr(sum.TopArtist, "—")},
 		{"Avg Duration", sum.FormattedMean},
 		{"Std Deviation", sum.FormattedStdDev},
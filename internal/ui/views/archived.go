@@ -0,0 +1,119 @@
+package views
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// ShowArchivedMsg asks the app for the archived tracks
+type ShowArchivedMsg struct{}
+
+// ArchiveMsg asks the app to archive tracks, or to restore them
+type ArchiveMsg struct {
+	TrackIDs []string
+	Archived bool
+}
+
+// ArchivedList is an overlay of archived tracks that can be restored
+type ArchivedList struct {
+	Items    []*api.Track
+	Selected int
+	Offset   int
+	Height   int
+	Width    int
+}
+
+// NewArchivedList creates the overlay
+func NewArchivedList(items []*api.Track, width, height int) ArchivedList {
+	return ArchivedList{Items: items, Width: width, Height: height}
+}
+
+// Update handles keys. done is true when the overlay should close.
+func (l ArchivedList) Update(msg tea.KeyMsg) (ArchivedList, tea.Cmd, bool) {
+	switch msg.String() {
+	case "esc", "Z":
+		return l, nil, true
+	case "up", "k":
+		if l.Selected > 0 {
+			l.Selected--
+		}
+	case "down", "j":
+		if l.Selected < len(l.Items)-1 {
+			l.Selected++
+		}
+	case "u", "enter":
+		if l.Selected >= len(l.Items) {
+			break
+		}
+		id := l.Items[l.Selected].ID
+		l.Items = append(l.Items[:l.Selected], l.Items[l.Selected+1:]...)
+		if l.Selected >= len(l.Items) && l.Selected > 0 {
+			l.Selected--
+		}
+		l.ensureVisible()
+		return l, func() tea.Msg { return ArchiveMsg{TrackIDs: []string{id}, Archived: false} }, false
+	}
+	l.ensureVisible()
+	return l, nil, false
+}
+
+func (l *ArchivedList) ensureVisible() {
+	visible := l.visibleRows()
+	if l.Selected < l.Offset {
+		l.Offset = l.Selected
+	} else if l.Selected >= l.Offset+visible {
+		l.Offset = l.Selected - visible + 1
+	}
+}
+
+func (l ArchivedList) visibleRows() int {
+	if l.Height-4 < 1 {
+		return 1
+	}
+	return l.Height - 4
+}
+
+// View renders the list
+func (l ArchivedList) View() string {
+	var sb strings.Builder
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	selectedStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230")).
+		Bold(true).
+		Padding(0, 1)
+	normalStyle := lipgloss.NewStyle().Padding(0, 1)
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	sb.WriteString(titleStyle.Render(glyphs.Icon(glyphs.Archive) + i18n.T("Archived (%d)", len(l.Items))))
+	sb.WriteString("\n\n")
+
+	if len(l.Items) == 0 {
+		sb.WriteString(dim.Render(i18n.T("No archived tracks")))
+		sb.WriteString("\n")
+	}
+	end := min(l.Offset+l.visibleRows(), len(l.Items))
+	for i := l.Offset; i < end; i++ {
+		t := l.Items[i]
+		name := t.Title
+		if t.Artist != "" {
+			name = t.Artist + " - " + t.Title
+		}
+		line := truncateLabel(name, max(l.Width-10, 10))
+		if i == l.Selected {
+			sb.WriteString(selectedStyle.Render(line))
+		} else {
+			sb.WriteString(normalStyle.Render(line))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(dim.Render(i18n.T("[u/Enter] Restore  [Esc] Close")))
+	return sb.String()
+}
@@ -0,0 +1,134 @@
+package views
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/audiobook"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/ui/components"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// BookmarkAddMsg asks the app to bookmark Position in the current track
+type BookmarkAddMsg struct {
+	Name     string
+	Position time.Duration
+}
+
+// BookmarkRemoveMsg asks the app to remove a bookmark of the current track
+type BookmarkRemoveMsg struct {
+	Bookmark audiobook.Bookmark
+}
+
+// BookmarkPopup lists the bookmarks of the current track to jump to or
+// remove, and names new ones. Choosing a bookmark sends a SeekMsg.
+type BookmarkPopup struct {
+	Open bool
+
+	naming bool          // typing the name of a new bookmark
+	at     time.Duration // position the new bookmark is for
+	marks  []audiobook.Bookmark
+	title  string
+	menu   components.Menu
+	input  components.SearchInput
+}
+
+// ShowList opens the list of marks in the track named title; pos is where
+// "add" puts a new one
+func (p *BookmarkPopup) ShowList(title string, marks []audiobook.Bookmark, pos time.Duration) {
+	p.Open, p.naming = true, false
+	p.title, p.marks, p.at = title, marks, pos
+
+	var items []components.MenuItem
+	for i, mark := range marks {
+		key := ""
+		if i < 9 {
+			key = strconv.Itoa(i + 1)
+		}
+		label := fmt.Sprintf("%s  %s", formatChapterTime(mark.Position), mark.Name)
+		items = append(items, components.MenuItem{ID: strconv.Itoa(i), Label: label, Key: key})
+	}
+	if len(items) == 0 {
+		items = append(items, components.MenuItem{Label: i18n.T("No bookmarks yet"), Disabled: true})
+	}
+	items = append(items, components.MenuItem{ID: "add", Label: i18n.T("Bookmark %s", formatChapterTime(pos)), Key: "a"})
+	p.menu = components.NewMenu(i18n.T("Bookmarks: %s", title), items)
+}
+
+// ShowName opens the prompt naming a new bookmark at pos
+func (p *BookmarkPopup) ShowName(pos time.Duration) {
+	p.Open, p.naming, p.at = true, true, pos
+	p.input = components.NewSearchInput(40)
+	p.input.Prompt = i18n.T("Name: ")
+	p.input.Placeholder = formatChapterTime(pos)
+	p.input.Focus()
+}
+
+// Update handles a key while the popup is open
+func (p BookmarkPopup) Update(msg tea.KeyMsg) (BookmarkPopup, tea.Cmd) {
+	if p.naming {
+		switch msg.String() {
+		case "esc":
+			p.Open = false
+		case "enter":
+			p.Open = false
+			add := BookmarkAddMsg{Name: strings.TrimSpace(p.input.Value), Position: p.at}
+			if add.Name == "" {
+				add.Name = formatChapterTime(p.at)
+			}
+			return p, func() tea.Msg { return add }
+		default:
+			p.input, _ = p.input.Update(msg)
+		}
+		return p, nil
+	}
+
+	// d removes the selected bookmark and keeps the list open
+	if s := msg.String(); (s == "d" || s == "delete") && p.menu.Selected < len(p.marks) {
+		i := p.menu.Selected
+		mark := p.marks[i]
+		marks := append(p.marks[:i:i], p.marks[i+1:]...)
+		p.ShowList(p.title, marks, p.at)
+		if len(marks) > 0 {
+			p.menu.Selected = min(i, len(marks)-1)
+		}
+		return p, func() tea.Msg { return BookmarkRemoveMsg{Bookmark: mark} }
+	}
+
+	var result components.MenuResult
+	p.menu, result = p.menu.Update(msg)
+	if !result.Done {
+		return p, nil
+	}
+	p.Open = false
+	switch result.ID {
+	case "":
+		return p, nil
+	case "add":
+		p.ShowName(p.at)
+		return p, nil
+	}
+	i, _ := strconv.Atoi(result.ID)
+	pos := p.marks[i].Position
+	return p, func() tea.Msg { return SeekMsg{Position: pos} }
+}
+
+// View renders the popup
+func (p BookmarkPopup) View() string {
+	if !p.naming {
+		hint := lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(i18n.T("enter: jump  d: remove  esc: close"))
+		return p.menu.View() + "\n" + hint
+	}
+	title := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212")).
+		Render(i18n.T("New bookmark at %s", formatChapterTime(p.at)))
+	return lipgloss.NewStyle().
+		Border(glyphs.RoundedBorder()).
+		BorderForeground(lipgloss.Color("212")).
+		Padding(0, 1).
+		Render(title + "\n" + p.input.View())
+}
@@ -0,0 +1,178 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+	"github.com/jscyril/golang_music_player/pkg/stats"
+)
+
+// DashboardDays is how many days of listening time the dashboard charts
+const DashboardDays = 14
+
+// dashboardTop caps the artists and albums charted
+const dashboardTop = 10
+
+// DashboardView charts the play log: listening time per day, the top
+// artists of the week or month and the most played albums
+type DashboardView struct {
+	Width     int
+	Height    int
+	Listening library.Listening
+	Session   stats.StatsSummary // listening since the player started
+	Month     bool               // top artists of the last 30 days instead of 7
+	Offset    int                // first line shown when the charts do not fit
+
+	BorderStyle lipgloss.Style
+	TitleStyle  lipgloss.Style
+}
+
+// NewDashboardView creates the statistics view
+func NewDashboardView(width, height int) DashboardView {
+	return DashboardView{
+		Width:  width,
+		Height: height,
+		BorderStyle: lipgloss.NewStyle().
+			Border(glyphs.RoundedBorder()).
+			BorderForeground(lipgloss.Color("62")).
+			Padding(1, 2),
+		TitleStyle: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color("212")),
+	}
+}
+
+// SetListening replaces the charted figures
+func (v *DashboardView) SetListening(l library.Listening) {
+	v.Listening = l
+	v.Offset = min(v.Offset, v.maxOffset())
+}
+
+// Update handles messages
+func (v DashboardView) Update(msg tea.Msg) (DashboardView, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+	switch key.String() {
+	case "w":
+		v.Month = !v.Month
+	case "up", "k":
+		if v.Offset > 0 {
+			v.Offset--
+		}
+	case "down", "j":
+		if v.Offset < v.maxOffset() {
+			v.Offset++
+		}
+	}
+	v.Offset = min(v.Offset, v.maxOffset())
+	return v, nil
+}
+
+// visibleLines is the number of chart lines that fit inside the border,
+// padding and help line
+func (v DashboardView) visibleLines() int {
+	return max(v.Height-7, 1)
+}
+
+func (v DashboardView) maxOffset() int {
+	return max(len(v.lines())-v.visibleLines(), 0)
+}
+
+// lines renders the charts one line each
+func (v DashboardView) lines() []string {
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	barStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	barWidth := max(min(v.Width-40, 40), 10)
+	var out []string
+
+	chart := func(labels, values []string, sizes []int) {
+		top := 0
+		for _, n := range sizes {
+			top = max(top, n)
+		}
+		for i := range labels {
+			bar := 0
+			if top > 0 && sizes[i] > 0 {
+				bar = max(sizes[i]*barWidth/top, 1)
+			}
+			out = append(out, fmt.Sprintf("  %-20s %s %s", truncateLabel(labels[i], 20),
+				barStyle.Render(glyphs.Repeat(glyphs.Block, bar)), values[i]))
+		}
+	}
+	counts := func(title string, counts []library.PlayCount, empty string) {
+		out = append(out, "", v.TitleStyle.Render(title))
+		if len(counts) == 0 {
+			out = append(out, dim.Render("  "+empty))
+			return
+		}
+		counts = counts[:min(len(counts), dashboardTop)]
+		labels := make([]string, len(counts))
+		values := make([]string, len(counts))
+		sizes := make([]int, len(counts))
+		for i, c := range counts {
+			labels[i], values[i], sizes[i] = c.Label, fmt.Sprint(c.Plays), c.Plays
+		}
+		chart(labels, values, sizes)
+	}
+
+	days := v.Listening.Days
+	var total time.Duration
+	labels := make([]string, len(days))
+	values := make([]string, len(days))
+	sizes := make([]int, len(days))
+	for i, d := range days {
+		total += d.Listened
+		labels[i] = d.Day.Format("Mon Jan 2")
+		values[i] = formatListened(d.Listened)
+		sizes[i] = int(d.Listened / time.Minute)
+	}
+	out = append(out, v.TitleStyle.Render(i18n.T("Listening time, last %d days", len(days)))+
+		dim.Render("  "+i18n.T("%s in all", formatListened(total))))
+	chart(labels, values, sizes)
+	heard := "  " + i18n.T("This session: %s heard", formatListened(time.Duration(v.Session.ListenedSeconds)*time.Second))
+	if n := v.Session.TracksSkipped; n > 0 {
+		heard += ", " + i18n.T("%d skipped", n)
+	}
+	out = append(out, dim.Render(heard))
+
+	if v.Month {
+		counts(i18n.T("Top artists, last 30 days"), v.Listening.ArtistsMonth, i18n.T("Nothing played this month"))
+	} else {
+		counts(i18n.T("Top artists, last 7 days"), v.Listening.ArtistsWeek, i18n.T("Nothing played this week"))
+	}
+	counts(i18n.T("Most played albums"), v.Listening.Albums, i18n.T("No album plays yet"))
+	return out
+}
+
+// formatListened formats a listening time as "1h 05m" or "12m"
+func formatListened(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh %02dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// View renders the statistics view
+func (v DashboardView) View() string {
+	var sb strings.Builder
+	lines := v.lines()
+	end := min(v.Offset+v.visibleLines(), len(lines))
+	sb.WriteString(strings.Join(lines[min(v.Offset, end):end], "\n"))
+	sb.WriteString("\n\n")
+
+	period := i18n.T("[w] Month")
+	if v.Month {
+		period = i18n.T("[w] Week")
+	}
+	help := period + "  " + glyphs.Text(i18n.T("[↑↓] Scroll"))
+	sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(help))
+	return v.BorderStyle.Width(v.Width - 4).Render(sb.String())
+}
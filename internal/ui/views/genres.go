@@ -0,0 +1,217 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/ui/components"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// GenreFilterMsg asks the app to show only a genre (and its sub-genres),
+// or the tracks with a user tag when Genre is "#tag"; an empty Genre clears
+// the filter
+type GenreFilterMsg struct {
+	Genre string
+}
+
+// GenreParentMsg asks the app to move a genre under a new parent in the
+// taxonomy; an empty Parent makes it a top-level genre
+type GenreParentMsg struct {
+	Genre  string
+	Parent string
+}
+
+// GenreBrowser is an overlay listing the genre tree, then the user tags
+type GenreBrowser struct {
+	Nodes    []library.GenreNode
+	Tags     []string
+	Selected int
+	Offset   int
+	Height   int
+	Width    int
+	Editing  bool // true while typing a new parent for the selected genre
+	Input    components.SearchInput
+}
+
+// NewGenreBrowser creates a browser over a flattened genre tree
+func NewGenreBrowser(nodes []library.GenreNode, width, height int) GenreBrowser {
+	return GenreBrowser{Nodes: nodes, Width: width, Height: height}
+}
+
+// SetNodes replaces the tree, keeping the cursor on the same genre if possible
+func (b *GenreBrowser) SetNodes(nodes []library.GenreNode) {
+	name := b.SelectedGenre()
+	b.Nodes = nodes
+	b.reselect(name)
+}
+
+// SetTags replaces the user tags, keeping the cursor on the same row if possible
+func (b *GenreBrowser) SetTags(tags []string) {
+	name := b.SelectedGenre()
+	b.Tags = tags
+	b.reselect(name)
+}
+
+// reselect moves the cursor to the row named name, or the first row
+func (b *GenreBrowser) reselect(name string) {
+	for b.Selected = 0; b.Selected < b.rowCount(); b.Selected++ {
+		if b.SelectedGenre() == name {
+			break
+		}
+	}
+	if b.Selected == b.rowCount() {
+		b.Selected = 0
+	}
+	b.ensureVisible()
+}
+
+// SelectedGenre returns the genre under the cursor, or "#tag" on a user tag
+func (b *GenreBrowser) SelectedGenre() string {
+	switch {
+	case b.Selected < 0:
+	case b.Selected < len(b.Nodes):
+		return b.Nodes[b.Selected].Name
+	case b.Selected < b.rowCount():
+		return "#" + b.Tags[b.Selected-len(b.Nodes)]
+	}
+	return ""
+}
+
+func (b *GenreBrowser) rowCount() int {
+	return len(b.Nodes) + len(b.Tags)
+}
+
+// Update handles keys. done is true when the browser should close.
+func (b GenreBrowser) Update(msg tea.KeyMsg) (GenreBrowser, tea.Cmd, bool) {
+	if b.Editing {
+		switch msg.String() {
+		case "esc":
+			b.Editing = false
+			b.Input.Blur()
+		case "enter":
+			b.Editing = false
+			b.Input.Blur()
+			genre, parent := b.SelectedGenre(), strings.TrimSpace(b.Input.Value)
+			return b, func() tea.Msg { return GenreParentMsg{Genre: genre, Parent: parent} }, false
+		default:
+			b.Input, _ = b.Input.Update(msg)
+		}
+		return b, nil, false
+	}
+
+	switch msg.String() {
+	case "esc", "g":
+		return b, nil, true
+	case "up", "k":
+		if b.Selected > 0 {
+			b.Selected--
+		}
+	case "down", "j":
+		if b.Selected < b.rowCount()-1 {
+			b.Selected++
+		}
+	case "enter":
+		genre := b.SelectedGenre()
+		return b, func() tea.Msg { return GenreFilterMsg{Genre: genre} }, true
+	case "e":
+		if b.Selected < len(b.Nodes) {
+			genre := b.SelectedGenre()
+			b.Editing = true
+			b.Input = components.NewSearchInput(b.Width - 8)
+			b.Input.Prompt = i18n.T("Parent of %s: ", genre)
+			b.Input.Placeholder = i18n.T("(none: top level)")
+			b.Input.SetValue(b.parentOf(b.Selected))
+			b.Input.Focus()
+		}
+	}
+	b.ensureVisible()
+	return b, nil, false
+}
+
+// parentOf finds the nearest shallower node above index i
+func (b *GenreBrowser) parentOf(i int) string {
+	depth := b.Nodes[i].Depth
+	for j := i - 1; j >= 0; j-- {
+		if b.Nodes[j].Depth < depth {
+			return b.Nodes[j].Name
+		}
+	}
+	return ""
+}
+
+func (b *GenreBrowser) ensureVisible() {
+	visible := b.visibleRows()
+	if b.Selected < b.Offset {
+		b.Offset = b.Selected
+	} else if b.Selected >= b.Offset+visible {
+		b.Offset = b.Selected - visible + 1
+	}
+}
+
+func (b GenreBrowser) visibleRows() int {
+	if b.Height-4 < 1 {
+		return 1
+	}
+	return b.Height - 4
+}
+
+// View renders the genre tree
+func (b GenreBrowser) View() string {
+	var sb strings.Builder
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	selectedStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230")).
+		Bold(true).
+		Padding(0, 1)
+	normalStyle := lipgloss.NewStyle().Padding(0, 1)
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	sb.WriteString(titleStyle.Render(glyphs.Icon(glyphs.Genre) + i18n.T("Genres")))
+	sb.WriteString("\n\n")
+
+	if b.rowCount() == 0 {
+		sb.WriteString(dim.Render(i18n.T("No genres in library")))
+	}
+	end := b.Offset + b.visibleRows()
+	if end > b.rowCount() {
+		end = b.rowCount()
+	}
+	for i := b.Offset; i < end; i++ {
+		var line string
+		if i < len(b.Nodes) {
+			n := b.Nodes[i]
+			if n.Depth > 0 {
+				line = strings.Repeat("  ", n.Depth-1) + glyphs.Pointer.String() + " "
+			}
+			line += n.Name
+		} else {
+			line = "#" + b.Tags[i-len(b.Nodes)]
+		}
+		if i == b.Selected {
+			sb.WriteString(selectedStyle.Render(line))
+		} else {
+			sb.WriteString(normalStyle.Render(line))
+		}
+		sb.WriteString("\n")
+	}
+	if b.rowCount() > b.visibleRows() {
+		sb.WriteString(dim.Render(fmt.Sprintf("  [%d/%d]", b.Selected+1, b.rowCount())))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+	if b.Editing {
+		sb.WriteString(b.Input.View())
+		sb.WriteString("\n")
+		sb.WriteString(dim.Render(i18n.T("[Enter] Save  [Esc] Cancel")))
+	} else {
+		sb.WriteString(dim.Render(i18n.T("[Enter] Browse genre  [e] Set parent  [Esc] Close")))
+	}
+	return sb.String()
+}
@@ -0,0 +1,195 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/ui/components"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// HistoryView lists the play log, newest first, with a filter on title and
+// artist
+type HistoryView struct {
+	Width     int
+	Height    int
+	Records   []library.PlayRecord // the whole log
+	Filtered  []library.PlayRecord // records matching the search
+	Selected  int
+	Offset    int
+	SearchBar components.SearchInput
+	Searching bool
+
+	BorderStyle lipgloss.Style
+}
+
+// NewHistoryView creates a new history view
+func NewHistoryView(width, height int) HistoryView {
+	return HistoryView{
+		Width:     width,
+		Height:    height,
+		SearchBar: components.NewSearchInput(width - 6),
+		BorderStyle: lipgloss.NewStyle().
+			Border(glyphs.RoundedBorder()).
+			BorderForeground(lipgloss.Color("62")).
+			Padding(1, 2),
+	}
+}
+
+// SetRecords replaces the listed play log, keeping the current filter
+func (v *HistoryView) SetRecords(records []library.PlayRecord) {
+	v.Records = records
+	v.filter(v.SearchBar.Value)
+}
+
+// Capturing reports whether the search input takes every key
+func (v HistoryView) Capturing() bool {
+	return v.Searching
+}
+
+// filter keeps the records whose title or artist contains query
+func (v *HistoryView) filter(query string) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	v.Filtered = v.Filtered[:0]
+	for _, rec := range v.Records {
+		if query == "" ||
+			strings.Contains(strings.ToLower(rec.Title), query) ||
+			strings.Contains(strings.ToLower(rec.Artist), query) {
+			v.Filtered = append(v.Filtered, rec)
+		}
+	}
+	if v.Selected >= len(v.Filtered) {
+		v.Selected = max(len(v.Filtered)-1, 0)
+	}
+	v.ensureVisible()
+}
+
+// SelectedRecord returns the play under the cursor
+func (v *HistoryView) SelectedRecord() (library.PlayRecord, bool) {
+	if v.Selected < len(v.Filtered) {
+		return v.Filtered[v.Selected], true
+	}
+	return library.PlayRecord{}, false
+}
+
+// Update handles messages
+func (v HistoryView) Update(msg tea.Msg) (HistoryView, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+
+	if v.Searching {
+		switch key.String() {
+		case "enter", "esc":
+			v.Searching = false
+			v.SearchBar.Blur()
+			if key.String() == "esc" {
+				v.SearchBar.Clear()
+				v.filter("")
+			}
+		default:
+			v.SearchBar, _ = v.SearchBar.Update(msg)
+			v.filter(v.SearchBar.Value)
+		}
+		return v, nil
+	}
+
+	switch key.String() {
+	case "/":
+		v.Searching = true
+		v.SearchBar.Focus()
+	case "up", "k":
+		if v.Selected > 0 {
+			v.Selected--
+		}
+	case "down", "j":
+		if v.Selected < len(v.Filtered)-1 {
+			v.Selected++
+		}
+	}
+	v.ensureVisible()
+	return v, nil
+}
+
+func (v *HistoryView) ensureVisible() {
+	visible := v.visibleRows()
+	if v.Selected < v.Offset {
+		v.Offset = v.Selected
+	} else if v.Selected >= v.Offset+visible {
+		v.Offset = v.Selected - visible + 1
+	}
+}
+
+func (v HistoryView) visibleRows() int {
+	if v.Height-12 < 1 {
+		return 1
+	}
+	return v.Height - 12
+}
+
+// View renders the history view
+func (v HistoryView) View() string {
+	var sb strings.Builder
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	selectedStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230")).
+		Bold(true).
+		Padding(0, 1)
+	normalStyle := lipgloss.NewStyle().Padding(0, 1)
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	sb.WriteString(titleStyle.Render(glyphs.Icon(glyphs.History) + i18n.T("History (%d)", len(v.Filtered))))
+	sb.WriteString("\n")
+	if v.Searching || v.SearchBar.Value != "" {
+		sb.WriteString(v.SearchBar.View())
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+
+	if len(v.Filtered) == 0 {
+		if len(v.Records) == 0 {
+			sb.WriteString(dim.Render(i18n.T("Nothing has been played yet")))
+		} else {
+			sb.WriteString(dim.Render(i18n.T("No plays match the search")))
+		}
+		sb.WriteString("\n")
+	}
+	end := min(v.Offset+v.visibleRows(), len(v.Filtered))
+	for i := v.Offset; i < end; i++ {
+		rec := v.Filtered[i]
+		mark := glyphs.Skipped.String()
+		if rec.Completed {
+			mark = glyphs.Done.String()
+		}
+		name := rec.Title
+		if rec.Artist != "" {
+			name = rec.Artist + " - " + rec.Title
+		}
+		played := formatChapterTime(rec.Played)
+		if rec.Duration > 0 {
+			played += "/" + formatChapterTime(rec.Duration)
+		}
+		line := fmt.Sprintf("%s  %s %s  (%s)", rec.PlayedAt.Local().Format("2006-01-02 15:04"), mark,
+			truncateLabel(name, max(v.Width-46, 10)), played)
+		if i == v.Selected {
+			sb.WriteString(selectedStyle.Render(line))
+		} else {
+			sb.WriteString(normalStyle.Render(line))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+	if v.Searching {
+		sb.WriteString(dim.Render(i18n.T("[Enter] Confirm  [Esc] Cancel")))
+	} else {
+		sb.WriteString(dim.Render(glyphs.Text(i18n.T("[/] Search  [Enter] Play again  [↑↓] Navigate  %s completed  %s skipped", glyphs.Done, glyphs.Skipped))))
+	}
+	return v.BorderStyle.Width(v.Width - 4).Render(sb.String())
+}
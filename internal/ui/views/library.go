@@ -1,47 +1,158 @@
 package views
 
 import (
+	"sort"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/enrich"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/search"
 	"github.com/jscyril/golang_music_player/internal/ui/components"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
 )
 
-// FileAddedMsg is sent when a file is added via the file browser
+// AddDirectoryMsg asks for a directory picked in the file browser to be
+// scanned into the library; Remember also adds it to the music directories
+type AddDirectoryMsg struct {
+	Path     string
+	Remember bool
+}
+
+// FileAddedMsg is sent when files are added via the file browser: the
+// selected one, or every file marked there
 type FileAddedMsg struct {
-	Path string
+	Paths []string
+}
+
+// SearchChangedMsg is sent whenever the search query is edited
+type SearchChangedMsg struct {
+	Query string
+}
+
+// AddToPlaylistMsg asks the app to add tracks to a playlist. NewName is set
+// instead of PlaylistID when the user chose to create a new playlist.
+type AddToPlaylistMsg struct {
+	PlaylistID string
+	NewName    string
+	Tracks     []*api.Track
+}
+
+// EnqueueMsg asks the app to append tracks to the play queue
+type EnqueueMsg struct {
+	Tracks []*api.Track
+}
+
+// PlayAlbumMsg asks the app to play the album of Track from its first track
+type PlayAlbumMsg struct {
+	Track *api.Track
+}
+
+// PlayArtistMsg asks the app to play everything by the artist of Track
+type PlayArtistMsg struct {
+	Track *api.Track
+}
+
+// ShuffleAllMsg asks the app to play the whole library shuffled
+type ShuffleAllMsg struct{}
+
+// PlaySelectedMsg asks the app to play the selected track, as Enter does
+type PlaySelectedMsg struct{}
+
+// PlayNextMsg asks the app to queue tracks right after the current one
+type PlayNextMsg struct {
+	Tracks []*api.Track
+}
+
+// DeleteFilesMsg asks the app to delete the files of tracks, to the trash
+// where there is one, and remove the tracks from the library
+type DeleteFilesMsg struct {
+	TrackIDs []string
+}
+
+// UserTagMsg asks the app to add and remove user tags on tracks
+type UserTagMsg struct {
+	TrackIDs []string
+	Add      []string
+	Remove   []string
+}
+
+// GoToAlbumMsg asks the app to narrow the library to the album of Track
+type GoToAlbumMsg struct {
+	Track *api.Track
+}
+
+// GoToArtistMsg asks the app to narrow the library to the artist of Track
+type GoToArtistMsg struct {
+	Track *api.Track
 }
 
 // LibraryView displays the music library
 type LibraryView struct {
-	Width       int
-	Height      int
-	TrackList   components.TrackList
-	SearchBar   components.SearchInput
-	FileBrowser components.FileBrowser
-	Searching   bool
-	Browsing    bool // True when file browser is open
-	AllTracks   []*api.Track
-	BorderStyle lipgloss.Style
-	TitleStyle  lipgloss.Style
+	Width        int
+	Height       int
+	TrackList    components.TrackList
+	SearchBar    components.SearchInput
+	FileBrowser  components.FileBrowser
+	Searching    bool
+	Browsing     bool // True when file browser is open
+	Picking      bool // True when the playlist picker overlay is open
+	Picker       components.PlaylistPicker
+	Playlists    []*api.Playlist
+	ShowGenres   bool // True when the genre browser overlay is open
+	Genres       GenreBrowser
+	GenreFilter  string // genre, or "#tag" for a user tag, the list is limited to; empty shows everything
+	Narrowed     string // album or artist the list is narrowed to by the track menu
+	ShowMenu     bool   // True when the track context menu is open
+	Menu         components.Menu
+	ShowSkipped  bool // True when the frequently-skipped overlay is open
+	Confirming   bool // True while asking whether to remove the marked tracks
+	Confirm      components.Confirm
+	SkipConfirm  map[string]bool // confirmations turned off with "don't ask again"
+	Trash        bool            // deleted files go to the trash rather than being gone for good
+	Skipped      SkippedList
+	ShowArchived bool // True when the archived-tracks overlay is open
+	Archived     ArchivedList
+	Editing      bool // True when the tag editor is open
+	Editor       TagEditor
+	Reviewing    bool // True when the metadata suggestions overlay is open
+	Review       ReviewList
+	Scanning     bool // True while a library scan runs
+	Scan         ScanPanel
+	ShowErrors   bool // True when the scan errors overlay is open
+	ScanErrors   ScanErrorList
+	ShowInfo     bool // True when the track details overlay is open
+	Info         TrackInfo
+	ShowStats    bool // True when the library totals overlay is open
+	Stats        LibraryStats
+	Tagging      bool // True while typing user tags for the marked tracks
+	TagInput     components.SearchInput
+	tagTargets   []string
+	AllTracks    []*api.Track
+	Results      map[*api.Track]search.Result // merged search results by listed track, as sources may share IDs
+	BorderStyle  lipgloss.Style
+	TitleStyle   lipgloss.Style
 }
 
 // NewLibraryView creates a new library view
 func NewLibraryView(width, height int) LibraryView {
-	trackList := components.NewTrackList(height-8, width-6)
-	trackList.Title = "🎵 Library"
+	trackList := components.NewTrackList(height-8, width-8)
+	trackList.Title = glyphs.Icon(glyphs.Music) + i18n.T("Library")
 
 	return LibraryView{
 		Width:       width,
 		Height:      height,
 		TrackList:   trackList,
-		SearchBar:   components.NewSearchInput(width - 6),
+		SearchBar:   components.NewSearchInput(width - 10),
 		FileBrowser: components.NewFileBrowser("", width, height),
+		Genres:      NewGenreBrowser(nil, width, height-8),
 		AllTracks:   make([]*api.Track, 0),
 		BorderStyle: lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
+			Border(glyphs.RoundedBorder()).
 			BorderForeground(lipgloss.Color("62")).
 			Padding(1, 2),
 		TitleStyle: lipgloss.NewStyle().
@@ -62,15 +173,468 @@ func (v *LibraryView) AddTrack(track *api.Track) {
 	v.TrackList.SetItems(v.AllTracks)
 }
 
+// OpenArchived shows the archived-tracks overlay
+func (v *LibraryView) OpenArchived(items []*api.Track) {
+	v.ShowArchived = true
+	v.Archived = NewArchivedList(items, v.Width, v.Height-8)
+}
+
+// Capturing reports whether an input mode or overlay should receive every key
+func (v *LibraryView) Capturing() bool {
+	return v.Searching || v.Browsing || v.Picking || v.ShowGenres || v.ShowSkipped ||
+		v.ShowArchived || v.Confirming || v.Editing || v.Reviewing || v.ShowErrors || v.ShowMenu ||
+		v.ShowInfo || v.ShowStats || v.Tagging || v.TrackList.Marking
+}
+
+// CommandMode reports whether the view is capturing keys only because it is
+// in marking mode, so its own bindings still apply
+func (v *LibraryView) CommandMode() bool {
+	return v.TrackList.Marking && !v.Searching && !v.Browsing && !v.Picking &&
+		!v.ShowGenres && !v.ShowSkipped && !v.ShowArchived && !v.Confirming && !v.Editing &&
+		!v.Reviewing && !v.ShowErrors && !v.ShowMenu && !v.ShowInfo && !v.ShowStats && !v.Tagging
+}
+
+// SetScanProgress shows the progress of a running scan, or hides the panel
+// once it is done
+func (v *LibraryView) SetScanProgress(p api.ScanProgress) {
+	v.Scanning = !p.Done
+	v.Scan.Progress = p
+	v.Scan.Width = v.Width - 6
+}
+
+// OpenScanErrors shows the scan errors overlay
+func (v *LibraryView) OpenScanErrors(items []*playerrors.ScanError, failed int) {
+	v.ShowErrors = true
+	v.ScanErrors = NewScanErrorList(items, failed, v.Width, v.Height-8)
+}
+
+// OpenReview shows the metadata suggestions overlay while pending tracks
+// are looked up
+func (v *LibraryView) OpenReview(pending int) {
+	v.Reviewing = true
+	v.Review = NewReviewList(pending, v.Width, v.Height-8)
+}
+
+// OpenInfo shows the track details overlay while the track's file is read
+func (v *LibraryView) OpenInfo(track *api.Track, stats library.PlayStat) {
+	v.ShowInfo = true
+	v.Info = NewTrackInfo(track, stats, v.Width, v.Height-8)
+	if v.IsRemote(track) {
+		v.Info.Source = v.Results[track].Source
+	} else {
+		v.Info.Loading = true
+	}
+}
+
+// SetInfoDetails fills in the details read from the file of the track
+// with the given ID, unless the overlay has moved on to another track
+func (v *LibraryView) SetInfoDetails(id string, details *library.FileDetails, err error) {
+	if !v.ShowInfo || v.Info.Track.ID != id {
+		return
+	}
+	v.Info.Loading = false
+	v.Info.Details, v.Info.Err = details, err
+}
+
+// OpenStats shows the library totals overlay
+func (v *LibraryView) OpenStats(s library.Summary) {
+	v.ShowStats = true
+	v.Stats = NewLibraryStats(s, v.Width, v.Height-8)
+}
+
+// OpenSkipped shows the frequently-skipped overlay
+func (v *LibraryView) OpenSkipped(items []library.SkipStat, banned func(id string) bool) {
+	v.ShowSkipped = true
+	v.Skipped = NewSkippedList(items, banned, v.Width, v.Height-8)
+	v.Skipped.SkipConfirm = v.SkipConfirm
+}
+
+// SetGenreTree updates the genres offered by the genre browser
+func (v *LibraryView) SetGenreTree(nodes []library.GenreNode) {
+	v.Genres.SetNodes(nodes)
+}
+
+// SetUserTags updates the user tags listed below the genres
+func (v *LibraryView) SetUserTags(tags []string) {
+	v.Genres.SetTags(tags)
+}
+
+// parseUserTags splits the tag input: comma-separated tags to add, those
+// prefixed with "-" to remove
+func parseUserTags(input string) (add, remove []string) {
+	for _, tag := range strings.Split(input, ",") {
+		tag = strings.TrimSpace(tag)
+		if t, ok := strings.CutPrefix(tag, "-"); ok {
+			if t = strings.TrimSpace(t); t != "" {
+				remove = append(remove, t)
+			}
+		} else if tag != "" {
+			add = append(add, tag)
+		}
+	}
+	return add, remove
+}
+
+// SetGenreFilter limits the list to a genre's tracks; an empty genre with
+// all tracks removes the filter
+func (v *LibraryView) SetGenreFilter(genre string, tracks []*api.Track) {
+	v.GenreFilter = genre
+	v.Narrowed = ""
+	v.setTitle()
+	v.SearchBar.Clear()
+	v.TrackList.Highlight = ""
+	v.SetTracks(tracks)
+}
+
+// Narrow lists only tracks, e.g. an album picked from the track menu,
+// under label until Esc or a search shows the whole list again
+func (v *LibraryView) Narrow(label string, tracks []*api.Track) {
+	v.Narrowed = label
+	v.setTitle()
+	v.SearchBar.Clear()
+	v.TrackList.Labels = nil
+	v.TrackList.Highlight = ""
+	v.Results = nil
+	v.TrackList.SetItems(tracks)
+}
+
+// setTitle names the list after the genre filter and narrowing in effect
+func (v *LibraryView) setTitle() {
+	v.TrackList.Title = glyphs.Icon(glyphs.Music) + i18n.T("Library")
+	if v.GenreFilter != "" {
+		v.TrackList.Title += " " + glyphs.Pointer.String() + " " + v.GenreFilter
+	}
+	if v.Narrowed != "" {
+		v.TrackList.Title += " " + glyphs.Pointer.String() + " " + v.Narrowed
+	}
+}
+
+// OpenMenu opens the context menu of the selected track, acting on the
+// marked tracks where an entry takes several
+func (v *LibraryView) OpenMenu() {
+	track := v.TrackList.SelectedItem()
+	if track == nil {
+		return
+	}
+	remote := v.IsRemote(track)
+	title := track.Title
+	if n := len(v.TrackList.MarkedItems()); n > 1 {
+		title = i18n.T("%s (%d marked)", track.Title, n)
+	}
+	v.ShowMenu = true
+	v.Menu = components.NewMenu(title, []components.MenuItem{
+		{ID: "play", Label: i18n.T("Play"), Key: "p"},
+		{ID: "play_next", Label: i18n.T("Play next"), Key: "n", Disabled: remote},
+		{ID: "enqueue", Label: i18n.T("Add to queue"), Key: "e", Disabled: remote},
+		{ID: "playlist", Label: glyphs.Text(i18n.T("Add to playlist…")), Key: "P"},
+		{ID: "album", Label: i18n.T("Go to album"), Key: "a", Disabled: remote},
+		{ID: "artist", Label: i18n.T("Go to artist"), Key: "r", Disabled: remote || track.Artist == ""},
+		{ID: "tags", Label: glyphs.Text(i18n.T("Edit tags…")), Key: "t", Disabled: remote},
+		{ID: "file", Label: i18n.T("Show file"), Key: "f", Disabled: remote},
+		{ID: "info", Label: i18n.T("Details"), Key: "i"},
+		{ID: "delete", Label: glyphs.Text(i18n.T("Delete file…")), Key: "x", Disabled: remote},
+	})
+}
+
+// menuChoice carries out the context menu entry id
+func (v *LibraryView) menuChoice(id string) tea.Cmd {
+	track := v.TrackList.SelectedItem()
+	if track == nil {
+		return nil
+	}
+
+	switch id {
+	case "play":
+		return func() tea.Msg { return PlaySelectedMsg{} }
+	case "play_next":
+		tracks := v.localTargets()
+		v.TrackList.StopMarking()
+		return func() tea.Msg { return PlayNextMsg{Tracks: tracks} }
+	case "enqueue":
+		tracks := v.localTargets()
+		v.TrackList.StopMarking()
+		return func() tea.Msg { return EnqueueMsg{Tracks: tracks} }
+	case "playlist":
+		v.Picking = true
+		v.Picker = components.NewPlaylistPicker(v.Playlists, v.Width)
+		if n := len(v.TrackList.Targets()); n > 1 {
+			v.Picker.Title = i18n.T("Add %d tracks to playlist", n)
+		}
+	case "album":
+		return func() tea.Msg { return GoToAlbumMsg{Track: track} }
+	case "artist":
+		return func() tea.Msg { return GoToArtistMsg{Track: track} }
+	case "tags":
+		if tracks := v.localTargets(); len(tracks) > 0 {
+			v.Editing = true
+			v.Editor = NewTagEditor(tracks, v.Width-6)
+		}
+	case "file":
+		v.Browsing = true
+		v.FileBrowser.Width, v.FileBrowser.Height = v.Width, v.Height
+		v.FileBrowser.Reveal(track.FilePath)
+	case "info":
+		return func() tea.Msg { return TrackInfoMsg{Track: track} }
+	case "delete":
+		v.askDelete()
+	}
+	return nil
+}
+
+// RowAt returns the index of the track drawn on line y of the view, or -1
+func (v *LibraryView) RowAt(y int) int {
+	if v.Capturing() && !v.CommandMode() {
+		return -1
+	}
+	// Border and padding, then the search bar and a blank line
+	top := 2 + lipgloss.Height(v.SearchBar.View()) + 1
+	if v.Scanning {
+		top += lipgloss.Height(v.Scan.View()) + 1
+	}
+	return v.TrackList.RowAt(y - top)
+}
+
+// SetBookmarks sets the file browser's bookmarked folders
+func (v *LibraryView) SetBookmarks(bookmarks []string) {
+	v.FileBrowser.Bookmarks = bookmarks
+}
+
+// SetPlaylists sets the playlists offered by the add-to-playlist picker
+func (v *LibraryView) SetPlaylists(playlists []*api.Playlist) {
+	sorted := make([]*api.Playlist, len(playlists))
+	copy(sorted, playlists)
+	sort.Slice(sorted, func(i, j int) bool {
+		return strings.ToLower(sorted[i].Name) < strings.ToLower(sorted[j].Name)
+	})
+	v.Playlists = sorted
+}
+
+// localTargets returns the marked (or selected) tracks that are local files
+func (v *LibraryView) localTargets() []*api.Track {
+	var tracks []*api.Track
+	for _, t := range v.TrackList.Targets() {
+		if !v.IsRemote(t) {
+			tracks = append(tracks, t)
+		}
+	}
+	return tracks
+}
+
+// askDelete asks whether to delete the files of the marked (or selected)
+// local tracks. Deleting files is always confirmed.
+func (v *LibraryView) askDelete() {
+	n := len(v.localTargets())
+	if n == 0 {
+		return
+	}
+	question := i18n.T("Permanently delete %d file(s) and remove them from the library?", n)
+	if v.Trash {
+		question = i18n.T("Move %d file(s) to the trash and remove them from the library?", n)
+	}
+	v.Confirming = true
+	v.Confirm = components.NewConfirm(components.ConfirmDeleteFiles, question)
+	v.Confirm.AskAlways = true
+}
+
+// deleteTargets stops marking and asks the app to delete the files of the
+// marked tracks
+func (v *LibraryView) deleteTargets() tea.Cmd {
+	var ids []string
+	for _, t := range v.localTargets() {
+		ids = append(ids, t.ID)
+	}
+	v.TrackList.StopMarking()
+	return func() tea.Msg { return DeleteFilesMsg{TrackIDs: ids} }
+}
+
+// removeTargets stops marking and asks the app to remove the marked tracks
+func (v *LibraryView) removeTargets() tea.Cmd {
+	var ids []string
+	for _, t := range v.TrackList.Targets() {
+		ids = append(ids, t.ID)
+	}
+	v.TrackList.StopMarking()
+	return func() tea.Msg { return RemoveTracksMsg{TrackIDs: ids} }
+}
+
 // Update handles messages
 func (v LibraryView) Update(msg tea.Msg) (LibraryView, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// Handle the track context menu
+		if v.ShowMenu {
+			var result components.MenuResult
+			v.Menu, result = v.Menu.Update(msg)
+			if !result.Done {
+				return v, nil
+			}
+			v.ShowMenu = false
+			if result.Cancelled {
+				return v, nil
+			}
+			return v, v.menuChoice(result.ID)
+		}
+
+		// Handle genre browser overlay
+		if v.ShowGenres {
+			var cmd tea.Cmd
+			var done bool
+			v.Genres, cmd, done = v.Genres.Update(msg)
+			if done {
+				v.ShowGenres = false
+			}
+			return v, cmd
+		}
+
+		// Confirm removal of the marked tracks
+		if v.Confirming {
+			var result components.ConfirmResult
+			v.Confirm, result = v.Confirm.Update(msg)
+			if !result.Done {
+				return v, nil
+			}
+			v.Confirming = false
+			if !result.Accepted {
+				return v, nil
+			}
+			if result.Action == components.ConfirmDeleteFiles {
+				return v, result.Then(v.deleteTargets())
+			}
+			return v, result.Then(v.removeTargets())
+		}
+
+		// Handle frequently-skipped overlay
+		if v.ShowSkipped {
+			var cmd tea.Cmd
+			var done bool
+			v.Skipped, cmd, done = v.Skipped.Update(msg)
+			if done {
+				v.ShowSkipped = false
+			}
+			return v, cmd
+		}
+
+		// Handle library totals overlay
+		if v.ShowStats {
+			var done bool
+			v.Stats, _, done = v.Stats.Update(msg)
+			if done {
+				v.ShowStats = false
+			}
+			return v, nil
+		}
+
+		// Handle archived-tracks overlay
+		if v.ShowArchived {
+			var cmd tea.Cmd
+			var done bool
+			v.Archived, cmd, done = v.Archived.Update(msg)
+			if done {
+				v.ShowArchived = false
+			}
+			return v, cmd
+		}
+
+		// Handle tag editor overlay
+		if v.Editing {
+			var cmd tea.Cmd
+			var done bool
+			v.Editor, cmd, done = v.Editor.Update(msg)
+			if done {
+				v.Editing = false
+				if cmd != nil {
+					v.TrackList.StopMarking()
+				}
+			}
+			return v, cmd
+		}
+
+		// Handle metadata suggestions overlay
+		if v.Reviewing {
+			var cmd tea.Cmd
+			var done bool
+			v.Review, cmd, done = v.Review.Update(msg)
+			if done {
+				v.Reviewing = false
+			}
+			return v, cmd
+		}
+
+		// Handle the user tag input
+		if v.Tagging {
+			switch msg.String() {
+			case "esc":
+				v.Tagging = false
+				v.TagInput.Blur()
+			case "enter":
+				v.Tagging = false
+				v.TagInput.Blur()
+				add, remove := parseUserTags(v.TagInput.Value)
+				if len(add) == 0 && len(remove) == 0 {
+					return v, nil
+				}
+				ids := v.tagTargets
+				v.TrackList.StopMarking()
+				return v, func() tea.Msg { return UserTagMsg{TrackIDs: ids, Add: add, Remove: remove} }
+			default:
+				v.TagInput, _ = v.TagInput.Update(msg)
+			}
+			return v, nil
+		}
+
+		// Handle track details overlay
+		if v.ShowInfo {
+			var cmd tea.Cmd
+			var done bool
+			v.Info, cmd, done = v.Info.Update(msg)
+			if done {
+				v.ShowInfo = false
+			}
+			return v, cmd
+		}
+
+		// Handle scan errors overlay
+		if v.ShowErrors {
+			var cmd tea.Cmd
+			var done bool
+			v.ScanErrors, cmd, done = v.ScanErrors.Update(msg)
+			if done {
+				v.ShowErrors = false
+			}
+			return v, cmd
+		}
+
+		// Handle playlist picker overlay
+		if v.Picking {
+			var result components.PickerResult
+			v.Picker, result = v.Picker.Update(msg)
+			if !result.Done {
+				return v, nil
+			}
+			v.Picking = false
+			if result.Cancelled {
+				return v, nil
+			}
+			addMsg := AddToPlaylistMsg{
+				PlaylistID: result.PlaylistID,
+				NewName:    result.NewName,
+				Tracks:     v.TrackList.Targets(),
+			}
+			v.TrackList.StopMarking()
+			return v, func() tea.Msg { return addMsg }
+		}
+
 		// Handle file browser mode
 		if v.Browsing {
+			if v.FileBrowser.Typing() {
+				var cmd tea.Cmd
+				v.FileBrowser, cmd = v.FileBrowser.Update(msg)
+				return v, cmd
+			}
 			switch msg.String() {
 			case "esc":
 				v.Browsing = false
+				v.FileBrowser.ClearMarks()
 				return v, nil
 			case "enter":
 				// Try to open/add the selected entry
@@ -78,16 +642,32 @@ func (v LibraryView) Update(msg tea.Msg) (LibraryView, tea.Cmd) {
 				if filePath != "" {
 					// File was selected, send message to add it
 					v.Browsing = false
+					v.FileBrowser.ClearMarks()
 					return v, func() tea.Msg {
-						return FileAddedMsg{Path: filePath}
+						return FileAddedMsg{Paths: []string{filePath}}
 					}
 				}
 				// Otherwise it was a directory navigation, stay in browser
 				return v, nil
+			case "A":
+				// With files marked, A adds them all in one go
+				if paths := v.FileBrowser.Marked(); len(paths) > 0 {
+					v.Browsing = false
+					v.FileBrowser.ClearMarks()
+					return v, func() tea.Msg { return FileAddedMsg{Paths: paths} }
+				}
+				fallthrough
+			case "a":
+				dir := v.FileBrowser.SelectedDir()
+				remember := msg.String() == "A"
+				v.Browsing = false
+				v.FileBrowser.ClearMarks()
+				return v, func() tea.Msg { return AddDirectoryMsg{Path: dir, Remember: remember} }
 			default:
-				v.FileBrowser, _ = v.FileBrowser.Update(msg)
+				var cmd tea.Cmd
+				v.FileBrowser, cmd = v.FileBrowser.Update(msg)
+				return v, cmd
 			}
-			return v, nil
 		}
 
 		// Handle search mode
@@ -96,17 +676,25 @@ func (v LibraryView) Update(msg tea.Msg) (LibraryView, tea.Cmd) {
 			case "enter", "esc":
 				v.Searching = false
 				v.SearchBar.Blur()
-				// Filter tracks based on search
-				if v.SearchBar.Value != "" {
+				// Keep merged results if they match the final query
+				if v.SearchBar.Value == "" {
+					v.filterTracks("")
+				} else if len(v.Results) == 0 {
 					v.filterTracks(v.SearchBar.Value)
-				} else {
-					v.TrackList.SetItems(v.AllTracks)
 				}
 				return v, nil
 			default:
+				prev := v.SearchBar.Value
 				v.SearchBar, _ = v.SearchBar.Update(msg)
-				// Live filtering
+				if v.SearchBar.Value == prev {
+					return v, nil
+				}
+				// Live filtering of local tracks; remote results arrive later
 				v.filterTracks(v.SearchBar.Value)
+				query := v.SearchBar.Value
+				return v, func() tea.Msg {
+					return SearchChangedMsg{Query: query}
+				}
 			}
 		} else {
 			// Normal mode
@@ -115,10 +703,169 @@ func (v LibraryView) Update(msg tea.Msg) (LibraryView, tea.Cmd) {
 				v.Searching = true
 				v.SearchBar.Focus()
 				return v, nil
+			case ".":
+				v.OpenMenu()
+				return v, nil
+			case "i":
+				if track := v.TrackList.SelectedItem(); track != nil {
+					return v, func() tea.Msg { return TrackInfoMsg{Track: track} }
+				}
+				return v, nil
 			case "a":
-				// Open file browser
+				// Open the file browser where it was left, with its hidden
+				// files, sort order and bookmarks
 				v.Browsing = true
-				v.FileBrowser = components.NewFileBrowser("", v.Width, v.Height)
+				v.FileBrowser.Width, v.FileBrowser.Height = v.Width, v.Height
+				v.FileBrowser.Reload()
+				return v, nil
+			case "m":
+				v.TrackList.StartMarking()
+				v.TrackList.ToggleMark()
+				v.TrackList.MoveDown()
+				return v, nil
+			case "v":
+				v.TrackList.StartMarking()
+				v.TrackList.ToggleVisual()
+				return v, nil
+			case "esc":
+				if v.TrackList.InVisual() {
+					v.TrackList.CancelVisual()
+					return v, nil
+				}
+				if !v.TrackList.Marking && v.Scanning {
+					return v, func() tea.Msg { return CancelScanMsg{} }
+				}
+				if !v.TrackList.Marking && v.Narrowed != "" {
+					v.Narrowed = ""
+					v.setTitle()
+					v.TrackList.SetItems(v.AllTracks)
+					return v, nil
+				}
+				if !v.TrackList.Marking && v.GenreFilter != "" {
+					return v, func() tea.Msg { return GenreFilterMsg{} }
+				}
+				v.TrackList.StopMarking()
+				return v, nil
+			case "e":
+				// Enqueue marked (or selected) tracks
+				if tracks := v.TrackList.Targets(); len(tracks) > 0 {
+					v.TrackList.StopMarking()
+					return v, func() tea.Msg { return EnqueueMsg{Tracks: tracks} }
+				}
+				return v, nil
+			case "D":
+				n := len(v.TrackList.Targets())
+				switch {
+				case !v.TrackList.Marking || n == 0:
+				case v.SkipConfirm[components.ConfirmRemoveTracks]:
+					return v, v.removeTargets()
+				default:
+					v.Confirming = true
+					v.Confirm = components.NewConfirm(components.ConfirmRemoveTracks,
+						i18n.T("Remove %d track(s) from the library?", n))
+				}
+				return v, nil
+			case "ctrl+d":
+				v.askDelete()
+				return v, nil
+			case "g":
+				v.ShowGenres = true
+				v.Genres.Width = v.Width
+				v.Genres.Height = v.Height - 8
+				return v, nil
+			case "F":
+				return v, func() tea.Msg { return ShowSkippedMsg{} }
+			case "T":
+				return v, func() tea.Msg { return ShowLibraryStatsMsg{} }
+			case "A":
+				// Archive marked (or selected) tracks
+				var ids []string
+				for _, t := range v.TrackList.Targets() {
+					if !v.IsRemote(t) {
+						ids = append(ids, t.ID)
+					}
+				}
+				if len(ids) > 0 {
+					v.TrackList.StopMarking()
+					return v, func() tea.Msg { return ArchiveMsg{TrackIDs: ids, Archived: true} }
+				}
+				return v, nil
+			case "Z":
+				return v, func() tea.Msg { return ShowArchivedMsg{} }
+			case "t":
+				// Edit the tags of marked (or selected) local tracks
+				var tracks []*api.Track
+				for _, t := range v.TrackList.Targets() {
+					if !v.IsRemote(t) {
+						tracks = append(tracks, t)
+					}
+				}
+				if len(tracks) > 0 {
+					v.Editing = true
+					v.Editor = NewTagEditor(tracks, v.Width-6)
+				}
+				return v, nil
+			case "#":
+				// Label marked (or selected) local tracks with user tags
+				v.tagTargets = nil
+				for _, t := range v.TrackList.Targets() {
+					if !v.IsRemote(t) {
+						v.tagTargets = append(v.tagTargets, t.ID)
+					}
+				}
+				if len(v.tagTargets) > 0 {
+					v.Tagging = true
+					v.TagInput = components.NewSearchInput(v.Width - 10)
+					v.TagInput.Prompt = i18n.T("Tags for %d track(s): ", len(v.tagTargets))
+					v.TagInput.Placeholder = i18n.T("workout, chill, -old")
+					v.TagInput.Focus()
+				}
+				return v, nil
+			case "R":
+				if !v.Scanning {
+					return v, func() tea.Msg { return RescanMsg{} }
+				}
+				return v, nil
+			case "E":
+				return v, func() tea.Msg { return ShowScanErrorsMsg{} }
+			case "L":
+				if track := v.TrackList.SelectedItem(); track != nil && !v.IsRemote(track) {
+					return v, func() tea.Msg { return PlayAlbumMsg{Track: track} }
+				}
+				return v, nil
+			case "I":
+				if track := v.TrackList.SelectedItem(); track != nil && !v.IsRemote(track) {
+					return v, func() tea.Msg { return PlayArtistMsg{Track: track} }
+				}
+				return v, nil
+			case "X":
+				return v, func() tea.Msg { return ShuffleAllMsg{} }
+			case "M":
+				// Look up metadata for the marked tracks, or for every
+				// track whose tags are missing
+				marked := v.TrackList.MarkedItems()
+				var tracks []*api.Track
+				for _, t := range v.AllTracks {
+					if len(marked) == 0 && !v.IsRemote(t) && enrich.NeedsLookup(t) {
+						tracks = append(tracks, t)
+					}
+				}
+				for _, t := range marked {
+					if !v.IsRemote(t) {
+						tracks = append(tracks, t)
+					}
+				}
+				v.TrackList.StopMarking()
+				return v, func() tea.Msg { return LookupMetadataMsg{Tracks: tracks} }
+			case "P":
+				// Add marked (or selected) tracks to a playlist
+				if n := len(v.TrackList.Targets()); n > 0 {
+					v.Picking = true
+					v.Picker = components.NewPlaylistPicker(v.Playlists, v.Width)
+					if n > 1 {
+						v.Picker.Title = i18n.T("Add %d tracks to playlist", n)
+					}
+				}
 				return v, nil
 			default:
 				v.TrackList, _ = v.TrackList.Update(msg)
@@ -130,23 +877,79 @@ func (v LibraryView) Update(msg tea.Msg) (LibraryView, tea.Cmd) {
 
 // filterTracks filters tracks based on search query
 func (v *LibraryView) filterTracks(query string) {
+	if v.Narrowed != "" {
+		v.Narrowed = ""
+		v.setTitle()
+	}
+	v.Results = nil
+	v.TrackList.Labels = nil
+	v.TrackList.Highlight = ""
 	if query == "" {
 		v.TrackList.SetItems(v.AllTracks)
 		return
 	}
 
-	query = strings.ToLower(query)
+	q := library.ParseQuery(query)
 	filtered := make([]*api.Track, 0)
 	for _, track := range v.AllTracks {
-		if strings.Contains(strings.ToLower(track.Title), query) ||
-			strings.Contains(strings.ToLower(track.Artist), query) ||
-			strings.Contains(strings.ToLower(track.Album), query) {
+		if q.Match(track) {
 			filtered = append(filtered, track)
 		}
 	}
+	q.Rank(filtered)
+	v.TrackList.Highlight = q.Text
 	v.TrackList.SetItems(filtered)
 }
 
+// SetSearchResults replaces the list with merged, ranked results from all
+// search sources. Results for a query other than the current one are ignored.
+func (v *LibraryView) SetSearchResults(query string, results []search.Result) {
+	if query != v.SearchBar.Value || query == "" {
+		return
+	}
+
+	tracks := make([]*api.Track, 0, len(results))
+	v.Results = make(map[*api.Track]search.Result, len(results))
+	labels := make(map[*api.Track]string)
+	for _, r := range results {
+		tracks = append(tracks, r.Track)
+		v.Results[r.Track] = r
+		// Label remote results by server, others by why they matched
+		var label []string
+		if r.Remote {
+			label = append(label, r.Source)
+		}
+		if r.Context != "" {
+			label = append(label, r.Context)
+		}
+		if len(label) > 0 {
+			labels[r.Track] = strings.Join(label, " "+glyphs.Separator.String()+" ")
+		}
+	}
+
+	selected := v.TrackList.Selected
+	v.TrackList.SetItems(tracks)
+	v.TrackList.Labels = labels
+	v.TrackList.Highlight = library.ParseQuery(query).Text
+	if selected < len(tracks) {
+		v.TrackList.Selected = selected
+	}
+}
+
+// SourceOf returns the search source a listed track came from
+func (v *LibraryView) SourceOf(track *api.Track) string {
+	if r, ok := v.Results[track]; ok {
+		return r.Source
+	}
+	return search.LocalSourceName
+}
+
+// IsRemote reports whether a listed track is a search result that has to
+// be streamed from its source rather than played from a local file
+func (v *LibraryView) IsRemote(track *api.Track) bool {
+	return v.Results[track].Remote
+}
+
 // SelectedTrack returns the currently selected track
 func (v *LibraryView) SelectedTrack() *api.Track {
 	return v.TrackList.SelectedItem()
@@ -165,16 +968,57 @@ func (v LibraryView) View() string {
 	sb.WriteString(v.SearchBar.View())
 	sb.WriteString("\n\n")
 
-	// Track list
-	sb.WriteString(v.TrackList.View())
+	if v.Scanning {
+		sb.WriteString(v.Scan.View())
+		sb.WriteString("\n\n")
+	}
+
+	// Track list, or an overlay on top of it
+	if v.ShowMenu {
+		sb.WriteString(v.Menu.View())
+	} else if v.Picking {
+		sb.WriteString(v.Picker.View())
+	} else if v.ShowGenres {
+		sb.WriteString(v.Genres.View())
+	} else if v.ShowSkipped {
+		sb.WriteString(v.Skipped.View())
+	} else if v.ShowArchived {
+		sb.WriteString(v.Archived.View())
+	} else if v.Editing {
+		sb.WriteString(v.Editor.View())
+	} else if v.Reviewing {
+		sb.WriteString(v.Review.View())
+	} else if v.ShowErrors {
+		sb.WriteString(v.ScanErrors.View())
+	} else if v.ShowInfo {
+		sb.WriteString(v.Info.View())
+	} else if v.ShowStats {
+		sb.WriteString(v.Stats.View())
+	} else {
+		sb.WriteString(v.TrackList.View())
+	}
 
 	// Help
 	sb.WriteString("\n\n")
 	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
-	if v.Searching {
-		sb.WriteString(helpStyle.Render("[Enter] Confirm  [Esc] Cancel"))
-	} else {
-		sb.WriteString(helpStyle.Render("[/] Search  [a] Add Files  [Enter] Play  [↑↓] Navigate"))
+	if v.Tagging {
+		sb.WriteString(v.TagInput.View())
+		sb.WriteString("\n")
+		sb.WriteString(helpStyle.Render(i18n.T("Comma-separated; prefix with - to remove  [Enter] Save  [Esc] Cancel")))
+	} else if v.Searching {
+		sb.WriteString(helpStyle.Render(i18n.T("[Enter] Confirm  [Esc] Cancel")))
+	} else if v.Confirming {
+		sb.WriteString(v.Confirm.View())
+	} else if v.TrackList.Marking {
+		status := i18n.T("%d marked", len(v.TrackList.MarkedItems()))
+		if v.TrackList.InVisual() {
+			status += " " + i18n.T("(visual)")
+		}
+		sb.WriteString(helpStyle.Render(status + "  " + i18n.T("[Space/m] Mark  [v] Range  [e] Enqueue  [P] Add to Playlist  [t] Edit Tags  [#] Tag  [M] Lookup  [A] Archive  [D] Remove  [^D] Delete Files  [Esc] Done")))
+	} else if v.ShowMenu {
+		sb.WriteString(helpStyle.Render(glyphs.Text(i18n.T("[Enter] Choose  [↑↓] Navigate  [Esc] Close"))))
+	} else if !v.Picking && !v.ShowGenres && !v.ShowSkipped && !v.ShowArchived && !v.Editing && !v.Reviewing && !v.ShowErrors && !v.ShowInfo && !v.ShowStats {
+		sb.WriteString(helpStyle.Render(glyphs.Text(i18n.T("[/] Search  [a] Add Files  [g] Genres  [F] Skipped  [Z] Archived  [T] Stats  [m/v] Mark  [e] Enqueue  [P] Add to Playlist  [t] Edit Tags  [#] Tag  [M] Fix Tags  [R] Rescan  [L] Play Album  [I] Play Artist  [X] Shuffle All  [i] Details  [.] Menu  [Enter] Play  [↑↓] Navigate"))))
 	}
 
 	return v.BorderStyle.Width(v.Width - 4).Render(sb.String())
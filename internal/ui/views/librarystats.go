@@ -0,0 +1,88 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// ShowLibraryStatsMsg asks the app for the library's totals
+type ShowLibraryStatsMsg struct{}
+
+// LibraryStats is an overlay with the size of the whole library: tracks,
+// albums, artists, hours of music, space on disk and the top genres
+type LibraryStats struct {
+	Summary library.Summary
+	Height  int
+	Width   int
+}
+
+// NewLibraryStats creates the overlay
+func NewLibraryStats(s library.Summary, width, height int) LibraryStats {
+	return LibraryStats{Summary: s, Width: width, Height: height}
+}
+
+// Update handles keys. done is true when the overlay should close.
+func (s LibraryStats) Update(msg tea.KeyMsg) (LibraryStats, tea.Cmd, bool) {
+	switch msg.String() {
+	case "esc", "q", "enter", "T":
+		return s, nil, true
+	}
+	return s, nil, false
+}
+
+// View renders the overlay
+func (s LibraryStats) View() string {
+	var sb strings.Builder
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	sum := s.Summary
+
+	sb.WriteString(titleStyle.Render(glyphs.Icon(glyphs.Stats) + i18n.T("Library")))
+	sb.WriteString("\n\n")
+	row := func(label, value string) {
+		sb.WriteString(labelStyle.Render(fmt.Sprintf("%-10s", label)))
+		sb.WriteString(" " + value + "\n")
+	}
+	row(i18n.T("Tracks"), fmt.Sprint(sum.Tracks))
+	row(i18n.T("Albums"), fmt.Sprint(sum.Albums))
+	row(i18n.T("Artists"), fmt.Sprint(sum.Artists))
+	hours := sum.Duration.Hours()
+	music := i18n.T("%.1f hours", hours)
+	if hours >= 48 {
+		music += " " + i18n.T("(%.1f days)", hours/24)
+	}
+	row(i18n.T("Music"), music)
+	row(i18n.T("On disk"), formatSize(sum.Size))
+	if sum.Archived > 0 {
+		row(i18n.T("Archived"), i18n.T("%d tracks, not counted", sum.Archived))
+	}
+
+	// Each genre line takes one row; the header above takes about ten
+	limit := max(s.Height-14, reportMaxRows)
+	if len(sum.Genres) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(titleStyle.Render(i18n.T("Top genres")))
+		sb.WriteString("\n")
+		top := sum.Genres[0].Tracks
+		for i, g := range sum.Genres {
+			if i == limit {
+				sb.WriteString(dim.Render("  " + glyphs.Text(i18n.T("… %d more", len(sum.Genres)-i))))
+				sb.WriteString("\n")
+				break
+			}
+			bar := max(g.Tracks*20/top, 1)
+			sb.WriteString(fmt.Sprintf("  %-16s %s %d\n", truncateLabel(g.Genre, 16),
+				lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Render(glyphs.Repeat(glyphs.Block, bar)), g.Tracks))
+		}
+	}
+	sb.WriteString("\n")
+	sb.WriteString(dim.Render(i18n.T("[Esc] Close")))
+	return sb.String()
+}
@@ -0,0 +1,122 @@
+package views
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/ui/components"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+	"github.com/mattn/go-runewidth"
+)
+
+// NowPlayingHeight is how many lines the now-playing bar takes
+const NowPlayingHeight = 3
+
+// NowPlayingBar is the compact strip at the bottom of every view but the
+// player: status, title and artist with the volume on one line, the
+// progress bar on the next
+type NowPlayingBar struct {
+	Width       int
+	State       *api.PlaybackState
+	ProgressBar components.ProgressBar
+
+	TitleStyle  lipgloss.Style
+	ArtistStyle lipgloss.Style
+	DimStyle    lipgloss.Style
+	BorderStyle lipgloss.Style
+}
+
+// NewNowPlayingBar creates a now-playing bar
+func NewNowPlayingBar(width int) NowPlayingBar {
+	return NowPlayingBar{
+		Width:       width,
+		ProgressBar: components.NewProgressBar(width - 2),
+		TitleStyle: lipgloss.NewStyle().
+			Bold(true).
+			Foreground(lipgloss.Color(defaultAccent)),
+		ArtistStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("86")),
+		DimStyle: lipgloss.NewStyle().
+			Foreground(lipgloss.Color("244")),
+		BorderStyle: lipgloss.NewStyle().
+			Border(glyphs.NormalBorder(), true, false, false, false).
+			BorderForeground(lipgloss.Color("240")).
+			Padding(0, 1),
+	}
+}
+
+// SetState updates the playback state
+func (b *NowPlayingBar) SetState(state *api.PlaybackState) {
+	b.State = state
+	if state != nil && state.CurrentTrack != nil {
+		b.ProgressBar.SetProgress(state.Position, state.CurrentTrack.Duration)
+	}
+}
+
+// SetAccent recolors the title and progress bar like the player view's;
+// an empty color restores the default
+func (b *NowPlayingBar) SetAccent(color string) {
+	if color == "" {
+		color = defaultAccent
+	}
+	accent := lipgloss.Color(color)
+	b.TitleStyle = b.TitleStyle.Foreground(accent)
+	b.ProgressBar.FilledStyle = b.ProgressBar.FilledStyle.Foreground(accent)
+	b.ProgressBar.HeadStyle = b.ProgressBar.HeadStyle.Foreground(accent)
+}
+
+// View renders the bar
+func (b *NowPlayingBar) View() string {
+	width := b.Width - 2
+	if b.State == nil || b.State.CurrentTrack == nil {
+		return b.BorderStyle.Width(b.Width).Render(b.DimStyle.Render(glyphs.Icon(glyphs.Note)+i18n.T("No track playing")) + "\n")
+	}
+	track := b.State.CurrentTrack
+
+	statusIcon := glyphs.Stopped.String()
+	switch b.State.Status {
+	case api.StatusPlaying:
+		statusIcon = glyphs.Playing.String()
+	case api.StatusPaused:
+		statusIcon = glyphs.Paused.String()
+	}
+	if b.State.Buffering {
+		statusIcon = glyphs.Buffering.String()
+	}
+
+	volume := fmt.Sprintf("%s%d%%", glyphs.Icon(glyphs.Volume), int(math.Round(b.State.Volume*100)))
+	if b.State.Muted {
+		volume = glyphs.Icon(glyphs.Muted) + i18n.T("Muted")
+	}
+
+	// Title and artist share what the icon and volume leave, the title
+	// getting the larger part when both are long
+	room := max(width-runewidth.StringWidth(volume)-4, 2)
+	title, artist := track.Title, track.Artist
+	switch {
+	case artist == "":
+		title = runewidth.Truncate(title, room, glyphs.Ellipsis.String())
+	case runewidth.StringWidth(title)+3+runewidth.StringWidth(artist) > room:
+		artistRoom := min(runewidth.StringWidth(artist), room/3)
+		artist = runewidth.Truncate(artist, artistRoom, glyphs.Ellipsis.String())
+		title = runewidth.Truncate(title, max(room-3-runewidth.StringWidth(artist), 1), glyphs.Ellipsis.String())
+	}
+	sep := " " + glyphs.Separator.String() + " "
+	text := title
+	if artist != "" {
+		text += sep + artist
+	}
+	gap := max(room-runewidth.StringWidth(text), 0) + 2
+
+	line := statusIcon + " " + b.TitleStyle.Render(title)
+	if artist != "" {
+		line += b.DimStyle.Render(sep) + b.ArtistStyle.Render(artist)
+	}
+	line += fmt.Sprintf("%*s", gap, "") + b.DimStyle.Render(volume)
+
+	b.ProgressBar.Width = width
+	return b.BorderStyle.Width(b.Width).Render(line + "\n" + b.ProgressBar.View())
+}
@@ -2,13 +2,17 @@ package views
 
 import (
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/audiobook"
+	"github.com/jscyril/golang_music_player/internal/i18n"
 	"github.com/jscyril/golang_music_player/internal/ui/components"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
 )
 
 // PlayerView displays the current playback state
@@ -18,6 +22,18 @@ type PlayerView struct {
 	State       *api.PlaybackState
 	ProgressBar components.ProgressBar
 
+	// ShowChapters expands the chapter list of the current track
+	ShowChapters bool
+
+	// UpNext is announced near the end of the current track; nil hides it
+	UpNext *api.Track
+
+	// Levels drives the left/right meters in the header; nil hides them
+	Levels *api.Levels
+
+	// scrubTrack is the track being scrubbed, empty when not scrubbing
+	scrubTrack string
+
 	// Styles
 	TitleStyle    lipgloss.Style
 	ArtistStyle   lipgloss.Style
@@ -35,7 +51,7 @@ func NewPlayerView(width, height int) PlayerView {
 		ProgressBar: components.NewProgressBar(width - 4),
 		TitleStyle: lipgloss.NewStyle().
 			Bold(true).
-			Foreground(lipgloss.Color("212")).
+			Foreground(lipgloss.Color(defaultAccent)).
 			MarginBottom(1),
 		ArtistStyle: lipgloss.NewStyle().
 			Foreground(lipgloss.Color("86")),
@@ -49,22 +65,119 @@ func NewPlayerView(width, height int) PlayerView {
 			Foreground(lipgloss.Color("240")).
 			MarginTop(1),
 		BorderStyle: lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
+			Border(glyphs.RoundedBorder()).
 			BorderForeground(lipgloss.Color("62")).
 			Padding(1, 2),
 	}
 }
 
-// SetState updates the playback state
+// defaultAccent is the player's accent color when no album-art accent is set
+const defaultAccent = "212"
+
+// SetAccent recolors the title, border and progress bar; an empty color
+// restores the defaults
+func (v *PlayerView) SetAccent(color string) {
+	border := "62"
+	if color == "" {
+		color = defaultAccent
+	} else {
+		border = color
+	}
+	accent := lipgloss.Color(color)
+	v.TitleStyle = v.TitleStyle.Foreground(accent)
+	v.BorderStyle = v.BorderStyle.BorderForeground(lipgloss.Color(border))
+	v.ProgressBar.FilledStyle = v.ProgressBar.FilledStyle.Foreground(accent)
+	v.ProgressBar.HeadStyle = v.ProgressBar.HeadStyle.Foreground(accent)
+}
+
+// SeekMsg asks the app to seek the current track to Position
+type SeekMsg struct {
+	Position time.Duration
+}
+
+// SetState updates the playback state. A track change ends scrubbing.
 func (v *PlayerView) SetState(state *api.PlaybackState) {
 	v.State = state
 	if state != nil && state.CurrentTrack != nil {
 		v.ProgressBar.SetProgress(state.Position, state.CurrentTrack.Duration)
 	}
+	if v.Scrubbing() && (state == nil || state.CurrentTrack == nil || state.CurrentTrack.ID != v.scrubTrack) {
+		v.stopScrub()
+	}
+}
+
+// SetBookmarks ticks the bookmarks of the current track on the progress bar
+func (v *PlayerView) SetBookmarks(marks []audiobook.Bookmark) {
+	v.ProgressBar.Marks = v.ProgressBar.Marks[:0]
+	for _, m := range marks {
+		v.ProgressBar.Marks = append(v.ProgressBar.Marks, m.Position)
+	}
+}
+
+// SetLevels shows the live output levels in the header meters
+func (v *PlayerView) SetLevels(l api.Levels) {
+	v.Levels = &l
+}
+
+// ClearLevels hides the header meters
+func (v *PlayerView) ClearLevels() {
+	v.Levels = nil
+}
+
+// Scrubbing reports whether the scrub cursor is shown; Enter and Esc then
+// belong to the player view
+func (v PlayerView) Scrubbing() bool {
+	return v.scrubTrack != ""
+}
+
+// scrubStep is how far one scrub key press moves the cursor: a hundredth
+// of the track, but at least a second
+func scrubStep(total time.Duration) time.Duration {
+	return max(total/100, time.Second)
+}
+
+// scrub moves the scrub cursor by delta, starting it at the playback
+// position if it is not shown yet
+func (v *PlayerView) scrub(delta time.Duration) {
+	if v.State == nil || v.State.CurrentTrack == nil || v.State.CurrentTrack.Duration <= 0 {
+		return
+	}
+	from := v.ProgressBar.Preview
+	if !v.Scrubbing() {
+		from = v.ProgressBar.Current
+		v.scrubTrack = v.State.CurrentTrack.ID
+	}
+	v.ProgressBar.SetPreview(from + delta)
+}
+
+// stopScrub hides the scrub cursor
+func (v *PlayerView) stopScrub() {
+	v.scrubTrack = ""
+	v.ProgressBar.ClearPreview()
 }
 
 // Update handles messages
 func (v PlayerView) Update(msg tea.Msg) (PlayerView, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return v, nil
+	}
+	switch keyMsg.String() {
+	case "c":
+		v.ShowChapters = !v.ShowChapters
+	case ",":
+		v.scrub(-scrubStep(v.ProgressBar.Total))
+	case ".":
+		v.scrub(scrubStep(v.ProgressBar.Total))
+	case "enter":
+		if v.Scrubbing() {
+			pos := v.ProgressBar.Preview
+			v.stopScrub()
+			return v, func() tea.Msg { return SeekMsg{Position: pos} }
+		}
+	case "esc":
+		v.stopScrub()
+	}
 	return v, nil
 }
 
@@ -87,9 +200,9 @@ func (v *PlayerView) View() string {
 	var sb strings.Builder
 
 	if v.State == nil || v.State.CurrentTrack == nil {
-		sb.WriteString(v.TitleStyle.Render("♪ No track playing"))
+		sb.WriteString(v.TitleStyle.Render(glyphs.Icon(glyphs.Note) + i18n.T("No track playing")))
 		sb.WriteString("\n\n")
-		sb.WriteString(v.ControlsStyle.Render("Press Enter on a track to play"))
+		sb.WriteString(v.ControlsStyle.Render(i18n.T("Press Enter on a track to play")))
 	} else {
 		track := v.State.CurrentTrack
 
@@ -97,16 +210,22 @@ func (v *PlayerView) View() string {
 		var statusIcon string
 		switch v.State.Status {
 		case api.StatusPlaying:
-			statusIcon = "▶"
+			statusIcon = glyphs.Playing.String()
 		case api.StatusPaused:
-			statusIcon = "⏸"
+			statusIcon = glyphs.Paused.String()
 		default:
-			statusIcon = "⏹"
+			statusIcon = glyphs.Stopped.String()
 		}
 
 		// Track info
 		sb.WriteString(v.StatusStyle.Render(statusIcon + " "))
 		sb.WriteString(v.TitleStyle.Render(track.Title))
+		if v.Levels != nil {
+			sb.WriteString("  ")
+			sb.WriteString(renderMeter("L", v.Levels.RMSL, v.Levels.PeakL))
+			sb.WriteString(" ")
+			sb.WriteString(renderMeter("R", v.Levels.RMSR, v.Levels.PeakR))
+		}
 		sb.WriteString("\n")
 		sb.WriteString(v.ArtistStyle.Render(track.Artist))
 		sb.WriteString("\n")
@@ -115,37 +234,191 @@ func (v *PlayerView) View() string {
 
 		// Progress bar
 		sb.WriteString(v.ProgressBar.View())
-		sb.WriteString("\n\n")
+		if v.State.Buffering {
+			sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render("  " + glyphs.Icon(glyphs.Buffering) + i18n.T("Buffering")))
+		}
+		if v.Scrubbing() {
+			sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("244")).Render("  " + i18n.T("enter: seek  esc: cancel")))
+		}
+		sb.WriteString("\n")
+		if len(track.Chapters) > 0 {
+			sb.WriteString(v.renderChapters(track.Chapters))
+		}
+		sb.WriteString("\n")
 
 		// Volume
-		volumeBar := renderVolumeBar(v.State.Volume)
-		sb.WriteString(fmt.Sprintf("Volume: %s %d%%", volumeBar, int(v.State.Volume*100)))
+		if v.State.Muted {
+			sb.WriteString(i18n.T("Volume: ") + lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render(
+				glyphs.Icon(glyphs.Muted)+i18n.T("Muted (%d%%)", int(math.Round(v.State.Volume*100)))))
+		} else {
+			volumeBar := renderVolumeBar(v.State.Volume)
+			sb.WriteString(fmt.Sprintf("%s%s %d%% %s", i18n.T("Volume: "), volumeBar, int(math.Round(v.State.Volume*100)), formatVolumeDB(v.State)))
+		}
+		if v.State.DuckDB > 0 {
+			sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render(
+				i18n.T(" (ducked -%.0f dB)", v.State.DuckDB)))
+		}
+		if v.State.Output != "" {
+			output := "  " + i18n.T("Output: ") + v.State.Output
+			if v.State.OutputTrim != "" {
+				output += " (" + v.State.OutputTrim + ")"
+			}
+			sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("244")).Render(output))
+		}
+		sb.WriteString("\n")
+		sb.WriteString(v.renderLevel())
 		sb.WriteString("\n")
 
-		// Repeat/Shuffle status
+		// Repeat/Shuffle/Consume/Party status
 		var modes []string
 		switch v.State.Repeat {
 		case api.RepeatOne:
-			modes = append(modes, "🔂 Repeat One")
+			modes = append(modes, glyphs.Icon(glyphs.RepeatOne)+i18n.T("Repeat One"))
 		case api.RepeatAll:
-			modes = append(modes, "🔁 Repeat All")
+			modes = append(modes, glyphs.Icon(glyphs.RepeatAll)+i18n.T("Repeat All"))
 		}
 		if v.State.Shuffle {
-			modes = append(modes, "🔀 Shuffle")
+			modes = append(modes, glyphs.Icon(glyphs.Shuffle)+i18n.T("Shuffle"))
+		}
+		if v.State.Consume {
+			modes = append(modes, glyphs.Icon(glyphs.Consume)+i18n.T("Consume"))
+		}
+		if v.State.Party {
+			modes = append(modes, glyphs.Icon(glyphs.Party)+i18n.T("Party"))
 		}
 		if len(modes) > 0 {
 			sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("244")).Render(strings.Join(modes, " | ")))
 		}
+		if next := v.UpNext; next != nil {
+			name := next.Title
+			if next.Artist != "" {
+				name = next.Artist + " " + glyphs.Dash.String() + " " + next.Title
+			}
+			sb.WriteString("\n")
+			sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Italic(true).Render(i18n.T("Up next: ") + name))
+		}
 	}
 
 	sb.WriteString("\n\n")
-	sb.WriteString(v.ControlsStyle.Render(
-		"[Space] Play/Pause  [s] Stop  [n] Next  [p] Prev  [←/→] Seek ±5s  [+/-] Volume  [q] Quit",
-	))
+	sb.WriteString(v.ControlsStyle.Render(glyphs.Text(i18n.T(
+		"[Space] Play/Pause  [s] Stop  [n] Next  [p] Prev  [←/→] Seek ±5s  [[/]] Chapter  [{/}] Bookmark  [c] Chapters  [=/-] Volume [+/_] Fine [m] Mute  [o] Output  [q] Quit",
+	))))
 
 	return v.BorderStyle.Width(v.Width - 4).Render(sb.String())
 }
 
+// chapterListSize is how many chapters the expanded list shows at once
+const chapterListSize = 7
+
+// renderChapters renders the current chapter line and, when expanded, a
+// window of the chapter list around the current chapter
+func (v *PlayerView) renderChapters(chapters []api.Chapter) string {
+	muted := lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	current := audiobook.ChapterAt(chapters, v.State.Position)
+
+	var sb strings.Builder
+	if current >= 0 {
+		sb.WriteString(muted.Render(i18n.T("Chapter %d/%d: ", current+1, len(chapters))))
+		sb.WriteString(v.ArtistStyle.Render(chapters[current].Title))
+	} else {
+		sb.WriteString(muted.Render(i18n.T("%d chapters", len(chapters))))
+	}
+	sb.WriteString("\n")
+
+	if !v.ShowChapters {
+		return sb.String()
+	}
+	start := current - chapterListSize/2
+	if start > len(chapters)-chapterListSize {
+		start = len(chapters) - chapterListSize
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := min(start+chapterListSize, len(chapters))
+	for i := start; i < end; i++ {
+		line := fmt.Sprintf("  %2d. %s  %s", i+1, formatChapterTime(chapters[i].Start), chapters[i].Title)
+		if i == current {
+			sb.WriteString(v.StatusStyle.Render(glyphs.Pointer.String() + line[1:]))
+		} else {
+			sb.WriteString(muted.Render(line))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// renderLevel shows the gain staging: the net gain applied to the track,
+// the recent output peak and whether it clips or is being limited
+// meterCells is the width of one channel meter, covering meterRangeDB
+const (
+	meterCells   = 12
+	meterRangeDB = 48.0
+)
+
+// renderMeter draws one channel: RMS as a filled bar, colored by how hot
+// it runs, and the falling peak as a tick
+func renderMeter(label string, rmsDB, peakDB float64) string {
+	cell := func(db float64) int {
+		return int(math.Round((db + meterRangeDB) / meterRangeDB * meterCells))
+	}
+	rms, peak := cell(rmsDB), min(cell(peakDB), meterCells)-1
+	color := "42"
+	switch {
+	case peakDB > -1:
+		color = "196"
+	case peakDB > -9:
+		color = "214"
+	}
+	fill := lipgloss.NewStyle().Foreground(lipgloss.Color(color))
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("238"))
+
+	var sb strings.Builder
+	sb.WriteString(dim.Render(label + " "))
+	for i := range meterCells {
+		switch {
+		case i < rms:
+			sb.WriteString(fill.Render(glyphs.LevelOn.String()))
+		case i == peak:
+			sb.WriteString(fill.Render("|"))
+		default:
+			sb.WriteString(dim.Render(glyphs.LevelOff.String()))
+		}
+	}
+	return sb.String()
+}
+
+func (v PlayerView) renderLevel() string {
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	line := dim.Render(i18n.T("Gain: %+.1f dB  Peak: %.1f dBFS", v.State.GainDB, v.State.PeakDB))
+	switch {
+	case v.State.Clipping:
+		line += lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true).Render("  " + glyphs.Dot.String() + " " + i18n.T("CLIP"))
+	case v.State.Limiting:
+		line += lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render("  " + glyphs.Diamond.String() + " " + i18n.T("Limiting"))
+	case v.State.Limiter:
+		line += dim.Render("  " + i18n.T("Limiter on"))
+	}
+	return line
+}
+
+// formatChapterTime formats a chapter offset as h:mm:ss or m:ss
+func formatChapterTime(d time.Duration) string {
+	secs := int(d.Seconds())
+	if secs >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", secs/3600, secs/60%60, secs%60)
+	}
+	return fmt.Sprintf("%d:%02d", secs/60, secs%60)
+}
+
+// formatVolumeDB shows the gain of the volume level, or that it is silent
+func formatVolumeDB(state *api.PlaybackState) string {
+	if state.Volume <= 0 {
+		return i18n.T("(silent)")
+	}
+	return fmt.Sprintf("(%+.1f dB)", state.VolumeDB)
+}
+
 // renderVolumeBar renders a volume bar
 func renderVolumeBar(volume float64) string {
 	filled := int(volume * 10)
@@ -154,5 +427,5 @@ func renderVolumeBar(volume float64) string {
 	filledStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
 	emptyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 
-	return filledStyle.Render(strings.Repeat("●", filled)) + emptyStyle.Render(strings.Repeat("○", empty))
+	return filledStyle.Render(glyphs.Repeat(glyphs.Dot, filled)) + emptyStyle.Render(glyphs.Repeat(glyphs.Ring, empty))
 }
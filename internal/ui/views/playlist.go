@@ -1,12 +1,79 @@
 package views
 
 import (
+	"fmt"
+	"sort"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/playlist"
 	"github.com/jscyril/golang_music_player/internal/ui/components"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+	"github.com/mattn/go-runewidth"
+)
+
+// PlaylistCreateMsg asks the app to create a playlist
+type PlaylistCreateMsg struct {
+	Name string
+}
+
+// PlaylistUpdateMsg asks the app to rename or re-describe a playlist
+type PlaylistUpdateMsg struct {
+	ID          string
+	Name        string
+	Description string
+}
+
+// PlaylistMoveTrackMsg asks the app to move a track within a playlist
+type PlaylistMoveTrackMsg struct {
+	ID   string
+	From int
+	To   int
+}
+
+// PlaylistImportMsg asks the app to import an M3U/PLS file
+type PlaylistImportMsg struct {
+	Path string
+}
+
+// PlaylistExportMsg asks the app to export a playlist to an M3U/PLS file
+type PlaylistExportMsg struct {
+	ID   string
+	Path string
+}
+
+// PlaylistStatsMsg asks the app for statistics on a playlist
+type PlaylistStatsMsg struct {
+	ID string
+}
+
+// PlaylistDedupeMsg asks the app to remove the repeated tracks of a playlist
+type PlaylistDedupeMsg struct {
+	ID string
+}
+
+// PlaylistOverlapMsg asks the app for the cross-playlist overlap report
+type PlaylistOverlapMsg struct{}
+
+// PlaylistDeleteMsg asks the app to delete a playlist
+type PlaylistDeleteMsg struct {
+	ID string
+}
+
+// playlistPrompt is the input the view is currently collecting
+type playlistPrompt int
+
+const (
+	promptNone playlistPrompt = iota
+	promptCreate
+	promptRename
+	promptDescribe
+	promptImport
+	promptExport
+	promptDelete
 )
 
 // PlaylistView displays playlist management
@@ -18,14 +85,24 @@ type PlaylistView struct {
 	Current     *api.Playlist
 	ShowingList bool // true = showing playlists, false = showing tracks
 	Selected    int
+	Input       components.SearchInput
+	prompt      playlistPrompt
+	Confirm     components.Confirm
+	SkipConfirm map[string]bool // confirmations turned off with "don't ask again"
+	Report      string          // stats or overlap report shown in place of the list; empty when closed
+	Services    []string        // streaming services playlists can be exported to
+	Sharing     bool            // true when the streaming export review is open
+	Share       ShareReview
+	picking     bool // choosing the streaming service to export to
+	serviceMenu components.Menu
 	BorderStyle lipgloss.Style
 	TitleStyle  lipgloss.Style
 }
 
 // NewPlaylistView creates a new playlist view
 func NewPlaylistView(width, height int) PlaylistView {
-	trackList := components.NewTrackList(height-8, width-6)
-	trackList.Title = "📋 Playlist"
+	trackList := components.NewTrackList(height-8, width-8)
+	trackList.Title = glyphs.Icon(glyphs.Playlist) + i18n.T("Playlist")
 
 	return PlaylistView{
 		Width:       width,
@@ -33,8 +110,9 @@ func NewPlaylistView(width, height int) PlaylistView {
 		TrackList:   trackList,
 		Playlists:   make([]*api.Playlist, 0),
 		ShowingList: true,
+		Input:       components.NewSearchInput(width - 6),
 		BorderStyle: lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
+			Border(glyphs.RoundedBorder()).
 			BorderForeground(lipgloss.Color("62")).
 			Padding(1, 2),
 		TitleStyle: lipgloss.NewStyle().
@@ -43,22 +121,216 @@ func NewPlaylistView(width, height int) PlaylistView {
 	}
 }
 
-// SetPlaylists sets the available playlists
+// SetPlaylists sets the available playlists, sorted by name
 func (v *PlaylistView) SetPlaylists(playlists []*api.Playlist) {
-	v.Playlists = playlists
+	sorted := make([]*api.Playlist, len(playlists))
+	copy(sorted, playlists)
+	sort.Slice(sorted, func(i, j int) bool {
+		return strings.ToLower(sorted[i].Name) < strings.ToLower(sorted[j].Name)
+	})
+	v.Playlists = sorted
+	if v.Selected >= len(v.Playlists) {
+		v.Selected = len(v.Playlists) - 1
+	}
+	if v.Selected < 0 {
+		v.Selected = 0
+	}
+}
+
+// RefreshCurrent reloads the open playlist's tracks, keeping the cursor
+func (v *PlaylistView) RefreshCurrent(playlist *api.Playlist) {
+	if v.ShowingList || v.Current == nil || playlist == nil || playlist.ID != v.Current.ID {
+		return
+	}
+	selected := v.TrackList.Selected
+	v.SetCurrentPlaylist(playlist)
+	v.TrackList.Select(selected)
+}
+
+// reportMaxRows caps the rows listed per report section
+const reportMaxRows = 8
+
+// ShowStats opens a report with a playlist's duration, genres and decades
+func (v *PlaylistView) ShowStats(name string, st playlist.Stats) {
+	var sb strings.Builder
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	sb.WriteString(v.TitleStyle.Render(glyphs.Icon(glyphs.Stats) + name))
+	sb.WriteString("\n\n")
+	hours := int(st.Duration.Hours())
+	mins := int(st.Duration.Minutes()) % 60
+	sb.WriteString(glyphs.Text(i18n.T("%d tracks  ·  %dh %02dm  ·  %d artists  ·  %d albums", st.TrackCount, hours, mins, st.Artists, st.Albums)) + "\n")
+
+	section := func(title string, counts []playlist.Count) {
+		sb.WriteString("\n")
+		sb.WriteString(v.TitleStyle.Render(title))
+		sb.WriteString("\n")
+		for i, c := range counts {
+			if i == reportMaxRows {
+				sb.WriteString(dim.Render("  " + glyphs.Text(i18n.T("… %d more", len(counts)-i))))
+				sb.WriteString("\n")
+				break
+			}
+			bar := 0
+			if st.TrackCount > 0 {
+				bar = c.Count * 20 / st.TrackCount
+			}
+			sb.WriteString(fmt.Sprintf("  %-16s %s %d\n", truncateLabel(c.Label, 16),
+				lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Render(glyphs.Repeat(glyphs.Block, bar)), c.Count))
+		}
+	}
+	section(i18n.T("Genres"), st.Genres)
+	section(i18n.T("Decades"), st.Decades)
+
+	v.Report = strings.TrimRight(sb.String(), "\n")
+}
+
+// ShowOverlaps opens a report of tracks shared between playlists
+func (v *PlaylistView) ShowOverlaps(overlaps []playlist.Overlap) {
+	var sb strings.Builder
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	sb.WriteString(v.TitleStyle.Render(glyphs.Icon(glyphs.RepeatAll) + i18n.T("Tracks in multiple playlists")))
+	sb.WriteString("\n\n")
+	if len(overlaps) == 0 {
+		sb.WriteString(dim.Render(glyphs.Text(i18n.T("No overlap — every track appears in only one playlist"))))
+	}
+	limit := v.Height - 10
+	if limit < reportMaxRows {
+		limit = reportMaxRows
+	}
+	for i, o := range overlaps {
+		if i == limit {
+			sb.WriteString(dim.Render(glyphs.Text(i18n.T("… %d more", len(overlaps)-i))))
+			break
+		}
+		sb.WriteString(fmt.Sprintf("%s - %s\n", o.Track.Artist, o.Track.Title))
+		sb.WriteString(dim.Render("    " + i18n.T("in %s", strings.Join(o.Playlists, ", "))))
+		sb.WriteString("\n")
+	}
+
+	v.Report = strings.TrimRight(sb.String(), "\n")
+}
+
+// truncateLabel shortens s to at most n terminal cells, counting wide
+// characters as two
+func truncateLabel(s string, n int) string {
+	return runewidth.Truncate(s, n, glyphs.Ellipsis.String())
+}
+
+// OpenShare shows the streaming export review of pl while its tracks are
+// looked up on service
+func (v *PlaylistView) OpenShare(service string, pl *api.Playlist) {
+	v.Sharing = true
+	v.Share = NewShareReview(service, pl.Name, pl.Description, v.Width-8, v.Height-4)
+}
+
+// SelectByID moves the list cursor to the playlist with the given ID
+func (v *PlaylistView) SelectByID(id string) {
+	for i, pl := range v.Playlists {
+		if pl.ID == id {
+			v.Selected = i
+			return
+		}
+	}
+}
+
+// Prompting reports whether the view is collecting text or a confirmation
+func (v *PlaylistView) Prompting() bool {
+	return v.prompt != promptNone
+}
+
+// HandlesKey reports whether the view wants a key that would otherwise be
+// a global binding (e.g. "r" renames here instead of cycling repeat)
+func (v *PlaylistView) HandlesKey(key string) bool {
+	if v.Prompting() || v.Report != "" || v.Sharing || v.picking {
+		return true
+	}
+	if !v.ShowingList {
+		return false
+	}
+	switch key {
+	case "c", "r", "e", "d", "i", "x", "I", "O", "u", "s":
+		return true
+	}
+	return false
+}
+
+// startPrompt opens the text prompt with an initial value
+func (v *PlaylistView) startPrompt(p playlistPrompt, placeholder, value string) {
+	v.prompt = p
+	v.Input = components.NewSearchInput(v.Width - 6)
+	v.Input.Prompt = glyphs.Icon(glyphs.Edit)
+	v.Input.Placeholder = placeholder
+	v.Input.SetValue(value)
+	v.Input.Focus()
+}
+
+// updatePrompt handles keys while a prompt is open
+func (v PlaylistView) updatePrompt(msg tea.KeyMsg) (PlaylistView, tea.Cmd) {
+	prompt := v.prompt
+	pl := v.SelectedPlaylist()
+
+	if prompt == promptDelete {
+		var result components.ConfirmResult
+		v.Confirm, result = v.Confirm.Update(msg)
+		if !result.Done {
+			return v, nil
+		}
+		v.prompt = promptNone
+		if pl == nil {
+			return v, nil
+		}
+		id := pl.ID
+		return v, result.Then(func() tea.Msg { return PlaylistDeleteMsg{ID: id} })
+	}
+
+	switch msg.String() {
+	case "esc":
+		v.prompt = promptNone
+		v.Input.Blur()
+		return v, nil
+	case "enter":
+		value := strings.TrimSpace(v.Input.Value)
+		v.prompt = promptNone
+		v.Input.Blur()
+		switch {
+		case prompt == promptCreate && value != "":
+			return v, func() tea.Msg { return PlaylistCreateMsg{Name: value} }
+		case prompt == promptRename && value != "" && pl != nil:
+			id, desc := pl.ID, pl.Description
+			return v, func() tea.Msg { return PlaylistUpdateMsg{ID: id, Name: value, Description: desc} }
+		case prompt == promptDescribe && pl != nil:
+			id, name := pl.ID, pl.Name
+			return v, func() tea.Msg { return PlaylistUpdateMsg{ID: id, Name: name, Description: value} }
+		case prompt == promptImport && value != "":
+			return v, func() tea.Msg { return PlaylistImportMsg{Path: value} }
+		case prompt == promptExport && value != "" && pl != nil:
+			id := pl.ID
+			return v, func() tea.Msg { return PlaylistExportMsg{ID: id, Path: value} }
+		}
+		return v, nil
+	default:
+		v.Input, _ = v.Input.Update(msg)
+	}
+	return v, nil
 }
 
 // SetCurrentPlaylist sets the current playlist to display
-func (v *PlaylistView) SetCurrentPlaylist(playlist *api.Playlist) {
-	v.Current = playlist
+func (v *PlaylistView) SetCurrentPlaylist(pl *api.Playlist) {
+	v.Current = pl
 	v.ShowingList = false
-	if playlist != nil {
-		tracks := make([]*api.Track, len(playlist.Tracks))
-		for i := range playlist.Tracks {
-			tracks[i] = &playlist.Tracks[i]
+	if pl != nil {
+		tracks := make([]*api.Track, len(pl.Tracks))
+		for i := range pl.Tracks {
+			tracks[i] = &pl.Tracks[i]
 		}
 		v.TrackList.SetItems(tracks)
-		v.TrackList.Title = "📋 " + playlist.Name
+		v.TrackList.Title = glyphs.Icon(glyphs.Playlist) + pl.Name
+		v.TrackList.Summary = ""
+		if len(tracks) > 0 {
+			v.TrackList.Summary = playlist.TrackStats(tracks).Summary()
+		}
 	}
 }
 
@@ -66,8 +338,92 @@ func (v *PlaylistView) SetCurrentPlaylist(playlist *api.Playlist) {
 func (v PlaylistView) Update(msg tea.Msg) (PlaylistView, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if v.Sharing {
+			var cmd tea.Cmd
+			var done bool
+			v.Share, cmd, done = v.Share.Update(msg)
+			if done {
+				v.Sharing = false
+			}
+			return v, cmd
+		}
+		if v.picking {
+			var result components.MenuResult
+			v.serviceMenu, result = v.serviceMenu.Update(msg)
+			if result.Done {
+				v.picking = false
+				if pl := v.SelectedPlaylist(); pl != nil && !result.Cancelled {
+					share := PlaylistShareMsg{ID: pl.ID, Service: result.ID}
+					return v, func() tea.Msg { return share }
+				}
+			}
+			return v, nil
+		}
+		if v.Prompting() {
+			return v.updatePrompt(msg)
+		}
+		if v.Report != "" {
+			// Any key closes the report
+			v.Report = ""
+			return v, nil
+		}
 		if v.ShowingList {
 			switch msg.String() {
+			case "c":
+				v.startPrompt(promptCreate, i18n.T("New playlist name"), "")
+			case "r":
+				if pl := v.SelectedPlaylist(); pl != nil {
+					v.startPrompt(promptRename, i18n.T("Playlist name"), pl.Name)
+				}
+			case "e":
+				if pl := v.SelectedPlaylist(); pl != nil {
+					v.startPrompt(promptDescribe, i18n.T("Description"), pl.Description)
+				}
+			case "d":
+				if pl := v.SelectedPlaylist(); pl != nil {
+					if v.SkipConfirm[components.ConfirmDeletePlaylist] {
+						id := pl.ID
+						return v, func() tea.Msg { return PlaylistDeleteMsg{ID: id} }
+					}
+					v.prompt = promptDelete
+					v.Confirm = components.NewConfirm(components.ConfirmDeletePlaylist,
+						i18n.T("Delete playlist %q?", pl.Name))
+				}
+			case "I":
+				if pl := v.SelectedPlaylist(); pl != nil {
+					id := pl.ID
+					return v, func() tea.Msg { return PlaylistStatsMsg{ID: id} }
+				}
+			case "O":
+				return v, func() tea.Msg { return PlaylistOverlapMsg{} }
+			case "u":
+				if pl := v.SelectedPlaylist(); pl != nil {
+					id := pl.ID
+					return v, func() tea.Msg { return PlaylistDedupeMsg{ID: id} }
+				}
+			case "s":
+				if pl := v.SelectedPlaylist(); pl != nil {
+					if len(v.Services) > 1 {
+						var items []components.MenuItem
+						for i, name := range v.Services {
+							items = append(items, components.MenuItem{ID: name, Label: name, Key: fmt.Sprint(i + 1)})
+						}
+						v.serviceMenu = components.NewMenu(i18n.T("Export to"), items)
+						v.picking = true
+						return v, nil
+					}
+					share := PlaylistShareMsg{ID: pl.ID}
+					if len(v.Services) == 1 {
+						share.Service = v.Services[0]
+					}
+					return v, func() tea.Msg { return share }
+				}
+			case "i":
+				v.startPrompt(promptImport, i18n.T("Path to .m3u, .m3u8, .pls or .xspf"), "")
+			case "x":
+				if pl := v.SelectedPlaylist(); pl != nil {
+					v.startPrompt(promptExport, i18n.T("Export to .m3u, .m3u8, .pls or .xspf"), pl.Name+".m3u8")
+				}
 			case "up", "k":
 				if v.Selected > 0 {
 					v.Selected--
@@ -87,6 +443,24 @@ func (v PlaylistView) Update(msg tea.Msg) (PlaylistView, tea.Cmd) {
 				v.ShowingList = true
 				v.Current = nil
 				return v, nil
+			case "shift+up", "K":
+				selected := v.TrackList.Selected
+				if v.Current != nil && selected > 0 {
+					id := v.Current.ID
+					v.TrackList.Select(selected - 1)
+					return v, func() tea.Msg {
+						return PlaylistMoveTrackMsg{ID: id, From: selected, To: selected - 1}
+					}
+				}
+			case "shift+down", "J":
+				selected := v.TrackList.Selected
+				if v.Current != nil && selected < len(v.TrackList.Items)-1 {
+					id := v.Current.ID
+					v.TrackList.Select(selected + 1)
+					return v, func() tea.Msg {
+						return PlaylistMoveTrackMsg{ID: id, From: selected, To: selected + 1}
+					}
+				}
 			default:
 				v.TrackList, _ = v.TrackList.Update(msg)
 			}
@@ -115,13 +489,23 @@ func (v *PlaylistView) SelectedPlaylist() *api.Playlist {
 func (v PlaylistView) View() string {
 	var sb strings.Builder
 
+	if v.Sharing {
+		return v.BorderStyle.Width(v.Width - 4).Render(v.Share.View())
+	}
+	if v.Report != "" {
+		sb.WriteString(v.Report)
+		sb.WriteString("\n\n")
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(i18n.T("[any key] Close")))
+		return v.BorderStyle.Width(v.Width - 4).Render(sb.String())
+	}
+
 	if v.ShowingList {
 		// Show playlist list
-		sb.WriteString(v.TitleStyle.Render("📋 Playlists"))
+		sb.WriteString(v.TitleStyle.Render(glyphs.Icon(glyphs.Playlist) + i18n.T("Playlists")))
 		sb.WriteString("\n\n")
 
 		if len(v.Playlists) == 0 {
-			sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render("No playlists yet"))
+			sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(i18n.T("No playlists yet")))
 		} else {
 			selectedStyle := lipgloss.NewStyle().
 				Background(lipgloss.Color("62")).
@@ -136,7 +520,7 @@ func (v PlaylistView) View() string {
 					line += " - " + pl.Description
 				}
 				line += lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(
-					" (" + string(rune('0'+len(pl.Tracks))) + " tracks)")
+					" " + i18n.T("(%d tracks)", len(pl.Tracks)))
 
 				if i == v.Selected {
 					sb.WriteString(selectedStyle.Render(line))
@@ -148,14 +532,26 @@ func (v PlaylistView) View() string {
 		}
 
 		sb.WriteString("\n")
-		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(
-			"[Enter] Open  [↑↓] Navigate"))
+		helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+		switch {
+		case v.picking:
+			sb.WriteString(v.serviceMenu.View())
+		case v.prompt == promptDelete:
+			sb.WriteString(v.Confirm.View())
+		case v.Prompting():
+			sb.WriteString(v.Input.View())
+			sb.WriteString("\n")
+			sb.WriteString(helpStyle.Render(i18n.T("[Enter] Save  [Esc] Cancel")))
+		default:
+			sb.WriteString(helpStyle.Render(
+				glyphs.Text(i18n.T("[Enter] Open  [c] New  [r] Rename  [e] Description  [d] Delete  [i] Import  [x] Export  [I] Stats  [O] Overlap  [u] Dedupe  [s] Share  [↑↓] Navigate"))))
+		}
 	} else {
 		// Show playlist tracks
 		sb.WriteString(v.TrackList.View())
 		sb.WriteString("\n\n")
 		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("240")).Render(
-			"[Backspace/Esc] Back  [Enter] Play  [↑↓] Navigate"))
+			glyphs.Text(i18n.T("[Backspace/Esc] Back  [Enter] Play  [Shift+↑↓/K/J] Move  [↑↓] Navigate"))))
 	}
 
 	return v.BorderStyle.Width(v.Width - 4).Render(sb.String())
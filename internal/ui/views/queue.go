@@ -0,0 +1,200 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/playlist"
+	"github.com/jscyril/golang_music_player/internal/ui/components"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// QueueMoveMsg asks the app to move a queue entry
+type QueueMoveMsg struct {
+	From int
+	To   int
+}
+
+// QueueRemoveMsg asks the app to remove a queue entry
+type QueueRemoveMsg struct {
+	Index int
+}
+
+// ShowSavedQueuesMsg asks the app for the saved queues, to save the queue
+// under one of their names (Save) or to load one
+type ShowSavedQueuesMsg struct {
+	Save bool
+}
+
+// SaveQueueMsg asks the app to save the queue under Name
+type SaveQueueMsg struct {
+	Name string
+}
+
+// LoadQueueMsg asks the app to replace the queue with the one saved as Name
+type LoadQueueMsg struct {
+	Name string
+}
+
+// QueueView displays the playback queue
+type QueueView struct {
+	Width       int
+	Height      int
+	TrackList   components.TrackList
+	Current     int
+	SavePoint   string // description of the queue save point, empty if none
+	Picking     bool   // True when the saved queue picker is open
+	Saving      bool   // whether the picker saves the queue or loads one
+	Picker      components.PlaylistPicker
+	BorderStyle lipgloss.Style
+}
+
+// NewQueueView creates a new queue view
+func NewQueueView(width, height int) QueueView {
+	trackList := components.NewTrackList(height-8, width-8)
+	trackList.Title = glyphs.Icon(glyphs.Queue) + i18n.T("Queue")
+
+	return QueueView{
+		Width:     width,
+		Height:    height,
+		TrackList: trackList,
+		Current:   -1,
+		BorderStyle: lipgloss.NewStyle().
+			Border(glyphs.RoundedBorder()).
+			BorderForeground(lipgloss.Color("62")).
+			Padding(1, 2),
+	}
+}
+
+// SetQueue refreshes the displayed queue, keeping the selection in place
+func (v *QueueView) SetQueue(tracks []*api.Track, current int) {
+	selected := v.TrackList.Selected
+	v.TrackList.SetItems(tracks)
+	v.TrackList.Select(selected)
+	v.TrackList.Summary = ""
+	if len(tracks) == 0 {
+		current = -1
+	} else {
+		v.TrackList.Summary = playlist.TrackStats(tracks).Summary()
+	}
+	v.Current = current
+	v.TrackList.ActiveIndex = current
+}
+
+// SetSavePoint shows where the queue save point is; nil clears it
+func (v *QueueView) SetSavePoint(track *api.Track, pos time.Duration) {
+	v.SavePoint = ""
+	if track != nil {
+		v.SavePoint = fmt.Sprintf("%s at %s", track.Title, formatChapterTime(pos))
+	}
+}
+
+// OpenSaved shows the saved queue picker. Saving also offers a new name.
+func (v *QueueView) OpenSaved(saved []*api.Playlist, save bool) {
+	v.Picking = true
+	v.Saving = save
+	v.Picker = components.NewPlaylistPicker(saved, v.Width)
+	v.Picker.Title = i18n.T("Load saved queue")
+	if save {
+		v.Picker.Title = i18n.T("Save queue as")
+	} else {
+		v.Picker.NoCreate = true
+	}
+}
+
+// Capturing reports whether the picker should receive every key
+func (v QueueView) Capturing() bool {
+	return v.Picking
+}
+
+// Update handles messages
+func (v QueueView) Update(msg tea.Msg) (QueueView, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if v.Picking {
+			var result components.PickerResult
+			v.Picker, result = v.Picker.Update(msg)
+			if !result.Done {
+				return v, nil
+			}
+			v.Picking = false
+			if result.Cancelled {
+				return v, nil
+			}
+			name := result.PlaylistID
+			if result.NewName != "" {
+				name = result.NewName
+			}
+			if v.Saving {
+				return v, func() tea.Msg { return SaveQueueMsg{Name: name} }
+			}
+			return v, func() tea.Msg { return LoadQueueMsg{Name: name} }
+		}
+
+		selected := v.TrackList.Selected
+		switch msg.String() {
+		case "w":
+			return v, func() tea.Msg { return ShowSavedQueuesMsg{Save: true} }
+		case "O":
+			return v, func() tea.Msg { return ShowSavedQueuesMsg{} }
+		case "shift+up", "K":
+			if selected > 0 {
+				v.TrackList.Select(selected - 1)
+				return v, func() tea.Msg {
+					return QueueMoveMsg{From: selected, To: selected - 1}
+				}
+			}
+		case "shift+down", "J":
+			if selected < len(v.TrackList.Items)-1 {
+				v.TrackList.Select(selected + 1)
+				return v, func() tea.Msg {
+					return QueueMoveMsg{From: selected, To: selected + 1}
+				}
+			}
+		case "d", "delete":
+			if selected < len(v.TrackList.Items) {
+				return v, func() tea.Msg {
+					return QueueRemoveMsg{Index: selected}
+				}
+			}
+		default:
+			v.TrackList, _ = v.TrackList.Update(msg)
+		}
+	}
+	return v, nil
+}
+
+// SelectedIndex returns the queue index under the cursor
+func (v *QueueView) SelectedIndex() int {
+	return v.TrackList.Selected
+}
+
+// View renders the queue view
+func (v QueueView) View() string {
+	var sb strings.Builder
+
+	if v.Picking {
+		sb.WriteString(v.Picker.View())
+	} else {
+		sb.WriteString(v.TrackList.View())
+	}
+	sb.WriteString("\n\n")
+
+	helpStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	if v.Current >= 0 {
+		sb.WriteString(helpStyle.Render(i18n.T("Playing %d of %d", v.Current+1, len(v.TrackList.Items))))
+		sb.WriteString("\n")
+	}
+	if v.SavePoint != "" {
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("214")).Render(glyphs.SavePoint.String() + " " + i18n.T("Save point: %s  [B] Return", v.SavePoint)))
+		sb.WriteString("\n")
+	}
+	sb.WriteString(helpStyle.Render(glyphs.Text(i18n.T("[Enter] Jump  [Shift+↑↓/K/J] Move  [d] Remove  [w] Save As  [O] Load Saved  [↑↓] Navigate"))))
+
+	return v.BorderStyle.Width(v.Width - 4).Render(sb.String())
+}
@@ -0,0 +1,178 @@
+package views
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/enrich"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// LookupMetadataMsg asks the app to look up corrected metadata for tracks
+type LookupMetadataMsg struct {
+	Tracks []*api.Track
+}
+
+// ReviewList is an overlay of metadata proposals from an online lookup.
+// Each can be accepted on its own, together with every proposal for the
+// same album, or dismissed.
+type ReviewList struct {
+	Items    []enrich.Proposal
+	Loading  bool // true until the lookup finishes
+	Pending  int  // tracks being looked up
+	Err      string
+	Selected int
+	Offset   int
+	Height   int
+	Width    int
+}
+
+// NewReviewList creates the overlay while pending tracks are looked up
+func NewReviewList(pending, width, height int) ReviewList {
+	return ReviewList{Loading: true, Pending: pending, Width: width, Height: height}
+}
+
+// SetProposals shows the lookup results
+func (l *ReviewList) SetProposals(items []enrich.Proposal, err error) {
+	l.Loading = false
+	l.Items = items
+	l.Selected, l.Offset = 0, 0
+	if err != nil {
+		l.Err = err.Error()
+	}
+}
+
+// accept removes the proposals at idx and returns the tag edits they make
+func (l *ReviewList) accept(idx []int) tea.Cmd {
+	var cmds []tea.Cmd
+	for i := len(idx) - 1; i >= 0; i-- {
+		p := l.Items[idx[i]]
+		tagsMsg := EditTagsMsg{TrackIDs: []string{p.Track.ID}, Edit: p.Edit()}
+		cmds = append(cmds, func() tea.Msg { return tagsMsg })
+		l.Items = append(l.Items[:idx[i]], l.Items[idx[i]+1:]...)
+	}
+	l.clamp()
+	return tea.Batch(cmds...)
+}
+
+func (l *ReviewList) clamp() {
+	if l.Selected >= len(l.Items) && l.Selected > 0 {
+		l.Selected = len(l.Items) - 1
+	}
+	l.ensureVisible()
+}
+
+// Update handles keys. done is true when the overlay should close.
+func (l ReviewList) Update(msg tea.KeyMsg) (ReviewList, tea.Cmd, bool) {
+	switch msg.String() {
+	case "esc", "M":
+		return l, nil, true
+	case "up", "k":
+		if l.Selected > 0 {
+			l.Selected--
+		}
+	case "down", "j":
+		if l.Selected < len(l.Items)-1 {
+			l.Selected++
+		}
+	case "a", "enter":
+		if l.Selected < len(l.Items) {
+			return l, l.accept([]int{l.Selected}), false
+		}
+	case "A":
+		// Accept every proposal for the selected one's album
+		if l.Selected < len(l.Items) {
+			sel := l.Items[l.Selected]
+			var idx []int
+			for i, p := range l.Items {
+				if p.Album == sel.Album && p.Artist == sel.Artist {
+					idx = append(idx, i)
+				}
+			}
+			return l, l.accept(idx), false
+		}
+	case "d", "x":
+		if l.Selected < len(l.Items) {
+			l.Items = append(l.Items[:l.Selected], l.Items[l.Selected+1:]...)
+			l.clamp()
+		}
+	}
+	l.ensureVisible()
+	return l, nil, false
+}
+
+func (l *ReviewList) ensureVisible() {
+	visible := l.visibleRows()
+	if l.Selected < l.Offset {
+		l.Offset = l.Selected
+	} else if l.Selected >= l.Offset+visible {
+		l.Offset = l.Selected - visible + 1
+	}
+}
+
+// visibleRows is the number of proposals shown; each takes two lines
+func (l ReviewList) visibleRows() int {
+	if (l.Height-4)/2 < 1 {
+		return 1
+	}
+	return (l.Height - 4) / 2
+}
+
+// View renders the list
+func (l ReviewList) View() string {
+	var sb strings.Builder
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	selectedStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230")).
+		Bold(true).
+		Padding(0, 1)
+	normalStyle := lipgloss.NewStyle().Padding(0, 1)
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	sb.WriteString(titleStyle.Render(glyphs.Icon(glyphs.Lookup) + i18n.T("Metadata suggestions")))
+	sb.WriteString("\n\n")
+
+	switch {
+	case l.Loading:
+		sb.WriteString(dim.Render(glyphs.Text(i18n.T("Looking up %d track(s)…", l.Pending))))
+		sb.WriteString("\n")
+	case l.Err != "" && len(l.Items) == 0:
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("Lookup failed: " + l.Err))
+		sb.WriteString("\n")
+	case len(l.Items) == 0:
+		sb.WriteString(dim.Render(i18n.T("No suggestions left")))
+		sb.WriteString("\n")
+	}
+	end := min(l.Offset+l.visibleRows(), len(l.Items))
+	for i := l.Offset; i < end; i++ {
+		p := l.Items[i]
+		from := truncateLabel(filepath.Base(p.Track.FilePath), max(l.Width-10, 10))
+		to := p.Artist + " - " + p.Title
+		if p.Album != "" {
+			to += " (" + p.Album
+			if p.Year > 0 {
+				to += fmt.Sprintf(", %d", p.Year)
+			}
+			to += ")"
+		}
+		line := fmt.Sprintf("%s %s  %d%%", glyphs.Right, truncateLabel(to, max(l.Width-16, 10)), int(p.Score*100))
+		if i == l.Selected {
+			sb.WriteString(selectedStyle.Render(line))
+		} else {
+			sb.WriteString(normalStyle.Render(line))
+		}
+		sb.WriteString("\n")
+		sb.WriteString(dim.Render("   " + from))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(dim.Render(i18n.T("[a] Accept  [A] Accept album  [d] Dismiss  [Esc] Close")))
+	return sb.String()
+}
@@ -0,0 +1,108 @@
+package views
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+	playerrors "github.com/jscyril/golang_music_player/pkg/errors"
+)
+
+// ShowScanErrorsMsg asks the app for the errors of the last scan
+type ShowScanErrorsMsg struct{}
+
+// ScanErrorList is an overlay of the files and directories the last scan
+// could not read
+type ScanErrorList struct {
+	Items    []*playerrors.ScanError
+	Failed   int // all failures, including those not kept in Items
+	Selected int
+	Offset   int
+	Height   int
+	Width    int
+}
+
+// NewScanErrorList creates the overlay
+func NewScanErrorList(items []*playerrors.ScanError, failed, width, height int) ScanErrorList {
+	return ScanErrorList{Items: items, Failed: failed, Width: width, Height: height}
+}
+
+// Update handles keys. done is true when the overlay should close.
+func (l ScanErrorList) Update(msg tea.KeyMsg) (ScanErrorList, tea.Cmd, bool) {
+	switch msg.String() {
+	case "esc", "E":
+		return l, nil, true
+	case "up", "k":
+		if l.Selected > 0 {
+			l.Selected--
+		}
+	case "down", "j":
+		if l.Selected < len(l.Items)-1 {
+			l.Selected++
+		}
+	}
+	l.ensureVisible()
+	return l, nil, false
+}
+
+func (l *ScanErrorList) ensureVisible() {
+	visible := l.visibleRows()
+	if l.Selected < l.Offset {
+		l.Offset = l.Selected
+	} else if l.Selected >= l.Offset+visible {
+		l.Offset = l.Selected - visible + 1
+	}
+}
+
+// visibleRows is the number of errors shown; each takes two lines
+func (l ScanErrorList) visibleRows() int {
+	if (l.Height-4)/2 < 1 {
+		return 1
+	}
+	return (l.Height - 4) / 2
+}
+
+// View renders the list
+func (l ScanErrorList) View() string {
+	var sb strings.Builder
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	selectedStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230")).
+		Bold(true).
+		Padding(0, 1)
+	normalStyle := lipgloss.NewStyle().Padding(0, 1)
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	title := glyphs.Icon(glyphs.Warning) + i18n.T("Scan errors")
+	if l.Failed > len(l.Items) {
+		title = glyphs.Icon(glyphs.Warning) + i18n.T("Scan errors (first %d of %d)", len(l.Items), l.Failed)
+	}
+	sb.WriteString(titleStyle.Render(title))
+	sb.WriteString("\n\n")
+
+	if len(l.Items) == 0 {
+		sb.WriteString(dim.Render(i18n.T("The last scan read every file")))
+		sb.WriteString("\n")
+	}
+	width := max(l.Width-6, 10)
+	end := min(l.Offset+l.visibleRows(), len(l.Items))
+	for i := l.Offset; i < end; i++ {
+		e := l.Items[i]
+		line := truncateLabel(e.Path, width)
+		if i == l.Selected {
+			sb.WriteString(selectedStyle.Render(line))
+		} else {
+			sb.WriteString(normalStyle.Render(line))
+		}
+		sb.WriteString("\n")
+		sb.WriteString(dim.Render("   " + truncateLabel(e.Err.Error(), width-3)))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+	sb.WriteString(dim.Render(glyphs.Text(i18n.T("[↑↓] Navigate  [Esc] Close"))))
+	return sb.String()
+}
@@ -0,0 +1,60 @@
+package views
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// RescanMsg asks the app to rescan the music directories
+type RescanMsg struct{}
+
+// CancelScanMsg asks the app to stop the running scan
+type CancelScanMsg struct{}
+
+// ScanPanel shows the progress of a library scan
+type ScanPanel struct {
+	Progress api.ScanProgress
+	Width    int
+}
+
+// View renders the panel
+func (p ScanPanel) View() string {
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	filled := lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	var sb strings.Builder
+
+	pr := p.Progress
+	sb.WriteString(titleStyle.Render(glyphs.Icon(glyphs.Scan) + i18n.T("Scanning library")))
+	sb.WriteString("  " + i18n.T("%d / %d files", pr.Processed, pr.Discovered))
+	if pr.Errors > 0 {
+		sb.WriteString("  " + i18n.T("%d unreadable", pr.Errors))
+	}
+	sb.WriteString("\n")
+
+	// The total grows while directories are still being walked
+	width := max(p.Width-4, 10)
+	done := 0
+	if pr.Discovered > 0 {
+		done = min(width*pr.Processed/pr.Discovered, width)
+	}
+	sb.WriteString(filled.Render(glyphs.Repeat(glyphs.HeavyLine, done)))
+	sb.WriteString(dim.Render(glyphs.Repeat(glyphs.Line, width-done)))
+	sb.WriteString("\n")
+
+	if pr.Current != "" {
+		sb.WriteString(dim.Render(truncateLabel(filepath.Base(pr.Current), width)))
+		sb.WriteString("\n")
+	}
+	if pr.LastError != "" {
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(truncateLabel(pr.LastError, width)))
+		sb.WriteString("\n")
+	}
+	sb.WriteString(dim.Render(i18n.T("[Esc] Cancel scan")))
+	return sb.String()
+}
@@ -0,0 +1,202 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/streaming"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// PlaylistShareMsg asks the app to match a playlist's tracks on the
+// streaming service named Service
+type PlaylistShareMsg struct {
+	ID      string
+	Service string
+}
+
+// ShareCreateMsg asks the app to create a playlist of the songs with IDs
+// on the streaming service named Service
+type ShareCreateMsg struct {
+	Service     string
+	Name        string
+	Description string
+	IDs         []string
+}
+
+// ShareReview is an overlay of the songs a streaming service matched to a
+// playlist's tracks. Confident matches are ticked; weak ones are shown
+// with their score for the user to tick, or to swap for another result,
+// before the playlist is created.
+type ShareReview struct {
+	Service     string
+	Name        string // of the playlist
+	Description string
+	Items       []streaming.Match
+	Loading     bool // true until the search finishes
+	Err         string
+	Selected    int
+	Offset      int
+	Height      int
+	Width       int
+}
+
+// NewShareReview creates the overlay while the tracks of the playlist
+// named name are looked up on service
+func NewShareReview(service, name, description string, width, height int) ShareReview {
+	return ShareReview{Service: service, Name: name, Description: description, Loading: true, Width: width, Height: height}
+}
+
+// SetMatches shows the search results
+func (r *ShareReview) SetMatches(items []streaming.Match, err error) {
+	r.Loading = false
+	r.Items = items
+	r.Selected, r.Offset = 0, 0
+	if err != nil {
+		r.Err = err.Error()
+	}
+}
+
+// chosen returns the IDs of the ticked songs, in playlist order
+func (r ShareReview) chosen() []string {
+	var ids []string
+	for _, m := range r.Items {
+		if c := m.Best(); c != nil {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids
+}
+
+// Update handles keys. done is true when the overlay should close.
+func (r ShareReview) Update(msg tea.KeyMsg) (ShareReview, tea.Cmd, bool) {
+	switch msg.String() {
+	case "esc":
+		return r, nil, true
+	case "up", "k":
+		if r.Selected > 0 {
+			r.Selected--
+		}
+	case "down", "j":
+		if r.Selected < len(r.Items)-1 {
+			r.Selected++
+		}
+	case " ", "x":
+		// Tick or untick the selected track's match
+		if r.Selected < len(r.Items) {
+			m := &r.Items[r.Selected]
+			switch {
+			case m.Chosen >= 0:
+				m.Chosen = -1
+			case len(m.Candidates) > 0:
+				m.Chosen = 0
+			}
+		}
+	case "tab", "right", "l":
+		// Try the next search result for the selected track
+		if r.Selected < len(r.Items) {
+			m := &r.Items[r.Selected]
+			if len(m.Candidates) > 0 {
+				m.Chosen = (m.Chosen + 1) % len(m.Candidates)
+			}
+		}
+	case "c", "enter":
+		ids := r.chosen()
+		if r.Loading || len(ids) == 0 {
+			return r, nil, false
+		}
+		create := ShareCreateMsg{Service: r.Service, Name: r.Name, Description: r.Description, IDs: ids}
+		return r, func() tea.Msg { return create }, true
+	}
+	r.ensureVisible()
+	return r, nil, false
+}
+
+func (r *ShareReview) ensureVisible() {
+	visible := r.visibleRows()
+	if r.Selected < r.Offset {
+		r.Offset = r.Selected
+	} else if r.Selected >= r.Offset+visible {
+		r.Offset = r.Selected - visible + 1
+	}
+}
+
+// visibleRows is the number of tracks shown; each takes two lines
+func (r ShareReview) visibleRows() int {
+	return max((r.Height-6)/2, 1)
+}
+
+// View renders the overlay
+func (r ShareReview) View() string {
+	var sb strings.Builder
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	selectedStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230")).
+		Bold(true).
+		Padding(0, 1)
+	normalStyle := lipgloss.NewStyle().Padding(0, 1)
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	weak := lipgloss.NewStyle().Foreground(lipgloss.Color("214"))
+
+	sb.WriteString(titleStyle.Render(glyphs.Icon(glyphs.Share) + i18n.T("Export %q to %s", r.Name, r.Service)))
+	sb.WriteString("\n\n")
+
+	switch {
+	case r.Loading:
+		sb.WriteString(dim.Render(glyphs.Text(i18n.T("Searching…"))))
+		sb.WriteString("\n")
+	case r.Err != "" && len(r.Items) == 0:
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render("Search failed: " + r.Err))
+		sb.WriteString("\n")
+	}
+	end := min(r.Offset+r.visibleRows(), len(r.Items))
+	for i := r.Offset; i < end; i++ {
+		m := r.Items[i]
+		local := m.Track.Title
+		if m.Track.Artist != "" {
+			local = m.Track.Artist + " - " + local
+		}
+		mark, detail := glyphs.Failed.String(), i18n.T("not found")
+		if c := m.Best(); c != nil {
+			mark = glyphs.Done.String()
+			detail = fmt.Sprintf("%s %s - %s", glyphs.Right, strings.Join(c.Artists, ", "), c.Title)
+			if c.Album != "" {
+				detail += " (" + c.Album + ")"
+			}
+			detail += fmt.Sprintf("  %d%%", int(m.Score()*100))
+			if len(m.Candidates) > 1 {
+				detail += fmt.Sprintf("  [%d/%d]", m.Chosen+1, len(m.Candidates))
+			}
+		} else if len(m.Candidates) > 0 {
+			mark = "?"
+			detail = i18n.T("weak match, best %d%%: %s", int(m.Scores[0]*100), m.Candidates[0].Title)
+		}
+		line := mark + " " + truncateLabel(local, max(r.Width-10, 10))
+		if i == r.Selected {
+			sb.WriteString(selectedStyle.Render(line))
+		} else {
+			sb.WriteString(normalStyle.Render(line))
+		}
+		sb.WriteString("\n")
+		detail = "   " + truncateLabel(detail, max(r.Width-10, 10))
+		if mark == "?" {
+			sb.WriteString(weak.Render(detail))
+		} else {
+			sb.WriteString(dim.Render(detail))
+		}
+		sb.WriteString("\n")
+	}
+
+	if !r.Loading && len(r.Items) > 0 {
+		sb.WriteString("\n")
+		sb.WriteString(dim.Render(i18n.T("%d of %d tracks will be exported", len(r.chosen()), len(r.Items))))
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(dim.Render(i18n.T("[Space] Tick  [Tab] Next result  [c] Create playlist  [Esc] Cancel")))
+	return sb.String()
+}
@@ -0,0 +1,181 @@
+package views
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/ui/components"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// ShowSkippedMsg asks the app for the frequently skipped tracks
+type ShowSkippedMsg struct{}
+
+// ShuffleBanMsg asks the app to leave a track out of shuffled queues, or to
+// let it back in
+type ShuffleBanMsg struct {
+	TrackID string
+	Banned  bool
+}
+
+// RemoveTracksMsg asks the app to remove tracks from the library
+type RemoveTracksMsg struct {
+	TrackIDs []string
+}
+
+// SkippedList is an overlay of tracks the play history shows are often
+// skipped early, with actions to ban them from shuffle or remove them
+type SkippedList struct {
+	Items       []library.SkipStat
+	Banned      map[string]bool
+	Selected    int
+	Offset      int
+	Height      int
+	Width       int
+	Confirming  bool // true while asking whether to remove the selected track
+	Confirm     components.Confirm
+	SkipConfirm map[string]bool // confirmations turned off with "don't ask again"
+}
+
+// NewSkippedList creates the overlay. banned reports the current shuffle
+// ban of a track.
+func NewSkippedList(items []library.SkipStat, banned func(id string) bool, width, height int) SkippedList {
+	l := SkippedList{Items: items, Banned: make(map[string]bool), Width: width, Height: height}
+	for _, it := range items {
+		l.Banned[it.TrackID] = banned(it.TrackID)
+	}
+	return l
+}
+
+// selectedID returns the track ID under the cursor
+func (l *SkippedList) selectedID() string {
+	if l.Selected >= 0 && l.Selected < len(l.Items) {
+		return l.Items[l.Selected].TrackID
+	}
+	return ""
+}
+
+// Update handles keys. done is true when the overlay should close.
+func (l SkippedList) Update(msg tea.KeyMsg) (SkippedList, tea.Cmd, bool) {
+	if l.Confirming {
+		var result components.ConfirmResult
+		l.Confirm, result = l.Confirm.Update(msg)
+		if !result.Done {
+			return l, nil, false
+		}
+		l.Confirming = false
+		if !result.Accepted {
+			return l, nil, false
+		}
+		return l, result.Then(l.removeSelected()), false
+	}
+
+	switch msg.String() {
+	case "esc", "F":
+		return l, nil, true
+	case "up", "k":
+		if l.Selected > 0 {
+			l.Selected--
+		}
+	case "down", "j":
+		if l.Selected < len(l.Items)-1 {
+			l.Selected++
+		}
+	case "b":
+		if id := l.selectedID(); id != "" {
+			l.Banned[id] = !l.Banned[id]
+			banned := l.Banned[id]
+			return l, func() tea.Msg { return ShuffleBanMsg{TrackID: id, Banned: banned} }, false
+		}
+	case "d":
+		switch {
+		case l.selectedID() == "":
+		case l.SkipConfirm[components.ConfirmRemoveTracks]:
+			return l, l.removeSelected(), false
+		default:
+			l.Confirming = true
+			l.Confirm = components.NewConfirm(components.ConfirmRemoveTracks, i18n.T("Remove this track from the library?"))
+		}
+	}
+	l.ensureVisible()
+	return l, nil, false
+}
+
+// removeSelected drops the selected track from the list and asks the app
+// to remove it from the library
+func (l *SkippedList) removeSelected() tea.Cmd {
+	id := l.selectedID()
+	l.Items = append(l.Items[:l.Selected], l.Items[l.Selected+1:]...)
+	if l.Selected >= len(l.Items) && l.Selected > 0 {
+		l.Selected--
+	}
+	l.ensureVisible()
+	return func() tea.Msg { return RemoveTracksMsg{TrackIDs: []string{id}} }
+}
+
+func (l *SkippedList) ensureVisible() {
+	visible := l.visibleRows()
+	if l.Selected < l.Offset {
+		l.Offset = l.Selected
+	} else if l.Selected >= l.Offset+visible {
+		l.Offset = l.Selected - visible + 1
+	}
+}
+
+func (l SkippedList) visibleRows() int {
+	if l.Height-4 < 1 {
+		return 1
+	}
+	return l.Height - 4
+}
+
+// View renders the list
+func (l SkippedList) View() string {
+	var sb strings.Builder
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	selectedStyle := lipgloss.NewStyle().
+		Background(lipgloss.Color("62")).
+		Foreground(lipgloss.Color("230")).
+		Bold(true).
+		Padding(0, 1)
+	normalStyle := lipgloss.NewStyle().Padding(0, 1)
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	sb.WriteString(titleStyle.Render(glyphs.Icon(glyphs.Skipped) + i18n.T("Frequently skipped")))
+	sb.WriteString("\n\n")
+
+	if len(l.Items) == 0 {
+		sb.WriteString(dim.Render(i18n.T("Nothing is skipped often enough to show up here")))
+		sb.WriteString("\n")
+	}
+	end := min(l.Offset+l.visibleRows(), len(l.Items))
+	for i := l.Offset; i < end; i++ {
+		it := l.Items[i]
+		flag := "  "
+		if l.Banned[it.TrackID] {
+			flag = glyphs.Off.String() + " "
+		}
+		name := it.Title
+		if it.Artist != "" {
+			name = it.Artist + " - " + it.Title
+		}
+		line := flag + truncateLabel(name, max(l.Width-34, 10)) + "  " + i18n.T("(skipped %d of %d plays)", it.Skips, it.Plays)
+		if i == l.Selected {
+			sb.WriteString(selectedStyle.Render(line))
+		} else {
+			sb.WriteString(normalStyle.Render(line))
+		}
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+	if l.Confirming {
+		sb.WriteString(l.Confirm.View())
+	} else {
+		sb.WriteString(dim.Render(i18n.T("[b] Ban/unban from shuffle  [d] Remove from library  [Esc] Close")))
+	}
+	return sb.String()
+}
@@ -0,0 +1,187 @@
+package views
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/ui/components"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+)
+
+// EditTagsMsg asks the app to write tag changes to tracks
+type EditTagsMsg struct {
+	TrackIDs []string
+	Edit     library.TagEdit
+}
+
+// Tag editor fields, in form order
+const (
+	tagTitle = iota
+	tagArtist
+	tagAlbum
+	tagGenre
+	tagYear
+	tagTrack
+	tagFieldCount
+)
+
+var tagLabels = [tagFieldCount]string{"Title", "Artist", "Album", "Genre", "Year", "Track #"}
+
+// TagEditor is an overlay form editing the tags of one or more tracks. With
+// several tracks, a field whose value differs between them starts empty and
+// is only changed if typed into.
+type TagEditor struct {
+	TrackIDs []string
+	Inputs   [tagFieldCount]components.SearchInput
+	Focus    int
+	Width    int
+	Err      string
+	initial  [tagFieldCount]string
+}
+
+// NewTagEditor creates the form for tracks
+func NewTagEditor(tracks []*api.Track, width int) TagEditor {
+	e := TagEditor{Width: width}
+	for i, t := range tracks {
+		e.TrackIDs = append(e.TrackIDs, t.ID)
+		values := tagValues(t)
+		for f := range values {
+			if i == 0 {
+				e.initial[f] = values[f]
+			} else if e.initial[f] != values[f] {
+				e.initial[f] = ""
+				e.Inputs[f].Placeholder = i18n.T("(mixed)")
+			}
+		}
+	}
+	for f := range e.Inputs {
+		in := components.NewSearchInput(width - 4)
+		in.Prompt = fmt.Sprintf("%-8s ", i18n.T(tagLabels[f]))
+		in.Placeholder = e.Inputs[f].Placeholder
+		in.Style = lipgloss.NewStyle()
+		in.FocusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+		in.SetValue(e.initial[f])
+		e.Inputs[f] = in
+	}
+	e.Inputs[0].Focus()
+	return e
+}
+
+// tagValues returns a track's editable tags as form text
+func tagValues(t *api.Track) [tagFieldCount]string {
+	var v [tagFieldCount]string
+	v[tagTitle], v[tagArtist], v[tagAlbum], v[tagGenre] = t.Title, t.Artist, t.Album, t.Genre
+	if t.Year > 0 {
+		v[tagYear] = strconv.Itoa(t.Year)
+	}
+	if t.TrackNum > 0 {
+		v[tagTrack] = strconv.Itoa(t.TrackNum)
+	}
+	return v
+}
+
+// edit collects the fields that were changed
+func (e *TagEditor) edit() (library.TagEdit, error) {
+	var edit library.TagEdit
+	for f := range e.Inputs {
+		value := strings.TrimSpace(e.Inputs[f].Value)
+		if value == e.initial[f] {
+			continue
+		}
+		switch f {
+		case tagTitle:
+			edit.Title = &value
+		case tagArtist:
+			edit.Artist = &value
+		case tagAlbum:
+			edit.Album = &value
+		case tagGenre:
+			edit.Genre = &value
+		case tagYear, tagTrack:
+			n := 0
+			if value != "" {
+				var err error
+				if n, err = strconv.Atoi(value); err != nil || n < 0 {
+					return edit, fmt.Errorf("%s must be a number", tagLabels[f])
+				}
+			}
+			if f == tagYear {
+				edit.Year = &n
+			} else {
+				edit.TrackNum = &n
+			}
+		}
+	}
+	return edit, nil
+}
+
+// Update handles keys. done is true when the form should close.
+func (e TagEditor) Update(msg tea.KeyMsg) (TagEditor, tea.Cmd, bool) {
+	switch msg.String() {
+	case "esc":
+		return e, nil, true
+	case "tab", "down":
+		e.move(1)
+	case "shift+tab", "up":
+		e.move(-1)
+	case "enter":
+		if e.Focus < tagFieldCount-1 {
+			e.move(1)
+			return e, nil, false
+		}
+		fallthrough
+	case "ctrl+s":
+		edit, err := e.edit()
+		if err != nil {
+			e.Err = err.Error()
+			return e, nil, false
+		}
+		if edit.IsZero() {
+			return e, nil, true
+		}
+		tagsMsg := EditTagsMsg{TrackIDs: e.TrackIDs, Edit: edit}
+		return e, func() tea.Msg { return tagsMsg }, true
+	default:
+		e.Inputs[e.Focus], _ = e.Inputs[e.Focus].Update(msg)
+		e.Err = ""
+	}
+	return e, nil, false
+}
+
+// move focuses the field delta places away
+func (e *TagEditor) move(delta int) {
+	e.Inputs[e.Focus].Blur()
+	e.Focus = (e.Focus + delta + tagFieldCount) % tagFieldCount
+	e.Inputs[e.Focus].Focus()
+}
+
+// View renders the form
+func (e TagEditor) View() string {
+	var sb strings.Builder
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+	title := glyphs.Icon(glyphs.Edit) + i18n.T("Edit tags")
+	if n := len(e.TrackIDs); n > 1 {
+		title = glyphs.Icon(glyphs.Edit) + i18n.T("Edit tags of %d tracks", n)
+	}
+	sb.WriteString(titleStyle.Render(title))
+	sb.WriteString("\n\n")
+	for _, in := range e.Inputs {
+		sb.WriteString(in.View())
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n")
+	if e.Err != "" {
+		sb.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Render(e.Err))
+		sb.WriteString("\n")
+	}
+	sb.WriteString(dim.Render(glyphs.Text(i18n.T("[Tab/↑↓] Field  [Enter] Next/Save  [Ctrl+S] Save  [Esc] Cancel"))))
+	return sb.String()
+}
@@ -0,0 +1,245 @@
+package views
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/i18n"
+	"github.com/jscyril/golang_music_player/internal/library"
+	"github.com/jscyril/golang_music_player/internal/ui/glyphs"
+	"github.com/mattn/go-runewidth"
+)
+
+// TrackInfoMsg asks the app for the details of a track
+type TrackInfoMsg struct {
+	Track *api.Track
+}
+
+// TrackInfo is an overlay listing everything known about one track: its
+// tags, what the file says about its encoding and the play log's counts
+type TrackInfo struct {
+	Track    *api.Track
+	Details  *library.FileDetails // nil while the file is read, or for streamed tracks
+	Err      error
+	Loading  bool
+	Stats    library.PlayStat
+	Source   string // search source of a streamed track
+	UserTags []string
+	Broken   string // why the track last failed to play
+	Rating   int    // stars out of 5
+	Offset   int
+	Height   int
+	Width    int
+}
+
+// NewTrackInfo creates the overlay for track
+func NewTrackInfo(track *api.Track, stats library.PlayStat, width, height int) TrackInfo {
+	return TrackInfo{Track: track, Stats: stats, Width: width, Height: height}
+}
+
+// Update handles keys. done is true when the overlay should close.
+func (t TrackInfo) Update(msg tea.KeyMsg) (TrackInfo, tea.Cmd, bool) {
+	switch msg.String() {
+	case "esc", "q", "i", "enter":
+		return t, nil, true
+	case "up", "k":
+		if t.Offset > 0 {
+			t.Offset--
+		}
+	case "down", "j":
+		if t.Offset < len(t.rows())-t.visibleRows() {
+			t.Offset++
+		}
+	}
+	return t, nil, false
+}
+
+func (t TrackInfo) visibleRows() int {
+	if t.Height-4 < 1 {
+		return 1
+	}
+	return t.Height - 4
+}
+
+// infoRow is one label and value of the overlay; an empty label is a gap
+type infoRow struct {
+	label string
+	value string
+}
+
+// rows lists what is known about the track, leaving out empty fields
+func (t TrackInfo) rows() []infoRow {
+	var rows []infoRow
+	add := func(label, value string) {
+		if value != "" && value != "0" {
+			rows = append(rows, infoRow{label, value})
+		}
+	}
+	gap := func() {
+		if len(rows) > 0 && rows[len(rows)-1].label != "" {
+			rows = append(rows, infoRow{})
+		}
+	}
+	count := func(n, of int) string {
+		switch {
+		case n == 0:
+			return ""
+		case of == 0:
+			return fmt.Sprint(n)
+		}
+		return fmt.Sprintf("%d / %d", n, of)
+	}
+
+	tr, d := t.Track, t.Details
+	if d == nil {
+		// Until the file is read, show what the scan recorded
+		d = &library.FileDetails{
+			SampleRate: tr.SampleRate,
+			Channels:   tr.Channels,
+			Bitrate:    tr.Bitrate,
+			Size:       tr.FileSize,
+		}
+	}
+	add("Title", tr.Title)
+	add("Artist", tr.Artist)
+	add("Album", tr.Album)
+	add("Album artist", tr.AlbumArtist)
+	if tr.Compilation {
+		add("Compilation", i18n.T("yes"))
+	}
+	add("Composer", d.Composer)
+	add("Genre", tr.Genre)
+	add("Tags", strings.Join(t.UserTags, ", "))
+	if t.Rating > 0 {
+		add("Rating", glyphs.Repeat(glyphs.Star, t.Rating)+glyphs.Repeat(glyphs.NoStar, 5-t.Rating))
+	}
+	add("Year", fmt.Sprint(tr.Year))
+	add("Track", count(tr.TrackNum, d.Tracks))
+	add("Disc", count(d.Disc, d.Discs))
+	add("Comment", d.Comment)
+
+	gap()
+	if t.Source != "" {
+		add("Source", t.Source)
+	} else {
+		add("Path", tr.FilePath)
+	}
+	add("Broken", t.Broken)
+	format := d.Format
+	if format != "" && d.TagFormat != "" {
+		format += " " + i18n.T("(%s tags)", d.TagFormat)
+	}
+	add("Format", format)
+	add("Codec", tr.Codec)
+	if tr.Duration > 0 {
+		add("Duration", formatChapterTime(tr.Duration))
+	}
+	if d.Bitrate > 0 {
+		add("Bitrate", fmt.Sprintf("%d kbps", d.Bitrate))
+	}
+	if d.SampleRate > 0 {
+		add("Sample rate", fmt.Sprintf("%g kHz", float64(d.SampleRate)/1000))
+	}
+	switch d.Channels {
+	case 0:
+	case 1:
+		add("Channels", i18n.T("1 (mono)"))
+	case 2:
+		add("Channels", i18n.T("2 (stereo)"))
+	default:
+		add("Channels", fmt.Sprint(d.Channels))
+	}
+	if d.BitDepth > 0 {
+		add("Bit depth", fmt.Sprintf("%d-bit", d.BitDepth))
+	}
+	if d.Size > 0 {
+		add("File size", formatSize(d.Size))
+	}
+	add("Modified", formatDate(d.ModTime))
+
+	gap()
+	if t.Stats.Plays > 0 {
+		add("Plays", i18n.T("%d (%d completed, %d skipped)", t.Stats.Plays, t.Stats.Completed, t.Stats.Skips))
+	} else {
+		add("Plays", i18n.T("never played"))
+	}
+	add("Last played", formatDate(t.Stats.LastPlayed))
+	add("Added", formatDate(tr.CreatedAt))
+	return rows
+}
+
+// formatSize formats a byte count in the largest fitting binary unit
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGT"[exp])
+}
+
+// formatDate formats a timestamp in local time, or "" when it is unset
+func formatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+// View renders the overlay
+func (t TrackInfo) View() string {
+	var sb strings.Builder
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	labelStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	errStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+
+	sb.WriteString(titleStyle.Render(glyphs.Icon(glyphs.Info) + i18n.T("Track details")))
+	sb.WriteString("\n\n")
+
+	rows := t.rows()
+	const labelWidth = 13
+	room := max(t.Width-labelWidth-10, 10)
+	end := min(t.Offset+t.visibleRows(), len(rows))
+	for _, row := range rows[t.Offset:end] {
+		if row.label == "" {
+			sb.WriteString("\n")
+			continue
+		}
+		value := row.value
+		if row.label == "Path" && runewidth.StringWidth(value) > room {
+			// Keep the file name rather than the start of the path
+			value = runewidth.TruncateLeft(value, runewidth.StringWidth(value)-room+1, glyphs.Ellipsis.String())
+		} else {
+			value = truncateLabel(value, room)
+		}
+		sb.WriteString(labelStyle.Render(fmt.Sprintf("%-*s", labelWidth, i18n.T(row.label))))
+		sb.WriteString(value)
+		sb.WriteString("\n")
+	}
+
+	switch {
+	case t.Loading:
+		sb.WriteString(dim.Render(glyphs.Text(i18n.T("Reading file…"))))
+		sb.WriteString("\n")
+	case t.Err != nil:
+		sb.WriteString(errStyle.Render(truncateLabel(t.Err.Error(), t.Width-10)))
+		sb.WriteString("\n")
+	}
+
+	sb.WriteString("\n")
+	help := i18n.T("[Esc] Close")
+	if len(rows) > t.visibleRows() {
+		help = glyphs.Text(i18n.T("[↑↓] Scroll")) + "  " + help
+	}
+	sb.WriteString(dim.Render(help))
+	return sb.String()
+}
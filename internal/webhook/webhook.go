@@ -0,0 +1,251 @@
+// Package webhook posts player events (track start/stop, queue changes) to
+// user-configured URLs so the player can drive chat bots, dashboards or
+// IFTTT-style automations without a plugin.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/internal/logger"
+	"github.com/jscyril/golang_music_player/pkg/events"
+)
+
+// Event names a player event a hook can subscribe to
+type Event string
+
+const (
+	EventTrackStart  Event = "track_start"
+	EventTrackStop   Event = "track_stop"
+	EventQueueChange Event = "queue_change"
+)
+
+// Payload is the data available to hook templates
+type Payload struct {
+	Event      Event      `json:"event"`
+	Time       time.Time  `json:"time"`
+	Track      *api.Track `json:"track,omitempty"`
+	Status     string     `json:"status"`
+	QueueLen   int        `json:"queue_length"`
+	QueueIndex int        `json:"queue_index"`
+}
+
+// Hook is a single outbound webhook
+type Hook struct {
+	URL         string
+	Events      map[Event]bool // empty means every event
+	ContentType string
+	tmpl        *template.Template
+}
+
+// NewHook creates a hook. body is a text/template rendered with a Payload;
+// an empty body posts the payload as JSON. Use {{json .Track.Title}} to embed
+// a value as a JSON-escaped string.
+func NewHook(url string, events []string, body, contentType string) (*Hook, error) {
+	h := &Hook{URL: url, ContentType: contentType, Events: make(map[Event]bool)}
+	if h.ContentType == "" {
+		h.ContentType = "application/json"
+	}
+	for _, e := range events {
+		h.Events[Event(e)] = true
+	}
+	if body != "" {
+		tmpl, err := template.New(url).Funcs(template.FuncMap{"json": toJSON}).Parse(body)
+		if err != nil {
+			return nil, fmt.Errorf("parse webhook template for %s: %w", url, err)
+		}
+		h.tmpl = tmpl
+	}
+	return h, nil
+}
+
+// Wants reports whether the hook subscribes to an event
+func (h *Hook) Wants(e Event) bool {
+	return len(h.Events) == 0 || h.Events[e]
+}
+
+// Render builds the request body for a payload
+func (h *Hook) Render(p Payload) ([]byte, error) {
+	if h.tmpl == nil {
+		return json.Marshal(p)
+	}
+	var buf bytes.Buffer
+	if err := h.tmpl.Execute(&buf, p); err != nil {
+		return nil, fmt.Errorf("render webhook template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// toJSON encodes v as JSON for use inside templates
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// queueSize bounds pending deliveries; events beyond it are dropped rather
+// than stalling the UI behind a slow endpoint
+const queueSize = 32
+
+// requestTimeout bounds a single delivery
+const requestTimeout = 5 * time.Second
+
+type delivery struct {
+	hook *Hook
+	body []byte
+}
+
+// Dispatcher delivers events to hooks from a background goroutine
+type Dispatcher struct {
+	hooks   []*Hook
+	client  *http.Client
+	pending chan delivery
+	done    chan struct{}
+	mu      sync.Mutex // guards sends on pending against Close
+	closed  bool
+}
+
+// NewDispatcher starts a dispatcher for the given hooks
+func NewDispatcher(hooks []*Hook) *Dispatcher {
+	d := &Dispatcher{
+		hooks:   hooks,
+		client:  &http.Client{Timeout: requestTimeout},
+		pending: make(chan delivery, queueSize),
+		done:    make(chan struct{}),
+	}
+	go d.run()
+	return d
+}
+
+// Fire queues an event for every subscribed hook without blocking.
+// A nil or closed dispatcher is a no-op so callers need not check for
+// configuration or shutdown.
+func (d *Dispatcher) Fire(p Payload) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return
+	}
+	if p.Time.IsZero() {
+		p.Time = time.Now()
+	}
+	for _, h := range d.hooks {
+		if !h.Wants(p.Event) {
+			continue
+		}
+		body, err := h.Render(p)
+		if err != nil {
+			logger.Warn("Webhook %s: %v", h.URL, err)
+			continue
+		}
+		select {
+		case d.pending <- delivery{hook: h, body: body}:
+		default:
+			logger.Warn("Webhook %s: queue full, dropping %s", h.URL, p.Event)
+		}
+	}
+}
+
+// Close stops the dispatcher after pending deliveries are sent
+func (d *Dispatcher) Close() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.pending)
+	}
+	d.mu.Unlock()
+	<-d.done
+}
+
+// Follow fires track_start, track_stop and queue_change for the player
+// events on bus until ctx is cancelled, whichever front end is running.
+// snapshot returns the playback state the payloads are made from.
+func (d *Dispatcher) Follow(ctx context.Context, bus *events.EventBus, snapshot func() *api.Snapshot) {
+	ch := bus.SubscribeWith(events.Policy{Types: []api.EventType{
+		api.EventTrackStarted, api.EventStateChange, api.EventQueueChanged,
+	}, Lossless: true})
+	defer bus.Unsubscribe(ch)
+
+	lastTrack, lastStatus := "", api.StatusStopped
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			state := &snapshot().PlaybackState
+			if ev.Type == api.EventQueueChanged {
+				d.fireState(EventQueueChange, state)
+				continue
+			}
+			trackID := ""
+			if state.CurrentTrack != nil {
+				trackID = state.CurrentTrack.ID
+			}
+			switch {
+			case state.Status == api.StatusPlaying && (trackID != lastTrack || lastStatus == api.StatusStopped):
+				d.fireState(EventTrackStart, state)
+			case state.Status == api.StatusStopped && lastStatus != api.StatusStopped:
+				d.fireState(EventTrackStop, state)
+			}
+			lastTrack, lastStatus = trackID, state.Status
+		}
+	}
+}
+
+// fireState fires event with the track and queue of state
+func (d *Dispatcher) fireState(event Event, state *api.PlaybackState) {
+	d.Fire(Payload{
+		Event:      event,
+		Track:      state.CurrentTrack,
+		Status:     StatusName(state.Status),
+		QueueLen:   len(state.Queue),
+		QueueIndex: state.QueueIndex,
+	})
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.done)
+	for dl := range d.pending {
+		if err := d.post(dl); err != nil {
+			logger.Warn("Webhook %s: %v", dl.hook.URL, err)
+		}
+	}
+}
+
+func (d *Dispatcher) post(dl delivery) error {
+	resp, err := d.client.Post(dl.hook.URL, dl.hook.ContentType, bytes.NewReader(dl.body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// StatusName returns the lowercase name of a playback status
+func StatusName(s api.PlayerStatus) string {
+	switch s {
+	case api.StatusPlaying:
+		return "playing"
+	case api.StatusPaused:
+		return "paused"
+	}
+	return "stopped"
+}
@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+	"github.com/jscyril/golang_music_player/pkg/events"
+)
+
+// TestDispatcherRendersTemplate verifies subscribed events are posted with the rendered body
+func TestDispatcherRendersTemplate(t *testing.T) {
+	bodies := make(chan string, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies <- string(data)
+	}))
+	defer srv.Close()
+
+	hook, err := NewHook(srv.URL, []string{"track_start"}, `{"text": {{json .Track.Title}}}`, "")
+	if err != nil {
+		t.Fatalf("NewHook: %v", err)
+	}
+	d := NewDispatcher([]*Hook{hook})
+
+	d.Fire(Payload{Event: EventQueueChange})
+	d.Fire(Payload{Event: EventTrackStart, Track: &api.Track{Title: `Say "Hi"`}})
+	d.Close()
+
+	select {
+	case body := <-bodies:
+		if want := `{"text": "Say \"Hi\""}`; body != want {
+			t.Errorf("body = %s, want %s", body, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+	if len(bodies) != 0 {
+		t.Errorf("unsubscribed event was delivered")
+	}
+}
+
+// TestDispatcherFireAfterClose verifies events fired during shutdown are
+// dropped instead of panicking
+func TestDispatcherFireAfterClose(t *testing.T) {
+	hook, err := NewHook("http://127.0.0.1:0", nil, "", "")
+	if err != nil {
+		t.Fatalf("NewHook: %v", err)
+	}
+	d := NewDispatcher([]*Hook{hook})
+	d.Close()
+	d.Fire(Payload{Event: EventTrackStop})
+	d.Close()
+}
+
+// TestFollowFiresTransitions verifies bus events become track_start,
+// track_stop and queue_change deliveries
+func TestFollowFiresTransitions(t *testing.T) {
+	delivered := make(chan Event, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p Payload
+		json.NewDecoder(r.Body).Decode(&p)
+		delivered <- p.Event
+	}))
+	defer srv.Close()
+
+	hook, err := NewHook(srv.URL, nil, "", "")
+	if err != nil {
+		t.Fatalf("NewHook: %v", err)
+	}
+	d := NewDispatcher([]*Hook{hook})
+	defer d.Close()
+
+	var mu sync.Mutex
+	state := api.PlaybackState{Status: api.StatusPlaying, CurrentTrack: &api.Track{ID: "a"}}
+	snapshot := func() *api.Snapshot {
+		mu.Lock()
+		defer mu.Unlock()
+		return &api.Snapshot{PlaybackState: state}
+	}
+	bus := events.NewEventBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Follow(ctx, bus, snapshot)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	next := func(want Event) {
+		t.Helper()
+		select {
+		case got := <-delivered:
+			if got != want {
+				t.Errorf("delivered %s, want %s", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s was not delivered", want)
+		}
+	}
+	// Follow subscribes in the background; a repeated start of the same
+	// track fires nothing once the first has arrived
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+	for len(delivered) == 0 {
+		bus.Publish(api.AudioEvent{Type: api.EventTrackStarted})
+		<-tick.C
+	}
+	next(EventTrackStart)
+	bus.Publish(api.AudioEvent{Type: api.EventQueueChanged})
+	next(EventQueueChange)
+	mu.Lock()
+	state.Status = api.StatusStopped
+	mu.Unlock()
+	bus.Publish(api.AudioEvent{Type: api.EventStateChange})
+	next(EventTrackStop)
+}
@@ -29,6 +29,11 @@ type APIClient struct {
 	BaseURL    string
 	HTTPClient *http.Client
 	Token      string
+
+	// StreamFormat ("mp3" or "opus") and StreamBitrate (kbit/s) ask the
+	// server to transcode streams; empty and 0 leave it to the server
+	StreamFormat  string
+	StreamBitrate int
 }
 
 // NewAPIClient creates a new APIClient with a 30-second timeout.
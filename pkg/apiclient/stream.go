@@ -1,8 +1,25 @@
 // Package apiclient provides stream URL helpers for the gtmpc REST API.
 package apiclient
 
+import (
+	"net/url"
+	"strconv"
+)
+
 // StreamURL returns the full URL for streaming a track by ID.
-// GET /api/stream/{trackId} with Authorization header.
+// GET /api/stream/{trackId} with Authorization header, asking for the
+// client's StreamFormat and StreamBitrate when set.
 func (c *APIClient) StreamURL(trackID string) string {
-	return c.BaseURL + "/api/stream/" + trackID
+	u := c.BaseURL + "/api/stream/" + trackID
+	q := url.Values{}
+	if c.StreamFormat != "" {
+		q.Set("format", c.StreamFormat)
+	}
+	if c.StreamBitrate > 0 {
+		q.Set("bitrate", strconv.Itoa(c.StreamBitrate))
+	}
+	if len(q) > 0 {
+		u += "?" + q.Encode()
+	}
+	return u
 }
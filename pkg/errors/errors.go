@@ -8,8 +8,12 @@ import (
 // Sentinel errors for common conditions
 var (
 	ErrTrackNotFound    = errors.New("track not found")
+	ErrAlbumNotFound    = errors.New("album not found")
+	ErrArtistNotFound   = errors.New("artist not found")
 	ErrPlaylistNotFound = errors.New("playlist not found")
+	ErrDuplicateTrack   = errors.New("track already in playlist")
 	ErrInvalidFormat    = errors.New("unsupported audio format")
+	ErrCorruptAudio     = errors.New("audio does not decode, the file is damaged or not what its extension says")
 	ErrPlaybackFailed   = errors.New("playback failed")
 	ErrEmptyQueue       = errors.New("playback queue is empty")
 	ErrInvalidVolume    = errors.New("volume must be between 0.0 and 1.0")
@@ -6,94 +6,250 @@ import (
 	"github.com/jscyril/golang_music_player/api"
 )
 
+// Critical reports whether an event must reach every subscriber. Critical
+// events that do not fit a subscriber's buffer are queued for it instead of
+// dropped; everything else is dropped and counted, so a stalled subscriber
+// never blocks the publisher.
+func Critical(t api.EventType) bool {
+	return t == api.EventTrackEnded || t == api.EventTrackFailed
+}
+
+// subscription is one subscriber's channel and its backlog of critical
+// events waiting for room
+type subscription struct {
+	ch       chan api.AudioEvent
+	lossless bool
+	mu       sync.Mutex
+	backlog  []api.AudioEvent
+	wake     chan struct{} // signals the drainer that the backlog grew
+	done     chan struct{} // closed on unsubscribe
+	drained  chan struct{} // closed when the drainer has exited
+}
+
+func newSubscription(size int) *subscription {
+	return &subscription{
+		ch:      make(chan api.AudioEvent, size),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		drained: make(chan struct{}),
+	}
+}
+
+// Stats counts events per type since the bus was created
+type Stats struct {
+	Published map[api.EventType]uint64
+	Dropped   map[api.EventType]uint64 // deliveries skipped because a subscriber was full
+}
+
 // EventBus handles event distribution using channels
 type EventBus struct {
-	subscribers map[api.EventType][]chan api.AudioEvent
+	subscribers map[api.EventType][]*subscription
 	mu          sync.RWMutex
+
+	statsMu   sync.Mutex
+	published map[api.EventType]uint64
+	dropped   map[api.EventType]uint64
 }
 
 // NewEventBus creates a new event bus
 func NewEventBus() *EventBus {
 	return &EventBus{
-		subscribers: make(map[api.EventType][]chan api.AudioEvent),
+		subscribers: make(map[api.EventType][]*subscription),
+		published:   make(map[api.EventType]uint64),
+		dropped:     make(map[api.EventType]uint64),
 	}
 }
 
+// AllTypes lists every event type published on the bus
+var AllTypes = []api.EventType{
+	api.EventTrackStarted,
+	api.EventTrackEnded,
+	api.EventPositionUpdate,
+	api.EventError,
+	api.EventStateChange,
+	api.EventLibraryChanged,
+	api.EventScanProgress,
+	api.EventPlaylistChanged,
+	api.EventTrackFailed,
+	api.EventBuffering,
+	api.EventQueueChanged,
+}
+
+// Policy sets how a subscription buffers events
+type Policy struct {
+	Types  []api.EventType // event types to receive; nil means AllTypes
+	Buffer int             // channel capacity; 0 means 20
+
+	// Lossless queues every event that does not fit the buffer, as is
+	// always done for critical ones, instead of dropping it. Use it for
+	// consumers that must see each change and keep up on average.
+	Lossless bool
+}
+
 // Subscribe returns a channel for receiving events of the specified type
 func (b *EventBus) Subscribe(eventType api.EventType) <-chan api.AudioEvent {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	ch := make(chan api.AudioEvent, 10)
-	b.subscribers[eventType] = append(b.subscribers[eventType], ch)
-	return ch
+	return b.SubscribeWith(Policy{Types: []api.EventType{eventType}, Buffer: 10})
 }
 
 // SubscribeAll returns a channel for receiving all event types
 func (b *EventBus) SubscribeAll() <-chan api.AudioEvent {
+	return b.SubscribeWith(Policy{})
+}
+
+// SubscribeWith returns a channel receiving events under the given policy
+func (b *EventBus) SubscribeWith(p Policy) <-chan api.AudioEvent {
+	if p.Types == nil {
+		p.Types = AllTypes
+	}
+	if p.Buffer <= 0 {
+		p.Buffer = 20
+	}
+
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	ch := make(chan api.AudioEvent, 20)
-	// Subscribe to all known event types
-	for _, eventType := range []api.EventType{
-		api.EventTrackStarted,
-		api.EventTrackEnded,
-		api.EventPositionUpdate,
-		api.EventError,
-		api.EventStateChange,
-	} {
-		b.subscribers[eventType] = append(b.subscribers[eventType], ch)
+	sub := newSubscription(p.Buffer)
+	sub.lossless = p.Lossless
+	go sub.drain()
+	for _, t := range p.Types {
+		b.subscribers[t] = append(b.subscribers[t], sub)
 	}
-	return ch
+	return sub.ch
 }
 
-// Publish broadcasts an event to all subscribers of that event type
+// Publish broadcasts an event to all subscribers of that event type. It
+// never blocks: a full subscriber misses non-critical events (counted in
+// Stats) and has critical ones queued.
 func (b *EventBus) Publish(event api.AudioEvent) {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
-	if subs, ok := b.subscribers[event.Type]; ok {
-		for _, ch := range subs {
+	critical := Critical(event.Type)
+	dropped := uint64(0)
+	for _, sub := range b.subscribers[event.Type] {
+		if !sub.offer(event, critical) {
+			dropped++
+		}
+	}
+
+	b.statsMu.Lock()
+	b.published[event.Type]++
+	b.dropped[event.Type] += dropped
+	b.statsMu.Unlock()
+}
+
+// offer delivers event without blocking, queueing it if critical. It
+// reports false if the event was dropped. Nothing overtakes queued events.
+func (s *subscription) offer(event api.AudioEvent, critical bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.backlog) == 0 {
+		select {
+		case s.ch <- event:
+			return true
+		default:
+		}
+	}
+	if !critical && !s.lossless {
+		// Channel full (or critical events waiting), skip to prevent blocking
+		return false
+	}
+	s.backlog = append(s.backlog, event)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// drain delivers a subscriber's queued critical events in order, blocking
+// until the subscriber reads them or unsubscribes
+func (sub *subscription) drain() {
+	defer close(sub.drained)
+	for {
+		select {
+		case <-sub.done:
+			return
+		case <-sub.wake:
+		}
+		for {
+			sub.mu.Lock()
+			if len(sub.backlog) == 0 {
+				sub.mu.Unlock()
+				break
+			}
+			ev := sub.backlog[0]
+			sub.mu.Unlock()
+
 			select {
-			case ch <- event:
-			default:
-				// Channel full, skip to prevent blocking
+			case sub.ch <- ev:
+			case <-sub.done:
+				return
 			}
+
+			sub.mu.Lock()
+			sub.backlog = sub.backlog[1:]
+			sub.mu.Unlock()
 		}
 	}
 }
 
+// Stats returns a copy of the published and dropped counters
+func (b *EventBus) Stats() Stats {
+	b.statsMu.Lock()
+	defer b.statsMu.Unlock()
+	st := Stats{
+		Published: make(map[api.EventType]uint64, len(b.published)),
+		Dropped:   make(map[api.EventType]uint64, len(b.dropped)),
+	}
+	for t, n := range b.published {
+		st.Published[t] = n
+	}
+	for t, n := range b.dropped {
+		if n > 0 {
+			st.Dropped[t] = n
+		}
+	}
+	return st
+}
+
 // Unsubscribe removes a subscriber channel
 func (b *EventBus) Unsubscribe(ch <-chan api.AudioEvent) {
 	b.mu.Lock()
-	defer b.mu.Unlock()
-
+	var found *subscription
 	for eventType, subs := range b.subscribers {
 		for i, sub := range subs {
-			if sub == ch {
+			if sub.ch == ch {
+				found = sub
 				b.subscribers[eventType] = append(subs[:i], subs[i+1:]...)
 				break
 			}
 		}
 	}
+	b.mu.Unlock()
+
+	if found != nil {
+		close(found.done)
+		<-found.drained
+	}
 }
 
 // Close closes all subscriber channels
 func (b *EventBus) Close() {
 	b.mu.Lock()
-	defer b.mu.Unlock()
-
 	// Track closed channels to avoid closing the same channel twice
-	closed := make(map[chan api.AudioEvent]bool)
-
+	closed := make(map[*subscription]bool)
 	for _, subs := range b.subscribers {
-		for _, ch := range subs {
-			if !closed[ch] {
-				close(ch)
-				closed[ch] = true
-			}
+		for _, sub := range subs {
+			closed[sub] = true
 		}
 	}
-	b.subscribers = make(map[api.EventType][]chan api.AudioEvent)
+	b.subscribers = make(map[api.EventType][]*subscription)
+	b.mu.Unlock()
+
+	for sub := range closed {
+		close(sub.done)
+		<-sub.drained
+		close(sub.ch)
+	}
 }
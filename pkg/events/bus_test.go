@@ -0,0 +1,77 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestPublish_FullSubscriber verifies a stalled subscriber never blocks the
+// publisher, loses only non-critical events, and still gets TrackEnded
+func TestPublish_FullSubscriber(t *testing.T) {
+	bus := NewEventBus()
+	ch := bus.SubscribeAll()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 30; i++ {
+			bus.Publish(api.AudioEvent{Type: api.EventPositionUpdate, Payload: i})
+		}
+		bus.Publish(api.AudioEvent{Type: api.EventTrackEnded})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a full subscriber")
+	}
+
+	st := bus.Stats()
+	if st.Published[api.EventPositionUpdate] != 30 || st.Dropped[api.EventPositionUpdate] != 10 {
+		t.Errorf("stats = %+v, want 30 position updates published and 10 dropped", st)
+	}
+	if st.Dropped[api.EventTrackEnded] != 0 {
+		t.Errorf("critical event counted as dropped: %+v", st)
+	}
+
+	var last api.AudioEvent
+	timeout := time.After(time.Second)
+	for n := 0; n < 21; n++ {
+		select {
+		case last = <-ch:
+		case <-timeout:
+			t.Fatalf("timed out after %d events", n)
+		}
+	}
+	if last.Type != api.EventTrackEnded {
+		t.Errorf("last event = %+v, want the queued track end", last)
+	}
+	bus.Close()
+}
+
+// TestSubscribeWith_Lossless verifies a lossless subscriber receives every
+// event in order, while a default one on the same bus drops the overflow
+func TestSubscribeWith_Lossless(t *testing.T) {
+	bus := NewEventBus()
+	defer bus.Close()
+	all := bus.SubscribeWith(Policy{Types: []api.EventType{api.EventPlaylistChanged}, Buffer: 2, Lossless: true})
+	lossy := bus.SubscribeWith(Policy{Types: []api.EventType{api.EventPlaylistChanged}, Buffer: 2})
+
+	for i := 0; i < 10; i++ {
+		bus.Publish(api.AudioEvent{Type: api.EventPlaylistChanged, Payload: i})
+	}
+	for i := 0; i < 10; i++ {
+		select {
+		case ev := <-all:
+			if ev.Payload != i {
+				t.Fatalf("event %d has payload %v", i, ev.Payload)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("lossless subscriber missed event %d", i)
+		}
+	}
+	if len(lossy) != 2 || bus.Stats().Dropped[api.EventPlaylistChanged] != 8 {
+		t.Errorf("lossy subscriber has %d events, stats %+v", len(lossy), bus.Stats())
+	}
+}
@@ -0,0 +1,90 @@
+package events
+
+import (
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// coalesced reports whether only the latest event of a type matters.
+// Position updates, state changes and scan progress carry full snapshots,
+// so a slow subscriber can skip intermediate ones without losing information.
+func coalesced(t api.EventType) bool {
+	return t == api.EventPositionUpdate || t == api.EventStateChange || t == api.EventScanProgress
+}
+
+// SubscribeThrottled returns a channel receiving all event types, rate-limited
+// for remote subscribers. Snapshot events (position updates, state changes)
+// are batched and delivered at most once per interval, keeping only the newest;
+// discrete events (track start/end, errors) are forwarded immediately, after
+// any pending snapshot so ordering is preserved. Call the returned function
+// to unsubscribe; the channel is closed once forwarding stops.
+func (b *EventBus) SubscribeThrottled(interval time.Duration) (<-chan api.AudioEvent, func()) {
+	in := b.SubscribeAll()
+	out := make(chan api.AudioEvent, 10)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		// Latest pending snapshot per type, in first-seen order
+		pending := make(map[api.EventType]api.AudioEvent)
+		var order []api.EventType
+
+		flush := func() bool {
+			for _, t := range order {
+				select {
+				case out <- pending[t]:
+				case <-done:
+					return false
+				}
+			}
+			pending = make(map[api.EventType]api.AudioEvent)
+			order = order[:0]
+			return true
+		}
+
+		for {
+			select {
+			case <-done:
+				return
+			case ev, ok := <-in:
+				if !ok {
+					flush()
+					return
+				}
+				if coalesced(ev.Type) {
+					if _, seen := pending[ev.Type]; !seen {
+						order = append(order, ev.Type)
+					}
+					pending[ev.Type] = ev
+					continue
+				}
+				if !flush() {
+					return
+				}
+				select {
+				case out <- ev:
+				case <-done:
+					return
+				}
+			case <-ticker.C:
+				if !flush() {
+					return
+				}
+			}
+		}
+	}()
+
+	stop := func() {
+		b.Unsubscribe(in)
+		select {
+		case <-done:
+		default:
+			close(done)
+		}
+	}
+	return out, stop
+}
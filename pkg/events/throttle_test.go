@@ -0,0 +1,45 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jscyril/golang_music_player/api"
+)
+
+// TestSubscribeThrottled verifies position updates are coalesced to the
+// latest one while discrete events still arrive in order
+func TestSubscribeThrottled(t *testing.T) {
+	bus := NewEventBus()
+	ch, stop := bus.SubscribeThrottled(50 * time.Millisecond)
+	defer stop()
+
+	for i := 1; i <= 5; i++ {
+		bus.Publish(api.AudioEvent{Type: api.EventPositionUpdate, Payload: i})
+	}
+	bus.Publish(api.AudioEvent{Type: api.EventTrackEnded})
+
+	var got []api.AudioEvent
+	timeout := time.After(time.Second)
+	for len(got) < 2 {
+		select {
+		case ev := <-ch:
+			got = append(got, ev)
+		case <-timeout:
+			t.Fatalf("timed out, got %d events", len(got))
+		}
+	}
+
+	if got[0].Type != api.EventPositionUpdate || got[0].Payload != 5 {
+		t.Errorf("expected latest position update (5) first, got %+v", got[0])
+	}
+	if got[1].Type != api.EventTrackEnded {
+		t.Errorf("expected track ended second, got %+v", got[1])
+	}
+
+	select {
+	case ev := <-ch:
+		t.Errorf("unexpected extra event %+v", ev)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,239 @@
+package stats
+
+import "time"
+
+// completionSlack is how close to the end of a track playback must get for
+// an unannounced track change to count as a completed listen.
+const completionSlack = 2 * time.Second
+
+// EndReason records why a listening session ended.
+type EndReason int
+
+const (
+	// EndOpen marks a session that is still playing.
+	EndOpen EndReason = iota
+	// EndCompleted means the track played through to its end.
+	EndCompleted
+	// EndSkipped means the listener moved on before the track finished.
+	EndSkipped
+	// EndStopped means playback was stopped outright.
+	EndStopped
+)
+
+// String returns a lower-case name for the reason.
+func (r EndReason) String() string {
+	switch r {
+	case EndCompleted:
+		return "completed"
+	case EndSkipped:
+		return "skipped"
+	case EndStopped:
+		return "stopped"
+	}
+	return "playing"
+}
+
+// SeekEvent records a jump within a track.
+type SeekEvent struct {
+	At   time.Time
+	From time.Duration
+	To   time.Duration
+}
+
+// Pause is a span of wall-clock time during which a track was paused. End is
+// zero while the pause is still in effect.
+type Pause struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Session is one uninterrupted stretch of a track being loaded in the player,
+// from the moment it started to the moment it was replaced or stopped. It is
+// the raw material for reconstructing what was actually heard.
+type Session struct {
+	TrackID  string
+	Title    string
+	Artist   string
+	Duration time.Duration // length of the track, 0 if unknown
+
+	StartedAt time.Time
+	EndedAt   time.Time // zero while the session is open
+	StartPos  time.Duration
+	EndPos    time.Duration
+
+	Seeks  []SeekEvent
+	Pauses []Pause
+	Reason EndReason
+}
+
+// Listened returns the wall-clock time the track was audibly playing, i.e.
+// the session length minus any pauses. Open sessions are measured up to now.
+func (s Session) Listened(now time.Time) time.Duration {
+	end := s.EndedAt
+	if end.IsZero() {
+		end = now
+	}
+	d := end.Sub(s.StartedAt)
+	for _, p := range s.Pauses {
+		pEnd := p.End
+		if pEnd.IsZero() {
+			pEnd = end
+		}
+		d -= pEnd.Sub(p.Start)
+	}
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// paused reports whether the session currently has an open pause.
+func (s *Session) paused() bool {
+	return len(s.Pauses) > 0 && s.Pauses[len(s.Pauses)-1].End.IsZero()
+}
+
+// position estimates the playback position at now from the last known
+// position (start or latest seek) plus the unpaused time since then.
+func (s *Session) position(now time.Time) time.Duration {
+	base, since := s.StartPos, s.StartedAt
+	if n := len(s.Seeks); n > 0 {
+		base, since = s.Seeks[n-1].To, s.Seeks[n-1].At
+	}
+	played := now.Sub(since)
+	for _, p := range s.Pauses {
+		start, end := p.Start, p.End
+		if end.IsZero() {
+			end = now
+		}
+		if end.Before(since) {
+			continue
+		}
+		if start.Before(since) {
+			start = since
+		}
+		played -= end.Sub(start)
+	}
+	if played < 0 {
+		played = 0
+	}
+	pos := base + played
+	if s.Duration > 0 && pos > s.Duration {
+		pos = s.Duration
+	}
+	return pos
+}
+
+// StartSession opens a listening session for a track that has just begun
+// playing at position 0. Any session still open is closed first: as
+// completed if its estimated position reached the end of the track,
+// otherwise as skipped.
+func (s *Stats) StartSession(trackID, title, artist string, duration time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if cur := s.current(); cur != nil {
+		pos := cur.position(now)
+		cur.close(now, pos, cur.endReason(pos, EndSkipped))
+	}
+	s.sessions = append(s.sessions, Session{
+		TrackID:   trackID,
+		Title:     title,
+		Artist:    artist,
+		Duration:  duration,
+		StartedAt: now,
+	})
+}
+
+// EndSession closes the open session at the given playback position.
+// It is a no-op if no session is open.
+func (s *Stats) EndSession(pos time.Duration, reason EndReason) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur := s.current(); cur != nil {
+		cur.close(s.now(), pos, reason)
+	}
+}
+
+// Ended closes the open session when playback stopped: as completed if its
+// estimated position reached the end of the track, otherwise as stopped.
+// Until then an open session counts towards the listening time, so players
+// call it when playback stops or runs out. It is a no-op if no session is
+// open.
+func (s *Stats) Ended() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur := s.current(); cur != nil {
+		now := s.now()
+		pos := cur.position(now)
+		cur.close(now, pos, cur.endReason(pos, EndStopped))
+	}
+}
+
+// Paused records that playback of the open session was paused.
+func (s *Stats) Paused() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur := s.current(); cur != nil && !cur.paused() {
+		cur.Pauses = append(cur.Pauses, Pause{Start: s.now()})
+	}
+}
+
+// Resumed records that playback of the open session was resumed.
+func (s *Stats) Resumed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur := s.current(); cur != nil && cur.paused() {
+		cur.Pauses[len(cur.Pauses)-1].End = s.now()
+	}
+}
+
+// Seeked records a jump from one position to another in the open session.
+func (s *Stats) Seeked(from, to time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cur := s.current(); cur != nil {
+		cur.Seeks = append(cur.Seeks, SeekEvent{At: s.now(), From: from, To: to})
+	}
+}
+
+// Sessions returns a copy of all recorded sessions in chronological order,
+// including the open one if a track is playing.
+func (s *Stats) Sessions() []Session {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Session, len(s.sessions))
+	for i, sess := range s.sessions {
+		sess.Seeks = append([]SeekEvent(nil), sess.Seeks...)
+		sess.Pauses = append([]Pause(nil), sess.Pauses...)
+		out[i] = sess
+	}
+	return out
+}
+
+// current returns the open session, or nil. Callers must hold s.mu.
+func (s *Stats) current() *Session {
+	if n := len(s.sessions); n > 0 && s.sessions[n-1].Reason == EndOpen {
+		return &s.sessions[n-1]
+	}
+	return nil
+}
+
+// endReason is EndCompleted if pos reached the end of the track, and
+// fallback if it did not
+func (s *Session) endReason(pos time.Duration, fallback EndReason) EndReason {
+	if s.Duration > 0 && pos >= s.Duration-completionSlack {
+		return EndCompleted
+	}
+	return fallback
+}
+
+// close ends the session, closing any pause still in effect.
+func (s *Session) close(now time.Time, pos time.Duration, reason EndReason) {
+	if s.paused() {
+		s.Pauses[len(s.Pauses)-1].End = now
+	}
+	s.EndedAt = now
+	s.EndPos = pos
+	s.Reason = reason
+}
@@ -35,6 +35,12 @@ type StatsSummary struct {
 	TotalSeconds  int
 	FormattedTime string // e.g. "1h 24m 08s"
 
+	// True listening time from session timestamps (pauses excluded), and
+	// how many tracks were skipped before they finished
+	ListenedSeconds   int
+	FormattedListened string
+	TracksSkipped     int
+
 	// Top artist (string manipulation: frequency map + sort)
 	TopArtist        string
 	ArtistPlayCounts map[string]int // artist → play count (sorted by count desc)
@@ -64,15 +70,18 @@ type ArtistBar struct {
 
 // Stats is the thread-safe in-memory statistics tracker.
 type Stats struct {
-	mu     sync.RWMutex
-	events []PlayEvent
-	likes  map[string]bool // trackID → liked
+	mu       sync.RWMutex
+	events   []PlayEvent
+	sessions []Session
+	likes    map[string]bool // trackID → liked
+	now      func() time.Time
 }
 
 // New creates an initialised Stats tracker.
 func New() *Stats {
 	return &Stats{
 		likes: make(map[string]bool),
+		now:   time.Now,
 	}
 }
 
@@ -86,7 +95,7 @@ func (s *Stats) RecordPlay(trackID, title, artist, album string, durationSecs in
 		Artist:       artist,
 		Album:        album,
 		DurationSecs: durationSecs,
-		PlayedAt:     time.Now(),
+		PlayedAt:     s.now(),
 	})
 }
 
@@ -110,6 +119,7 @@ func (s *Stats) Clear() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.events = nil
+	s.sessions = nil
 	s.likes = make(map[string]bool)
 }
 
@@ -123,6 +133,18 @@ func (s *Stats) Summary() StatsSummary {
 		ArtistPlayCounts: make(map[string]int),
 	}
 
+	// ── True listening time from sessions ──────────────────────────────────
+	now := s.now()
+	var listened time.Duration
+	for _, sess := range s.sessions {
+		listened += sess.Listened(now)
+		if sess.Reason == EndSkipped {
+			sum.TracksSkipped++
+		}
+	}
+	sum.ListenedSeconds = int(listened / time.Second)
+	sum.FormattedListened = FormatListenTime(sum.ListenedSeconds)
+
 	if len(s.events) == 0 {
 		sum.FormattedTime = "0s"
 		sum.FormattedMean = "—"
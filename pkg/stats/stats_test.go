@@ -5,6 +5,7 @@ import (
 	"math"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestRecordPlay verifies that RecordPlay increments the play count correctly.
@@ -177,3 +178,90 @@ func TestClearResetsState(t *testing.T) {
 		t.Errorf("after Clear, FormattedTime = %q, want '0s'", sum.FormattedTime)
 	}
 }
+
+// TestSessions verifies listening time excludes pauses and that replacing a
+// track early closes its session as skipped.
+func TestSessions(t *testing.T) {
+	s := New()
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return clock }
+
+	s.StartSession("id1", "Song A", "Artist X", 3*time.Minute)
+	clock = clock.Add(30 * time.Second)
+	s.Paused()
+	clock = clock.Add(time.Minute)
+	s.Resumed()
+	clock = clock.Add(10 * time.Second)
+	s.Seeked(40*time.Second, 2*time.Minute+55*time.Second)
+	clock = clock.Add(5 * time.Second)
+
+	// id1 reached its end, so starting id2 completes it
+	s.StartSession("id2", "Song B", "Artist Y", 4*time.Minute)
+	clock = clock.Add(5 * time.Second)
+	s.StartSession("id3", "Song C", "Artist Y", 4*time.Minute)
+
+	sessions := s.Sessions()
+	if len(sessions) != 3 {
+		t.Fatalf("got %d sessions, want 3", len(sessions))
+	}
+	if got := sessions[0].Reason; got != EndCompleted {
+		t.Errorf("session 0 reason = %v, want completed", got)
+	}
+	if got := sessions[0].Listened(clock); got != 45*time.Second {
+		t.Errorf("session 0 listened = %v, want 45s", got)
+	}
+	if got := sessions[1].Reason; got != EndSkipped {
+		t.Errorf("session 1 reason = %v, want skipped", got)
+	}
+	if got := sessions[1].EndPos; got != 5*time.Second {
+		t.Errorf("session 1 end position = %v, want 5s", got)
+	}
+	if got := sessions[2].Reason; got != EndOpen {
+		t.Errorf("session 2 reason = %v, want playing", got)
+	}
+
+	sum := s.Summary()
+	if sum.ListenedSeconds != 50 {
+		t.Errorf("ListenedSeconds = %d, want 50", sum.ListenedSeconds)
+	}
+	if sum.TracksSkipped != 1 {
+		t.Errorf("TracksSkipped = %d, want 1", sum.TracksSkipped)
+	}
+}
+
+// TestSessionEnded verifies that stopping closes the open session, so the
+// listening time stops growing, and that a pause stops the clock too
+func TestSessionEnded(t *testing.T) {
+	s := New()
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return clock }
+
+	s.StartSession("id1", "Song A", "Artist X", 3*time.Minute)
+	clock = clock.Add(20 * time.Second)
+	s.Paused()
+	clock = clock.Add(time.Hour)
+	if got := s.Summary().ListenedSeconds; got != 20 {
+		t.Errorf("ListenedSeconds while paused = %d, want 20", got)
+	}
+	s.Resumed()
+	clock = clock.Add(10 * time.Second)
+	s.Ended()
+	clock = clock.Add(time.Hour)
+
+	sessions := s.Sessions()
+	if len(sessions) != 1 || sessions[0].Reason != EndStopped || sessions[0].EndPos != 30*time.Second {
+		t.Fatalf("sessions = %+v, want one stopped at 30s", sessions)
+	}
+	if got := s.Summary().ListenedSeconds; got != 30 {
+		t.Errorf("ListenedSeconds an hour after stopping = %d, want 30", got)
+	}
+
+	// A track that plays out is completed; Ended without a session is a no-op
+	s.StartSession("id2", "Song B", "Artist Y", time.Minute)
+	clock = clock.Add(time.Minute)
+	s.Ended()
+	s.Ended()
+	if sessions = s.Sessions(); len(sessions) != 2 || sessions[1].Reason != EndCompleted {
+		t.Errorf("played-out session = %+v, want completed", sessions[len(sessions)-1])
+	}
+}